@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// =====================================================
+// AI PROVIDERS (Gemini / OpenAI-compatible)
+// =====================================================
+
+// aiProvider generates a text reply to one user turn
+type aiProvider interface {
+	Name() string
+	DefaultModel() string
+	Generate(model string, parts []aiPart) (string, error)
+}
+
+// aiPart is one piece of a prompt: text, or inline data such as a photo
+type aiPart struct {
+	Text     string
+	MimeType string
+	Data     []byte
+}
+
+// aiReply is the text a provider returned and which provider/model wrote it
+type aiReply struct {
+	Text     string
+	Provider string
+	Model    string
+}
+
+// aiError is a provider's non-200 reply
+type aiError struct {
+	Provider string
+	Status   int
+	Body     string
+}
+
+func (e *aiError) Error() string {
+	return fmt.Sprintf("%s error %d: %s", e.Provider, e.Status, e.Body)
+}
+
+var aiClient = &http.Client{Timeout: 60 * time.Second}
+
+// getAIProvider returns the named provider if its key is set
+func getAIProvider(name string) aiProvider {
+	switch name {
+	case "gemini":
+		if key := os.Getenv("GEMINI_API_KEY"); key != "" {
+			return &geminiProvider{apiKey: key, model: os.Getenv("GEMINI_MODEL")}
+		}
+	case "openai":
+		if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+			return &openAIProvider{
+				baseURL: strings.TrimSuffix(os.Getenv("OPENAI_BASE_URL"), "/"),
+				apiKey:  key,
+				model:   os.Getenv("OPENAI_MODEL"),
+			}
+		}
+	}
+	return nil
+}
+
+// aiProviders returns the configured providers in the order they are tried:
+// AI_PROVIDER (default gemini), then AI_FALLBACK_PROVIDER. When AI_PROVIDER
+// has no key, Gemini is tried last if its key is set.
+func aiProviders() []aiProvider {
+	primary := os.Getenv("AI_PROVIDER")
+	if primary == "" {
+		primary = "gemini"
+	}
+	names := []string{primary, os.Getenv("AI_FALLBACK_PROVIDER")}
+	if getAIProvider(primary) == nil {
+		names = append(names, "gemini")
+	}
+	providers := []aiProvider{}
+	for _, name := range names {
+		if p := getAIProvider(name); p != nil && !hasAIProvider(providers, name) {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+func hasAIProvider(providers []aiProvider, name string) bool {
+	for _, p := range providers {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAIProviders logs at startup when AI_PROVIDER or AI_FALLBACK_PROVIDER
+// names a provider without a key, so grading doesn't quietly use another one
+func checkAIProviders() {
+	for _, env := range []string{"AI_PROVIDER", "AI_FALLBACK_PROVIDER"} {
+		if name := os.Getenv(env); name != "" && getAIProvider(name) == nil {
+			log.Printf("Warning: %s=%s but its API key is not set (or the provider is unknown; use gemini or openai)", env, name)
+		}
+	}
+	if providers := aiProviders(); len(providers) == 0 {
+		log.Println("Warning: no AI provider configured (GEMINI_API_KEY or OPENAI_API_KEY); AI grading stays queued")
+	} else if os.Getenv("AI_PROVIDER") != "" && providers[0].Name() != os.Getenv("AI_PROVIDER") {
+		log.Printf("Warning: AI requests go to %s first", providers[0].Name())
+	}
+}
+
+// aiConfigured reports whether any AI provider key is set
+func aiConfigured() bool {
+	return len(aiProviders()) > 0
+}
+
+// generateText sends a single prompt to the AI provider and returns the text reply
+func generateText(prompt string) (string, error) {
+	reply, err := generateContent("", []aiPart{{Text: prompt}})
+	return reply.Text, err
+}
+
+// generateWithImage sends a prompt along with one image, e.g. a photographed
+// answer, and returns the reply. model "" uses the provider's default; see
+// generateContent for naming a provider.
+func generateWithImage(model, prompt, mimeType string, image []byte) (aiReply, error) {
+	return generateContent(model, []aiPart{{MimeType: mimeType, Data: image}, {Text: prompt}})
+}
+
+// generateContent sends one user turn to each configured provider in turn
+// until one answers, so an outage or rate limit on the primary falls back to
+// the next. model may be "provider:model" to ask one provider for a specific
+// model, which is then tried first; a bare model name is for the primary
+// provider, and any provider not named uses its own default.
+func generateContent(model string, parts []aiPart) (aiReply, error) {
+	providers := aiProviders()
+	if len(providers) == 0 {
+		return aiReply{}, fmt.Errorf("no AI provider configured (GEMINI_API_KEY or OPENAI_API_KEY)")
+	}
+
+	forProvider := providers[0].Name()
+	if i := strings.Index(model, ":"); i >= 0 {
+		forProvider, model = model[:i], model[i+1:]
+		named := getAIProvider(forProvider)
+		if named == nil {
+			return aiReply{}, fmt.Errorf("AI provider %s is not configured", forProvider)
+		}
+		ordered := []aiProvider{named}
+		for _, p := range providers {
+			if p.Name() != forProvider {
+				ordered = append(ordered, p)
+			}
+		}
+		providers = ordered
+	}
+
+	var errs []string
+	for _, p := range providers {
+		m := p.DefaultModel()
+		if model != "" && p.Name() == forProvider {
+			m = model
+		}
+		text, err := p.Generate(m, parts)
+		if err == nil {
+			return aiReply{Text: text, Provider: p.Name(), Model: m}, nil
+		}
+		if e, ok := err.(*aiError); ok && e.Status == http.StatusTooManyRequests {
+			log.Printf("AI provider %s is rate limited, trying the next one", p.Name())
+		}
+		errs = append(errs, err.Error())
+	}
+	return aiReply{}, fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// stripCodeFence returns a reply's content without the markdown code fence
+// (```json ... ```) models often wrap JSON in
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	} else {
+		text = strings.TrimLeft(strings.TrimPrefix(text, "```"), "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// ---------- Gemini ----------
+
+type geminiProvider struct {
+	apiKey string
+	model  string
+}
+
+func (p *geminiProvider) Name() string { return "gemini" }
+
+func (p *geminiProvider) DefaultModel() string {
+	if p.model != "" {
+		return p.model
+	}
+	return "gemini-1.5-flash"
+}
+
+func (p *geminiProvider) Generate(model string, parts []aiPart) (string, error) {
+	contentParts := []map[string]interface{}{}
+	for _, part := range parts {
+		if part.Data != nil {
+			contentParts = append(contentParts, map[string]interface{}{
+				"inline_data": map[string]string{"mime_type": part.MimeType, "data": base64.StdEncoding.EncodeToString(part.Data)},
+			})
+			continue
+		}
+		contentParts = append(contentParts, map[string]interface{}{"text": part.Text})
+	}
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{"parts": contentParts},
+		},
+	})
+
+	// The key goes in a header so it never appears in a logged URL
+	endpoint := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", url.PathEscape(model))
+	req, _ := http.NewRequest("POST", endpoint, bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.apiKey)
+	resp, err := aiClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gemini: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", &aiError{Provider: "gemini", Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var geminiResp struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+	}
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return "", fmt.Errorf("gemini: %v", err)
+	}
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("gemini returned no content")
+	}
+
+	return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// ---------- OpenAI-compatible (OpenAI, Azure-style gateways, OpenRouter, local servers) ----------
+
+type openAIProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+func (p *openAIProvider) Name() string { return "openai" }
+
+func (p *openAIProvider) DefaultModel() string {
+	if p.model != "" {
+		return p.model
+	}
+	return "gpt-4o-mini"
+}
+
+func (p *openAIProvider) Generate(model string, parts []aiPart) (string, error) {
+	content := []map[string]interface{}{}
+	for _, part := range parts {
+		if part.Data != nil {
+			content = append(content, map[string]interface{}{
+				"type": "image_url",
+				"image_url": map[string]string{
+					"url": "data:" + part.MimeType + ";base64," + base64.StdEncoding.EncodeToString(part.Data),
+				},
+			})
+			continue
+		}
+		content = append(content, map[string]interface{}{"type": "text", "text": part.Text})
+	}
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"model": model,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": content},
+		},
+	})
+
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	req, _ := http.NewRequest("POST", baseURL+"/chat/completions", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	resp, err := aiClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("openai: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", &aiError{Provider: "openai", Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var openAIResp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return "", fmt.Errorf("openai: %v", err)
+	}
+	if len(openAIResp.Choices) == 0 || openAIResp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("openai returned no content")
+	}
+
+	return openAIResp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// RANGE ANALYTICS (Income/expense per month or week)
+// ============================================
+
+// maxAnalyticsRangeDays caps /analytics/range at about three years
+const maxAnalyticsRangeDays = 1096
+
+// percentChange is the change from previous to current in percent, or nil
+// when there's nothing to compare against
+func percentChange(current, previous float64) interface{} {
+	if previous == 0 {
+		return nil
+	}
+	return roundMoney((current - previous) / previous * 100)
+}
+
+// getRangeAnalytics - Income, expenses and profit per month or week over
+// ?from=&to= (default this year to date), each bucket next to the same
+// bucket a year earlier. Totals are summed in SQL, in the default currency.
+func getRangeAnalytics(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "month")
+	if groupBy != "month" && groupBy != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "group_by must be 'month' or 'week'"})
+		return
+	}
+
+	today := localToday()
+	from := time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	to := today
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := c.Query(param); v != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD"})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxAnalyticsRangeDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from must not be after to, and the range may span at most 3 years"})
+		return
+	}
+
+	if rejectMissingExchangeRates(c, "t.date BETWEEN $1::date - INTERVAL '1 year' AND $2::date", from, to) {
+		return
+	}
+
+	// Last year's transactions are shifted forward a year so they land in the
+	// bucket they compare with
+	rows, err := db.Query(`
+		WITH buckets AS (
+			SELECT b::date AS bucket
+			FROM generate_series(date_trunc($3, $1::date), $2::date, ('1 ' || $3)::interval) b
+		),
+		this_year AS (
+			SELECT date_trunc($3, date)::date AS bucket,
+			       SUM(`+inDefaultCurrencySQL("t")+`) FILTER (WHERE type = 'income') AS income,
+			       SUM(`+inDefaultCurrencySQL("t")+`) FILTER (WHERE type = 'expense') AS expense
+			FROM mentor.transactions t
+			WHERE voided_at IS NULL AND date BETWEEN $1 AND $2
+			GROUP BY 1
+		),
+		last_year AS (
+			SELECT date_trunc($3, date + INTERVAL '1 year')::date AS bucket,
+			       SUM(`+inDefaultCurrencySQL("t")+`) FILTER (WHERE type = 'income') AS income,
+			       SUM(`+inDefaultCurrencySQL("t")+`) FILTER (WHERE type = 'expense') AS expense
+			FROM mentor.transactions t
+			WHERE voided_at IS NULL AND date BETWEEN $1::date - INTERVAL '1 year' AND $2::date - INTERVAL '1 year'
+			GROUP BY 1
+		)
+		SELECT b.bucket, COALESCE(cur.income, 0), COALESCE(cur.expense, 0),
+		       COALESCE(prev.income, 0), COALESCE(prev.expense, 0)
+		FROM buckets b
+		LEFT JOIN this_year cur ON cur.bucket = b.bucket
+		LEFT JOIN last_year prev ON prev.bucket = b.bucket
+		ORDER BY b.bucket
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"), groupBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	buckets := []gin.H{}
+	var income, expense, prevIncome, prevExpense float64
+	for rows.Next() {
+		var bucket time.Time
+		var in, out, prevIn, prevOut float64
+		if err := rows.Scan(&bucket, &in, &out, &prevIn, &prevOut); err != nil {
+			continue
+		}
+
+		// The first and last buckets are cut to the requested range
+		start, end := bucket, bucket.AddDate(0, 1, -1)
+		if groupBy == "week" {
+			end = bucket.AddDate(0, 0, 6)
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		buckets = append(buckets, gin.H{
+			"period_start": start.Format("2006-01-02"),
+			"period_end":   end.Format("2006-01-02"),
+			"income":       in,
+			"expense":      out,
+			"profit":       roundMoney(in - out),
+			"previous_year": gin.H{
+				"income":  prevIn,
+				"expense": prevOut,
+				"profit":  roundMoney(prevIn - prevOut),
+			},
+			"income_change_percent": percentChange(in, prevIn),
+			"profit_change_percent": percentChange(in-out, prevIn-prevOut),
+		})
+		income += in
+		expense += out
+		prevIncome += prevIn
+		prevExpense += prevOut
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"group_by": groupBy,
+		"currency": currencyCode(),
+		"buckets":  buckets,
+		"totals": gin.H{
+			"income":  roundMoney(income),
+			"expense": roundMoney(expense),
+			"profit":  roundMoney(income - expense),
+		},
+		"previous_year": gin.H{
+			"income":  roundMoney(prevIncome),
+			"expense": roundMoney(prevExpense),
+			"profit":  roundMoney(prevIncome - prevExpense),
+		},
+		"income_change_percent": percentChange(income, prevIncome),
+		"profit_change_percent": percentChange(income-expense, prevIncome-prevExpense),
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TEACHER ANALYTICS (Fee income vs salary per teacher)
+// ============================================
+
+// getTeacherAnalytics - Fee income and salary cost per teacher for
+// ?year=&month= (default last month). A subscription's payments that month
+// are split between the teachers who taught it in proportion to classes
+// logged, or go to its main teacher if no class was logged; a billing group
+// payment is first split across the group by subscription amount. Salary is
+// counted for the month it pays for. Amounts are in the default currency.
+func getTeacherAnalytics(c *gin.Context) {
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	type teacherMargin struct {
+		name                      string
+		classes, students         int
+		income, salary, otherCost float64
+	}
+	teachers := map[string]*teacherMargin{}
+	get := func(id string) *teacherMargin {
+		if teachers[id] == nil {
+			teachers[id] = &teacherMargin{}
+		}
+		return teachers[id]
+	}
+
+	if rejectMissingExchangeRates(c, "(t.date >= $1 AND t.date < $2) OR t.payroll_month = $1", monthStart, monthEnd) {
+		return
+	}
+
+	rows, err := db.Query(`
+		WITH income AS (
+			SELECT t.subscription_id, `+inDefaultCurrencySQL("t")+` AS amount
+			FROM mentor.transactions t
+			WHERE t.type = 'income' AND t.voided_at IS NULL AND t.date >= $1 AND t.date < $2
+			  AND t.subscription_id IS NOT NULL
+			UNION ALL
+			SELECT s.id, `+inDefaultCurrencySQL("t")+` * s.amount / NULLIF(SUM(s.amount) OVER (PARTITION BY t.id), 0) AS amount
+			FROM mentor.transactions t
+			JOIN mentor.subscriptions s ON s.billing_group_id = t.billing_group_id
+			     AND s.status = 'active' AND s.deleted_at IS NULL
+			WHERE t.type = 'income' AND t.voided_at IS NULL AND t.date >= $1 AND t.date < $2
+			  AND t.subscription_id IS NULL AND t.billing_group_id IS NOT NULL
+		),
+		sub_income AS (
+			SELECT subscription_id, SUM(amount) AS income FROM income GROUP BY subscription_id
+		),
+		classes AS (
+			SELECT subscription_id, teacher_id, COUNT(*) AS n
+			FROM mentor.progress
+			WHERE completed_at >= $1 AND completed_at < $2
+			GROUP BY subscription_id, teacher_id
+		)
+		SELECT COALESCE(cl.teacher_id, s.teacher_id),
+		       si.income * COALESCE(cl.n::numeric / SUM(cl.n) OVER (PARTITION BY si.subscription_id), 1)
+		FROM sub_income si
+		JOIN mentor.subscriptions s ON s.id = si.subscription_id
+		LEFT JOIN classes cl ON cl.subscription_id = si.subscription_id
+	`, monthStart, monthEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	unattributed := 0.0
+	for rows.Next() {
+		var teacherID sql.NullString
+		var income sql.NullFloat64
+		if err := rows.Scan(&teacherID, &income); err != nil {
+			continue
+		}
+		if !teacherID.Valid || teacherID.String == "" {
+			unattributed += income.Float64
+			continue
+		}
+		get(teacherID.String).income += income.Float64
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT teacher_id, COUNT(*), COUNT(DISTINCT subscription_id)
+		FROM mentor.progress
+		WHERE completed_at >= $1 AND completed_at < $2 AND teacher_id IS NOT NULL
+		GROUP BY teacher_id
+	`, monthStart, monthEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var teacherID string
+		var classes, students int
+		if err := rows.Scan(&teacherID, &classes, &students); err != nil {
+			continue
+		}
+		t := get(teacherID)
+		t.classes, t.students = classes, students
+	}
+	rows.Close()
+
+	// Salaries are booked against the month they pay for; advances and other
+	// teacher expenses against the month they were paid
+	rows, err = db.Query(`
+		SELECT t.teacher_id,
+		       COALESCE(SUM(`+inDefaultCurrencySQL("t")+`) FILTER (WHERE t.category = 'teacher_salary'), 0),
+		       COALESCE(SUM(`+inDefaultCurrencySQL("t")+`) FILTER (WHERE t.category IS DISTINCT FROM 'teacher_salary'), 0)
+		FROM mentor.transactions t
+		WHERE t.type = 'expense' AND t.voided_at IS NULL AND t.teacher_id IS NOT NULL
+		  AND (t.payroll_month = $1 OR (t.payroll_month IS NULL AND t.date >= $1 AND t.date < $2))
+		GROUP BY t.teacher_id
+	`, monthStart, monthEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var teacherID string
+		var salary, other float64
+		if err := rows.Scan(&teacherID, &salary, &other); err != nil {
+			continue
+		}
+		t := get(teacherID)
+		t.salary, t.otherCost = salary, other
+	}
+	rows.Close()
+
+	rows, err = db.Query("SELECT id, name FROM mentor.teachers")
+	if err == nil {
+		for rows.Next() {
+			var id, name string
+			if rows.Scan(&id, &name) == nil && teachers[id] != nil {
+				teachers[id].name = name
+			}
+		}
+		rows.Close()
+	}
+
+	result := []gin.H{}
+	var totalIncome, totalCost float64
+	for id, t := range teachers {
+		cost := t.salary + t.otherCost
+		entry := gin.H{
+			"teacher_id":     id,
+			"teacher_name":   t.name,
+			"classes":        t.classes,
+			"students":       t.students,
+			"income":         roundMoney(t.income),
+			"salary":         roundMoney(t.salary),
+			"other_costs":    roundMoney(t.otherCost),
+			"margin":         roundMoney(t.income - cost),
+			"margin_percent": nil,
+		}
+		if t.income > 0 {
+			entry["margin_percent"] = roundMoney((t.income - cost) / t.income * 100)
+		}
+		result = append(result, entry)
+		totalIncome += t.income
+		totalCost += cost
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i]["margin"].(float64) > result[j]["margin"].(float64)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"year":     monthStart.Year(),
+		"month":    int(monthStart.Month()),
+		"currency": currencyCode(),
+		"teachers": result,
+		"totals": gin.H{
+			"income":              roundMoney(totalIncome),
+			"teacher_costs":       roundMoney(totalCost),
+			"margin":              roundMoney(totalIncome - totalCost),
+			"unattributed_income": roundMoney(unattributed),
+		},
+	})
+}
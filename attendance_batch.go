@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ATTENDANCE BATCH (Offline check-ins synced later)
+// ============================================
+
+const (
+	attendanceBatchMaxEvents = 200 // events accepted per request
+	attendanceBatchMaxDays   = 14  // older offline events are rejected
+	attendanceClockSkew      = 5 * time.Minute
+)
+
+var clientEventIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// attendanceEvent is one check-in or check-out the app recorded offline
+type attendanceEvent struct {
+	ClientID        string  `json:"client_id"` // UUID generated on the device
+	SubscriptionID  int     `json:"subscription_id"`
+	Action          string  `json:"action"`      // "start" or "end"
+	RecordedAt      string  `json:"recorded_at"` // device time, ISO-8601
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+	Notes           string  `json:"notes"`
+	Mode            string  `json:"mode"`
+	OnlineSessionID *int    `json:"online_session_id"`
+	ClassSessionID  *int    `json:"class_session_id"` // defaults to the teacher's session that day
+}
+
+// validate checks the event and returns its parsed time
+func (ev *attendanceEvent) validate(now time.Time) (time.Time, error) {
+	if !clientEventIDPattern.MatchString(ev.ClientID) {
+		return time.Time{}, fmt.Errorf("client_id must be a UUID")
+	}
+	if ev.SubscriptionID == 0 {
+		return time.Time{}, fmt.Errorf("subscription_id is required")
+	}
+	if ev.Action != "start" && ev.Action != "end" {
+		return time.Time{}, fmt.Errorf("action must be 'start' or 'end'")
+	}
+	if ev.Mode == "" {
+		ev.Mode = "offline"
+	}
+	if ev.Mode != "offline" && ev.Mode != "online" {
+		return time.Time{}, fmt.Errorf("mode must be 'offline' or 'online'")
+	}
+	at, err := parseTimestamp(ev.RecordedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("recorded_at must be an ISO-8601 timestamp")
+	}
+	if at.After(now.Add(attendanceClockSkew)) {
+		return time.Time{}, fmt.Errorf("recorded_at is in the future")
+	}
+	if at.Before(now.AddDate(0, 0, -attendanceBatchMaxDays)) {
+		return time.Time{}, fmt.Errorf("recorded_at is more than %d days old; submit an attendance correction instead", attendanceBatchMaxDays)
+	}
+	return at, nil
+}
+
+// recordAttendanceBatch - Replay check-ins/outs recorded offline. Each event
+// is stored at its device time and keyed by its client_id, so sending the
+// same batch again reports duplicates rather than adding records. Teachers
+// sync their own events; admins name the teacher.
+func recordAttendanceBatch(c *gin.Context) {
+	var input struct {
+		TeacherID string            `json:"teacher_id"`
+		Events    []attendanceEvent `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if caller := c.GetString("teacher_id"); caller != "" {
+		input.TeacherID = caller
+	}
+	if input.TeacherID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "teacher_id is required"})
+		return
+	}
+	if len(input.Events) == 0 || len(input.Events) > attendanceBatchMaxEvents {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("events must have 1 to %d items", attendanceBatchMaxEvents)})
+		return
+	}
+
+	now := time.Now()
+	results := []gin.H{}
+	counts := gin.H{"accepted": 0, "duplicate": 0, "rejected": 0}
+	for _, ev := range input.Events {
+		ev.ClientID = strings.ToLower(ev.ClientID)
+		result := gin.H{"client_id": ev.ClientID}
+		results = append(results, result)
+
+		id, status, err := storeAttendanceEvent(input.TeacherID, &ev, now)
+		result["status"] = status
+		if id != 0 {
+			result["id"] = id
+		}
+		if err != nil {
+			result["error"] = err.Error()
+		}
+		counts[status] = counts[status].(int) + 1
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "results": results, "counts": counts})
+}
+
+// storeAttendanceEvent saves one batch event and reports accepted, duplicate
+// or rejected. Events are stored one at a time, so the same client_id twice in
+// one batch is a duplicate too.
+func storeAttendanceEvent(teacherID string, ev *attendanceEvent, now time.Time) (int, string, error) {
+	at, err := ev.validate(now)
+	if err != nil {
+		return 0, "rejected", err
+	}
+
+	var existing int
+	if db.QueryRow("SELECT id FROM mentor.attendance WHERE client_event_id = $1", ev.ClientID).Scan(&existing) == nil {
+		return existing, "duplicate", nil
+	}
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL)", ev.SubscriptionID).Scan(&exists)
+	if !exists {
+		return 0, "rejected", fmt.Errorf("subscription %d not found", ev.SubscriptionID)
+	}
+	if !teachesSubscription(teacherID, ev.SubscriptionID) {
+		return 0, "rejected", fmt.Errorf("subscription %d is not taught by this teacher", ev.SubscriptionID)
+	}
+
+	classSessionID := sql.NullInt64{}
+	if ev.ClassSessionID != nil {
+		// A named session must be this teacher's class with the student that day
+		var ours bool
+		db.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM mentor.class_sessions
+			              WHERE id = $1 AND subscription_id = $2 AND teacher_id = $3 AND session_date = $4::date)
+		`, *ev.ClassSessionID, ev.SubscriptionID, teacherID, at.Format("2006-01-02")).Scan(&ours)
+		if !ours {
+			return 0, "rejected", fmt.Errorf("class_session_id %d is not this teacher's session with the student on %s",
+				*ev.ClassSessionID, at.Format("2006-01-02"))
+		}
+		classSessionID = sql.NullInt64{Int64: int64(*ev.ClassSessionID), Valid: true}
+	} else {
+		classSessionID = classSessionOn(ev.SubscriptionID, teacherID, at.Format("2006-01-02"))
+	}
+
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO mentor.attendance (teacher_id, subscription_id, latitude, longitude, action, notes, mode, online_session_id,
+		                               class_session_id, recorded_at, client_event_id, synced_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		ON CONFLICT (client_event_id) WHERE client_event_id IS NOT NULL DO NOTHING
+		RETURNING id
+	`, teacherID, ev.SubscriptionID, ev.Latitude, ev.Longitude, ev.Action, ev.Notes, ev.Mode, ev.OnlineSessionID,
+		classSessionID, at, ev.ClientID).Scan(&id)
+	if err == sql.ErrNoRows {
+		db.QueryRow("SELECT id FROM mentor.attendance WHERE client_event_id = $1", ev.ClientID).Scan(&id)
+		return id, "duplicate", nil
+	}
+	if err != nil {
+		return 0, "rejected", err
+	}
+
+	if classSessionID.Valid && ev.Action == "start" {
+		db.Exec(clearMissedSessionSQL, classSessionID)
+	}
+	return id, "accepted", nil
+}
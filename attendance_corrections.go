@@ -0,0 +1,363 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ATTENDANCE CORRECTIONS (Manual check-ins, admin approved)
+// ============================================
+
+// attendanceCorrectionMaxDays is how far back a teacher can correct attendance
+const attendanceCorrectionMaxDays = 14
+
+// attendanceCorrectionSelect lists the columns scanAttendanceCorrection reads
+const attendanceCorrectionSelect = `
+	SELECT r.id, r.teacher_id, r.subscription_id, s.student_name, r.class_session_id, r.action, r.occurred_at,
+	       r.reason, r.status, COALESCE(r.decided_by, ''), COALESCE(r.decision_note, ''), r.decided_at,
+	       r.attendance_id, r.created_at
+	FROM mentor.attendance_corrections r
+	JOIN mentor.subscriptions s ON s.id = r.subscription_id
+`
+
+func scanAttendanceCorrection(rows *sql.Rows) (gin.H, error) {
+	var id, subId int
+	var teacherID, studentName, action, reason, status, decidedBy, note string
+	var sessionID, attendanceID sql.NullInt64
+	var occurredAt, createdAt time.Time
+	var decidedAt sql.NullTime
+	if err := rows.Scan(&id, &teacherID, &subId, &studentName, &sessionID, &action, &occurredAt,
+		&reason, &status, &decidedBy, &note, &decidedAt, &attendanceID, &createdAt); err != nil {
+		return nil, err
+	}
+	correction := gin.H{
+		"id":              id,
+		"teacher_id":      teacherID,
+		"subscription_id": subId,
+		"student_name":    studentName,
+		"action":          action,
+		"occurred_at":     isoTimestamp(occurredAt),
+		"reason":          reason,
+		"status":          status,
+		"created_at":      isoTimestamp(createdAt),
+	}
+	if sessionID.Valid {
+		correction["class_session_id"] = sessionID.Int64
+	}
+	if decidedAt.Valid {
+		correction["decided_by"] = decidedBy
+		correction["decision_note"] = note
+		correction["decided_at"] = isoTimestamp(decidedAt.Time)
+	}
+	if attendanceID.Valid {
+		correction["attendance_id"] = attendanceID.Int64
+	}
+	return correction, nil
+}
+
+// queryAttendanceCorrections runs attendanceCorrectionSelect with a WHERE
+// clause, newest first
+func queryAttendanceCorrections(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(attendanceCorrectionSelect+" WHERE "+where+" ORDER BY r.created_at DESC, r.id DESC LIMIT 200", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	corrections := []gin.H{}
+	for rows.Next() {
+		if correction, err := scanAttendanceCorrection(rows); err == nil {
+			corrections = append(corrections, correction)
+		}
+	}
+	return corrections, nil
+}
+
+// createAttendanceCorrection - A teacher submits a check-in or check-out they
+// forgot to record, with the time it happened and why
+func createAttendanceCorrection(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+
+	var input struct {
+		SubscriptionID int    `json:"subscription_id"`
+		ClassSessionID *int   `json:"class_session_id"`
+		Action         string `json:"action"`      // "start" or "end"
+		OccurredAt     string `json:"occurred_at"` // ISO-8601
+		Reason         string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	fields := map[string]string{}
+	if input.SubscriptionID == 0 {
+		fields["subscription_id"] = "is required"
+	}
+	if input.Action != "start" && input.Action != "end" {
+		fields["action"] = "must be 'start' or 'end'"
+	}
+	occurredAt, err := parseTimestamp(input.OccurredAt)
+	switch {
+	case err != nil:
+		fields["occurred_at"] = "must be an ISO-8601 timestamp"
+	case occurredAt.After(time.Now()):
+		fields["occurred_at"] = "cannot be in the future"
+	case occurredAt.Before(time.Now().AddDate(0, 0, -attendanceCorrectionMaxDays)):
+		fields["occurred_at"] = fmt.Sprintf("must be within the last %d days", attendanceCorrectionMaxDays)
+	}
+	if input.Reason == "" {
+		fields["reason"] = "is required"
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	var taught bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.subscriptions s
+		              WHERE s.id = $2 AND s.deleted_at IS NULL AND `+teacherAssignedSQL+`)
+	`, teacherID, input.SubscriptionID).Scan(&taught)
+	if !taught {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	// The class day is the teacher's, like session linking at check-in
+	loc := teacherLocation(teacherID)
+	local := occurredAt.In(loc)
+	date := local.Format("2006-01-02")
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	classSessionID := sql.NullInt64{}
+	if input.ClassSessionID != nil {
+		// A named session must be this teacher's class with the student that day
+		var ours bool
+		db.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM mentor.class_sessions
+			              WHERE id = $1 AND subscription_id = $2 AND teacher_id = $3 AND session_date = $4::date)
+		`, *input.ClassSessionID, input.SubscriptionID, teacherID, date).Scan(&ours)
+		if !ours {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+				"class_session_id": "must be your session with this student on " + date,
+			}))
+			return
+		}
+		classSessionID = sql.NullInt64{Int64: int64(*input.ClassSessionID), Valid: true}
+	} else {
+		classSessionID = classSessionOn(input.SubscriptionID, teacherID, date)
+	}
+
+	var recorded bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.attendance
+		              WHERE teacher_id = $1 AND subscription_id = $2 AND action = $3
+		                AND recorded_at >= $4 AND recorded_at < $5)
+	`, teacherID, input.SubscriptionID, input.Action, dayStart, dayStart.AddDate(0, 0, 1)).Scan(&recorded)
+	if recorded {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "A " + input.Action + " is already recorded for this student on " + date})
+		return
+	}
+
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO mentor.attendance_corrections (teacher_id, subscription_id, class_session_id, action, occurred_at, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (teacher_id, subscription_id, action, (occurred_at::date)) WHERE status = 'pending' DO NOTHING
+		RETURNING id
+	`, teacherID, input.SubscriptionID, classSessionID, input.Action, occurredAt, input.Reason).Scan(&id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "A correction for this " + input.Action + " is already pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", input.SubscriptionID, "attendance_correction_requested", teacherID, gin.H{
+		"correction_id": id,
+		"action":        input.Action,
+		"occurred_at":   isoTimestamp(occurredAt),
+		"reason":        input.Reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "status": "pending", "message": "Correction submitted for approval"})
+}
+
+// getMyAttendanceCorrections - The signed-in teacher's corrections
+func getMyAttendanceCorrections(c *gin.Context) {
+	corrections, err := queryAttendanceCorrections("r.teacher_id = $1", c.GetString("teacher_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "corrections": corrections})
+}
+
+// withdrawAttendanceCorrection - The teacher takes back a pending correction
+func withdrawAttendanceCorrection(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+	id := c.Param("id")
+
+	var subId int
+	err := db.QueryRow(`
+		UPDATE mentor.attendance_corrections
+		SET status = 'withdrawn', decided_by = $1, decided_at = NOW()
+		WHERE id = $2 AND teacher_id = $1 AND status = 'pending'
+		RETURNING subscription_id
+	`, teacherID, id).Scan(&subId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Pending correction not found"})
+		return
+	}
+
+	logAudit("subscription", subId, "attendance_correction_withdrawn", teacherID, gin.H{"correction_id": id})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Correction withdrawn"})
+}
+
+// getAttendanceCorrections - Admin approval queue, pending first by default
+func getAttendanceCorrections(c *gin.Context) {
+	where := "1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	status := c.DefaultQuery("status", "pending")
+	if status != "all" {
+		argCount++
+		where += fmt.Sprintf(" AND r.status = $%d", argCount)
+		args = append(args, status)
+	}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+
+	corrections, err := queryAttendanceCorrections(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "corrections": corrections})
+}
+
+// decideAttendanceCorrection approves or rejects a pending correction.
+// Approving records the check-in/out at the reported time, flagged
+// is_corrected and without GPS, and clears a missed mark on its session.
+func decideAttendanceCorrection(id string, approve bool, decidedBy, note string) (gin.H, int) {
+	var subId int
+	var teacherID, action, reason string
+	var sessionID sql.NullInt64
+	var occurredAt time.Time
+	err := db.QueryRow(`
+		SELECT subscription_id, teacher_id, class_session_id, action, occurred_at, reason
+		FROM mentor.attendance_corrections
+		WHERE id = $1 AND status = 'pending'
+	`, id).Scan(&subId, &teacherID, &sessionID, &action, &occurredAt, &reason)
+	if err != nil {
+		return gin.H{"success": false, "error": "Pending correction not found"}, http.StatusNotFound
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+	defer tx.Rollback()
+
+	status, auditAction := "rejected", "attendance_correction_rejected"
+	attendanceID := sql.NullInt64{}
+	if approve {
+		status, auditAction = "approved", "attendance_corrected"
+		err := tx.QueryRow(`
+			INSERT INTO mentor.attendance (teacher_id, subscription_id, latitude, longitude, action, notes, mode,
+			                               class_session_id, recorded_at, is_corrected, correction_id)
+			VALUES ($1, $2, 0, 0, $3, $4, 'offline', $5, $6, TRUE, $7)
+			RETURNING id
+		`, teacherID, subId, action, "Correction: "+reason, sessionID, occurredAt, id).Scan(&attendanceID)
+		if err != nil {
+			return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+		}
+
+		if sessionID.Valid && action == "start" {
+			if _, err := tx.Exec(clearMissedSessionSQL, sessionID); err != nil {
+				return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+			}
+		}
+	}
+
+	result, err := tx.Exec(`
+		UPDATE mentor.attendance_corrections
+		SET status = $1, decided_by = $2, decision_note = NULLIF($3, ''), decided_at = NOW(), attendance_id = $4
+		WHERE id = $5 AND status = 'pending'
+	`, status, decidedBy, note, attendanceID, id)
+	if err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return gin.H{"success": false, "error": "Pending correction not found"}, http.StatusNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+
+	logAudit("subscription", subId, auditAction, decidedBy, gin.H{
+		"correction_id": id,
+		"teacher_id":    teacherID,
+		"action":        action,
+		"occurred_at":   isoTimestamp(occurredAt),
+		"reason":        note,
+	})
+
+	response := gin.H{"success": true, "status": status, "message": "Correction " + status}
+	if attendanceID.Valid {
+		response["attendance_id"] = attendanceID.Int64
+	}
+	return response, http.StatusOK
+}
+
+// approveAttendanceCorrection / rejectAttendanceCorrection - Admin decisions
+func approveAttendanceCorrection(c *gin.Context) {
+	respondAttendanceCorrectionDecision(c, true)
+}
+
+func rejectAttendanceCorrection(c *gin.Context) {
+	respondAttendanceCorrectionDecision(c, false)
+}
+
+// respondAttendanceCorrectionDecision reads {decided_by, note} and decides the
+// correction
+func respondAttendanceCorrectionDecision(c *gin.Context, approve bool) {
+	var input struct {
+		DecidedBy string `json:"decided_by"`
+		Note      string `json:"note"`
+	}
+	c.ShouldBindJSON(&input)
+	if input.DecidedBy == "" {
+		input.DecidedBy = "admin"
+	}
+
+	if _, err := strconv.Atoi(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid correction id"})
+		return
+	}
+
+	resp, status := decideAttendanceCorrection(c.Param("id"), approve, input.DecidedBy, strings.TrimSpace(input.Note))
+	c.JSON(status, resp)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ATTENDANCE EXPORT (CSV / Excel)
+// ============================================
+
+// attendanceExportColumns is the header row of the export
+var attendanceExportColumns = []string{
+	"date", "teacher_id", "teacher_name", "subscription_id", "student_name", "mode", "status",
+	"checked_in_at", "checked_out_at", "duration_minutes",
+	"check_in_latitude", "check_in_longitude", "check_out_latitude", "check_out_longitude",
+	"distance_from_home_m", "corrected",
+}
+
+// getAttendanceExport - Visits over a date range (default the last 30 days) as
+// a CSV or Excel file for accountants and guardians. The student's home is the
+// average of all GPS check-ins for the subscription; distance_from_home_m is
+// how far the check-in was from it.
+func getAttendanceExport(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parsePastDateRange(c, 30)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	query := `
+		WITH home AS (
+			SELECT subscription_id, AVG(latitude) AS latitude, AVG(longitude) AS longitude
+			FROM mentor.attendance
+			WHERE action = 'start' AND COALESCE(mode, 'offline') <> 'online'
+			  AND latitude <> 0 AND longitude <> 0 AND NOT is_corrected
+			GROUP BY subscription_id
+		)
+		SELECT v.visit_date, v.teacher_id, COALESCE(t.name, ''), v.subscription_id, COALESCE(s.student_name, ''),
+		       COALESCE(v.mode, 'offline'), v.started_at, v.ended_at, v.duration_minutes, v.missing_check_out, v.missing_check_in,
+		       ai.latitude, ai.longitude, ao.latitude, ao.longitude, h.latitude, h.longitude,
+		       COALESCE(ai.is_corrected, FALSE) OR COALESCE(ao.is_corrected, FALSE)
+		FROM mentor.visits v
+		LEFT JOIN mentor.teachers t ON t.id = v.teacher_id
+		LEFT JOIN mentor.subscriptions s ON s.id = v.subscription_id
+		LEFT JOIN mentor.attendance ai ON ai.id = v.start_attendance_id
+		LEFT JOIN mentor.attendance ao ON ao.id = v.end_attendance_id
+		LEFT JOIN home h ON h.subscription_id = v.subscription_id
+		WHERE v.visit_date BETWEEN $1 AND $2
+	`
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02")}
+	argCount := 2
+
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND v.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		query += fmt.Sprintf(" AND v.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+
+	query += " ORDER BY v.visit_date, v.teacher_id, COALESCE(v.started_at, v.ended_at)"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("attendance_%s_%s.%s", from.Format("2006-01-02"), to.Format("2006-01-02"), format)
+	writeRow, finish, ok := startExport(c, format, filename, "Attendance", attendanceExportColumns)
+	if !ok {
+		return
+	}
+
+	// nullable renders a missing value as an empty cell
+	nullable := func(v sql.NullFloat64) interface{} {
+		if !v.Valid {
+			return nil
+		}
+		return v.Float64
+	}
+
+	for rows.Next() {
+		var date time.Time
+		var teacherID, teacherName, studentName, mode string
+		var subId, minutes sql.NullInt64
+		var startedAt, endedAt sql.NullTime
+		var missingOut, missingIn, corrected bool
+		var inLat, inLng, outLat, outLng, homeLat, homeLng sql.NullFloat64
+		if err := rows.Scan(&date, &teacherID, &teacherName, &subId, &studentName, &mode, &startedAt, &endedAt,
+			&minutes, &missingOut, &missingIn, &inLat, &inLng, &outLat, &outLng, &homeLat, &homeLng, &corrected); err != nil {
+			continue
+		}
+
+		var checkedIn, checkedOut, duration, distance interface{}
+		if startedAt.Valid {
+			checkedIn = isoTimestamp(startedAt.Time)
+		}
+		if endedAt.Valid {
+			checkedOut = isoTimestamp(endedAt.Time)
+		}
+		if minutes.Valid {
+			duration = minutes.Int64
+		}
+		located := inLat.Valid && inLng.Valid && inLat.Float64 != 0 && inLng.Float64 != 0
+		if located && mode != "online" && homeLat.Valid && homeLng.Valid {
+			distance = math.Round(distanceKm(homeLat.Float64, homeLng.Float64, inLat.Float64, inLng.Float64) * 1000)
+		}
+
+		writeRow([]interface{}{
+			date.Format("2006-01-02"), teacherID, teacherName, subId.Int64, studentName, mode,
+			visitStatus(missingOut, missingIn, endedAt.Valid),
+			checkedIn, checkedOut, duration,
+			nullable(inLat), nullable(inLng), nullable(outLat), nullable(outLng),
+			distance, corrected,
+		})
+	}
+
+	if err := finish(); err != nil {
+		log.Println("Warning: attendance export:", err)
+	}
+}
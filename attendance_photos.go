@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ATTENDANCE PHOTOS (Proof photo at check-in)
+// ============================================
+
+const (
+	attendancePhotoMaxBytes = 8 << 20
+	attendanceThumbEdge     = 320
+	attendancePhotoLinkTTL  = time.Hour
+	// attendancePhotoMaxPixels caps the decoded size, since a small file can
+	// declare huge dimensions
+	attendancePhotoMaxPixels = 40_000_000
+)
+
+var attendancePhotoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// attendancePhoto is a decoded photo upload, ready to store
+type attendancePhoto struct {
+	data        []byte
+	contentType string
+	ext         string
+	thumb       []byte
+}
+
+// decodeAttendancePhoto reads a base64 (or data URL) JPEG/PNG and builds its
+// thumbnail; the error is meant for the client
+func decodeAttendancePhoto(encoded string) (*attendancePhoto, error) {
+	if i := strings.Index(encoded, "base64,"); i >= 0 {
+		encoded = encoded[i+len("base64,"):]
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("photo must be base64 encoded")
+	}
+	if len(data) > attendancePhotoMaxBytes {
+		return nil, fmt.Errorf("photo is larger than 8 MB")
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := attendancePhotoExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("photo must be a JPEG or PNG")
+	}
+	thumb, err := photoThumbnail(data)
+	if err == errPhotoTooLarge {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("photo could not be read")
+	}
+	return &attendancePhoto{data: data, contentType: contentType, ext: ext, thumb: thumb}, nil
+}
+
+// store uploads the photo and thumbnail, returning their keys
+func (p *attendancePhoto) store(teacherID string) (string, string, error) {
+	store := getObjectStore()
+	if store == nil {
+		return "", "", fmt.Errorf("object storage is not configured")
+	}
+	base := fmt.Sprintf("attendance/%s/%s/%s", teacherID, time.Now().Format("2006-01"), randomID())
+	key, thumbKey := base+p.ext, base+"_thumb.jpg"
+	if err := store.Put(key, p.contentType, p.data); err != nil {
+		return "", "", err
+	}
+	if err := store.Put(thumbKey, "image/jpeg", p.thumb); err != nil {
+		store.Delete(key)
+		return "", "", err
+	}
+	return key, thumbKey, nil
+}
+
+// addPhotoLinks adds short-lived photo_url / thumbnail_url to an attendance record
+func addPhotoLinks(record gin.H, store *objectStore, key, thumbKey string) {
+	if store == nil || key == "" {
+		return
+	}
+	record["photo_url"] = store.PresignGet(key, attendancePhotoLinkTTL)
+	if thumbKey != "" {
+		record["thumbnail_url"] = store.PresignGet(thumbKey, attendancePhotoLinkTTL)
+	}
+}
+
+var errPhotoTooLarge = errors.New("photo is larger than 40 megapixels")
+
+// photoThumbnail scales a photo down to attendanceThumbEdge on its longest
+// side, upright per EXIF, as a JPEG
+func photoThumbnail(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > attendancePhotoMaxPixels {
+		return nil, errPhotoTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	thumb := orientRGBA(downscaleRGBA(rgba, attendanceThumbEdge), jpegOrientation(data))
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// downscaleRGBA is downscaleGray for colour images
+func downscaleRGBA(img *image.RGBA, maxEdge int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := float64(maxEdge) / float64(max(w, h))
+	if scale >= 1 {
+		return img
+	}
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for ch := 0; ch < 4; ch++ {
+						sum[ch] += int(row[sx*4+ch])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			for ch := 0; ch < 4; ch++ {
+				dst.Pix[y*dst.Stride+x*4+ch] = uint8(sum[ch] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// orientRGBA is applyOrientation for colour images
+func orientRGBA(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], img.Pix[sy*img.Stride+sx*4:])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ATTENDANCE SUMMARY (Payroll and guardian reporting)
+// ============================================
+
+// lateStartGraceMinutes is how long after the class time a check-in still
+// counts as on time
+const lateStartGraceMinutes = 10
+
+// attendanceTally accumulates one teacher's or subscription's visits
+type attendanceTally struct {
+	classesAttended int
+	teachingMinutes int
+	lateStarts      int
+	missedCheckouts int
+}
+
+func (t *attendanceTally) add(attended bool, minutes int, late, missedCheckout bool) {
+	if attended {
+		t.classesAttended++
+	}
+	t.teachingMinutes += minutes
+	if late {
+		t.lateStarts++
+	}
+	if missedCheckout {
+		t.missedCheckouts++
+	}
+}
+
+func (t *attendanceTally) addTo(h gin.H) gin.H {
+	h["classes_attended"] = t.classesAttended
+	h["teaching_minutes"] = t.teachingMinutes
+	h["teaching_hours"] = math.Round(float64(t.teachingMinutes)/60*100) / 100
+	h["late_starts"] = t.lateStarts
+	h["missed_checkouts"] = t.missedCheckouts
+	return h
+}
+
+// getAttendanceSummary - Classes attended, teaching hours, late starts and
+// missed check-outs per teacher and per subscription over a date range
+// (default the last 30 days)
+func getAttendanceSummary(c *gin.Context) {
+	from, to, ok := parsePastDateRange(c, 30)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	query := `
+		SELECT v.teacher_id, COALESCE(t.name, ''), v.subscription_id, COALESCE(s.student_name, ''),
+		       v.started_at, COALESCE(v.duration_minutes, 0), v.missing_check_out,
+		       cs.session_date, COALESCE(cs.session_time, '')
+		FROM mentor.visits v
+		LEFT JOIN mentor.teachers t ON t.id = v.teacher_id
+		LEFT JOIN mentor.subscriptions s ON s.id = v.subscription_id
+		LEFT JOIN mentor.class_sessions cs ON cs.id = v.class_session_id
+		WHERE v.visit_date BETWEEN $1 AND $2
+	`
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02")}
+	argCount := 2
+
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND v.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		query += fmt.Sprintf(" AND v.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type teacherKey struct{ id, name string }
+	type subKey struct {
+		id   int64
+		name string
+	}
+	teachers := map[teacherKey]*attendanceTally{}
+	subs := map[subKey]*attendanceTally{}
+	var total attendanceTally
+	locations := map[string]*time.Location{}
+
+	for rows.Next() {
+		var teacherID, teacherName, studentName, sessionTime string
+		var subId sql.NullInt64
+		var startedAt, sessionDate sql.NullTime
+		var minutes int
+		var missedCheckout bool
+		if err := rows.Scan(&teacherID, &teacherName, &subId, &studentName, &startedAt, &minutes, &missedCheckout,
+			&sessionDate, &sessionTime); err != nil {
+			continue
+		}
+
+		late := false
+		if startedAt.Valid && sessionDate.Valid {
+			loc, ok := locations[teacherID]
+			if !ok {
+				loc = teacherLocation(teacherID)
+				locations[teacherID] = loc
+			}
+			if start, ok := sessionStart(sessionDate.Time, sessionTime, loc); ok {
+				late = storedTime(startedAt.Time).After(start.Add(lateStartGraceMinutes * time.Minute))
+			}
+		}
+
+		tk := teacherKey{teacherID, teacherName}
+		if teachers[tk] == nil {
+			teachers[tk] = &attendanceTally{}
+		}
+		teachers[tk].add(startedAt.Valid, minutes, late, missedCheckout)
+
+		sk := subKey{subId.Int64, studentName}
+		if subs[sk] == nil {
+			subs[sk] = &attendanceTally{}
+		}
+		subs[sk].add(startedAt.Valid, minutes, late, missedCheckout)
+
+		total.add(startedAt.Valid, minutes, late, missedCheckout)
+	}
+
+	teacherList := []gin.H{}
+	for k, t := range teachers {
+		teacherList = append(teacherList, t.addTo(gin.H{"teacher_id": k.id, "teacher_name": k.name}))
+	}
+	sort.Slice(teacherList, func(i, j int) bool {
+		return teacherList[i]["teacher_id"].(string) < teacherList[j]["teacher_id"].(string)
+	})
+
+	subList := []gin.H{}
+	for k, t := range subs {
+		subList = append(subList, t.addTo(gin.H{"subscription_id": k.id, "student_name": k.name}))
+	}
+	sort.Slice(subList, func(i, j int) bool {
+		return subList[i]["subscription_id"].(int64) < subList[j]["subscription_id"].(int64)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"from":               from.Format("2006-01-02"),
+		"to":                 to.Format("2006-01-02"),
+		"late_after_minutes": lateStartGraceMinutes,
+		"teachers":           teacherList,
+		"subscriptions":      subList,
+		"totals":             total.addTo(gin.H{}),
+	})
+}
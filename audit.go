@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// AUDIT LOG
+// ============================================
+
+// logAudit records an action against an entity. Failures are logged but never
+// block the request that triggered them.
+func logAudit(entityType string, entityID interface{}, action, actor string, details gin.H) {
+	if details == nil {
+		details = gin.H{}
+	}
+	detailsJSON, _ := json.Marshal(details)
+
+	_, err := db.Exec(`
+		INSERT INTO mentor.audit_log (entity_type, entity_id, action, actor, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`, entityType, fmt.Sprint(entityID), action, actor, string(detailsJSON))
+	if err != nil {
+		log.Println("Warning: could not write audit log:", err)
+	}
+}
+
+// getAuditLog - List audit entries, optionally filtered by entity
+func getAuditLog(c *gin.Context) {
+	entityType := c.Query("entity_type")
+	entityID := c.Query("entity_id")
+	action := c.Query("action")
+
+	query := `
+		SELECT id, entity_type, entity_id, action, actor, details::text, created_at
+		FROM mentor.audit_log
+		WHERE 1=1
+	`
+	args := []interface{}{}
+	argCount := 0
+
+	if entityType != "" {
+		argCount++
+		query += fmt.Sprintf(" AND entity_type = $%d", argCount)
+		args = append(args, entityType)
+	}
+	if entityID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND entity_id = $%d", argCount)
+		args = append(args, entityID)
+	}
+	if action != "" {
+		argCount++
+		query += fmt.Sprintf(" AND action = $%d", argCount)
+		args = append(args, action)
+	}
+
+	query += " ORDER BY created_at DESC LIMIT 200"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var entries []gin.H
+	for rows.Next() {
+		var id int
+		var entType, entID, entAction, detailsJSON string
+		var actorNull sql.NullString
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &entType, &entID, &entAction, &actorNull, &detailsJSON, &createdAt); err != nil {
+			continue
+		}
+
+		var details map[string]interface{}
+		json.Unmarshal([]byte(detailsJSON), &details)
+
+		entries = append(entries, gin.H{
+			"id":          id,
+			"entity_type": entType,
+			"entity_id":   entID,
+			"action":      entAction,
+			"actor":       actorNull.String,
+			"details":     details,
+			"created_at":  isoTimestamp(createdAt),
+		})
+	}
+
+	if entries == nil {
+		entries = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "entries": entries})
+}
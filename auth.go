@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ADMIN AUTH
+// ============================================
+
+// adminOnly rejects requests without the X-Admin-Token header matching ADMIN_TOKEN,
+// or an admin session from single sign-on as "Authorization: Bearer <token>".
+// If ADMIN_TOKEN is not configured the route is disabled entirely.
+func adminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "error": "ADMIN_TOKEN not configured"})
+			return
+		}
+		if !isAdminRequest(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdminRequest reports whether the request carries admin credentials
+func isAdminRequest(c *gin.Context) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	session := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) == 1 ||
+		verifyAdminSession(session, token)
+}
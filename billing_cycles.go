@@ -0,0 +1,389 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// BILLING CYCLES (Expected vs delivered classes)
+// ============================================
+
+// scheduleDayWeekdays maps schedule_days entries to weekdays. Rows are stored
+// as day names ("Mon"); codes (Sat=1 ... Fri=7) are still accepted as input.
+var scheduleDayWeekdays = map[string]time.Weekday{
+	"Sat": time.Saturday, "Sun": time.Sunday, "Mon": time.Monday, "Tue": time.Tuesday,
+	"Wed": time.Wednesday, "Thu": time.Thursday, "Fri": time.Friday,
+	"1": time.Saturday, "2": time.Sunday, "3": time.Monday, "4": time.Tuesday,
+	"5": time.Wednesday, "6": time.Thursday, "7": time.Friday,
+}
+
+// scheduledWeekdays parses a subscription's schedule_day_list
+func scheduledWeekdays(scheduleDays []string) map[time.Weekday]bool {
+	days := map[time.Weekday]bool{}
+	for _, d := range scheduleDays {
+		d = strings.TrimSpace(d)
+		if len(d) > 3 {
+			d = d[:3]
+		}
+		if wd, ok := scheduleDayWeekdays[d]; ok {
+			days[wd] = true
+		}
+	}
+	return days
+}
+
+// billingCycleFor returns the [start, end) cycle containing day for a
+// subscription billed on billingDay of each month. Short months bill on
+// their last day.
+func billingCycleFor(day time.Time, billingDay int) (time.Time, time.Time) {
+	anchor := func(year int, month time.Month) time.Time {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local).Day()
+		d := billingDay
+		if d < 1 {
+			d = 1
+		}
+		if d > last {
+			d = last
+		}
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	start := anchor(day.Year(), day.Month())
+	if day.Before(start) {
+		prev := time.Date(day.Year(), day.Month()-1, 1, 0, 0, 0, 0, time.Local)
+		start = anchor(prev.Year(), prev.Month())
+	}
+	next := time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.Local)
+	return start, anchor(next.Year(), next.Month())
+}
+
+// refreshBillingCycle recounts expected and delivered classes for the cycle
+// containing day and stores the result
+func refreshBillingCycle(subId int, day time.Time) (gin.H, error) {
+	var teacherID string
+	var scheduleDays []string
+	var billingDay int
+	var startDate, endDate sql.NullTime
+	err := db.QueryRow(`
+		SELECT teacher_id, schedule_day_list, COALESCE(billing_date, 1), start_date, end_date
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, subId).Scan(&teacherID, pq.Array(&scheduleDays), &billingDay, &startDate, &endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	cycleStart, cycleEnd := billingCycleFor(day, billingDay)
+
+	holidays := map[string]bool{}
+	hRows, err := db.Query(`
+		SELECT date FROM mentor.holidays
+		WHERE date >= $1 AND date < $2 AND (teacher_id IS NULL OR teacher_id = $3)
+	`, cycleStart, cycleEnd, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	for hRows.Next() {
+		var d time.Time
+		hRows.Scan(&d)
+		holidays[d.Format("2006-01-02")] = true
+	}
+	hRows.Close()
+
+	blackouts := map[string]bool{}
+	bRows, err := db.Query(`
+		SELECT date FROM mentor.teacher_blackouts
+		WHERE teacher_id = $1 AND date >= $2 AND date < $3
+	`, teacherID, cycleStart, cycleEnd)
+	if err != nil {
+		return nil, err
+	}
+	for bRows.Next() {
+		var d time.Time
+		bRows.Scan(&d)
+		blackouts[d.Format("2006-01-02")] = true
+	}
+	bRows.Close()
+
+	// Walk the cycle, counting scheduled days that fall inside the subscription's active period
+	weekdays := scheduledWeekdays(scheduleDays)
+	scheduled, holidayDays, blackoutDays := 0, 0, 0
+	for d := cycleStart; d.Before(cycleEnd); d = d.AddDate(0, 0, 1) {
+		if !weekdays[d.Weekday()] {
+			continue
+		}
+		if startDate.Valid && d.Before(startDate.Time) {
+			continue
+		}
+		if endDate.Valid && d.After(endDate.Time) {
+			continue
+		}
+		scheduled++
+		key := d.Format("2006-01-02")
+		switch {
+		case holidays[key]:
+			holidayDays++
+		case blackouts[key]:
+			blackoutDays++
+		}
+	}
+	expected := scheduled - holidayDays - blackoutDays
+
+	// A visit covering several subjects still counts as one class day
+	var delivered int
+	db.QueryRow(`
+		SELECT COUNT(DISTINCT completed_at::date) FROM mentor.progress
+		WHERE subscription_id = $1 AND completed_at >= $2 AND completed_at < $3
+	`, subId, cycleStart, cycleEnd).Scan(&delivered)
+
+	// Days the student missed every class aren't the school's shortfall
+	var absences int
+	db.QueryRow(`
+		SELECT COUNT(DISTINCT session_date) FROM mentor.class_sessions cs
+		WHERE subscription_id = $1 AND session_date >= $2 AND session_date < $3 AND student_status = 'absent'
+		  AND NOT EXISTS (
+			SELECT 1 FROM mentor.class_sessions o
+			WHERE o.subscription_id = cs.subscription_id AND o.session_date = cs.session_date
+			  AND o.student_status IN ('present', 'late')
+		  )
+	`, subId, cycleStart, cycleEnd).Scan(&absences)
+
+	_, err = db.Exec(`
+		INSERT INTO mentor.billing_cycles (subscription_id, cycle_start, cycle_end, scheduled_days,
+		    holiday_days, blackout_days, expected_classes, delivered_classes, student_absences)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (subscription_id, cycle_start) DO UPDATE SET
+		    cycle_end = EXCLUDED.cycle_end, scheduled_days = EXCLUDED.scheduled_days,
+		    holiday_days = EXCLUDED.holiday_days, blackout_days = EXCLUDED.blackout_days,
+		    expected_classes = EXCLUDED.expected_classes, delivered_classes = EXCLUDED.delivered_classes,
+		    student_absences = EXCLUDED.student_absences, updated_at = NOW()
+	`, subId, cycleStart, cycleEnd, scheduled, holidayDays, blackoutDays, expected, delivered, absences)
+	if err != nil {
+		return nil, err
+	}
+
+	return billingCycleJSON(subId, cycleStart, cycleEnd, scheduled, holidayDays, blackoutDays, expected, delivered, absences), nil
+}
+
+func billingCycleJSON(subId int, start, end time.Time, scheduled, holidays, blackouts, expected, delivered, absences int) gin.H {
+	shortfall := expected - delivered - absences
+	if shortfall < 0 {
+		shortfall = 0
+	}
+	return gin.H{
+		"subscription_id":   subId,
+		"cycle_start":       start.Format("2006-01-02"),
+		"cycle_end":         end.AddDate(0, 0, -1).Format("2006-01-02"),
+		"scheduled_days":    scheduled,
+		"holiday_days":      holidays,
+		"blackout_days":     blackouts,
+		"expected_classes":  expected,
+		"delivered_classes": delivered,
+		"student_absences":  absences,
+		"shortfall":         shortfall,
+	}
+}
+
+// refreshBillingCycles updates the current and previous cycle of every active
+// subscription, so late completions are picked up before a cycle is reviewed
+func refreshBillingCycles() error {
+	rows, err := db.Query("SELECT id FROM mentor.subscriptions WHERE status = 'active' AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	// One subscription's failure is logged and the rest still refresh
+	now := time.Now()
+	failed := 0
+	for _, id := range ids {
+		current, err := refreshBillingCycle(id, now)
+		if err != nil {
+			log.Printf("Billing cycle for subscription %d: %v", id, err)
+			failed++
+			continue
+		}
+		start, _ := time.ParseInLocation("2006-01-02", current["cycle_start"].(string), time.Local)
+		if _, err := refreshBillingCycle(id, start.AddDate(0, 0, -1)); err != nil {
+			log.Printf("Previous billing cycle for subscription %d: %v", id, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d billing cycles could not be refreshed", failed)
+	}
+	return nil
+}
+
+// getBillingCycles - Expected vs delivered classes per cycle for a subscription.
+// The cycle containing ?date= (default today) is recounted first.
+func getBillingCycles(c *gin.Context) {
+	id := c.Param("id")
+
+	var subId int
+	if err := db.QueryRow("SELECT id FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL", id).Scan(&subId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	day := time.Now()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date must be YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+
+	if _, err := refreshBillingCycle(subId, day); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cycles, err := queryBillingCycles("WHERE subscription_id = $1", subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "cycles": cycles})
+}
+
+// getBillingShortfalls - Completed cycles where fewer classes were delivered
+// than expected; candidates for makeup classes or a fee credit
+func getBillingShortfalls(c *gin.Context) {
+	cycles, err := queryBillingCycles(`
+		WHERE delivered_classes + student_absences < expected_classes AND cycle_end <= CURRENT_DATE
+		  AND subscription_id IN (SELECT id FROM mentor.subscriptions WHERE deleted_at IS NULL)
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "shortfalls": cycles})
+}
+
+func queryBillingCycles(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT subscription_id, cycle_start, cycle_end, scheduled_days, holiday_days,
+		       blackout_days, expected_classes, delivered_classes, student_absences
+		FROM mentor.billing_cycles
+		`+where+`
+		ORDER BY cycle_start DESC, subscription_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cycles := []gin.H{}
+	for rows.Next() {
+		var subId, scheduled, holidays, blackouts, expected, delivered, absences int
+		var start, end time.Time
+		if err := rows.Scan(&subId, &start, &end, &scheduled, &holidays, &blackouts, &expected, &delivered, &absences); err != nil {
+			continue
+		}
+		cycles = append(cycles, billingCycleJSON(subId, start, end, scheduled, holidays, blackouts, expected, delivered, absences))
+	}
+	return cycles, nil
+}
+
+// ============================================
+// TEACHER BLACKOUT DATES
+// ============================================
+func addTeacherBlackout(c *gin.Context) {
+	teacherId := c.Param("id")
+
+	var input struct {
+		Date   string `json:"date"` // YYYY-MM-DD
+		Reason string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if _, err := time.Parse("2006-01-02", input.Date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO mentor.teacher_blackouts (teacher_id, date, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (teacher_id, date) DO UPDATE SET reason = EXCLUDED.reason
+	`, teacherId, input.Date, input.Reason)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	requestClassSessionSync()
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Blackout date saved"})
+}
+
+func getTeacherBlackouts(c *gin.Context) {
+	teacherId := c.Param("id")
+
+	rows, err := db.Query(`
+		SELECT date, COALESCE(reason, '') FROM mentor.teacher_blackouts
+		WHERE teacher_id = $1 AND date >= CURRENT_DATE - 90
+		ORDER BY date
+	`, teacherId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var blackouts []gin.H
+	for rows.Next() {
+		var date time.Time
+		var reason string
+		if err := rows.Scan(&date, &reason); err != nil {
+			continue
+		}
+		blackouts = append(blackouts, gin.H{"date": date.Format("2006-01-02"), "reason": reason})
+	}
+
+	if blackouts == nil {
+		blackouts = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "blackouts": blackouts})
+}
+
+func deleteTeacherBlackout(c *gin.Context) {
+	result, err := db.Exec("DELETE FROM mentor.teacher_blackouts WHERE teacher_id = $1 AND date = $2",
+		c.Param("id"), c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Blackout date not found"})
+		return
+	}
+
+	requestClassSessionSync()
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Blackout date removed"})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// BILLING DOCUMENTS (Invoice and receipt PDFs)
+// ============================================
+
+// paymentMethods are the accepted transaction payment_method values
+var paymentMethods = map[string]string{
+	"cash":   "Cash",
+	"bkash":  "bKash",
+	"nagad":  "Nagad",
+	"rocket": "Rocket",
+	"bank":   "Bank transfer",
+	"card":   "Card",
+	"other":  "Other",
+}
+
+// brandName is the business name printed on documents (BRAND_NAME)
+func brandName() string {
+	if name := os.Getenv("BRAND_NAME"); name != "" {
+		return name
+	}
+	return "Mentor"
+}
+
+// currencyCode is the default currency (CURRENCY, default BDT): amounts
+// without a currency of their own and analytics totals are in it
+func currencyCode() string {
+	if code := os.Getenv("CURRENCY"); code != "" {
+		return code
+	}
+	return "BDT"
+}
+
+// formatMoney renders 12500.5, "BDT" as "BDT 12,500.50"
+func formatMoney(v float64, currency string) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	cents := int64(math.Round(v * 100))
+	whole := fmt.Sprint(cents / 100)
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return fmt.Sprintf("%s%s %s.%02d", sign, currency, b.String(), cents%100)
+}
+
+// documentHeader draws the brand bar and the document title; returns the y
+// to continue from
+func documentHeader(pdf *pdfDoc, title, number string) float64 {
+	pdf.SetColor(31, 78, 121)
+	pdf.FillRect(0, 0, pdfPageWidth, 80)
+	pdf.SetColor(255, 255, 255)
+	pdf.Text(40, 45, 22, true, brandName())
+	if contact := os.Getenv("BRAND_CONTACT"); contact != "" {
+		pdf.Text(40, 64, 9, false, contact)
+	}
+	pdf.TextRight(pdfPageWidth-40, 45, 18, true, title)
+	pdf.TextRight(pdfPageWidth-40, 64, 10, false, number)
+	pdf.SetColor(0, 0, 0)
+	return 115
+}
+
+// documentField writes a "label: value" pair
+func documentField(pdf *pdfDoc, x, y float64, label, value string) {
+	pdf.SetColor(110, 110, 110)
+	pdf.Text(x, y, 9, false, label)
+	pdf.SetColor(0, 0, 0)
+	pdf.Text(x, y+14, 11, false, value)
+}
+
+// documentFooter closes the page with a thank-you line
+func documentFooter(pdf *pdfDoc) {
+	pdf.Line(40, pdfPageHeight-60, pdfPageWidth-40, pdfPageHeight-60)
+	pdf.SetColor(110, 110, 110)
+	pdf.Text(40, pdfPageHeight-42, 9, false, "Thank you. This document was generated by "+brandName()+" on "+time.Now().Format("2 Jan 2006")+".")
+	pdf.SetColor(0, 0, 0)
+}
+
+// sendPDF sends the PDF inline so WhatsApp and browsers preview it
+func sendPDF(c *gin.Context, filename string, pdf *pdfDoc) {
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/pdf", pdf.Bytes())
+}
+
+// getInvoicePDF - The invoice as a branded PDF for the guardian
+func getInvoicePDF(c *gin.Context) {
+	invoice, err := loadInvoice(c.Param("id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Invoice not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var guardianName string
+	db.QueryRow("SELECT COALESCE(guardian_name, '') FROM mentor.subscriptions WHERE id = $1", invoice["subscription_id"]).Scan(&guardianName)
+
+	number := invoice["invoice_number"].(string)
+	currency := invoice["currency"].(string)
+	pdf := newPDF()
+	y := documentHeader(pdf, "INVOICE", number)
+
+	documentField(pdf, 40, y, "Student", invoice["student_name"].(string))
+	documentField(pdf, 300, y, "Issue date", invoice["issue_date"].(string))
+	y += 40
+	documentField(pdf, 40, y, "Guardian", guardianName)
+	documentField(pdf, 300, y, "Due date", invoice["due_date"].(string))
+	y += 40
+	documentField(pdf, 40, y, "Period", invoice["cycle_start"].(string)+" to "+invoice["cycle_end"].(string))
+	documentField(pdf, 300, y, "Status", strings.ReplaceAll(invoice["payment_status"].(string), "_", " "))
+	y += 40
+	taxLabel, taxed := invoice["tax_label"].(string)
+	if taxed {
+		if settings, err := loadInvoiceSettings(); err == nil && settings.TaxRegistration != "" {
+			documentField(pdf, 40, y, taxLabel+" registration", settings.TaxRegistration)
+			y += 40
+		}
+	}
+	y += 15
+
+	pdf.SetColor(235, 240, 246)
+	pdf.FillRect(40, y-14, pdfPageWidth-80, 22)
+	pdf.SetColor(0, 0, 0)
+	pdf.Text(48, y+1, 10, true, "Description")
+	if taxed {
+		pdf.TextRight(pdfPageWidth-210, y+1, 10, true, "Net")
+		pdf.TextRight(pdfPageWidth-130, y+1, 10, true, taxLabel)
+	}
+	pdf.TextRight(pdfPageWidth-48, y+1, 10, true, "Amount")
+	y += 26
+
+	for _, item := range invoice["items"].([]gin.H) {
+		if y > pdfPageHeight-160 {
+			documentFooter(pdf)
+			pdf.AddPage()
+			y = documentHeader(pdf, "INVOICE", number)
+		}
+		pdf.Text(48, y, 10, false, item["description"].(string))
+		if taxed {
+			pdf.TextRight(pdfPageWidth-210, y, 10, false, formatMoney(item["net_amount"].(float64), currency))
+			pdf.TextRight(pdfPageWidth-130, y, 10, false, fmt.Sprintf("%g%%", item["tax_percent"].(float64)))
+		}
+		pdf.TextRight(pdfPageWidth-48, y, 10, false, formatMoney(item["line_total"].(float64), currency))
+		pdf.Line(40, y+8, pdfPageWidth-40, y+8)
+		y += 22
+	}
+
+	y += 10
+	type totalLine struct {
+		label string
+		value float64
+		bold  bool
+	}
+	var totals []totalLine
+	if taxed {
+		label := taxLabel
+		if invoice["tax_mode"] == "inclusive" {
+			label += " (included)"
+		}
+		totals = append(totals,
+			totalLine{"Subtotal", invoice["taxable_amount"].(float64), false},
+			totalLine{label, invoice["tax_total"].(float64), false})
+	}
+	totals = append(totals,
+		totalLine{"Total", invoice["total"].(float64), true},
+		totalLine{"Paid", invoice["amount_paid"].(float64), false},
+		totalLine{"Balance due", invoice["balance"].(float64), true})
+	for _, t := range totals {
+		pdf.Text(330, y, 11, t.bold, t.label)
+		pdf.TextRight(pdfPageWidth-48, y, 11, t.bold, formatMoney(t.value, currency))
+		y += 20
+	}
+
+	if invoice["status"] == "void" {
+		pdf.SetColor(190, 30, 45)
+		pdf.Text(40, y+20, 16, true, "VOID: "+invoice["void_reason"].(string))
+		pdf.SetColor(0, 0, 0)
+	}
+
+	documentFooter(pdf)
+	sendPDF(c, number+".pdf", pdf)
+}
+
+// getTransactionReceiptPDF - A payment receipt: student, period, amount and
+// payment method
+func getTransactionReceiptPDF(c *gin.Context) {
+	var id int
+	var date time.Time
+	var amount float64
+	var txType, description, method string
+	var subId, groupID, invoiceID sql.NullInt64
+	var currency sql.NullString
+	err := db.QueryRow(`
+		SELECT id, date, type, amount, COALESCE(description, ''), COALESCE(payment_method, ''),
+		       subscription_id, billing_group_id, invoice_id, currency
+		FROM mentor.transactions WHERE id = $1 AND voided_at IS NULL
+	`, c.Param("id")).Scan(&id, &date, &txType, &amount, &description, &method, &subId, &groupID, &invoiceID, &currency)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Transaction not found"})
+		return
+	}
+	if txType != "income" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Receipts are only issued for payments received"})
+		return
+	}
+
+	payer, period, reference := "", "", ""
+	switch {
+	case subId.Valid:
+		var billingDay int
+		db.QueryRow(`
+			SELECT student_name, COALESCE(billing_date, 1) FROM mentor.subscriptions WHERE id = $1
+		`, subId.Int64).Scan(&payer, &billingDay)
+		start, end := billingCycleFor(date, billingDay)
+		period = start.Format("2006-01-02") + " to " + end.AddDate(0, 0, -1).Format("2006-01-02")
+	case groupID.Valid:
+		db.QueryRow("SELECT name FROM mentor.billing_groups WHERE id = $1", groupID.Int64).Scan(&payer)
+	}
+	if invoiceID.Valid {
+		var cycleStart, cycleEnd time.Time
+		if db.QueryRow(`
+			SELECT invoice_number, cycle_start, cycle_end FROM mentor.invoices WHERE id = $1
+		`, invoiceID.Int64).Scan(&reference, &cycleStart, &cycleEnd) == nil {
+			period = cycleStart.Format("2006-01-02") + " to " + cycleEnd.Format("2006-01-02")
+		}
+	}
+
+	methodLabel := paymentMethods[method]
+	if methodLabel == "" {
+		methodLabel = "Not recorded"
+	}
+
+	number := fmt.Sprintf("RCPT-%06d", id)
+	pdf := newPDF()
+	y := documentHeader(pdf, "RECEIPT", number)
+
+	documentField(pdf, 40, y, "Received from", payer)
+	documentField(pdf, 300, y, "Date", date.Format("2006-01-02"))
+	y += 40
+	documentField(pdf, 40, y, "Period", period)
+	documentField(pdf, 300, y, "Payment method", methodLabel)
+	y += 40
+	if reference != "" {
+		documentField(pdf, 40, y, "Invoice", reference)
+		y += 40
+	}
+	if description != "" {
+		documentField(pdf, 40, y, "Note", description)
+		y += 40
+	}
+
+	y += 10
+	pdf.SetColor(235, 240, 246)
+	pdf.FillRect(40, y, pdfPageWidth-80, 50)
+	pdf.SetColor(0, 0, 0)
+	pdf.Text(56, y+31, 13, true, "Amount received")
+	pdf.TextRight(pdfPageWidth-56, y+31, 16, true, formatMoney(amount, orDefaultCurrency(currency)))
+
+	documentFooter(pdf)
+	sendPDF(c, number+".pdf", pdf)
+}
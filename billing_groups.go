@@ -0,0 +1,235 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// BILLING GROUPS (One bill for siblings)
+// ============================================
+
+// billingGroupAmountSQL is the combined monthly amount of a subscription's
+// billing group; matches subscriptions aliased s
+const billingGroupAmountSQL = `(SELECT SUM(g.amount) FROM mentor.subscriptions g
+	WHERE g.billing_group_id = s.billing_group_id AND g.status = 'active' AND g.deleted_at IS NULL)`
+
+// createBillingGroup - Link subscriptions so the family gets one bill
+func createBillingGroup(c *gin.Context) {
+	var input struct {
+		Name            string `json:"name"`
+		GuardianName    string `json:"guardian_name"`
+		GuardianPhone   string `json:"guardian_phone"`
+		SubscriptionIDs []int  `json:"subscription_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Name == "" || len(input.SubscriptionIDs) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "name and at least two subscription_ids are required"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.billing_groups (name, guardian_name, guardian_phone)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''))
+		RETURNING id
+	`, input.Name, input.GuardianName, input.GuardianPhone).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := tx.Exec(`
+		UPDATE mentor.subscriptions SET billing_group_id = $1, updated_at = NOW()
+		WHERE id = ANY($2) AND deleted_at IS NULL
+	`, id, pq.Array(input.SubscriptionIDs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n != int64(len(input.SubscriptionIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "One or more subscriptions not found"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "message": "Billing group created"})
+}
+
+// getBillingGroup - Group details with members and the combined amount
+func getBillingGroup(c *gin.Context) {
+	id := c.Param("id")
+
+	var groupID int
+	var name string
+	var guardianName, guardianPhone sql.NullString
+	err := db.QueryRow(`
+		SELECT id, name, guardian_name, guardian_phone FROM mentor.billing_groups WHERE id = $1
+	`, id).Scan(&groupID, &name, &guardianName, &guardianPhone)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Billing group not found"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, student_name, class, amount, status, currency
+		FROM mentor.subscriptions
+		WHERE billing_group_id = $1 AND deleted_at IS NULL
+		ORDER BY id
+	`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var members []gin.H
+	type billed struct {
+		amount   float64
+		currency string
+	}
+	var active []billed
+	for rows.Next() {
+		var subId, class int
+		var studentName, status string
+		var amount float64
+		var currency sql.NullString
+		if err := rows.Scan(&subId, &studentName, &class, &amount, &status, &currency); err != nil {
+			continue
+		}
+		// Only active subscriptions are billed
+		if status == "active" {
+			active = append(active, billed{amount, orDefaultCurrency(currency)})
+		}
+		members = append(members, gin.H{
+			"subscription_id": subId,
+			"student_name":    studentName,
+			"class":           class,
+			"amount":          amount,
+			"currency":        orDefaultCurrency(currency),
+			"status":          status,
+		})
+	}
+
+	// Members billed in one currency are totalled in it; a mix is converted
+	// to the default currency at today's rates
+	combined := float64(0)
+	combinedCurrency := currencyCode()
+	if len(active) > 0 {
+		combinedCurrency = active[0].currency
+	}
+	for _, b := range active {
+		if b.currency != combinedCurrency {
+			combinedCurrency = currencyCode()
+			break
+		}
+	}
+	for _, b := range active {
+		amount := b.amount
+		if b.currency != combinedCurrency {
+			converted, err := toDefaultCurrency(b.amount, b.currency, time.Now())
+			if err != nil {
+				c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+				return
+			}
+			amount = converted
+		}
+		combined += amount
+	}
+
+	if members == nil {
+		members = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"billing_group": gin.H{
+			"id":              groupID,
+			"name":            name,
+			"guardian_name":   guardianName.String,
+			"guardian_phone":  guardianPhone.String,
+			"combined_amount": combined,
+			"currency":        combinedCurrency,
+			"subscriptions":   members,
+		},
+	})
+}
+
+// setSubscriptionBillingGroup - Add a subscription to a group, or remove it with billing_group_id = null
+func setSubscriptionBillingGroup(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		BillingGroupID *int `json:"billing_group_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.BillingGroupID != nil {
+		var exists bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.billing_groups WHERE id = $1)", *input.BillingGroupID).Scan(&exists)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Billing group not found"})
+			return
+		}
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.subscriptions SET billing_group_id = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL
+	`, input.BillingGroupID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Billing group updated"})
+}
+
+// billingGroupMembers returns the active subscriptions a group payment settles
+func billingGroupMembers(groupID int) ([]int, error) {
+	rows, err := db.Query(`
+		SELECT id FROM mentor.subscriptions
+		WHERE billing_group_id = $1 AND status = 'active' AND deleted_at IS NULL
+		ORDER BY id
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
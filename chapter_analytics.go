@@ -0,0 +1,137 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CHAPTER TIME ANALYTICS (Planned vs actual)
+// ============================================
+
+// getChapterAnalytics - Sessions each chapter actually took per student, against the plan.
+// Only chapters a student has finished (reached the last part) are counted.
+func getChapterAnalytics(c *gin.Context) {
+	query := `
+		WITH per_student AS (
+			SELECT s.class, p.subject, p.chapter, p.subscription_id,
+			       COUNT(*) AS sessions,
+			       EXTRACT(EPOCH FROM MAX(p.completed_at) - MIN(p.completed_at)) / 86400 AS days
+			FROM mentor.progress p
+			JOIN mentor.subscriptions s ON s.id = p.subscription_id AND s.deleted_at IS NULL
+			LEFT JOIN mentor.chapters pc ON pc.class = s.class AND pc.subject = p.subject
+			GROUP BY s.class, p.subject, p.chapter, p.subscription_id
+			HAVING MAX(p.part) >= MAX(COALESCE(pc.parts_per_chapter, 3))
+		)
+		SELECT ps.class, ps.subject, ps.chapter,
+		       COALESCE(e.planned_sessions, ch.parts_per_chapter, 3),
+		       e.planned_sessions IS NOT NULL,
+		       COUNT(*),
+		       AVG(ps.sessions),
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY ps.sessions),
+		       MIN(ps.sessions), MAX(ps.sessions),
+		       AVG(ps.days)
+		FROM per_student ps
+		LEFT JOIN mentor.chapter_estimates e ON e.class = ps.class AND e.subject = ps.subject AND e.chapter = ps.chapter
+		LEFT JOIN mentor.chapters ch ON ch.class = ps.class AND ch.subject = ps.subject
+		WHERE 1=1
+	`
+	args := []interface{}{}
+	argCount := 0
+
+	if class := c.Query("class"); class != "" {
+		argCount++
+		query += fmt.Sprintf(" AND ps.class = $%d", argCount)
+		args = append(args, class)
+	}
+	if subject := c.Query("subject"); subject != "" {
+		argCount++
+		query += fmt.Sprintf(" AND ps.subject = $%d", argCount)
+		args = append(args, subject)
+	}
+
+	query += `
+		GROUP BY ps.class, ps.subject, ps.chapter, e.planned_sessions, ch.parts_per_chapter
+		ORDER BY ps.class, ps.subject, ps.chapter`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	chapters := []gin.H{}
+	for rows.Next() {
+		var class, chapter, planned, students, minSessions, maxSessions int
+		var subject string
+		var customPlan bool
+		var avgSessions, medianSessions float64
+		var avgDays sql.NullFloat64
+
+		if err := rows.Scan(&class, &subject, &chapter, &planned, &customPlan, &students,
+			&avgSessions, &medianSessions, &minSessions, &maxSessions, &avgDays); err != nil {
+			continue
+		}
+
+		chapters = append(chapters, gin.H{
+			"class":              class,
+			"subject":            subject,
+			"chapter":            chapter,
+			"planned_sessions":   planned,
+			"planned_is_custom":  customPlan,
+			"students":           students,
+			"avg_sessions":       avgSessions,
+			"median_sessions":    medianSessions,
+			"min_sessions":       minSessions,
+			"max_sessions":       maxSessions,
+			"avg_days":           avgDays.Float64,
+			"variance_sessions":  avgSessions - float64(planned),
+			"suggested_sessions": int(medianSessions + 0.5),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "chapters": chapters})
+}
+
+// updateChapterEstimate - Admin sets the planned sessions for one chapter
+func updateChapterEstimate(c *gin.Context) {
+	var input struct {
+		Class           int    `json:"class"`
+		Subject         string `json:"subject"`
+		Chapter         int    `json:"chapter"`
+		PlannedSessions int    `json:"planned_sessions"`
+		UpdatedBy       string `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Class == 0 || input.Subject == "" || input.Chapter < 1 || input.PlannedSessions < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "class, subject, chapter and planned_sessions (>= 1) are required"})
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO mentor.chapter_estimates (class, subject, chapter, planned_sessions, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (class, subject, chapter)
+		DO UPDATE SET planned_sessions = $4, updated_by = $5, updated_at = NOW()
+	`, input.Class, input.Subject, input.Chapter, input.PlannedSessions, input.UpdatedBy)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("chapter", fmt.Sprintf("%d/%s/%d", input.Class, input.Subject, input.Chapter), "estimate_updated", input.UpdatedBy, gin.H{
+		"planned_sessions": input.PlannedSessions,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Chapter estimate saved"})
+}
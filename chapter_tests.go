@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CHAPTER TEST GATING
+// ============================================
+
+// bestChapterTestScore returns the best graded test percentage for a
+// subscription's subject/chapter, and whether any graded test exists.
+func bestChapterTestScore(subId string, subject string, chapter int) (float64, bool) {
+	var best sql.NullFloat64
+	db.QueryRow(`
+		SELECT MAX(actual_marks::float / NULLIF(total_marks, 0) * 100)
+		FROM mentor.answer_papers
+		WHERE subscription_id = $1 AND LOWER(subject) = LOWER($2)
+		  AND chapter_number = $3 AND status = 'graded'
+	`, subId, subject, chapter).Scan(&best)
+
+	return best.Float64, best.Valid
+}
+
+// updateChapterTestGate - Enable/disable the chapter test rule for a subscription
+func updateChapterTestGate(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Required bool `json:"required"`
+		MinScore int  `json:"min_score"` // percent, 0-100
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.MinScore < 0 || input.MinScore > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "min_score must be between 0 and 100"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.subscriptions
+		SET chapter_test_required = $1, chapter_test_min_score = $2, updated_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL
+	`, input.Required, input.MinScore, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"required":  input.Required,
+		"min_score": input.MinScore,
+		"message":   "Chapter test rule updated",
+	})
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// CHAPTER VIDEOS (lessons + watch tracking)
+// ============================================
+
+var (
+	youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDPattern   = regexp.MustCompile(`^[0-9]{6,12}$`)
+)
+
+// videoCompleteRatio is how much of a video must be reached to count as watched
+const videoCompleteRatio = 0.9
+
+// maxWatchReportSeconds caps the watch time one progress report can add
+const maxWatchReportSeconds = 600
+
+// parseVideoURL recognises YouTube and Vimeo links and returns the provider
+// and video id
+func parseVideoURL(raw string) (string, string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch host {
+	case "youtube.com", "youtube-nocookie.com":
+		id := u.Query().Get("v")
+		if len(parts) == 2 && (parts[0] == "embed" || parts[0] == "shorts" || parts[0] == "live") {
+			id = parts[1]
+		}
+		if youtubeIDPattern.MatchString(id) {
+			return "youtube", id, true
+		}
+	case "youtu.be":
+		if youtubeIDPattern.MatchString(parts[0]) {
+			return "youtube", parts[0], true
+		}
+	case "vimeo.com", "player.vimeo.com":
+		id := parts[len(parts)-1]
+		if len(parts) >= 2 && parts[0] == "video" {
+			id = parts[1]
+		}
+		if vimeoIDPattern.MatchString(id) {
+			return "vimeo", id, true
+		}
+	}
+	return "", "", false
+}
+
+// videoLinks returns the watch and embed URLs for a video
+func videoLinks(c *gin.Context, provider, externalID, assetID string) (string, string) {
+	switch provider {
+	case "youtube":
+		return "https://www.youtube.com/watch?v=" + externalID, "https://www.youtube.com/embed/" + externalID
+	case "vimeo":
+		return "https://vimeo.com/" + externalID, "https://player.vimeo.com/video/" + externalID
+	}
+	link := contentAssetURL(c, assetID)
+	return link, link
+}
+
+// videoSource resolves the url or asset_id of a video body; returns a field error
+func videoSource(rawURL, assetID string) (provider, externalID string, asset interface{}, fields map[string]string) {
+	fields = map[string]string{}
+	switch {
+	case rawURL != "" && assetID != "":
+		fields["url"] = "send either url or asset_id, not both"
+	case rawURL != "":
+		var ok bool
+		if provider, externalID, ok = parseVideoURL(rawURL); !ok {
+			fields["url"] = "must be a YouTube or Vimeo video link"
+		}
+	case assetID != "":
+		var kind string
+		db.QueryRow("SELECT kind FROM mentor.content_assets WHERE id = $1", assetID).Scan(&kind)
+		if kind != "video" {
+			fields["asset_id"] = "must be an uploaded video asset"
+		}
+		provider, asset = "upload", assetID
+	default:
+		fields["url"] = "url or asset_id is required"
+	}
+	return
+}
+
+// videoPublishedSQL holds for videos (aliased v) whose chapter content is
+// published; students and the app only see those
+const videoPublishedSQL = `EXISTS (SELECT 1 FROM mentor.content ct
+	WHERE ct.class = v.class AND ct.subject = v.subject AND ct.chapter_number = v.chapter_number
+	  AND ct.status = 'published')`
+
+// queryChapterVideos lists a chapter's videos in order; with a subscription id
+// each video carries that student's watch progress. publishedOnly hides the
+// videos of chapters that aren't published.
+func queryChapterVideos(c *gin.Context, class int, subject string, chapter int, subId int, publishedOnly bool) ([]gin.H, error) {
+	visibility := ""
+	if publishedOnly {
+		visibility = " AND " + videoPublishedSQL
+	}
+	rows, err := db.Query(`
+		SELECT v.id, v.position, v.title, COALESCE(v.description, ''), v.provider, COALESCE(v.external_id, ''),
+		       COALESCE(v.asset_id, ''), v.duration_seconds,
+		       w.position_seconds, w.furthest_seconds, w.watched_seconds, w.completed_at, w.last_watched_at
+		FROM mentor.chapter_videos v
+		LEFT JOIN mentor.video_watch_progress w ON w.video_id = v.id AND w.subscription_id = $4
+		WHERE v.class = $1 AND v.subject = $2 AND v.chapter_number = $3`+visibility+`
+		ORDER BY v.position, v.id
+	`, class, subject, chapter, subId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	videos := []gin.H{}
+	for rows.Next() {
+		var id, position int
+		var title, description, provider, externalID, assetID string
+		var duration, watchPosition, furthest, watched sql.NullInt64
+		var completedAt, lastWatched sql.NullTime
+		if err := rows.Scan(&id, &position, &title, &description, &provider, &externalID, &assetID, &duration,
+			&watchPosition, &furthest, &watched, &completedAt, &lastWatched); err != nil {
+			continue
+		}
+		watchURL, embedURL := videoLinks(c, provider, externalID, assetID)
+		video := gin.H{
+			"id":          id,
+			"position":    position,
+			"title":       title,
+			"description": description,
+			"provider":    provider,
+			"url":         watchURL,
+			"embed_url":   embedURL,
+		}
+		if assetID != "" {
+			video["asset_id"] = assetID
+		}
+		if duration.Valid {
+			video["duration_seconds"] = duration.Int64
+		}
+		if subId != 0 {
+			progress := gin.H{"started": watched.Valid, "completed": completedAt.Valid}
+			if watched.Valid {
+				progress["position_seconds"] = watchPosition.Int64
+				progress["furthest_seconds"] = furthest.Int64
+				progress["watched_seconds"] = watched.Int64
+				progress["last_watched_at"] = isoTimestamp(lastWatched.Time)
+			}
+			if completedAt.Valid {
+				progress["completed_at"] = isoTimestamp(completedAt.Time)
+			}
+			video["progress"] = progress
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+// getChapterVideos - A chapter's videos in order
+func getChapterVideos(c *gin.Context) {
+	class, _ := strconv.Atoi(c.Param("class"))
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+
+	videos, err := queryChapterVideos(c, class, c.Param("subject"), chapter, 0, !isAdminRequest(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "videos": videos})
+}
+
+// addChapterVideo - Attach a YouTube/Vimeo link (url) or an uploaded video
+// (asset_id) to a chapter, at the end of its list
+func addChapterVideo(c *gin.Context) {
+	var input struct {
+		Title           string `json:"title" binding:"required"`
+		Description     string `json:"description"`
+		URL             string `json:"url"`
+		AssetID         string `json:"asset_id"`
+		DurationSeconds *int   `json:"duration_seconds"`
+		CreatedBy       string `json:"created_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	provider, externalID, asset, fields := videoSource(input.URL, input.AssetID)
+	if input.DurationSeconds != nil && *input.DurationSeconds <= 0 {
+		fields["duration_seconds"] = "must be positive"
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	class, _ := strconv.Atoi(c.Param("class"))
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Serialize adds to one chapter so two at once can't take the same position
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('chapter_videos:' || $1 || ':' || $2 || ':' || $3))",
+		class, c.Param("subject"), chapter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var id, position int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.chapter_videos (class, subject, chapter_number, position, title, description,
+		                                   provider, external_id, asset_id, duration_seconds, created_by)
+		SELECT $1, $2, $3, COALESCE(MAX(position), 0) + 1, $4, NULLIF($5, ''), $6, NULLIF($7, ''), $8, $9, NULLIF($10, '')
+		FROM mentor.chapter_videos WHERE class = $1 AND subject = $2 AND chapter_number = $3
+		RETURNING id, position
+	`, class, c.Param("subject"), chapter, strings.TrimSpace(input.Title), input.Description,
+		provider, externalID, asset, input.DurationSeconds, input.CreatedBy).Scan(&id, &position)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "position": position, "provider": provider, "message": "Video added"})
+}
+
+// updateChapterVideo - Change a video's title, description, duration or source;
+// omitted fields are kept
+func updateChapterVideo(c *gin.Context) {
+	var input struct {
+		Title           *string `json:"title"`
+		Description     *string `json:"description"`
+		URL             string  `json:"url"`
+		AssetID         string  `json:"asset_id"`
+		DurationSeconds *int    `json:"duration_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	argCount := 0
+	set := func(column string, value interface{}) {
+		argCount++
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, argCount))
+		args = append(args, value)
+	}
+
+	fields := map[string]string{}
+	if input.Title != nil {
+		if strings.TrimSpace(*input.Title) == "" {
+			fields["title"] = "must not be empty"
+		}
+		set("title", strings.TrimSpace(*input.Title))
+	}
+	if input.Description != nil {
+		set("description", sql.NullString{String: *input.Description, Valid: *input.Description != ""})
+	}
+	if input.DurationSeconds != nil {
+		if *input.DurationSeconds <= 0 {
+			fields["duration_seconds"] = "must be positive"
+		}
+		set("duration_seconds", *input.DurationSeconds)
+	}
+	if input.URL != "" || input.AssetID != "" {
+		provider, externalID, asset, sourceFields := videoSource(input.URL, input.AssetID)
+		for k, v := range sourceFields {
+			fields[k] = v
+		}
+		set("provider", provider)
+		set("external_id", sql.NullString{String: externalID, Valid: externalID != ""})
+		set("asset_id", asset)
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+	if len(sets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Nothing to update"})
+		return
+	}
+
+	argCount++
+	args = append(args, c.Param("id"))
+	result, err := db.Exec(fmt.Sprintf("UPDATE mentor.chapter_videos SET %s, updated_at = NOW() WHERE id = $%d",
+		strings.Join(sets, ", "), argCount), args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Video not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Video updated"})
+}
+
+// deleteChapterVideo - Remove a video (and its watch history); later videos move up
+func deleteChapterVideo(c *gin.Context) {
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var class, chapter, position int
+	var subject string
+	err = tx.QueryRow(`
+		DELETE FROM mentor.chapter_videos WHERE id = $1
+		RETURNING class, subject, chapter_number, position
+	`, c.Param("id")).Scan(&class, &subject, &chapter, &position)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE mentor.chapter_videos SET position = position - 1, updated_at = NOW()
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3 AND position > $4
+	`, class, subject, chapter, position)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Video deleted"})
+}
+
+// reorderChapterVideos - Set the order of a chapter's videos; video_ids must
+// list every video of the chapter once
+func reorderChapterVideos(c *gin.Context) {
+	var input struct {
+		VideoIDs []int `json:"video_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	class, _ := strconv.Atoi(c.Param("class"))
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE mentor.chapter_videos v SET position = o.position, updated_at = NOW()
+		FROM unnest($4::int[]) WITH ORDINALITY AS o(id, position)
+		WHERE v.id = o.id AND v.class = $1 AND v.subject = $2 AND v.chapter_number = $3
+	`, class, c.Param("subject"), chapter, pq.Array(input.VideoIDs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var total int
+	tx.QueryRow(`
+		SELECT COUNT(*) FROM mentor.chapter_videos WHERE class = $1 AND subject = $2 AND chapter_number = $3
+	`, class, c.Param("subject"), chapter).Scan(&total)
+
+	if n, _ := result.RowsAffected(); n != int64(len(input.VideoIDs)) || total != len(input.VideoIDs) {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+			"video_ids": fmt.Sprintf("must list each of the chapter's %d videos exactly once", total),
+		}))
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Videos reordered"})
+}
+
+// getStudentChapterVideos - The logged-in student's videos for a chapter of
+// their class, with their progress
+func getStudentChapterVideos(c *gin.Context) {
+	subId := c.GetInt("subscription_id")
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+
+	var class int
+	db.QueryRow("SELECT class FROM mentor.subscriptions WHERE id = $1", subId).Scan(&class)
+
+	videos, err := queryChapterVideos(c, class, c.Param("subject"), chapter, subId, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "videos": videos})
+}
+
+// recordVideoProgress - The player reports where the student is
+// (position_seconds), how long they played since the last report
+// (watched_seconds) and optionally completed; reaching 90% of the duration
+// also completes the video
+func recordVideoProgress(c *gin.Context) {
+	var input struct {
+		PositionSeconds int  `json:"position_seconds"`
+		WatchedSeconds  int  `json:"watched_seconds"`
+		Completed       bool `json:"completed"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if input.PositionSeconds < 0 || input.WatchedSeconds < 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"position_seconds": "must not be negative"}))
+		return
+	}
+	if input.WatchedSeconds > maxWatchReportSeconds {
+		input.WatchedSeconds = maxWatchReportSeconds
+	}
+
+	var furthest, watched int
+	var completedAt sql.NullTime
+	err := db.QueryRow(`
+		INSERT INTO mentor.video_watch_progress (video_id, subscription_id, position_seconds, furthest_seconds,
+		                                         watched_seconds, completed_at)
+		SELECT v.id, $2, $3, $3, $4,
+		       CASE WHEN $5 OR $3 >= v.duration_seconds * $6::float8 THEN NOW() END
+		FROM mentor.chapter_videos v
+		JOIN mentor.subscriptions s ON s.id = $2
+		WHERE v.id = $1 AND v.class = s.class
+		  AND EXISTS (SELECT 1 FROM unnest(s.subject_list) subj WHERE LOWER(subj) = LOWER(v.subject))
+		  AND `+videoPublishedSQL+`
+		ON CONFLICT (video_id, subscription_id) DO UPDATE SET
+			position_seconds = EXCLUDED.position_seconds,
+			furthest_seconds = GREATEST(video_watch_progress.furthest_seconds, EXCLUDED.furthest_seconds),
+			watched_seconds = video_watch_progress.watched_seconds + EXCLUDED.watched_seconds,
+			completed_at = COALESCE(video_watch_progress.completed_at, EXCLUDED.completed_at),
+			last_watched_at = NOW()
+		RETURNING furthest_seconds, watched_seconds, completed_at
+	`, c.Param("id"), c.GetInt("subscription_id"), input.PositionSeconds, input.WatchedSeconds,
+		input.Completed, videoCompleteRatio).Scan(&furthest, &watched, &completedAt)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success":          true,
+		"furthest_seconds": furthest,
+		"watched_seconds":  watched,
+		"completed":        completedAt.Valid,
+	}
+	if completedAt.Valid {
+		response["completed_at"] = isoTimestamp(completedAt.Time)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getSubscriptionVideoProgress - Every video a student has started, by chapter
+func getSubscriptionVideoProgress(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT v.id, v.subject, v.chapter_number, v.title, v.duration_seconds,
+		       w.furthest_seconds, w.watched_seconds, w.completed_at, w.last_watched_at
+		FROM mentor.video_watch_progress w
+		JOIN mentor.chapter_videos v ON v.id = w.video_id
+		WHERE w.subscription_id = $1
+		ORDER BY v.subject, v.chapter_number, v.position
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	videos := []gin.H{}
+	completed, watchedTotal := 0, 0
+	for rows.Next() {
+		var id, chapter, furthest, watched int
+		var subject, title string
+		var duration sql.NullInt64
+		var completedAt sql.NullTime
+		var lastWatched time.Time
+		if err := rows.Scan(&id, &subject, &chapter, &title, &duration, &furthest, &watched, &completedAt, &lastWatched); err != nil {
+			continue
+		}
+		video := gin.H{
+			"video_id":         id,
+			"subject":          subject,
+			"chapter_number":   chapter,
+			"title":            title,
+			"furthest_seconds": furthest,
+			"watched_seconds":  watched,
+			"completed":        completedAt.Valid,
+			"last_watched_at":  isoTimestamp(lastWatched),
+		}
+		if duration.Valid && duration.Int64 > 0 {
+			video["duration_seconds"] = duration.Int64
+			video["percent_reached"] = roundMoney(float64(min(int64(furthest), duration.Int64)) / float64(duration.Int64) * 100)
+		}
+		if completedAt.Valid {
+			completed++
+		}
+		watchedTotal += watched
+		videos = append(videos, video)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":               true,
+		"started":               len(videos),
+		"completed":             completed,
+		"total_watched_seconds": watchedTotal,
+		"videos":                videos,
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CLASS DURATION (End times + short classes)
+// ============================================
+
+const (
+	minClassMinutes = 15
+	maxClassMinutes = 300
+
+	// A class checked out before this share of its length is flagged short
+	shortClassRatio = 0.8
+)
+
+// visitMinutesSQL is how long a subscription's visit takes: its longest
+// subject class. Needs the subscription aliased as s.
+const visitMinutesSQL = `COALESCE((
+		SELECT MAX(COALESCE(sc.class_minutes, s.class_minutes, 60))
+		FROM mentor.schedule sc WHERE sc.subscription_id = s.id
+	), s.class_minutes, 60)`
+
+// classMinutesOr returns minutes, or the default when it isn't set
+func classMinutesOr(minutes int) int {
+	if minutes <= 0 {
+		return defaultClassMinutes
+	}
+	return minutes
+}
+
+// validClassMinutes reports whether 0 (unset) or a sensible class length
+func validClassMinutes(minutes int) bool {
+	return minutes == 0 || (minutes >= minClassMinutes && minutes <= maxClassMinutes)
+}
+
+// addSessionTimes adds starts_at, end_time, ends_at and duration_minutes to a
+// session entry. Sessions without a readable class time only get the duration.
+func addSessionTimes(session gin.H, date time.Time, classTime string, minutes int, loc *time.Location) {
+	minutes = classMinutesOr(minutes)
+	session["duration_minutes"] = minutes
+	start, ok := sessionStart(date, classTime, loc)
+	if !ok {
+		return
+	}
+	end := start.Add(time.Duration(minutes) * time.Minute)
+	session["starts_at"] = start.Format(time.RFC3339)
+	session["end_time"] = end.Format("3:04 PM")
+	session["ends_at"] = end.Format(time.RFC3339)
+}
+
+// getShortClasses - Sessions whose check-in to check-out was shorter than
+// planned, e.g. a 60 minute class checked out after 35 minutes
+func getShortClasses(c *gin.Context) {
+	from, to, ok := parsePastDateRange(c, 30)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	query := `
+		SELECT cs.id, cs.subscription_id, s.student_name, cs.session_date, COALESCE(cs.session_time, ''),
+		       cs.subject, COALESCE(cs.teacher_id, ''), COALESCE(cs.duration_minutes, 0),
+		       a.checked_in, a.checked_out
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		JOIN (
+			SELECT class_session_id,
+			       MIN(recorded_at) FILTER (WHERE action = 'start') AS checked_in,
+			       MAX(recorded_at) FILTER (WHERE action = 'end') AS checked_out
+			FROM mentor.attendance
+			WHERE class_session_id IS NOT NULL
+			GROUP BY class_session_id
+		) a ON a.class_session_id = cs.id
+		WHERE cs.session_date BETWEEN $1 AND $2
+		  AND a.checked_in IS NOT NULL AND a.checked_out > a.checked_in
+		  AND EXTRACT(EPOCH FROM a.checked_out - a.checked_in) / 60 < COALESCE(cs.duration_minutes, 60) * $3
+	`
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02"), shortClassRatio}
+	argCount := 3
+
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND cs.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		query += fmt.Sprintf(" AND cs.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+
+	query += " ORDER BY cs.session_date DESC, cs.session_time, cs.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	classes := []gin.H{}
+	for rows.Next() {
+		var id, subId, minutes int
+		var studentName, sessionTime, subject, teacher string
+		var sessionDate time.Time
+		var checkedIn, checkedOut sql.NullTime
+		if err := rows.Scan(&id, &subId, &studentName, &sessionDate, &sessionTime, &subject, &teacher,
+			&minutes, &checkedIn, &checkedOut); err != nil {
+			continue
+		}
+		planned := classMinutesOr(minutes)
+		actual := int(checkedOut.Time.Sub(checkedIn.Time).Minutes())
+		classes = append(classes, gin.H{
+			"class_session_id": id,
+			"subscription_id":  subId,
+			"student_name":     studentName,
+			"date":             sessionDate.Format("2006-01-02"),
+			"time":             sessionTime,
+			"subject":          subject,
+			"teacher_id":       teacher,
+			"planned_minutes":  planned,
+			"actual_minutes":   actual,
+			"short_by_minutes": planned - actual,
+			"checked_in_at":    isoTimestamp(checkedIn.Time),
+			"checked_out_at":   isoTimestamp(checkedOut.Time),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"from":      from.Format("2006-01-02"),
+		"to":        to.Format("2006-01-02"),
+		"threshold": shortClassRatio,
+		"classes":   classes,
+	})
+}
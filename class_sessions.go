@@ -0,0 +1,768 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// CLASS SESSIONS (Planned classes per date)
+// ============================================
+
+// classSessionHorizonDays is how far ahead the job keeps sessions generated
+const classSessionHorizonDays = 14
+
+// plannedSession is one subject class a subscription's schedule puts on a date
+type plannedSession struct {
+	SubscriptionID int
+	Subject        string
+	TeacherID      string
+	Time           string
+	Minutes        int // 0 uses defaultClassMinutes
+}
+
+// planClassSessions lists the classes active subscriptions have on day: one per
+// subject, taught by the subject's teacher or else the main teacher, at the
+// subject's slot time that day or else the subscription's time. Holidays,
+// paused subscriptions and teachers on leave or a personal holiday have none. With teacherID set,
+// only that teacher's classes are returned.
+func planClassSessions(day time.Time, teacherID string) ([]plannedSession, error) {
+	var holiday bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.holidays WHERE date = $1 AND teacher_id IS NULL)", day.Format("2006-01-02")).Scan(&holiday)
+	if holiday {
+		return nil, nil
+	}
+
+	query := `
+		SELECT s.id, s.teacher_id, s.subject_list, s.schedule_day_list, COALESCE(s.time, ''),
+		       COALESCE(s.class_minutes, 0)
+		FROM mentor.subscriptions s
+		WHERE s.status = 'active' AND s.deleted_at IS NULL
+	`
+	args := []interface{}{}
+	if teacherID != "" {
+		query += " AND " + teacherAssignedSQL
+		args = append(args, teacherID)
+	}
+	// A cancellation scheduled for a later date keeps classes until then
+	args = append(args, day.Format("2006-01-02"))
+	query += fmt.Sprintf(" AND (s.end_date IS NULL OR s.end_date >= $%d)", len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		id, minutes        int
+		teacherID, time    string
+		subjects, schedule []string
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var cand candidate
+		if err := rows.Scan(&cand.id, &cand.teacherID, pq.Array(&cand.subjects), pq.Array(&cand.schedule), &cand.time, &cand.minutes); err != nil {
+			continue
+		}
+		if scheduledWeekdays(cand.schedule)[day.Weekday()] {
+			candidates = append(candidates, cand)
+		}
+	}
+	rows.Close()
+
+	date := day.Format("2006-01-02")
+	slots, err := loadScheduleSlots("sl.day = $1", day.Format("Mon"))
+	if err != nil {
+		return nil, err
+	}
+
+	paused := map[int]bool{}
+	pauseRows, err := db.Query(`
+		SELECT subscription_id FROM mentor.subscription_pauses WHERE $1 BETWEEN start_date AND end_date
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	for pauseRows.Next() {
+		var subId int
+		pauseRows.Scan(&subId)
+		paused[subId] = true
+	}
+	pauseRows.Close()
+
+	onLeave := map[string]bool{}
+	leaveRows, err := db.Query(`
+		SELECT teacher_id FROM mentor.teacher_blackouts WHERE date = $1
+		UNION
+		SELECT teacher_id FROM mentor.holidays WHERE date = $1 AND teacher_id IS NOT NULL
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	for leaveRows.Next() {
+		var teacher string
+		leaveRows.Scan(&teacher)
+		onLeave[teacher] = true
+	}
+	leaveRows.Close()
+
+	var planned []plannedSession
+	for _, cand := range candidates {
+		if paused[cand.id] {
+			continue
+		}
+
+		subjectTeachers := map[string]string{}
+		subjectMinutes := map[string]int{}
+		var order []string
+		schedRows, err := db.Query(`
+			SELECT subject, COALESCE(teacher_id, $2), COALESCE(class_minutes, $3) FROM mentor.schedule
+			WHERE subscription_id = $1 ORDER BY id
+		`, cand.id, cand.teacherID, cand.minutes)
+		if err != nil {
+			return nil, err
+		}
+		for schedRows.Next() {
+			var subject, teacher string
+			var minutes int
+			if err := schedRows.Scan(&subject, &teacher, &minutes); err != nil {
+				continue
+			}
+			subjectTeachers[subject] = teacher
+			subjectMinutes[subject] = minutes
+			order = append(order, subject)
+		}
+		schedRows.Close()
+
+		// Subscriptions without schedule rows fall back to the subject list
+		if len(order) == 0 {
+			for _, subject := range cand.subjects {
+				subjectTeachers[subject] = cand.teacherID
+				subjectMinutes[subject] = cand.minutes
+				order = append(order, subject)
+			}
+		}
+
+		for _, subject := range order {
+			teacher := subjectTeachers[subject]
+			if (teacherID != "" && teacher != teacherID) || onLeave[teacher] {
+				continue
+			}
+			planned = append(planned, plannedSession{
+				SubscriptionID: cand.id,
+				Subject:        subject,
+				TeacherID:      teacher,
+				Time:           slotTime(slots[cand.id][day.Weekday()], subject, cand.time),
+				Minutes:        subjectMinutes[subject],
+			})
+		}
+	}
+
+	return planned, nil
+}
+
+// syncClassSessions brings the sessions for [from, to] in line with the current
+// schedules: missing ones are added, teacher and time changes are applied, and
+// sessions that are no longer planned are removed. Days cancelled for a
+// subscription are kept as cancelled sessions. Completed and rescheduled
+// sessions, and sessions moved onto a day, are left alone. With teacherID set,
+// only that teacher's sessions are synced.
+func syncClassSessions(from, to time.Time, teacherID string) error {
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+
+		planned, err := planClassSessions(day, teacherID)
+		if err != nil {
+			return err
+		}
+
+		subIDs, subjects := []int64{}, []string{}
+		for _, p := range planned {
+			_, err := db.Exec(`
+				INSERT INTO mentor.class_sessions (subscription_id, session_date, session_time, subject, teacher_id, status,
+				                                   duration_minutes)
+				VALUES ($1, $2, $3, $4, $5,
+				        CASE WHEN EXISTS(SELECT 1 FROM mentor.class_cancellations WHERE subscription_id = $1 AND date = $2)
+				             THEN 'cancelled' ELSE 'scheduled' END,
+				        NULLIF($6, 0))
+				ON CONFLICT (subscription_id, session_date, subject) WHERE rescheduled_from IS NULL DO UPDATE
+				SET session_time = EXCLUDED.session_time, teacher_id = EXCLUDED.teacher_id,
+				    duration_minutes = EXCLUDED.duration_minutes,
+				    status = CASE WHEN EXCLUDED.status = 'cancelled' THEN 'cancelled' ELSE class_sessions.status END,
+				    updated_at = NOW()
+				WHERE class_sessions.status = 'scheduled'
+				  AND (class_sessions.session_time, class_sessions.teacher_id, class_sessions.duration_minutes, EXCLUDED.status)
+				      IS DISTINCT FROM (EXCLUDED.session_time, EXCLUDED.teacher_id, EXCLUDED.duration_minutes, 'scheduled')
+			`, p.SubscriptionID, date, p.Time, p.Subject, p.TeacherID, p.Minutes)
+			if err != nil {
+				return err
+			}
+			subIDs = append(subIDs, int64(p.SubscriptionID))
+			subjects = append(subjects, p.Subject)
+		}
+
+		query := `
+			DELETE FROM mentor.class_sessions
+			WHERE session_date = $1 AND status = 'scheduled' AND rescheduled_from IS NULL
+			  AND (subscription_id, subject) NOT IN (SELECT * FROM unnest($2::int[], $3::text[]))
+		`
+		args := []interface{}{date, pq.Array(subIDs), pq.Array(subjects)}
+		if teacherID != "" {
+			query += " AND teacher_id = $4"
+			args = append(args, teacherID)
+		}
+		if _, err := db.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localToday is midnight at the start of the current day
+func localToday() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// syncClassSessionsAhead syncs the part of [from, to] from today on; dates
+// before today are history and left as they are
+func syncClassSessionsAhead(from, to time.Time, teacherID string) error {
+	start := localToday()
+	if to.Before(start) {
+		return nil
+	}
+	if from.After(start) {
+		start = from
+	}
+	return syncClassSessions(start, to, teacherID)
+}
+
+// syncUpcomingClassSessions keeps today and the coming horizon generated
+func syncUpcomingClassSessions() error {
+	today := localToday()
+	return syncClassSessions(today, today.AddDate(0, 0, classSessionHorizonDays), "")
+}
+
+// classSessionsGeneratedUntil is the last date the job keeps generated;
+// schedule views forecast later dates from the schedules instead
+func classSessionsGeneratedUntil() time.Time {
+	return localToday().AddDate(0, 0, classSessionHorizonDays)
+}
+
+// classSessionSyncRequests holds at most one pending resync, so a burst of
+// schedule changes costs one sync
+var classSessionSyncRequests = make(chan struct{}, 1)
+
+// requestClassSessionSync asks the background worker to bring the upcoming
+// sessions in line after a change to subscriptions, holidays or leave. Schedule
+// views only read class_sessions, so they show the change once it has run.
+func requestClassSessionSync() {
+	select {
+	case classSessionSyncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// runClassSessionSyncWorker serves requestClassSessionSync until shutdown
+func runClassSessionSyncWorker() {
+	for range classSessionSyncRequests {
+		if err := syncUpcomingClassSessions(); err != nil {
+			log.Printf("Class session sync failed: %v", err)
+		}
+	}
+}
+
+// forecastSessions lists the classes the schedules put on day, without storing
+// them, for dates past classSessionsGeneratedUntil. They have no id; status is
+// "cancelled" on days cancelled for the subscription, else "scheduled". Classes
+// already stored for the day (e.g. by a sync over a longer range) are left to
+// the stored row. With teacherID set, only that teacher's classes are listed.
+func forecastSessions(day time.Time, teacherID string) ([]gin.H, error) {
+	planned, err := planClassSessions(day, teacherID)
+	if err != nil {
+		return nil, err
+	}
+
+	date := day.Format("2006-01-02")
+	cancelled := map[int]bool{}
+	rows, err := db.Query("SELECT subscription_id FROM mentor.class_cancellations WHERE date = $1", date)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var subId int
+		if rows.Scan(&subId) == nil {
+			cancelled[subId] = true
+		}
+	}
+	rows.Close()
+
+	type classKey struct {
+		subId   int
+		subject string
+	}
+	stored := map[classKey]bool{}
+	rows, err = db.Query(`
+		SELECT subscription_id, subject FROM mentor.class_sessions
+		WHERE session_date = $1 AND rescheduled_from IS NULL
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var k classKey
+		if rows.Scan(&k.subId, &k.subject) == nil {
+			stored[k] = true
+		}
+	}
+	rows.Close()
+
+	sessions := []gin.H{}
+	for _, p := range planned {
+		if stored[classKey{p.SubscriptionID, p.Subject}] {
+			continue
+		}
+		status := "scheduled"
+		if cancelled[p.SubscriptionID] {
+			status = "cancelled"
+		}
+		session := gin.H{
+			"subscription_id": p.SubscriptionID,
+			"date":            date,
+			"subject":         p.Subject,
+			"time":            p.Time,
+			"status":          status,
+			"forecast":        true,
+		}
+		addSessionTimes(session, day, p.Time, p.Minutes, teacherLocation(p.TeacherID))
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// forecastSessionsBetween is forecastSessions for the days of [from, to] past
+// classSessionsGeneratedUntil, each with the student's name and class
+func forecastSessionsBetween(from, to time.Time, teacherID string) ([]gin.H, error) {
+	if start := classSessionsGeneratedUntil().AddDate(0, 0, 1); from.Before(start) {
+		from = start
+	}
+
+	students := map[int]gin.H{}
+	sessions := []gin.H{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		forecast, err := forecastSessions(day, teacherID)
+		if err != nil {
+			return nil, err
+		}
+		for _, session := range forecast {
+			subId := session["subscription_id"].(int)
+			student, ok := students[subId]
+			if !ok {
+				var name string
+				var class int
+				db.QueryRow("SELECT student_name, class FROM mentor.subscriptions WHERE id = $1", subId).Scan(&name, &class)
+				student = gin.H{"student_name": name, "class": class}
+				students[subId] = student
+			}
+			session["student_name"] = student["student_name"]
+			session["class"] = student["class"]
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// teacherSessionOnSQL matches subscriptions (aliased s) where teacher $1 has a
+// session that isn't cancelled on date $2
+const teacherSessionOnSQL = `s.id IN (
+		SELECT cs.subscription_id FROM mentor.class_sessions cs
+		WHERE cs.teacher_id = $1 AND cs.session_date = $2 AND cs.status <> 'cancelled'
+	)`
+
+// teacherSessionsBetween returns a teacher's sessions for [from, to] keyed by
+// subscription, by date then subject order. Cancelled sessions are left out;
+// rescheduled ones stay so the app can show where they moved.
+func teacherSessionsBetween(teacherID string, from, to time.Time) (map[int][]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT cs.id, cs.subscription_id, cs.session_date, cs.subject, COALESCE(cs.session_time, ''), cs.status,
+		       moved_to.session_date, moved_from.session_date, COALESCE(cs.reschedule_reason, ''),
+		       COALESCE(cs.duration_minutes, 0)
+		FROM mentor.class_sessions cs
+		LEFT JOIN mentor.class_sessions moved_to ON moved_to.id = cs.rescheduled_to
+		LEFT JOIN mentor.class_sessions moved_from ON moved_from.id = cs.rescheduled_from
+		WHERE cs.teacher_id = $1 AND cs.session_date BETWEEN $2 AND $3 AND cs.status <> 'cancelled'
+		ORDER BY cs.subscription_id, cs.session_date, cs.id
+	`, teacherID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loc := teacherLocation(teacherID)
+	sessions := map[int][]gin.H{}
+	for rows.Next() {
+		var id, subId, minutes int
+		var sessionDate time.Time
+		var subject, sessionTime, status, reason string
+		var movedTo, movedFrom sql.NullTime
+		if err := rows.Scan(&id, &subId, &sessionDate, &subject, &sessionTime, &status, &movedTo, &movedFrom, &reason, &minutes); err != nil {
+			continue
+		}
+		session := gin.H{
+			"id":      id,
+			"date":    sessionDate.Format("2006-01-02"),
+			"subject": subject,
+			"time":    sessionTime,
+			"status":  status,
+		}
+		addSessionTimes(session, sessionDate, sessionTime, minutes, loc)
+		if movedTo.Valid {
+			session["rescheduled_to"] = movedTo.Time.Format("2006-01-02")
+		}
+		if movedFrom.Valid {
+			session["rescheduled_from"] = movedFrom.Time.Format("2006-01-02")
+		}
+		if reason != "" {
+			session["reschedule_reason"] = reason
+		}
+		sessions[subId] = append(sessions[subId], session)
+	}
+	return sessions, nil
+}
+
+// orEmpty keeps a missing session list as [] in JSON
+func orEmpty(items []gin.H) []gin.H {
+	if items == nil {
+		return []gin.H{}
+	}
+	return items
+}
+
+// todayClassSession finds the teacher's first session today (in the teacher's
+// time zone) for a subscription, generating that day's sessions if they aren't
+// there yet
+func todayClassSession(subId int, teacherID string) sql.NullInt64 {
+	today := teacherToday(teacherID)
+	var id sql.NullInt64
+	find := func() error {
+		return db.QueryRow(`
+			SELECT id FROM mentor.class_sessions
+			WHERE subscription_id = $1 AND teacher_id = $2 AND session_date = $3
+			  AND status NOT IN ('cancelled', 'rescheduled')
+			ORDER BY id LIMIT 1
+		`, subId, teacherID, today.Format("2006-01-02")).Scan(&id)
+	}
+	if find() == sql.ErrNoRows && teacherID != "" {
+		if syncClassSessions(today, today, teacherID) == nil {
+			find()
+		}
+	}
+	return id
+}
+
+// classSessionOn finds the teacher's first session for a subscription on a
+// date (YYYY-MM-DD), without generating any
+func classSessionOn(subId int, teacherID, date string) sql.NullInt64 {
+	var id sql.NullInt64
+	db.QueryRow(`
+		SELECT id FROM mentor.class_sessions
+		WHERE subscription_id = $1 AND teacher_id = $2 AND session_date = $3
+		  AND status NOT IN ('cancelled', 'rescheduled')
+		ORDER BY id LIMIT 1
+	`, subId, teacherID, date).Scan(&id)
+	return id
+}
+
+// sessionSubjects lists the subjects of a subscription's sessions, in order
+func sessionSubjects(sessions []gin.H) []string {
+	subjects := []string{}
+	for _, s := range sessions {
+		subjects = append(subjects, s["subject"].(string))
+	}
+	return subjects
+}
+
+// completeClassSession marks today's session for a subject done (today in the
+// teacher's time zone) and links the progress row. A class taught off-schedule
+// gets a completed session of its own.
+func completeClassSession(subId string, subject, teacherID string, progressID sql.NullInt64) (int, error) {
+	today := teacherToday(teacherID).Format("2006-01-02")
+	var id int
+	err := db.QueryRow(`
+		UPDATE mentor.class_sessions
+		SET status = 'completed', progress_id = $3, completed_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM mentor.class_sessions
+			WHERE subscription_id = $1 AND session_date = $4 AND subject = $2 AND status = 'scheduled'
+			ORDER BY id LIMIT 1
+		)
+		RETURNING id
+	`, subId, subject, progressID, today).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	err = db.QueryRow(`
+		INSERT INTO mentor.class_sessions (subscription_id, session_date, session_time, subject, teacher_id, status, progress_id, completed_at)
+		SELECT s.id, $5::date, s.time, $2, COALESCE(NULLIF($3, ''), s.teacher_id), 'completed', $4, NOW()
+		FROM mentor.subscriptions s WHERE s.id = $1
+		ON CONFLICT (subscription_id, session_date, subject) WHERE rescheduled_from IS NULL DO UPDATE
+		SET status = 'completed', progress_id = EXCLUDED.progress_id, completed_at = NOW(), updated_at = NOW()
+		RETURNING id
+	`, subId, subject, teacherID, progressID, today).Scan(&id)
+	return id, err
+}
+
+// getClassSessions - Planned sessions by teacher or subscription over a date range
+func getClassSessions(c *gin.Context) {
+	teacherID := c.Query("teacher_id")
+	subscriptionID := c.Query("subscription_id")
+	if teacherID == "" && subscriptionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "teacher_id or subscription_id is required"})
+		return
+	}
+
+	from, to, ok := parseDateRange(c, 1)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	query := `
+		SELECT cs.id, cs.subscription_id, s.student_name, cs.session_date, COALESCE(cs.session_time, ''),
+		       cs.subject, COALESCE(cs.teacher_id, ''), cs.status, cs.completed_at, COALESCE(cs.duration_minutes, 0)
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		WHERE cs.session_date BETWEEN $1 AND $2
+	`
+	args := []interface{}{from, to}
+	argCount := 2
+
+	if teacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND cs.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if subscriptionID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND cs.subscription_id = $%d", argCount)
+		args = append(args, subscriptionID)
+	}
+	query += " ORDER BY cs.session_date, cs.session_time, cs.subscription_id, cs.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	locations := map[string]*time.Location{}
+	var sessions []gin.H
+	for rows.Next() {
+		var id, subId, minutes int
+		var studentName, sessionTime, subject, teacher, status string
+		var sessionDate time.Time
+		var completedAt sql.NullTime
+		if err := rows.Scan(&id, &subId, &studentName, &sessionDate, &sessionTime, &subject, &teacher, &status, &completedAt, &minutes); err != nil {
+			continue
+		}
+		session := gin.H{
+			"id":              id,
+			"subscription_id": subId,
+			"student_name":    studentName,
+			"date":            sessionDate.Format("2006-01-02"),
+			"time":            sessionTime,
+			"subject":         subject,
+			"teacher_id":      teacher,
+			"status":          status,
+		}
+		if _, ok := locations[teacher]; !ok {
+			locations[teacher] = teacherLocation(teacher)
+		}
+		addSessionTimes(session, sessionDate, sessionTime, minutes, locations[teacher])
+		if completedAt.Valid {
+			session["completed_at"] = isoTimestamp(completedAt.Time)
+		}
+		sessions = append(sessions, session)
+	}
+
+	// Days past the sync job's horizon have nothing stored yet
+	forecast, err := forecastSessionsBetween(from, to, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for _, session := range forecast {
+		if subscriptionID != "" && strconv.Itoa(session["subscription_id"].(int)) != subscriptionID {
+			continue
+		}
+		delete(session, "class")
+		sessions = append(sessions, session)
+	}
+
+	if sessions == nil {
+		sessions = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "sessions": sessions})
+}
+
+// rescheduleClassSession - Move one session to another date and/or time. The
+// original stays as "rescheduled" pointing at the new session, so both days
+// show the move. Teachers move their own sessions and guardians their
+// student's; admins any.
+func rescheduleClassSession(c *gin.Context) {
+	id := c.Param("id")
+
+	admin, callerTeacher, callerSub, ok := sessionCaller(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Teacher, student or admin token required"})
+		return
+	}
+
+	var input struct {
+		Date          string `json:"date"` // YYYY-MM-DD
+		Time          string `json:"time"` // optional, e.g. "5:00 PM"; defaults to the current time
+		Reason        string `json:"reason"`
+		RescheduledBy string `json:"rescheduled_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	newDate, err := time.ParseInLocation("2006-01-02", input.Date, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	var subId, minutes int
+	var subject, teacherID, sessionTime, status string
+	var sessionDate time.Time
+	err = db.QueryRow(`
+		SELECT subscription_id, subject, COALESCE(teacher_id, ''), COALESCE(session_time, ''), status, session_date,
+		       COALESCE(duration_minutes, 0)
+		FROM mentor.class_sessions WHERE id = $1
+	`, id).Scan(&subId, &subject, &teacherID, &sessionTime, &status, &sessionDate, &minutes)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+	if (callerTeacher != "" && callerTeacher != teacherID) || (callerSub != 0 && callerSub != subId) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "You can only reschedule your own sessions"})
+		return
+	}
+	if !admin {
+		input.RescheduledBy = callerTeacher
+		if callerSub != 0 {
+			input.RescheduledBy = "guardian"
+		}
+	}
+	if status != "scheduled" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Only scheduled sessions can be rescheduled (this one is " + status + ")"})
+		return
+	}
+	if newDate.Before(teacherToday(teacherID)) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Can't move a session into the past"})
+		return
+	}
+
+	newTime := sessionTime
+	if input.Time != "" {
+		parsed, ok := parseClassTime(input.Time)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": `time must look like "5:00 PM"`})
+			return
+		}
+		newTime = parsed
+	}
+	if newDate.Equal(sessionDate) && newTime == sessionTime {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "New date and time are the same as the current ones"})
+		return
+	}
+
+	if holidayName, ok := holidayOn(input.Date, teacherID); ok {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": input.Date + " is a holiday (" + holidayName + ")"})
+		return
+	}
+
+	if teacherID != "" {
+		if start, ok := sessionStart(newDate, newTime, teacherLocation(teacherID)); ok {
+			end := start.Add(time.Duration(classMinutesOr(minutes)) * time.Minute)
+			busy, err := googleBusyPeriods(teacherID, start, end)
+			if err != nil {
+				log.Printf("Google Calendar busy check for teacher %s failed: %v", teacherID, err)
+			}
+			if p, ok := busyDuring(busy, start, end); ok {
+				c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Teacher's calendar is busy then (" + p.Summary + ")"})
+				return
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var newID int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.class_sessions (subscription_id, session_date, session_time, subject, teacher_id, status, rescheduled_from, reschedule_reason,
+		                                   duration_minutes)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), 'scheduled', $6, NULLIF($7, ''), NULLIF($8, 0))
+		RETURNING id
+	`, subId, input.Date, newTime, subject, teacherID, id, input.Reason, minutes).Scan(&newID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := tx.Exec(`
+		UPDATE mentor.class_sessions
+		SET status = 'rescheduled', rescheduled_to = $1, reschedule_reason = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $3 AND status = 'scheduled'
+	`, newID, input.Reason, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Session changed meanwhile; reload and try again"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	from := sessionDate.Format("2006-01-02") + " " + sessionTime
+	to := input.Date + " " + newTime
+	logAudit("subscription", subId, "session_rescheduled", input.RescheduledBy, gin.H{
+		"session_id":     id,
+		"new_session_id": newID,
+		"subject":        subject,
+		"moved":          subject + " " + from + " → " + to,
+		"reason":         input.Reason,
+	})
+	syncGoogleCalendarLater(teacherID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"session_id":     newID,
+		"rescheduled_to": gin.H{"date": input.Date, "time": newTime},
+		"message":        "Session rescheduled",
+	})
+}
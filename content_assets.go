@@ -0,0 +1,432 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// CONTENT ASSETS (PDFs, images, audio, video for chapters)
+// ============================================
+
+// contentAssetType is an accepted upload, by content type
+type contentAssetType struct {
+	kind     string
+	ext      string
+	maxBytes int
+}
+
+var contentAssetTypes = map[string]contentAssetType{
+	"application/pdf": {"pdf", ".pdf", 25 << 20},
+	"image/jpeg":      {"image", ".jpg", 10 << 20},
+	"image/png":       {"image", ".png", 10 << 20},
+	"image/webp":      {"image", ".webp", 10 << 20},
+	"image/gif":       {"image", ".gif", 10 << 20},
+	"audio/mpeg":      {"audio", ".mp3", 50 << 20},
+	"audio/mp4":       {"audio", ".m4a", 50 << 20},
+	"audio/aac":       {"audio", ".aac", 50 << 20},
+	"audio/wave":      {"audio", ".wav", 50 << 20},
+	"audio/ogg":       {"audio", ".ogg", 50 << 20},
+	"application/ogg": {"audio", ".ogg", 50 << 20},
+	"video/mp4":       {"video", ".mp4", 200 << 20},
+	"video/webm":      {"video", ".webm", 200 << 20},
+}
+
+const contentAssetMaxBytes = 200 << 20
+
+// contentAssetOrphanAge is how long an upload may sit unreferenced before
+// cleanup removes it, so editors can upload first and save the chapter later
+const contentAssetOrphanAge = 24 * time.Hour
+
+// contentAssetReferencedSQL is true when any chapter's content_json has an
+// "asset_id" (at any depth) equal to the asset aliased a, or a chapter video
+// plays it
+const contentAssetReferencedSQL = `(EXISTS (
+	SELECT 1 FROM mentor.content ct
+	WHERE jsonb_path_exists(ct.content_json, '$.** ? (@.asset_id == $id)', jsonb_build_object('id', a.id))
+) OR EXISTS (SELECT 1 FROM mentor.chapter_videos v WHERE v.asset_id = a.id))`
+
+// contentAssetURL is the stable link for an asset; it redirects to a
+// short-lived signed storage URL
+func contentAssetURL(c *gin.Context, id string) string {
+	return publicBaseURL(c) + "/api/content/assets/" + id
+}
+
+// sniffContentAsset picks the asset type from the file bytes, falling back to
+// the declared audio type since many MP3 files have no sniffable header and
+// M4A audio sniffs as MP4 video
+func sniffContentAsset(data []byte, declared string) (string, contentAssetType, bool) {
+	contentType := http.DetectContentType(data)
+	t, ok := contentAssetTypes[contentType]
+	if declared, _, err := mime.ParseMediaType(declared); err == nil && strings.HasPrefix(declared, "audio/") {
+		if audio, known := contentAssetTypes[declared]; known && (!ok || t.kind == "video") {
+			return declared, audio, true
+		}
+	}
+	return contentType, t, ok
+}
+
+// uploadContentAsset - Upload a PDF, image or audio file (multipart `file`)
+// for use in chapter content; identical files return the existing asset
+func uploadContentAsset(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, contentAssetMaxBytes+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file is required (multipart form field 'file')"})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, contentAssetMaxBytes+1))
+	f.Close()
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file is empty or unreadable"})
+		return
+	}
+
+	contentType, assetType, ok := sniffContentAsset(data, header.Header.Get("Content-Type"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file must be a PDF, image (JPEG, PNG, WebP, GIF), audio (MP3, M4A, AAC, WAV, OGG) or video (MP4, WebM)"})
+		return
+	}
+	if len(data) > assetType.maxBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("%s files must be at most %d MB", assetType.kind, assetType.maxBytes>>20)})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	// Reusing an orphaned asset restarts its grace period, so the cleanup job
+	// doesn't delete it before the chapter that uploaded it again is saved
+	var existingID string
+	if err := db.QueryRow(`
+		UPDATE mentor.content_assets a
+		SET created_at = CASE WHEN `+contentAssetReferencedSQL+` THEN a.created_at ELSE NOW() END
+		WHERE a.sha256 = $1
+		RETURNING a.id
+	`, hash).Scan(&existingID); err == nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "asset": loadContentAsset(c, existingID), "duplicate": true})
+		return
+	}
+
+	store := getObjectStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Object storage is not configured"})
+		return
+	}
+
+	uploadedBy := c.PostForm("uploaded_by")
+	if teacherID := c.GetString("teacher_id"); teacherID != "" {
+		uploadedBy = teacherID
+	}
+
+	id := randomID() + randomID()
+	key := fmt.Sprintf("content/%s/%s%s", assetType.kind, id, assetType.ext)
+	if err := store.Put(key, contentType, data); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Upload failed: " + err.Error()})
+		return
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO mentor.content_assets (id, kind, filename, object_key, content_type, size_bytes, sha256, uploaded_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, NULLIF($8, ''))
+	`, id, assetType.kind, filepath.Base(header.Filename), key, contentType, len(data), hash, uploadedBy)
+
+	if err != nil {
+		store.Delete(key)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("content_asset", id, "uploaded", uploadedBy, gin.H{"kind": assetType.kind, "size_bytes": len(data)})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "asset": loadContentAsset(c, id)})
+}
+
+// queryContentAssets lists assets with their stable URL and whether any
+// chapter references them
+func queryContentAssets(c *gin.Context, where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.kind, COALESCE(a.filename, ''), a.content_type, a.size_bytes,
+		       COALESCE(a.uploaded_by, ''), a.created_at, `+contentAssetReferencedSQL+`
+		FROM mentor.content_assets a
+		WHERE `+where+`
+		ORDER BY a.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []gin.H{}
+	for rows.Next() {
+		var id, kind, filename, contentType, uploadedBy string
+		var size int
+		var createdAt time.Time
+		var referenced bool
+		if err := rows.Scan(&id, &kind, &filename, &contentType, &size, &uploadedBy, &createdAt, &referenced); err != nil {
+			continue
+		}
+		assets = append(assets, gin.H{
+			"id":           id,
+			"kind":         kind,
+			"filename":     filename,
+			"content_type": contentType,
+			"size_bytes":   size,
+			"uploaded_by":  uploadedBy,
+			"created_at":   isoTimestamp(createdAt),
+			"referenced":   referenced,
+			"url":          contentAssetURL(c, id),
+		})
+	}
+	return assets, nil
+}
+
+// loadContentAsset returns one asset, or nil when it does not exist
+func loadContentAsset(c *gin.Context, id string) gin.H {
+	assets, err := queryContentAssets(c, "a.id = $1", id)
+	if err != nil || len(assets) == 0 {
+		return nil
+	}
+	return assets[0]
+}
+
+// getContentAssets - Uploaded assets (?kind=, ?orphaned=true for unreferenced ones)
+func getContentAssets(c *gin.Context) {
+	where := "1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	if kind := c.Query("kind"); kind != "" {
+		argCount++
+		where += fmt.Sprintf(" AND a.kind = $%d", argCount)
+		args = append(args, kind)
+	}
+	if c.Query("orphaned") == "true" {
+		where += " AND NOT " + contentAssetReferencedSQL
+	}
+
+	assets, err := queryContentAssets(c, where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "assets": assets})
+}
+
+// serveContentAsset - The stable asset URL: redirects to a signed storage link
+// (?format=json returns the asset details instead)
+func serveContentAsset(c *gin.Context) {
+	id := c.Param("id")
+
+	if c.Query("format") == "json" {
+		asset := loadContentAsset(c, id)
+		if asset == nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Asset not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "asset": asset})
+		return
+	}
+
+	var key string
+	err := db.QueryRow("SELECT object_key FROM mentor.content_assets WHERE id = $1", id).Scan(&key)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	store := getObjectStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Object storage is not configured"})
+		return
+	}
+
+	// Cache the redirect for less than the signed link's lifetime
+	c.Header("Cache-Control", "private, max-age=600")
+	c.Redirect(http.StatusFound, store.PresignGet(key, 15*time.Minute))
+}
+
+// deleteContentAsset - Delete an asset; refused while a chapter still references it
+func deleteContentAsset(c *gin.Context) {
+	id := c.Param("id")
+
+	rows, err := db.Query(`
+		SELECT class, subject, chapter_number FROM mentor.content
+		WHERE jsonb_path_exists(content_json, '$.** ? (@.asset_id == $id)', jsonb_build_object('id', $1::text))
+		UNION
+		SELECT class, subject, chapter_number FROM mentor.chapter_videos WHERE asset_id = $1
+		ORDER BY 1, 2, 3
+	`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	chapters := []gin.H{}
+	for rows.Next() {
+		var class, chapter int
+		var subject string
+		if err := rows.Scan(&class, &subject, &chapter); err == nil {
+			chapters = append(chapters, gin.H{"class": class, "subject": subject, "chapter_number": chapter})
+		}
+	}
+	rows.Close()
+
+	if len(chapters) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Asset is still used by chapter content", "chapters": chapters})
+		return
+	}
+
+	var key string
+	err = db.QueryRow("DELETE FROM mentor.content_assets WHERE id = $1 RETURNING object_key", id).Scan(&key)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if store := getObjectStore(); store != nil {
+		store.Delete(key)
+	}
+
+	logAudit("content_asset", id, "deleted", c.Query("deleted_by"), nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Asset deleted"})
+}
+
+// cleanupOrphanContentAssets deletes assets that no chapter references and
+// that are older than the grace period; dryRun only lists them
+func cleanupOrphanContentAssets(dryRun bool) ([]string, error) {
+	query := `SELECT a.id, a.object_key FROM mentor.content_assets a
+		WHERE a.created_at < $1 AND NOT ` + contentAssetReferencedSQL
+	if !dryRun {
+		query = `DELETE FROM mentor.content_assets a
+			WHERE a.created_at < $1 AND NOT ` + contentAssetReferencedSQL + `
+			RETURNING a.id, a.object_key`
+	}
+
+	rows, err := db.Query(query, time.Now().Add(-contentAssetOrphanAge))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	store := getObjectStore()
+	ids := []string{}
+	for rows.Next() {
+		var id, key string
+		if err := rows.Scan(&id, &key); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		if !dryRun && store != nil {
+			store.Delete(key)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// cleanupContentAssets - Remove orphaned assets now (?dry_run=true to preview)
+func cleanupContentAssets(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	ids, err := cleanupOrphanContentAssets(dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "dry_run": dryRun, "count": len(ids), "asset_ids": ids})
+}
+
+// contentAssetIDs collects every "asset_id" string in a content_json tree
+func contentAssetIDs(node interface{}, ids map[string]bool) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if id, ok := child.(string); ok && k == "asset_id" && id != "" {
+				ids[id] = true
+				continue
+			}
+			contentAssetIDs(child, ids)
+		}
+	case []interface{}:
+		for _, child := range v {
+			contentAssetIDs(child, ids)
+		}
+	}
+}
+
+// missingContentAssets returns referenced asset ids that were never uploaded
+func missingContentAssets(content interface{}) ([]string, error) {
+	ids := map[string]bool{}
+	contentAssetIDs(content, ids)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	wanted := make([]string, 0, len(ids))
+	for id := range ids {
+		wanted = append(wanted, id)
+	}
+
+	rows, err := db.Query("SELECT id FROM mentor.content_assets WHERE id = ANY($1)", pq.Array(wanted))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			delete(ids, id)
+		}
+	}
+
+	missing := []string{}
+	for id := range ids {
+		missing = append(missing, id)
+	}
+	return missing, rows.Err()
+}
+
+// resolveContentAssets adds an "asset_url" next to every "asset_id" so the
+// app can fetch the file directly
+func resolveContentAssets(c *gin.Context, node interface{}) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if id, ok := v["asset_id"].(string); ok && id != "" {
+			v["asset_url"] = contentAssetURL(c, id)
+		}
+		for _, child := range v {
+			resolveContentAssets(c, child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			resolveContentAssets(c, child)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CONTENT COPY (across classes and subjects)
+// ============================================
+
+// chapterRef identifies a chapter by class, subject and number
+type chapterRef struct {
+	Class         int    `json:"class"`
+	Subject       string `json:"subject"`
+	ChapterNumber int    `json:"chapter_number"`
+}
+
+// copyContent - Clone a chapter's title and content_json to another class,
+// subject or chapter (e.g. a parallel board). The copy is a draft unless an
+// admin sends as_draft=false; an existing target is only replaced with overwrite=true.
+func copyContent(c *gin.Context) {
+	var input struct {
+		From         chapterRef `json:"from"`
+		To           chapterRef `json:"to"`
+		ChapterTitle *string    `json:"chapter_title"` // defaults to the source title
+		AsDraft      *bool      `json:"as_draft"`      // default true
+		Overwrite    bool       `json:"overwrite"`
+		CopiedBy     string     `json:"copied_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	fields := map[string]string{}
+	for prefix, ref := range map[string]*chapterRef{"from": &input.From, "to": &input.To} {
+		ref.Subject = strings.TrimSpace(ref.Subject)
+		if ref.Class < 1 || ref.Class > 12 {
+			fields[prefix+".class"] = "must be between 1 and 12"
+		}
+		if ref.Subject == "" {
+			fields[prefix+".subject"] = "is required"
+		}
+		if ref.ChapterNumber < 1 {
+			fields[prefix+".chapter_number"] = "must be 1 or more"
+		}
+	}
+	if input.From == input.To {
+		fields["to"] = "must differ from the source chapter"
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	asDraft := input.AsDraft == nil || *input.AsDraft
+	if !asDraft && !isAdminRequest(c) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Only admins can copy without review (as_draft=false)"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var sourceID int
+	var sourceTitle, sourceStatus string
+	err = tx.QueryRow(`
+		SELECT id, COALESCE(chapter_title, ''), status FROM mentor.content
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3
+	`, input.From.Class, input.From.Subject, input.From.ChapterNumber).Scan(&sourceID, &sourceTitle, &sourceStatus)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Source chapter not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	title := sourceTitle
+	if input.ChapterTitle != nil {
+		title = strings.TrimSpace(*input.ChapterTitle)
+	}
+	status := "draft"
+	if !asDraft {
+		status = sourceStatus
+	}
+
+	var targetID int
+	var inserted bool
+	err = tx.QueryRow(`
+		INSERT INTO mentor.content (class, subject, chapter_number, chapter_title, content_json, status, copied_from_id,
+		                            published_at)
+		SELECT $1, $2, $3, NULLIF($4, ''), content_json, $5, id, CASE WHEN $5 = 'published' THEN NOW() END
+		FROM mentor.content WHERE id = $6
+		ON CONFLICT (class, subject, chapter_number) DO UPDATE SET
+			chapter_title = EXCLUDED.chapter_title,
+			content_json = EXCLUDED.content_json,
+			status = EXCLUDED.status,
+			copied_from_id = EXCLUDED.copied_from_id,
+			published_at = COALESCE(EXCLUDED.published_at, mentor.content.published_at),
+			updated_at = NOW()
+		WHERE $7
+		RETURNING id, (xmax = 0)
+	`, input.To.Class, input.To.Subject, input.To.ChapterNumber, title, status, sourceID, input.Overwrite).Scan(&targetID, &inserted)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Target chapter already has content; send overwrite=true to replace it"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("content", targetID, "copied", input.CopiedBy, gin.H{"from": input.From, "to": input.To, "status": status, "overwrote": !inserted})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"id":             targetID,
+		"copied_from_id": sourceID,
+		"status":         status,
+		"overwritten":    !inserted,
+		"message":        "Chapter copied",
+	})
+}
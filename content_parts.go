@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CHAPTER CONTENT PARTS (one per class session)
+// ============================================
+
+// partsPerChapter is how many class sessions a chapter is taught over, from
+// the syllabus; the schedule's current_part runs from 1 to this
+func partsPerChapter(class int, subject string) int {
+	parts := 3
+	db.QueryRow(`
+		SELECT COALESCE(parts_per_chapter, 3) FROM mentor.chapters WHERE class = $1 AND subject = $2
+	`, class, subject).Scan(&parts)
+	if parts < 1 {
+		parts = 3
+	}
+	return parts
+}
+
+// contentPartURL deep-links to what should be taught in one class session
+func contentPartURL(c *gin.Context, class int, subject string, chapter, part int) string {
+	return publicBaseURL(c) + fmt.Sprintf("/api/content/%d/%s/%d/part/%d", class, url.PathEscape(subject), chapter, part)
+}
+
+// contentSectionPart reads a section's "part" tag; tagged is false when the
+// section has none
+func contentSectionPart(section interface{}) (part int, tagged bool) {
+	m, ok := section.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	v, ok := m["part"]
+	if !ok || v == nil {
+		return 0, false
+	}
+	n, ok := v.(float64)
+	if !ok || n != math.Trunc(n) {
+		return -1, true
+	}
+	return int(n), true
+}
+
+// invalidContentParts lists the indexes of sections whose "part" isn't a
+// whole number from 1 to parts
+func invalidContentParts(content interface{}, parts int) []int {
+	m, _ := content.(map[string]interface{})
+	sections, _ := m["sections"].([]interface{})
+	invalid := []int{}
+	for i, s := range sections {
+		if part, tagged := contentSectionPart(s); tagged && (part < 1 || part > parts) {
+			invalid = append(invalid, i)
+		}
+	}
+	return invalid
+}
+
+// splitContentParts groups a chapter's sections into parts. Sections tagged
+// with "part" go to that part and untagged ones follow the section before
+// them; with no tags at all the sections are shared out evenly in order.
+func splitContentParts(sections []interface{}, parts int) ([][]interface{}, bool) {
+	split := make([][]interface{}, parts)
+	for i := range split {
+		split[i] = []interface{}{}
+	}
+
+	tagged := false
+	for _, s := range sections {
+		if _, ok := contentSectionPart(s); ok {
+			tagged = true
+			break
+		}
+	}
+
+	current := 1
+	for i, s := range sections {
+		part := i*parts/len(sections) + 1
+		if tagged {
+			part = current
+			if p, ok := contentSectionPart(s); ok && p >= 1 {
+				part = p
+			}
+		}
+		if part > parts {
+			part = parts
+		}
+		current = part
+		split[part-1] = append(split[part-1], s)
+	}
+	return split, tagged
+}
+
+// getContentPart - The sections of a chapter to teach in one class session,
+// so the today-schedule screen can open the schedule's current_part directly
+func getContentPart(c *gin.Context) {
+	class, _ := strconv.Atoi(c.Param("class"))
+	subject := c.Param("subject")
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+	part, err := strconv.Atoi(c.Param("part"))
+	if err != nil || part < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "part must be a positive number"})
+		return
+	}
+
+	var id int
+	var title sql.NullString
+	var contentJSON, version string
+	err = db.QueryRow(`
+		SELECT id, chapter_title, content_json::text, `+contentVersionSQL+`
+		FROM mentor.content
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3`+contentVisibilitySQL(c),
+		class, subject, chapter).Scan(&id, &title, &contentJSON, &version)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Chapter content not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	parts := partsPerChapter(class, subject)
+	if part > parts {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":     false,
+			"error":       fmt.Sprintf("Chapter is taught in %d parts", parts),
+			"total_parts": parts,
+		})
+		return
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal([]byte(contentJSON), &content); err != nil {
+		content = map[string]interface{}{"sections": []interface{}{}}
+	}
+	resolveContentAssets(c, content)
+	sections, _ := content["sections"].([]interface{})
+	split, tagged := splitContentParts(sections, parts)
+
+	splitBy := "even"
+	if tagged {
+		splitBy = "tagged"
+	}
+
+	result := gin.H{
+		"id":             id,
+		"class":          class,
+		"subject":        subject,
+		"chapter_number": chapter,
+		"chapter_title":  title.String,
+		"part":           part,
+		"total_parts":    parts,
+		"split":          splitBy,
+		"sections":       split[part-1],
+		"version":        version,
+	}
+	if part > 1 {
+		result["previous_url"] = contentPartURL(c, class, subject, chapter, part-1)
+	}
+	if part < parts {
+		result["next_url"] = contentPartURL(c, class, subject, chapter, part+1)
+	}
+
+	// The split also depends on parts_per_chapter, so it's part of the ETag
+	writeContentResponse(c, fmt.Sprintf("%s-%d-%d", version, part, parts), gin.H{
+		"success": true,
+		"content": result,
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CHAPTER CONTENT PDF (printable handout)
+// ============================================
+
+// contentPDFKeyOrder is the order fields of a content object are printed in;
+// other keys follow alphabetically
+var contentPDFKeyOrder = []string{
+	"title", "heading", "name", "subtitle",
+	"text", "content", "body", "description", "explanation", "definition",
+	"question", "problem", "options", "steps", "solution", "answer", "hint",
+}
+
+// contentPDFHeadingKeys are printed bold as the object's heading
+var contentPDFHeadingKeys = map[string]bool{"title": true, "heading": true, "name": true}
+
+// contentPDFLabels prefix values whose meaning isn't obvious from the layout
+var contentPDFLabels = map[string]string{
+	"question": "Q: ", "problem": "Q: ", "answer": "Answer: ", "solution": "Solution: ", "hint": "Hint: ",
+	"subtitle": "", "definition": "Definition: ",
+}
+
+// contentPDFSkipKeys are ids, links and layout hints with nothing to print;
+// mentor.content_text (migration 078) leaves the same keys out of search
+var contentPDFSkipKeys = map[string]bool{
+	"id": true, "type": true, "asset_id": true, "asset_url": true, "url": true, "image": true,
+	"image_url": true, "audio_url": true, "video_url": true, "order": true, "position": true,
+}
+
+// contentPDFWriter lays out content_json top to bottom, adding pages as needed
+type contentPDFWriter struct {
+	pdf      *pdfDoc
+	y        float64
+	subtitle string
+}
+
+const contentPDFLeft = 40.0
+
+func (w *contentPDFWriter) ensure(height float64) {
+	if w.y+height > pdfPageHeight-70 {
+		handoutFooter(w.pdf, "Chapter notes")
+		w.pdf.AddPage()
+		w.y = documentHeader(w.pdf, "CHAPTER", w.subtitle)
+	}
+}
+
+// paragraph writes wrapped text at indent
+func (w *contentPDFWriter) paragraph(text string, size float64, bold bool, indent float64) {
+	lineHeight := size * 1.4
+	for _, line := range pdfWrap(text, size, bold, pdfPageWidth-2*contentPDFLeft-indent) {
+		w.ensure(lineHeight)
+		w.pdf.Text(contentPDFLeft+indent, w.y, size, bold, line)
+		w.y += lineHeight
+	}
+}
+
+// orderedKeys sorts an object's keys by contentPDFKeyOrder, then by name
+func orderedKeys(m map[string]interface{}) []string {
+	rank := func(k string) int {
+		for i, known := range contentPDFKeyOrder {
+			if k == known {
+				return i
+			}
+		}
+		return len(contentPDFKeyOrder)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := rank(keys[i]), rank(keys[j]); ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// itemLabel names the i-th entry of a list such as "examples" ("Example 2")
+func itemLabel(key string, i int) string {
+	name := strings.TrimSuffix(strings.ReplaceAll(key, "_", " "), "s")
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%s %d", strings.ToUpper(name[:1]), name[1:], i+1)
+}
+
+// node prints one value of content_json; depth 0 are the chapter's sections
+func (w *contentPDFWriter) node(key string, v interface{}, depth int) {
+	indent := float64(depth) * 14
+	if depth > 0 {
+		indent -= 14
+	}
+
+	switch val := v.(type) {
+	case string:
+		if strings.TrimSpace(val) == "" {
+			return
+		}
+		if contentPDFHeadingKeys[key] {
+			size := 12.0
+			if depth <= 1 {
+				size = 15
+				w.y += 6
+			}
+			w.ensure(size * 2)
+			w.paragraph(val, size, true, indent)
+			w.y += 2
+			return
+		}
+		w.paragraph(contentPDFLabels[key]+val, 11, false, indent)
+		w.y += 4
+	case float64, bool:
+		w.node(key, fmt.Sprint(val), depth)
+	case []interface{}:
+		for i, item := range val {
+			switch item := item.(type) {
+			case map[string]interface{}:
+				if label := itemLabel(key, i); key != "sections" && label != "" {
+					w.ensure(30)
+					w.paragraph(label, 11, true, indent)
+				}
+				w.object(item, depth+1)
+				w.y += 4
+			case string:
+				prefix := "- "
+				if key == "options" {
+					prefix = fmt.Sprintf("(%c) ", 'a'+i%26)
+				} else if key == "steps" {
+					prefix = fmt.Sprintf("%d. ", i+1)
+				}
+				w.paragraph(prefix+item, 11, false, indent+10)
+			default:
+				w.node(key, item, depth)
+			}
+		}
+		w.y += 4
+	case map[string]interface{}:
+		w.object(val, depth+1)
+	}
+}
+
+// object prints an object's fields in reading order
+func (w *contentPDFWriter) object(m map[string]interface{}, depth int) {
+	for _, k := range orderedKeys(m) {
+		if contentPDFSkipKeys[k] {
+			continue
+		}
+		w.node(k, m[k], depth)
+	}
+}
+
+// getContentPDF - A chapter's content as a printable PDF: sections with their
+// text, examples and exercises, plus links to the chapter's videos. Text
+// outside Latin-1 prints as "?" with the built-in PDF fonts.
+func getContentPDF(c *gin.Context) {
+	class, _ := strconv.Atoi(c.Param("class"))
+	subject := c.Param("subject")
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+
+	var title sql.NullString
+	var contentJSON string
+	err := db.QueryRow(`
+		SELECT chapter_title, content_json::text FROM mentor.content
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3`+contentVisibilitySQL(c),
+		class, subject, chapter).Scan(&title, &contentJSON)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Chapter content not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal([]byte(contentJSON), &content); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Chapter content is not valid JSON"})
+		return
+	}
+
+	chapterTitle := title.String
+	if chapterTitle == "" {
+		chapterTitle = fmt.Sprintf("Chapter %d", chapter)
+	}
+
+	w := &contentPDFWriter{pdf: newPDF(), subtitle: fmt.Sprintf("Class %d %s - Chapter %d", class, subject, chapter)}
+	w.y = documentHeader(w.pdf, "CHAPTER", w.subtitle)
+	w.paragraph(chapterTitle, 18, true, 0)
+	w.y += 8
+	w.object(content, 0)
+
+	videos, _ := queryChapterVideos(c, class, subject, chapter, 0, false)
+	if len(videos) > 0 {
+		w.y += 6
+		w.ensure(40)
+		w.paragraph("Videos", 15, true, 0)
+		for _, v := range videos {
+			w.paragraph(fmt.Sprintf("%d. %s", v["position"], v["title"]), 11, true, 0)
+			w.paragraph(v["url"].(string), 10, false, 14)
+		}
+	}
+	handoutFooter(w.pdf, "Chapter notes")
+
+	sendPDF(c, fmt.Sprintf("chapter-%d-%s-%d.pdf", class, strings.ReplaceAll(strings.ToLower(subject), " ", "-"), chapter), w.pdf)
+}
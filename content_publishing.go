@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CONTENT PUBLISHING (draft -> in_review -> published)
+// ============================================
+
+var contentStatuses = map[string]bool{"draft": true, "in_review": true, "published": true}
+
+// contentVisibilitySQL limits content queries to published chapters unless
+// the request comes from an admin, so drafts never reach the app
+func contentVisibilitySQL(c *gin.Context) string {
+	if isAdminRequest(c) {
+		return ""
+	}
+	return " AND status = 'published'"
+}
+
+// submitContentForReview - Send a draft chapter for review
+func submitContentForReview(c *gin.Context) {
+	var input struct {
+		SubmittedBy string `json:"submitted_by" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	var id int
+	err := db.QueryRow(`
+		UPDATE mentor.content
+		SET status = 'in_review', submitted_by = $4, submitted_at = NOW()
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3 AND status = 'draft'
+		RETURNING id
+	`, c.Param("class"), c.Param("subject"), c.Param("chapter"), input.SubmittedBy).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Only draft chapters can be submitted for review"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("content", id, "submitted_for_review", input.SubmittedBy, nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "in_review", "message": "Chapter submitted for review"})
+}
+
+// reviewContent - Approve (publish) or reject (back to draft) a chapter in
+// review; a comment is required to reject
+func reviewContent(c *gin.Context) {
+	var input struct {
+		Action     string `json:"action" binding:"required"` // approve or reject
+		Comment    string `json:"comment"`
+		ReviewedBy string `json:"reviewed_by" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	status := ""
+	switch input.Action {
+	case "approve":
+		status = "published"
+	case "reject":
+		status = "draft"
+		if strings.TrimSpace(input.Comment) == "" {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"comment": "is required to reject"}))
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"action": "must be 'approve' or 'reject'"}))
+		return
+	}
+
+	var id int
+	err := db.QueryRow(`
+		UPDATE mentor.content
+		SET status = $4, review_comment = NULLIF($5, ''), reviewed_by = $6, reviewed_at = NOW(),
+		    published_at = CASE WHEN $4 = 'published' THEN NOW() ELSE published_at END
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3 AND status = 'in_review'
+		RETURNING id
+	`, c.Param("class"), c.Param("subject"), c.Param("chapter"), status, input.Comment, input.ReviewedBy).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Chapter is not waiting for review"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("content", id, input.Action+"d", input.ReviewedBy, gin.H{"comment": input.Comment})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": status, "message": "Chapter " + input.Action + "d"})
+}
+
+// unpublishContent - Take a published chapter out of the app, back to draft
+func unpublishContent(c *gin.Context) {
+	var id int
+	err := db.QueryRow(`
+		UPDATE mentor.content SET status = 'draft'
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3 AND status = 'published'
+		RETURNING id
+	`, c.Param("class"), c.Param("subject"), c.Param("chapter")).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Chapter is not published"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("content", id, "unpublished", c.Query("unpublished_by"), nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "draft", "message": "Chapter unpublished"})
+}
+
+// getContentReviewQueue - Chapters waiting for review, oldest submission first
+func getContentReviewQueue(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT id, class, subject, chapter_number, COALESCE(chapter_title, ''),
+		       COALESCE(submitted_by, ''), submitted_at, updated_at
+		FROM mentor.content
+		WHERE status = 'in_review'
+		ORDER BY submitted_at NULLS FIRST, class, subject, chapter_number
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	queue := []gin.H{}
+	for rows.Next() {
+		var id, class, chapter int
+		var subject, title, submittedBy string
+		var submittedAt sql.NullTime
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &class, &subject, &chapter, &title, &submittedBy, &submittedAt, &updatedAt); err != nil {
+			continue
+		}
+		item := gin.H{
+			"id":             id,
+			"class":          class,
+			"subject":        subject,
+			"chapter_number": chapter,
+			"chapter_title":  title,
+			"submitted_by":   submittedBy,
+			"updated_at":     isoTimestamp(updatedAt),
+		}
+		if submittedAt.Valid {
+			item["submitted_at"] = isoTimestamp(submittedAt.Time)
+		}
+		queue = append(queue, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "chapters": queue})
+}
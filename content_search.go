@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CONTENT SEARCH (Postgres full-text)
+// ============================================
+
+// contentTextSQL is the readable text of a chapter's content_json (no ids,
+// asset references or URLs) joined into one text, as indexed for search; see
+// migration 078
+const contentTextSQL = `mentor.content_text(content_json)`
+
+// htmlEscapeSQL escapes a text expression for HTML, so only the <b></b> that
+// ts_headline adds is markup
+func htmlEscapeSQL(expr string) string {
+	return `replace(replace(replace(` + expr + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`
+}
+
+// contentHeadlineOptions marks matches with <b></b>, which the app renders
+// as HTML
+const contentHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=\" … \""
+
+// searchContent - Chapters matching ?q= (web search syntax: "quoted phrases",
+// or, -exclude), best first, with highlighted title and snippet
+func searchContent(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "q is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	query := `
+		SELECT class, subject, chapter_number, COALESCE(chapter_title, ''), updated_at,
+		       ts_rank(search_vector, query),
+		       ts_headline('english', ` + htmlEscapeSQL(`COALESCE(chapter_title, '')`) + `, query, 'StartSel=<b>, StopSel=</b>, HighlightAll=true'),
+		       ts_headline('english', ` + htmlEscapeSQL(`COALESCE(`+contentTextSQL+`, '')`) + `, query, $2)
+		FROM mentor.content, websearch_to_tsquery('english', $1) query
+		WHERE search_vector @@ query` + contentVisibilitySQL(c)
+	args := []interface{}{q, contentHeadlineOptions}
+	argCount := 2
+
+	if classNum := c.Query("class"); classNum != "" {
+		argCount++
+		query += fmt.Sprintf(" AND class = $%d", argCount)
+		args = append(args, classNum)
+	}
+	if subject := c.Query("subject"); subject != "" {
+		argCount++
+		query += fmt.Sprintf(" AND subject = $%d", argCount)
+		args = append(args, subject)
+	}
+	argCount++
+	query += fmt.Sprintf(" ORDER BY 6 DESC, class, subject, chapter_number LIMIT $%d", argCount)
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	results := []gin.H{}
+	for rows.Next() {
+		var class, chapterNum int
+		var subject, title, titleHighlight, snippet string
+		var rank float64
+		var updatedAt time.Time
+		if err := rows.Scan(&class, &subject, &chapterNum, &title, &updatedAt, &rank, &titleHighlight, &snippet); err != nil {
+			continue
+		}
+		results = append(results, gin.H{
+			"class":           class,
+			"subject":         subject,
+			"chapter_number":  chapterNum,
+			"chapter_title":   title,
+			"title_highlight": titleHighlight,
+			"snippet":         snippet,
+			"rank":            rank,
+			"updated_at":      isoTimestamp(updatedAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "query": q, "count": len(results), "results": results})
+}
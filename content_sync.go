@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// OFFLINE CONTENT SYNC (Manifest + gzip)
+// ============================================
+
+// contentBodyVersionSQL is the version hash of a content row's title and body
+const contentBodyVersionSQL = `md5(COALESCE(chapter_title, '') || content_json::text)`
+
+// contentVersionSQL is the version hash of a chapter; it changes whenever the
+// chapter title, body or video list changes (chapters without videos keep
+// their body version)
+const contentVersionSQL = `COALESCE(md5(` + contentBodyVersionSQL + ` || (
+	SELECT string_agg(v.id || ':' || v.position || ':' || extract(epoch FROM v.updated_at), ',' ORDER BY v.position)
+	FROM mentor.chapter_videos v
+	WHERE v.class = content.class AND v.subject = content.subject AND v.chapter_number = content.chapter_number
+)), ` + contentBodyVersionSQL + `)`
+
+// getContentManifest - Every chapter with its version hash, so the app can
+// download only chapters whose version differs from its offline copy
+func getContentManifest(c *gin.Context) {
+	classNum := c.Query("class")
+	subject := c.Query("subject")
+
+	query := `SELECT class, subject, chapter_number, ` + contentVersionSQL + `,
+			  octet_length(content_json::text), updated_at
+			  FROM mentor.content WHERE 1=1` + contentVisibilitySQL(c)
+	args := []interface{}{}
+	argCount := 0
+
+	if classNum != "" {
+		argCount++
+		query += fmt.Sprintf(" AND class = $%d", argCount)
+		args = append(args, classNum)
+	}
+	if subject != "" {
+		argCount++
+		query += fmt.Sprintf(" AND subject = $%d", argCount)
+		args = append(args, subject)
+	}
+	query += " ORDER BY class, subject, chapter_number"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	chapters := []gin.H{}
+	for rows.Next() {
+		var class, chapterNum, size int
+		var subject, version string
+		var updatedAt time.Time
+		if err := rows.Scan(&class, &subject, &chapterNum, &version, &size, &updatedAt); err != nil {
+			continue
+		}
+		chapters = append(chapters, gin.H{
+			"class":          class,
+			"subject":        subject,
+			"chapter_number": chapterNum,
+			"version":        version,
+			"size":           size,
+			"updated_at":     isoTimestamp(updatedAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "chapters": chapters})
+}
+
+// writeContentResponse sends a content payload with its version as ETag.
+// A matching If-None-Match gets 304; ?format=gzip sends the JSON gzipped
+// as a file the app can store as-is for offline use.
+func writeContentResponse(c *gin.Context, version string, payload gin.H) {
+	etag := `"` + version + `"`
+	c.Header("ETag", etag)
+	c.Header("X-Content-Version", version)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if c.Query("format") != "gzip" {
+		c.JSON(http.StatusOK, payload)
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(body)
+	zw.Close()
+
+	c.Data(http.StatusOK, "application/gzip", buf.Bytes())
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// AI PRACTICE WORKSHEETS (from chapter content)
+// ============================================
+
+const (
+	defaultWorksheetQuestions = 10
+	maxWorksheetQuestions     = 30
+)
+
+// worksheetAnswerLines is the writing space left under each question type
+var worksheetAnswerLines = map[string]int{"fill_blank": 0, "mcq": 0, "short": 2, "long": 5}
+
+type worksheetQuestion struct {
+	Type     string   `json:"type"` // mcq, fill_blank, short, long
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+	Answer   string   `json:"answer"`
+	Marks    int      `json:"marks"`
+}
+
+type worksheet struct {
+	Title        string              `json:"title"`
+	Instructions string              `json:"instructions"`
+	Questions    []worksheetQuestion `json:"questions"`
+}
+
+// generateWorksheet asks the AI provider for a worksheet on the chapter text
+// and keeps only complete questions
+func generateWorksheet(class int, subject, chapterTitle, material string, count int) (worksheet, error) {
+	if len(material) > 15000 {
+		material = material[:15000]
+	}
+
+	prompt := fmt.Sprintf("You are a school teacher writing a printable practice worksheet for a class %d %s student "+
+		"on the chapter \"%s\". Write %d questions that cover the whole chapter, mixing multiple choice, "+
+		"fill in the blank, short answer and one or two long answer questions, easiest first. "+
+		"Use only facts from the chapter material below. Reply with only JSON, no markdown, in this shape: "+
+		`{"title": "...", "instructions": "one sentence", "questions": [{"type": "mcq|fill_blank|short|long", `+
+		`"question": "... (use ____ for the blank)", "options": ["..."] (mcq only, 4 choices), `+
+		`"answer": "the correct option text, missing word(s) or a model answer", "marks": 1}]}`+
+		"\n\nChapter material:\n%s",
+		class, subject, chapterTitle, count, material)
+
+	text, err := generateText(prompt)
+	if err != nil {
+		return worksheet{}, err
+	}
+	text = stripCodeFence(text)
+
+	var ws worksheet
+	if err := json.Unmarshal([]byte(text), &ws); err != nil {
+		return worksheet{}, fmt.Errorf("AI reply was not a JSON worksheet: %v", err)
+	}
+
+	questions := []worksheetQuestion{}
+	for _, q := range ws.Questions {
+		q.Question, q.Answer = strings.TrimSpace(q.Question), strings.TrimSpace(q.Answer)
+		if _, ok := worksheetAnswerLines[q.Type]; !ok || q.Question == "" || q.Answer == "" {
+			continue
+		}
+		if q.Type == "mcq" && len(q.Options) < 2 {
+			continue
+		}
+		if q.Type != "mcq" {
+			q.Options = nil
+		}
+		if q.Marks <= 0 {
+			q.Marks = 1
+		}
+		questions = append(questions, q)
+	}
+	if len(questions) == 0 {
+		return worksheet{}, fmt.Errorf("AI reply had no usable questions")
+	}
+
+	ws.Questions = questions
+	if strings.TrimSpace(ws.Title) == "" {
+		ws.Title = chapterTitle + " - Practice Worksheet"
+	}
+	return ws, nil
+}
+
+// getContentWorksheet - A practice worksheet with answer key for a chapter,
+// generated once per content version and size (?count=, default 10) and then
+// served from cache. ?refresh=true regenerates; ?format=pdf returns a
+// printable PDF (answers=false leaves out the answer key page).
+func getContentWorksheet(c *gin.Context) {
+	class, _ := strconv.Atoi(c.Param("class"))
+	subject := c.Param("subject")
+	chapter, _ := strconv.Atoi(c.Param("chapter"))
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultWorksheetQuestions)))
+	if err != nil || count < 1 || count > maxWorksheetQuestions {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"count": "must be between 1 and " + strconv.Itoa(maxWorksheetQuestions)}))
+		return
+	}
+
+	var chapterTitle, version string
+	var material sql.NullString
+	err = db.QueryRow(`
+		SELECT COALESCE(chapter_title, ''), `+contentBodyVersionSQL+`, `+contentTextSQL+`
+		FROM mentor.content
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3`+contentVisibilitySQL(c),
+		class, subject, chapter).Scan(&chapterTitle, &version, &material)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Chapter content not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if chapterTitle == "" {
+		chapterTitle = fmt.Sprintf("Chapter %d", chapter)
+	}
+
+	var ws worksheet
+	var createdAt time.Time
+	cached := false
+	if c.Query("refresh") != "true" {
+		var data string
+		err := db.QueryRow(`
+			SELECT worksheet::text, created_at FROM mentor.content_worksheets
+			WHERE class = $1 AND subject = $2 AND chapter_number = $3 AND content_version = $4 AND question_count = $5
+		`, class, subject, chapter, version, count).Scan(&data, &createdAt)
+		cached = err == nil && json.Unmarshal([]byte(data), &ws) == nil
+	}
+
+	if !cached {
+		if !aiConfigured() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "AI provider is not configured"})
+			return
+		}
+		if strings.TrimSpace(material.String) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Chapter has no text to build a worksheet from"})
+			return
+		}
+
+		ws, err = generateWorksheet(class, subject, chapterTitle, material.String, count)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Worksheet generation failed: " + err.Error()})
+			return
+		}
+
+		data, _ := json.Marshal(ws)
+		err = db.QueryRow(`
+			INSERT INTO mentor.content_worksheets (class, subject, chapter_number, content_version, question_count, worksheet)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (class, subject, chapter_number, content_version, question_count)
+			DO UPDATE SET worksheet = EXCLUDED.worksheet, created_at = NOW()
+			RETURNING created_at
+		`, class, subject, chapter, version, count, string(data)).Scan(&createdAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	if c.Query("format") == "pdf" {
+		pdf := worksheetPDF(ws, fmt.Sprintf("Class %d %s - %s", class, subject, chapterTitle), c.Query("answers") != "false")
+		sendPDF(c, fmt.Sprintf("worksheet-%d-%s-%d.pdf", class, strings.ReplaceAll(strings.ToLower(subject), " ", "-"), chapter), pdf)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"class":           class,
+		"subject":         subject,
+		"chapter_number":  chapter,
+		"content_version": version,
+		"cached":          cached,
+		"generated_at":    isoTimestamp(createdAt),
+		"worksheet":       ws,
+	})
+}
+
+// worksheetPDF lays out the questions with writing space, and the answer key
+// on a separate page so it can be left out when printing
+func worksheetPDF(ws worksheet, subtitle string, withAnswers bool) *pdfDoc {
+	const left, width = 40.0, pdfPageWidth - 80
+	title := "WORKSHEET"
+	pdf := newPDF()
+	y := documentHeader(pdf, title, subtitle)
+
+	// ensure starts a new page when the next block would run into the footer
+	ensure := func(height float64) {
+		if y+height > pdfPageHeight-70 {
+			handoutFooter(pdf, "Practice worksheet")
+			pdf.AddPage()
+			y = documentHeader(pdf, title, subtitle)
+		}
+	}
+
+	for _, line := range pdfWrap(ws.Title, 14, true, width) {
+		pdf.Text(left, y, 14, true, line)
+		y += 18
+	}
+	documentField(pdf, left, y+4, "Name", "______________________________")
+	documentField(pdf, 330, y+4, "Date", "________________")
+	y += 44
+	if ws.Instructions != "" {
+		pdf.SetColor(110, 110, 110)
+		for _, line := range pdfWrap(ws.Instructions, 10, false, width) {
+			pdf.Text(left, y, 10, false, line)
+			y += 14
+		}
+		pdf.SetColor(0, 0, 0)
+	}
+	y += 10
+
+	for i, q := range ws.Questions {
+		number := fmt.Sprintf("%d.", i+1)
+		lines := pdfWrap(q.Question, 11, false, width-70)
+		ensure(float64(len(lines))*15 + float64(len(q.Options))*15 + float64(worksheetAnswerLines[q.Type])*22 + 12)
+
+		pdf.Text(left, y, 11, true, number)
+		pdf.TextRight(pdfPageWidth-left, y, 9, false, fmt.Sprintf("[%d]", q.Marks))
+		for _, line := range lines {
+			pdf.Text(left+22, y, 11, false, line)
+			y += 15
+		}
+		for j, option := range q.Options {
+			pdf.Text(left+34, y, 11, false, fmt.Sprintf("(%c) %s", 'a'+j, option))
+			y += 15
+		}
+		for k := 0; k < worksheetAnswerLines[q.Type]; k++ {
+			y += 18
+			pdf.Line(left+22, y, pdfPageWidth-left, y)
+		}
+		y += 14
+	}
+	handoutFooter(pdf, "Practice worksheet")
+
+	if withAnswers {
+		title = "ANSWER KEY"
+		pdf.AddPage()
+		y = documentHeader(pdf, title, subtitle)
+		for i, q := range ws.Questions {
+			lines := pdfWrap(q.Answer, 10, false, width-22)
+			ensure(float64(len(lines))*14 + 6)
+			pdf.Text(left, y, 10, true, fmt.Sprintf("%d.", i+1))
+			for _, line := range lines {
+				pdf.Text(left+22, y, 10, false, line)
+				y += 14
+			}
+			y += 6
+		}
+		handoutFooter(pdf, "Practice worksheet")
+	}
+	return pdf
+}
+
+// handoutFooter marks each page of a printed handout with what it is, the
+// brand and the print date
+func handoutFooter(pdf *pdfDoc, what string) {
+	pdf.Line(40, pdfPageHeight-60, pdfPageWidth-40, pdfPageHeight-60)
+	pdf.SetColor(110, 110, 110)
+	pdf.Text(40, pdfPageHeight-42, 9, false, what+" from "+brandName()+", printed "+time.Now().Format("2 Jan 2006")+".")
+	pdf.SetColor(0, 0, 0)
+}
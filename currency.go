@@ -0,0 +1,222 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// CURRENCIES (Per-subscription currency, exchange rates)
+// ============================================
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeCurrency upper-cases a currency code; ok is false unless it is
+// three letters. "" is the default currency.
+func normalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return currencyCode(), true
+	}
+	return code, currencyPattern.MatchString(code)
+}
+
+// orDefaultCurrency is the code stored on a row, or the default for rows
+// from before currencies were recorded
+func orDefaultCurrency(code sql.NullString) string {
+	if code.Valid && code.String != "" {
+		return code.String
+	}
+	return currencyCode()
+}
+
+// inDefaultCurrencySQL converts the amount of a transactions row (by alias)
+// to the default currency at the latest rate on or before its date. It is
+// NULL for a currency with no rate, so callers check missingExchangeRates first.
+func inDefaultCurrencySQL(alias string) string {
+	return fmt.Sprintf(`(%[1]s.amount * CASE WHEN %[1]s.currency IS NULL OR %[1]s.currency = '%[2]s' THEN 1 ELSE (
+		SELECT r.rate FROM mentor.exchange_rates r
+		WHERE r.currency = %[1]s.currency AND r.effective_date <= %[1]s.date
+		ORDER BY r.effective_date DESC LIMIT 1) END)`, alias, currencyCode())
+}
+
+// missingExchangeRates lists the currencies of transactions matching where
+// (over alias t) that have no rate on or before their date
+func missingExchangeRates(where string, args ...interface{}) ([]string, error) {
+	args = append(args, currencyCode())
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT DISTINCT t.currency FROM mentor.transactions t
+		WHERE t.voided_at IS NULL AND t.currency IS NOT NULL AND t.currency <> $%d AND (%s)
+		  AND NOT EXISTS (
+			SELECT 1 FROM mentor.exchange_rates r WHERE r.currency = t.currency AND r.effective_date <= t.date
+		  )
+		ORDER BY 1
+	`, len(args), where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missing := []string{}
+	for rows.Next() {
+		var code string
+		if rows.Scan(&code) == nil {
+			missing = append(missing, code)
+		}
+	}
+	return missing, nil
+}
+
+// rejectMissingExchangeRates answers 409 when transactions matching where
+// can't be converted to the default currency; true means a response was sent
+func rejectMissingExchangeRates(c *gin.Context, where string, args ...interface{}) bool {
+	missing, err := missingExchangeRates(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return true
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success":       false,
+			"error":         "No exchange rate for " + strings.Join(missing, ", ") + "; add one with PUT /api/exchange-rates",
+			"missing_rates": missing,
+		})
+		return true
+	}
+	return false
+}
+
+// toDefaultCurrency converts amount in code to the default currency at the
+// latest rate on or before day; it fails when there is no such rate
+func toDefaultCurrency(amount float64, code string, day time.Time) (float64, error) {
+	if code == "" || code == currencyCode() {
+		return amount, nil
+	}
+	var rate float64
+	err := db.QueryRow(`
+		SELECT rate FROM mentor.exchange_rates
+		WHERE currency = $1 AND effective_date <= $2
+		ORDER BY effective_date DESC LIMIT 1
+	`, code, day).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("No exchange rate for %s on %s; add one with PUT /api/exchange-rates", code, day.Format("2006-01-02"))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// getExchangeRates - Stored rates, newest first, optionally for ?currency=
+func getExchangeRates(c *gin.Context) {
+	query := `
+		SELECT id, currency, rate, effective_date, COALESCE(updated_by, ''), created_at
+		FROM mentor.exchange_rates
+	`
+	args := []interface{}{}
+	if code := c.Query("currency"); code != "" {
+		query += " WHERE currency = $1"
+		args = append(args, strings.ToUpper(code))
+	}
+	query += " ORDER BY currency, effective_date DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	rates := []gin.H{}
+	for rows.Next() {
+		var id int
+		var code, updatedBy string
+		var rate float64
+		var effective, createdAt time.Time
+		if err := rows.Scan(&id, &code, &rate, &effective, &updatedBy, &createdAt); err != nil {
+			continue
+		}
+		rates = append(rates, gin.H{
+			"id":             id,
+			"currency":       code,
+			"rate":           rate,
+			"effective_date": effective.Format("2006-01-02"),
+			"updated_by":     updatedBy,
+			"created_at":     isoTimestamp(createdAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "default_currency": currencyCode(), "rates": rates})
+}
+
+// setExchangeRate - Record how many units of the default currency one unit
+// of currency is worth from effective_date (default today) on
+func setExchangeRate(c *gin.Context) {
+	var input struct {
+		Currency      string  `json:"currency" binding:"required"`
+		Rate          float64 `json:"rate" binding:"required"`
+		EffectiveDate string  `json:"effective_date"`
+		UpdatedBy     string  `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	fields := map[string]string{}
+	code, ok := normalizeCurrency(input.Currency)
+	if !ok {
+		fields["currency"] = "must be a 3-letter ISO code"
+	} else if code == currencyCode() {
+		fields["currency"] = "is the default currency"
+	}
+	if input.Rate <= 0 {
+		fields["rate"] = "must be greater than 0"
+	}
+	effective := localToday()
+	if input.EffectiveDate != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", input.EffectiveDate, time.Local)
+		if err != nil {
+			fields["effective_date"] = "must be YYYY-MM-DD"
+		}
+		effective = parsed
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.exchange_rates (currency, rate, effective_date, updated_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (currency, effective_date) DO UPDATE SET rate = EXCLUDED.rate, updated_by = EXCLUDED.updated_by
+		RETURNING id
+	`, code, input.Rate, effective, input.UpdatedBy).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("exchange_rate", fmt.Sprint(id), "rate_set", input.UpdatedBy, gin.H{
+		"currency":       code,
+		"rate":           input.Rate,
+		"effective_date": effective.Format("2006-01-02"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"id":               id,
+		"currency":         code,
+		"rate":             input.Rate,
+		"effective_date":   effective.Format("2006-01-02"),
+		"default_currency": currencyCode(),
+	})
+}
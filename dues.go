@@ -0,0 +1,271 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// DUES (Expected fees vs payments received)
+// ============================================
+
+// duesLookbackCycles caps how many past cycles are counted per subscription
+const duesLookbackCycles = 12
+
+// cyclePayments is what was paid towards one cycle by asOf: payments linked
+// to its invoice, plus payments made during the cycle that aren't linked to
+// any invoice. A billing group payment settles the cycle in full.
+func cyclePayments(subId int, fee float64, invoiceID sql.NullInt64, start, end, asOf time.Time) float64 {
+	var paid float64
+	var groupPaid bool
+	db.QueryRow(`
+		SELECT COALESCE(SUM(t.amount) FILTER (WHERE t.subscription_id = s.id), 0),
+		       COALESCE(BOOL_OR(t.billing_group_id = s.billing_group_id), false)
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.transactions t
+		       ON (t.subscription_id = s.id OR t.billing_group_id = s.billing_group_id)
+		      AND t.type = 'income' AND t.voided_at IS NULL AND t.date <= $5
+		      AND (t.invoice_id = $2 OR (t.invoice_id IS NULL AND t.date >= $3 AND t.date < $4))
+		WHERE s.id = $1
+	`, subId, invoiceID, start, end, asOf).Scan(&paid, &groupPaid)
+
+	if groupPaid && paid < fee {
+		return fee
+	}
+	return paid
+}
+
+// cyclePaymentStatus is paid, partially_paid or unpaid for a cycle's fee
+func cyclePaymentStatus(fee, paid float64) string {
+	switch {
+	case paid+0.005 >= fee:
+		return "paid"
+	case paid > 0:
+		return "partially_paid"
+	}
+	return "unpaid"
+}
+
+// subscriptionDues walks a subscription's cycles from its start (at most
+// duesLookbackCycles back) to asOf. Each cycle's fee is its invoice total, or
+// the monthly amount if it wasn't invoiced, plus late fees charged for it that
+// no invoice carries yet. Payments, including installments
+// spread over later cycles, are applied oldest cycle first, so the first
+// cycle they don't cover is the one overdue and may be partially paid.
+func subscriptionDues(subId int, amount float64, billingDay int, startDate time.Time, asOf time.Time, graceDays int) gin.H {
+	type invoiceRef struct {
+		id     int64
+		number string
+		total  float64
+		due    time.Time
+	}
+	invoiced := map[string]invoiceRef{}
+	rows, err := db.Query(`
+		SELECT id, invoice_number, cycle_start, total, due_date FROM mentor.invoices
+		WHERE subscription_id = $1 AND status <> 'void' AND cycle_start <= $2
+	`, subId, asOf)
+	if err == nil {
+		for rows.Next() {
+			var inv invoiceRef
+			var start time.Time
+			if rows.Scan(&inv.id, &inv.number, &start, &inv.total, &inv.due) == nil {
+				invoiced[start.Format("2006-01-02")] = inv
+			}
+		}
+		rows.Close()
+	}
+
+	// Late fees are added to an invoice when the next one is issued; until
+	// then they're owed on top of their cycle's fee
+	lateFees := map[string]float64{}
+	rows, err = db.Query(`
+		SELECT cycle_start, SUM(amount) FROM mentor.late_fees
+		WHERE subscription_id = $1 AND status = 'applied' AND invoice_id IS NULL AND created_at::date <= $2
+		GROUP BY cycle_start
+	`, subId, asOf)
+	if err == nil {
+		for rows.Next() {
+			var start time.Time
+			var fee float64
+			if rows.Scan(&start, &fee) == nil {
+				lateFees[start.Format("2006-01-02")] = fee
+			}
+		}
+		rows.Close()
+	}
+
+	first, _ := billingCycleFor(asOf, billingDay)
+	for i := 1; i < duesLookbackCycles; i++ {
+		prev, _ := billingCycleFor(first.AddDate(0, 0, -1), billingDay)
+		if !first.After(startDate) {
+			break
+		}
+		first = prev
+	}
+
+	type cycle struct {
+		start, due time.Time
+		fee        float64
+		lateFee    float64
+		invoice    invoiceRef
+	}
+	var cycles []cycle
+	expected, paid := 0.0, 0.0
+	for start := first; !start.After(asOf); {
+		_, end := billingCycleFor(start, billingDay)
+		cy := cycle{start: start, fee: amount, due: start.AddDate(0, 0, graceDays)}
+		var invoiceID sql.NullInt64
+		if inv, ok := invoiced[start.Format("2006-01-02")]; ok {
+			cy.invoice, cy.fee, cy.due = inv, inv.total, inv.due
+			invoiceID = sql.NullInt64{Int64: inv.id, Valid: true}
+		}
+		cy.lateFee = lateFees[start.Format("2006-01-02")]
+		cy.fee += cy.lateFee
+		cycles = append(cycles, cy)
+		expected += cy.fee
+		paid += cyclePayments(subId, cy.fee, invoiceID, start, end, asOf)
+		start = end
+	}
+
+	result := gin.H{
+		"cycles":         len(cycles),
+		"expected":       roundMoney(expected),
+		"paid":           roundMoney(paid),
+		"outstanding":    roundMoney(math.Max(expected-paid, 0)),
+		"payment_status": "paid",
+	}
+
+	openCycles := []gin.H{}
+	remaining := paid
+	for _, cy := range cycles {
+		applied := math.Min(remaining, cy.fee)
+		remaining -= applied
+		status := cyclePaymentStatus(cy.fee, applied)
+		if status == "paid" {
+			continue
+		}
+		open := gin.H{
+			"cycle_start":    cy.start.Format("2006-01-02"),
+			"due_date":       cy.due.Format("2006-01-02"),
+			"fee":            roundMoney(cy.fee),
+			"paid":           roundMoney(applied),
+			"balance":        roundMoney(cy.fee - applied),
+			"payment_status": status,
+		}
+		if cy.lateFee > 0 {
+			open["late_fee"] = roundMoney(cy.lateFee)
+		}
+		if cy.invoice.id != 0 {
+			open["invoice_id"] = cy.invoice.id
+			open["invoice_number"] = cy.invoice.number
+		}
+		openCycles = append(openCycles, open)
+
+		if len(openCycles) == 1 {
+			result["payment_status"] = status
+			result["oldest_unpaid_cycle"] = cy.start.Format("2006-01-02")
+			result["due_since"] = cy.due.Format("2006-01-02")
+			if days := int(asOf.Sub(cy.due).Hours() / 24); days > 0 {
+				result["days_overdue"] = days
+			} else {
+				result["days_overdue"] = 0
+			}
+		}
+	}
+	result["open_cycles"] = openCycles
+	return result
+}
+
+// getDues - Active paid subscriptions' expected fees against payments
+// received up to ?as_of= (default today), most overdue first. Settled
+// subscriptions are left out unless ?all=true. Each is in its own currency;
+// total_outstanding is in the default one.
+func getDues(c *gin.Context) {
+	asOf := localToday()
+	if v := c.Query("as_of"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "as_of must be YYYY-MM-DD"})
+			return
+		}
+		asOf = parsed
+	}
+	all := c.Query("all") == "true"
+
+	graceDays := 0
+	if policy, err := loadLateFeePolicy(); err == nil && policy.Enabled {
+		graceDays = policy.GraceDays
+	}
+
+	rows, err := db.Query(`
+		SELECT id, student_name, COALESCE(guardian_phone, ''), COALESCE(amount, 0), COALESCE(billing_date, 1),
+		       COALESCE(start_date, created_at::date), currency
+		FROM mentor.subscriptions
+		WHERE status = 'active' AND deleted_at IS NULL AND subscription_type = 'paid' AND amount > 0
+		  AND COALESCE(start_date, created_at::date) <= $1
+		ORDER BY id
+	`, asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	type sub struct {
+		id, billingDay      int
+		name, guardianPhone string
+		amount              float64
+		start               time.Time
+		currency            sql.NullString
+	}
+	var subs []sub
+	for rows.Next() {
+		var s sub
+		if err := rows.Scan(&s.id, &s.name, &s.guardianPhone, &s.amount, &s.billingDay, &s.start, &s.currency); err != nil {
+			continue
+		}
+		subs = append(subs, s)
+	}
+	rows.Close()
+
+	dues := []gin.H{}
+	totalOutstanding := 0.0
+	for _, s := range subs {
+		due := subscriptionDues(s.id, s.amount, s.billingDay, s.start, asOf, graceDays)
+		if !all && due["outstanding"].(float64) <= 0 {
+			continue
+		}
+		due["subscription_id"] = s.id
+		due["student_name"] = s.name
+		due["guardian_phone"] = s.guardianPhone
+		due["monthly_amount"] = s.amount
+		due["currency"] = orDefaultCurrency(s.currency)
+		outstanding, err := toDefaultCurrency(due["outstanding"].(float64), orDefaultCurrency(s.currency), asOf)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		totalOutstanding += outstanding
+		dues = append(dues, due)
+	}
+
+	daysOverdue := func(d gin.H) int {
+		if days, ok := d["days_overdue"].(int); ok {
+			return days
+		}
+		return -1
+	}
+	sort.SliceStable(dues, func(i, j int) bool { return daysOverdue(dues[i]) > daysOverdue(dues[j]) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"as_of":             asOf.Format("2006-01-02"),
+		"dues":              dues,
+		"count":             len(dues),
+		"total_outstanding": roundMoney(totalOutstanding),
+		"currency":          currencyCode(),
+	})
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// EMERGENCY CANCELLATION BROADCAST
+// ============================================
+
+// notCancelledSQL excludes subscriptions whose class on $3 was cancelled;
+// matches subscriptions aliased s
+const notCancelledSQL = `NOT EXISTS (
+		SELECT 1 FROM mentor.class_cancellations cc WHERE cc.subscription_id = s.id AND cc.date = $3
+	)`
+
+// cancelDay - Cancel every class on a date (optionally one teacher's), give each
+// student a makeup credit, and notify guardians and teachers
+func cancelDay(c *gin.Context) {
+	var input struct {
+		Date      string `json:"date"`       // YYYY-MM-DD
+		TeacherID string `json:"teacher_id"` // optional: only this teacher's classes
+		Reason    string `json:"reason"`     // e.g. "Heavy rain", "Hartal"
+		Message   string `json:"message"`    // optional; a default is built from date + reason
+		CreatedBy string `json:"created_by"`
+		DryRun    bool   `json:"dry_run"` // list who would be affected without acting
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil || input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date (YYYY-MM-DD) and reason are required"})
+		return
+	}
+	if input.Message == "" {
+		input.Message = fmt.Sprintf("Classes on %s are cancelled (%s). A makeup class will be scheduled.",
+			date.Format("Mon 2 Jan"), input.Reason)
+	}
+
+	// Classes scheduled on that weekday, plus online sessions booked for the date
+	rows, err := db.Query(`
+		SELECT s.id, s.student_name, COALESCE(NULLIF(s.guardian_phone, ''), s.student_phone, ''),
+		       COALESCE(s.preferred_channel, 'whatsapp')
+		FROM mentor.subscriptions s
+		WHERE s.status = 'active' AND s.deleted_at IS NULL
+		  AND ($1 = '' OR `+teacherAssignedSQL+`)
+		  AND ($2 = ANY(s.schedule_day_list)
+		       OR EXISTS (SELECT 1 FROM mentor.online_sessions o WHERE o.subscription_id = s.id AND o.session_date = $3))
+		  AND `+notCancelledSQL+`
+		ORDER BY s.id
+	`, input.TeacherID, date.Format("Mon"), input.Date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	type affected struct {
+		id                int
+		name, to, channel string
+	}
+	var subs []affected
+	var subIDs []int
+	for rows.Next() {
+		var a affected
+		if err := rows.Scan(&a.id, &a.name, &a.to, &a.channel); err != nil {
+			continue
+		}
+		subs = append(subs, a)
+		subIDs = append(subIDs, a.id)
+	}
+	rows.Close()
+
+	// Every teacher with a cancelled subject, or just the one requested
+	type teacherContact struct {
+		id, name, to, channel string
+		classes               int
+	}
+	var teachers []teacherContact
+	if len(subIDs) > 0 {
+		trows, err := db.Query(`
+			SELECT t.id, t.name, COALESCE(t.phone, ''), COALESCE(t.preferred_channel, 'sms'), COUNT(DISTINCT s.id)
+			FROM mentor.subscriptions s
+			LEFT JOIN mentor.schedule sc ON sc.subscription_id = s.id
+			JOIN mentor.teachers t ON t.id = COALESCE(sc.teacher_id, s.teacher_id)
+			WHERE s.id = ANY($1) AND ($2 = '' OR t.id = $2)
+			GROUP BY t.id, t.name, t.phone, t.preferred_channel
+		`, pq.Array(subIDs), input.TeacherID)
+		if err == nil {
+			for trows.Next() {
+				var t teacherContact
+				if err := trows.Scan(&t.id, &t.name, &t.to, &t.channel, &t.classes); err == nil {
+					teachers = append(teachers, t)
+				}
+			}
+			trows.Close()
+		}
+	}
+
+	if input.DryRun {
+		students := []gin.H{}
+		for _, s := range subs {
+			students = append(students, gin.H{"subscription_id": s.id, "student_name": s.name, "channel": s.channel, "recipient": s.to})
+		}
+		teacherList := []gin.H{}
+		for _, t := range teachers {
+			teacherList = append(teacherList, gin.H{"teacher_id": t.id, "name": t.name, "channel": t.channel, "classes": t.classes})
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "dry_run": true, "students": students, "teachers": teacherList, "message": input.Message})
+		return
+	}
+
+	// A teacher's cancellation only touches that teacher's sessions, so other
+	// subjects that day still go ahead; make sure the day's sessions exist first
+	if input.TeacherID != "" {
+		if err := syncClassSessions(date, date, input.TeacherID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	var broadcastID int
+	err = db.QueryRow(`
+		INSERT INTO mentor.broadcasts (date, teacher_id, reason, message, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING id
+	`, input.Date, input.TeacherID, input.Reason, input.Message, input.CreatedBy).Scan(&broadcastID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	reached, failed := 0, 0
+	students := []gin.H{}
+	for _, s := range subs {
+		if input.TeacherID == "" {
+			db.Exec(`
+				INSERT INTO mentor.class_cancellations (subscription_id, date, reason, broadcast_id, cancelled_by)
+				VALUES ($1, $2, $3, $4, $5) ON CONFLICT (subscription_id, date) DO NOTHING
+			`, s.id, input.Date, input.Reason, broadcastID, input.CreatedBy)
+		}
+		db.Exec(`
+			UPDATE mentor.class_sessions SET status = 'cancelled', updated_at = NOW()
+			WHERE subscription_id = $1 AND session_date = $2 AND status = 'scheduled'
+			  AND ($3 = '' OR teacher_id = $3)
+		`, s.id, input.Date, input.TeacherID)
+		db.Exec(`
+			INSERT INTO mentor.makeup_credits (subscription_id, cancelled_date, reason, broadcast_id)
+			VALUES ($1, $2, $3, $4) ON CONFLICT (subscription_id, cancelled_date) WHERE broadcast_id IS NOT NULL DO NOTHING
+		`, s.id, input.Date, input.Reason, broadcastID)
+
+		entry := gin.H{"subscription_id": s.id, "student_name": s.name, "channel": s.channel, "reached": true}
+		if err := notifySubscription(s.id, s.channel, s.to, "class_cancellation", input.Message, input.CreatedBy); err != nil {
+			entry["reached"], entry["error"] = false, err.Error()
+			failed++
+		} else {
+			reached++
+		}
+		students = append(students, entry)
+	}
+
+	teacherReport := []gin.H{}
+	for _, t := range teachers {
+		msg := fmt.Sprintf("%d of your classes on %s are cancelled (%s). Students have been informed.",
+			t.classes, date.Format("Mon 2 Jan"), input.Reason)
+		entry := gin.H{"teacher_id": t.id, "name": t.name, "channel": t.channel, "classes": t.classes, "reached": true}
+		if err := notifySubscription(0, t.channel, t.to, "class_cancellation", msg, input.CreatedBy); err != nil {
+			entry["reached"], entry["error"] = false, err.Error()
+			failed++
+		} else {
+			reached++
+		}
+		teacherReport = append(teacherReport, entry)
+	}
+
+	report := gin.H{
+		"broadcast_id":      broadcastID,
+		"date":              input.Date,
+		"cancelled_classes": len(subs),
+		"makeup_credits":    len(subs),
+		"reached":           reached,
+		"failed":            failed,
+		"students":          students,
+		"teachers":          teacherReport,
+	}
+	reportJSON, _ := json.Marshal(report)
+	db.Exec("UPDATE mentor.broadcasts SET report = $1 WHERE id = $2", string(reportJSON), broadcastID)
+
+	logAudit("broadcast", strconv.Itoa(broadcastID), "day_cancelled", input.CreatedBy, gin.H{
+		"date":       input.Date,
+		"teacher_id": input.TeacherID,
+		"reason":     input.Reason,
+		"classes":    len(subs),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "report": report})
+}
+
+// getBroadcast - The stored confirmation report of a cancellation broadcast
+func getBroadcast(c *gin.Context) {
+	var date time.Time
+	var teacherID, createdBy sql.NullString
+	var reason, message, report string
+	var createdAt time.Time
+	err := db.QueryRow(`
+		SELECT date, teacher_id, reason, message, report::TEXT, created_by, created_at
+		FROM mentor.broadcasts WHERE id = $1
+	`, c.Param("id")).Scan(&date, &teacherID, &reason, &message, &report, &createdBy, &createdAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Broadcast not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"date":       date.Format("2006-01-02"),
+		"teacher_id": teacherID.String,
+		"reason":     reason,
+		"message":    message,
+		"report":     json.RawMessage(report),
+		"created_by": createdBy.String,
+		"created_at": isoTimestamp(createdAt),
+	})
+}
+
+// getMakeupCredits - Makeup classes owed to a subscription (used=false for open ones only)
+func getMakeupCredits(c *gin.Context) {
+	query := `
+		SELECT id, cancelled_date, COALESCE(reason, ''), used_at
+		FROM mentor.makeup_credits WHERE subscription_id = $1`
+	if c.Query("used") == "false" {
+		query += " AND used_at IS NULL"
+	}
+	query += " ORDER BY cancelled_date DESC"
+
+	rows, err := db.Query(query, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	credits := []gin.H{}
+	open := 0
+	for rows.Next() {
+		var id int
+		var cancelledDate time.Time
+		var reason string
+		var usedAt sql.NullTime
+		if err := rows.Scan(&id, &cancelledDate, &reason, &usedAt); err != nil {
+			continue
+		}
+		credit := gin.H{"id": id, "cancelled_date": cancelledDate.Format("2006-01-02"), "reason": reason, "used_at": nil}
+		if usedAt.Valid {
+			credit["used_at"] = isoTimestamp(usedAt.Time)
+		} else {
+			open++
+		}
+		credits = append(credits, credit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "credits": credits, "open": open})
+}
+
+// useMakeupCredit - Mark a makeup class as given
+func useMakeupCredit(c *gin.Context) {
+	result, err := db.Exec("UPDATE mentor.makeup_credits SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Open makeup credit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Makeup credit used"})
+}
@@ -0,0 +1,949 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// AI EXAM GRADING (Queued, graded in the background)
+// =====================================================
+
+const (
+	maxExamImageBytes       = 8 << 20
+	examGradingBatch        = 5
+	examGradingAttempts     = 3
+	examGradingStuckMinutes = 10 // a 'grading' row older than this was interrupted
+)
+
+var examImageTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true}
+
+// decodeExamImage accepts plain base64 or a data URL and checks it's an image
+func decodeExamImage(encoded string) ([]byte, string, error) {
+	if i := strings.Index(encoded, "base64,"); i >= 0 {
+		encoded = encoded[i+len("base64,"):]
+	}
+	encoded = strings.TrimSpace(encoded)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("is not valid base64")
+	}
+	if len(data) > maxExamImageBytes {
+		return nil, "", fmt.Errorf("is larger than %d MB", maxExamImageBytes>>20)
+	}
+	imageType := http.DetectContentType(data)
+	if !examImageTypes[imageType] {
+		return nil, "", fmt.Errorf("must be a JPEG, PNG or WebP image")
+	}
+	return data, imageType, nil
+}
+
+// examSubmission is one graded (or queued) answer, without its image
+type examSubmission struct {
+	ID             int
+	SubscriptionID sql.NullInt64
+	TeacherID      string
+	StudentName    string
+	Class          int
+	Subject        string
+	Chapter        sql.NullInt64
+	QuestionText   sql.NullString
+	Score          sql.NullInt64
+	Feedback       sql.NullString
+	Suggestions    sql.NullString
+	TeacherNotes   sql.NullString
+	AnswerKey      sql.NullString
+	MarkingScheme  []markingItem
+	RubricID       sql.NullInt64
+	Transcription  sql.NullString
+	TranscribedBy  sql.NullString // set once a teacher corrects it
+	ImageKey       sql.NullString
+	Status         string
+	Selected       sql.NullInt64
+	GradedAt       sql.NullTime
+	CreatedAt      sql.NullTime
+}
+
+const examSubmissionColumns = `id, subscription_id, teacher_id, student_name, class, subject, chapter_number,
+	question_text, ai_score, ai_feedback, ai_suggestions, teacher_notes, answer_key,
+	COALESCE(marking_scheme::text, ''), rubric_id, transcription, transcription_edited_by, image_key, status, selected_grading_id, graded_at, created_at`
+
+func scanExamSubmission(scan func(...interface{}) error) (examSubmission, error) {
+	var s examSubmission
+	var scheme string
+	err := scan(&s.ID, &s.SubscriptionID, &s.TeacherID, &s.StudentName, &s.Class, &s.Subject, &s.Chapter,
+		&s.QuestionText, &s.Score, &s.Feedback, &s.Suggestions, &s.TeacherNotes, &s.AnswerKey, &scheme,
+		&s.RubricID, &s.Transcription, &s.TranscribedBy, &s.ImageKey, &s.Status, &s.Selected, &s.GradedAt, &s.CreatedAt)
+	if scheme != "" {
+		json.Unmarshal([]byte(scheme), &s.MarkingScheme)
+	}
+	return s, err
+}
+
+func (s examSubmission) toH() gin.H {
+	h := gin.H{
+		"id":            s.ID,
+		"teacher_id":    s.TeacherID,
+		"student_name":  s.StudentName,
+		"class":         s.Class,
+		"subject":       s.Subject,
+		"question_text": s.QuestionText.String,
+		"status":        s.Status,
+		"score":         nil,
+		"feedback":      s.Feedback.String,
+		"suggestions":   s.Suggestions.String,
+		"teacher_notes": s.TeacherNotes.String,
+		"answer_key":    s.AnswerKey.String,
+		"transcription": s.Transcription.String,
+	}
+	if s.TranscribedBy.Valid {
+		h["transcription_edited_by"] = s.TranscribedBy.String
+	}
+	if s.ImageKey.Valid {
+		if store := getObjectStore(); store != nil {
+			h["image_url"] = store.PresignGet(s.ImageKey.String, examImageLinkTTL)
+		}
+	}
+	if s.MarkingScheme != nil {
+		h["marking_scheme"] = s.MarkingScheme
+	}
+	if s.RubricID.Valid {
+		h["rubric_id"] = s.RubricID.Int64
+	}
+	if s.SubscriptionID.Valid {
+		h["subscription_id"] = s.SubscriptionID.Int64
+	}
+	if s.Chapter.Valid {
+		h["chapter_number"] = s.Chapter.Int64
+	}
+	if s.Score.Valid {
+		h["score"] = s.Score.Int64
+	}
+	if s.Selected.Valid {
+		h["selected_grading_id"] = s.Selected.Int64
+	}
+	if s.GradedAt.Valid {
+		h["graded_at"] = isoTimestamp(s.GradedAt.Time)
+	}
+	if s.CreatedAt.Valid {
+		h["created_at"] = isoTimestamp(s.CreatedAt.Time)
+	}
+	return h
+}
+
+// submitExamForGrading - Accept a photographed answer and queue it for AI
+// grading; the app polls the submission (or gets a message) for the result
+func submitExamForGrading(c *gin.Context) {
+	var input struct {
+		SubscriptionID *int          `json:"subscription_id"`
+		TeacherID      string        `json:"teacher_id"`
+		StudentName    string        `json:"student_name"`
+		Class          int           `json:"class"`
+		Subject        string        `json:"subject" binding:"required"`
+		ChapterNumber  *int          `json:"chapter_number"`
+		QuestionText   string        `json:"question_text"`
+		ImageBase64    string        `json:"image_base64" binding:"required"`
+		AnswerKey      string        `json:"answer_key"`
+		MarkingScheme  []markingItem `json:"marking_scheme"`
+		RubricID       *int          `json:"rubric_id"`
+		Notify         bool          `json:"notify"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	// Teachers submit as themselves; admins name the teacher
+	caller := c.GetString("teacher_id")
+	if caller != "" {
+		input.TeacherID = caller
+	}
+	if input.SubscriptionID != nil && caller != "" && !teachesSubscription(caller, *input.SubscriptionID) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "You can only submit answers for your own students"})
+		return
+	}
+
+	// Student details default to the subscription's
+	if input.SubscriptionID != nil {
+		var name string
+		var class int
+		err := db.QueryRow(`
+			SELECT student_name, class FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+		`, *input.SubscriptionID).Scan(&name, &class)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+			return
+		}
+		if input.StudentName == "" {
+			input.StudentName = name
+		}
+		if input.Class == 0 {
+			input.Class = class
+		}
+	}
+
+	fields := map[string]string{}
+	if input.TeacherID == "" {
+		fields["teacher_id"] = "is required"
+	}
+	if strings.TrimSpace(input.StudentName) == "" {
+		fields["student_name"] = "is required without subscription_id"
+	}
+	if input.Class <= 0 {
+		fields["class"] = "is required without subscription_id"
+	}
+	image, imageType, err := decodeExamImage(input.ImageBase64)
+	if err != nil {
+		fields["image_base64"] = err.Error()
+	}
+	scheme, schemeErrors := resolveMarkingScheme(input.MarkingScheme)
+	for k, v := range schemeErrors {
+		fields[k] = v
+	}
+	rubric, rubricError := examRubric(input.RubricID, input.Class, input.Subject)
+	if rubricError != "" {
+		fields["rubric_id"] = rubricError
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	store := getObjectStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Object storage is not configured"})
+		return
+	}
+	imageKey, err := storeExamImage(store, input.TeacherID, time.Now(), imageType, image)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Image upload failed: " + err.Error()})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		store.Delete(imageKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.exam_submissions
+			(subscription_id, teacher_id, student_name, class, subject, chapter_number, question_text,
+			 image_key, image_type, notify, answer_key, marking_scheme, rubric_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, NULLIF($11, ''), $12, $13)
+		RETURNING id
+	`, input.SubscriptionID, input.TeacherID, input.StudentName, input.Class, input.Subject, input.ChapterNumber,
+		input.QuestionText, imageKey, imageType, input.Notify, strings.TrimSpace(input.AnswerKey),
+		markingSchemeJSON(scheme), input.RubricID).Scan(&id)
+	if err != nil {
+		store.Delete(imageKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	gradingID, err := queueExamGrading(tx, id, examGradingOptions{
+		AnswerKey:     strings.TrimSpace(input.AnswerKey),
+		MarkingScheme: scheme,
+		Rubric:        rubric,
+		RequestedBy:   input.TeacherID,
+	})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		store.Delete(imageKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	startExamGrading()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":       true,
+		"submission_id": id,
+		"grading_id":    gradingID,
+		"status":        "pending",
+		"poll_url":      publicBaseURL(c) + "/api/exam/submissions/" + strconv.Itoa(id),
+		"message":       "Submitted for grading",
+	})
+}
+
+// examGradingOptions override how a grading is done; empty means the default
+type examGradingOptions struct {
+	Model         string        `json:"model"`
+	Prompt        string        `json:"prompt"`
+	AnswerKey     string        `json:"answer_key"`
+	MarkingScheme []markingItem `json:"marking_scheme"`
+	RubricID      *int          `json:"rubric_id"`
+	RequestedBy   string        `json:"requested_by"`
+
+	Rubric *gradingRubric `json:"-"` // loaded from RubricID
+}
+
+// aiModelPattern keeps model overrides to plain model names, optionally for
+// one provider ("openai:gpt-4o")
+var aiModelPattern = regexp.MustCompile(`^((gemini|openai):)?[A-Za-z0-9][A-Za-z0-9._/-]{0,99}$`)
+
+// queueExamGrading adds a grading attempt for the background worker
+func queueExamGrading(tx *sql.Tx, submissionID int, opts examGradingOptions) (int, error) {
+	var rubric interface{}
+	if opts.Rubric != nil {
+		b, _ := json.Marshal(opts.Rubric)
+		rubric = string(b)
+	}
+
+	var id int
+	err := tx.QueryRow(`
+		INSERT INTO mentor.exam_gradings (submission_id, model, prompt, answer_key, marking_scheme, rubric, requested_by)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, $6, NULLIF($7, ''))
+		RETURNING id
+	`, submissionID, opts.Model, opts.Prompt, opts.AnswerKey, markingSchemeJSON(opts.MarkingScheme),
+		rubric, opts.RequestedBy).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(syncExamSubmissionSQL, submissionID)
+	return id, err
+}
+
+// startExamGrading runs the worker now rather than waiting for its next run
+func startExamGrading() {
+	go func() {
+		if err := processExamGrading(); err != nil {
+			log.Printf("Exam grading: %v", err)
+		}
+	}()
+}
+
+// syncExamSubmissionSQL sets the status of a submission that has no selected
+// grading yet from its gradings in progress; $1 is the submission id
+const syncExamSubmissionSQL = `
+	UPDATE mentor.exam_submissions e
+	SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM mentor.exam_gradings g WHERE g.submission_id = e.id AND g.status = 'grading') THEN 'grading'
+			WHEN EXISTS (SELECT 1 FROM mentor.exam_gradings g WHERE g.submission_id = e.id AND g.status = 'pending') THEN 'pending'
+			ELSE 'failed'
+		END,
+		updated_at = NOW()
+	WHERE e.id = $1 AND e.selected_grading_id IS NULL`
+
+// selectExamGradingSQL makes a finished grading the submission's result;
+// $1 is the submission, $2 the grading
+const selectExamGradingSQL = `
+	UPDATE mentor.exam_submissions e
+	SET selected_grading_id = g.id, ai_score = g.score, ai_feedback = g.feedback, ai_suggestions = g.suggestions,
+	    transcription = CASE WHEN e.transcription_edited_at IS NULL THEN COALESCE(g.transcription, e.transcription)
+	                         ELSE e.transcription END,
+	    status = 'graded', graded_at = g.graded_at, updated_at = NOW()
+	FROM mentor.exam_gradings g
+	WHERE e.id = $1 AND g.id = $2 AND g.submission_id = e.id AND g.status = 'graded'`
+
+// examGrading is one AI grading attempt of a submission
+type examGrading struct {
+	ID           int
+	SubmissionID int
+	Model        sql.NullString
+	Prompt       sql.NullString
+	AnswerKey    sql.NullString
+	Scheme       []markingItem
+	Rubric       *gradingRubric
+	Status       string
+	Attempts     int
+	Error        sql.NullString
+	NextAttempt  sql.NullTime
+	Score        sql.NullInt64
+	Feedback     sql.NullString
+	Suggestions  sql.NullString
+	Transcribed  sql.NullString
+	GradedWith   sql.NullString
+	Breakdown    []questionMark
+	MarksAwarded sql.NullFloat64
+	MarksMax     sql.NullFloat64
+	RequestedBy  sql.NullString
+	CreatedAt    sql.NullTime
+	GradedAt     sql.NullTime
+}
+
+const examGradingColumns = `id, submission_id, model, prompt, answer_key, COALESCE(marking_scheme::text, ''),
+	COALESCE(rubric::text, ''), status, attempts, error, next_attempt_at, score, feedback, suggestions,
+	transcription, graded_with, COALESCE(breakdown::text, ''),
+	marks_awarded, marks_max, requested_by, created_at, graded_at`
+
+func scanExamGrading(scan func(...interface{}) error) (examGrading, error) {
+	var g examGrading
+	var scheme, rubric, breakdown string
+	err := scan(&g.ID, &g.SubmissionID, &g.Model, &g.Prompt, &g.AnswerKey, &scheme, &rubric, &g.Status, &g.Attempts,
+		&g.Error, &g.NextAttempt, &g.Score, &g.Feedback, &g.Suggestions, &g.Transcribed, &g.GradedWith, &breakdown, &g.MarksAwarded,
+		&g.MarksMax, &g.RequestedBy, &g.CreatedAt, &g.GradedAt)
+	if scheme != "" {
+		json.Unmarshal([]byte(scheme), &g.Scheme)
+	}
+	if rubric != "" {
+		json.Unmarshal([]byte(rubric), &g.Rubric)
+	}
+	if breakdown != "" {
+		json.Unmarshal([]byte(breakdown), &g.Breakdown)
+	}
+	return g, err
+}
+
+func (g examGrading) toH(selected sql.NullInt64) gin.H {
+	h := gin.H{
+		"id":            g.ID,
+		"status":        g.Status,
+		"attempts":      g.Attempts,
+		"model":         g.Model.String,
+		"prompt":        g.Prompt.String,
+		"answer_key":    g.AnswerKey.String,
+		"requested_by":  g.RequestedBy.String,
+		"score":         nil,
+		"feedback":      g.Feedback.String,
+		"suggestions":   g.Suggestions.String,
+		"transcription": g.Transcribed.String,
+		"selected":      selected.Valid && selected.Int64 == int64(g.ID),
+	}
+	if g.Score.Valid {
+		h["score"] = g.Score.Int64
+	}
+	if g.Scheme != nil {
+		h["marking_scheme"] = g.Scheme
+	}
+	if g.Rubric != nil {
+		h["rubric"] = gin.H{"id": g.Rubric.ID, "name": g.Rubric.Name}
+	}
+	if g.Breakdown != nil {
+		h["breakdown"] = g.Breakdown
+		h["marks_awarded"] = g.MarksAwarded.Float64
+		h["marks_max"] = g.MarksMax.Float64
+	}
+	if g.GradedWith.Valid {
+		h["graded_with"] = g.GradedWith.String
+	}
+	if g.Error.Valid {
+		h["error"] = g.Error.String
+	}
+	if g.Status == "pending" && g.Attempts > 0 && g.NextAttempt.Valid {
+		h["next_attempt_at"] = isoTimestamp(g.NextAttempt.Time)
+	}
+	if g.CreatedAt.Valid {
+		h["created_at"] = isoTimestamp(g.CreatedAt.Time)
+	}
+	if g.GradedAt.Valid {
+		h["graded_at"] = isoTimestamp(g.GradedAt.Time)
+	}
+	return h
+}
+
+// examGrade is the AI provider's verdict on one answer
+type examGrade struct {
+	Feedback      string        `json:"feedback"`
+	Suggestions   string        `json:"suggestions"`
+	Transcription string        `json:"transcription"`
+	Questions     []gradedReply `json:"questions"`
+
+	// Worked out from the per-question marks
+	Breakdown    []questionMark `json:"-"`
+	MarksAwarded float64        `json:"-"`
+	MarksMax     float64        `json:"-"`
+	Score        int            `json:"-"`
+	GradedWith   string         `json:"-"`
+}
+
+// defaultExamGradingPrompt is used unless a grading overrides it
+const defaultExamGradingPrompt = "Read the answer carefully and judge it for correctness, completeness " +
+	"and clarity at the student's level."
+
+// gradeExamAnswer asks the AI provider to read and mark a photographed answer
+func gradeExamAnswer(s examSubmission, g examGrading, imageType string, image []byte) (examGrade, error) {
+	student := fmt.Sprintf("a class %d %s student", s.Class, s.Subject)
+	if s.Chapter.Valid {
+		var title sql.NullString
+		db.QueryRow(`
+			SELECT chapter_title FROM mentor.content WHERE class = $1 AND subject = $2 AND chapter_number = $3
+		`, s.Class, s.Subject, s.Chapter.Int64).Scan(&title)
+		student += fmt.Sprintf(", chapter %d", s.Chapter.Int64)
+		if title.String != "" {
+			student += " (" + title.String + ")"
+		}
+	}
+
+	prompt := fmt.Sprintf("You are a school teacher grading the handwritten answer in this photo, written by %s. ", student)
+	if s.QuestionText.String != "" {
+		prompt += fmt.Sprintf("The question was: \"%s\". ", s.QuestionText.String)
+	} else {
+		prompt += "Work out the question from the page if it is written there. "
+	}
+	if g.Prompt.String != "" {
+		prompt += g.Prompt.String + " "
+	} else {
+		prompt += defaultExamGradingPrompt + " "
+	}
+	if g.AnswerKey.String != "" {
+		prompt += "Mark it against this answer key, giving credit only for what the key expects:\n" +
+			g.AnswerKey.String + "\n"
+	}
+	if g.Rubric != nil {
+		prompt += g.Rubric.prompt()
+	}
+	prompt += "Also transcribe everything the student wrote, word for word, keeping their spelling and " +
+		"line breaks and writing [illegible] for words you cannot read. "
+	if len(g.Scheme) > 0 {
+		prompt += markingSchemePrompt(g.Scheme) +
+			"Reply with only JSON, no markdown, in this shape: " +
+			`{"transcription": "the student's answer as written", ` +
+			`"questions": [{"number": 1, "awarded": 0, "comment": "why these marks"}], ` +
+			`"feedback": "2-3 sentences on what is right and what is wrong", "suggestions": "concrete ways to improve"}`
+	} else {
+		prompt += "Mark each question answered on the page separately (one item if it is a single answer): " +
+			"the question as written, or a short description of it; its maximum marks as printed on the paper, " +
+			"otherwise a fair maximum from 1 to 20 for its length and difficulty; and the marks awarded " +
+			"(half marks allowed). Reply with only JSON, no markdown, in this shape: " +
+			`{"transcription": "the student's answer as written", ` +
+			`"questions": [{"number": 1, "question": "...", "max_marks": 5, "awarded": 0, "comment": "why these marks"}], ` +
+			`"feedback": "2-3 sentences on what is right and what is wrong", "suggestions": "concrete ways to improve"}`
+	}
+
+	reply, err := generateWithImage(g.Model.String, prompt, imageType, image)
+	if err != nil {
+		return examGrade{}, err
+	}
+	text := stripCodeFence(reply.Text)
+
+	grade := examGrade{GradedWith: reply.Provider + ":" + reply.Model}
+	if err := json.Unmarshal([]byte(text), &grade); err != nil {
+		return examGrade{}, fmt.Errorf("AI reply was not a JSON grade: %v", err)
+	}
+	if len(grade.Questions) == 0 {
+		return examGrade{}, fmt.Errorf("AI reply had no marks per question")
+	}
+	if len(g.Scheme) > 0 {
+		grade.Breakdown, grade.MarksAwarded, grade.MarksMax = markAgainstScheme(g.Scheme, grade.Questions)
+	} else {
+		grade.Breakdown, grade.MarksAwarded, grade.MarksMax = markWithoutScheme(grade.Questions)
+	}
+	if grade.MarksMax <= 0 {
+		return examGrade{}, fmt.Errorf("AI reply had no question with a maximum mark")
+	}
+	grade.Score = int(math.Round(grade.MarksAwarded / grade.MarksMax * 100))
+	return grade, nil
+}
+
+// runExamGrading grades one claimed attempt and stores its result. The first
+// grading to finish becomes the submission's result; later ones wait for the
+// teacher to select them.
+func runExamGrading(g examGrading) error {
+	s, err := scanExamSubmission(db.QueryRow("SELECT "+examSubmissionColumns+
+		" FROM mentor.exam_submissions WHERE id = $1", g.SubmissionID).Scan)
+	if err != nil {
+		return err
+	}
+	image, imageType, err := loadExamImage(s.ID)
+	if err != nil {
+		return err
+	}
+
+	// Straightened, high-contrast pages are easier to read; fall back to the
+	// original photo if preprocessing fails
+	if processed, _, err := preprocessPage(image); err == nil {
+		image, imageType = processed, "image/jpeg"
+	}
+
+	grade, err := gradeExamAnswer(s, g, imageType, image)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.exam_gradings
+		SET score = $1, feedback = $2, suggestions = $3, breakdown = $4, marks_awarded = $5, marks_max = $6,
+		    transcription = NULLIF($7, ''), graded_with = $8, status = 'graded', error = NULL, graded_at = NOW()
+		WHERE id = $9
+	`, grade.Score, grade.Feedback, grade.Suggestions, breakdownJSON(grade.Breakdown),
+		grade.MarksAwarded, grade.MarksMax, strings.TrimSpace(grade.Transcription), grade.GradedWith, g.ID)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(selectExamGradingSQL+" AND e.selected_grading_id IS NULL", s.ID, g.ID)
+	return err
+}
+
+// processExamGrading is the background worker: claims queued gradings (and
+// ones interrupted mid-grading), runs them, and retries failures with a
+// growing delay until examGradingAttempts is reached
+func processExamGrading() error {
+	if !aiConfigured() {
+		return nil // stay queued until a key is set
+	}
+
+	// Interrupted on every attempt (e.g. it crashes the worker), so give up
+	// rather than retry forever
+	stuck, err := db.Query(`
+		UPDATE mentor.exam_gradings SET status = 'failed', error = 'Grading was interrupted on every attempt'
+		WHERE status = 'grading' AND grading_started_at < NOW() - make_interval(mins => $1) AND attempts >= $2
+		RETURNING id, submission_id
+	`, examGradingStuckMinutes, examGradingAttempts)
+	if err != nil {
+		return err
+	}
+	type failedGrading struct{ id, submissionID int }
+	var failed []failedGrading
+	for stuck.Next() {
+		var f failedGrading
+		if stuck.Scan(&f.id, &f.submissionID) == nil {
+			failed = append(failed, f)
+		}
+	}
+	stuck.Close()
+	for _, f := range failed {
+		db.Exec(syncExamSubmissionSQL, f.submissionID)
+		notifyExamResult(f.id)
+	}
+
+	rows, err := db.Query(`
+		UPDATE mentor.exam_gradings
+		SET status = 'grading', attempts = attempts + 1, grading_started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM mentor.exam_gradings
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'grading' AND grading_started_at < NOW() - make_interval(mins => $2))
+			ORDER BY created_at
+			LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+examGradingColumns, examGradingBatch, examGradingStuckMinutes)
+	if err != nil {
+		return err
+	}
+	var claimed []examGrading
+	for rows.Next() {
+		if g, err := scanExamGrading(rows.Scan); err == nil {
+			claimed = append(claimed, g)
+		}
+	}
+	rows.Close()
+
+	for _, g := range claimed {
+		db.Exec(syncExamSubmissionSQL, g.SubmissionID)
+
+		if err := runExamGrading(g); err != nil {
+			log.Printf("Exam grading %d (submission %d, attempt %d): %v", g.ID, g.SubmissionID, g.Attempts, err)
+			// The detail (provider replies, URLs) stays in the log; the
+			// grading shows a message fit for the app
+			if g.Attempts >= examGradingAttempts {
+				db.Exec("UPDATE mentor.exam_gradings SET status = 'failed', error = $1 WHERE id = $2",
+					"Grading failed after "+strconv.Itoa(g.Attempts)+" attempts", g.ID)
+				db.Exec(syncExamSubmissionSQL, g.SubmissionID)
+				notifyExamResult(g.ID)
+				continue
+			}
+			db.Exec(`
+				UPDATE mentor.exam_gradings
+				SET status = 'pending', error = $1, next_attempt_at = NOW() + make_interval(mins => $2)
+				WHERE id = $3
+			`, "Grading attempt "+strconv.Itoa(g.Attempts)+" failed, retrying", g.Attempts*g.Attempts, g.ID)
+			db.Exec(syncExamSubmissionSQL, g.SubmissionID)
+			continue
+		}
+		notifyExamResult(g.ID)
+	}
+	return nil
+}
+
+// notifyExamResult messages the teacher when they asked to hear about a
+// finished (or failed) grading
+func notifyExamResult(gradingID int) {
+	var notify, regrade bool
+	var subId sql.NullInt64
+	var studentName, subject, status, phone, channel string
+	var score sql.NullInt64
+	err := db.QueryRow(`
+		SELECT e.notify, EXISTS (SELECT 1 FROM mentor.exam_gradings p WHERE p.submission_id = e.id AND p.id < g.id),
+		       e.subscription_id, e.student_name, e.subject, g.status, g.score,
+		       COALESCE(t.phone, ''), COALESCE(t.preferred_channel, 'sms')
+		FROM mentor.exam_gradings g
+		JOIN mentor.exam_submissions e ON e.id = g.submission_id
+		LEFT JOIN mentor.teachers t ON t.id = e.teacher_id
+		WHERE g.id = $1
+	`, gradingID).Scan(&notify, &regrade, &subId, &studentName, &subject, &status, &score, &phone, &channel)
+	if err != nil || !notify || phone == "" {
+		return
+	}
+
+	what := "graded"
+	if regrade {
+		what = "regraded"
+	}
+	msg := fmt.Sprintf("%s's %s answer is %s: %d/100.", studentName, subject, what, score.Int64)
+	if status == "failed" {
+		msg = fmt.Sprintf("%s's %s answer could not be %s automatically. Please try again or grade it yourself.",
+			studentName, subject, what)
+	}
+	notifySubscription(subId.Int64, channel, phone, "exam_graded", msg, "system")
+}
+
+// callerExamSubmission checks a teacher caller owns submission id, answering
+// 404 otherwise; admins may see any. false means a response was sent.
+func callerExamSubmission(c *gin.Context, id string) bool {
+	caller := c.GetString("teacher_id")
+	if caller == "" {
+		return true
+	}
+	var owner string
+	err := db.QueryRow("SELECT teacher_id FROM mentor.exam_submissions WHERE id = $1", id).Scan(&owner)
+	if err != nil || owner != caller {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Submission not found"})
+		return false
+	}
+	return true
+}
+
+// getExamSubmissions - Grading history, newest first (teacher_id,
+// subscription_id, status filters; q searches questions and transcribed
+// answers in web search syntax). Teachers only see their own.
+func getExamSubmissions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	query := "SELECT " + examSubmissionColumns + " FROM mentor.exam_submissions WHERE 1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	for _, filter := range []string{"teacher_id", "subscription_id", "status"} {
+		v := c.Query(filter)
+		if filter == "teacher_id" && c.GetString("teacher_id") != "" {
+			v = c.GetString("teacher_id")
+		}
+		if v != "" {
+			argCount++
+			query += fmt.Sprintf(" AND %s = $%d", filter, argCount)
+			args = append(args, v)
+		}
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		argCount++
+		query += fmt.Sprintf(" AND search_vector @@ websearch_to_tsquery('english', $%d)", argCount)
+		args = append(args, q)
+	}
+	argCount++
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", argCount)
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	submissions := []gin.H{}
+	for rows.Next() {
+		s, err := scanExamSubmission(rows.Scan)
+		if err != nil {
+			continue
+		}
+		submissions = append(submissions, s.toH())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "submissions": submissions})
+}
+
+// getExamSubmission - One submission with its grading attempts; the app polls
+// this until status is 'graded' or 'failed'. The photo is at image_url;
+// include_image=true adds it inline for submissions not yet moved to storage.
+func getExamSubmission(c *gin.Context) {
+	if !callerExamSubmission(c, c.Param("id")) {
+		return
+	}
+	s, err := scanExamSubmission(db.QueryRow("SELECT "+examSubmissionColumns+
+		" FROM mentor.exam_submissions WHERE id = $1", c.Param("id")).Scan)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Submission not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	submission := s.toH()
+	submission["breakdown"] = []questionMark{}
+	gradings := []gin.H{}
+	rows, err := db.Query("SELECT "+examGradingColumns+
+		" FROM mentor.exam_gradings WHERE submission_id = $1 ORDER BY id", s.ID)
+	if err == nil {
+		for rows.Next() {
+			g, err := scanExamGrading(rows.Scan)
+			if err != nil {
+				continue
+			}
+			gradings = append(gradings, g.toH(s.Selected))
+
+			// The selected grading's marks per question, to review answer by answer
+			if s.Selected.Valid && s.Selected.Int64 == int64(g.ID) && g.Breakdown != nil {
+				submission["breakdown"] = g.Breakdown
+				submission["marks_awarded"] = g.MarksAwarded.Float64
+				submission["marks_max"] = g.MarksMax.Float64
+			}
+		}
+		rows.Close()
+	}
+	submission["gradings"] = gradings
+
+	if c.Query("include_image") == "true" && !s.ImageKey.Valid {
+		var encoded, imageType sql.NullString
+		db.QueryRow("SELECT image_data, image_type FROM mentor.exam_submissions WHERE id = $1", s.ID).
+			Scan(&encoded, &imageType)
+		submission["image_data"] = encoded.String
+		submission["image_type"] = imageType.String
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "submission": submission})
+}
+
+// regradeExamSubmission - Grade a submission again, optionally with a
+// different prompt, answer key or model. The result is kept as a separate
+// grading; the submission's result only changes when the teacher selects it.
+func regradeExamSubmission(c *gin.Context) {
+	if !callerExamSubmission(c, c.Param("id")) {
+		return
+	}
+	var input examGradingOptions
+	c.ShouldBindJSON(&input)
+	input.Model = strings.TrimSpace(input.Model)
+	input.AnswerKey = strings.TrimSpace(input.AnswerKey)
+
+	scheme, fields := resolveMarkingScheme(input.MarkingScheme)
+	if input.Model != "" && !aiModelPattern.MatchString(input.Model) {
+		fields["model"] = "is not a valid model name"
+	} else if i := strings.Index(input.Model, ":"); i >= 0 && getAIProvider(input.Model[:i]) == nil {
+		fields["model"] = "names a provider that is not configured"
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+	input.MarkingScheme = scheme
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var submissionID, class int
+	var subject, savedScheme string
+	var answerKey sql.NullString
+	var rubricID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT id, class, subject, answer_key, COALESCE(marking_scheme::text, ''), rubric_id
+		FROM mentor.exam_submissions WHERE id = $1 FOR UPDATE
+	`, c.Param("id")).Scan(&submissionID, &class, &subject, &answerKey, &savedScheme, &rubricID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Submission not found"})
+		return
+	}
+
+	// The answer key, marking scheme and rubric attached to the submission
+	// apply unless the regrade brings its own
+	if input.AnswerKey == "" {
+		input.AnswerKey = answerKey.String
+	}
+	if len(input.MarkingScheme) == 0 && savedScheme != "" {
+		json.Unmarshal([]byte(savedScheme), &input.MarkingScheme)
+	}
+	if input.RubricID != nil {
+		rubric, rubricError := examRubric(input.RubricID, class, subject)
+		if rubricError != "" {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"rubric_id": rubricError}))
+			return
+		}
+		input.Rubric = rubric
+	} else if rubricID.Valid {
+		if rubric, err := loadGradingRubric(rubricID.Int64); err == nil {
+			input.Rubric = &rubric
+		}
+	}
+
+	var inProgress int
+	tx.QueryRow(`
+		SELECT COUNT(*) FROM mentor.exam_gradings WHERE submission_id = $1 AND status IN ('pending', 'grading')
+	`, submissionID).Scan(&inProgress)
+	if inProgress > 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "A grading is already in progress for this submission"})
+		return
+	}
+
+	gradingID, err := queueExamGrading(tx, submissionID, input)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	startExamGrading()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":       true,
+		"submission_id": submissionID,
+		"grading_id":    gradingID,
+		"status":        "pending",
+		"poll_url":      publicBaseURL(c) + "/api/exam/submissions/" + strconv.Itoa(submissionID),
+		"message":       "Queued for regrading",
+	})
+}
+
+// selectExamGrading - Keep one grading attempt as the submission's result
+func selectExamGrading(c *gin.Context) {
+	if !callerExamSubmission(c, c.Param("id")) {
+		return
+	}
+	var input struct {
+		SelectedBy string `json:"selected_by"`
+	}
+	c.ShouldBindJSON(&input)
+
+	var previous sql.NullInt64
+	var gradingStatus string
+	err := db.QueryRow(`
+		SELECT e.selected_grading_id, g.status
+		FROM mentor.exam_gradings g
+		JOIN mentor.exam_submissions e ON e.id = g.submission_id
+		WHERE e.id = $1 AND g.id = $2
+	`, c.Param("id"), c.Param("gradingId")).Scan(&previous, &gradingStatus)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Grading not found for this submission"})
+		return
+	}
+	if gradingStatus != "graded" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Only a finished grading can be selected", "status": gradingStatus})
+		return
+	}
+
+	if _, err := db.Exec(selectExamGradingSQL, c.Param("id"), c.Param("gradingId")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("exam_submission", c.Param("id"), "grading_selected", input.SelectedBy, gin.H{
+		"grading_id":          c.Param("gradingId"),
+		"previous_grading_id": previous,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Grading selected"})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ============================================
+// EXAM IMAGES (Answer photos in object storage)
+// ============================================
+
+const (
+	examImageLinkTTL       = 15 * time.Minute
+	examImageBackfillBatch = 50 // rows moved per batch by migrate-exam-images
+)
+
+var examImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// storeExamImage uploads an answer photo and returns its key
+func storeExamImage(store *objectStore, teacherID string, created time.Time, imageType string, image []byte) (string, error) {
+	key := fmt.Sprintf("exam/%s/%s/%s%s", teacherID, created.Format("2006-01"), randomID(), examImageExtensions[imageType])
+	if err := store.Put(key, imageType, image); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// loadExamImage reads a submission's photo from object storage, or from the
+// image_data column for rows migrate-exam-images hasn't moved yet
+func loadExamImage(submissionID int) ([]byte, string, error) {
+	var key, encoded, imageType sql.NullString
+	err := db.QueryRow("SELECT image_key, image_data, image_type FROM mentor.exam_submissions WHERE id = $1",
+		submissionID).Scan(&key, &encoded, &imageType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if key.Valid {
+		store := getObjectStore()
+		if store == nil {
+			return nil, "", fmt.Errorf("object storage is not configured")
+		}
+		image, err := store.Get(key.String)
+		if err != nil {
+			return nil, "", err
+		}
+		return image, imageType.String, nil
+	}
+
+	image, detected, err := decodeExamImage(encoded.String)
+	if err != nil {
+		return nil, "", fmt.Errorf("stored image %v", err)
+	}
+	if imageType.String == "" {
+		imageType.String = detected
+	}
+	return image, imageType.String, nil
+}
+
+// backfillExamImages moves base64 images out of exam_submissions into object
+// storage in batches, clearing image_data as each one lands. Rows that fail
+// are skipped and reported, so a rerun only retries those.
+func backfillExamImages(store *objectStore) (int, int, error) {
+	moved, failed, lastID := 0, 0, 0
+	for {
+		rows, err := db.Query(`
+			SELECT id, teacher_id, image_data, created_at FROM mentor.exam_submissions
+			WHERE image_key IS NULL AND image_data IS NOT NULL AND id > $1
+			ORDER BY id
+			LIMIT $2
+		`, lastID, examImageBackfillBatch)
+		if err != nil {
+			return moved, failed, err
+		}
+		type pending struct {
+			id        int
+			teacherID string
+			encoded   string
+			created   time.Time
+		}
+		var batch []pending
+		seen := 0
+		for rows.Next() {
+			seen++
+			var p pending
+			var created sql.NullTime
+			if err := rows.Scan(&p.id, &p.teacherID, &p.encoded, &created); err != nil {
+				// id is scanned first, so the next batch still starts after this row
+				log.Printf("Exam submission %d: unreadable row: %v", p.id, err)
+				failed++
+				lastID = max(lastID, p.id)
+				continue
+			}
+			p.created = created.Time
+			if !created.Valid {
+				p.created = time.Now()
+			}
+			batch = append(batch, p)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return moved, failed, err
+		}
+		if seen == 0 {
+			return moved, failed, nil
+		}
+
+		for _, p := range batch {
+			lastID = max(lastID, p.id)
+			image, imageType, err := decodeExamImage(p.encoded)
+			if err != nil {
+				log.Printf("Exam submission %d: stored image %v", p.id, err)
+				failed++
+				continue
+			}
+			key, err := storeExamImage(store, p.teacherID, p.created, imageType, image)
+			if err != nil {
+				log.Printf("Exam submission %d: %v", p.id, err)
+				failed++
+				continue
+			}
+			if _, err := db.Exec(`
+				UPDATE mentor.exam_submissions SET image_key = $1, image_type = $2, image_data = NULL WHERE id = $3
+			`, key, imageType, p.id); err != nil {
+				store.Delete(key)
+				log.Printf("Exam submission %d: %v", p.id, err)
+				failed++
+				continue
+			}
+			moved++
+		}
+		log.Printf("Exam images: %d moved, %d failed so far", moved, failed)
+	}
+}
+
+// runExamImageBackfillCommand is the migrate-exam-images CLI command
+func runExamImageBackfillCommand() {
+	store := getObjectStore()
+	if store == nil {
+		log.Fatal("Object storage is not configured (S3_*)")
+	}
+	moved, failed, err := backfillExamImages(store)
+	if err != nil {
+		log.Fatal("Exam image migration failed:", err)
+	}
+	log.Printf("Exam image migration complete: %d moved, %d failed", moved, failed)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// =====================================================
+// EXAM MARKING SCHEMES (Answer-key based grading)
+// =====================================================
+
+const (
+	maxMarkingItems    = 50
+	maxMarksPerItem    = 100.0
+	unansweredQuestion = "No answer found for this question"
+)
+
+// markingItem is one question of a marking scheme: typed in by the teacher,
+// or a question bank reference filled in from the bank
+type markingItem struct {
+	QuestionID *int    `json:"question_id,omitempty"`
+	Question   string  `json:"question"`
+	MaxMarks   float64 `json:"max_marks"`
+	Answer     string  `json:"answer,omitempty"` // expected answer or key points
+}
+
+// questionMark is the marks given for one question of a graded answer
+type questionMark struct {
+	Number   int     `json:"number"`
+	Question string  `json:"question"`
+	MaxMarks float64 `json:"max_marks"`
+	Awarded  float64 `json:"awarded"`
+	Comment  string  `json:"comment"`
+}
+
+// resolveMarkingScheme fills question bank references and checks every item
+// has a question and a maximum. Errors are keyed like marking_scheme[2].max_marks.
+func resolveMarkingScheme(items []markingItem) ([]markingItem, map[string]string) {
+	fields := map[string]string{}
+	if len(items) > maxMarkingItems {
+		fields["marking_scheme"] = fmt.Sprintf("can have at most %d questions", maxMarkingItems)
+		return nil, fields
+	}
+
+	resolved := make([]markingItem, 0, len(items))
+	for i, item := range items {
+		key := fmt.Sprintf("marking_scheme[%d]", i)
+		item.Question = strings.TrimSpace(item.Question)
+		item.Answer = strings.TrimSpace(item.Answer)
+
+		if item.QuestionID != nil {
+			q, err := scanBankQuestion(db.QueryRow("SELECT "+bankQuestionColumns+
+				" FROM mentor.questions q WHERE q.id = $1", *item.QuestionID).Scan)
+			if err != nil {
+				fields[key+".question_id"] = "is not in the question bank"
+				continue
+			}
+			if item.Question == "" {
+				item.Question = q.Prompt
+				for j, option := range q.Options {
+					item.Question += fmt.Sprintf(" (%c) %s", 'a'+j%26, option)
+				}
+			}
+			if item.Answer == "" {
+				item.Answer = q.Answer
+				if q.CorrectOption != nil && *q.CorrectOption < len(q.Options) {
+					item.Answer = q.Options[*q.CorrectOption]
+				}
+			}
+			if item.MaxMarks == 0 {
+				item.MaxMarks = q.Marks
+			}
+		}
+
+		if item.Question == "" {
+			fields[key+".question"] = "is required without question_id"
+		}
+		if item.MaxMarks <= 0 || item.MaxMarks > maxMarksPerItem {
+			fields[key+".max_marks"] = fmt.Sprintf("must be more than 0 and at most %g", maxMarksPerItem)
+		}
+		resolved = append(resolved, item)
+	}
+	return resolved, fields
+}
+
+// markingSchemePrompt lists the scheme's questions, numbered, for the AI provider
+func markingSchemePrompt(scheme []markingItem) string {
+	var b strings.Builder
+	b.WriteString("Mark the answers against this marking scheme. Give each question marks from 0 to its " +
+		"maximum (half marks allowed), using the expected answer where one is given:\n")
+	for i, item := range scheme {
+		fmt.Fprintf(&b, "%d. [%g marks] %s\n", i+1, item.MaxMarks, item.Question)
+		if item.Answer != "" {
+			fmt.Fprintf(&b, "   Expected: %s\n", item.Answer)
+		}
+	}
+	return b.String()
+}
+
+// markAgainstScheme turns the AI provider's per-question marks into a
+// breakdown in scheme order. Marks are kept within each question's maximum
+// and questions it skipped count as unanswered.
+func markAgainstScheme(scheme []markingItem, replies []gradedReply) ([]questionMark, float64, float64) {
+	byNumber := map[int]gradedReply{}
+	for _, r := range replies {
+		byNumber[r.Number] = r
+	}
+
+	breakdown := make([]questionMark, 0, len(scheme))
+	var awarded, outOf float64
+	for i, item := range scheme {
+		mark := questionMark{Number: i + 1, Question: item.Question, MaxMarks: item.MaxMarks, Comment: unansweredQuestion}
+		if r, ok := byNumber[i+1]; ok && r.Awarded != nil {
+			mark.Awarded = math.Min(math.Round(math.Max(*r.Awarded, 0)*2)/2, item.MaxMarks)
+			mark.Comment = strings.TrimSpace(r.Comment)
+		}
+		breakdown = append(breakdown, mark)
+		awarded += mark.Awarded
+		outOf += item.MaxMarks
+	}
+	return breakdown, awarded, outOf
+}
+
+// markingSchemeJSON stores a scheme as JSONB, or NULL when there is none
+func markingSchemeJSON(scheme []markingItem) interface{} {
+	if len(scheme) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(scheme)
+	return string(b)
+}
+
+// breakdownJSON stores per-question marks as JSONB, or NULL when there are none
+func breakdownJSON(breakdown []questionMark) interface{} {
+	if len(breakdown) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(breakdown)
+	return string(b)
+}
+
+// markWithoutScheme builds the breakdown when there is no marking scheme;
+// the AI provider names each question and its maximum itself. Questions
+// without a maximum are left out.
+func markWithoutScheme(replies []gradedReply) ([]questionMark, float64, float64) {
+	breakdown := []questionMark{}
+	var awarded, outOf float64
+	for _, r := range replies {
+		if r.MaxMarks == nil || *r.MaxMarks <= 0 {
+			continue
+		}
+		mark := questionMark{
+			Number:   len(breakdown) + 1,
+			Question: strings.TrimSpace(r.Question),
+			MaxMarks: math.Min(*r.MaxMarks, maxMarksPerItem),
+			Comment:  strings.TrimSpace(r.Comment),
+		}
+		if mark.Question == "" {
+			mark.Question = fmt.Sprintf("Question %d", mark.Number)
+		}
+		if r.Awarded != nil {
+			mark.Awarded = math.Min(math.Round(math.Max(*r.Awarded, 0)*2)/2, mark.MaxMarks)
+		}
+		breakdown = append(breakdown, mark)
+		awarded += mark.Awarded
+		outOf += mark.MaxMarks
+	}
+	return breakdown, awarded, outOf
+}
+
+// gradedReply is the AI provider's marks for one numbered question; question
+// and max_marks are only asked for without a marking scheme
+type gradedReply struct {
+	Number   int      `json:"number"`
+	Question string   `json:"question"`
+	MaxMarks *float64 `json:"max_marks"`
+	Awarded  *float64 `json:"awarded"`
+	Comment  string   `json:"comment"`
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// EXAM TRANSCRIPTIONS (Review, search, copying)
+// ============================================
+
+const (
+	// similarCandidates is how many recent answers to the same class and
+	// subject a submission is compared against
+	similarCandidates = 500
+	// shingleWords is the length of the word runs compared between answers
+	shingleWords = 3
+)
+
+// updateExamTranscription - Correct the transcribed answer; later gradings
+// no longer overwrite it
+func updateExamTranscription(c *gin.Context) {
+	if !callerExamSubmission(c, c.Param("id")) {
+		return
+	}
+	var input struct {
+		Transcription string `json:"transcription"`
+		EditedBy      string `json:"edited_by" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	input.Transcription = strings.TrimSpace(input.Transcription)
+
+	var previous sql.NullString
+	err := db.QueryRow("SELECT transcription FROM mentor.exam_submissions WHERE id = $1", c.Param("id")).Scan(&previous)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Submission not found"})
+		return
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.exam_submissions
+		SET transcription = NULLIF($1, ''), transcription_edited_by = $2, transcription_edited_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`, input.Transcription, input.EditedBy, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("exam_submission", c.Param("id"), "transcription_edited", input.EditedBy, gin.H{
+		"previous": previous.String,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Transcription updated"})
+}
+
+// transcriptShingles is the set of overlapping shingleWords-word runs in a
+// transcription, ignoring case, punctuation and unreadable words
+func transcriptShingles(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(strings.ReplaceAll(text, "[illegible]", " ")), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	shingles := map[string]bool{}
+	for i := 0; i+shingleWords <= len(words); i++ {
+		shingles[strings.Join(words[i:i+shingleWords], " ")] = true
+	}
+	return shingles
+}
+
+// shingleSimilarity is the share of word runs two answers have in common
+// (Jaccard index), from 0 to 1
+func shingleSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for s := range a {
+		if b[s] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// getSimilarExamSubmissions - Other students' answers to the same class and
+// subject (and chapter, when set) whose transcriptions overlap with this one,
+// most similar first, for spotting copied answers. A teacher's submission is
+// only compared with their own students' answers.
+func getSimilarExamSubmissions(c *gin.Context) {
+	if !callerExamSubmission(c, c.Param("id")) {
+		return
+	}
+	minSimilarity, err := strconv.ParseFloat(c.DefaultQuery("min_similarity", "0.5"), 64)
+	if err != nil || minSimilarity <= 0 || minSimilarity > 1 {
+		minSimilarity = 0.5
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	s, err := scanExamSubmission(db.QueryRow("SELECT "+examSubmissionColumns+
+		" FROM mentor.exam_submissions WHERE id = $1", c.Param("id")).Scan)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Submission not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	shingles := transcriptShingles(s.Transcription.String)
+	if len(shingles) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Submission has no transcription to compare yet", "status": s.Status})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT `+examSubmissionColumns+`
+		FROM mentor.exam_submissions
+		WHERE id <> $1 AND class = $2 AND LOWER(subject) = LOWER($3)
+		  AND ($4::int IS NULL OR chapter_number = $4)
+		  AND transcription IS NOT NULL
+		  AND NOT (subscription_id IS NOT DISTINCT FROM $5::int AND LOWER(student_name) = LOWER($6))
+		  AND (NULLIF($8, '') IS NULL OR teacher_id = $8)
+		ORDER BY created_at DESC
+		LIMIT $7
+	`, s.ID, s.Class, s.Subject, s.Chapter, s.SubscriptionID, s.StudentName, similarCandidates, c.GetString("teacher_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type match struct {
+		similarity float64
+		submission gin.H
+	}
+	matches := []match{}
+	compared := 0
+	for rows.Next() {
+		other, err := scanExamSubmission(rows.Scan)
+		if err != nil {
+			continue
+		}
+		compared++
+		similarity := shingleSimilarity(shingles, transcriptShingles(other.Transcription.String))
+		if similarity < minSimilarity {
+			continue
+		}
+		h := other.toH()
+		h["similarity"] = math.Round(similarity*1000) / 1000
+		matches = append(matches, match{similarity, h})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].similarity > matches[j].similarity })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	similar := []gin.H{}
+	for _, m := range matches {
+		similar = append(similar, m.submission)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"submission_id":  s.ID,
+		"min_similarity": minSimilarity,
+		"compared":       compared,
+		"similar":        similar,
+	})
+}
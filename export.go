@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// EXPORTS (CSV / Excel downloads)
+// ============================================
+
+// exportFormat reads ?format= (csv by default, or xlsx), answering 400 for
+// anything else
+func exportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "format must be 'csv' or 'xlsx'"})
+		return "", false
+	}
+	return format, true
+}
+
+// exportCell renders a cell for CSV; the xlsx writer takes the raw values.
+// Text that a spreadsheet would run as a formula (starting with =, +, -, @,
+// tab or carriage return) gets a leading apostrophe.
+func exportCell(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// startExport sends the download headers for filename and writes the header
+// row; rows then go through writeRow, and finish must be called at the end.
+// xlsx cells are inline strings, which spreadsheets never evaluate.
+func startExport(c *gin.Context, format, filename, sheet string, columns []string) (writeRow func([]interface{}), finish func() error, ok bool) {
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == "xlsx" {
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		xw, err := newXLSXWriter(c.Writer, sheet)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return nil, nil, false
+		}
+		writeRow, finish = xw.WriteRow, xw.Close
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(c.Writer)
+		writeRow = func(cells []interface{}) {
+			record := make([]string, len(cells))
+			for i, cell := range cells {
+				record[i] = exportCell(cell)
+			}
+			cw.Write(record)
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = col
+	}
+	writeRow(header)
+	return writeRow, finish, true
+}
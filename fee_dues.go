@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// FEE DUES (This cycle's invoice per subscription)
+// ============================================
+
+// getCycleDues - Every active paid subscription's billing cycle containing
+// ?date= (default today) with its invoice, amount paid and balance, so the
+// admin sees who owes what this cycle. Subscriptions without an invoice for
+// the cycle (e.g. started mid-cycle) are listed as not_invoiced.
+func getCycleDues(c *gin.Context) {
+	day := localToday()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date must be YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+
+	rows, err := db.Query(`
+		SELECT id, student_name, COALESCE(billing_date, 1), COALESCE(amount, 0), currency FROM mentor.subscriptions
+		WHERE status = 'active' AND deleted_at IS NULL AND subscription_type = 'paid' AND amount > 0
+		ORDER BY COALESCE(billing_date, 1), id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	type sub struct {
+		id, billingDay int
+		name           string
+		amount         float64
+		currency       sql.NullString
+	}
+	var subs []sub
+	for rows.Next() {
+		var s sub
+		if err := rows.Scan(&s.id, &s.name, &s.billingDay, &s.amount, &s.currency); err != nil {
+			continue
+		}
+		subs = append(subs, s)
+	}
+	rows.Close()
+
+	dues := []gin.H{}
+	totals := gin.H{"invoiced": 0.0, "paid": 0.0, "outstanding": 0.0, "not_invoiced": 0}
+	for _, s := range subs {
+		start, end := billingCycleFor(day, s.billingDay)
+		due := gin.H{
+			"subscription_id": s.id,
+			"student_name":    s.name,
+			"cycle_start":     start.Format("2006-01-02"),
+			"cycle_end":       end.AddDate(0, 0, -1).Format("2006-01-02"),
+			"monthly_amount":  s.amount,
+			"currency":        orDefaultCurrency(s.currency),
+		}
+
+		var invoiceID sql.NullInt64
+		db.QueryRow(`
+			SELECT id FROM mentor.invoices WHERE subscription_id = $1 AND cycle_start = $2 AND status <> 'void'
+		`, s.id, start).Scan(&invoiceID)
+		if !invoiceID.Valid {
+			due["payment_status"] = "not_invoiced"
+			totals["not_invoiced"] = totals["not_invoiced"].(int) + 1
+			dues = append(dues, due)
+			continue
+		}
+
+		invoices, err := queryInvoices("i.id = $1", invoiceID.Int64)
+		if err != nil || len(invoices) == 0 {
+			continue
+		}
+		inv := invoices[0]
+		for _, key := range []string{"invoice_number", "due_date", "total", "amount_paid", "balance", "payment_status", "currency"} {
+			due[key] = inv[key]
+		}
+		due["invoice_id"] = invoiceID.Int64
+		rate, err := toDefaultCurrency(1, inv["currency"].(string), day)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		totals["invoiced"] = totals["invoiced"].(float64) + rate*inv["total"].(float64)
+		totals["paid"] = totals["paid"].(float64) + rate*math.Min(inv["amount_paid"].(float64), inv["total"].(float64))
+		totals["outstanding"] = totals["outstanding"].(float64) + rate*inv["balance"].(float64)
+		dues = append(dues, due)
+	}
+
+	for _, key := range []string{"invoiced", "paid", "outstanding"} {
+		totals[key] = roundMoney(totals[key].(float64))
+	}
+	totals["currency"] = currencyCode()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"date":    day.Format("2006-01-02"),
+		"dues":    dues,
+		"totals":  totals,
+	})
+}
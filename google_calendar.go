@@ -0,0 +1,781 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// GOOGLE CALENDAR SYNC (Per-teacher OAuth)
+// =====================================================
+
+const (
+	googleAuthURL        = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL       = "https://oauth2.googleapis.com/token"
+	googleRevokeURL      = "https://oauth2.googleapis.com/revoke"
+	googleCalendarAPI    = "https://www.googleapis.com/calendar/v3"
+	googleCalendarScopes = "openid email https://www.googleapis.com/auth/calendar.events"
+
+	googleOAuthStateTTL = 15 * time.Minute
+	googleBusyLookahead = 7 // days of busy events checked for weekly slots
+)
+
+var googleCalendarClient = &http.Client{Timeout: 15 * time.Second}
+
+// googleSyncLocks keeps one sync per teacher at a time, so the job and a
+// reschedule can't both create the same event
+var googleSyncLocks sync.Map
+
+func googleCalendarConfigured() bool {
+	return os.Getenv("GOOGLE_CALENDAR_CLIENT_ID") != "" && os.Getenv("GOOGLE_CALENDAR_CLIENT_SECRET") != ""
+}
+
+// googleCalendarRedirectURL is GOOGLE_CALENDAR_REDIRECT_URL, or the callback
+// on this API; it must be registered with the OAuth client
+func googleCalendarRedirectURL(c *gin.Context) string {
+	if u := os.Getenv("GOOGLE_CALENDAR_REDIRECT_URL"); u != "" {
+		return u
+	}
+	return publicBaseURL(c) + "/api/google-calendar/callback"
+}
+
+// ---------- OAuth tokens ----------
+
+type googleToken struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func googleTokenRequest(form url.Values) (*googleToken, error) {
+	form.Set("client_id", os.Getenv("GOOGLE_CALENDAR_CLIENT_ID"))
+	form.Set("client_secret", os.Getenv("GOOGLE_CALENDAR_CLIENT_SECRET"))
+
+	resp, err := googleCalendarClient.PostForm(googleTokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token googleToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("google token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	return &token, nil
+}
+
+// idTokenEmail reads the email claim of an ID token received directly from
+// Google's token endpoint (so its signature needn't be checked)
+func idTokenEmail(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Email string `json:"email"`
+	}
+	json.Unmarshal(payload, &claims)
+	return claims.Email
+}
+
+// googleCalendarLink is a teacher's connected calendar
+type googleCalendarLink struct {
+	TeacherID   string
+	CalendarID  string
+	AccessToken string
+	BlockBusy   bool
+}
+
+// googleCalendarFor returns the teacher's connected calendar with a fresh
+// access token, or nil when they haven't connected one
+func googleCalendarFor(teacherID string) (*googleCalendarLink, error) {
+	if !googleCalendarConfigured() {
+		return nil, nil
+	}
+
+	link := &googleCalendarLink{TeacherID: teacherID}
+	var refreshToken, accessToken sql.NullString
+	var fresh bool
+	err := db.QueryRow(`
+		SELECT calendar_id, refresh_token, access_token, token_expires_at > NOW() + INTERVAL '1 minute', block_busy
+		FROM mentor.teacher_google_calendars WHERE teacher_id = $1
+	`, teacherID).Scan(&link.CalendarID, &refreshToken, &accessToken, &fresh, &link.BlockBusy)
+	if err == sql.ErrNoRows || (err == nil && !refreshToken.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if accessToken.Valid && fresh {
+		link.AccessToken = accessToken.String
+		return link, nil
+	}
+
+	token, err := googleTokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken.String},
+	})
+	if err != nil {
+		db.Exec("UPDATE mentor.teacher_google_calendars SET last_error = $1 WHERE teacher_id = $2", err.Error(), teacherID)
+		return nil, err
+	}
+	db.Exec(`
+		UPDATE mentor.teacher_google_calendars
+		SET access_token = $1, token_expires_at = NOW() + make_interval(secs => $2)
+		WHERE teacher_id = $3
+	`, token.AccessToken, token.ExpiresIn, teacherID)
+
+	link.AccessToken = token.AccessToken
+	return link, nil
+}
+
+// ---------- Calendar API ----------
+
+// googleAPIError carries the HTTP status so callers can treat 404/410 as gone
+type googleAPIError struct {
+	Status int
+	Body   string
+}
+
+func (e *googleAPIError) Error() string {
+	return fmt.Sprintf("google calendar API %d: %s", e.Status, e.Body)
+}
+
+func eventGone(err error) bool {
+	apiErr, ok := err.(*googleAPIError)
+	return ok && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusGone)
+}
+
+// call sends a Calendar API request for this calendar; path is relative to
+// /calendars/{calendarId}
+func (l *googleCalendarLink) call(method, path string, query url.Values, body, out interface{}) error {
+	u := googleCalendarAPI + "/calendars/" + url.PathEscape(l.CalendarID) + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+l.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := googleCalendarClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		return &googleAPIError{Status: resp.StatusCode, Body: string(msg)}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// googleEvent is the part of a Calendar event we read back
+type googleEvent struct {
+	ID           string `json:"id"`
+	Summary      string `json:"summary"`
+	Status       string `json:"status"`
+	Transparency string `json:"transparency"`
+	Start        struct {
+		DateTime string `json:"dateTime"`
+		Date     string `json:"date"`
+	} `json:"start"`
+	End struct {
+		DateTime string `json:"dateTime"`
+		Date     string `json:"date"`
+	} `json:"end"`
+	ExtendedProperties struct {
+		Private map[string]string `json:"private"`
+	} `json:"extendedProperties"`
+}
+
+// listEvents returns single events overlapping [from, to); extra filters go in query
+func (l *googleCalendarLink) listEvents(from, to time.Time, query url.Values) ([]googleEvent, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("timeMin", from.Format(time.RFC3339))
+	query.Set("timeMax", to.Format(time.RFC3339))
+	query.Set("singleEvents", "true")
+	query.Set("maxResults", "250")
+
+	var events []googleEvent
+	for {
+		var page struct {
+			Items         []googleEvent `json:"items"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := l.call("GET", "/events", query, nil, &page); err != nil {
+			return nil, err
+		}
+		events = append(events, page.Items...)
+		if page.NextPageToken == "" {
+			return events, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// sessionEventBody builds the Calendar event for a class session. Sessions
+// without a class time become all-day events.
+func sessionEventBody(sessionID int, date time.Time, classTime string, minutes int, subject, studentName string, class int, loc *time.Location) gin.H {
+	event := gin.H{
+		"summary":     fmt.Sprintf("%s - %s (Class %d)", subject, studentName, class),
+		"description": "Scheduled by Mentor",
+		"extendedProperties": gin.H{"private": gin.H{
+			"mentor":            "1",
+			"mentor_session_id": strconv.Itoa(sessionID),
+		}},
+	}
+	start, ok := sessionStart(date, classTime, loc)
+	if !ok {
+		event["start"] = gin.H{"date": date.Format("2006-01-02")}
+		event["end"] = gin.H{"date": date.AddDate(0, 0, 1).Format("2006-01-02")}
+		return event
+	}
+	end := start.Add(time.Duration(classMinutesOr(minutes)) * time.Minute)
+	event["start"] = gin.H{"dateTime": start.Format(time.RFC3339)}
+	event["end"] = gin.H{"dateTime": end.Format(time.RFC3339)}
+	if loc != time.UTC && loc.String() != "Local" {
+		event["start"].(gin.H)["timeZone"] = loc.String()
+		event["end"].(gin.H)["timeZone"] = loc.String()
+	}
+	return event
+}
+
+// ---------- Push sync ----------
+
+type googleSyncResult struct {
+	Created, Updated, Deleted int
+}
+
+// syncGoogleCalendar pushes the teacher's sessions from today through the
+// session horizon to their Google Calendar: scheduled sessions become events,
+// changed ones are updated, and cancelled, moved, reassigned or removed
+// sessions have their events deleted. Teachers without a calendar are skipped.
+func syncGoogleCalendar(teacherID string) (googleSyncResult, error) {
+	var result googleSyncResult
+
+	lock, _ := googleSyncLocks.LoadOrStore(teacherID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	link, err := googleCalendarFor(teacherID)
+	if err != nil || link == nil {
+		return result, err
+	}
+
+	today := localToday()
+	until := today.AddDate(0, 0, classSessionHorizonDays)
+	loc := teacherLocation(teacherID)
+
+	rows, err := db.Query(`
+		SELECT cs.id, cs.session_date, COALESCE(cs.session_time, ''), COALESCE(cs.duration_minutes, 0), cs.subject,
+		       s.student_name, s.class, cs.status, COALESCE(cs.teacher_id, ''), COALESCE(cs.google_event_id, ''),
+		       COALESCE(cs.google_event_teacher_id, ''), cs.google_synced_at IS NULL OR cs.updated_at > cs.google_synced_at
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		WHERE cs.session_date BETWEEN $2 AND $3 AND (cs.teacher_id = $1 OR cs.google_event_teacher_id = $1)
+		ORDER BY cs.session_date, cs.id
+	`, teacherID, today, until)
+	if err != nil {
+		return result, err
+	}
+
+	type sessionRow struct {
+		id, minutes, class                  int
+		date                                time.Time
+		classTime, subject, student, status string
+		teacher, eventID, eventTeacher      string
+		dirty                               bool
+	}
+	var sessions []sessionRow
+	for rows.Next() {
+		var s sessionRow
+		if err := rows.Scan(&s.id, &s.date, &s.classTime, &s.minutes, &s.subject, &s.student, &s.class, &s.status,
+			&s.teacher, &s.eventID, &s.eventTeacher, &s.dirty); err == nil {
+			sessions = append(sessions, s)
+		}
+	}
+	rows.Close()
+
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	known := map[string]bool{}
+	for _, s := range sessions {
+		ours := s.eventID != "" && s.eventTeacher == teacherID
+		wanted := s.teacher == teacherID && s.status != "cancelled" && s.status != "rescheduled"
+
+		switch {
+		case ours && !wanted:
+			if err := link.call("DELETE", "/events/"+url.PathEscape(s.eventID), nil, nil, nil); err != nil && !eventGone(err) {
+				fail(err)
+				continue
+			}
+			db.Exec(`
+				UPDATE mentor.class_sessions
+				SET google_event_id = NULL, google_event_teacher_id = NULL, google_synced_at = NOW()
+				WHERE id = $1
+			`, s.id)
+			result.Deleted++
+
+		case wanted && s.eventID == "" && s.status == "scheduled":
+			var created googleEvent
+			body := sessionEventBody(s.id, s.date, s.classTime, s.minutes, s.subject, s.student, s.class, loc)
+			if err := link.call("POST", "/events", nil, body, &created); err != nil {
+				fail(err)
+				continue
+			}
+			db.Exec(`
+				UPDATE mentor.class_sessions
+				SET google_event_id = $1, google_event_teacher_id = $2, google_synced_at = NOW()
+				WHERE id = $3
+			`, created.ID, teacherID, s.id)
+			known[created.ID] = true
+			result.Created++
+
+		case ours && s.dirty:
+			known[s.eventID] = true
+			body := sessionEventBody(s.id, s.date, s.classTime, s.minutes, s.subject, s.student, s.class, loc)
+			if err := link.call("PATCH", "/events/"+url.PathEscape(s.eventID), nil, body, nil); err != nil {
+				fail(err)
+				continue
+			}
+			db.Exec("UPDATE mentor.class_sessions SET google_synced_at = NOW() WHERE id = $1", s.id)
+			result.Updated++
+
+		case ours:
+			known[s.eventID] = true
+		}
+	}
+
+	// Sessions deleted outright (schedule changes) leave events behind
+	events, err := link.listEvents(today, until.AddDate(0, 0, 1), url.Values{"privateExtendedProperty": {"mentor=1"}})
+	if err != nil {
+		fail(err)
+	}
+	for _, e := range events {
+		if known[e.ID] || e.Status == "cancelled" {
+			continue
+		}
+		var tracked bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.class_sessions WHERE google_event_id = $1)", e.ID).Scan(&tracked)
+		if tracked {
+			continue
+		}
+		if err := link.call("DELETE", "/events/"+url.PathEscape(e.ID), nil, nil, nil); err != nil && !eventGone(err) {
+			fail(err)
+			continue
+		}
+		result.Deleted++
+	}
+
+	var lastError interface{}
+	if firstErr != nil {
+		lastError = firstErr.Error()
+	}
+	db.Exec(`
+		UPDATE mentor.teacher_google_calendars SET last_synced_at = NOW(), last_error = $1 WHERE teacher_id = $2
+	`, lastError, teacherID)
+
+	return result, firstErr
+}
+
+// syncGoogleCalendarLater runs a sync in the background after a change
+func syncGoogleCalendarLater(teacherID string) {
+	if teacherID == "" || !googleCalendarConfigured() {
+		return
+	}
+	go func() {
+		if _, err := syncGoogleCalendar(teacherID); err != nil {
+			log.Printf("Google Calendar sync for teacher %s failed: %v", teacherID, err)
+		}
+	}()
+}
+
+// syncAllGoogleCalendars is the background job for every connected teacher
+func syncAllGoogleCalendars() error {
+	if !googleCalendarConfigured() {
+		return nil
+	}
+	rows, err := db.Query("SELECT teacher_id FROM mentor.teacher_google_calendars WHERE refresh_token IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	var teachers []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			teachers = append(teachers, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range teachers {
+		if _, err := syncGoogleCalendar(id); err != nil {
+			log.Printf("Google Calendar sync for teacher %s failed: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// ---------- Busy events ----------
+
+// busyPeriod is a time the teacher is busy in their own calendar
+type busyPeriod struct {
+	Start, End time.Time
+	Summary    string
+}
+
+// googleBusyPeriods returns the teacher's busy events in [from, to), leaving
+// out free (transparent) events and the class events we pushed. nil when the
+// teacher hasn't connected a calendar or turned on block_busy.
+func googleBusyPeriods(teacherID string, from, to time.Time) ([]busyPeriod, error) {
+	link, err := googleCalendarFor(teacherID)
+	if err != nil || link == nil || !link.BlockBusy {
+		return nil, err
+	}
+
+	events, err := link.listEvents(from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := teacherLocation(teacherID)
+	var periods []busyPeriod
+	for _, e := range events {
+		if e.Status == "cancelled" || e.Transparency == "transparent" || e.ExtendedProperties.Private["mentor"] == "1" {
+			continue
+		}
+		var start, end time.Time
+		var err1, err2 error
+		if e.Start.DateTime != "" {
+			start, err1 = time.Parse(time.RFC3339, e.Start.DateTime)
+			end, err2 = time.Parse(time.RFC3339, e.End.DateTime)
+		} else {
+			start, err1 = time.ParseInLocation("2006-01-02", e.Start.Date, loc)
+			end, err2 = time.ParseInLocation("2006-01-02", e.End.Date, loc)
+		}
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		periods = append(periods, busyPeriod{Start: start, End: end, Summary: e.Summary})
+	}
+	return periods, nil
+}
+
+// busyDuring returns the first busy period overlapping [start, end)
+func busyDuring(periods []busyPeriod, start, end time.Time) (busyPeriod, bool) {
+	for _, p := range periods {
+		if start.Before(p.End) && p.Start.Before(end) {
+			return p, true
+		}
+	}
+	return busyPeriod{}, false
+}
+
+// weekBusy checks weekly class slots against the teacher's calendar: a slot
+// is busy when its next occurrence overlaps a busy event
+type weekBusy struct {
+	periods []busyPeriod
+	from    time.Time
+	loc     *time.Location
+}
+
+// googleWeekBusy loads the coming week of busy events; nil when the teacher
+// doesn't block scheduling on their calendar
+func googleWeekBusy(teacherID string) (*weekBusy, error) {
+	loc := teacherLocation(teacherID)
+	from := teacherToday(teacherID).AddDate(0, 0, 1)
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	periods, err := googleBusyPeriods(teacherID, start, start.AddDate(0, 0, googleBusyLookahead))
+	if err != nil || periods == nil {
+		return nil, err
+	}
+	return &weekBusy{periods: periods, from: from, loc: loc}, nil
+}
+
+// at reports the busy event overlapping a visit of minutes from start
+// (minutes after midnight) on the next wd
+func (w *weekBusy) at(wd time.Weekday, start, minutes int) (busyPeriod, bool) {
+	if w == nil {
+		return busyPeriod{}, false
+	}
+	date := w.from.AddDate(0, 0, (int(wd)-int(w.from.Weekday())+7)%7)
+	begin := time.Date(date.Year(), date.Month(), date.Day(), 0, start, 0, 0, w.loc)
+	return busyDuring(w.periods, begin, begin.Add(time.Duration(minutes)*time.Minute))
+}
+
+// ---------- Handlers ----------
+
+// getMyGoogleCalendar - Connection status for the signed-in teacher
+func getMyGoogleCalendar(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+
+	status := gin.H{"available": googleCalendarConfigured(), "connected": false}
+	var email, lastError sql.NullString
+	var calendarID string
+	var blockBusy, connected bool
+	var connectedAt, lastSynced sql.NullTime
+	err := db.QueryRow(`
+		SELECT google_email, calendar_id, block_busy, refresh_token IS NOT NULL, connected_at, last_synced_at, last_error
+		FROM mentor.teacher_google_calendars WHERE teacher_id = $1
+	`, teacherID).Scan(&email, &calendarID, &blockBusy, &connected, &connectedAt, &lastSynced, &lastError)
+	if err == nil && connected {
+		status["connected"] = true
+		status["google_email"] = email.String
+		status["calendar_id"] = calendarID
+		status["block_busy"] = blockBusy
+		status["last_error"] = lastError.String
+		if connectedAt.Valid {
+			status["connected_at"] = isoTimestamp(connectedAt.Time)
+		}
+		if lastSynced.Valid {
+			status["last_synced_at"] = isoTimestamp(lastSynced.Time)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "google_calendar": status})
+}
+
+// connectMyGoogleCalendar - Start the OAuth flow; the app opens auth_url in a browser
+func connectMyGoogleCalendar(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+	if !googleCalendarConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Google Calendar is not configured"})
+		return
+	}
+
+	stateBytes := make([]byte, 24)
+	if _, err := rand.Read(stateBytes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	_, err := db.Exec(`
+		INSERT INTO mentor.teacher_google_calendars (teacher_id, oauth_state, oauth_state_expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (teacher_id) DO UPDATE SET oauth_state = EXCLUDED.oauth_state,
+		    oauth_state_expires_at = EXCLUDED.oauth_state_expires_at
+	`, teacherID, state, int(googleOAuthStateTTL.Seconds()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	authURL := googleAuthURL + "?" + url.Values{
+		"client_id":     {os.Getenv("GOOGLE_CALENDAR_CLIENT_ID")},
+		"redirect_uri":  {googleCalendarRedirectURL(c)},
+		"response_type": {"code"},
+		"scope":         {googleCalendarScopes},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+		"state":         {state},
+	}.Encode()
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "auth_url": authURL})
+}
+
+// googleCalendarCallback - Google redirects here after the teacher consents
+func googleCalendarCallback(c *gin.Context) {
+	page := func(status int, message string) {
+		c.Data(status, "text/html; charset=utf-8", []byte(
+			"<!doctype html><meta name=viewport content=\"width=device-width\"><p>"+message+"</p>"))
+	}
+
+	state := c.Query("state")
+	var teacherID string
+	err := db.QueryRow(`
+		SELECT teacher_id FROM mentor.teacher_google_calendars
+		WHERE oauth_state = $1 AND oauth_state_expires_at > NOW()
+	`, state).Scan(&teacherID)
+	if state == "" || err != nil {
+		page(http.StatusBadRequest, "This link has expired. Start again from the app.")
+		return
+	}
+	if c.Query("error") != "" || c.Query("code") == "" {
+		db.Exec("UPDATE mentor.teacher_google_calendars SET oauth_state = NULL WHERE teacher_id = $1", teacherID)
+		page(http.StatusOK, "Google Calendar was not connected. You can close this page.")
+		return
+	}
+
+	token, err := googleTokenRequest(url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {c.Query("code")},
+		"redirect_uri": {googleCalendarRedirectURL(c)},
+	})
+	if err != nil || token.RefreshToken == "" {
+		page(http.StatusBadGateway, "Google did not grant calendar access. Please try again.")
+		return
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.teacher_google_calendars
+		SET refresh_token = $1, access_token = $2, token_expires_at = NOW() + make_interval(secs => $3),
+		    google_email = NULLIF($4, ''), oauth_state = NULL, oauth_state_expires_at = NULL,
+		    connected_at = NOW(), last_error = NULL
+		WHERE teacher_id = $5
+	`, token.RefreshToken, token.AccessToken, token.ExpiresIn, idTokenEmail(token.IDToken), teacherID)
+	if err != nil {
+		page(http.StatusInternalServerError, "Could not save the connection. Please try again.")
+		return
+	}
+
+	// Events from a previous connection belong to that calendar
+	db.Exec(`
+		UPDATE mentor.class_sessions SET google_event_id = NULL, google_event_teacher_id = NULL, google_synced_at = NULL
+		WHERE google_event_teacher_id = $1
+	`, teacherID)
+
+	logAudit("teacher", teacherID, "google_calendar_connected", teacherID, nil)
+	syncGoogleCalendarLater(teacherID)
+
+	page(http.StatusOK, "Google Calendar connected. Your classes will appear shortly; you can return to the app.")
+}
+
+// updateMyGoogleCalendar - Choose the calendar and whether busy events block scheduling
+func updateMyGoogleCalendar(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+
+	var input struct {
+		BlockBusy  *bool   `json:"block_busy"`
+		CalendarID *string `json:"calendar_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if input.CalendarID != nil && strings.TrimSpace(*input.CalendarID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "calendar_id must not be empty"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.teacher_google_calendars
+		SET block_busy = COALESCE($1, block_busy), calendar_id = COALESCE($2, calendar_id)
+		WHERE teacher_id = $3 AND refresh_token IS NOT NULL
+	`, input.BlockBusy, input.CalendarID, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Google Calendar is not connected"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Google Calendar settings updated"})
+}
+
+// syncMyGoogleCalendar - Push changes now instead of waiting for the job
+func syncMyGoogleCalendar(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+
+	var connected bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.teacher_google_calendars WHERE teacher_id = $1 AND refresh_token IS NOT NULL)
+	`, teacherID).Scan(&connected)
+	if !connected || !googleCalendarConfigured() {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Google Calendar is not connected"})
+		return
+	}
+
+	result, err := syncGoogleCalendar(teacherID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"created": result.Created,
+		"updated": result.Updated,
+		"deleted": result.Deleted,
+	})
+}
+
+// disconnectMyGoogleCalendar - Remove upcoming class events, revoke access and forget the calendar
+func disconnectMyGoogleCalendar(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+
+	if link, err := googleCalendarFor(teacherID); err == nil && link != nil {
+		rows, err := db.Query(`
+			SELECT google_event_id FROM mentor.class_sessions
+			WHERE google_event_teacher_id = $1 AND session_date >= $2
+		`, teacherID, localToday())
+		if err == nil {
+			var eventIDs []string
+			for rows.Next() {
+				var id string
+				if rows.Scan(&id) == nil {
+					eventIDs = append(eventIDs, id)
+				}
+			}
+			rows.Close()
+			for _, id := range eventIDs {
+				link.call("DELETE", "/events/"+url.PathEscape(id), nil, nil, nil)
+			}
+		}
+
+		var refreshToken string
+		db.QueryRow("SELECT refresh_token FROM mentor.teacher_google_calendars WHERE teacher_id = $1", teacherID).Scan(&refreshToken)
+		googleCalendarClient.PostForm(googleRevokeURL, url.Values{"token": {refreshToken}})
+	}
+
+	db.Exec(`
+		UPDATE mentor.class_sessions SET google_event_id = NULL, google_event_teacher_id = NULL, google_synced_at = NULL
+		WHERE google_event_teacher_id = $1
+	`, teacherID)
+	db.Exec("DELETE FROM mentor.teacher_google_calendars WHERE teacher_id = $1", teacherID)
+
+	logAudit("teacher", teacherID, "google_calendar_disconnected", teacherID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Google Calendar disconnected"})
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// GRADING RUBRICS (Shared criteria for AI exam grading)
+// =====================================================
+
+const (
+	maxRubricCriteria    = 20
+	maxRubricDescriptors = 10
+)
+
+// rubricDescriptor says what an answer at one level of a criterion looks like
+type rubricDescriptor struct {
+	Level       string `json:"level"`
+	Description string `json:"description"`
+}
+
+// rubricCriterion is one thing answers are judged on, weighted in percent
+type rubricCriterion struct {
+	Name        string             `json:"name"`
+	Weight      float64            `json:"weight"`
+	Descriptors []rubricDescriptor `json:"descriptors"` // best level first
+}
+
+// gradingRubric is a reusable rubric for a subject, optionally one class
+type gradingRubric struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Class       *int              `json:"class"` // nil: every class
+	Subject     string            `json:"subject"`
+	Description string            `json:"description"`
+	Criteria    []rubricCriterion `json:"criteria"`
+	CreatedBy   string            `json:"created_by"`
+}
+
+// fieldErrors checks the rubric is complete and its weights add up to 100
+func (r *gradingRubric) fieldErrors() map[string]string {
+	fields := map[string]string{}
+	r.Name = strings.TrimSpace(r.Name)
+	r.Subject = strings.TrimSpace(r.Subject)
+	r.Description = strings.TrimSpace(r.Description)
+
+	if r.Name == "" {
+		fields["name"] = "is required"
+	}
+	if r.Subject == "" {
+		fields["subject"] = "is required"
+	}
+	if r.Class != nil && *r.Class <= 0 {
+		fields["class"] = "must be positive"
+	}
+	if len(r.Criteria) == 0 || len(r.Criteria) > maxRubricCriteria {
+		fields["criteria"] = fmt.Sprintf("must have 1 to %d criteria", maxRubricCriteria)
+		return fields
+	}
+
+	total := 0.0
+	for i := range r.Criteria {
+		criterion := &r.Criteria[i]
+		key := fmt.Sprintf("criteria[%d]", i)
+		criterion.Name = strings.TrimSpace(criterion.Name)
+		if criterion.Name == "" {
+			fields[key+".name"] = "is required"
+		}
+		if criterion.Weight <= 0 {
+			fields[key+".weight"] = "must be positive"
+		}
+		total += criterion.Weight
+
+		if len(criterion.Descriptors) == 0 || len(criterion.Descriptors) > maxRubricDescriptors {
+			fields[key+".descriptors"] = fmt.Sprintf("must have 1 to %d levels", maxRubricDescriptors)
+			continue
+		}
+		for j := range criterion.Descriptors {
+			d := &criterion.Descriptors[j]
+			d.Level, d.Description = strings.TrimSpace(d.Level), strings.TrimSpace(d.Description)
+			if d.Level == "" || d.Description == "" {
+				fields[fmt.Sprintf("%s.descriptors[%d]", key, j)] = "needs a level and a description"
+			}
+		}
+	}
+	if _, ok := fields["criteria"]; !ok && math.Abs(total-100) > 0.01 {
+		fields["criteria"] = fmt.Sprintf("weights must add up to 100 (they add up to %g)", total)
+	}
+	return fields
+}
+
+// appliesTo reports whether the rubric can grade a class/subject
+func (r gradingRubric) appliesTo(class int, subject string) bool {
+	return strings.EqualFold(r.Subject, subject) && (r.Class == nil || *r.Class == class)
+}
+
+// prompt describes the rubric for the AI provider
+func (r gradingRubric) prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Use the rubric \"%s\" when deciding marks, so answers are judged the same way by every teacher. "+
+		"Weigh each criterion by its share of the marks:\n", r.Name)
+	for _, criterion := range r.Criteria {
+		fmt.Fprintf(&b, "- %s (%g%%):", criterion.Name, criterion.Weight)
+		for _, d := range criterion.Descriptors {
+			fmt.Fprintf(&b, " %s: %s;", d.Level, d.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+const gradingRubricColumns = `id, name, class, subject, COALESCE(description, ''), criteria::text, COALESCE(created_by, '')`
+
+// scanGradingRubric reads the gradingRubricColumns of one row
+func scanGradingRubric(scan func(...interface{}) error) (gradingRubric, error) {
+	var r gradingRubric
+	var class sql.NullInt64
+	var criteria string
+	err := scan(&r.ID, &r.Name, &class, &r.Subject, &r.Description, &criteria, &r.CreatedBy)
+	if err != nil {
+		return r, err
+	}
+	if class.Valid {
+		n := int(class.Int64)
+		r.Class = &n
+	}
+	json.Unmarshal([]byte(criteria), &r.Criteria)
+	return r, nil
+}
+
+// loadGradingRubric finds a rubric by id
+func loadGradingRubric(id interface{}) (gradingRubric, error) {
+	return scanGradingRubric(db.QueryRow("SELECT "+gradingRubricColumns+" FROM mentor.grading_rubrics WHERE id = $1", id).Scan)
+}
+
+// getGradingRubrics - Rubrics (subject filter; class matches rubrics for that
+// class and for every class)
+func getGradingRubrics(c *gin.Context) {
+	query := "SELECT " + gradingRubricColumns + " FROM mentor.grading_rubrics WHERE 1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	if subject := c.Query("subject"); subject != "" {
+		argCount++
+		query += fmt.Sprintf(" AND LOWER(subject) = LOWER($%d)", argCount)
+		args = append(args, subject)
+	}
+	if class := c.Query("class"); class != "" {
+		argCount++
+		query += fmt.Sprintf(" AND (class IS NULL OR class = $%d)", argCount)
+		args = append(args, class)
+	}
+	query += " ORDER BY subject, class NULLS FIRST, name"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	rubrics := []gradingRubric{}
+	for rows.Next() {
+		r, err := scanGradingRubric(rows.Scan)
+		if err != nil {
+			continue
+		}
+		rubrics = append(rubrics, r)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "rubrics": rubrics})
+}
+
+// getGradingRubric - One rubric with its criteria
+func getGradingRubric(c *gin.Context) {
+	r, err := loadGradingRubric(c.Param("id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Rubric not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "rubric": r})
+}
+
+// createGradingRubric - Add a rubric
+func createGradingRubric(c *gin.Context) {
+	var input gradingRubric
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if fields := input.fieldErrors(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	if teacherID := c.GetString("teacher_id"); teacherID != "" {
+		input.CreatedBy = teacherID
+	}
+
+	criteria, _ := json.Marshal(input.Criteria)
+	err := db.QueryRow(`
+		INSERT INTO mentor.grading_rubrics (name, class, subject, description, criteria, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''))
+		RETURNING id
+	`, input.Name, input.Class, input.Subject, input.Description, string(criteria), input.CreatedBy).Scan(&input.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "rubric": input})
+}
+
+// updateGradingRubric - Replace a rubric; gradings already done keep the copy
+// they were graded with. Teachers can only change rubrics they created.
+func updateGradingRubric(c *gin.Context) {
+	var input gradingRubric
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if fields := input.fieldErrors(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	criteria, _ := json.Marshal(input.Criteria)
+	result, err := db.Exec(`
+		UPDATE mentor.grading_rubrics
+		SET name = $1, class = $2, subject = $3, description = NULLIF($4, ''), criteria = $5, updated_at = NOW()
+		WHERE id = $6 AND ($7 = '' OR created_by = $7)
+	`, input.Name, input.Class, input.Subject, input.Description, string(criteria), c.Param("id"), c.GetString("teacher_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Rubric not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Rubric updated"})
+}
+
+// deleteGradingRubric - Remove a rubric; submissions that chose it are graded
+// without one from then on
+func deleteGradingRubric(c *gin.Context) {
+	result, err := db.Exec("DELETE FROM mentor.grading_rubrics WHERE id = $1", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Rubric not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Rubric deleted"})
+}
+
+// examRubric loads the rubric chosen for a submission and checks it fits the
+// submission's class and subject; the message is for the rubric_id field
+func examRubric(id *int, class int, subject string) (*gradingRubric, string) {
+	if id == nil {
+		return nil, ""
+	}
+	r, err := loadGradingRubric(*id)
+	if err != nil {
+		return nil, "is not a rubric"
+	}
+	if !r.appliesTo(class, subject) {
+		return nil, "is for another class or subject"
+	}
+	return &r, ""
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// GUARDIAN ATTENDANCE (Visits without GPS)
+// ============================================
+
+// getSubscriptionAttendance - A subscription's visits over a date range
+// (default the last 30 days) for the guardian: date, subject, check-in/out
+// times and duration, with no coordinates, photos or notes
+func getSubscriptionAttendance(c *gin.Context) {
+	subId := c.Param("id")
+
+	from, to, ok := parsePastDateRange(c, 30)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT v.visit_date, COALESCE(cs.subject, ''), COALESCE(t.name, ''), v.mode, v.started_at, v.ended_at,
+		       v.duration_minutes, v.missing_check_out, v.missing_check_in,
+		       NOT (COALESCE(ai.is_corrected, FALSE) OR COALESCE(ao.is_corrected, FALSE))
+		FROM mentor.visits v
+		LEFT JOIN mentor.class_sessions cs ON cs.id = v.class_session_id
+		LEFT JOIN mentor.teachers t ON t.id = v.teacher_id
+		LEFT JOIN mentor.attendance ai ON ai.id = v.start_attendance_id
+		LEFT JOIN mentor.attendance ao ON ao.id = v.end_attendance_id
+		WHERE v.subscription_id = $1 AND v.visit_date BETWEEN $2 AND $3
+		ORDER BY COALESCE(v.started_at, v.ended_at) DESC
+	`, subId, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	visits := []gin.H{}
+	totalMinutes := 0
+	for rows.Next() {
+		var date time.Time
+		var subject, teacherName, mode string
+		var startedAt, endedAt sql.NullTime
+		var minutes sql.NullInt64
+		var missingOut, missingIn, verified bool
+		if err := rows.Scan(&date, &subject, &teacherName, &mode, &startedAt, &endedAt, &minutes,
+			&missingOut, &missingIn, &verified); err != nil {
+			continue
+		}
+
+		visit := gin.H{
+			"date":             date.Format("2006-01-02"),
+			"subject":          subject,
+			"teacher_name":     teacherName,
+			"mode":             mode,
+			"status":           visitStatus(missingOut, missingIn, endedAt.Valid),
+			"duration_minutes": nil,
+			"verified":         verified,
+		}
+		if startedAt.Valid {
+			visit["checked_in_at"] = isoTimestamp(startedAt.Time)
+		}
+		if endedAt.Valid {
+			visit["checked_out_at"] = isoTimestamp(endedAt.Time)
+		}
+		if minutes.Valid {
+			visit["duration_minutes"] = minutes.Int64
+			totalMinutes += int(minutes.Int64)
+		}
+		visits = append(visits, visit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"from":          from.Format("2006-01-02"),
+		"to":            to.Format("2006-01-02"),
+		"visits":        visits,
+		"total_minutes": totalMinutes,
+	})
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// GUARDIAN REPORTS (AI-drafted monthly summaries)
+// =====================================================
+
+// collectReportData gathers the structured facts a monthly report is written from
+func collectReportData(subId string, year, month int) (gin.H, error) {
+	var studentName, teacherID string
+	var guardianName, teacherName sql.NullString
+	var class int
+	err := db.QueryRow(`
+		SELECT s.student_name, s.guardian_name, s.class, s.teacher_id, t.name
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.teachers t ON t.id = s.teacher_id
+		WHERE s.id = $1 AND s.deleted_at IS NULL
+	`, subId).Scan(&studentName, &guardianName, &class, &teacherID, &teacherName)
+	if err != nil {
+		return nil, err
+	}
+
+	monthStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	prevStart := monthStart.AddDate(0, -1, 0)
+
+	// Classes completed per subject this month
+	subjects := []gin.H{}
+	totalClasses := 0
+	rows, err := db.Query(`
+		SELECT p.subject, COUNT(*), MIN(p.chapter), MAX(p.chapter)
+		FROM mentor.progress p
+		WHERE p.subscription_id = $1 AND p.completed_at >= $2 AND p.completed_at < $3
+		GROUP BY p.subject
+		ORDER BY p.subject
+	`, subId, monthStart, monthEnd)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var subject string
+		var count, fromChapter, toChapter int
+		if err := rows.Scan(&subject, &count, &fromChapter, &toChapter); err != nil {
+			continue
+		}
+		totalClasses += count
+		subjects = append(subjects, gin.H{
+			"subject":       subject,
+			"classes":       count,
+			"chapters_from": fromChapter,
+			"chapters_to":   toChapter,
+		})
+	}
+	rows.Close()
+
+	// Test scores this month vs last month, per subject
+	scores := []gin.H{}
+	scoreRows, err := db.Query(`
+		SELECT subject,
+		       AVG(actual_marks::float / NULLIF(total_marks, 0) * 100) FILTER (WHERE created_at >= $2),
+		       AVG(actual_marks::float / NULLIF(total_marks, 0) * 100) FILTER (WHERE created_at < $2)
+		FROM mentor.answer_papers
+		WHERE subscription_id = $1 AND status = 'graded' AND created_at >= $3 AND created_at < $4
+		GROUP BY subject
+	`, subId, monthStart, prevStart, monthEnd)
+	if err == nil {
+		for scoreRows.Next() {
+			var subject string
+			var current, previous sql.NullFloat64
+			if err := scoreRows.Scan(&subject, &current, &previous); err != nil || !current.Valid {
+				continue
+			}
+			score := gin.H{"subject": subject, "average_percent": current.Float64}
+			if previous.Valid {
+				score["previous_percent"] = previous.Float64
+				switch {
+				case current.Float64 > previous.Float64+5:
+					score["trend"] = "improved"
+				case current.Float64 < previous.Float64-5:
+					score["trend"] = "declined"
+				default:
+					score["trend"] = "steady"
+				}
+			}
+			scores = append(scores, score)
+		}
+		scoreRows.Close()
+	}
+
+	var visits int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM mentor.attendance
+		WHERE subscription_id = $1 AND action = 'start' AND recorded_at >= $2 AND recorded_at < $3
+	`, subId, monthStart, monthEnd).Scan(&visits)
+
+	return gin.H{
+		"student_name":      studentName,
+		"guardian_name":     guardianName.String,
+		"class":             class,
+		"teacher_name":      teacherName.String,
+		"month":             monthStart.Format("January 2006"),
+		"classes_completed": totalClasses,
+		"teacher_visits":    visits,
+		"subjects":          subjects,
+		"test_scores":       scores,
+	}, nil
+}
+
+// templateReportSummary is used when no AI provider is configured or it fails
+func templateReportSummary(data gin.H) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s completed %v classes in %s.", data["student_name"], data["classes_completed"], data["month"])
+
+	if subjects, ok := data["subjects"].([]gin.H); ok && len(subjects) > 0 {
+		var parts []string
+		for _, s := range subjects {
+			parts = append(parts, fmt.Sprintf("%s (%v classes, up to chapter %v)", s["subject"], s["classes"], s["chapters_to"]))
+		}
+		fmt.Fprintf(&b, " Subjects covered: %s.", strings.Join(parts, ", "))
+	}
+
+	if scores, ok := data["test_scores"].([]gin.H); ok {
+		for _, s := range scores {
+			fmt.Fprintf(&b, " %s test average: %.0f%%", s["subject"], s["average_percent"])
+			if trend, ok := s["trend"].(string); ok {
+				fmt.Fprintf(&b, " (%s)", trend)
+			}
+			b.WriteString(".")
+		}
+	}
+
+	return b.String()
+}
+
+// generateGuardianReport - Draft a monthly guardian summary for a subscription
+func generateGuardianReport(c *gin.Context) {
+	subId := c.Param("id")
+
+	now := time.Now()
+	year, _ := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(now.Year())))
+	month, _ := strconv.Atoi(c.DefaultQuery("month", strconv.Itoa(int(now.Month()))))
+	if month < 1 || month > 12 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid month"})
+		return
+	}
+
+	data, err := collectReportData(subId, year, month)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	summary := templateReportSummary(data)
+	generatedBy := "template"
+
+	if aiConfigured() {
+		dataJSON, _ := json.MarshalIndent(data, "", "  ")
+		prompt := "You write short monthly progress messages from a home tutor to a student's guardian. " +
+			"Using only the facts in this JSON, write 3-5 warm, plain sentences (no markdown, no greeting line). " +
+			"Mention classes completed, subjects covered, and any test score trend. Do not invent facts.\n\n" +
+			string(dataJSON)
+
+		if text, err := generateText(prompt); err == nil {
+			summary = strings.TrimSpace(text)
+			generatedBy = "ai"
+		} else {
+			log.Println("Warning: AI report generation failed, using template:", err)
+		}
+	}
+
+	dataJSON, _ := json.Marshal(data)
+	var reportID int
+	err = db.QueryRow(`
+		INSERT INTO mentor.guardian_reports (subscription_id, year, month, data, summary, generated_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, subId, year, month, string(dataJSON), summary, generatedBy).Scan(&reportID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"id":           reportID,
+		"summary":      summary,
+		"generated_by": generatedBy,
+		"data":         data,
+		"status":       "draft",
+	})
+}
+
+// getGuardianReports - List report drafts and sent reports for a subscription
+func getGuardianReports(c *gin.Context) {
+	subId := c.Param("id")
+
+	rows, err := db.Query(`
+		SELECT id, year, month, summary, generated_by, status, edited_by, sent_via, sent_at, created_at
+		FROM mentor.guardian_reports
+		WHERE subscription_id = $1
+		ORDER BY year DESC, month DESC, created_at DESC
+	`, subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var reports []gin.H
+	for rows.Next() {
+		var id, year, month int
+		var summary, status string
+		var generatedBy, editedBy, sentVia sql.NullString
+		var sentAt sql.NullTime
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &year, &month, &summary, &generatedBy, &status, &editedBy, &sentVia, &sentAt, &createdAt); err != nil {
+			continue
+		}
+
+		report := gin.H{
+			"id":           id,
+			"year":         year,
+			"month":        month,
+			"summary":      summary,
+			"generated_by": generatedBy.String,
+			"status":       status,
+			"edited_by":    editedBy.String,
+			"sent_via":     sentVia.String,
+			"created_at":   isoTimestamp(createdAt),
+		}
+		if sentAt.Valid {
+			report["sent_at"] = isoTimestamp(sentAt.Time)
+		}
+		reports = append(reports, report)
+	}
+
+	if reports == nil {
+		reports = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "reports": reports})
+}
+
+// updateGuardianReport - Teacher edits the draft before sending
+func updateGuardianReport(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Summary  string `json:"summary"`
+		EditedBy string `json:"edited_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(input.Summary) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "summary is required"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.guardian_reports
+		SET summary = $1, edited_by = $2, updated_at = NOW()
+		WHERE id = $3 AND status = 'draft'
+	`, input.Summary, input.EditedBy, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Draft report not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Report updated"})
+}
+
+// sendGuardianReport - Send the (edited) report to the guardian
+func sendGuardianReport(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Channel string `json:"channel"` // "sms" or "whatsapp"
+	}
+	c.ShouldBindJSON(&input)
+	if input.Channel == "" {
+		input.Channel = "whatsapp"
+	}
+
+	var subId int
+	var summary, status string
+	var guardianPhone, editedBy sql.NullString
+	err := db.QueryRow(`
+		SELECT r.subscription_id, r.summary, r.status, s.guardian_phone, r.edited_by
+		FROM mentor.guardian_reports r
+		JOIN mentor.subscriptions s ON s.id = r.subscription_id
+		WHERE r.id = $1
+	`, id).Scan(&subId, &summary, &status, &guardianPhone, &editedBy)
+
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Report not found"})
+		return
+	}
+	if status == "sent" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Report already sent"})
+		return
+	}
+
+	if err := notifySubscription(subId, input.Channel, guardianPhone.String, "monthly_report", summary, editedBy.String); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Failed to send: " + err.Error()})
+		return
+	}
+
+	db.Exec(`
+		UPDATE mentor.guardian_reports
+		SET status = 'sent', sent_via = $1, sent_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`, input.Channel, id)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Report sent"})
+}
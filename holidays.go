@@ -0,0 +1,286 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// HOLIDAYS (Everyone off, or one teacher)
+// ============================================
+
+// maxHolidayRangeDays caps how many dates one request can add
+const maxHolidayRangeDays = 60
+
+// upcomingHolidayDays is how far ahead schedule responses list holidays
+const upcomingHolidayDays = 30
+
+// holidayOn returns the holiday name if date is off for everyone or for teacherID
+func holidayOn(date, teacherID string) (string, bool) {
+	var name string
+	err := db.QueryRow(`
+		SELECT name FROM mentor.holidays
+		WHERE date = $1 AND (teacher_id IS NULL OR teacher_id = $2)
+		ORDER BY teacher_id NULLS FIRST LIMIT 1
+	`, date, teacherID).Scan(&name)
+	return name, err == nil
+}
+
+// upcomingHolidays lists the holidays from today through the next
+// upcomingHolidayDays that apply to teacherID ("" for everyone's only)
+func upcomingHolidays(teacherID string) []gin.H {
+	holidays := []gin.H{}
+	today := localToday()
+	rows, err := db.Query(`
+		SELECT date, name, COALESCE(type, 'public'), teacher_id IS NOT NULL
+		FROM mentor.holidays
+		WHERE date BETWEEN $1 AND $2 AND (teacher_id IS NULL OR teacher_id = $3)
+		ORDER BY date
+	`, today, today.AddDate(0, 0, upcomingHolidayDays), teacherID)
+	if err != nil {
+		return holidays
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date time.Time
+		var name, holidayType string
+		var personal bool
+		if rows.Scan(&date, &name, &holidayType, &personal) != nil {
+			continue
+		}
+		holidays = append(holidays, gin.H{
+			"date":     date.Format("2006-01-02"),
+			"name":     name,
+			"type":     holidayType,
+			"personal": personal,
+		})
+	}
+	return holidays
+}
+
+// getHolidays - Holidays over a range; with teacher_id, everyone's plus that teacher's
+func getHolidays(c *gin.Context) {
+	from := localToday()
+	to := from.AddDate(1, 0, 0)
+	var err error
+	if f := c.Query("from"); f != "" {
+		if from, err = time.ParseInLocation("2006-01-02", f, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from must be YYYY-MM-DD"})
+			return
+		}
+	}
+	if t := c.Query("to"); t != "" {
+		if to, err = time.ParseInLocation("2006-01-02", t, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "to must be YYYY-MM-DD"})
+			return
+		}
+	}
+
+	query := `
+		SELECT id, date, name, COALESCE(type, 'public'), COALESCE(teacher_id, '')
+		FROM mentor.holidays
+		WHERE date BETWEEN $1 AND $2
+	`
+	args := []interface{}{from, to}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		query += " AND (teacher_id IS NULL OR teacher_id = $3)"
+		args = append(args, teacherID)
+	}
+	query += " ORDER BY date, teacher_id NULLS FIRST"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	holidays := []gin.H{}
+	for rows.Next() {
+		var id int
+		var date time.Time
+		var name, holidayType, teacherID string
+		if err := rows.Scan(&id, &date, &name, &holidayType, &teacherID); err != nil {
+			continue
+		}
+		holidays = append(holidays, gin.H{
+			"id":         id,
+			"date":       date.Format("2006-01-02"),
+			"name":       name,
+			"type":       holidayType,
+			"teacher_id": teacherID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "holidays": holidays})
+}
+
+// createHolidays - Add a holiday on one date or every date of a range. Saving
+// an existing date and scope renames it.
+func createHolidays(c *gin.Context) {
+	var input struct {
+		Date      string `json:"date"`       // YYYY-MM-DD, or:
+		StartDate string `json:"start_date"` // range, inclusive
+		EndDate   string `json:"end_date"`
+		Name      string `json:"name"`
+		Type      string `json:"type"`       // public (default), school, personal, ...
+		TeacherID string `json:"teacher_id"` // only this teacher is off
+		CreatedBy string `json:"created_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "name is required"})
+		return
+	}
+	if input.Type == "" {
+		input.Type = "public"
+	}
+	if input.Date != "" {
+		input.StartDate, input.EndDate = input.Date, input.Date
+	}
+
+	start, err1 := time.ParseInLocation("2006-01-02", input.StartDate, time.Local)
+	end, err2 := time.ParseInLocation("2006-01-02", input.EndDate, time.Local)
+	if err1 != nil || err2 != nil || end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Give date, or start_date and end_date (YYYY-MM-DD, in order)"})
+		return
+	}
+	if end.Sub(start) >= maxHolidayRangeDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("A range can cover at most %d days", maxHolidayRangeDays)})
+		return
+	}
+
+	if input.TeacherID != "" {
+		var exists bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1)", input.TeacherID).Scan(&exists)
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Teacher not found"})
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	ids := []int{}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		var id int
+		err := tx.QueryRow(`
+			INSERT INTO mentor.holidays (date, name, type, teacher_id, created_by)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+			ON CONFLICT (date, (COALESCE(teacher_id, ''))) DO UPDATE SET name = EXCLUDED.name, type = EXCLUDED.type
+			RETURNING id
+		`, d.Format("2006-01-02"), input.Name, input.Type, input.TeacherID, input.CreatedBy).Scan(&id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// Drop the sessions planned on the new holidays
+	syncClassSessionsAhead(start, end, input.TeacherID)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "ids": ids, "days": len(ids), "message": "Holiday saved"})
+}
+
+// updateHoliday - Rename or retype a holiday
+func updateHoliday(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.holidays
+		SET name = COALESCE(NULLIF($1, ''), name), type = COALESCE(NULLIF($2, ''), type)
+		WHERE id = $3
+	`, strings.TrimSpace(input.Name), input.Type, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Holiday not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Holiday updated"})
+}
+
+// deleteHoliday - Remove one holiday; its classes are planned again
+func deleteHoliday(c *gin.Context) {
+	id := c.Param("id")
+
+	var date time.Time
+	var teacherID sql.NullString
+	err := db.QueryRow("DELETE FROM mentor.holidays WHERE id = $1 RETURNING date, teacher_id", id).Scan(&date, &teacherID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Holiday not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	syncClassSessionsAhead(date, date, teacherID.String)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Holiday deleted"})
+}
+
+// deleteHolidayRange - Remove every holiday of one scope between from and to
+func deleteHolidayRange(c *gin.Context) {
+	from, err1 := time.ParseInLocation("2006-01-02", c.Query("from"), time.Local)
+	to, err2 := time.ParseInLocation("2006-01-02", c.Query("to"), time.Local)
+	if err1 != nil || err2 != nil || to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from and to are required (YYYY-MM-DD, in order)"})
+		return
+	}
+	teacherID := c.Query("teacher_id")
+
+	result, err := db.Exec(`
+		DELETE FROM mentor.holidays
+		WHERE date BETWEEN $1 AND $2 AND COALESCE(teacher_id, '') = $3
+	`, from, to, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	n, _ := result.RowsAffected()
+	if n > 0 {
+		syncClassSessionsAhead(from, to, teacherID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "deleted": n, "message": "Holidays deleted"})
+}
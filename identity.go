@@ -0,0 +1,355 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// IDENTITY PROVIDERS (Google Sign-In / generic OIDC)
+// =====================================================
+
+// identityProvider verifies a token issued by an external sign-in service
+type identityProvider interface {
+	Name() string
+	Verify(token string) (*externalIdentity, error)
+}
+
+// externalIdentity is the provider-independent result of a verified token
+type externalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// getIdentityProvider returns the named provider if it is configured
+func getIdentityProvider(name string) identityProvider {
+	switch {
+	case name == "google" && os.Getenv("GOOGLE_CLIENT_IDS") != "":
+		return &googleIdentityProvider{clientIDs: strings.Split(os.Getenv("GOOGLE_CLIENT_IDS"), ",")}
+	case os.Getenv("OIDC_USERINFO_URL") != "" && name == oidcProviderName():
+		return &oidcIdentityProvider{name: name, userinfoURL: os.Getenv("OIDC_USERINFO_URL")}
+	}
+	return nil
+}
+
+func oidcProviderName() string {
+	if name := os.Getenv("OIDC_PROVIDER_NAME"); name != "" {
+		return name
+	}
+	return "oidc"
+}
+
+var identityClient = &http.Client{Timeout: 10 * time.Second}
+
+// ---------- Google ----------
+
+type googleIdentityProvider struct {
+	clientIDs []string // Android + web client IDs accepted as the token audience
+}
+
+func (p *googleIdentityProvider) Name() string { return "google" }
+
+// Verify checks a Google ID token with Google's tokeninfo endpoint
+func (p *googleIdentityProvider) Verify(idToken string) (*externalIdentity, error) {
+	resp, err := identityClient.Get("https://oauth2.googleapis.com/tokeninfo?id_token=" + url.QueryEscape(idToken))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google rejected the token (%d)", resp.StatusCode)
+	}
+
+	var info struct {
+		Aud           string `json:"aud"`
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified string `json:"email_verified"`
+		Name          string `json:"name"`
+		Iss           string `json:"iss"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+
+	if info.Iss != "accounts.google.com" && info.Iss != "https://accounts.google.com" {
+		return nil, fmt.Errorf("unexpected issuer %q", info.Iss)
+	}
+	audienceOK := false
+	for _, id := range p.clientIDs {
+		if strings.TrimSpace(id) == info.Aud {
+			audienceOK = true
+		}
+	}
+	if !audienceOK || info.Sub == "" {
+		return nil, fmt.Errorf("token was not issued for this app")
+	}
+
+	return &externalIdentity{Subject: info.Sub, Email: info.Email, EmailVerified: info.EmailVerified == "true", Name: info.Name}, nil
+}
+
+// ---------- Generic OIDC ----------
+
+// oidcIdentityProvider accepts an access token and resolves it with the
+// provider's userinfo endpoint, which works for any OIDC-compliant IdP
+type oidcIdentityProvider struct {
+	name        string
+	userinfoURL string
+}
+
+func (p *oidcIdentityProvider) Name() string { return p.name }
+
+func (p *oidcIdentityProvider) Verify(accessToken string) (*externalIdentity, error) {
+	req, _ := http.NewRequest("GET", p.userinfoURL, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := identityClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s rejected the token (%d)", p.name, resp.StatusCode)
+	}
+
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil || info.Sub == "" {
+		return nil, fmt.Errorf("%s returned no subject", p.name)
+	}
+
+	return &externalIdentity{Subject: info.Sub, Email: info.Email, EmailVerified: info.EmailVerified, Name: info.Name}, nil
+}
+
+// ---------- Admin sessions ----------
+
+// adminEmail reports whether a verified email is in ADMIN_EMAILS
+func adminEmail(email string) bool {
+	for _, e := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if e = strings.TrimSpace(e); e != "" && strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+const adminSessionTTL = 12 * time.Hour
+
+// verifyAdminSession checks an HS256 token issued by signJWT with ADMIN_TOKEN
+func verifyAdminSession(token, secret string) bool {
+	var claims struct {
+		Role string `json:"role"`
+		Exp  int64  `json:"exp"`
+	}
+	if !verifyJWT(token, secret, &claims) {
+		return false
+	}
+	return claims.Role == "admin" && time.Now().Unix() < claims.Exp
+}
+
+// ssoEnforced disables password login for teachers who have a linked identity
+func ssoEnforced() bool {
+	return os.Getenv("SSO_ENFORCED") == "true"
+}
+
+// ---------- Handlers ----------
+
+// identityLogin - Sign in with an external provider. Teachers are matched by a
+// linked identity, or on first login by their verified email. Emails in
+// ADMIN_EMAILS get an admin session token usable as "Authorization: Bearer".
+func identityLogin(c *gin.Context) {
+	provider := getIdentityProvider(c.Param("provider"))
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Sign-in provider not configured"})
+		return
+	}
+
+	var input struct {
+		Token string `json:"token"` // Google ID token, or OIDC access token
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "token is required"})
+		return
+	}
+
+	identity, err := provider.Verify(input.Token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Sign-in failed: " + err.Error()})
+		return
+	}
+
+	if identity.EmailVerified && adminEmail(identity.Email) && os.Getenv("ADMIN_TOKEN") != "" {
+		token := signJWT(map[string]interface{}{
+			"sub":  identity.Email,
+			"role": "admin",
+			"iat":  time.Now().Unix(),
+			"exp":  time.Now().Add(adminSessionTTL).Unix(),
+		}, os.Getenv("ADMIN_TOKEN"))
+		logAudit("admin", identity.Email, "login", identity.Email, gin.H{"provider": provider.Name()})
+		c.JSON(http.StatusOK, gin.H{
+			"success":     true,
+			"role":        "admin",
+			"admin_token": token,
+			"expires_in":  int(adminSessionTTL.Seconds()),
+			"email":       identity.Email,
+		})
+		return
+	}
+
+	var id, name string
+	var phone sql.NullString
+	var active int
+	err = db.QueryRow(`
+		SELECT t.id, t.name, t.phone, t.active
+		FROM mentor.teacher_identities ti
+		JOIN mentor.teachers t ON t.id = ti.teacher_id
+		WHERE ti.provider = $1 AND ti.subject = $2
+	`, provider.Name(), identity.Subject).Scan(&id, &name, &phone, &active)
+
+	// First sign-in: link by verified email
+	if err == sql.ErrNoRows && identity.EmailVerified && identity.Email != "" {
+		err = db.QueryRow(`
+			SELECT id, name, phone, active FROM mentor.teachers WHERE LOWER(email) = LOWER($1)
+		`, identity.Email).Scan(&id, &name, &phone, &active)
+		if err == nil {
+			_, err = db.Exec(`
+				INSERT INTO mentor.teacher_identities (provider, subject, teacher_id, email)
+				VALUES ($1, $2, $3, $4)
+			`, provider.Name(), identity.Subject, id, identity.Email)
+			if err == nil {
+				logAudit("teacher", id, "identity_linked", id, gin.H{"provider": provider.Name(), "email": identity.Email})
+			}
+		}
+	}
+
+	if err != nil || active != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "No teacher account is linked to this sign-in"})
+		return
+	}
+
+	db.Exec("UPDATE mentor.teacher_identities SET last_login_at = NOW() WHERE provider = $1 AND subject = $2",
+		provider.Name(), identity.Subject)
+
+	token, expiresAt, err := createTeacherSession(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"role":       "teacher",
+		"token":      token,
+		"expires_at": isoTimestamp(expiresAt),
+		"teacher": gin.H{
+			"id":    id,
+			"name":  name,
+			"phone": phone.String,
+		},
+	})
+}
+
+// getTeacherIdentities - Providers linked to a teacher
+func getTeacherIdentities(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT provider, COALESCE(email, ''), linked_at, last_login_at
+		FROM mentor.teacher_identities WHERE teacher_id = $1
+		ORDER BY provider
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	identities := []gin.H{}
+	for rows.Next() {
+		var provider, email string
+		var linkedAt time.Time
+		var lastLoginAt sql.NullTime
+		if err := rows.Scan(&provider, &email, &linkedAt, &lastLoginAt); err != nil {
+			continue
+		}
+		identity := gin.H{"provider": provider, "email": email, "linked_at": isoTimestamp(linkedAt), "last_login_at": nil}
+		if lastLoginAt.Valid {
+			identity["last_login_at"] = isoTimestamp(lastLoginAt.Time)
+		}
+		identities = append(identities, identity)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "identities": identities})
+}
+
+// linkTeacherIdentity - Admin: link a provider account to a teacher using a
+// token the teacher obtained by signing in on their device
+func linkTeacherIdentity(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var input struct {
+		Provider string `json:"provider"`
+		Token    string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	provider := getIdentityProvider(input.Provider)
+	if provider == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Sign-in provider not configured"})
+		return
+	}
+	identity, err := provider.Verify(input.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Token rejected: " + err.Error()})
+		return
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO mentor.teacher_identities (provider, subject, teacher_id, email)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (provider, teacher_id) DO UPDATE SET subject = $2, email = NULLIF($4, ''), linked_at = NOW()
+	`, provider.Name(), identity.Subject, teacherID, identity.Email)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Could not link: " + err.Error()})
+		return
+	}
+
+	logAudit("teacher", teacherID, "identity_linked", "admin", gin.H{"provider": provider.Name(), "email": identity.Email})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "provider": provider.Name(), "email": identity.Email, "message": "Identity linked"})
+}
+
+// unlinkTeacherIdentity - Admin: remove a provider link
+func unlinkTeacherIdentity(c *gin.Context) {
+	result, err := db.Exec("DELETE FROM mentor.teacher_identities WHERE teacher_id = $1 AND provider = $2",
+		c.Param("id"), c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Identity not found"})
+		return
+	}
+
+	logAudit("teacher", c.Param("id"), "identity_unlinked", "admin", gin.H{"provider": c.Param("provider")})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Identity unlinked"})
+}
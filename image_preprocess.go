@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// IMAGE PREPROCESSING (Answer paper pages before grading)
+// =====================================================
+
+// gradingImageMaxEdge is the longest side a page is scaled down to; enough
+// for handwriting to stay legible to the model
+func gradingImageMaxEdge() int {
+	n, err := strconv.Atoi(os.Getenv("GRADING_IMAGE_MAX_EDGE"))
+	if err != nil || n < 400 {
+		return 1600
+	}
+	return n
+}
+
+const (
+	maxSkewDegrees   = 6.0
+	skewStepDegrees  = 0.5
+	minSkewCorrected = 0.4 // smaller angles aren't worth resampling the page
+	imageJobBatch    = 10
+	imageJobAttempts = 3
+	// imageJobStuckMinutes is how long a page can stay 'processing' before
+	// it's taken to be interrupted and run again
+	imageJobStuckMinutes = 10
+	// pageSourceMaxBytes and pageMaxPixels bound what a page may be, since a
+	// small file can declare huge dimensions
+	pageSourceMaxBytes = 20 << 20
+	pageMaxPixels      = 50_000_000
+)
+
+// preprocessPage auto-rotates (EXIF), converts to grayscale, downscales,
+// de-skews and stretches contrast, returning a JPEG and what was done
+func preprocessPage(data []byte) ([]byte, gin.H, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode: %w", err)
+	}
+	if cfg.Width*cfg.Height > pageMaxPixels {
+		return nil, nil, fmt.Errorf("page is %dx%d, larger than %d megapixels", cfg.Width, cfg.Height, pageMaxPixels/1_000_000)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode: %w", err)
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(gray, gray.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	orientation := jpegOrientation(data)
+	gray = applyOrientation(gray, orientation)
+	gray = downscaleGray(gray, gradingImageMaxEdge())
+
+	skew := detectSkew(gray)
+	if math.Abs(skew) >= minSkewCorrected {
+		gray = rotateGray(gray, skew*math.Pi/180)
+	}
+
+	lo, hi := stretchContrast(gray)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, gray, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, nil, err
+	}
+
+	return out.Bytes(), gin.H{
+		"exif_orientation": orientation,
+		"skew_degrees":     skew,
+		"contrast_range":   []int{lo, hi},
+		"width":            gray.Bounds().Dx(),
+		"height":           gray.Bounds().Dy(),
+	}, nil
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8), or 1 if absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			return 1 // image data reached without an EXIF block
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(segment) > 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		off := ifd + 2 + e*12
+		if off+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[off:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[off+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation turns the stored pixels upright per the EXIF orientation
+func applyOrientation(g *image.Gray, orientation int) *image.Gray {
+	if orientation <= 1 || orientation > 8 {
+		return g
+	}
+	w, h := g.Bounds().Dx(), g.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewGray(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Pix[y*dst.Stride+x] = g.Pix[sy*g.Stride+sx]
+		}
+	}
+	return dst
+}
+
+// downscaleGray box-filters the image so its longest side is at most maxEdge
+func downscaleGray(g *image.Gray, maxEdge int) *image.Gray {
+	w, h := g.Bounds().Dx(), g.Bounds().Dy()
+	scale := float64(maxEdge) / float64(max(w, h))
+	if scale >= 1 {
+		return g
+	}
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+	dst := image.NewGray(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			sum := 0
+			for sy := y0; sy < y1; sy++ {
+				row := g.Pix[sy*g.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					sum += int(row[sx])
+				}
+			}
+			dst.Pix[y*dst.Stride+x] = uint8(sum / ((y1 - y0) * (x1 - x0)))
+		}
+	}
+	return dst
+}
+
+// detectSkew finds the angle (degrees) at which ink rows line up best:
+// the horizontal projection of dark pixels is sharpest when text lines are level
+func detectSkew(g *image.Gray) float64 {
+	sample := downscaleGray(g, 600)
+	w, h := sample.Bounds().Dx(), sample.Bounds().Dy()
+
+	mean := 0
+	for _, p := range sample.Pix {
+		mean += int(p)
+	}
+	threshold := uint8(mean / len(sample.Pix) * 3 / 4)
+
+	type point struct{ x, y float64 }
+	var ink []point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if sample.Pix[y*sample.Stride+x] < threshold {
+				ink = append(ink, point{float64(x - w/2), float64(y - h/2)})
+			}
+		}
+	}
+	if len(ink) < 100 {
+		return 0
+	}
+
+	best, bestScore := 0.0, -1.0
+	rows := make([]float64, 2*(w+h))
+	for deg := -maxSkewDegrees; deg <= maxSkewDegrees; deg += skewStepDegrees {
+		a := deg * math.Pi / 180
+		sin, cos := math.Sin(a), math.Cos(a)
+		for i := range rows {
+			rows[i] = 0
+		}
+		for _, p := range ink {
+			r := int(-p.x*sin+p.y*cos) + w + h
+			if r >= 0 && r < len(rows) {
+				rows[r]++
+			}
+		}
+		score := 0.0
+		for _, n := range rows {
+			score += n * n
+		}
+		if score > bestScore {
+			best, bestScore = deg, score
+		}
+	}
+	return best
+}
+
+// rotateGray samples the source rotated by angle (radians) about the centre,
+// filling uncovered corners with white
+func rotateGray(g *image.Gray, angle float64) *image.Gray {
+	w, h := g.Bounds().Dx(), g.Bounds().Dy()
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	sin, cos := math.Sin(angle), math.Cos(angle)
+	cx, cy := float64(w)/2, float64(h)/2
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			sx := int(cx + dx*cos - dy*sin)
+			sy := int(cy + dx*sin + dy*cos)
+			v := uint8(255)
+			if sx >= 0 && sx < w && sy >= 0 && sy < h {
+				v = g.Pix[sy*g.Stride+sx]
+			}
+			dst.Pix[y*dst.Stride+x] = v
+		}
+	}
+	return dst
+}
+
+// stretchContrast maps the 2nd-98th percentile of brightness onto 0-255
+// in place, lifting faded pencil and grey phone-camera paper
+func stretchContrast(g *image.Gray) (int, int) {
+	var hist [256]int
+	for _, p := range g.Pix {
+		hist[p]++
+	}
+	total := len(g.Pix)
+	lo, hi, seen := 0, 255, 0
+	for v := 0; v < 256; v++ {
+		seen += hist[v]
+		if seen >= total*2/100 {
+			lo = v
+			break
+		}
+	}
+	seen = 0
+	for v := 255; v >= 0; v-- {
+		seen += hist[v]
+		if seen >= total*2/100 {
+			hi = v
+			break
+		}
+	}
+	if hi-lo < 16 {
+		return lo, hi // blank or already flat; stretching would amplify noise
+	}
+
+	var lut [256]uint8
+	for v := 0; v < 256; v++ {
+		switch {
+		case v <= lo:
+			lut[v] = 0
+		case v >= hi:
+			lut[v] = 255
+		default:
+			lut[v] = uint8((v - lo) * 255 / (hi - lo))
+		}
+	}
+	for i, p := range g.Pix {
+		g.Pix[i] = lut[p]
+	}
+	return lo, hi
+}
+
+// ---------- Queue ----------
+
+var imageFetchClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if !pageSourceAllowed(req.URL) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		if len(via) >= 5 {
+			return fmt.Errorf("too many redirects")
+		}
+		return nil
+	},
+}
+
+// pageSourceAllowed limits page URLs to where the app uploads them: ImgBB,
+// or the object storage endpoint, so a paper can't make the server fetch
+// internal addresses
+func pageSourceAllowed(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if u.Scheme == "https" && (host == "ibb.co" || strings.HasSuffix(host, ".ibb.co")) {
+		return true
+	}
+	if endpoint, err := url.Parse(os.Getenv("S3_ENDPOINT")); err == nil && endpoint.Host != "" {
+		return strings.EqualFold(u.Host, endpoint.Host) && u.Scheme == endpoint.Scheme
+	}
+	return false
+}
+
+// enqueuePagePreprocessing queues every page of an answer paper
+func enqueuePagePreprocessing(paperID int, pages []string) {
+	for i, source := range pages {
+		db.Exec(`
+			INSERT INTO mentor.image_jobs (answer_paper_id, page, source)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (answer_paper_id, page) DO UPDATE SET status = 'queued', attempts = 0, error = NULL
+		`, paperID, i, source)
+	}
+}
+
+// loadPageSource fetches a page by URL, or decodes it if stored as base64
+func loadPageSource(source string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		u, err := url.Parse(source)
+		if err != nil || !pageSourceAllowed(u) {
+			return nil, fmt.Errorf("page URL host is not allowed (ImgBB or object storage only)")
+		}
+		resp, err := imageFetchClient.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch returned %d", resp.StatusCode)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, pageSourceMaxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > pageSourceMaxBytes {
+			return nil, fmt.Errorf("page is larger than %d MB", pageSourceMaxBytes>>20)
+		}
+		return data, nil
+	}
+	if i := strings.Index(source, "base64,"); i >= 0 {
+		source = source[i+len("base64,"):]
+	}
+	return base64.StdEncoding.DecodeString(source)
+}
+
+// storeProcessedPage uploads to ImgBB when configured, else keeps a data URL
+func storeProcessedPage(data []byte, name string) (string, error) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	imgbbKey := os.Getenv("IMGBB_API_KEY")
+	if imgbbKey == "" {
+		return "data:image/jpeg;base64," + encoded, nil
+	}
+
+	resp, err := http.PostForm("https://api.imgbb.com/1/upload", url.Values{
+		"key":   {imgbbKey},
+		"image": {encoded},
+		"name":  {name},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var imgbbResp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			DisplayURL string `json:"display_url"`
+		} `json:"data"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(body, &imgbbResp)
+	if !imgbbResp.Success {
+		return "", fmt.Errorf("ImgBB upload failed")
+	}
+	return imgbbResp.Data.DisplayURL, nil
+}
+
+// processImageJobs is the background worker: claims a batch of queued pages
+// (and ones interrupted mid-way), preprocesses them, and fills
+// processed_image_urls once a paper is complete
+func processImageJobs() error {
+	// Interrupted on every attempt, so give up rather than retry forever
+	db.Exec(`
+		UPDATE mentor.image_jobs SET status = 'failed', error = 'Interrupted while processing'
+		WHERE status = 'processing' AND started_at < NOW() - make_interval(mins => $1) AND attempts >= $2
+	`, imageJobStuckMinutes, imageJobAttempts)
+
+	rows, err := db.Query(`
+		UPDATE mentor.image_jobs SET status = 'processing', attempts = attempts + 1, started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM mentor.image_jobs
+			WHERE status = 'queued'
+			   OR (status = 'processing' AND (started_at IS NULL OR started_at < NOW() - make_interval(mins => $2)))
+			ORDER BY created_at
+			LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, answer_paper_id, page, source, attempts
+	`, imageJobBatch, imageJobStuckMinutes)
+	if err != nil {
+		return err
+	}
+	type imageJob struct {
+		id, paperID, page, attempts int
+		source                      string
+	}
+	var claimed []imageJob
+	for rows.Next() {
+		var j imageJob
+		if err := rows.Scan(&j.id, &j.paperID, &j.page, &j.source, &j.attempts); err == nil {
+			claimed = append(claimed, j)
+		}
+	}
+	rows.Close()
+
+	papers := map[int]bool{}
+	for _, j := range claimed {
+		papers[j.paperID] = true
+
+		resultURL, before, after, details, err := func() (string, int, int, gin.H, error) {
+			data, err := loadPageSource(j.source)
+			if err != nil {
+				return "", 0, 0, nil, err
+			}
+			processed, details, err := preprocessPage(data)
+			if err != nil {
+				return "", len(data), 0, nil, err
+			}
+			resultURL, err := storeProcessedPage(processed, fmt.Sprintf("paper_%d_page_%d", j.paperID, j.page))
+			return resultURL, len(data), len(processed), details, err
+		}()
+
+		if err != nil {
+			status := "queued"
+			if j.attempts >= imageJobAttempts {
+				status = "failed"
+			}
+			db.Exec("UPDATE mentor.image_jobs SET status = $1, error = $2 WHERE id = $3", status, err.Error(), j.id)
+			log.Printf("Image job %d (paper %d page %d): %v", j.id, j.paperID, j.page, err)
+			continue
+		}
+
+		detailsJSON, _ := json.Marshal(details)
+		db.Exec(`
+			UPDATE mentor.image_jobs
+			SET status = 'done', error = NULL, result_url = $1, bytes_before = $2, bytes_after = $3,
+			    details = $4, processed_at = NOW()
+			WHERE id = $5
+		`, resultURL, before, after, string(detailsJSON), j.id)
+	}
+
+	for paperID := range papers {
+		// Only once every page is done; failed pages leave the originals in use
+		db.Exec(`
+			UPDATE mentor.answer_papers ap
+			SET processed_image_urls = (
+				SELECT json_agg(result_url ORDER BY page)::TEXT FROM mentor.image_jobs WHERE answer_paper_id = ap.id
+			)
+			WHERE ap.id = $1
+			  AND NOT EXISTS (SELECT 1 FROM mentor.image_jobs WHERE answer_paper_id = ap.id AND status <> 'done')
+		`, paperID)
+	}
+	return nil
+}
+
+// gradingImageURLs returns the pages a grader (AI or admin) should look at:
+// the preprocessed pages when ready, otherwise the originals
+func gradingImageURLs(paperID int) []string {
+	var original, processed *string
+	db.QueryRow("SELECT image_urls, processed_image_urls FROM mentor.answer_papers WHERE id = $1", paperID).
+		Scan(&original, &processed)
+
+	var urls []string
+	if processed != nil && json.Unmarshal([]byte(*processed), &urls) == nil && len(urls) > 0 {
+		return urls
+	}
+	if original != nil {
+		json.Unmarshal([]byte(*original), &urls)
+	}
+	return urls
+}
+
+// getPaperPreprocessing - Per-page preprocessing status for an answer paper
+func getPaperPreprocessing(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT page, status, attempts, COALESCE(error, ''), COALESCE(result_url, ''),
+		       bytes_before, bytes_after, details::TEXT
+		FROM mentor.image_jobs WHERE answer_paper_id = $1
+		ORDER BY page
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	pages := []gin.H{}
+	for rows.Next() {
+		var page, attempts int
+		var status, errText, resultURL, details string
+		var before, after *int
+		if err := rows.Scan(&page, &status, &attempts, &errText, &resultURL, &before, &after, &details); err != nil {
+			continue
+		}
+		pages = append(pages, gin.H{
+			"page":         page,
+			"status":       status,
+			"attempts":     attempts,
+			"error":        errText,
+			"result_url":   resultURL,
+			"bytes_before": before,
+			"bytes_after":  after,
+			"details":      json.RawMessage(details),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "pages": pages})
+}
+
+// requeuePaperPreprocessing - Run preprocessing again for every page of a paper
+func requeuePaperPreprocessing(c *gin.Context) {
+	var paperID int
+	var imageURLs *string
+	err := db.QueryRow("SELECT id, image_urls FROM mentor.answer_papers WHERE id = $1", c.Param("id")).Scan(&paperID, &imageURLs)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Paper not found"})
+		return
+	}
+
+	var pages []string
+	if imageURLs != nil {
+		json.Unmarshal([]byte(*imageURLs), &pages)
+	}
+	db.Exec("UPDATE mentor.answer_papers SET processed_image_urls = NULL WHERE id = $1", paperID)
+	enqueuePagePreprocessing(paperID, pages)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "queued": len(pages), "message": "Pages queued for preprocessing"})
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// INTEGRITY CHECKS (Orphaned rows)
+// ============================================
+
+// integrityCheck finds rows of table whose ids are selected by idsSQL.
+// repairs maps an action name to the SET clause applied to those rows;
+// an empty clause deletes them.
+type integrityCheck struct {
+	name, description, hint string
+	table, idsSQL           string
+	repairs                 map[string]string
+}
+
+var integrityChecks = []integrityCheck{
+	{
+		name:        "progress_without_subscription",
+		description: "Progress records whose subscription no longer exists",
+		hint:        "The subscription was hard deleted; the progress can't be shown anywhere. Delete it.",
+		table:       "progress",
+		idsSQL: `SELECT p.id FROM mentor.progress p
+			WHERE NOT EXISTS (SELECT 1 FROM mentor.subscriptions s WHERE s.id = p.subscription_id)`,
+		repairs: map[string]string{"delete": ""},
+	},
+	{
+		name:        "progress_without_schedule",
+		description: "Progress records pointing at a missing schedule row",
+		hint:        "Relink to the subscription's schedule row for the same subject, or delete if none exists.",
+		table:       "progress",
+		idsSQL: `SELECT p.id FROM mentor.progress p
+			WHERE p.schedule_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM mentor.schedule sc WHERE sc.id = p.schedule_id)`,
+		repairs: map[string]string{
+			"relink": `schedule_id = (SELECT sc.id FROM mentor.schedule sc
+				WHERE sc.subscription_id = progress.subscription_id AND sc.subject = progress.subject LIMIT 1)`,
+			"delete": "",
+		},
+	},
+	{
+		name:        "schedule_without_subscription",
+		description: "Schedule rows whose subscription no longer exists",
+		hint:        "Nothing can reach these rows. Delete them.",
+		table:       "schedule",
+		idsSQL: `SELECT sc.id FROM mentor.schedule sc
+			WHERE NOT EXISTS (SELECT 1 FROM mentor.subscriptions s WHERE s.id = sc.subscription_id)`,
+		repairs: map[string]string{"delete": ""},
+	},
+	{
+		name:        "attendance_without_subscription",
+		description: "Attendance records whose subscription no longer exists",
+		hint:        "Detach to keep the teacher's visit history (payroll, GPS proof), or delete.",
+		table:       "attendance",
+		idsSQL: `SELECT a.id FROM mentor.attendance a
+			WHERE a.subscription_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM mentor.subscriptions s WHERE s.id = a.subscription_id)`,
+		repairs: map[string]string{"detach": "subscription_id = NULL", "delete": ""},
+	},
+	{
+		name:        "attendance_after_deletion",
+		description: "Attendance recorded against a subscription after it was soft deleted",
+		hint:        "Usually a teacher still visiting a removed student. Restore the subscription (POST /subscriptions/:id/restore) if classes continue, otherwise delete.",
+		table:       "attendance",
+		idsSQL: `SELECT a.id FROM mentor.attendance a
+			JOIN mentor.subscriptions s ON s.id = a.subscription_id
+			WHERE s.deleted_at IS NOT NULL AND a.recorded_at > s.deleted_at`,
+		repairs: map[string]string{"delete": ""},
+	},
+	{
+		name:        "transactions_without_subscription",
+		description: "Transactions pointing at a subscription that no longer exists",
+		hint:        "Money records must be kept. Detach them from the subscription.",
+		table:       "transactions",
+		idsSQL: `SELECT t.id FROM mentor.transactions t
+			WHERE t.subscription_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM mentor.subscriptions s WHERE s.id = t.subscription_id)`,
+		repairs: map[string]string{"detach": "subscription_id = NULL"},
+	},
+	{
+		name:        "answer_papers_without_subscription",
+		description: "Answer papers pointing at a subscription that no longer exists",
+		hint:        "Detach to keep the graded paper in the teacher's history.",
+		table:       "answer_papers",
+		idsSQL: `SELECT ap.id FROM mentor.answer_papers ap
+			WHERE ap.subscription_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM mentor.subscriptions s WHERE s.id = ap.subscription_id)`,
+		repairs: map[string]string{"detach": "subscription_id = NULL"},
+	},
+	{
+		name:        "subscriptions_without_teacher",
+		description: "Active subscriptions whose teacher no longer exists",
+		hint:        "Assign a new teacher with POST /subscriptions/:id/transfer.",
+		table:       "subscriptions",
+		idsSQL: `SELECT s.id FROM mentor.subscriptions s
+			WHERE s.deleted_at IS NULL AND s.status = 'active'
+			  AND NOT EXISTS (SELECT 1 FROM mentor.teachers t WHERE t.id = s.teacher_id)`,
+		repairs: map[string]string{},
+	},
+	{
+		name:        "subject_teacher_missing",
+		description: "Schedule rows assigned to a subject teacher who no longer exists",
+		hint:        "Clear the assignment so the subject falls back to the main teacher.",
+		table:       "schedule",
+		idsSQL: `SELECT sc.id FROM mentor.schedule sc
+			WHERE sc.teacher_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM mentor.teachers t WHERE t.id = sc.teacher_id)`,
+		repairs: map[string]string{"clear": "teacher_id = NULL"},
+	},
+}
+
+const integritySampleSize = 20
+
+func findIntegrityCheck(name string) *integrityCheck {
+	for i := range integrityChecks {
+		if integrityChecks[i].name == name {
+			return &integrityChecks[i]
+		}
+	}
+	return nil
+}
+
+// runIntegrityChecks returns one entry per check with the orphan count and sample ids
+func runIntegrityChecks() ([]gin.H, int, error) {
+	results := []gin.H{}
+	total := 0
+	for _, check := range integrityChecks {
+		var count int
+		var sample []int64
+		err := db.QueryRow(`
+			SELECT COUNT(*), COALESCE((ARRAY_AGG(id ORDER BY id))[1:`+fmt.Sprint(integritySampleSize)+`], '{}')
+			FROM (`+check.idsSQL+`) orphans
+		`).Scan(&count, pq.Array(&sample))
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", check.name, err)
+		}
+		total += count
+
+		actions := []string{}
+		for action := range check.repairs {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		results = append(results, gin.H{
+			"check":       check.name,
+			"description": check.description,
+			"table":       check.table,
+			"count":       count,
+			"sample_ids":  sample,
+			"hint":        check.hint,
+			"actions":     actions,
+		})
+	}
+	return results, total, nil
+}
+
+// getIntegrityReport - Admin: orphaned rows per check with suggested repairs
+func getIntegrityReport(c *gin.Context) {
+	results, total, err := runIntegrityChecks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "total": total, "checks": results})
+}
+
+// repairIntegrity - Admin: apply one repair action to the rows a check finds
+func repairIntegrity(c *gin.Context) {
+	var input struct {
+		Check  string `json:"check"`
+		Action string `json:"action"`
+		Actor  string `json:"actor"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	check := findIntegrityCheck(input.Check)
+	if check == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Unknown check"})
+		return
+	}
+	set, ok := check.repairs[input.Action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("Action %q is not available for %s: %s", input.Action, check.name, check.hint)})
+		return
+	}
+
+	table := "mentor." + pq.QuoteIdentifier(check.table)
+	stmt := "DELETE FROM " + table + " WHERE id IN (" + check.idsSQL + ")"
+	if set != "" {
+		stmt = "UPDATE " + table + " SET " + set + " WHERE id IN (" + check.idsSQL + ")"
+	}
+
+	result, err := db.Exec(stmt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	n, _ := result.RowsAffected()
+
+	logAudit("integrity", check.name, "repair_"+input.Action, input.Actor, gin.H{"rows": n})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "check": check.name, "action": input.Action, "rows": n})
+}
+
+// logIntegrityReport is the background job; it only reports, repairs stay manual
+func logIntegrityReport() error {
+	results, total, err := runIntegrityChecks()
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return nil
+	}
+	for _, r := range results {
+		if r["count"].(int) > 0 {
+			log.Printf("Integrity: %s: %d rows", r["check"], r["count"])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// INVOICE SETTINGS (Tax and financial year numbering)
+// ============================================
+
+type invoiceSettings struct {
+	TaxEnabled      bool    `json:"tax_enabled"`
+	TaxLabel        string  `json:"tax_label"` // e.g. VAT or GST
+	TaxPercent      float64 `json:"tax_percent"`
+	TaxMode         string  `json:"tax_mode"` // "exclusive" adds tax to prices, "inclusive" takes it out of them
+	TaxRegistration string  `json:"tax_registration"`
+	FYStartMonth    int     `json:"fy_start_month"` // 1 = calendar year
+	// LineTaxPercents overrides TaxPercent by line kind, e.g. {"late_fee": 0}
+	LineTaxPercents map[string]float64 `json:"line_tax_percents"`
+}
+
+// invoiceLineKinds are the kinds of invoice line a tax rate can be set for
+var invoiceLineKinds = map[string]bool{"subject_fee": true, "discount": true, "makeup_credit": true, "late_fee": true}
+
+func loadInvoiceSettings() (invoiceSettings, error) {
+	var s invoiceSettings
+	var linePercents string
+	err := db.QueryRow(`
+		SELECT tax_enabled, tax_label, tax_percent, tax_mode, COALESCE(tax_registration, ''), fy_start_month,
+		       line_tax_percents::text
+		FROM mentor.invoice_settings WHERE id = 1
+	`).Scan(&s.TaxEnabled, &s.TaxLabel, &s.TaxPercent, &s.TaxMode, &s.TaxRegistration, &s.FYStartMonth, &linePercents)
+	if err == sql.ErrNoRows {
+		return invoiceSettings{TaxLabel: "Tax", TaxMode: "exclusive", FYStartMonth: 1, LineTaxPercents: map[string]float64{}}, nil
+	}
+	s.LineTaxPercents = map[string]float64{}
+	json.Unmarshal([]byte(linePercents), &s.LineTaxPercents)
+	return s, err
+}
+
+// lineTax splits a line's amount into net and tax under the settings, at the
+// rate for its kind or else the default. In exclusive mode tax is added on
+// top of the amount; in inclusive mode the amount already contains it.
+func (s invoiceSettings) lineTax(kind string, amount float64) (net, tax, percent float64) {
+	percent = s.TaxPercent
+	if p, ok := s.LineTaxPercents[kind]; ok {
+		percent = p
+	}
+	if !s.TaxEnabled || percent <= 0 {
+		return amount, 0, 0
+	}
+	if s.TaxMode == "inclusive" {
+		net = roundMoney(amount / (1 + percent/100))
+		return net, roundMoney(amount - net), percent
+	}
+	return amount, roundMoney(amount * percent / 100), percent
+}
+
+// financialYear labels the financial year containing day: "2026" for a
+// calendar year, else "2026-27" for one starting mid-year
+func (s invoiceSettings) financialYear(day time.Time) string {
+	if s.FYStartMonth <= 1 {
+		return fmt.Sprint(day.Year())
+	}
+	start := day.Year()
+	if int(day.Month()) < s.FYStartMonth {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// nextInvoiceNumber takes the financial year's next number inside tx. The
+// counter row stays locked until tx ends, and a rollback returns the number,
+// so numbers have no gaps.
+func nextInvoiceNumber(tx *sql.Tx, financialYear string) (int, string, error) {
+	var seq int
+	err := tx.QueryRow(`
+		INSERT INTO mentor.invoice_counters (financial_year, last_number) VALUES ($1, 1)
+		ON CONFLICT (financial_year) DO UPDATE SET last_number = mentor.invoice_counters.last_number + 1
+		RETURNING last_number
+	`, financialYear).Scan(&seq)
+	if err != nil {
+		return 0, "", err
+	}
+	return seq, fmt.Sprintf("INV-%s-%05d", financialYear, seq), nil
+}
+
+// getInvoiceSettingsHandler - Current tax and numbering settings
+func getInvoiceSettingsHandler(c *gin.Context) {
+	settings, err := loadInvoiceSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":                true,
+		"settings":               settings,
+		"current_financial_year": settings.financialYear(localToday()),
+	})
+}
+
+// updateInvoiceSettings - Admin sets tax and the financial year start. Only
+// invoices issued afterwards are affected.
+func updateInvoiceSettings(c *gin.Context) {
+	var input struct {
+		invoiceSettings
+		UpdatedBy string `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	input.TaxLabel = strings.TrimSpace(input.TaxLabel)
+	if input.TaxLabel == "" {
+		input.TaxLabel = "Tax"
+	}
+	if input.TaxMode == "" {
+		input.TaxMode = "exclusive"
+	}
+	if input.FYStartMonth == 0 {
+		input.FYStartMonth = 1
+	}
+
+	fields := map[string]string{}
+	if input.TaxMode != "exclusive" && input.TaxMode != "inclusive" {
+		fields["tax_mode"] = "must be 'exclusive' or 'inclusive'"
+	}
+	if input.TaxPercent < 0 || input.TaxPercent > 100 {
+		fields["tax_percent"] = "must be between 0 and 100"
+	}
+	if input.FYStartMonth < 1 || input.FYStartMonth > 12 {
+		fields["fy_start_month"] = "must be 1-12"
+	}
+	if input.LineTaxPercents == nil {
+		input.LineTaxPercents = map[string]float64{}
+	}
+	for kind, p := range input.LineTaxPercents {
+		if !invoiceLineKinds[kind] {
+			fields["line_tax_percents."+kind] = "is not a line kind; use subject_fee, discount, makeup_credit or late_fee"
+		} else if p < 0 || p > 100 {
+			fields["line_tax_percents."+kind] = "must be between 0 and 100"
+		}
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	linePercents, _ := json.Marshal(input.LineTaxPercents)
+	_, err := db.Exec(`
+		INSERT INTO mentor.invoice_settings (id, tax_enabled, tax_label, tax_percent, tax_mode, tax_registration,
+		                                     fy_start_month, line_tax_percents, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, NOW())
+		ON CONFLICT (id) DO UPDATE SET tax_enabled = EXCLUDED.tax_enabled, tax_label = EXCLUDED.tax_label,
+		    tax_percent = EXCLUDED.tax_percent, tax_mode = EXCLUDED.tax_mode,
+		    tax_registration = EXCLUDED.tax_registration, fy_start_month = EXCLUDED.fy_start_month,
+		    line_tax_percents = EXCLUDED.line_tax_percents, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, input.TaxEnabled, input.TaxLabel, input.TaxPercent, input.TaxMode, strings.TrimSpace(input.TaxRegistration),
+		input.FYStartMonth, string(linePercents), input.UpdatedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("invoice_settings", 1, "updated", input.UpdatedBy, gin.H{
+		"tax_enabled":       input.TaxEnabled,
+		"tax_percent":       input.TaxPercent,
+		"line_tax_percents": input.LineTaxPercents,
+		"tax_mode":          input.TaxMode,
+		"fy_start_month":    input.FYStartMonth,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "settings": input.invoiceSettings})
+}
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// INVOICES (One per subscription per billing cycle)
+// ============================================
+
+// invoiceLeadDays is how long before a cycle starts its invoice is issued
+const invoiceLeadDays = 3
+
+// errNotBillable means the subscription doesn't run during the requested cycle
+var errNotBillable = errors.New("subscription is not active in that billing cycle")
+
+// invoiceLine is one line item; credits and discounts are negative
+type invoiceLine struct {
+	Kind        string
+	Description string
+	Amount      float64
+}
+
+// roundMoney rounds to two decimals
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// invoiceFeeLines prices the cycle: one line per subject when every subject
+// has a price adding up to the subscription amount, else one monthly line
+func invoiceFeeLines(subId int, amount float64) ([]invoiceLine, error) {
+	rows, err := db.Query(`
+		SELECT subject, COALESCE(price, 0) FROM mentor.schedule
+		WHERE subscription_id = $1 ORDER BY id
+	`, subId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []invoiceLine
+	priced, total := true, 0.0
+	for rows.Next() {
+		var subject string
+		var price float64
+		if err := rows.Scan(&subject, &price); err != nil {
+			continue
+		}
+		if price <= 0 {
+			priced = false
+		}
+		total += price
+		lines = append(lines, invoiceLine{"subject_fee", "Tuition: " + subject, roundMoney(price)})
+	}
+
+	if len(lines) == 0 || !priced || math.Abs(total-amount) >= 0.01 {
+		return []invoiceLine{{"subject_fee", "Monthly tuition fee", roundMoney(amount)}}, nil
+	}
+	return lines, nil
+}
+
+// generateInvoice issues the invoice for the billing cycle containing day,
+// unless the subscription already has one. Unused makeup credits from before
+// the cycle become fee credits at the previous cycle's per-class rate, and
+// late fees not yet billed are added. Tax is worked out per line under the
+// invoice settings, and the number is the financial year's next one with no
+// gaps. Returns the invoice id and whether it was created now.
+func generateInvoice(subId int, day time.Time) (int, bool, error) {
+	var amount, discountPercent float64
+	var billingDay int
+	var discountReason string
+	var startDate, endDate sql.NullTime
+	var currency sql.NullString
+	err := db.QueryRow(`
+		SELECT COALESCE(amount, 0), COALESCE(billing_date, 1), discount_percent, COALESCE(discount_reason, ''),
+		       start_date, end_date, currency
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, subId).Scan(&amount, &billingDay, &discountPercent, &discountReason, &startDate, &endDate, &currency)
+	if err != nil {
+		return 0, false, err
+	}
+
+	cycleStart, cycleEnd := billingCycleFor(day, billingDay)
+	lastDay := cycleEnd.AddDate(0, 0, -1)
+	if (startDate.Valid && startDate.Time.After(lastDay)) || (endDate.Valid && endDate.Time.Before(cycleStart)) {
+		return 0, false, errNotBillable
+	}
+
+	var existing int
+	if db.QueryRow(`
+		SELECT id FROM mentor.invoices WHERE subscription_id = $1 AND cycle_start = $2 AND status <> 'void'
+	`, subId, cycleStart).Scan(&existing) == nil {
+		return existing, false, nil
+	}
+
+	lines, err := invoiceFeeLines(subId, amount)
+	if err != nil {
+		return 0, false, err
+	}
+	subtotal := 0.0
+	for _, l := range lines {
+		subtotal += l.Amount
+	}
+
+	if discountPercent > 0 {
+		desc := fmt.Sprintf("Discount (%g%%)", discountPercent)
+		if discountReason != "" {
+			desc += ": " + discountReason
+		}
+		lines = append(lines, invoiceLine{"discount", desc, -roundMoney(subtotal * discountPercent / 100)})
+	}
+
+	// Makeup credits still owed become a credit at the last cycle's per-class rate
+	var creditIDs []int
+	prev, err := refreshBillingCycle(subId, cycleStart.AddDate(0, 0, -1))
+	if err != nil {
+		return 0, false, err
+	}
+	if expected := prev["expected_classes"].(int); expected > 0 {
+		rate := roundMoney(amount * (1 - discountPercent/100) / float64(expected))
+		rows, err := db.Query(`
+			SELECT id, cancelled_date FROM mentor.makeup_credits
+			WHERE subscription_id = $1 AND used_at IS NULL AND invoice_id IS NULL AND cancelled_date < $2
+			ORDER BY cancelled_date
+		`, subId, cycleStart)
+		if err != nil {
+			return 0, false, err
+		}
+		for rows.Next() {
+			var id int
+			var date time.Time
+			if err := rows.Scan(&id, &date); err != nil {
+				continue
+			}
+			creditIDs = append(creditIDs, id)
+			lines = append(lines, invoiceLine{"makeup_credit", "Credit: class cancelled on " + date.Format("2006-01-02"), -rate})
+		}
+		rows.Close()
+	}
+
+	var lateFeeIDs []int
+	rows, err := db.Query(`
+		SELECT id, cycle_start, amount FROM mentor.late_fees
+		WHERE subscription_id = $1 AND status = 'applied' AND invoice_id IS NULL
+		ORDER BY cycle_start
+	`, subId)
+	if err != nil {
+		return 0, false, err
+	}
+	for rows.Next() {
+		var id int
+		var feeCycle time.Time
+		var fee float64
+		if err := rows.Scan(&id, &feeCycle, &fee); err != nil {
+			continue
+		}
+		lateFeeIDs = append(lateFeeIDs, id)
+		lines = append(lines, invoiceLine{"late_fee", "Late fee for the cycle from " + feeCycle.Format("2006-01-02"), roundMoney(fee)})
+	}
+	rows.Close()
+
+	settings, err := loadInvoiceSettings()
+	if err != nil {
+		return 0, false, err
+	}
+	type taxedLine struct {
+		net, tax, percent float64
+	}
+	taxed := make([]taxedLine, len(lines))
+	total, taxTotal := 0.0, 0.0
+	for i, l := range lines {
+		net, tax, percent := settings.lineTax(l.Kind, l.Amount)
+		taxed[i] = taxedLine{net, tax, percent}
+		total += net + tax
+		taxTotal += tax
+	}
+	total = math.Max(roundMoney(total), 0)
+	taxTotal = roundMoney(taxTotal)
+
+	dueDate := cycleStart
+	if policy, err := loadLateFeePolicy(); err == nil && policy.Enabled {
+		dueDate = cycleStart.AddDate(0, 0, policy.GraceDays)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	financialYear := settings.financialYear(localToday())
+	seq, number, err := nextInvoiceNumber(tx, financialYear)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var taxMode, taxLabel interface{}
+	if taxTotal != 0 {
+		taxMode, taxLabel = settings.TaxMode, settings.TaxLabel
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.invoices (invoice_number, subscription_id, cycle_start, cycle_end, due_date, subtotal, total, currency,
+		                             tax_mode, tax_label, tax_total, financial_year, sequence_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (subscription_id, cycle_start) WHERE status <> 'void' DO NOTHING
+		RETURNING id
+	`, number, subId, cycleStart, lastDay, dueDate, roundMoney(subtotal), total, orDefaultCurrency(currency),
+		taxMode, taxLabel, taxTotal, financialYear, seq).Scan(&id)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return generateInvoice(subId, day)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i, l := range lines {
+		if _, err := tx.Exec(`
+			INSERT INTO mentor.invoice_items (invoice_id, kind, description, amount, position, net_amount, tax_percent, tax_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, id, l.Kind, l.Description, l.Amount, i, taxed[i].net, taxed[i].percent, taxed[i].tax); err != nil {
+			return 0, false, err
+		}
+	}
+	for _, creditID := range creditIDs {
+		if _, err := tx.Exec("UPDATE mentor.makeup_credits SET used_at = NOW(), invoice_id = $1 WHERE id = $2", id, creditID); err != nil {
+			return 0, false, err
+		}
+	}
+	for _, feeID := range lateFeeIDs {
+		if _, err := tx.Exec("UPDATE mentor.late_fees SET invoice_id = $1 WHERE id = $2", id, feeID); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+
+	logAudit("subscription", subId, "invoice_issued", "system", gin.H{
+		"invoice_id":     id,
+		"invoice_number": number,
+		"total":          total,
+		"tax_total":      taxTotal,
+	})
+	return id, true, nil
+}
+
+// generateDueInvoices is the daily job: every active paid subscription gets
+// its invoice up to invoiceLeadDays before its billing date, and any whose
+// billing date is today and still has none (e.g. converted from a trial since)
+// gets it now. One subscription failing is logged and doesn't hold up the rest.
+func generateDueInvoices() error {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(billing_date, 1) FROM mentor.subscriptions
+		WHERE status = 'active' AND deleted_at IS NULL AND subscription_type = 'paid' AND amount > 0
+	`)
+	if err != nil {
+		return err
+	}
+	billingDays := map[int]int{}
+	for rows.Next() {
+		var id, billingDay int
+		rows.Scan(&id, &billingDay)
+		billingDays[id] = billingDay
+	}
+	rows.Close()
+
+	today := localToday()
+	failed := 0
+	for id, billingDay := range billingDays {
+		days := []time.Time{today.AddDate(0, 0, invoiceLeadDays)}
+		if start, _ := billingCycleFor(today, billingDay); start.Equal(today) {
+			days = append(days, today)
+		}
+		for _, day := range days {
+			if _, _, err := generateInvoice(id, day); err != nil && err != errNotBillable {
+				log.Printf("Invoice for subscription %d (%s): %v", id, day.Format("2006-01-02"), err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d invoices could not be generated", failed)
+	}
+	return nil
+}
+
+// invoiceSelect lists the columns scanInvoice reads; paid sums the income
+// transactions linked to the invoice
+const invoiceSelect = `
+	SELECT i.id, i.invoice_number, i.subscription_id, s.student_name, i.cycle_start, i.cycle_end, i.issue_date,
+	       i.due_date, i.subtotal, i.total, i.status, COALESCE(p.paid, 0), i.voided_at, COALESCE(i.void_reason, ''),
+	       COALESCE(i.currency, s.currency), i.tax_total, COALESCE(i.tax_mode, ''), COALESCE(i.tax_label, '')
+	FROM mentor.invoices i
+	JOIN mentor.subscriptions s ON s.id = i.subscription_id
+	LEFT JOIN (
+		SELECT invoice_id, SUM(amount) AS paid FROM mentor.transactions
+		WHERE type = 'income' AND voided_at IS NULL AND invoice_id IS NOT NULL
+		GROUP BY invoice_id
+	) p ON p.invoice_id = i.id
+`
+
+// invoicePaymentStatus is paid, partially_paid, overdue or unpaid for an
+// issued invoice
+func invoicePaymentStatus(total, paid float64, due time.Time) string {
+	switch {
+	case paid >= total:
+		return "paid"
+	case paid > 0:
+		return "partially_paid"
+	case localToday().After(due):
+		return "overdue"
+	}
+	return "unpaid"
+}
+
+func scanInvoice(rows *sql.Rows) (gin.H, error) {
+	var id, subId int
+	var number, studentName, status, voidReason string
+	var cycleStart, cycleEnd, issueDate, dueDate time.Time
+	var subtotal, total, paid, taxTotal float64
+	var voidedAt sql.NullTime
+	var currency sql.NullString
+	var taxMode, taxLabel string
+	if err := rows.Scan(&id, &number, &subId, &studentName, &cycleStart, &cycleEnd, &issueDate, &dueDate,
+		&subtotal, &total, &status, &paid, &voidedAt, &voidReason, &currency, &taxTotal, &taxMode, &taxLabel); err != nil {
+		return nil, err
+	}
+	invoice := gin.H{
+		"id":              id,
+		"invoice_number":  number,
+		"subscription_id": subId,
+		"student_name":    studentName,
+		"cycle_start":     cycleStart.Format("2006-01-02"),
+		"cycle_end":       cycleEnd.Format("2006-01-02"),
+		"issue_date":      issueDate.Format("2006-01-02"),
+		"due_date":        dueDate.Format("2006-01-02"),
+		"subtotal":        subtotal,
+		"taxable_amount":  roundMoney(total - taxTotal),
+		"tax_total":       taxTotal,
+		"total":           total,
+		"currency":        orDefaultCurrency(currency),
+		"amount_paid":     paid,
+		"balance":         roundMoney(math.Max(total-paid, 0)),
+		"status":          status,
+	}
+	if taxMode != "" {
+		invoice["tax_mode"] = taxMode
+		invoice["tax_label"] = taxLabel
+	}
+	if status == "void" {
+		invoice["payment_status"] = "void"
+		invoice["void_reason"] = voidReason
+		if voidedAt.Valid {
+			invoice["voided_at"] = isoTimestamp(voidedAt.Time)
+		}
+	} else {
+		invoice["payment_status"] = invoicePaymentStatus(total, paid, dueDate)
+	}
+	return invoice, nil
+}
+
+// queryInvoices runs invoiceSelect with a WHERE clause, newest first
+func queryInvoices(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(invoiceSelect+" WHERE "+where+" ORDER BY i.issue_date DESC, i.id DESC LIMIT 500", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []gin.H{}
+	for rows.Next() {
+		if invoice, err := scanInvoice(rows); err == nil {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+// getInvoices - Invoices filtered by subscription, status (issued, void) or
+// payment status (paid, partially_paid, overdue, unpaid), and issue date range
+func getInvoices(c *gin.Context) {
+	where := "1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		where += fmt.Sprintf(" AND i.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+	if status := c.Query("status"); status == "issued" || status == "void" {
+		argCount++
+		where += fmt.Sprintf(" AND i.status = $%d", argCount)
+		args = append(args, status)
+	}
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<="}} {
+		if v := c.Query(bound.param); v != "" {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": bound.param + " must be YYYY-MM-DD"})
+				return
+			}
+			argCount++
+			where += fmt.Sprintf(" AND i.issue_date %s $%d", bound.op, argCount)
+			args = append(args, v)
+		}
+	}
+
+	invoices, err := queryInvoices(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if paymentStatus := c.Query("payment_status"); paymentStatus != "" {
+		filtered := []gin.H{}
+		for _, inv := range invoices {
+			if inv["payment_status"] == paymentStatus {
+				filtered = append(filtered, inv)
+			}
+		}
+		invoices = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invoices": invoices})
+}
+
+// loadInvoice returns one invoice with its line items and linked payments
+func loadInvoice(id string) (gin.H, error) {
+	invoices, err := queryInvoices("i.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	invoice := invoices[0]
+
+	items := []gin.H{}
+	rows, err := db.Query(`
+		SELECT kind, description, amount, COALESCE(net_amount, amount), tax_percent, tax_amount FROM mentor.invoice_items
+		WHERE invoice_id = $1 ORDER BY position, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var kind, description string
+		var amount, net, taxPercent, tax float64
+		if err := rows.Scan(&kind, &description, &amount, &net, &taxPercent, &tax); err != nil {
+			continue
+		}
+		items = append(items, gin.H{
+			"kind":        kind,
+			"description": description,
+			"amount":      amount,
+			"net_amount":  net,
+			"tax_percent": taxPercent,
+			"tax_amount":  tax,
+			"line_total":  roundMoney(net + tax),
+		})
+	}
+	rows.Close()
+	invoice["items"] = items
+
+	payments := []gin.H{}
+	rows, err = db.Query(`
+		SELECT id, date, amount, COALESCE(description, '') FROM mentor.transactions
+		WHERE invoice_id = $1 AND type = 'income' AND voided_at IS NULL
+		ORDER BY date, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var txID int
+		var date time.Time
+		var amount float64
+		var description string
+		if err := rows.Scan(&txID, &date, &amount, &description); err != nil {
+			continue
+		}
+		payments = append(payments, gin.H{
+			"transaction_id": txID,
+			"date":           date.Format("2006-01-02"),
+			"amount":         amount,
+			"description":    description,
+		})
+	}
+	rows.Close()
+	invoice["payments"] = payments
+
+	return invoice, nil
+}
+
+// getInvoice - One invoice with line items and payments
+func getInvoice(c *gin.Context) {
+	invoice, err := loadInvoice(c.Param("id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Invoice not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "invoice": invoice})
+}
+
+// createSubscriptionInvoice - Issue the invoice for the cycle containing
+// ?date= (default today) now rather than waiting for the job
+func createSubscriptionInvoice(c *gin.Context) {
+	subId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid subscription id"})
+		return
+	}
+
+	day := time.Now()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date must be YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+
+	id, created, err := generateInvoice(subId, day)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	invoice, err := loadInvoice(strconv.Itoa(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	message := "Invoice issued"
+	if !created {
+		message = "An invoice for this cycle already exists"
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "created": created, "invoice": invoice, "message": message})
+}
+
+// voidInvoice - Cancel an issued invoice. Its late fees and makeup credits
+// are released for the next invoice, and its payments are unlinked.
+func voidInvoice(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		VoidedBy string `json:"voided_by"`
+		Reason   string `json:"reason"`
+	}
+	c.ShouldBindJSON(&input)
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"reason": "is required"}))
+		return
+	}
+	if input.VoidedBy == "" {
+		input.VoidedBy = "admin"
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var subId int
+	var number string
+	err = tx.QueryRow(`
+		UPDATE mentor.invoices SET status = 'void', voided_by = $1, void_reason = $2, voided_at = NOW()
+		WHERE id = $3 AND status = 'issued'
+		RETURNING subscription_id, invoice_number
+	`, input.VoidedBy, input.Reason, id).Scan(&subId, &number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Issued invoice not found"})
+		return
+	}
+
+	for _, stmt := range []string{
+		"UPDATE mentor.late_fees SET invoice_id = NULL WHERE invoice_id = $1",
+		"UPDATE mentor.makeup_credits SET used_at = NULL, invoice_id = NULL WHERE invoice_id = $1",
+		"UPDATE mentor.transactions SET invoice_id = NULL WHERE invoice_id = $1",
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", subId, "invoice_voided", input.VoidedBy, gin.H{
+		"invoice_id":     id,
+		"invoice_number": number,
+		"reason":         input.Reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Invoice " + number + " voided"})
+}
+
+// openInvoiceFor is the subscription's oldest issued invoice not yet paid in
+// full, which a fee payment without an invoice_id settles
+func openInvoiceFor(subId int) sql.NullInt64 {
+	var id sql.NullInt64
+	db.QueryRow(`
+		SELECT i.id FROM mentor.invoices i
+		WHERE i.subscription_id = $1 AND i.status = 'issued'
+		  AND i.total > (SELECT COALESCE(SUM(t.amount), 0) FROM mentor.transactions t
+		                 WHERE t.invoice_id = i.id AND t.type = 'income' AND t.voided_at IS NULL)
+		ORDER BY i.cycle_start, i.id LIMIT 1
+	`, subId).Scan(&id)
+	return id
+}
+
+// setSubscriptionDiscount - Standing percentage discount shown on every
+// future invoice
+func setSubscriptionDiscount(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		DiscountPercent float64 `json:"discount_percent"`
+		Reason          string  `json:"reason"`
+		UpdatedBy       string  `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if input.DiscountPercent < 0 || input.DiscountPercent > 100 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"discount_percent": "must be between 0 and 100"}))
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.subscriptions SET discount_percent = $1, discount_reason = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL
+	`, input.DiscountPercent, strings.TrimSpace(input.Reason), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	logAudit("subscription", id, "discount_updated", input.UpdatedBy, gin.H{
+		"discount_percent": input.DiscountPercent,
+		"reason":           input.Reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Discount updated"})
+}
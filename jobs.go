@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// ============================================
+// BACKGROUND JOBS
+// ============================================
+
+// job is a periodic task run by the in-process scheduler
+type job struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+var jobs = []job{
+	{"purge-deleted-subscriptions", 24 * time.Hour, func() error {
+		_, err := purgeSoftDeleted()
+		return err
+	}},
+	{"apply-scheduled-cancellations", time.Hour, applyScheduledCancellations},
+	{"expire-trials", time.Hour, func() error {
+		_, err := expireTrials()
+		return err
+	}},
+	{"refresh-billing-cycles", 6 * time.Hour, refreshBillingCycles},
+	{"apply-late-fees", 24 * time.Hour, func() error {
+		_, err := applyLateFees()
+		return err
+	}},
+	{"detect-duplicate-transactions", time.Hour, func() error {
+		_, err := detectDuplicateTransactions()
+		return err
+	}},
+	{"check-integrity", 24 * time.Hour, logIntegrityReport},
+	{"preprocess-answer-pages", time.Minute, processImageJobs},
+	{"grade-exam-submissions", time.Minute, processExamGrading},
+	{"weekly-owner-digest", time.Hour, sendWeeklyDigestIfDue},
+	{"sync-class-sessions", time.Hour, syncUpcomingClassSessions},
+	{"prune-teacher-sessions", 24 * time.Hour, pruneTeacherSessions},
+	{"sync-google-calendars", 15 * time.Minute, syncAllGoogleCalendars},
+	{"detect-missed-classes", time.Hour, detectMissedClassesJob},
+	{"generate-invoices", 24 * time.Hour, generateDueInvoices},
+	{"cleanup-orphan-content-assets", 24 * time.Hour, func() error {
+		_, err := cleanupOrphanContentAssets(false)
+		return err
+	}},
+}
+
+// startJobs runs each job once shortly after boot and then on its interval,
+// and starts the worker behind requestClassSessionSync
+func startJobs() {
+	go runClassSessionSyncWorker()
+	for _, j := range jobs {
+		go func(j job) {
+			time.Sleep(time.Minute)
+			for {
+				if err := j.run(); err != nil {
+					log.Printf("Job %s failed: %v", j.name, err)
+				}
+				time.Sleep(j.interval)
+			}
+		}(j)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// LATE FEES
+// ============================================
+
+type lateFeePolicy struct {
+	Enabled   bool    `json:"enabled"`
+	Mode      string  `json:"mode"`  // "flat" or "percent"
+	Value     float64 `json:"value"` // amount, or percent of the monthly fee
+	GraceDays int     `json:"grace_days"`
+}
+
+func loadLateFeePolicy() (lateFeePolicy, error) {
+	var p lateFeePolicy
+	err := db.QueryRow(`
+		SELECT enabled, mode, value, grace_days FROM mentor.late_fee_policy WHERE id = 1
+	`).Scan(&p.Enabled, &p.Mode, &p.Value, &p.GraceDays)
+	return p, err
+}
+
+// fee returns the late fee for a monthly amount under this policy
+func (p lateFeePolicy) fee(monthlyAmount float64) float64 {
+	if p.Mode == "percent" {
+		return monthlyAmount * p.Value / 100
+	}
+	return p.Value
+}
+
+// cyclePaidAmount is the fee income recorded for a subscription within a
+// billing cycle. A payment against its billing group settles it in full.
+func cyclePaidAmount(subId int, amount float64, start, end time.Time) float64 {
+	var paid float64
+	var groupPaid bool
+	db.QueryRow(`
+		SELECT COALESCE(SUM(t.amount) FILTER (WHERE t.subscription_id = s.id), 0),
+		       COALESCE(BOOL_OR(t.billing_group_id = s.billing_group_id), false)
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.transactions t
+		       ON (t.subscription_id = s.id OR t.billing_group_id = s.billing_group_id)
+		      AND t.type = 'income' AND t.voided_at IS NULL AND t.date >= $2 AND t.date < $3
+		WHERE s.id = $1
+	`, subId, start, end).Scan(&paid, &groupPaid)
+
+	if groupPaid && paid < amount {
+		return amount
+	}
+	return paid
+}
+
+// applyLateFees charges the policy's late fee once per cycle to active paid
+// subscriptions whose fee is still unpaid after the grace period
+func applyLateFees() (int, error) {
+	policy, err := loadLateFeePolicy()
+	if err != nil || !policy.Enabled || policy.Value <= 0 {
+		return 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, amount, COALESCE(billing_date, 1) FROM mentor.subscriptions
+		WHERE status = 'active' AND deleted_at IS NULL AND subscription_type = 'paid' AND amount > 0
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type due struct {
+		id          int
+		amount      float64
+		billingDate int
+	}
+	var subs []due
+	for rows.Next() {
+		var d due
+		rows.Scan(&d.id, &d.amount, &d.billingDate)
+		subs = append(subs, d)
+	}
+	rows.Close()
+
+	today := time.Now()
+	applied := 0
+	for _, s := range subs {
+		start, end := billingCycleFor(today, s.billingDate)
+		if today.Before(start.AddDate(0, 0, policy.GraceDays+1)) {
+			continue
+		}
+		if cyclePaidAmount(s.id, s.amount, start, end) >= s.amount {
+			continue
+		}
+
+		result, err := db.Exec(`
+			INSERT INTO mentor.late_fees (subscription_id, cycle_start, amount)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (subscription_id, cycle_start) DO NOTHING
+		`, s.id, start, policy.fee(s.amount))
+		if err != nil {
+			return applied, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// getLateFeePolicyHandler - Current late-fee rule
+func getLateFeePolicyHandler(c *gin.Context) {
+	policy, err := loadLateFeePolicy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "policy": policy})
+}
+
+// updateLateFeePolicy - Admin sets the late-fee rule
+func updateLateFeePolicy(c *gin.Context) {
+	var input struct {
+		lateFeePolicy
+		UpdatedBy string `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Mode != "flat" && input.Mode != "percent" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "mode must be 'flat' or 'percent'"})
+		return
+	}
+	if input.Value < 0 || input.GraceDays < 0 || (input.Mode == "percent" && input.Value > 100) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "value and grace_days must be non-negative (percent at most 100)"})
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO mentor.late_fee_policy (id, enabled, mode, value, grace_days, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (id) DO UPDATE SET enabled = EXCLUDED.enabled, mode = EXCLUDED.mode,
+		    value = EXCLUDED.value, grace_days = EXCLUDED.grace_days,
+		    updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, input.Enabled, input.Mode, input.Value, input.GraceDays, input.UpdatedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("late_fee_policy", 1, "updated", input.UpdatedBy, gin.H{
+		"enabled":    input.Enabled,
+		"mode":       input.Mode,
+		"value":      input.Value,
+		"grace_days": input.GraceDays,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "policy": input.lateFeePolicy})
+}
+
+// getSubscriptionLateFees - Late fees charged to a subscription
+func getSubscriptionLateFees(c *gin.Context) {
+	lateFees, err := queryLateFees(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	outstanding := float64(0)
+	for _, f := range lateFees {
+		if f["status"] == "applied" {
+			outstanding += f["amount"].(float64)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "late_fees": lateFees, "outstanding": outstanding})
+}
+
+func queryLateFees(subId interface{}) ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT lf.id, lf.cycle_start, lf.amount, s.currency, lf.status, lf.waived_by, lf.waive_reason, lf.waived_at, lf.created_at
+		FROM mentor.late_fees lf
+		JOIN mentor.subscriptions s ON s.id = lf.subscription_id
+		WHERE lf.subscription_id = $1
+		ORDER BY lf.cycle_start DESC
+	`, subId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lateFees := []gin.H{}
+	for rows.Next() {
+		var id int
+		var cycleStart, createdAt time.Time
+		var amount float64
+		var status string
+		var currency, waivedBy, waiveReason sql.NullString
+		var waivedAt sql.NullTime
+		if err := rows.Scan(&id, &cycleStart, &amount, &currency, &status, &waivedBy, &waiveReason, &waivedAt, &createdAt); err != nil {
+			continue
+		}
+		fee := gin.H{
+			"id":          id,
+			"cycle_start": cycleStart.Format("2006-01-02"),
+			"amount":      amount,
+			"currency":    orDefaultCurrency(currency),
+			"status":      status,
+			"charged_at":  createdAt.Format("2006-01-02"),
+		}
+		if waivedAt.Valid {
+			fee["waived_by"] = waivedBy.String
+			fee["waive_reason"] = waiveReason.String
+			fee["waived_at"] = isoTimestamp(waivedAt.Time)
+		}
+		lateFees = append(lateFees, fee)
+	}
+	return lateFees, nil
+}
+
+// waiveLateFee - Admin waives an individual late fee
+func waiveLateFee(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Reason   string `json:"reason"`
+		WaivedBy string `json:"waived_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "reason is required"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.late_fees
+		SET status = 'waived', waived_by = $1, waive_reason = $2, waived_at = NOW()
+		WHERE id = $3 AND status = 'applied'
+	`, input.WaivedBy, input.Reason, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Late fee not found or already waived"})
+		return
+	}
+
+	logAudit("late_fee", id, "waived", input.WaivedBy, gin.H{"reason": input.Reason})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Late fee waived"})
+}
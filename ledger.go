@@ -0,0 +1,228 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// SUBSCRIPTION LEDGER (Statement of account)
+// ============================================
+
+// ledgerEntry is one line of a subscription's statement. Debits are charges
+// to the guardian, credits are payments and reversals.
+type ledgerEntry struct {
+	Date        time.Time
+	Order       int // charges before payments on the same day
+	Kind        string
+	Description string
+	Debit       float64
+	Credit      float64
+	Ref         gin.H
+}
+
+// getSubscriptionLedger - Chronological statement for a subscription:
+// invoices raised (and voided), late fees not yet invoiced, payments and
+// refunds, with the running balance the guardian owes. A payment made for
+// the whole billing group is credited at the subscription's fee for that
+// cycle.
+func getSubscriptionLedger(c *gin.Context) {
+	subId := c.Param("id")
+
+	var studentName string
+	var amount float64
+	var billingDay int
+	var groupId sql.NullInt64
+	var currency sql.NullString
+	err := db.QueryRow(`
+		SELECT student_name, COALESCE(amount, 0), COALESCE(billing_date, 1), billing_group_id, currency
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, subId).Scan(&studentName, &amount, &billingDay, &groupId, &currency)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var entries []ledgerEntry
+	cycleFees := map[string]float64{}
+	invoiceTotals := map[int64]float64{}
+
+	rows, err := db.Query(`
+		SELECT i.id, i.invoice_number, i.cycle_start, i.cycle_end, i.issue_date, i.due_date, i.total, i.status,
+		       COALESCE(p.paid, 0), i.voided_at, COALESCE(i.void_reason, '')
+		FROM mentor.invoices i
+		LEFT JOIN (
+			SELECT invoice_id, SUM(amount) AS paid FROM mentor.transactions
+			WHERE type = 'income' AND voided_at IS NULL AND invoice_id IS NOT NULL
+			GROUP BY invoice_id
+		) p ON p.invoice_id = i.id
+		WHERE i.subscription_id = $1
+	`, subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var id int
+		var number, status, voidReason string
+		var cycleStart, cycleEnd, issueDate, dueDate time.Time
+		var total, invoicePaid float64
+		var voidedAt sql.NullTime
+		if err := rows.Scan(&id, &number, &cycleStart, &cycleEnd, &issueDate, &dueDate, &total, &status, &invoicePaid,
+			&voidedAt, &voidReason); err != nil {
+			continue
+		}
+		ref := gin.H{"invoice_id": id, "invoice_number": number}
+		invoiceTotals[int64(id)] = total
+		entries = append(entries, ledgerEntry{
+			Date: issueDate, Kind: "invoice", Debit: total, Ref: ref,
+			Description: fmt.Sprintf("Invoice %s (%s to %s)", number, cycleStart.Format("2006-01-02"), cycleEnd.Format("2006-01-02")),
+		})
+		if status == "void" {
+			voided := issueDate
+			if voidedAt.Valid {
+				voided = voidedAt.Time
+			}
+			description := "Invoice " + number + " voided"
+			if voidReason != "" {
+				description += ": " + voidReason
+			}
+			entries = append(entries, ledgerEntry{Date: voided, Order: 1, Kind: "invoice_void", Description: description, Credit: total, Ref: ref})
+			entries[len(entries)-2].Ref = gin.H{"invoice_id": id, "invoice_number": number, "payment_status": "void"}
+		} else {
+			cycleFees[cycleStart.Format("2006-01-02")] = total
+			ref["payment_status"] = invoicePaymentStatus(total, invoicePaid, dueDate)
+			ref["invoice_balance"] = roundMoney(math.Max(total-invoicePaid, 0))
+		}
+	}
+	rows.Close()
+
+	// Late fees carried on an invoice are already in its total
+	rows, err = db.Query(`
+		SELECT id, cycle_start, amount, created_at FROM mentor.late_fees
+		WHERE subscription_id = $1 AND status = 'applied' AND invoice_id IS NULL
+	`, subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var id int
+		var cycleStart, createdAt time.Time
+		var fee float64
+		if err := rows.Scan(&id, &cycleStart, &fee, &createdAt); err != nil {
+			continue
+		}
+		entries = append(entries, ledgerEntry{
+			Date: createdAt, Kind: "late_fee", Debit: fee, Ref: gin.H{"late_fee_id": id},
+			Description: "Late fee for the cycle starting " + cycleStart.Format("2006-01-02"),
+		})
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT t.id, t.date, t.type, t.amount, COALESCE(t.description, ''), COALESCE(t.payment_method, ''),
+		       t.invoice_id, t.subscription_id IS NULL
+		FROM mentor.transactions t
+		WHERE t.voided_at IS NULL
+		  AND (t.subscription_id = $1 OR (t.subscription_id IS NULL AND t.billing_group_id = $2 AND t.type = 'income'))
+	`, subId, groupId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var id int
+		var date time.Time
+		var txType, description, method string
+		var txAmount float64
+		var invoiceId sql.NullInt64
+		var groupPayment bool
+		if err := rows.Scan(&id, &date, &txType, &txAmount, &description, &method, &invoiceId, &groupPayment); err != nil {
+			continue
+		}
+		ref := gin.H{"transaction_id": id}
+		if method != "" {
+			ref["payment_method"] = method
+		}
+		if invoiceId.Valid {
+			ref["invoice_id"] = invoiceId.Int64
+		}
+
+		entry := ledgerEntry{Date: date, Order: 2, Kind: "payment", Description: description, Credit: txAmount, Ref: ref}
+		switch {
+		case txType == "expense":
+			entry.Kind, entry.Order, entry.Debit, entry.Credit = "refund", 1, txAmount, 0
+		case groupPayment:
+			start, _ := billingCycleFor(date, billingDay)
+			fee, ok := cycleFees[start.Format("2006-01-02")]
+			if !ok {
+				fee = amount
+			}
+			entry.Kind, entry.Credit = "group_payment", math.Min(fee, txAmount)
+			ref["group_payment_amount"] = txAmount
+		}
+		if entry.Description == "" {
+			entry.Description = entry.Kind
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		di, dj := entries[i].Date.Format("2006-01-02"), entries[j].Date.Format("2006-01-02")
+		if di != dj {
+			return di < dj
+		}
+		return entries[i].Order < entries[j].Order
+	})
+
+	// Each installment shows what is left on its invoice after it
+	invoicePaid := map[int64]float64{}
+	ledger := []gin.H{}
+	balance, charged, paid := 0.0, 0.0, 0.0
+	for _, e := range entries {
+		if invoiceId, ok := e.Ref["invoice_id"].(int64); ok && e.Kind == "payment" {
+			if total, ok := invoiceTotals[invoiceId]; ok {
+				invoicePaid[invoiceId] += e.Credit
+				e.Ref["invoice_balance_after"] = roundMoney(math.Max(total-invoicePaid[invoiceId], 0))
+			}
+		}
+		balance = roundMoney(balance + e.Debit - e.Credit)
+		charged += e.Debit
+		paid += e.Credit
+		line := gin.H{
+			"date":        e.Date.Format("2006-01-02"),
+			"kind":        e.Kind,
+			"description": e.Description,
+			"debit":       roundMoney(e.Debit),
+			"credit":      roundMoney(e.Credit),
+			"balance":     balance,
+		}
+		for k, v := range e.Ref {
+			line[k] = v
+		}
+		ledger = append(ledger, line)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"subscription_id": subId,
+		"student_name":    studentName,
+		"currency":        orDefaultCurrency(currency),
+		"ledger":          ledger,
+		"total_charged":   roundMoney(charged),
+		"total_credited":  roundMoney(paid),
+		"balance":         balance,
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,16 +16,17 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var db *sql.DB
 
 func main() {
 	godotenv.Load()
+	setupTimezone()
 
 	var err error
-	db, err = sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	db, err = sql.Open("postgres", databaseURLWithTimezone(os.Getenv("DATABASE_URL")))
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -40,12 +42,27 @@ func main() {
 	}
 	log.Println("Connected to PostgreSQL (mentor schema)")
 
+	// One-off admin commands: ./main <command> [args]
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "clone-staging":
+			runStagingCloneCommand(os.Args[2:])
+			return
+		case "migrate-exam-images":
+			runExamImageBackfillCommand()
+			return
+		}
+	}
+
+	checkAIProviders()
+	startJobs()
+
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Admin-Token"},
 		AllowCredentials: true,
 	}))
 
@@ -54,10 +71,18 @@ func main() {
 		// Auth
 		api.POST("/login", login)
 		api.GET("/login", login)
+		api.POST("/auth/:provider", identityLogin)
 
 		// Legacy endpoints (for existing app)
 		api.GET("/schedule/:teacherId", getSchedule)
 		api.GET("/schedule/:teacherId/today", getTodaySchedule)
+		api.GET("/schedule/suggest", suggestSchedule)
+		api.POST("/schedule/suggest/accept", adminOnly(), acceptScheduleSuggestion)
+
+		// Schedule change requests (teachers propose under /me, guardians decide under /student)
+		api.GET("/schedule-requests", adminOnly(), getScheduleRequests)
+		api.POST("/schedule-requests/:id/approve", adminOnly(), approveScheduleRequest)
+		api.POST("/schedule-requests/:id/reject", adminOnly(), rejectScheduleRequest)
 		api.GET("/students/:teacherId", getStudents)
 		api.GET("/subjects/:class", getSubjects)
 
@@ -66,9 +91,92 @@ func main() {
 		api.GET("/subscriptions/:id", getSubscription)
 		api.POST("/subscriptions", createSubscription)
 		api.PUT("/subscriptions/:id", updateSubscription)
+		api.PATCH("/subscriptions/:id", patchSubscription)
 		api.DELETE("/subscriptions/:id", deleteSubscription)
+		api.POST("/subscriptions/:id/cancel", cancelSubscription)
+		api.POST("/subscriptions/:id/restore", restoreSubscription)
+		api.POST("/subscriptions/:id/transfer", transferSubscription)
+		api.POST("/subscriptions/:id/convert", convertTrial)
 		api.POST("/subscriptions/:id/complete", markClassComplete)
 		api.GET("/subscriptions/:id/progress", getProgress)
+		api.GET("/subscriptions/:id/student-attendance", getStudentAttendance)
+		api.GET("/subscriptions/:id/attendance", guardianOrAdmin("id"), getSubscriptionAttendance)
+		api.PUT("/subscriptions/:id/test-gate", updateChapterTestGate)
+		api.PUT("/subscriptions/:id/subjects/:subject/teacher", assignSubjectTeacher)
+		api.GET("/subscriptions/:id/slots", getSubscriptionSlots)
+		api.PUT("/subscriptions/:id/slots", setSubscriptionSlots)
+		api.PUT("/subscriptions/:id/billing-group", setSubscriptionBillingGroup)
+		api.GET("/subscriptions/:id/billing-cycles", getBillingCycles)
+		api.GET("/billing/shortfalls", getBillingShortfalls)
+		api.PUT("/subscriptions/:id/discount", setSubscriptionDiscount)
+		api.POST("/subscriptions/:id/invoices", adminOnly(), createSubscriptionInvoice)
+		api.GET("/subscriptions/:id/ledger", guardianOrAdmin("id"), getSubscriptionLedger)
+		api.GET("/invoices", getInvoices)
+		api.GET("/billing/cycle-dues", getCycleDues)
+		api.GET("/dues", adminOnly(), getDues)
+		api.GET("/invoices/:id", getInvoice)
+		api.GET("/admin/invoice-settings", adminOnly(), getInvoiceSettingsHandler)
+		api.PUT("/admin/invoice-settings", adminOnly(), updateInvoiceSettings)
+		api.GET("/invoices/:id/pdf", adminOnly(), getInvoicePDF)
+		api.POST("/invoices/:id/payment-link", adminOnly(), createPaymentLink)
+		api.GET("/pay/:token", getPaymentPage)
+		api.POST("/pay/:token/checkout", startPaymentCheckout)
+		api.POST("/webhooks/payments", paymentWebhook)
+		api.POST("/invoices/:id/void", adminOnly(), voidInvoice)
+		api.GET("/subscriptions/:id/late-fees", getSubscriptionLateFees)
+		api.GET("/subscriptions/:id/projection", getSubscriptionProjection)
+		api.GET("/subscriptions/:id/pauses", getSubscriptionPauses)
+		api.POST("/subscriptions/:id/pauses", addSubscriptionPause)
+		api.DELETE("/subscriptions/:id/pauses/:pauseId", deleteSubscriptionPause)
+		api.POST("/late-fees/:id/waive", adminOnly(), waiveLateFee)
+		api.GET("/admin/late-fee-policy", adminOnly(), getLateFeePolicyHandler)
+		api.PUT("/admin/late-fee-policy", adminOnly(), updateLateFeePolicy)
+
+		// Billing groups (siblings on one bill)
+		api.POST("/billing-groups", createBillingGroup)
+		api.GET("/billing-groups/:id", getBillingGroup)
+
+		// Notes + timeline
+		api.GET("/subscriptions/:id/notes", getSubscriptionNotes)
+		api.POST("/subscriptions/:id/notes", createSubscriptionNote)
+		api.PUT("/notes/:id", updateSubscriptionNote)
+		api.DELETE("/notes/:id", deleteSubscriptionNote)
+		api.GET("/subscriptions/:id/timeline", getSubscriptionTimeline)
+
+		// Guardian reports (AI-drafted, teacher-edited)
+		api.POST("/subscriptions/:id/reports/generate", generateGuardianReport)
+		api.GET("/subscriptions/:id/reports", getGuardianReports)
+		api.PUT("/reports/:id", updateGuardianReport)
+		api.POST("/reports/:id/send", sendGuardianReport)
+
+		// Emergency cancellations + makeup credits
+		api.POST("/admin/broadcasts/cancel-day", adminOnly(), cancelDay)
+		api.GET("/admin/broadcasts/:id", adminOnly(), getBroadcast)
+		api.GET("/subscriptions/:id/makeup-credits", getMakeupCredits)
+		api.POST("/makeup-credits/:id/use", useMakeupCredit)
+
+		// Guardian ratings
+		api.POST("/subscriptions/:id/ratings", guardianOrAdmin("id"), createSubscriptionRating)
+		api.GET("/admin/ratings", adminOnly(), getRatingsForModeration)
+		api.PUT("/admin/ratings/:id", adminOnly(), moderateRating)
+
+		// Communication log
+		api.GET("/subscriptions/:id/communications", getSubscriptionCommunications)
+		api.POST("/subscriptions/:id/communications", logSubscriptionCommunication)
+		api.POST("/webhooks/notifications", notificationStatusWebhook)
+
+		// Waitlist
+		api.GET("/waitlist", getWaitlist)
+		api.POST("/waitlist", createWaitlistEntry)
+		api.PUT("/waitlist/:id", updateWaitlistEntry)
+		api.POST("/waitlist/:id/promote", promoteWaitlistEntry)
+
+		// Plan templates
+		api.GET("/plans", getPlans)
+		api.GET("/plans/:id", getPlan)
+		api.POST("/plans", createPlan)
+		api.PUT("/plans/:id", updatePlan)
+		api.DELETE("/plans/:id", deletePlan)
 
 		// Teacher CRUD endpoints
 		api.GET("/teachers", getTeachers)
@@ -76,41 +184,238 @@ func main() {
 		api.POST("/teachers", createTeacher)
 		api.PUT("/teachers/:id", updateTeacher)
 		api.DELETE("/teachers/:id", deleteTeacher)
+		api.PUT("/teachers/:id/active", adminOnly(), setTeacherActive)
+		api.GET("/teachers/:id/profile", getTeacherPublicProfile)
+		api.GET("/teachers/:id/benchmark", getTeacherBenchmark)
+		api.GET("/teachers/:id/workload", getTeacherWorkload)
+		api.GET("/teachers/:id/zones", getTeacherZones)
+		api.PUT("/teachers/:id/zones", adminOnly(), updateTeacherZones)
+		api.GET("/teachers/match", matchTeachers)
+		api.GET("/teachers/:id/ratings", getTeacherRatings)
+		api.GET("/teachers/:id/documents", teacherSelfOrAdmin("id"), getTeacherDocuments)
+		api.POST("/teachers/:id/documents", teacherSelfOrAdmin("id"), uploadTeacherDocument)
+		api.DELETE("/teachers/:id/documents/:docId", teacherSelfOrAdmin("id"), deleteTeacherDocument)
+		api.GET("/admin/documents", adminOnly(), getDocumentReviewQueue)
+		api.POST("/admin/documents/:id/review", adminOnly(), reviewTeacherDocument)
+		api.GET("/teachers/:id/capabilities", getTeacherCapabilities)
+		api.PUT("/teachers/:id/capabilities", adminOnly(), updateTeacherCapabilities)
+		api.PUT("/teachers/:id/pay-rate", adminOnly(), updateTeacherPayRate)
+		api.GET("/teachers/:id/identities", adminOnly(), getTeacherIdentities)
+		api.POST("/teachers/:id/identities", adminOnly(), linkTeacherIdentity)
+		api.DELETE("/teachers/:id/identities/:provider", adminOnly(), unlinkTeacherIdentity)
+		api.GET("/teachers/:id/blackouts", teacherSelfOrAdmin("id"), getTeacherBlackouts)
+		api.POST("/teachers/:id/blackouts", adminOnly(), addTeacherBlackout)
+		api.DELETE("/teachers/:id/blackouts/:date", adminOnly(), deleteTeacherBlackout)
 
 		// Teacher's today schedule (V2)
 		api.GET("/teacher/:teacherId/today", getTeacherTodayV2)
 
 		// Content Management endpoints
 		api.GET("/content", getContentList)
+		api.GET("/content/manifest", getContentManifest)
+		api.GET("/content/search", searchContent)
 		api.GET("/content/:class/:subject/:chapter", getContent)
-		api.POST("/content", upsertContent)
-		api.DELETE("/content/:class/:subject/:chapter", deleteContent)
+		api.GET("/content/:class/:subject/:chapter/worksheet", getContentWorksheet)
+		api.GET("/content/:class/:subject/:chapter/pdf", getContentPDF)
+		api.GET("/content/:class/:subject/:chapter/part/:part", getContentPart)
+		api.GET("/content/:class/:subject/:chapter/videos", getChapterVideos)
+		api.POST("/content/:class/:subject/:chapter/videos", addChapterVideo)
+		api.PUT("/content/:class/:subject/:chapter/videos/order", reorderChapterVideos)
+		api.PUT("/videos/:id", updateChapterVideo)
+		api.DELETE("/videos/:id", deleteChapterVideo)
+		api.GET("/subscriptions/:id/video-progress", guardianOrAdmin("id"), getSubscriptionVideoProgress)
+		api.POST("/content/:class/:subject/:chapter/submit", teacherOrAdmin(), submitContentForReview)
+		api.POST("/content/:class/:subject/:chapter/review", adminOnly(), reviewContent)
+		api.POST("/content/:class/:subject/:chapter/unpublish", adminOnly(), unpublishContent)
+		api.GET("/content/review-queue", adminOnly(), getContentReviewQueue)
+		api.POST("/content", teacherOrAdmin(), upsertContent)
+		api.POST("/content/copy", teacherOrAdmin(), copyContent)
+		api.DELETE("/content/:class/:subject/:chapter", adminOnly(), deleteContent)
+		api.GET("/content/assets", getContentAssets)
+		api.POST("/content/assets", teacherOrAdmin(), uploadContentAsset)
+		api.GET("/content/assets/:id", serveContentAsset)
+		api.DELETE("/content/assets/:id", adminOnly(), deleteContentAsset)
+		api.POST("/admin/content/assets/cleanup", adminOnly(), cleanupContentAssets)
 
 		// Chapters lookup
 		api.GET("/chapters", getChapters)
+		api.GET("/chapters/:class/:subject", getSyllabus)
+		api.POST("/chapters", adminOnly(), createSyllabus)
+		api.PUT("/chapters/:class/:subject", adminOnly(), updateSyllabus)
+		api.DELETE("/chapters/:class/:subject", adminOnly(), deleteSyllabus)
+
+		// Question bank & quizzes
+		// (answers are only shown to teachers and admins; students use /student/quizzes)
+		api.GET("/questions", teacherOrAdmin(), getQuestions)
+		api.POST("/questions", teacherOrAdmin(), createQuestion)
+		api.POST("/questions/generate", teacherOrAdmin(), generateBankQuestions)
+		api.PUT("/questions/:id", teacherOrAdmin(), updateQuestion)
+		api.DELETE("/questions/:id", adminOnly(), deleteQuestion)
+		api.GET("/quizzes", teacherOrAdmin(), getQuizzes)
+		api.POST("/quizzes", teacherOrAdmin(), createQuiz)
+		api.GET("/quizzes/:id", teacherOrAdmin(), getQuiz)
+		api.DELETE("/quizzes/:id", adminOnly(), deleteQuiz)
+		api.POST("/quizzes/:id/assign", teacherOrAdmin(), assignQuiz)
+		api.GET("/quizzes/:id/results", teacherOrAdmin(), getQuizResults)
+		api.GET("/subscriptions/:id/quizzes", teacherOrAdmin(), getSubscriptionQuizzes)
+		api.GET("/quiz-assignments/:id", teacherOrAdmin(), getQuizAssignment)
+		api.POST("/quiz-attempts/:id/review", teacherOrAdmin(), reviewQuizAttempt)
 
 		// Transactions & Analytics endpoints
 		api.GET("/transactions", getTransactions)
+		api.GET("/transactions/export", adminOnly(), getTransactionsExport)
 		api.POST("/transactions", createTransaction)
 		api.DELETE("/transactions/:id", deleteTransaction)
+		api.POST("/transactions/:id/approve", adminOnly(), approveTransaction)
+		api.GET("/transactions/:id/receipt.pdf", adminOnly(), getTransactionReceiptPDF)
+		api.GET("/admin/transactions/duplicates", adminOnly(), getDuplicateTransactions)
+		api.POST("/admin/transactions/duplicates/scan", adminOnly(), scanDuplicateTransactions)
+		api.POST("/admin/transactions/duplicates/:id/resolve", adminOnly(), resolveDuplicateTransaction)
 		api.GET("/analytics/monthly", getMonthlyAnalytics)
+		api.GET("/analytics/range", getRangeAnalytics)
+		api.GET("/exchange-rates", getExchangeRates)
+		api.PUT("/exchange-rates", adminOnly(), setExchangeRate)
+		api.GET("/analytics/teachers", adminOnly(), getTeacherAnalytics)
+
+		// Payroll (salary from completed classes)
+		api.POST("/payroll/run", adminOnly(), runPayroll)
+		api.GET("/payroll/payouts", adminOnly(), getPayouts)
+		api.POST("/payroll/payouts/paid", adminOnly(), markPayoutsPaid)
+		api.GET("/payroll/:teacherId", adminOnly(), getPayroll)
+		api.POST("/payroll/:teacherId", adminOnly(), createPayroll)
+		api.POST("/payroll/:teacherId/adjustments", adminOnly(), createPayAdjustment)
+		api.GET("/teacher/:teacherId/earnings", teacherSelfOrAdmin("teacherId"), getTeacherEarnings)
+		api.GET("/teacher/:teacherId/calendar.ics", getTeacherCalendar)
+		api.GET("/google-calendar/callback", googleCalendarCallback)
+		api.GET("/teacher/:teacherId/schedule", getTeacherScheduleRange)
+		api.GET("/teacher/:teacherId/schedule/:date", getTeacherScheduleOnDate)
+		api.GET("/teacher/:teacherId/upcoming", getTeacherUpcoming)
+		api.GET("/analytics/chapters", getChapterAnalytics)
+		api.PUT("/admin/chapter-estimates", adminOnly(), updateChapterEstimate)
 
 		// Attendance endpoints
 		api.POST("/attendance", recordAttendance)
-		api.GET("/attendance/:teacherId", getAttendanceHistory)
+		api.POST("/attendance/batch", teacherOrAdmin(), recordAttendanceBatch)
+		api.GET("/class-sessions", getClassSessions)
+		api.GET("/class-sessions/short", getShortClasses)
+		api.GET("/class-sessions/missed", adminOnly(), getMissedClasses)
+		api.POST("/class-sessions/missed/detect", adminOnly(), runMissedClassDetection)
+		api.GET("/holidays", getHolidays)
+		api.POST("/holidays", adminOnly(), createHolidays)
+		api.PUT("/holidays/:id", adminOnly(), updateHoliday)
+		api.DELETE("/holidays/:id", adminOnly(), deleteHoliday)
+		api.DELETE("/holidays", adminOnly(), deleteHolidayRange)
+		api.POST("/sessions/:id/reschedule", rescheduleClassSession) // teacher, student or admin token, checked in the handler
+		api.POST("/sessions/:id/cancel", cancelClassSession)         // teacher, student or admin token, checked in the handler
+		api.GET("/attendance/summary", adminOnly(), getAttendanceSummary)
+		api.GET("/attendance/export", adminOnly(), getAttendanceExport)
+		api.GET("/attendance/corrections", adminOnly(), getAttendanceCorrections)
+		api.POST("/attendance/corrections/:id/approve", adminOnly(), approveAttendanceCorrection)
+		api.POST("/attendance/corrections/:id/reject", adminOnly(), rejectAttendanceCorrection)
+		api.GET("/attendance/:teacherId", teacherSelfOrAdmin("teacherId"), getAttendanceHistory)
+		api.GET("/visits/:teacherId", getTeacherVisits)
+
+		// Online sessions
+		api.POST("/online-sessions", createOnlineSession)
+		api.GET("/online-sessions", getOnlineSessions)
+		api.PUT("/online-sessions/:id/recording", saveSessionRecording)
+		api.PUT("/online-sessions/:id/stats", saveSessionStats)
+		api.POST("/online-sessions/:id/room", createSessionRoom)
+		api.GET("/online-sessions/:id/join", getSessionJoinToken)
+		api.POST("/webhooks/video/:provider", videoWebhook)
 
 		// Manual Grading System (ImgBB + Admin Review)
-		api.POST("/upload/image", uploadToImgBB)              // Upload image to ImgBB
-		api.POST("/answer-papers/submit", submitAnswerPaper)  // Teacher submits paper
-		api.GET("/answer-papers", getAnswerPapers)            // List answer papers
-		api.GET("/answer-papers/:id", getAnswerPaper)         // Get single paper
-		
+		api.POST("/upload/image", uploadToImgBB)             // Upload image to ImgBB
+		api.POST("/answer-papers/submit", submitAnswerPaper) // Teacher submits paper
+		api.GET("/answer-papers", getAnswerPapers)           // List answer papers
+		api.GET("/answer-papers/:id", getAnswerPaper)        // Get single paper
+		api.GET("/answer-papers/:id/preprocessing", getPaperPreprocessing)
+		api.POST("/answer-papers/:id/preprocessing", requeuePaperPreprocessing)
+
 		// Admin Grading
-		api.GET("/admin/grading", getGradingQueue)            // Papers pending grading
-		api.POST("/admin/grading/:id", saveGrade)             // Admin saves grade
-		
-		// Teacher Grades History  
+		api.GET("/admin/grading", getGradingQueue) // Papers pending grading
+		api.POST("/admin/grading/:id", saveGrade)  // Admin saves grade
+
+		// Teacher Grades History
 		api.GET("/teacher/grades/:teacherId", getTeacherGrades)
+
+		// AI exam grading (queued)
+		// (teachers see and grade only their own submissions)
+		api.POST("/exam/submit", teacherOrAdmin(), submitExamForGrading)
+		api.GET("/exam/submissions", teacherOrAdmin(), getExamSubmissions)
+		api.GET("/exam/submissions/:id", teacherOrAdmin(), getExamSubmission)
+		api.POST("/exam/submissions/:id/regrade", teacherOrAdmin(), regradeExamSubmission)
+		api.POST("/exam/submissions/:id/gradings/:gradingId/select", teacherOrAdmin(), selectExamGrading)
+		api.PUT("/exam/submissions/:id/transcription", teacherOrAdmin(), updateExamTranscription)
+		api.GET("/exam/submissions/:id/similar", teacherOrAdmin(), getSimilarExamSubmissions)
+
+		// Grading rubrics
+		api.GET("/rubrics", getGradingRubrics)
+		api.POST("/rubrics", teacherOrAdmin(), createGradingRubric)
+		api.GET("/rubrics/:id", getGradingRubric)
+		api.PUT("/rubrics/:id", teacherOrAdmin(), updateGradingRubric)
+		api.DELETE("/rubrics/:id", adminOnly(), deleteGradingRubric)
+
+		// Audit log
+		api.GET("/admin/audit", adminOnly(), getAuditLog)
+
+		// Soft-deleted subscriptions
+		api.GET("/admin/subscriptions/deleted", adminOnly(), getDeletedSubscriptions)
+		api.POST("/admin/subscriptions/purge", adminOnly(), purgeDeletedSubscriptions)
+		api.POST("/admin/subscriptions/recompute-progress", adminOnly(), recomputeProgress)
+
+		// Staging data (anonymized clone, requires X-Admin-Token)
+		api.POST("/admin/staging/clone", adminOnly(), cloneStaging)
+
+		// Integrity checks (orphaned rows)
+		api.GET("/admin/integrity", adminOnly(), getIntegrityReport)
+		api.POST("/admin/integrity/repair", adminOnly(), repairIntegrity)
+
+		// Weekly owner digest (exceptions only)
+		api.GET("/admin/digest", adminOnly(), getOwnerDigest)
+		api.POST("/admin/digest/send", adminOnly(), sendOwnerDigestHandler)
+
+		// Homework (teacher side)
+		api.POST("/subscriptions/:id/homework", assignHomework)
+		api.GET("/subscriptions/:id/homework", getHomework)
+
+		// Student self-service (PIN login, Bearer token)
+		api.POST("/subscriptions/:id/student-pin/code", sendStudentPinCode)
+		api.PUT("/subscriptions/:id/student-pin", setStudentPin) // admin or teacher token, or the guardian's code; checked in the handler
+		api.POST("/student/login", studentLogin)
+		student := api.Group("/student", studentAuth())
+		student.GET("/homework", getStudentHomework)
+		student.GET("/upcoming", getStudentUpcoming)
+		student.GET("/tests", getStudentTests)
+		student.GET("/videos/:subject/:chapter", getStudentChapterVideos)
+		student.POST("/videos/:id/progress", recordVideoProgress)
+		student.GET("/quizzes", getStudentQuizzes)
+		student.GET("/quizzes/:id", getStudentQuiz)
+		student.POST("/quizzes/:id/submit", submitStudentQuiz)
+		student.GET("/badges", getStudentBadges)
+		student.POST("/ratings", createStudentRating)
+		student.GET("/schedule-requests", getStudentScheduleRequests)
+		student.POST("/schedule-requests/:id/approve", approveStudentScheduleRequest)
+		student.POST("/schedule-requests/:id/reject", rejectStudentScheduleRequest)
+
+		// Teacher self-service (token from login)
+		me := api.Group("/me", teacherAuth())
+		me.GET("", getMe)
+		me.PUT("", updateMe)
+		me.POST("/logout", logoutMe)
+		me.GET("/calendar", getMyCalendarFeed)
+		me.POST("/calendar/rotate", rotateMyCalendarFeed)
+		me.GET("/google-calendar", getMyGoogleCalendar)
+		me.PUT("/google-calendar", updateMyGoogleCalendar)
+		me.DELETE("/google-calendar", disconnectMyGoogleCalendar)
+		me.POST("/google-calendar/connect", connectMyGoogleCalendar)
+		me.POST("/google-calendar/sync", syncMyGoogleCalendar)
+		me.POST("/schedule-requests", createScheduleRequest)
+		me.GET("/schedule-requests", getMyScheduleRequests)
+		me.DELETE("/schedule-requests/:id", withdrawScheduleRequest)
+		me.POST("/attendance-corrections", createAttendanceCorrection)
+		me.GET("/attendance-corrections", getMyAttendanceCorrections)
+		me.DELETE("/attendance-corrections/:id", withdrawAttendanceCorrection)
 	}
 
 	r.GET("/health", func(c *gin.Context) {
@@ -158,8 +463,25 @@ func login(c *gin.Context) {
 		return
 	}
 
+	if ssoEnforced() {
+		var linked bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teacher_identities WHERE teacher_id = $1)", id).Scan(&linked)
+		if linked {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Password login is disabled; use single sign-on", "sso_required": true})
+			return
+		}
+	}
+
+	token, expiresAt, err := createTeacherSession(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
+		"success":    true,
+		"token":      token,
+		"expires_at": isoTimestamp(expiresAt),
 		"teacher": gin.H{
 			"id":    id,
 			"name":  name,
@@ -173,18 +495,20 @@ func login(c *gin.Context) {
 // ============================================
 func getSubscriptions(c *gin.Context) {
 	teacherId := c.Query("teacher_id")
+	status := c.DefaultQuery("status", "active") // active, cancelled, ...
 
 	query := `
 		SELECT id, student_name, student_phone, guardian_name, guardian_phone,
-		       class, subjects, teacher_id, days_per_week, schedule_days, time,
-		       amount, billing_date, status, total_classes, completed_classes, progress_percent
-		FROM mentor.subscriptions
-		WHERE status = 'active'
+		       class, subject_list, teacher_id, days_per_week, schedule_day_list, time,
+		       amount, billing_date, status, total_classes, completed_classes, progress_percent,
+		       billing_group_id, ` + billingGroupAmountSQL + `, currency, progress_stale
+		FROM mentor.subscriptions s
+		WHERE status = $1 AND deleted_at IS NULL
 	`
-	args := []interface{}{}
+	args := []interface{}{status}
 
 	if teacherId != "" {
-		query += " AND teacher_id = $1"
+		query += " AND teacher_id = $2"
 		args = append(args, teacherId)
 	}
 	query += " ORDER BY created_at DESC"
@@ -199,13 +523,18 @@ func getSubscriptions(c *gin.Context) {
 	var subscriptions []gin.H
 	for rows.Next() {
 		var id, class, daysPerWeek, billingDate, totalClasses, completedClasses int
-		var studentName, studentPhone, guardianName, guardianPhone, subjects, teacherID, scheduleDays, schedTime, status string
+		var studentName, studentPhone, guardianName, guardianPhone, teacherID, schedTime, status string
+		var subjects, scheduleDays []string
 		var amount, progressPercent float64
-		var studentPhoneNull, guardianNameNull, guardianPhoneNull sql.NullString
+		var studentPhoneNull, guardianNameNull, guardianPhoneNull, currency sql.NullString
+		var billingGroupID sql.NullInt64
+		var groupAmount sql.NullFloat64
+		var progressStale bool
 
 		rows.Scan(&id, &studentName, &studentPhoneNull, &guardianNameNull, &guardianPhoneNull,
-			&class, &subjects, &teacherID, &daysPerWeek, &scheduleDays, &schedTime,
-			&amount, &billingDate, &status, &totalClasses, &completedClasses, &progressPercent)
+			&class, pq.Array(&subjects), &teacherID, &daysPerWeek, pq.Array(&scheduleDays), &schedTime,
+			&amount, &billingDate, &status, &totalClasses, &completedClasses, &progressPercent,
+			&billingGroupID, &groupAmount, &currency, &progressStale)
 
 		if studentPhoneNull.Valid {
 			studentPhone = studentPhoneNull.String
@@ -217,25 +546,33 @@ func getSubscriptions(c *gin.Context) {
 			guardianPhone = guardianPhoneNull.String
 		}
 
-		subscriptions = append(subscriptions, gin.H{
+		subscription := gin.H{
 			"id":                id,
 			"student_name":      studentName,
 			"student_phone":     studentPhone,
 			"guardian_name":     guardianName,
 			"guardian_phone":    guardianPhone,
 			"class":             class,
-			"subjects":          strings.Split(subjects, ","),
+			"subjects":          subjects,
 			"teacher_id":        teacherID,
 			"days_per_week":     daysPerWeek,
-			"schedule_days":     strings.Split(scheduleDays, ","),
+			"schedule_days":     scheduleDays,
 			"time":              schedTime,
 			"amount":            amount,
+			"currency":          orDefaultCurrency(currency),
 			"billing_date":      billingDate,
 			"status":            status,
 			"total_classes":     totalClasses,
 			"completed_classes": completedClasses,
 			"progress_percent":  progressPercent,
-		})
+			"progress_stale":    progressStale,
+		}
+		// Siblings in a billing group share one bill
+		if billingGroupID.Valid {
+			subscription["billing_group_id"] = billingGroupID.Int64
+			subscription["group_amount"] = groupAmount.Float64
+		}
+		subscriptions = append(subscriptions, subscription)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "subscriptions": subscriptions})
@@ -248,18 +585,23 @@ func getSubscription(c *gin.Context) {
 	id := c.Param("id")
 
 	var subId, class, daysPerWeek, billingDate, totalClasses, completedClasses int
-	var studentName, studentPhone, guardianName, guardianPhone, subjects, teacherID, scheduleDays, schedTime, status string
+	var studentName, studentPhone, guardianName, guardianPhone, teacherID, schedTime, status string
+	var subjects, scheduleDays []string
 	var amount, progressPercent float64
-	var studentPhoneNull, guardianNameNull, guardianPhoneNull sql.NullString
+	var studentPhoneNull, guardianNameNull, guardianPhoneNull, cancelReasonNull, areaNull, postcodeNull, currency sql.NullString
+	var endDate sql.NullTime
+	var progressStale bool
 
 	err := db.QueryRow(`
 		SELECT id, student_name, student_phone, guardian_name, guardian_phone,
-		       class, subjects, teacher_id, days_per_week, schedule_days, time,
-		       amount, billing_date, status, total_classes, completed_classes, progress_percent
-		FROM mentor.subscriptions WHERE id = $1
+		       class, subject_list, teacher_id, days_per_week, schedule_day_list, time,
+		       amount, billing_date, status, total_classes, completed_classes, progress_percent,
+		       end_date, cancel_reason, area, postcode, currency, progress_stale
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
 	`, id).Scan(&subId, &studentName, &studentPhoneNull, &guardianNameNull, &guardianPhoneNull,
-		&class, &subjects, &teacherID, &daysPerWeek, &scheduleDays, &schedTime,
-		&amount, &billingDate, &status, &totalClasses, &completedClasses, &progressPercent)
+		&class, pq.Array(&subjects), &teacherID, &daysPerWeek, pq.Array(&scheduleDays), &schedTime,
+		&amount, &billingDate, &status, &totalClasses, &completedClasses, &progressPercent,
+		&endDate, &cancelReasonNull, &areaNull, &postcodeNull, &currency, &progressStale)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
@@ -275,93 +617,196 @@ func getSubscription(c *gin.Context) {
 	if guardianPhoneNull.Valid {
 		guardianPhone = guardianPhoneNull.String
 	}
+	endDateStr := ""
+	if endDate.Valid {
+		endDateStr = endDate.Time.Format("2006-01-02")
+	}
 
-	// Get schedule (subjects with progress)
-	schedRows, _ := db.Query(`
-		SELECT id, subject, current_chapter, current_part, total_parts_done, total_parts_needed
-		FROM mentor.schedule WHERE subscription_id = $1
-	`, id)
-	defer schedRows.Close()
+	// Get schedule (subjects with progress); lite clients skip it entirely
+	var schedules, priceBreakdown []gin.H
+	if wantsField(c, "schedule") {
+		schedRows, _ := db.Query(`
+			SELECT id, subject, current_chapter, current_part, total_parts_done, total_parts_needed,
+			       COALESCE(teacher_id, ''), price
+			FROM mentor.schedule WHERE subscription_id = $1
+		`, id)
+		defer schedRows.Close()
 
-	var schedules []gin.H
-	for schedRows.Next() {
-		var schedId, currentChapter, currentPart, totalPartsDone, totalPartsNeeded int
-		var subject string
-		schedRows.Scan(&schedId, &subject, &currentChapter, &currentPart, &totalPartsDone, &totalPartsNeeded)
+		for schedRows.Next() {
+			var schedId, currentChapter, currentPart, totalPartsDone, totalPartsNeeded int
+			var subject, subjectTeacherID string
+			var price sql.NullFloat64
+			schedRows.Scan(&schedId, &subject, &currentChapter, &currentPart, &totalPartsDone, &totalPartsNeeded,
+				&subjectTeacherID, &price)
+
+			// Subjects without their own teacher follow the subscription's teacher
+			if subjectTeacherID == "" {
+				subjectTeacherID = teacherID
+			}
+
+			subjectProgress := float64(0)
+			if totalPartsNeeded > 0 {
+				subjectProgress = float64(totalPartsDone) / float64(totalPartsNeeded) * 100
+			}
 
-		subjectProgress := float64(0)
-		if totalPartsNeeded > 0 {
-			subjectProgress = float64(totalPartsDone) / float64(totalPartsNeeded) * 100
+			schedules = append(schedules, gin.H{
+				"id":                 schedId,
+				"subject":            subject,
+				"current_chapter":    currentChapter,
+				"current_part":       currentPart,
+				"total_parts_done":   totalPartsDone,
+				"total_parts_needed": totalPartsNeeded,
+				"progress_percent":   subjectProgress,
+				"teacher_id":         subjectTeacherID,
+			})
+			if price.Valid {
+				schedules[len(schedules)-1]["price"] = price.Float64
+				priceBreakdown = append(priceBreakdown, gin.H{"subject": subject, "price": price.Float64})
+			}
 		}
+	}
 
-		schedules = append(schedules, gin.H{
-			"id":                 schedId,
-			"subject":            subject,
-			"current_chapter":    currentChapter,
-			"current_part":       currentPart,
-			"total_parts_done":   totalPartsDone,
-			"total_parts_needed": totalPartsNeeded,
-			"progress_percent":   subjectProgress,
-		})
+	var projectedEndDate interface{}
+	if wantsField(c, "projected_end_date") {
+		if projection, err := projectCompletion(subId); err == nil {
+			projectedEndDate = projection["projected_end_date"]
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"subscription": gin.H{
-			"id":                subId,
-			"student_name":      studentName,
-			"student_phone":     studentPhone,
-			"guardian_name":     guardianName,
-			"guardian_phone":    guardianPhone,
-			"class":             class,
-			"subjects":          strings.Split(subjects, ","),
-			"teacher_id":        teacherID,
-			"days_per_week":     daysPerWeek,
-			"schedule_days":     strings.Split(scheduleDays, ","),
-			"time":              schedTime,
-			"amount":            amount,
-			"billing_date":      billingDate,
-			"status":            status,
-			"total_classes":     totalClasses,
-			"completed_classes": completedClasses,
-			"progress_percent":  progressPercent,
-			"schedule":          schedules,
-		},
+		"subscription": shapeItem(c, gin.H{
+			"id":                 subId,
+			"student_name":       studentName,
+			"student_phone":      studentPhone,
+			"guardian_name":      guardianName,
+			"guardian_phone":     guardianPhone,
+			"class":              class,
+			"subjects":           subjects,
+			"teacher_id":         teacherID,
+			"days_per_week":      daysPerWeek,
+			"schedule_days":      scheduleDays,
+			"time":               schedTime,
+			"amount":             amount,
+			"currency":           orDefaultCurrency(currency),
+			"billing_date":       billingDate,
+			"status":             status,
+			"total_classes":      totalClasses,
+			"completed_classes":  completedClasses,
+			"progress_percent":   progressPercent,
+			"progress_stale":     progressStale,
+			"end_date":           endDateStr,
+			"cancel_reason":      cancelReasonNull.String,
+			"area":               areaNull.String,
+			"postcode":           postcodeNull.String,
+			"schedule":           schedules,
+			"price_breakdown":    priceBreakdown,
+			"projected_end_date": projectedEndDate,
+		}),
 	})
 }
 
 // ============================================
 // CREATE SUBSCRIPTION (Auto-creates schedule)
 // ============================================
+type subscriptionInput struct {
+	StudentName   string     `json:"student_name" binding:"max=255"`
+	StudentPhone  string     `json:"student_phone" binding:"max=20"`
+	GuardianName  string     `json:"guardian_name" binding:"max=255"`
+	GuardianPhone string     `json:"guardian_phone" binding:"max=20"`
+	Class         int        `json:"class" binding:"gte=0,lte=12"`
+	Subjects      stringList `json:"subjects"` // ["Math", "English"] or "Math,English"
+	TeacherID     string     `json:"teacher_id" binding:"max=50"`
+	DaysPerWeek   int        `json:"days_per_week" binding:"gte=0,lte=7"`
+	ScheduleDays  stringList `json:"schedule_days"` // ["Sat", "Mon"] or "Sat,Mon"
+	Time          string     `json:"time"`          // "4:30 PM" or "16:30"
+	ClassMinutes  int        `json:"class_minutes"` // default 60
+	Amount        float64    `json:"amount" binding:"gte=0"`
+	BillingDate   int        `json:"billing_date" binding:"gte=0,lte=31"`
+
+	// Optional per-subject teacher, e.g. {"English For Today": "1002"}
+	SubjectTeachers map[string]string `json:"subject_teachers"`
+
+	// Optional per-subject monthly price; amount becomes their total
+	SubjectPrices map[string]float64 `json:"subject_prices"`
+
+	// Optional per-subject class length in minutes, e.g. {"Math": 90}
+	SubjectMinutes map[string]int `json:"subject_minutes"`
+
+	// "paid" (default) or "trial"; trials default to TRIAL_CLASS_LIMIT classes within TRIAL_DAYS
+	SubscriptionType string `json:"subscription_type"`
+	TrialClasses     int    `json:"trial_classes" binding:"gte=0"`
+
+	// Optional plan template; prefills subjects, days_per_week, amount and total_classes
+	PlanID int `json:"plan_id"`
+
+	// Overrides the chapter count (and the plan's total); 0 computes it
+	TotalClasses int `json:"total_classes" binding:"gte=0"`
+
+	// Student location, matched against teacher zones
+	Area     string `json:"area" binding:"max=255"`
+	Postcode string `json:"postcode" binding:"max=10"`
+
+	// ISO code the subscription is billed in; empty means the default currency
+	Currency string `json:"currency"`
+}
+
 func createSubscription(c *gin.Context) {
-	var input struct {
-		StudentName   string  `json:"student_name"`
-		StudentPhone  string  `json:"student_phone"`
-		GuardianName  string  `json:"guardian_name"`
-		GuardianPhone string  `json:"guardian_phone"`
-		Class         int     `json:"class"`
-		Subjects      string  `json:"subjects"`
-		TeacherID     string  `json:"teacher_id"`
-		DaysPerWeek   int     `json:"days_per_week"`
-		ScheduleDays  string  `json:"schedule_days"`
-		Time          string  `json:"time"`
-		Amount        float64 `json:"amount"`
-		BillingDate   int     `json:"billing_date"`
-	}
+	var input subscriptionInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
 		return
 	}
 
+	resp, status := createSubscriptionFrom(input)
+	c.JSON(status, resp)
+}
+
+// createSubscriptionFrom validates and creates a subscription and its schedule
+// rows, returning the response body and HTTP status
+func createSubscriptionFrom(input subscriptionInput) (gin.H, int) {
+	// Fields sent explicitly win over the plan's
+	var plan *subscriptionPlan
+	if input.PlanID != 0 {
+		p, err := loadPlan(input.PlanID)
+		if err != nil || !p.Active {
+			return gin.H{"success": false, "error": "plan_id not found or retired"}, http.StatusBadRequest
+		}
+		if p.Class.Valid && input.Class != 0 && int64(input.Class) != p.Class.Int64 {
+			return gin.H{"success": false, "error": fmt.Sprintf("Plan is for class %d", p.Class.Int64)}, http.StatusBadRequest
+		}
+		if input.Class == 0 && p.Class.Valid {
+			input.Class = int(p.Class.Int64)
+		}
+		if len(input.Subjects) == 0 {
+			input.Subjects = p.Subjects
+		}
+		if input.DaysPerWeek == 0 && len(input.ScheduleDays) == 0 {
+			input.DaysPerWeek = p.DaysPerWeek
+		}
+		if input.Amount == 0 && len(input.SubjectPrices) == 0 {
+			input.Amount = p.Amount
+		}
+		plan = &p
+	}
+
 	// Auto-calculate days_per_week from schedule_days if not provided
-	if input.DaysPerWeek == 0 && input.ScheduleDays != "" {
-		dayCount := len(strings.Split(input.ScheduleDays, ","))
-		input.DaysPerWeek = dayCount
+	if input.DaysPerWeek == 0 && len(input.ScheduleDays) > 0 {
+		input.DaysPerWeek = len(input.ScheduleDays)
+	}
+
+	if fields := validateSubscriptionInput(&input); len(fields) > 0 {
+		return validationErrorResponse(fields), http.StatusBadRequest
+	}
+
+	gaps := capabilityGaps(input.Class, input.TeacherID, input.Subjects, input.SubjectTeachers)
+	if len(gaps) > 0 && capabilitiesEnforced() {
+		return validationErrorResponse(capabilityFieldErrors(gaps)), http.StatusBadRequest
 	}
 
 	// Calculate total classes: 1 chapter = 1 class
-	subjectList := strings.Split(input.Subjects, ",")
+	subjectList := input.Subjects
 	totalClasses := 0
 	var debugInfo []string
 	for _, subj := range subjectList {
@@ -389,21 +834,61 @@ func createSubscription(c *gin.Context) {
 	}
 	log.Printf("CreateSubscription debug: %v, total=%d", debugInfo, totalClasses)
 
+	var planID *int
+	if plan != nil {
+		planID = &plan.ID
+		if plan.TotalClasses.Valid {
+			totalClasses = int(plan.TotalClasses.Int64)
+		}
+	}
+	if input.TotalClasses > 0 {
+		totalClasses = input.TotalClasses
+	}
+
+	if len(input.SubjectPrices) > 0 {
+		total, err := subjectPriceTotal(input.Subjects, input.SubjectPrices)
+		if err != nil {
+			return gin.H{"success": false, "error": err.Error()}, http.StatusBadRequest
+		}
+		input.Amount = total
+	}
+
+	// Trial limits
+	if input.SubscriptionType == "" {
+		input.SubscriptionType = "paid"
+	}
+	var trialClassLimit *int
+	var trialEndsAt *string
+	if input.SubscriptionType == "trial" {
+		limit, days := trialDefaults()
+		if input.TrialClasses > 0 {
+			limit = input.TrialClasses
+		}
+		endsAt := time.Now().AddDate(0, 0, days).Format("2006-01-02")
+		trialClassLimit, trialEndsAt = &limit, &endsAt
+	} else if input.SubscriptionType != "paid" {
+		return gin.H{"success": false, "error": "subscription_type must be 'paid' or 'trial'"}, http.StatusBadRequest
+	}
+
 	// Insert subscription
 	var subId int
 	err := db.QueryRow(`
 		INSERT INTO mentor.subscriptions 
 		(student_name, student_phone, guardian_name, guardian_phone, class, subjects,
-		 teacher_id, days_per_week, schedule_days, time, amount, billing_date, total_classes)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 teacher_id, days_per_week, schedule_days, time, amount, billing_date, total_classes,
+		 subscription_type, trial_class_limit, trial_ends_at, subject_list, schedule_day_list, plan_id,
+		 area, postcode, class_minutes, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+		        NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, 0), NULLIF($23, ''))
 		RETURNING id
 	`, input.StudentName, input.StudentPhone, input.GuardianName, input.GuardianPhone,
-		input.Class, input.Subjects, input.TeacherID, input.DaysPerWeek, input.ScheduleDays,
-		input.Time, input.Amount, input.BillingDate, totalClasses).Scan(&subId)
+		input.Class, strings.Join(input.Subjects, ","), input.TeacherID, input.DaysPerWeek, strings.Join(input.ScheduleDays, ","),
+		input.Time, input.Amount, input.BillingDate, totalClasses,
+		input.SubscriptionType, trialClassLimit, trialEndsAt, pq.Array(input.Subjects), pq.Array(input.ScheduleDays), planID,
+		strings.TrimSpace(input.Area), strings.TrimSpace(input.Postcode), input.ClassMinutes, input.Currency).Scan(&subId)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
-		return
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
 	}
 
 	// Create schedule entries for each subject
@@ -424,20 +909,36 @@ func createSubscription(c *gin.Context) {
 			chapters = 15 // Default
 		}
 
+		var price *float64
+		if p, ok := input.SubjectPrices[subj]; ok {
+			price = &p
+		}
+
 		// Simple: 1 chapter = 1 class/part
 		db.Exec(`
-			INSERT INTO mentor.schedule (subscription_id, subject, total_parts_needed)
-			VALUES ($1, $2, $3)
-		`, subId, subj, chapters)
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"id":            subId,
-		"total_classes": totalClasses,
-		"debug_info":    debugInfo,
-		"message":       "Subscription created with schedule",
-	})
+			INSERT INTO mentor.schedule (subscription_id, subject, total_parts_needed, teacher_id, price, class_minutes)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, 0))
+		`, subId, subj, chapters, input.SubjectTeachers[subj], price, input.SubjectMinutes[subj])
+	}
+
+	resp := gin.H{
+		"success":             true,
+		"id":                  subId,
+		"subscription_type":   input.SubscriptionType,
+		"trial_class_limit":   trialClassLimit,
+		"trial_ends_at":       trialEndsAt,
+		"amount":              input.Amount,
+		"total_classes":       totalClasses,
+		"plan_id":             planID,
+		"capability_warnings": capabilityWarnings(gaps),
+		"debug_info":          debugInfo,
+		"message":             "Subscription created with schedule",
+	}
+	if !teacherCoversLocation(input.TeacherID, input.Area, input.Postcode) {
+		resp["zone_warning"] = "Teacher " + input.TeacherID + " does not cover this area"
+	}
+	requestClassSessionSync()
+	return resp, http.StatusOK
 }
 
 // ============================================
@@ -447,18 +948,21 @@ func updateSubscription(c *gin.Context) {
 	id := c.Param("id")
 
 	var input struct {
-		StudentName   string  `json:"student_name"`
-		StudentPhone  string  `json:"student_phone"`
-		GuardianName  string  `json:"guardian_name"`
-		GuardianPhone string  `json:"guardian_phone"`
-		Class         int     `json:"class"`
-		Subjects      string  `json:"subjects"`
-		TeacherID     string  `json:"teacher_id"`
-		ScheduleDays  string  `json:"schedule_days"`
-		DaysPerWeek   int     `json:"days_per_week"`
-		Time          string  `json:"time"`
-		Amount        float64 `json:"amount"`
-		Status        string  `json:"status"`
+		StudentName   string     `json:"student_name"`
+		StudentPhone  string     `json:"student_phone"`
+		GuardianName  string     `json:"guardian_name"`
+		GuardianPhone string     `json:"guardian_phone"`
+		Class         int        `json:"class"`
+		Subjects      stringList `json:"subjects"`
+		TeacherID     string     `json:"teacher_id"`
+		ScheduleDays  stringList `json:"schedule_days"`
+		DaysPerWeek   int        `json:"days_per_week"`
+		Time          string     `json:"time"`
+		Amount        float64    `json:"amount"`
+		Status        string     `json:"status"`
+		UpdatedBy     string     `json:"updated_by"`
+
+		SubjectPrices map[string]float64 `json:"subject_prices"` // Replaces amount with their total
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -466,35 +970,37 @@ func updateSubscription(c *gin.Context) {
 		return
 	}
 
+	days, invalidDays := canonicalScheduleDays(input.ScheduleDays)
+	if len(invalidDays) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+			"schedule_days": fmt.Sprintf("invalid day(s) %s; use Sat-Fri or codes 1-7", strings.Join(invalidDays, ", ")),
+		}))
+		return
+	}
+	input.ScheduleDays = days
+
 	// Auto-calculate days_per_week from schedule_days
 	daysPerWeek := input.DaysPerWeek
-	if daysPerWeek == 0 && input.ScheduleDays != "" {
-		daysPerWeek = len(strings.Split(input.ScheduleDays, ","))
+	if daysPerWeek == 0 && len(input.ScheduleDays) > 0 {
+		daysPerWeek = len(input.ScheduleDays)
+	}
+
+	gaps := subscriptionCapabilityGaps(id, input.Class, input.TeacherID, input.Subjects)
+	if len(gaps) > 0 && capabilitiesEnforced() {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(capabilityFieldErrors(gaps)))
+		return
+	}
+
+	var previousStatus string
+	if err := db.QueryRow("SELECT status FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL", id).Scan(&previousStatus); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
 	}
 
 	// Recalculate total_classes based on new subjects
 	totalClasses := 0
-	if input.Class > 0 && input.Subjects != "" {
-		subjectList := strings.Split(input.Subjects, ",")
-		for _, subj := range subjectList {
-			subj = strings.TrimSpace(subj)
-			var chapters int
-			err := db.QueryRow(
-				"SELECT total_chapters FROM mentor.chapters WHERE class = $1 AND subject = $2",
-				input.Class, subj,
-			).Scan(&chapters)
-			if err != nil {
-				// Try case-insensitive search
-				db.QueryRow(
-					"SELECT total_chapters FROM mentor.chapters WHERE class = $1 AND LOWER(subject) = LOWER($2)",
-					input.Class, subj,
-				).Scan(&chapters)
-			}
-			if chapters == 0 {
-				chapters = 15 // Default if not found
-			}
-			totalClasses += chapters
-		}
+	if input.Class > 0 && len(input.Subjects) > 0 {
+		totalClasses = countTotalClasses(input.Class, input.Subjects)
 	}
 
 	_, err := db.Exec(`
@@ -502,35 +1008,63 @@ func updateSubscription(c *gin.Context) {
 			student_name = $1, student_phone = $2, guardian_name = $3, guardian_phone = $4,
 			class = $5, subjects = $6, teacher_id = $7, schedule_days = $8, time = $9,
 			amount = $10, status = COALESCE(NULLIF($11, ''), 'active'), days_per_week = $12, 
-			total_classes = $13, updated_at = NOW()
-		WHERE id = $14
+			total_classes = $13, subject_list = $15, schedule_day_list = $16, updated_at = NOW()
+		WHERE id = $14 AND deleted_at IS NULL
 	`, input.StudentName, input.StudentPhone, input.GuardianName, input.GuardianPhone,
-		input.Class, input.Subjects, input.TeacherID, input.ScheduleDays, input.Time,
-		input.Amount, input.Status, daysPerWeek, totalClasses, id)
+		input.Class, strings.Join(input.Subjects, ","), input.TeacherID, strings.Join(input.ScheduleDays, ","), input.Time,
+		input.Amount, input.Status, daysPerWeek, totalClasses, id,
+		pq.Array(input.Subjects), pq.Array(input.ScheduleDays))
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Subscription updated", "total_classes": totalClasses})
+	status := input.Status
+	if status == "" {
+		status = "active"
+	}
+	if status != previousStatus {
+		logAudit("subscription", id, "status_changed", input.UpdatedBy, gin.H{"from": previousStatus, "to": status})
+	}
+	requestClassSessionSync()
+
+	amount := input.Amount
+	if len(input.SubjectPrices) > 0 {
+		amount, err = applySubjectPrices(id, input.SubjectPrices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Subscription updated", "total_classes": totalClasses, "amount": amount,
+		"capability_warnings": capabilityWarnings(gaps)})
 }
 
 // ============================================
-// DELETE SUBSCRIPTION
+// DELETE SUBSCRIPTION (Soft delete, restorable)
 // ============================================
 func deleteSubscription(c *gin.Context) {
 	id := c.Param("id")
+	deletedBy := c.Query("deleted_by")
 
-	// Delete related records first
-	db.Exec("DELETE FROM mentor.progress WHERE subscription_id = $1", id)
-	db.Exec("DELETE FROM mentor.schedule WHERE subscription_id = $1", id)
-
-	_, err := db.Exec("DELETE FROM mentor.subscriptions WHERE id = $1", id)
+	// Schedule and progress stay in place so the subscription can be restored
+	result, err := db.Exec(`
+		UPDATE mentor.subscriptions SET deleted_at = NOW(), deleted_by = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`, deletedBy, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	logAudit("subscription", id, "deleted", deletedBy, nil)
+	requestClassSessionSync()
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Subscription deleted"})
 }
@@ -546,35 +1080,158 @@ func markClassComplete(c *gin.Context) {
 		Subject    string `json:"subject"`
 		TeacherID  string `json:"teacher_id"`
 		Notes      string `json:"notes"`
+
+		// Skip the chapter test rule (recorded in the audit log)
+		OverrideTestGate bool   `json:"override_test_gate"`
+		OverrideReason   string `json:"override_reason"`
+
+		// The part needed another session; log it without advancing
+		RepeatPart bool `json:"repeat_part"`
+
+		// "present" (default), "late" or "absent"; absent classes aren't counted
+		StudentStatus       string `json:"student_status"`
+		StudentStatusReason string `json:"student_status_reason"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
+	if input.TeacherID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "teacher_id is required"})
+		return
+	}
+
+	if input.StudentStatus == "" {
+		input.StudentStatus = "present"
+	}
+	if !studentStatuses[input.StudentStatus] {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "student_status must be 'present', 'late' or 'absent'"})
+		return
+	}
 
 	// Get current chapter/part from schedule
-	var schedId, currentChapter, currentPart, totalPartsDone, totalPartsNeeded int
+	var schedId, currentChapter, currentPart, totalPartsDone, totalPartsNeeded, class int
+	var assignedTeacherID string
 	err := db.QueryRow(`
-		SELECT id, current_chapter, current_part, total_parts_done, total_parts_needed
-		FROM mentor.schedule WHERE subscription_id = $1 AND subject = $2
-	`, subId, input.Subject).Scan(&schedId, &currentChapter, &currentPart, &totalPartsDone, &totalPartsNeeded)
+		SELECT sc.id, sc.current_chapter, sc.current_part, sc.total_parts_done, sc.total_parts_needed,
+		       COALESCE(sc.teacher_id, s.teacher_id), s.class
+		FROM mentor.schedule sc
+		JOIN mentor.subscriptions s ON s.id = sc.subscription_id
+		WHERE sc.subscription_id = $1 AND sc.subject = $2 AND s.deleted_at IS NULL
+	`, subId, input.Subject).Scan(&schedId, &currentChapter, &currentPart, &totalPartsDone, &totalPartsNeeded,
+		&assignedTeacherID, &class)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Schedule not found"})
 		return
 	}
 
+	// Only the teacher assigned to this subject can complete its classes
+	if input.TeacherID != assignedTeacherID {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Subject is assigned to another teacher"})
+		return
+	}
+
+	if input.StudentStatus == "absent" {
+		recordStudentAbsence(c, subId, input.Subject, assignedTeacherID, input.StudentStatusReason)
+		return
+	}
+
+	// Trials stop at their class limit until converted to paid
+	var subType, subStatus string
+	var trialClassLimit sql.NullInt64
+	var completedSoFar int
+	db.QueryRow(`
+		SELECT subscription_type, status, trial_class_limit, completed_classes
+		FROM mentor.subscriptions WHERE id = $1
+	`, subId).Scan(&subType, &subStatus, &trialClassLimit, &completedSoFar)
+	if subType == "trial" && (subStatus == "expired" ||
+		(trialClassLimit.Valid && int64(completedSoFar) >= trialClassLimit.Int64)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success":       false,
+			"error":         "Trial has ended; convert to a paid subscription to continue",
+			"trial_expired": true,
+		})
+		return
+	}
+
+	// Chapter test gate: finishing the last part of a chapter requires a passing test
+	parts := partsPerChapter(class, input.Subject)
+	if currentPart+1 > parts && !input.RepeatPart {
+		var testRequired bool
+		var minScore int
+		db.QueryRow(`
+			SELECT COALESCE(chapter_test_required, FALSE), COALESCE(chapter_test_min_score, 0)
+			FROM mentor.subscriptions WHERE id = $1
+		`, subId).Scan(&testRequired, &minScore)
+
+		if testRequired {
+			score, found := bestChapterTestScore(subId, input.Subject, currentChapter)
+			passed := found && score >= float64(minScore)
+
+			if !passed && !input.OverrideTestGate {
+				c.JSON(http.StatusConflict, gin.H{
+					"success":       false,
+					"error":         "Chapter test required before advancing to the next chapter",
+					"test_required": true,
+					"chapter":       currentChapter,
+					"min_score":     minScore,
+					"best_score":    score,
+					"test_found":    found,
+				})
+				return
+			}
+
+			if !passed {
+				logAudit("subscription", subId, "chapter_test_override", input.TeacherID, gin.H{
+					"subject":    input.Subject,
+					"chapter":    currentChapter,
+					"min_score":  minScore,
+					"best_score": score,
+					"test_found": found,
+					"reason":     input.OverrideReason,
+				})
+			}
+		}
+	}
+
 	// Add progress record
-	db.Exec(`
+	var progressID sql.NullInt64
+	db.QueryRow(`
 		INSERT INTO mentor.progress (subscription_id, schedule_id, subject, chapter, part, teacher_id, notes)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, subId, schedId, input.Subject, currentChapter, currentPart, input.TeacherID, input.Notes)
+		RETURNING id
+	`, subId, schedId, input.Subject, currentChapter, currentPart, input.TeacherID, input.Notes).Scan(&progressID)
+
+	// Close today's class session for the subject
+	sessionID, err := completeClassSession(subId, input.Subject, assignedTeacherID, progressID)
+	if err == nil {
+		setSessionStudentStatus(sessionID, input.StudentStatus, input.StudentStatusReason)
+	}
+
+	if input.RepeatPart {
+		// The class was held, so it still counts towards completed_classes
+		db.Exec("UPDATE mentor.schedule SET repeat_sessions = repeat_sessions + 1 WHERE id = $1", schedId)
+		totalCompleted, progressPercent := updateSubscriptionProgress(subId)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":          true,
+			"new_chapter":      currentChapter,
+			"new_part":         currentPart,
+			"completed_total":  totalCompleted,
+			"progress_percent": progressPercent,
+			"class_session_id": sessionID,
+			"student_status":   input.StudentStatus,
+			"message":          "Session logged; part continues next class",
+		})
+		return
+	}
 
 	// Advance to next part/chapter
 	newPart := currentPart + 1
 	newChapter := currentChapter
-	if newPart > 3 {
+	if newPart > parts {
 		newPart = 1
 		newChapter++
 	}
@@ -587,34 +1244,42 @@ func markClassComplete(c *gin.Context) {
 		WHERE id = $4
 	`, newChapter, newPart, totalPartsDone, schedId)
 
-	// Update subscription totals
-	var totalCompleted int
-	db.QueryRow(`
-		SELECT COALESCE(SUM(total_parts_done), 0) FROM mentor.schedule WHERE subscription_id = $1
-	`, subId).Scan(&totalCompleted)
+	totalCompleted, progressPercent := updateSubscriptionProgress(subId)
 
-	var totalNeeded int
-	db.QueryRow(`SELECT total_classes FROM mentor.subscriptions WHERE id = $1`, subId).Scan(&totalNeeded)
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"new_chapter":      newChapter,
+		"new_part":         newPart,
+		"completed_total":  totalCompleted,
+		"progress_percent": progressPercent,
+		"class_session_id": sessionID,
+		"student_status":   input.StudentStatus,
+		"message":          "Class marked as complete",
+	})
+}
+
+// updateSubscriptionProgress saves a subscription's completed_classes (every
+// class held, repeated parts included) and progress_percent (parts covered
+// out of total_classes) from its schedule rows
+func updateSubscriptionProgress(subId interface{}) (int, float64) {
+	var completed, covered, totalNeeded int
+	db.QueryRow(`
+		SELECT COALESCE(SUM(sc.total_parts_done + sc.repeat_sessions), 0), COALESCE(SUM(sc.total_parts_done), 0),
+		       (SELECT total_classes FROM mentor.subscriptions WHERE id = $1)
+		FROM mentor.schedule sc WHERE sc.subscription_id = $1
+	`, subId).Scan(&completed, &covered, &totalNeeded)
 
 	progressPercent := float64(0)
 	if totalNeeded > 0 {
-		progressPercent = float64(totalCompleted) / float64(totalNeeded) * 100
+		progressPercent = float64(covered) / float64(totalNeeded) * 100
 	}
 
 	db.Exec(`
 		UPDATE mentor.subscriptions 
 		SET completed_classes = $1, progress_percent = $2, updated_at = NOW()
 		WHERE id = $3
-	`, totalCompleted, progressPercent, subId)
-
-	c.JSON(http.StatusOK, gin.H{
-		"success":          true,
-		"new_chapter":      newChapter,
-		"new_part":         newPart,
-		"completed_total":  totalCompleted,
-		"progress_percent": progressPercent,
-		"message":          "Class marked as complete",
-	})
+	`, completed, progressPercent, subId)
+	return completed, progressPercent
 }
 
 // ============================================
@@ -622,12 +1287,26 @@ func markClassComplete(c *gin.Context) {
 // ============================================
 func getProgress(c *gin.Context) {
 	subId := c.Param("id")
+	teacherId := c.Query("teacher_id") // Optional: only subjects assigned to this teacher
 
-	rows, err := db.Query(`
-		SELECT id, subject, chapter, part, teacher_id, notes, completed_at
-		FROM mentor.progress WHERE subscription_id = $1
-		ORDER BY completed_at DESC LIMIT 50
-	`, subId)
+	query := `
+		SELECT p.id, p.subject, p.chapter, p.part, p.teacher_id, p.notes, p.completed_at
+		FROM mentor.progress p
+		WHERE p.subscription_id = $1
+	`
+	args := []interface{}{subId}
+
+	if teacherId != "" {
+		query += ` AND EXISTS (
+			SELECT 1 FROM mentor.schedule sc
+			JOIN mentor.subscriptions s ON s.id = sc.subscription_id
+			WHERE sc.id = p.schedule_id AND COALESCE(sc.teacher_id, s.teacher_id) = $2
+		)`
+		args = append(args, teacherId)
+	}
+	query += " ORDER BY p.completed_at DESC LIMIT 50"
+
+	rows, err := db.Query(query, args...)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
@@ -658,7 +1337,7 @@ func getProgress(c *gin.Context) {
 			"part":         part,
 			"teacher_id":   teacherId,
 			"notes":        notes,
-			"completed_at": completedAt.Format("2006-01-02 15:04"),
+			"completed_at": isoTimestamp(completedAt),
 		})
 	}
 
@@ -670,7 +1349,25 @@ func getProgress(c *gin.Context) {
 // ============================================
 func getTeacherTodayV2(c *gin.Context) {
 	teacherId := c.Param("teacherId")
-	todayName := getDayName() // "Mon", "Tue", etc.
+	respondTeacherDay(c, teacherId, teacherToday(teacherId)) // in the teacher's time zone
+}
+
+// getTeacherScheduleOnDate - The today view for any past or future date
+func getTeacherScheduleOnDate(c *gin.Context) {
+	teacherId := c.Param("teacherId")
+	day, err := time.ParseInLocation("2006-01-02", c.Param("date"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date must be YYYY-MM-DD"})
+		return
+	}
+	respondTeacherDay(c, teacherId, day)
+}
+
+// respondTeacherDay writes a teacher's students and sessions for one day.
+// Days up to the sync job's horizon show the stored sessions; later days are
+// forecast from the schedules.
+func respondTeacherDay(c *gin.Context, teacherId string, today time.Time) {
+	todayName := today.Format("Mon")
 
 	// Map day names to codes: Sun=2, Mon=3, Tue=4, Wed=5, Thu=6, Fri=7, Sat=1
 	dayNameToCode := map[string]string{
@@ -679,15 +1376,54 @@ func getTeacherTodayV2(c *gin.Context) {
 	}
 	todayCode := dayNameToCode[todayName]
 
-	// Query for students where schedule_days contains either the day name OR day code
+	if holidayName, ok := holidayOn(today.Format("2006-01-02"), teacherId); ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success":           true,
+			"date":              today.Format("2006-01-02"),
+			"today":             todayName,
+			"today_code":        todayCode,
+			"sessions":          []gin.H{},
+			"is_holiday":        true,
+			"holiday_name":      holidayName,
+			"upcoming_holidays": upcomingHolidays(teacherId),
+		})
+		return
+	}
+
+	// Students with a class session that day
+	var todaySessions map[int][]gin.H
+	filter, args := teacherSessionOnSQL, []interface{}{teacherId, today.Format("2006-01-02")}
+	if !today.After(classSessionsGeneratedUntil()) {
+		todaySessions, _ = teacherSessionsBetween(teacherId, today, today)
+	} else {
+		forecast, err := forecastSessions(today, teacherId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		todaySessions = map[int][]gin.H{}
+		subIDs := []int64{}
+		for _, s := range forecast {
+			if s["status"] == "cancelled" {
+				continue
+			}
+			subId := s["subscription_id"].(int)
+			delete(s, "subscription_id")
+			if todaySessions[subId] == nil {
+				subIDs = append(subIDs, int64(subId))
+			}
+			todaySessions[subId] = append(todaySessions[subId], s)
+		}
+		filter, args = "s.id = ANY($1)", []interface{}{pq.Array(subIDs)}
+	}
+
 	rows, err := db.Query(`
-		SELECT s.id, s.student_name, s.class, s.subjects, s.schedule_days, s.time,
+		SELECT s.id, s.student_name, s.class, s.subject_list, s.schedule_day_list, s.time,
 		       s.completed_classes, s.total_classes, s.progress_percent
 		FROM mentor.subscriptions s
-		WHERE s.teacher_id = $1 AND s.status = 'active' 
-		  AND (s.schedule_days LIKE $2 OR s.schedule_days LIKE $3)
+		WHERE `+filter+` AND s.deleted_at IS NULL
 		ORDER BY s.time
-	`, teacherId, "%"+todayName+"%", "%"+todayCode+"%")
+	`, args...)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
@@ -698,16 +1434,20 @@ func getTeacherTodayV2(c *gin.Context) {
 	var sessions []gin.H
 	for rows.Next() {
 		var id, class, completedClasses, totalClasses int
-		var studentName, subjects, scheduleDays, schedTime string
+		var studentName, schedTime string
+		var subjects, scheduleDays []string
 		var progressPercent float64
 
-		rows.Scan(&id, &studentName, &class, &subjects, &scheduleDays, &schedTime,
+		rows.Scan(&id, &studentName, &class, pq.Array(&subjects), pq.Array(&scheduleDays), &schedTime,
 			&completedClasses, &totalClasses, &progressPercent)
 
-		// Get current subject progress
+		// Get current progress for the subjects this teacher teaches
 		schedRows, _ := db.Query(`
-			SELECT subject, current_chapter, current_part FROM mentor.schedule WHERE subscription_id = $1
-		`, id)
+			SELECT sc.subject, sc.current_chapter, sc.current_part
+			FROM mentor.schedule sc
+			JOIN mentor.subscriptions s ON s.id = sc.subscription_id
+			WHERE sc.subscription_id = $1 AND COALESCE(sc.teacher_id, s.teacher_id) = $2
+		`, id, teacherId)
 
 		var subjectProgress []gin.H
 		for schedRows.Next() {
@@ -718,29 +1458,61 @@ func getTeacherTodayV2(c *gin.Context) {
 				"subject":         subj,
 				"current_chapter": ch,
 				"current_part":    pt,
+				"content_url":     contentPartURL(c, class, subj, ch, pt),
 			})
 		}
 		schedRows.Close()
 
-		sessions = append(sessions, gin.H{
-			"subscription_id":   id,
-			"student_name":      studentName,
-			"class":             class,
-			"subjects":          strings.Split(subjects, ","),
-			"schedule_days":     strings.Split(scheduleDays, ","),
-			"time":              schedTime,
-			"completed_classes": completedClasses,
-			"total_classes":     totalClasses,
-			"progress_percent":  progressPercent,
-			"subject_progress":  subjectProgress,
-		})
+		// Subjects at different times of day are separate visits; each
+		// visit's sessions say which subjects this teacher teaches then
+		for _, visit := range splitSessionsByTime(todaySessions[id]) {
+			visitSubjects := sessionSubjects(visit)
+			visitProgress := []gin.H{}
+			for _, p := range subjectProgress {
+				for _, subj := range visitSubjects {
+					if p["subject"] == subj {
+						visitProgress = append(visitProgress, p)
+					}
+				}
+			}
+			visitTime := visit[0]["time"].(string)
+			if visitTime == "" {
+				visitTime = schedTime
+			}
+
+			sessions = append(sessions, gin.H{
+				"subscription_id":   id,
+				"student_name":      studentName,
+				"class":             class,
+				"subjects":          visitSubjects,
+				"schedule_days":     scheduleDays,
+				"time":              visitTime,
+				"completed_classes": completedClasses,
+				"total_classes":     totalClasses,
+				"progress_percent":  progressPercent,
+				"subject_progress":  visitProgress,
+				"sessions":          visit,
+			})
+		}
 	}
 
+	// Visits in time order across students
+	sort.SliceStable(sessions, func(i, j int) bool {
+		a, okA := clockMinutes(sessions[i]["time"].(string))
+		b, okB := clockMinutes(sessions[j]["time"].(string))
+		if okA != okB {
+			return okA
+		}
+		return a < b
+	})
+
 	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"today":      todayName,
-		"today_code": todayCode,
-		"sessions":   sessions,
+		"success":           true,
+		"date":              today.Format("2006-01-02"),
+		"today":             todayName,
+		"today_code":        todayCode,
+		"sessions":          shapeItems(c, sessions),
+		"upcoming_holidays": upcomingHolidays(teacherId),
 	})
 }
 
@@ -751,10 +1523,10 @@ func getSchedule(c *gin.Context) {
 	teacherId := c.Param("teacherId")
 
 	rows, err := db.Query(`
-		SELECT s.id, s.student_name, s.class, s.subjects, s.schedule_days, s.time,
+		SELECT s.id, s.student_name, s.class, s.subject_list, s.schedule_day_list, s.time,
 		       s.completed_classes, s.total_classes, s.progress_percent
 		FROM mentor.subscriptions s
-		WHERE s.teacher_id = $1 AND s.status = 'active'
+		WHERE s.teacher_id = $1 AND s.status = 'active' AND s.deleted_at IS NULL
 	`, teacherId)
 
 	if err != nil {
@@ -763,13 +1535,19 @@ func getSchedule(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	// Planned sessions for the coming week (kept generated by the sync job)
+	weekStart := teacherToday(teacherId)
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	weekSessions, _ := teacherSessionsBetween(teacherId, weekStart, weekEnd)
+
 	var schedules []gin.H
 	for rows.Next() {
 		var id, class, completedClasses, totalClasses int
-		var studentName, subjects, scheduleDays, schedTime string
+		var studentName, schedTime string
+		var subjects, scheduleDays []string
 		var progressPercent float64
 
-		rows.Scan(&id, &studentName, &class, &subjects, &scheduleDays, &schedTime,
+		rows.Scan(&id, &studentName, &class, pq.Array(&subjects), pq.Array(&scheduleDays), &schedTime,
 			&completedClasses, &totalClasses, &progressPercent)
 
 		schedules = append(schedules, gin.H{
@@ -779,62 +1557,63 @@ func getSchedule(c *gin.Context) {
 				"name":  studentName,
 				"class": class,
 			},
-			"subject":          strings.Split(subjects, ",")[0],
+			"subject":          firstOrEmpty(subjects),
 			"class":            class,
-			"days":             strings.Split(scheduleDays, ","),
+			"days":             scheduleDays,
 			"time":             schedTime,
 			"current_chapter":  1,
 			"current_part":     1,
 			"progress_percent": progressPercent,
+			"sessions":         orEmpty(weekSessions[id]),
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "schedules": schedules})
+	c.JSON(http.StatusOK, gin.H{"success": true, "schedules": schedules, "upcoming_holidays": upcomingHolidays(teacherId)})
 }
 
 func getTodaySchedule(c *gin.Context) {
 	teacherId := c.Param("teacherId")
-	todayName := getDayName()
-
-	// Map day names to codes: Sun=2, Mon=3, Tue=4, Wed=5, Thu=6, Fri=7, Sat=1
-	dayNameToCode := map[string]string{
-		"Sat": "1", "Sun": "2", "Mon": "3", "Tue": "4",
-		"Wed": "5", "Thu": "6", "Fri": "7",
-	}
-	todayCode := dayNameToCode[todayName]
+	today := teacherToday(teacherId) // in the teacher's time zone
+	todayName := today.Format("Mon")
 
-	// Check for holiday
-	var holidayName string
-	todayDate := time.Now().Format("2006-01-02")
-	err := db.QueryRow("SELECT name FROM mentor.holidays WHERE date = $1", todayDate).Scan(&holidayName)
-	if err == nil {
+	// Check for holiday (everyone's or this teacher's)
+	todayDate := today.Format("2006-01-02")
+	if holidayName, ok := holidayOn(todayDate, teacherId); ok {
 		c.JSON(http.StatusOK, gin.H{
-			"success":     true,
-			"schedules":   []gin.H{},
-			"isHoliday":   true,
-			"holidayName": holidayName,
+			"success":           true,
+			"schedules":         []gin.H{},
+			"isHoliday":         true,
+			"holidayName":       holidayName,
+			"upcoming_holidays": upcomingHolidays(teacherId),
 		})
 		return
 	}
 
-	// Query matching both day name (Mon) and day code (3)
-	rows, _ := db.Query(`
-		SELECT s.id, s.student_name, s.class, s.subjects, s.schedule_days, s.time,
+	// Students with a class session today
+	todaySessions, _ := teacherSessionsBetween(teacherId, today, today)
+
+	rows, err := db.Query(`
+		SELECT s.id, s.student_name, s.class, s.subject_list, s.schedule_day_list, s.time,
 		       s.total_classes, s.completed_classes, s.progress_percent,
 		       COALESCE(s.schedule_json::TEXT, '{}')
 		FROM mentor.subscriptions s
-		WHERE s.teacher_id = $1 AND s.status = 'active' 
-		  AND (s.schedule_days LIKE $2 OR s.schedule_days LIKE $3)
-	`, teacherId, "%"+todayName+"%", "%"+todayCode+"%")
+		WHERE `+teacherSessionOnSQL+` AND s.deleted_at IS NULL
+		ORDER BY s.time
+	`, teacherId, todayDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 	defer rows.Close()
 
 	var schedules []gin.H
 	for rows.Next() {
 		var id, class, totalClasses, completedClasses int
-		var studentName, subjects, scheduleDays, schedTime, scheduleJSON string
+		var studentName, schedTime, scheduleJSON string
+		var subjects, scheduleDays []string
 		var progressPercent float64
 
-		rows.Scan(&id, &studentName, &class, &subjects, &scheduleDays, &schedTime,
+		rows.Scan(&id, &studentName, &class, pq.Array(&subjects), pq.Array(&scheduleDays), &schedTime,
 			&totalClasses, &completedClasses, &progressPercent, &scheduleJSON)
 
 		// Find today's class from schedule_json
@@ -842,18 +1621,18 @@ func getTodaySchedule(c *gin.Context) {
 		var todaySubject string
 
 		// Parse schedule_json to find today's lesson
-		// For now, use first subject and get from schedule table
+		// For now, use the first subject this teacher teaches from the schedule table
 		db.QueryRow(`
-			SELECT current_chapter, current_part FROM mentor.schedule 
-			WHERE subscription_id = $1 LIMIT 1
-		`, id).Scan(&currentChapter, &currentPart)
-
-		// Use first subject if todaySubject not set
+			SELECT sc.subject, sc.current_chapter, sc.current_part
+			FROM mentor.schedule sc
+			JOIN mentor.subscriptions s ON s.id = sc.subscription_id
+			WHERE sc.subscription_id = $1 AND COALESCE(sc.teacher_id, s.teacher_id) = $2
+			ORDER BY sc.id LIMIT 1
+		`, id, teacherId).Scan(&todaySubject, &currentChapter, &currentPart)
+
+		// Use the first session's subject if todaySubject not set
 		if todaySubject == "" {
-			subjectList := strings.Split(subjects, ",")
-			if len(subjectList) > 0 {
-				todaySubject = strings.TrimSpace(subjectList[0])
-			}
+			todaySubject = firstOrEmpty(sessionSubjects(todaySessions[id]))
 		}
 
 		schedules = append(schedules, gin.H{
@@ -866,42 +1645,60 @@ func getTodaySchedule(c *gin.Context) {
 			"subscription_id":   id,
 			"student_name":      studentName,
 			"subject":           todaySubject,
-			"subjects":          strings.Split(subjects, ","),
+			"subjects":          subjects,
 			"class":             class,
-			"days":              strings.Split(scheduleDays, ","),
+			"days":              scheduleDays,
 			"time":              schedTime,
 			"current_chapter":   currentChapter,
 			"current_part":      currentPart,
+			"content_url":       nil,
 			"total_classes":     totalClasses,
 			"completed_classes": completedClasses,
 			"progress_percent":  progressPercent,
+			"sessions":          orEmpty(todaySessions[id]),
 			"schedule_json":     scheduleJSON,
 		})
+
+		// Deep link to the content for the part taught in today's class
+		if todaySubject != "" {
+			schedules[len(schedules)-1]["content_url"] = contentPartURL(c, class, todaySubject, currentChapter, currentPart)
+		}
+
+		// schedule_json is the largest field; only send it when it's wanted
+		if !wantsField(c, "schedule_json") {
+			delete(schedules[len(schedules)-1], "schedule_json")
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "schedules": schedules, "today": todayName})
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"schedules":         shapeItems(c, schedules),
+		"today":             todayName,
+		"upcoming_holidays": upcomingHolidays(teacherId),
+	})
 }
 
 func getStudents(c *gin.Context) {
 	teacherId := c.Param("teacherId")
 
 	rows, _ := db.Query(`
-		SELECT id, student_name, class, subjects, time FROM mentor.subscriptions
-		WHERE teacher_id = $1 AND status = 'active'
+		SELECT id, student_name, class, subject_list, time FROM mentor.subscriptions
+		WHERE teacher_id = $1 AND status = 'active' AND deleted_at IS NULL
 	`, teacherId)
 	defer rows.Close()
 
 	var students []gin.H
 	for rows.Next() {
 		var id, class int
-		var name, subjects, studentTime string
-		rows.Scan(&id, &name, &class, &subjects, &studentTime)
+		var name, studentTime string
+		var subjects []string
+		rows.Scan(&id, &name, &class, pq.Array(&subjects), &studentTime)
 
 		students = append(students, gin.H{
 			"id":       strconv.Itoa(id),
 			"name":     name,
 			"class":    class,
-			"subjects": strings.Split(subjects, ","),
+			"subjects": subjects,
 			"time":     studentTime,
 		})
 	}
@@ -925,21 +1722,91 @@ func getSubjects(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "subjects": subjects})
 }
 
-func getDayName() string {
-	days := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
-	return days[time.Now().Weekday()]
-}
+// teacherAssignedSQL matches subscriptions (aliased s) where teacher $1 teaches at
+// least one subject, either via a per-subject assignment or as the main teacher.
+const teacherAssignedSQL = `(EXISTS (
+		SELECT 1 FROM mentor.schedule sc
+		WHERE sc.subscription_id = s.id AND COALESCE(sc.teacher_id, s.teacher_id) = $1
+	) OR (s.teacher_id = $1 AND NOT EXISTS (
+		SELECT 1 FROM mentor.schedule sc WHERE sc.subscription_id = s.id
+	)))`
 
 // ============================================
 // TEACHER CRUD FUNCTIONS
 // ============================================
 
+// likeContains turns a search term into an ILIKE pattern matching it anywhere,
+// with %, _ and \ in the term matched literally
+func likeContains(q string) string {
+	q = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q)
+	return "%" + q + "%"
+}
+
 func getTeachers(c *gin.Context) {
-	rows, err := db.Query(`
-		SELECT id, name, phone, password 
-		FROM mentor.teachers 
-		ORDER BY id
-	`)
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	// ?active=true for assignment pickers, ?active=false for the archive
+	switch c.Query("active") {
+	case "true":
+		where += " AND COALESCE(active, 1) = 1"
+	case "false":
+		where += " AND COALESCE(active, 1) <> 1"
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		argCount++
+		where += fmt.Sprintf(" AND (name ILIKE $%d OR phone ILIKE $%d)", argCount, argCount)
+		args = append(args, likeContains(q))
+	}
+
+	// Teachers listed as able to teach the subject (and class, if given)
+	if subject := c.Query("subject"); subject != "" {
+		argCount++
+		capability := fmt.Sprintf("LOWER(tc.subject) = LOWER($%d)", argCount)
+		args = append(args, subject)
+		if class, err := strconv.Atoi(c.Query("class")); err == nil {
+			argCount++
+			capability += fmt.Sprintf(" AND tc.class = $%d", argCount)
+			args = append(args, class)
+		}
+		where += " AND EXISTS(SELECT 1 FROM mentor.teacher_capabilities tc WHERE tc.teacher_id = teachers.id AND " + capability + ")"
+	}
+
+	if zone := normalizeZone(c.Query("zone")); zone != "" {
+		argCount++
+		where += fmt.Sprintf(" AND EXISTS(SELECT 1 FROM mentor.teacher_zones tz WHERE tz.teacher_id = teachers.id AND tz.zone = $%d)", argCount)
+		args = append(args, zone)
+	}
+
+	query := `
+		SELECT id, name, phone, password, COALESCE(active, 1), ` + teacherProfileSelect + `
+		FROM mentor.teachers
+	` + where + " ORDER BY name, id"
+
+	// Pagination is opt-in so existing callers still get the full list
+	response := gin.H{"success": true}
+	if c.Query("page") != "" || c.Query("per_page") != "" {
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "25"))
+		if err != nil || perPage < 1 || perPage > 100 {
+			perPage = 25
+		}
+
+		var total int
+		db.QueryRow("SELECT COUNT(*) FROM mentor.teachers"+where, args...).Scan(&total)
+
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", perPage, (page-1)*perPage)
+		response["page"] = page
+		response["per_page"] = perPage
+		response["total"] = total
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -949,28 +1816,38 @@ func getTeachers(c *gin.Context) {
 	var teachers []gin.H
 	for rows.Next() {
 		var id, name, phone, password string
-		if err := rows.Scan(&id, &name, &phone, &password); err != nil {
+		var active int
+		var profile teacherProfile
+		if err := rows.Scan(append([]interface{}{&id, &name, &phone, &password, &active}, profile.scanTargets()...)...); err != nil {
 			continue
 		}
-		teachers = append(teachers, gin.H{
+		teachers = append(teachers, profile.addTo(gin.H{
 			"id":       id,
 			"name":     name,
 			"phone":    phone,
 			"password": password,
-		})
+			"active":   active == 1,
+		}))
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "teachers": teachers})
+	if teachers == nil {
+		teachers = []gin.H{}
+	}
+
+	response["teachers"] = teachers
+	c.JSON(http.StatusOK, response)
 }
 
 func getTeacher(c *gin.Context) {
 	id := c.Param("id")
 
 	var name, phone, password string
+	var active int
+	var profile teacherProfile
 	err := db.QueryRow(`
-		SELECT name, phone, password 
+		SELECT name, phone, password, COALESCE(active, 1), `+teacherProfileSelect+`
 		FROM mentor.teachers WHERE id = $1
-	`, id).Scan(&name, &phone, &password)
+	`, id).Scan(append([]interface{}{&name, &phone, &password, &active}, profile.scanTargets()...)...)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Teacher not found"})
@@ -979,12 +1856,13 @@ func getTeacher(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"teacher": gin.H{
+		"teacher": profile.addTo(gin.H{
 			"id":       id,
 			"name":     name,
 			"phone":    phone,
 			"password": password,
-		},
+			"active":   active == 1,
+		}),
 	})
 }
 
@@ -993,12 +1871,17 @@ func createTeacher(c *gin.Context) {
 		Name     string `json:"name"`
 		Phone    string `json:"phone"`
 		Password string `json:"password"`
+		teacherProfileInput
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := req.timezoneError(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// Auto-generate teacher ID starting from 1001
 	var maxID int
@@ -1010,6 +1893,9 @@ func createTeacher(c *gin.Context) {
 		VALUES ($1, $2, $3, $4)
 	`, newID, req.Name, req.Phone, req.Password)
 
+	if err == nil {
+		err = saveTeacherProfile(newID, req.teacherProfileInput)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1025,12 +1911,17 @@ func updateTeacher(c *gin.Context) {
 		Name     string `json:"name"`
 		Phone    string `json:"phone"`
 		Password string `json:"password"`
+		teacherProfileInput
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if msg := req.timezoneError(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	_, err := db.Exec(`
 		UPDATE mentor.teachers 
@@ -1038,6 +1929,9 @@ func updateTeacher(c *gin.Context) {
 		WHERE id = $4
 	`, req.Name, req.Phone, req.Password, id)
 
+	if err == nil {
+		err = saveTeacherProfile(id, req.teacherProfileInput)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1049,6 +1943,16 @@ func updateTeacher(c *gin.Context) {
 func deleteTeacher(c *gin.Context) {
 	id := c.Param("id")
 
+	// Deleting a teacher with students leaves their subscriptions unassigned
+	if subs := teacherActiveSubscriptions(id); len(subs) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success":              false,
+			"error":                "Teacher has active subscriptions; transfer them first or deactivate the teacher",
+			"active_subscriptions": subs,
+		})
+		return
+	}
+
 	_, err := db.Exec(`DELETE FROM mentor.teachers WHERE id = $1`, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1070,13 +1974,13 @@ func getChapters(c *gin.Context) {
 
 	if classNum != "" {
 		rows, err = db.Query(`
-			SELECT class, subject, total_chapters
+			SELECT class, subject, total_chapters, COALESCE(chapter_titles::text, '[]')
 			FROM mentor.chapters WHERE class = $1
 			ORDER BY subject
 		`, classNum)
 	} else {
 		rows, err = db.Query(`
-			SELECT class, subject, total_chapters
+			SELECT class, subject, total_chapters, COALESCE(chapter_titles::text, '[]')
 			FROM mentor.chapters
 			ORDER BY class, subject
 		`)
@@ -1091,12 +1995,15 @@ func getChapters(c *gin.Context) {
 	var chapters []gin.H
 	for rows.Next() {
 		var class, totalChapters int
-		var subject string
-		rows.Scan(&class, &subject, &totalChapters)
+		var subject, titlesJSON string
+		rows.Scan(&class, &subject, &totalChapters, &titlesJSON)
+		titles := []string{}
+		json.Unmarshal([]byte(titlesJSON), &titles)
 		chapters = append(chapters, gin.H{
 			"class":          class,
 			"subject":        subject,
 			"total_chapters": totalChapters,
+			"chapter_titles": titles,
 		})
 	}
 
@@ -1107,11 +2014,17 @@ func getContentList(c *gin.Context) {
 	classNum := c.Query("class")
 	subject := c.Query("subject")
 
-	query := `SELECT id, class, subject, chapter_number, chapter_title, created_at, updated_at
-			  FROM mentor.content WHERE 1=1`
+	query := `SELECT id, class, subject, chapter_number, chapter_title, status, created_at, updated_at
+			  FROM mentor.content WHERE 1=1` + contentVisibilitySQL(c)
 	args := []interface{}{}
 	argCount := 0
 
+	if status := c.Query("status"); status != "" && contentStatuses[status] {
+		argCount++
+		query += fmt.Sprintf(" AND status = $%d", argCount)
+		args = append(args, status)
+	}
+
 	if classNum != "" {
 		argCount++
 		query += fmt.Sprintf(" AND class = $%d", argCount)
@@ -1134,11 +2047,11 @@ func getContentList(c *gin.Context) {
 	var content []gin.H
 	for rows.Next() {
 		var id, class, chapterNum int
-		var subject, chapterTitle string
+		var subject, chapterTitle, status string
 		var createdAt, updatedAt time.Time
 		var chapterTitleNull sql.NullString
 
-		rows.Scan(&id, &class, &subject, &chapterNum, &chapterTitleNull, &createdAt, &updatedAt)
+		rows.Scan(&id, &class, &subject, &chapterNum, &chapterTitleNull, &status, &createdAt, &updatedAt)
 
 		if chapterTitleNull.Valid {
 			chapterTitle = chapterTitleNull.String
@@ -1150,8 +2063,9 @@ func getContentList(c *gin.Context) {
 			"subject":        subject,
 			"chapter_number": chapterNum,
 			"chapter_title":  chapterTitle,
-			"created_at":     createdAt.Format("2006-01-02 15:04"),
-			"updated_at":     updatedAt.Format("2006-01-02 15:04"),
+			"status":         status,
+			"created_at":     isoTimestamp(createdAt),
+			"updated_at":     isoTimestamp(updatedAt),
 		})
 	}
 
@@ -1165,14 +2079,16 @@ func getContent(c *gin.Context) {
 
 	var id, class, chapterNum int
 	var subjectName, chapterTitle string
-	var contentJSON string
-	var chapterTitleNull sql.NullString
+	var contentJSON, version, status string
+	var chapterTitleNull, reviewComment sql.NullString
 
 	err := db.QueryRow(`
-		SELECT id, class, subject, chapter_number, chapter_title, content_json::text
+		SELECT id, class, subject, chapter_number, chapter_title, content_json::text, `+contentVersionSQL+`,
+		       status, review_comment
 		FROM mentor.content
-		WHERE class = $1 AND subject = $2 AND chapter_number = $3
-	`, classNum, subject, chapter).Scan(&id, &class, &subjectName, &chapterNum, &chapterTitleNull, &contentJSON)
+		WHERE class = $1 AND subject = $2 AND chapter_number = $3`+contentVisibilitySQL(c),
+		classNum, subject, chapter).Scan(&id, &class, &subjectName, &chapterNum, &chapterTitleNull, &contentJSON, &version,
+		&status, &reviewComment)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -1193,6 +2109,7 @@ func getContent(c *gin.Context) {
 		// If parsing fails, return empty sections
 		parsedContent = map[string]interface{}{"sections": []interface{}{}}
 	}
+	resolveContentAssets(c, parsedContent)
 
 	// Merge parsed content with metadata
 	parsedContent["id"] = id
@@ -1200,8 +2117,16 @@ func getContent(c *gin.Context) {
 	parsedContent["subject"] = subjectName
 	parsedContent["chapter_number"] = chapterNum
 	parsedContent["chapter_title"] = chapterTitle
+	parsedContent["version"] = version
+	parsedContent["status"] = status
+	if videos, err := queryChapterVideos(c, class, subjectName, chapterNum, 0, false); err == nil {
+		parsedContent["videos"] = videos
+	}
+	if reviewComment.Valid {
+		parsedContent["review_comment"] = reviewComment.String
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	writeContentResponse(c, version, gin.H{
 		"success": true,
 		"content": parsedContent,
 	})
@@ -1228,23 +2153,48 @@ func upsertContent(c *gin.Context) {
 		return
 	}
 
-	// Upsert (insert or update on conflict)
-	_, err = db.Exec(`
+	// Sections may reference uploaded files by "asset_id"; reject unknown ones
+	missing, err := missingContentAssets(input.ContentJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown asset_id in content", "missing_assets": missing})
+		return
+	}
+
+	// Sections may be tagged with the class session ("part") they're taught in
+	parts := partsPerChapter(input.Class, input.Subject)
+	if invalid := invalidContentParts(input.ContentJSON, parts); len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            fmt.Sprintf("Section part must be a number from 1 to %d", parts),
+			"invalid_sections": invalid,
+		})
+		return
+	}
+
+	// Upsert (insert or update on conflict); new chapters start as drafts, and
+	// a non-admin edit sends the chapter back to draft so it's reviewed again
+	var status string
+	err = db.QueryRow(`
 		INSERT INTO mentor.content (class, subject, chapter_number, chapter_title, content_json)
 		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (class, subject, chapter_number) 
 		DO UPDATE SET 
 			chapter_title = EXCLUDED.chapter_title,
 			content_json = EXCLUDED.content_json,
+			status = CASE WHEN $6 THEN mentor.content.status ELSE 'draft' END,
 			updated_at = NOW()
-	`, input.Class, input.Subject, input.ChapterNumber, input.ChapterTitle, string(contentBytes))
+		RETURNING status
+	`, input.Class, input.Subject, input.ChapterNumber, input.ChapterTitle, string(contentBytes), isAdminRequest(c)).Scan(&status)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Content saved"})
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": status, "message": "Content saved"})
 }
 
 func deleteContent(c *gin.Context) {
@@ -1273,9 +2223,10 @@ func getTransactions(c *gin.Context) {
 	month := c.Query("month")
 
 	query := `
-		SELECT id, date, type, amount, description, category, subscription_id, created_at
+		SELECT id, date, type, amount, description, category, subscription_id, billing_group_id, created_at, approved_at,
+		       invoice_id, COALESCE(payment_method, ''), currency
 		FROM mentor.transactions
-		WHERE 1=1
+		WHERE voided_at IS NULL
 	`
 	args := []interface{}{}
 	argNum := 1
@@ -1298,13 +2249,15 @@ func getTransactions(c *gin.Context) {
 	var transactions []gin.H
 	for rows.Next() {
 		var id int
-		var date, txType, description, category string
+		var date, txType, description, category, paymentMethod string
 		var amount float64
-		var subscriptionId sql.NullInt64
+		var subscriptionId, billingGroupId, invoiceId sql.NullInt64
 		var createdAt time.Time
-		var categoryNull, descNull sql.NullString
+		var approvedAt sql.NullTime
+		var categoryNull, descNull, currency sql.NullString
 
-		rows.Scan(&id, &date, &txType, &amount, &descNull, &categoryNull, &subscriptionId, &createdAt)
+		rows.Scan(&id, &date, &txType, &amount, &descNull, &categoryNull, &subscriptionId, &billingGroupId, &createdAt, &approvedAt,
+			&invoiceId, &paymentMethod, &currency)
 
 		if descNull.Valid {
 			description = descNull.String
@@ -1318,13 +2271,26 @@ func getTransactions(c *gin.Context) {
 			"date":        date,
 			"type":        txType,
 			"amount":      amount,
+			"currency":    orDefaultCurrency(currency),
 			"description": description,
 			"category":    category,
-			"created_at":  createdAt.Format("2006-01-02 15:04"),
+			"created_at":  isoTimestamp(createdAt),
 		}
 		if subscriptionId.Valid {
 			tx["subscription_id"] = subscriptionId.Int64
 		}
+		if billingGroupId.Valid {
+			tx["billing_group_id"] = billingGroupId.Int64
+		}
+		if invoiceId.Valid {
+			tx["invoice_id"] = invoiceId.Int64
+		}
+		if paymentMethod != "" {
+			tx["payment_method"] = paymentMethod
+		}
+		if txType == "expense" {
+			tx["approved"] = approvedAt.Valid
+		}
 		transactions = append(transactions, tx)
 	}
 
@@ -1339,6 +2305,10 @@ func createTransaction(c *gin.Context) {
 		Description    string  `json:"description"`
 		Category       string  `json:"category"` // "student_fee", "teacher_salary", "rent", "materials", "other"
 		SubscriptionID *int    `json:"subscription_id"`
+		BillingGroupID *int    `json:"billing_group_id"` // One payment settling all linked subscriptions
+		InvoiceID      *int    `json:"invoice_id"`       // defaults to the subscription's oldest unpaid invoice
+		PaymentMethod  string  `json:"payment_method"`   // cash, bkash, nagad, rocket, bank, card, other
+		Currency       string  `json:"currency"`         // defaults to the subscription's, else CURRENCY
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -1350,20 +2320,78 @@ func createTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "date, type, and amount are required"})
 		return
 	}
+	if _, ok := paymentMethods[input.PaymentMethod]; input.PaymentMethod != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "payment_method must be cash, bkash, nagad, rocket, bank, card or other"})
+		return
+	}
+
+	if input.Currency == "" && input.SubscriptionID != nil {
+		var subCurrency sql.NullString
+		db.QueryRow("SELECT currency FROM mentor.subscriptions WHERE id = $1", *input.SubscriptionID).Scan(&subCurrency)
+		input.Currency = subCurrency.String
+	}
+	currency, ok := normalizeCurrency(input.Currency)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "currency must be a 3-letter ISO code"})
+		return
+	}
+
+	var settled []int
+	if input.BillingGroupID != nil {
+		members, err := billingGroupMembers(*input.BillingGroupID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		if len(members) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Billing group has no active subscriptions"})
+			return
+		}
+		settled = members
+	}
+
+	invoiceID := sql.NullInt64{}
+	if input.InvoiceID != nil {
+		var subId int
+		if err := db.QueryRow("SELECT subscription_id FROM mentor.invoices WHERE id = $1 AND status = 'issued'", *input.InvoiceID).Scan(&subId); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Issued invoice not found"})
+			return
+		}
+		if input.SubscriptionID == nil {
+			input.SubscriptionID = &subId
+		}
+		invoiceID = sql.NullInt64{Int64: int64(*input.InvoiceID), Valid: true}
+	} else if input.Type == "income" && input.SubscriptionID != nil {
+		invoiceID = openInvoiceFor(*input.SubscriptionID)
+	}
 
 	var id int
 	err := db.QueryRow(`
-		INSERT INTO mentor.transactions (date, type, amount, description, category, subscription_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO mentor.transactions (date, type, amount, description, category, subscription_id, billing_group_id, invoice_id,
+		                                 payment_method, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
 		RETURNING id
-	`, input.Date, input.Type, input.Amount, input.Description, input.Category, input.SubscriptionID).Scan(&id)
+	`, input.Date, input.Type, input.Amount, input.Description, input.Category, input.SubscriptionID, input.BillingGroupID,
+		invoiceID, input.PaymentMethod, currency).Scan(&id)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "message": "Transaction created"})
+	response := gin.H{"success": true, "id": id, "currency": currency, "message": "Transaction created"}
+	if invoiceID.Valid {
+		response["invoice_id"] = invoiceID.Int64
+		// An installment leaves the rest of the invoice open for the next payment
+		if invoices, err := queryInvoices("i.id = $1", invoiceID.Int64); err == nil && len(invoices) == 1 {
+			response["invoice_balance"] = invoices[0]["balance"]
+			response["invoice_payment_status"] = invoices[0]["payment_status"]
+		}
+	}
+	if settled != nil {
+		response["settled_subscription_ids"] = settled
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func deleteTransaction(c *gin.Context) {
@@ -1388,25 +2416,29 @@ func getMonthlyAnalytics(c *gin.Context) {
 		month = strconv.Itoa(int(now.Month()))
 	}
 
-	// Get total income
+	if rejectMissingExchangeRates(c, "EXTRACT(YEAR FROM t.date) = $1 AND EXTRACT(MONTH FROM t.date) = $2", year, month) {
+		return
+	}
+
+	// Get total income (amounts in the default currency, at stored exchange rates)
 	var totalIncome float64
 	db.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0) FROM mentor.transactions 
-		WHERE type = 'income' AND EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
+		SELECT COALESCE(SUM(`+inDefaultCurrencySQL("t")+`), 0) FROM mentor.transactions t
+		WHERE voided_at IS NULL AND type = 'income' AND EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
 	`, year, month).Scan(&totalIncome)
 
 	// Get total expenses
 	var totalExpenses float64
 	db.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0) FROM mentor.transactions 
-		WHERE type = 'expense' AND EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
+		SELECT COALESCE(SUM(`+inDefaultCurrencySQL("t")+`), 0) FROM mentor.transactions t
+		WHERE voided_at IS NULL AND type = 'expense' AND EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
 	`, year, month).Scan(&totalExpenses)
 
 	// Get breakdown by category
 	categoryRows, _ := db.Query(`
-		SELECT category, type, SUM(amount) as total
-		FROM mentor.transactions 
-		WHERE EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
+		SELECT category, type, SUM(`+inDefaultCurrencySQL("t")+`) as total
+		FROM mentor.transactions t
+		WHERE voided_at IS NULL AND EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
 		GROUP BY category, type
 		ORDER BY total DESC
 	`, year, month)
@@ -1432,9 +2464,9 @@ func getMonthlyAnalytics(c *gin.Context) {
 
 	// Get daily breakdown for calendar view
 	dailyRows, _ := db.Query(`
-		SELECT date, type, SUM(amount) as total
-		FROM mentor.transactions 
-		WHERE EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
+		SELECT date, type, SUM(`+inDefaultCurrencySQL("t")+`) as total
+		FROM mentor.transactions t
+		WHERE voided_at IS NULL AND EXTRACT(YEAR FROM date) = $1 AND EXTRACT(MONTH FROM date) = $2
 		GROUP BY date, type
 		ORDER BY date
 	`, year, month)
@@ -1459,18 +2491,31 @@ func getMonthlyAnalytics(c *gin.Context) {
 
 	// Get student count and active subscriptions
 	var activeStudents int
-	db.QueryRow("SELECT COUNT(*) FROM mentor.subscriptions WHERE status = 'active'").Scan(&activeStudents)
+	db.QueryRow("SELECT COUNT(*) FROM mentor.subscriptions WHERE status = 'active' AND deleted_at IS NULL").Scan(&activeStudents)
+
+	// Classes held online vs in person (counted from attendance check-ins)
+	var onlineClasses, offlineClasses int
+	db.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE mode = 'online'), COUNT(*) FILTER (WHERE mode = 'offline')
+		FROM mentor.attendance
+		WHERE action = 'start' AND EXTRACT(YEAR FROM recorded_at) = $1 AND EXTRACT(MONTH FROM recorded_at) = $2
+	`, year, month).Scan(&onlineClasses, &offlineClasses)
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"year":          year,
-		"month":         month,
-		"total_income":  totalIncome,
-		"total_expense": totalExpenses,
-		"profit":        totalIncome - totalExpenses,
-		"categories":    categoryBreakdown,
-		"daily":         dailyList,
+		"success":         true,
+		"year":            year,
+		"month":           month,
+		"total_income":    totalIncome,
+		"total_expense":   totalExpenses,
+		"profit":          totalIncome - totalExpenses,
+		"currency":        currencyCode(),
+		"categories":      categoryBreakdown,
+		"daily":           dailyList,
 		"active_students": activeStudents,
+		"class_modes": gin.H{
+			"online":  onlineClasses,
+			"offline": offlineClasses,
+		},
 	})
 }
 
@@ -1479,12 +2524,16 @@ func getMonthlyAnalytics(c *gin.Context) {
 // ============================================
 func recordAttendance(c *gin.Context) {
 	var input struct {
-		TeacherID      string  `json:"teacher_id"`
-		SubscriptionID int     `json:"subscription_id"`
-		Latitude       float64 `json:"latitude"`
-		Longitude      float64 `json:"longitude"`
-		Action         string  `json:"action"` // "start" or "end"
-		Notes          string  `json:"notes"`
+		TeacherID       string  `json:"teacher_id"`
+		SubscriptionID  int     `json:"subscription_id"`
+		Latitude        float64 `json:"latitude"`
+		Longitude       float64 `json:"longitude"`
+		Action          string  `json:"action"` // "start" or "end"
+		Notes           string  `json:"notes"`
+		Mode            string  `json:"mode"` // "offline" (default) or "online"
+		OnlineSessionID *int    `json:"online_session_id"`
+		ClassSessionID  *int    `json:"class_session_id"` // defaults to the teacher's session today
+		Photo           string  `json:"photo"`            // optional proof photo, base64 JPEG/PNG
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -1492,24 +2541,64 @@ func recordAttendance(c *gin.Context) {
 		return
 	}
 
+	if input.Mode == "" {
+		input.Mode = "offline"
+	}
+	if input.Mode != "offline" && input.Mode != "online" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "mode must be 'offline' or 'online'"})
+		return
+	}
+
+	var photoKey, thumbKey string
+	if input.Photo != "" {
+		photo, err := decodeAttendancePhoto(input.Photo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		if photoKey, thumbKey, err = photo.store(input.TeacherID); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Photo upload failed: " + err.Error()})
+			return
+		}
+	}
+
+	classSessionID := sql.NullInt64{}
+	if input.ClassSessionID != nil {
+		classSessionID = sql.NullInt64{Int64: int64(*input.ClassSessionID), Valid: true}
+	} else {
+		classSessionID = todayClassSession(input.SubscriptionID, input.TeacherID)
+	}
+
 	var id int
 	err := db.QueryRow(`
-		INSERT INTO mentor.attendance (teacher_id, subscription_id, latitude, longitude, action, notes)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO mentor.attendance (teacher_id, subscription_id, latitude, longitude, action, notes, mode, online_session_id, class_session_id,
+		                               photo_key, photo_thumb_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''))
 		RETURNING id
-	`, input.TeacherID, input.SubscriptionID, input.Latitude, input.Longitude, input.Action, input.Notes).Scan(&id)
+	`, input.TeacherID, input.SubscriptionID, input.Latitude, input.Longitude, input.Action, input.Notes,
+		input.Mode, input.OnlineSessionID, classSessionID, photoKey, thumbKey).Scan(&id)
 
 	if err != nil {
+		if photoKey != "" {
+			store := getObjectStore()
+			store.Delete(photoKey)
+			store.Delete(thumbKey)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success":   true,
 		"id":        id,
 		"message":   "Attendance recorded",
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-	})
+		"timestamp": isoTimestamp(time.Now()),
+	}
+	if classSessionID.Valid {
+		response["class_session_id"] = classSessionID.Int64
+	}
+	addPhotoLinks(response, getObjectStore(), photoKey, thumbKey)
+	c.JSON(http.StatusOK, response)
 }
 
 func getAttendanceHistory(c *gin.Context) {
@@ -1519,7 +2608,8 @@ func getAttendanceHistory(c *gin.Context) {
 
 	query := `
 		SELECT a.id, a.subscription_id, s.student_name, a.latitude, a.longitude, 
-		       a.action, a.notes, a.recorded_at
+		       a.action, a.notes, a.recorded_at, a.mode, COALESCE(a.photo_key, ''), COALESCE(a.photo_thumb_key, ''),
+		       a.is_corrected
 		FROM mentor.attendance a
 		LEFT JOIN mentor.subscriptions s ON a.subscription_id = s.id
 		WHERE a.teacher_id = $1
@@ -1544,15 +2634,18 @@ func getAttendanceHistory(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	store := getObjectStore()
 	var records []gin.H
 	for rows.Next() {
 		var id, subscriptionId int
-		var studentName, action, notes string
+		var studentName, action, notes, mode, photoKey, thumbKey string
 		var latitude, longitude float64
 		var recordedAt time.Time
 		var studentNameNull, notesNull sql.NullString
+		var corrected bool
 
-		rows.Scan(&id, &subscriptionId, &studentNameNull, &latitude, &longitude, &action, &notesNull, &recordedAt)
+		rows.Scan(&id, &subscriptionId, &studentNameNull, &latitude, &longitude, &action, &notesNull, &recordedAt, &mode,
+			&photoKey, &thumbKey, &corrected)
 
 		if studentNameNull.Valid {
 			studentName = studentNameNull.String
@@ -1569,8 +2662,11 @@ func getAttendanceHistory(c *gin.Context) {
 			"longitude":       longitude,
 			"action":          action,
 			"notes":           notes,
-			"recorded_at":     recordedAt.Format("2006-01-02 15:04"),
+			"mode":            mode,
+			"recorded_at":     isoTimestamp(recordedAt),
+			"is_corrected":    corrected,
 		})
+		addPhotoLinks(records[len(records)-1], store, photoKey, thumbKey)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "attendance": records})
@@ -1735,6 +2831,9 @@ func submitAnswerPaper(c *gin.Context) {
 		return
 	}
 
+	// Rotate/de-skew/shrink pages in the background before grading
+	enqueuePagePreprocessing(paperID, imageURLs)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":    true,
 		"paper_id":   paperID,
@@ -1813,7 +2912,7 @@ func getAnswerPapers(c *gin.Context) {
 			"actual_marks":      actualMarks.Int64,
 			"admin_suggestions": adminSuggestions.String,
 			"status":            status,
-			"created_at":        createdAt.Format("2006-01-02 15:04:05"),
+			"created_at":        isoTimestamp(createdAt),
 		})
 	}
 
@@ -1857,21 +2956,22 @@ func getAnswerPaper(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"paper": map[string]interface{}{
-			"id":                paperID,
-			"subscription_id":   subscriptionID,
-			"teacher_id":        teacherID,
-			"student_name":      studentName,
-			"class_name":        className,
-			"subject":           subject,
-			"chapter_number":    chapterNumber,
-			"chapter_name":      chapterName,
-			"image_urls":        urls,
-			"question_text":     questionText.String,
-			"total_marks":       totalMarks.Int64,
-			"actual_marks":      actualMarks.Int64,
-			"admin_suggestions": adminSuggestions.String,
-			"status":            status,
-			"created_at":        createdAt.Format("2006-01-02 15:04:05"),
+			"id":                 paperID,
+			"subscription_id":    subscriptionID,
+			"teacher_id":         teacherID,
+			"student_name":       studentName,
+			"class_name":         className,
+			"subject":            subject,
+			"chapter_number":     chapterNumber,
+			"chapter_name":       chapterName,
+			"image_urls":         urls,
+			"grading_image_urls": gradingImageURLs(paperID),
+			"question_text":      questionText.String,
+			"total_marks":        totalMarks.Int64,
+			"actual_marks":       actualMarks.Int64,
+			"admin_suggestions":  adminSuggestions.String,
+			"status":             status,
+			"created_at":         isoTimestamp(createdAt),
 		},
 	})
 }
@@ -1931,7 +3031,7 @@ func getGradingQueue(c *gin.Context) {
 			"actual_marks":      actualMarks.Int64,
 			"admin_suggestions": adminSuggestions.String,
 			"status":            status,
-			"created_at":        createdAt.Format("2006-01-02 15:04:05"),
+			"created_at":        isoTimestamp(createdAt),
 		})
 	}
 
@@ -2020,7 +3120,7 @@ func getTeacherGrades(c *gin.Context) {
 
 		gradedAtStr := ""
 		if gradedAt.Valid {
-			gradedAtStr = gradedAt.Time.Format("2006-01-02 15:04:05")
+			gradedAtStr = isoTimestamp(gradedAt.Time)
 		}
 
 		grades = append(grades, map[string]interface{}{
@@ -2033,7 +3133,7 @@ func getTeacherGrades(c *gin.Context) {
 			"actual_marks":      actualMarks.Int64,
 			"admin_suggestions": adminSuggestions.String,
 			"graded_at":         gradedAtStr,
-			"submitted_at":      createdAt.Format("2006-01-02 15:04:05"),
+			"submitted_at":      isoTimestamp(createdAt),
 		})
 	}
 
@@ -2043,4 +3143,3 @@ func getTeacherGrades(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "grades": grades})
 }
-
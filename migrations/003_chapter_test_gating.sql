@@ -0,0 +1,20 @@
+-- Migration: Chapter test gating + audit log
+-- Run this in your Supabase SQL editor
+
+-- Audit log for overrides and other admin-visible actions
+CREATE TABLE IF NOT EXISTS mentor.audit_log (
+    id SERIAL PRIMARY KEY,
+    entity_type TEXT NOT NULL,     -- 'subscription', 'teacher', 'transaction', ...
+    entity_id TEXT NOT NULL,
+    action TEXT NOT NULL,          -- e.g. 'chapter_test_override'
+    actor TEXT,                    -- teacher/admin id that performed the action
+    details JSONB DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON mentor.audit_log(entity_type, entity_id);
+
+-- Optional per-subscription rule: a graded chapter test is required
+-- before markClassComplete can move past a chapter boundary
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS chapter_test_required BOOLEAN DEFAULT FALSE;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS chapter_test_min_score INTEGER DEFAULT 40; -- percent
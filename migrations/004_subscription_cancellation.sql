@@ -0,0 +1,10 @@
+-- Migration: Subscription cancellation workflow
+-- Run this in your Supabase SQL editor
+
+-- Cancelled subscriptions keep their schedule/progress history;
+-- end_date marks the last billable day
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS cancelled_by VARCHAR(100);
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_status ON mentor.subscriptions(status);
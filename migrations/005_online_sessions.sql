@@ -0,0 +1,25 @@
+-- Migration: Online sessions (meeting links, recordings, join stats)
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.online_sessions (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    teacher_id TEXT NOT NULL,
+    session_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    subject TEXT,
+    meeting_link TEXT NOT NULL,
+    recording_url TEXT,
+    teacher_joined_at TIMESTAMP,
+    student_joined_at TIMESTAMP,
+    duration_minutes INT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_online_sessions_subscription ON mentor.online_sessions(subscription_id);
+CREATE INDEX IF NOT EXISTS idx_online_sessions_teacher_date ON mentor.online_sessions(teacher_id, session_date);
+
+-- Attendance records whether the class was held online or in person
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'offline'
+    CHECK (mode IN ('offline', 'online'));
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS online_session_id INT
+    REFERENCES mentor.online_sessions(id) ON DELETE SET NULL;
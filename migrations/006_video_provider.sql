@@ -0,0 +1,11 @@
+-- Migration: Video provider rooms for online sessions
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.online_sessions ADD COLUMN IF NOT EXISTS provider TEXT;  -- 'jitsi', '100ms'
+ALTER TABLE mentor.online_sessions ADD COLUMN IF NOT EXISTS room_id TEXT;
+ALTER TABLE mentor.online_sessions ADD COLUMN IF NOT EXISTS ended_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_online_sessions_room ON mentor.online_sessions(provider, room_id);
+
+-- Provisioned rooms get their link from the provider
+ALTER TABLE mentor.online_sessions ALTER COLUMN meeting_link DROP NOT NULL;
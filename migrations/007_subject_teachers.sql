@@ -0,0 +1,7 @@
+-- Migration: Per-subject teacher assignment
+-- Run this in your Supabase SQL editor
+
+-- NULL means the subject is taught by the subscription's teacher_id
+ALTER TABLE mentor.schedule ADD COLUMN IF NOT EXISTS teacher_id VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_teacher ON mentor.schedule(teacher_id);
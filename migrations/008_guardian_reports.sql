@@ -0,0 +1,20 @@
+-- Migration: Monthly guardian report summaries
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.guardian_reports (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    year INT NOT NULL,
+    month INT NOT NULL,
+    data JSONB DEFAULT '{}',        -- Structured facts the summary was generated from
+    summary TEXT NOT NULL,          -- AI draft, editable by the teacher
+    generated_by TEXT,              -- 'ai' or 'template'
+    status TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'sent')),
+    edited_by TEXT,
+    sent_via TEXT,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_guardian_reports_subscription ON mentor.guardian_reports(subscription_id, year, month);
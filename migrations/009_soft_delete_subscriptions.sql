@@ -0,0 +1,9 @@
+-- Migration: Soft delete for subscriptions
+-- Run this in your Supabase SQL editor
+
+-- Deleted subscriptions keep their schedule/progress until the purge job
+-- removes them after SOFT_DELETE_RETENTION_DAYS (default 30)
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_deleted ON mentor.subscriptions(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Migration: Per-subscription communication log
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.communications (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT REFERENCES mentor.subscriptions(id) ON DELETE SET NULL,
+    channel TEXT NOT NULL CHECK (channel IN ('sms', 'whatsapp', 'push', 'email', 'call', 'other')),
+    recipient TEXT NOT NULL,
+    purpose TEXT,                   -- 'monthly_report', 'fee_reminder', 'fee_change', ...
+    message TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'sent', 'delivered', 'read', 'failed')),
+    error TEXT,
+    provider_message_id TEXT,
+    sent_by TEXT,                   -- 'system' or the teacher/admin who sent it
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_communications_subscription ON mentor.communications(subscription_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_communications_provider_id ON mentor.communications(provider_message_id);
@@ -0,0 +1,12 @@
+-- Migration: Trial subscriptions
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS subscription_type TEXT NOT NULL DEFAULT 'paid'
+    CHECK (subscription_type IN ('paid', 'trial'));
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS trial_class_limit INT;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS trial_ends_at DATE;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS converted_at TIMESTAMP;
+
+-- Trials past their window are moved to status 'expired' by the background job
+CREATE INDEX IF NOT EXISTS idx_subscriptions_trial ON mentor.subscriptions(trial_ends_at)
+    WHERE subscription_type = 'trial';
@@ -0,0 +1,16 @@
+-- Migration: Billing groups (siblings billed together)
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.billing_groups (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    guardian_name TEXT,
+    guardian_phone TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS billing_group_id INT REFERENCES mentor.billing_groups(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_billing_group ON mentor.subscriptions(billing_group_id);
+
+-- A fee transaction against a group settles every linked subscription for that cycle
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS billing_group_id INT REFERENCES mentor.billing_groups(id) ON DELETE SET NULL;
@@ -0,0 +1,27 @@
+-- Migration: Holiday-aware class counts per billing cycle
+-- Run this in your Supabase SQL editor
+
+-- Days a teacher can't take classes (leave, exams, travel)
+CREATE TABLE IF NOT EXISTS mentor.teacher_blackouts (
+    id SERIAL PRIMARY KEY,
+    teacher_id VARCHAR(50) NOT NULL,
+    date DATE NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (teacher_id, date)
+);
+
+-- Expected vs delivered classes for each subscription's billing cycle
+CREATE TABLE IF NOT EXISTS mentor.billing_cycles (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    cycle_start DATE NOT NULL,
+    cycle_end DATE NOT NULL,              -- exclusive
+    scheduled_days INT NOT NULL DEFAULT 0,
+    holiday_days INT NOT NULL DEFAULT 0,
+    blackout_days INT NOT NULL DEFAULT 0,
+    expected_classes INT NOT NULL DEFAULT 0,
+    delivered_classes INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, cycle_start)
+);
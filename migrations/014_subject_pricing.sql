@@ -0,0 +1,5 @@
+-- Migration: Per-subject pricing
+-- Run this in your Supabase SQL editor
+
+-- Optional monthly price per subject; when set, subscriptions.amount is their sum
+ALTER TABLE mentor.schedule ADD COLUMN IF NOT EXISTS price NUMERIC(10, 2);
@@ -0,0 +1,27 @@
+-- Migration: Student self-service (PIN login, homework)
+-- Run this in your Supabase SQL editor
+
+-- PIN is set with the guardian's approval; stored as "salt$sha256"
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_hash TEXT;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_approved_at TIMESTAMP;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_failures INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS mentor.student_sessions (
+    token TEXT PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mentor.homework (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    subject TEXT,
+    chapter INT,
+    description TEXT NOT NULL,
+    due_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    assigned_by VARCHAR(50),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_homework_subscription ON mentor.homework(subscription_id, due_date);
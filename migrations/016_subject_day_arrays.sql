@@ -0,0 +1,36 @@
+-- Migration: Structured subjects / schedule days
+-- Run this in your Supabase SQL editor
+
+-- Arrays replace the comma-joined text columns (subject names can contain commas).
+-- The text columns are still written for the schedule_json trigger and old clients.
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS subject_list TEXT[];
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS schedule_day_list TEXT[];
+
+UPDATE mentor.subscriptions
+SET subject_list = ARRAY(SELECT trim(x) FROM unnest(string_to_array(subjects, ',')) x WHERE trim(x) <> '')
+WHERE subject_list IS NULL;
+
+UPDATE mentor.subscriptions
+SET schedule_day_list = ARRAY(SELECT trim(x) FROM unnest(string_to_array(schedule_days, ',')) x WHERE trim(x) <> '')
+WHERE schedule_day_list IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_day_list ON mentor.subscriptions USING GIN (schedule_day_list);
+
+-- Rows written outside the API (SQL editor, imports) get their arrays from the text columns
+CREATE OR REPLACE FUNCTION mentor.fn_subscription_arrays()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.subject_list IS NULL THEN
+        NEW.subject_list := ARRAY(SELECT trim(x) FROM unnest(string_to_array(NEW.subjects, ',')) x WHERE trim(x) <> '');
+    END IF;
+    IF NEW.schedule_day_list IS NULL THEN
+        NEW.schedule_day_list := ARRAY(SELECT trim(x) FROM unnest(string_to_array(NEW.schedule_days, ',')) x WHERE trim(x) <> '');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_subscription_arrays ON mentor.subscriptions;
+CREATE TRIGGER trg_subscription_arrays
+    BEFORE INSERT OR UPDATE ON mentor.subscriptions
+    FOR EACH ROW EXECUTE FUNCTION mentor.fn_subscription_arrays();
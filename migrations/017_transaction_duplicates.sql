@@ -0,0 +1,17 @@
+-- Migration: Duplicate transaction detection
+-- Run this in your Supabase SQL editor
+
+-- Voided transactions are kept for history but excluded from lists and analytics
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP;
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS void_reason TEXT;
+
+CREATE TABLE IF NOT EXISTS mentor.transaction_duplicates (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES mentor.transactions(id) ON DELETE CASCADE,  -- the later entry
+    duplicate_of INT NOT NULL REFERENCES mentor.transactions(id) ON DELETE CASCADE,    -- the original
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'merged', 'voided', 'dismissed')),
+    resolved_by TEXT,
+    resolved_at TIMESTAMP,
+    detected_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (transaction_id, duplicate_of)
+);
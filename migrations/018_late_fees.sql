@@ -0,0 +1,28 @@
+-- Migration: Late fees on overdue cycle fees
+-- Run this in your Supabase SQL editor
+
+-- Single-row policy (id = 1)
+CREATE TABLE IF NOT EXISTS mentor.late_fee_policy (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    mode TEXT NOT NULL DEFAULT 'flat' CHECK (mode IN ('flat', 'percent')),
+    value NUMERIC(10, 2) NOT NULL DEFAULT 0,   -- amount, or percent of the monthly fee
+    grace_days INT NOT NULL DEFAULT 7,
+    updated_by TEXT,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+INSERT INTO mentor.late_fee_policy (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+-- At most one late fee per subscription per billing cycle
+CREATE TABLE IF NOT EXISTS mentor.late_fees (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    cycle_start DATE NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'applied' CHECK (status IN ('applied', 'waived')),
+    waived_by TEXT,
+    waive_reason TEXT,
+    waived_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, cycle_start)
+);
@@ -0,0 +1,13 @@
+-- Migration: Subscription notes
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.subscription_notes (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    author TEXT,                   -- teacher/admin id
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_notes_sub ON mentor.subscription_notes(subscription_id, created_at);
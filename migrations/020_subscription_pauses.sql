@@ -0,0 +1,15 @@
+-- Migration: Subscription pauses (exam breaks, travel)
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.subscription_pauses (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,        -- inclusive
+    reason TEXT,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_pauses_sub ON mentor.subscription_pauses(subscription_id);
@@ -0,0 +1,16 @@
+-- Migration: Planned sessions per chapter (syllabus calibration)
+-- Run this in your Supabase SQL editor
+
+-- Per-chapter planned session counts; chapters without a row fall back to
+-- mentor.chapters.parts_per_chapter
+CREATE TABLE IF NOT EXISTS mentor.chapter_estimates (
+    class INT NOT NULL,
+    subject TEXT NOT NULL,
+    chapter INT NOT NULL,
+    planned_sessions INT NOT NULL CHECK (planned_sessions > 0),
+    updated_by TEXT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (class, subject, chapter)
+);
+
+CREATE INDEX IF NOT EXISTS idx_progress_sub_subject_chapter ON mentor.progress(subscription_id, subject, chapter);
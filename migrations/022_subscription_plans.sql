@@ -0,0 +1,17 @@
+-- Migration: Subscription plan templates
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.plans (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    class INT,                      -- NULL = any class
+    subject_list TEXT[] NOT NULL DEFAULT '{}',
+    days_per_week INT NOT NULL DEFAULT 0,
+    amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    total_classes INT,              -- NULL = count chapters of the subjects
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS plan_id INT REFERENCES mentor.plans(id);
@@ -0,0 +1,40 @@
+-- Migration: Emergency day cancellations, makeup credits, preferred channels
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS preferred_channel TEXT DEFAULT 'whatsapp';
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS preferred_channel TEXT DEFAULT 'sms';
+
+CREATE TABLE IF NOT EXISTS mentor.broadcasts (
+    id SERIAL PRIMARY KEY,
+    date DATE NOT NULL,
+    teacher_id TEXT,                -- NULL = every teacher
+    reason TEXT NOT NULL,
+    message TEXT NOT NULL,
+    report JSONB DEFAULT '{}',
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mentor.class_cancellations (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    reason TEXT,
+    broadcast_id INT REFERENCES mentor.broadcasts(id) ON DELETE SET NULL,
+    cancelled_by TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS mentor.makeup_credits (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    cancelled_date DATE NOT NULL,
+    reason TEXT,
+    broadcast_id INT REFERENCES mentor.broadcasts(id) ON DELETE SET NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, cancelled_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_class_cancellations_date ON mentor.class_cancellations(date);
@@ -0,0 +1,23 @@
+-- Migration: Student waitlist (leads waiting for a teacher)
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.waitlist (
+    id SERIAL PRIMARY KEY,
+    student_name TEXT NOT NULL,
+    student_phone TEXT,
+    guardian_name TEXT,
+    guardian_phone TEXT,
+    class INT NOT NULL,
+    subject_list TEXT[] NOT NULL DEFAULT '{}',
+    schedule_day_list TEXT[] NOT NULL DEFAULT '{}',
+    preferred_time TEXT,
+    area TEXT,
+    notes TEXT,
+    priority INT NOT NULL DEFAULT 0,   -- higher first
+    status TEXT NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'promoted', 'dropped')),
+    subscription_id INT REFERENCES mentor.subscriptions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_status ON mentor.waitlist(status, priority DESC, created_at);
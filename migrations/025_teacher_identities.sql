@@ -0,0 +1,13 @@
+-- Migration: External identity providers (Google Sign-In / OIDC)
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_identities (
+    provider TEXT NOT NULL,            -- 'google', or OIDC_PROVIDER_NAME
+    subject TEXT NOT NULL,             -- provider's stable user id ("sub")
+    teacher_id VARCHAR(50) NOT NULL REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    email TEXT,
+    linked_at TIMESTAMP DEFAULT NOW(),
+    last_login_at TIMESTAMP,
+    PRIMARY KEY (provider, subject),
+    UNIQUE (provider, teacher_id)
+);
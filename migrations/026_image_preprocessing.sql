@@ -0,0 +1,24 @@
+-- Migration: Answer paper page preprocessing queue
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.image_jobs (
+    id SERIAL PRIMARY KEY,
+    answer_paper_id INT NOT NULL REFERENCES mentor.answer_papers(id) ON DELETE CASCADE,
+    page INT NOT NULL,                 -- 0-based position in image_urls
+    source TEXT NOT NULL,              -- image URL, or base64 when ImgBB upload failed
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'processing', 'done', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT,
+    result_url TEXT,
+    bytes_before INT,
+    bytes_after INT,
+    details JSONB DEFAULT '{}',        -- rotation, skew angle, contrast range, size
+    created_at TIMESTAMP DEFAULT NOW(),
+    processed_at TIMESTAMP,
+    UNIQUE (answer_paper_id, page)
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_jobs_status ON mentor.image_jobs(status, created_at);
+
+-- Preprocessed pages in page order, filled once every page is done
+ALTER TABLE mentor.answer_papers ADD COLUMN IF NOT EXISTS processed_image_urls TEXT;
@@ -0,0 +1,9 @@
+-- Migration: Extended teacher profile
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS photo_url TEXT;
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS address TEXT;
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS qualifications TEXT;       -- e.g. "BSc Physics, University of Dhaka"
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS experience_years INT;
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS preferred_subjects TEXT[] DEFAULT '{}';
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS bio TEXT;
@@ -0,0 +1,22 @@
+-- Migration: Weekly owner digest + expense approval
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP;
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS approved_by VARCHAR(100);
+
+-- Expenses recorded before approval existed are treated as approved
+UPDATE mentor.transactions SET approved_at = created_at
+WHERE type = 'expense' AND approved_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS mentor.owner_digests (
+    id SERIAL PRIMARY KEY,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    content JSONB NOT NULL,
+    sent_via VARCHAR(20),
+    sent_to VARCHAR(255),
+    error TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_owner_digests_created ON mentor.owner_digests(created_at);
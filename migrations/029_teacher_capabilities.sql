@@ -0,0 +1,13 @@
+-- Migration: Teacher capability matrix (which classes/subjects a teacher can teach)
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_capabilities (
+    teacher_id VARCHAR(50) NOT NULL REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    class INT NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (teacher_id, class, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_teacher_capabilities_class_subject
+    ON mentor.teacher_capabilities(class, LOWER(subject));
@@ -0,0 +1,13 @@
+-- Migration: Teacher pay rates + payroll salary transactions
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS pay_type VARCHAR(20) DEFAULT 'per_class'; -- per_class or per_subscription
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS pay_rate NUMERIC(10,2) DEFAULT 0;
+
+-- Salary transactions created by payroll point back at the teacher and month
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS teacher_id VARCHAR(50);
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS payroll_month DATE; -- first day of the month paid for
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_payroll
+    ON mentor.transactions(teacher_id, payroll_month)
+    WHERE payroll_month IS NOT NULL AND voided_at IS NULL;
@@ -0,0 +1,24 @@
+-- Migration: Guardian ratings and feedback for teachers
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_ratings (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    teacher_id VARCHAR(50) NOT NULL,
+    progress_id INT REFERENCES mentor.progress(id) ON DELETE SET NULL, -- set when rating one class
+    rating_month DATE,                                                  -- set for a monthly rating
+    rating INT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    submitted_by VARCHAR(20) DEFAULT 'guardian',                        -- guardian or student
+    status VARCHAR(20) DEFAULT 'published',                             -- published or hidden
+    moderated_by VARCHAR(100),
+    moderation_note TEXT,
+    moderated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teacher_ratings_class
+    ON mentor.teacher_ratings(progress_id) WHERE progress_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teacher_ratings_month
+    ON mentor.teacher_ratings(subscription_id, teacher_id, rating_month) WHERE rating_month IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_teacher_ratings_teacher ON mentor.teacher_ratings(teacher_id, status);
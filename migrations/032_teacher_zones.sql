@@ -0,0 +1,15 @@
+-- Migration: Teacher service areas + student location
+-- Run this in your Supabase SQL editor
+
+-- One row per neighborhood name or postcode a teacher covers (stored lowercase)
+CREATE TABLE IF NOT EXISTS mentor.teacher_zones (
+    teacher_id VARCHAR(50) NOT NULL REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    zone VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (teacher_id, zone)
+);
+
+CREATE INDEX IF NOT EXISTS idx_teacher_zones_zone ON mentor.teacher_zones(zone);
+
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS area VARCHAR(255);
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS postcode VARCHAR(10);
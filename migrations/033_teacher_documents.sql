@@ -0,0 +1,20 @@
+-- Migration: Teacher documents (ID proof, certificates) + verification
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_documents (
+    id SERIAL PRIMARY KEY,
+    teacher_id VARCHAR(50) NOT NULL REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    doc_type VARCHAR(20) NOT NULL,            -- id_proof, certificate, other
+    title VARCHAR(255),
+    object_key TEXT NOT NULL,                 -- key in the private S3 bucket
+    content_type VARCHAR(100),
+    size_bytes INT,
+    status VARCHAR(20) DEFAULT 'pending',     -- pending, approved, rejected
+    review_note TEXT,
+    reviewed_by VARCHAR(100),
+    reviewed_at TIMESTAMP,
+    uploaded_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_teacher_documents_teacher ON mentor.teacher_documents(teacher_id);
+CREATE INDEX IF NOT EXISTS idx_teacher_documents_status ON mentor.teacher_documents(status);
@@ -0,0 +1,16 @@
+-- Migration: Teacher sessions + self-service availability
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_sessions (
+    token TEXT PRIMARY KEY,
+    teacher_id VARCHAR(50) NOT NULL REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_teacher_sessions_teacher ON mentor.teacher_sessions(teacher_id);
+
+-- Weekly availability the teacher maintains from the app
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS available_days TEXT[] DEFAULT '{}';
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS available_from VARCHAR(10);  -- "3:00 PM"
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS available_to VARCHAR(10);    -- "9:00 PM"
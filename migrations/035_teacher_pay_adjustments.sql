@@ -0,0 +1,16 @@
+-- Migration: Payroll deductions and salary advances
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_pay_adjustments (
+    id SERIAL PRIMARY KEY,
+    teacher_id VARCHAR(50) NOT NULL REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    payroll_month DATE NOT NULL,           -- first day of the month it applies to
+    kind VARCHAR(20) NOT NULL,             -- deduction or advance
+    amount NUMERIC(10,2) NOT NULL,
+    reason TEXT,
+    transaction_id INTEGER,                -- expense transaction for advances paid out
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_teacher_pay_adjustments_month ON mentor.teacher_pay_adjustments(teacher_id, payroll_month);
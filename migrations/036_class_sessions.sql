@@ -0,0 +1,23 @@
+-- Migration: Planned class sessions generated from subscription schedules
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.class_sessions (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    session_date DATE NOT NULL,
+    session_time VARCHAR(20),                 -- "4:00 PM", from the subscription
+    subject VARCHAR(100) NOT NULL,
+    teacher_id VARCHAR(50),                   -- teacher assigned to the subject
+    status VARCHAR(20) DEFAULT 'scheduled',   -- scheduled, completed, cancelled
+    progress_id INT,                          -- progress row logged when completed
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, session_date, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_class_sessions_teacher_date ON mentor.class_sessions(teacher_id, session_date);
+CREATE INDEX IF NOT EXISTS idx_class_sessions_date ON mentor.class_sessions(session_date);
+
+-- Check-ins point at the session they were for
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS class_session_id INT REFERENCES mentor.class_sessions(id) ON DELETE SET NULL;
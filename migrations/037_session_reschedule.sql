@@ -0,0 +1,14 @@
+-- Migration: Reschedule a single class session
+-- Run this in your Supabase SQL editor
+
+-- The moved-away session gets status 'rescheduled' and points at its replacement
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS rescheduled_from INT REFERENCES mentor.class_sessions(id) ON DELETE SET NULL;
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS rescheduled_to INT REFERENCES mentor.class_sessions(id) ON DELETE SET NULL;
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS reschedule_reason TEXT;
+
+-- A moved session can land on a day that already has the same subject,
+-- so only sessions generated from the schedule stay unique per day
+ALTER TABLE mentor.class_sessions DROP CONSTRAINT IF EXISTS class_sessions_subscription_id_session_date_subject_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_class_sessions_planned
+    ON mentor.class_sessions(subscription_id, session_date, subject)
+    WHERE rescheduled_from IS NULL;
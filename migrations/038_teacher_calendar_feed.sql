@@ -0,0 +1,7 @@
+-- Migration: Secret token for each teacher's calendar feed
+-- Run this in your Supabase SQL editor
+
+-- Calendar apps can't send headers, so the feed URL carries this token
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS calendar_token TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teachers_calendar_token ON mentor.teachers(calendar_token) WHERE calendar_token IS NOT NULL;
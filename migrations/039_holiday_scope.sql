@@ -0,0 +1,11 @@
+-- Migration: Manageable holidays with optional per-teacher scope
+-- Run this in your Supabase SQL editor
+
+-- NULL teacher_id = everyone is off; otherwise only that teacher's classes
+ALTER TABLE mentor.holidays ADD COLUMN IF NOT EXISTS teacher_id VARCHAR(50) REFERENCES mentor.teachers(id) ON DELETE CASCADE;
+ALTER TABLE mentor.holidays ADD COLUMN IF NOT EXISTS created_by VARCHAR(100);
+ALTER TABLE mentor.holidays ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT NOW();
+
+-- One holiday per date and scope
+ALTER TABLE mentor.holidays DROP CONSTRAINT IF EXISTS holidays_date_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_holidays_date_scope ON mentor.holidays(date, (COALESCE(teacher_id, '')));
@@ -0,0 +1,5 @@
+-- Migration: Per-teacher time zone
+-- Run this in your Supabase SQL editor
+
+-- IANA name, e.g. "Asia/Dhaka"; NULL uses APP_TIMEZONE
+ALTER TABLE mentor.teachers ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
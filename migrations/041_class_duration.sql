@@ -0,0 +1,11 @@
+-- Migration: Class duration and end times
+-- Run this in your Supabase SQL editor
+
+-- Minutes per class; NULL means the 60 minute default
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS class_minutes INT;
+
+-- Per-subject override, e.g. a longer Math class
+ALTER TABLE mentor.schedule ADD COLUMN IF NOT EXISTS class_minutes INT;
+
+-- Planned length of each session, copied from the above when generated
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS duration_minutes INT;
@@ -0,0 +1,24 @@
+-- Migration: Schedule change requests
+-- Run this in your Supabase SQL editor
+
+-- Teacher-proposed slot changes, approved by an admin or the guardian
+CREATE TABLE IF NOT EXISTS mentor.schedule_change_requests (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    teacher_id VARCHAR(50) NOT NULL,
+    proposed_days TEXT[] NOT NULL,
+    proposed_time VARCHAR(20) NOT NULL,
+    previous_days TEXT[],
+    previous_time VARCHAR(20),
+    reason TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, approved, rejected, withdrawn
+    decided_by VARCHAR(100),
+    decision_note TEXT,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- At most one open request per subscription
+CREATE UNIQUE INDEX IF NOT EXISTS idx_schedule_requests_pending
+    ON mentor.schedule_change_requests(subscription_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_schedule_requests_teacher ON mentor.schedule_change_requests(teacher_id);
@@ -0,0 +1,13 @@
+-- Migration: Cancel a single class session
+-- Run this in your Supabase SQL editor
+
+-- Who cancelled (teacher, guardian or admin), the person, and why
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS cancelled_party VARCHAR(20);
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS cancelled_by TEXT;
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+-- Guardian cancellations close to the class are still paid to the teacher
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS late_cancellation BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Makeup credits can come from one session rather than a whole day
+ALTER TABLE mentor.makeup_credits ADD COLUMN IF NOT EXISTS class_session_id INT REFERENCES mentor.class_sessions(id) ON DELETE SET NULL;
@@ -0,0 +1,23 @@
+-- Migration: Google Calendar sync per teacher
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.teacher_google_calendars (
+    teacher_id VARCHAR(50) PRIMARY KEY REFERENCES mentor.teachers(id) ON DELETE CASCADE,
+    google_email TEXT,
+    calendar_id TEXT NOT NULL DEFAULT 'primary',
+    refresh_token TEXT,                 -- NULL until the OAuth flow completes
+    access_token TEXT,
+    token_expires_at TIMESTAMP,
+    oauth_state TEXT UNIQUE,            -- pending authorization
+    oauth_state_expires_at TIMESTAMP,
+    block_busy BOOLEAN NOT NULL DEFAULT FALSE,  -- don't schedule over busy events
+    connected_at TIMESTAMP,
+    last_synced_at TIMESTAMP,
+    last_error TEXT
+);
+
+-- The event each session was pushed as, and to whose calendar
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS google_event_id TEXT;
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS google_event_teacher_id VARCHAR(50);
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS google_synced_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_class_sessions_google_event ON mentor.class_sessions(google_event_teacher_id) WHERE google_event_id IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Migration: Per-day, per-subject class times
+-- Run this in your Supabase SQL editor
+
+-- A subject taught at its own time on one scheduled day (e.g. Math at 9:00 AM
+-- and Science at 6:00 PM on Saturday). Subjects without a slot use the
+-- subscription's time.
+CREATE TABLE IF NOT EXISTS mentor.schedule_slots (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    day VARCHAR(3) NOT NULL,            -- Sat..Fri
+    subject VARCHAR(100) NOT NULL,
+    time VARCHAR(20) NOT NULL,          -- e.g. "9:00 AM"
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (subscription_id, day, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_slots_day ON mentor.schedule_slots(day);
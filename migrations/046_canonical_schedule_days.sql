@@ -0,0 +1,50 @@
+-- Migration: One encoding for schedule days
+-- Run this in your Supabase SQL editor
+
+-- Schedule days were stored both as names ("Mon") and codes (Sat=1 ... Fri=7).
+-- Everything is rewritten as unique names in week order (Sat..Fri) so queries
+-- can match days exactly; entries that aren't days are dropped.
+CREATE OR REPLACE FUNCTION mentor.canonical_schedule_days(days TEXT[])
+RETURNS TEXT[] AS $$
+    SELECT COALESCE(array_agg(d ORDER BY array_position(ARRAY['Sat','Sun','Mon','Tue','Wed','Thu','Fri'], d)), '{}')
+    FROM (
+        SELECT DISTINCT CASE left(trim(x), 3)
+            WHEN '1' THEN 'Sat' WHEN '2' THEN 'Sun' WHEN '3' THEN 'Mon' WHEN '4' THEN 'Tue'
+            WHEN '5' THEN 'Wed' WHEN '6' THEN 'Thu' WHEN '7' THEN 'Fri'
+            ELSE initcap(left(trim(x), 3))
+        END AS d
+        FROM unnest(days) x
+    ) t
+    WHERE d IN ('Sat','Sun','Mon','Tue','Wed','Thu','Fri');
+$$ LANGUAGE sql IMMUTABLE;
+
+UPDATE mentor.subscriptions
+SET schedule_day_list = mentor.canonical_schedule_days(COALESCE(schedule_day_list, string_to_array(schedule_days, ',')));
+
+UPDATE mentor.subscriptions
+SET schedule_days = array_to_string(schedule_day_list, ',')
+WHERE schedule_days IS DISTINCT FROM array_to_string(schedule_day_list, ',');
+
+UPDATE mentor.waitlist SET schedule_day_list = mentor.canonical_schedule_days(schedule_day_list);
+
+UPDATE mentor.schedule_change_requests SET proposed_days = mentor.canonical_schedule_days(proposed_days);
+UPDATE mentor.schedule_change_requests SET previous_days = mentor.canonical_schedule_days(previous_days)
+WHERE previous_days IS NOT NULL;
+
+-- Rows written outside the API are normalized too
+CREATE OR REPLACE FUNCTION mentor.fn_subscription_arrays()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.subject_list IS NULL THEN
+        NEW.subject_list := ARRAY(SELECT trim(x) FROM unnest(string_to_array(NEW.subjects, ',')) x WHERE trim(x) <> '');
+    END IF;
+    NEW.schedule_day_list := mentor.canonical_schedule_days(
+        COALESCE(NEW.schedule_day_list, string_to_array(NEW.schedule_days, ',')));
+    NEW.schedule_days := array_to_string(NEW.schedule_day_list, ',');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE mentor.subscriptions DROP CONSTRAINT IF EXISTS subscriptions_schedule_days_canonical;
+ALTER TABLE mentor.subscriptions ADD CONSTRAINT subscriptions_schedule_days_canonical
+    CHECK (schedule_day_list <@ ARRAY['Sat','Sun','Mon','Tue','Wed','Thu','Fri']::TEXT[]);
@@ -0,0 +1,38 @@
+-- Migration: Visits (paired attendance check-in / check-out)
+-- Run this in your Supabase SQL editor
+
+-- Each check-in is paired with the teacher's next event for the same student
+-- that day when that event is a check-out. A check-in with no check-out is
+-- flagged once the day is over or it has been open 4 hours; a check-out with
+-- no check-in is its own visit.
+CREATE OR REPLACE VIEW mentor.visits AS
+WITH ordered AS (
+    SELECT a.id, a.teacher_id, a.subscription_id, a.class_session_id, a.mode, a.action, a.recorded_at,
+           LEAD(a.action) OVER w AS next_action,
+           LEAD(a.id) OVER w AS next_id,
+           LEAD(a.recorded_at) OVER w AS next_at,
+           LAG(a.action) OVER w AS prev_action
+    FROM mentor.attendance a
+    WINDOW w AS (PARTITION BY a.teacher_id, a.subscription_id, a.recorded_at::date ORDER BY a.recorded_at, a.id)
+),
+paired AS (
+    SELECT id AS start_attendance_id,
+           CASE WHEN next_action = 'end' THEN next_id END AS end_attendance_id,
+           teacher_id, subscription_id, class_session_id, mode,
+           recorded_at AS started_at,
+           CASE WHEN next_action = 'end' THEN next_at END AS ended_at
+    FROM ordered WHERE action = 'start'
+    UNION ALL
+    SELECT NULL, id, teacher_id, subscription_id, class_session_id, mode, NULL, recorded_at
+    FROM ordered WHERE action = 'end' AND prev_action IS DISTINCT FROM 'start'
+)
+SELECT p.*,
+       COALESCE(p.started_at, p.ended_at)::date AS visit_date,
+       CASE WHEN p.started_at IS NOT NULL AND p.ended_at IS NOT NULL
+            THEN ROUND(EXTRACT(EPOCH FROM p.ended_at - p.started_at) / 60)::int END AS duration_minutes,
+       p.started_at IS NOT NULL AND p.ended_at IS NULL
+           AND (p.started_at::date < CURRENT_DATE OR p.started_at < NOW() - INTERVAL '4 hours') AS missing_check_out,
+       p.started_at IS NULL AS missing_check_in
+FROM paired p;
+
+CREATE INDEX IF NOT EXISTS idx_attendance_teacher_sub_time ON mentor.attendance(teacher_id, subscription_id, recorded_at);
@@ -0,0 +1,6 @@
+-- Migration: Photo proof on attendance
+-- Run this in your Supabase SQL editor
+
+-- Object storage keys of the photo taken at check-in/out and its thumbnail
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS photo_key TEXT;
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS photo_thumb_key TEXT;
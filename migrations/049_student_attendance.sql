@@ -0,0 +1,11 @@
+-- Migration: Student attendance per session
+-- Run this in your Supabase SQL editor
+
+-- Whether the student attended, recorded when the teacher completes the class.
+-- Absent classes don't advance progress or count as delivered.
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS student_status VARCHAR(10)
+    CHECK (student_status IN ('present', 'absent', 'late'));
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS student_status_reason TEXT;
+
+-- Absences are the student's, so they don't count toward a cycle's shortfall
+ALTER TABLE mentor.billing_cycles ADD COLUMN IF NOT EXISTS student_absences INT NOT NULL DEFAULT 0;
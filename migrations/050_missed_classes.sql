@@ -0,0 +1,8 @@
+-- Migration: Missed class detection
+-- Run this in your Supabase SQL editor
+
+-- Past sessions still 'scheduled' with no check-in or progress become 'missed'
+-- (status: scheduled, completed, cancelled, rescheduled, missed)
+ALTER TABLE mentor.class_sessions ADD COLUMN IF NOT EXISTS missed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_class_sessions_scheduled_date ON mentor.class_sessions(session_date) WHERE status = 'scheduled';
@@ -0,0 +1,27 @@
+-- Migration: Attendance corrections
+-- Run this in your Supabase SQL editor
+
+-- Check-ins/outs a teacher forgot, submitted with a reason for admin approval
+CREATE TABLE IF NOT EXISTS mentor.attendance_corrections (
+    id SERIAL PRIMARY KEY,
+    teacher_id VARCHAR(50) NOT NULL,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    class_session_id INT REFERENCES mentor.class_sessions(id) ON DELETE SET NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('start', 'end')),
+    occurred_at TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, approved, rejected, withdrawn
+    decided_by VARCHAR(100),
+    decision_note TEXT,
+    decided_at TIMESTAMP,
+    attendance_id INT,                             -- the record created on approval
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- At most one open correction per teacher, student, action and day
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_corrections_pending
+    ON mentor.attendance_corrections(teacher_id, subscription_id, action, (occurred_at::date)) WHERE status = 'pending';
+
+-- Approved corrections become attendance records flagged as not GPS-verified
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS is_corrected BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS correction_id INT REFERENCES mentor.attendance_corrections(id) ON DELETE SET NULL;
@@ -0,0 +1,9 @@
+-- Migration: Offline attendance batch sync
+-- Run this in your Supabase SQL editor
+
+-- Events recorded offline carry the app's own id so a replayed batch isn't
+-- stored twice, and when they reached the server
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS client_event_id UUID;
+ALTER TABLE mentor.attendance ADD COLUMN IF NOT EXISTS synced_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_client_event ON mentor.attendance(client_event_id) WHERE client_event_id IS NOT NULL;
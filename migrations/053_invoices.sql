@@ -0,0 +1,48 @@
+-- Migration: Monthly invoices per subscription
+-- Run this in your Supabase SQL editor
+
+-- Standing discount applied to every invoice, e.g. a sibling discount
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS discount_percent NUMERIC(5, 2) NOT NULL DEFAULT 0
+    CHECK (discount_percent >= 0 AND discount_percent <= 100);
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS discount_reason TEXT;
+
+CREATE SEQUENCE IF NOT EXISTS mentor.invoice_number_seq;
+
+-- One invoice per subscription per billing cycle
+CREATE TABLE IF NOT EXISTS mentor.invoices (
+    id SERIAL PRIMARY KEY,
+    invoice_number VARCHAR(30) NOT NULL UNIQUE,   -- INV-2026-00042
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    cycle_start DATE NOT NULL,
+    cycle_end DATE NOT NULL,                      -- last day of the cycle
+    issue_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    due_date DATE NOT NULL,
+    subtotal NUMERIC(12, 2) NOT NULL,
+    total NUMERIC(12, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued', -- issued, void
+    voided_by TEXT,
+    void_reason TEXT,
+    voided_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_cycle ON mentor.invoices(subscription_id, cycle_start) WHERE status <> 'void';
+CREATE INDEX IF NOT EXISTS idx_invoices_issue_date ON mentor.invoices(issue_date);
+
+-- Line items: subject fees, makeup credits, discounts and late fees
+CREATE TABLE IF NOT EXISTS mentor.invoice_items (
+    id SERIAL PRIMARY KEY,
+    invoice_id INT NOT NULL REFERENCES mentor.invoices(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,                    -- subject_fee, makeup_credit, discount, late_fee
+    description TEXT NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL,               -- credits and discounts are negative
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_items_invoice ON mentor.invoice_items(invoice_id);
+
+-- Payments, late fees and makeup credits point at the invoice that settled or carried them
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS invoice_id INT REFERENCES mentor.invoices(id) ON DELETE SET NULL;
+ALTER TABLE mentor.late_fees ADD COLUMN IF NOT EXISTS invoice_id INT REFERENCES mentor.invoices(id) ON DELETE SET NULL;
+ALTER TABLE mentor.makeup_credits ADD COLUMN IF NOT EXISTS invoice_id INT REFERENCES mentor.invoices(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_invoice ON mentor.transactions(invoice_id);
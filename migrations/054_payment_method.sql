@@ -0,0 +1,5 @@
+-- Migration: Payment method on transactions
+-- Run this in your Supabase SQL editor
+
+-- How a payment was made, shown on receipts: cash, bkash, nagad, rocket, bank, card, other
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS payment_method VARCHAR(20);
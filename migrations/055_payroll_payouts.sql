@@ -0,0 +1,11 @@
+-- Migration: Salary payouts
+-- Run this in your Supabase SQL editor
+
+-- Salary transactions are pending until the money is handed over
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS paid_at TIMESTAMP;
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS paid_by VARCHAR(100);
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS payment_reference VARCHAR(100); -- bKash trx ID, bank ref, cheque no.
+
+CREATE INDEX IF NOT EXISTS idx_transactions_payroll_pending
+    ON mentor.transactions(payroll_month)
+    WHERE payroll_month IS NOT NULL AND paid_at IS NULL AND voided_at IS NULL;
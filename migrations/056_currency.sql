@@ -0,0 +1,18 @@
+-- Migration: Currency per subscription, transaction and invoice + exchange rates
+-- Run this in your Supabase SQL editor
+
+-- ISO 4217 codes; NULL means the configured default (CURRENCY, default BDT)
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS currency CHAR(3);
+ALTER TABLE mentor.transactions ADD COLUMN IF NOT EXISTS currency CHAR(3);
+ALTER TABLE mentor.invoices ADD COLUMN IF NOT EXISTS currency CHAR(3);
+
+-- Units of the default currency per 1 unit of currency, from effective_date on
+CREATE TABLE IF NOT EXISTS mentor.exchange_rates (
+    id SERIAL PRIMARY KEY,
+    currency CHAR(3) NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    effective_date DATE NOT NULL,
+    updated_by TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (currency, effective_date)
+);
@@ -0,0 +1,43 @@
+-- Migration: Tax on invoice lines + gap-free invoice numbers per financial year
+-- Run this in your Supabase SQL editor
+
+-- Single-row settings (id = 1)
+CREATE TABLE IF NOT EXISTS mentor.invoice_settings (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    tax_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    tax_label TEXT NOT NULL DEFAULT 'Tax',           -- printed on invoices, e.g. VAT or GST
+    tax_percent NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (tax_percent >= 0 AND tax_percent <= 100),
+    tax_mode TEXT NOT NULL DEFAULT 'exclusive' CHECK (tax_mode IN ('exclusive', 'inclusive')),
+    tax_registration TEXT,                           -- VAT/GST registration number
+    fy_start_month INT NOT NULL DEFAULT 1 CHECK (fy_start_month BETWEEN 1 AND 12),
+    updated_by TEXT,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+INSERT INTO mentor.invoice_settings (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+-- Tax per line; amount stays the line price as entered
+ALTER TABLE mentor.invoice_items ADD COLUMN IF NOT EXISTS net_amount NUMERIC(12, 2);
+ALTER TABLE mentor.invoice_items ADD COLUMN IF NOT EXISTS tax_percent NUMERIC(5, 2) NOT NULL DEFAULT 0;
+ALTER TABLE mentor.invoice_items ADD COLUMN IF NOT EXISTS tax_amount NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE mentor.invoices ADD COLUMN IF NOT EXISTS tax_mode VARCHAR(10);
+ALTER TABLE mentor.invoices ADD COLUMN IF NOT EXISTS tax_label TEXT;
+ALTER TABLE mentor.invoices ADD COLUMN IF NOT EXISTS tax_total NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE mentor.invoices ADD COLUMN IF NOT EXISTS financial_year VARCHAR(9);
+ALTER TABLE mentor.invoices ADD COLUMN IF NOT EXISTS sequence_number INT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_fy_sequence ON mentor.invoices(financial_year, sequence_number);
+
+-- Last number issued per financial year; incremented in the invoice's
+-- transaction so a rolled back invoice doesn't leave a gap
+CREATE TABLE IF NOT EXISTS mentor.invoice_counters (
+    financial_year VARCHAR(9) PRIMARY KEY,
+    last_number INT NOT NULL
+);
+
+-- Continue from invoices numbered before this migration (INV-2026-00042)
+INSERT INTO mentor.invoice_counters (financial_year, last_number)
+SELECT split_part(invoice_number, '-', 2), MAX(split_part(invoice_number, '-', 3)::INT)
+FROM mentor.invoices
+WHERE invoice_number ~ '^INV-[0-9]{4}-[0-9]+$'
+GROUP BY 1
+ON CONFLICT (financial_year) DO UPDATE SET last_number = GREATEST(mentor.invoice_counters.last_number, EXCLUDED.last_number);
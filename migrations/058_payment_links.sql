@@ -0,0 +1,20 @@
+-- Migration: Payment links and gateway checkouts
+-- Run this in your Supabase SQL editor
+
+-- One row per checkout started from a payment link
+CREATE TABLE IF NOT EXISTS mentor.payment_checkouts (
+    id SERIAL PRIMARY KEY,
+    invoice_id INT NOT NULL REFERENCES mentor.invoices(id) ON DELETE CASCADE,
+    amount NUMERIC(12, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    gateway_id VARCHAR(100),                      -- the gateway's checkout/session id
+    checkout_url TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, paid, failed
+    gateway_reference VARCHAR(100),               -- the gateway's payment/transaction id
+    transaction_id INT REFERENCES mentor.transactions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_checkouts_gateway ON mentor.payment_checkouts(gateway_id) WHERE gateway_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payment_checkouts_invoice ON mentor.payment_checkouts(invoice_id);
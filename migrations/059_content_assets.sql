@@ -0,0 +1,17 @@
+-- Migration: Uploaded content assets (PDFs, images, audio) referenced from content_json
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.content_assets (
+    id VARCHAR(32) PRIMARY KEY,               -- stable id referenced as "asset_id" in content_json
+    kind VARCHAR(10) NOT NULL,                -- pdf, image, audio
+    filename VARCHAR(255),
+    object_key TEXT NOT NULL,                 -- key in the private S3 bucket
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes INT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    uploaded_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_assets_sha256 ON mentor.content_assets(sha256);
+CREATE INDEX IF NOT EXISTS idx_content_assets_created ON mentor.content_assets(created_at);
@@ -0,0 +1,11 @@
+-- Migration: Full-text search over chapter content
+-- Run this in your Supabase SQL editor
+
+-- Chapter title (weight A) plus every string in content_json (weight B)
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(chapter_title, '')), 'A') ||
+        setweight(jsonb_to_tsvector('english', content_json, '["string"]'), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_content_search ON mentor.content USING GIN (search_vector);
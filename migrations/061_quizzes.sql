@@ -0,0 +1,75 @@
+-- Migration: Question bank, quizzes, assignments and scored attempts
+-- Run this in your Supabase SQL editor
+
+CREATE TABLE IF NOT EXISTS mentor.questions (
+    id SERIAL PRIMARY KEY,
+    class INT NOT NULL,
+    subject VARCHAR(100) NOT NULL,
+    chapter_number INT,
+    question_type VARCHAR(20) NOT NULL,        -- mcq, true_false, short, long
+    prompt TEXT NOT NULL,
+    options JSONB,                             -- mcq choices, in order
+    correct_option INT,                        -- mcq: 0-based index into options
+    answer TEXT,                               -- true_false: true/false; short: expected answer; long: model answer
+    marks NUMERIC(6, 2) NOT NULL DEFAULT 1,
+    explanation TEXT,
+    source VARCHAR(10) NOT NULL DEFAULT 'manual', -- manual, ai
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_questions_chapter ON mentor.questions(class, subject, chapter_number);
+
+CREATE TABLE IF NOT EXISTS mentor.quizzes (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    class INT NOT NULL,
+    subject VARCHAR(100) NOT NULL,
+    chapter_number INT,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mentor.quiz_questions (
+    quiz_id INT NOT NULL REFERENCES mentor.quizzes(id) ON DELETE CASCADE,
+    question_id INT NOT NULL REFERENCES mentor.questions(id) ON DELETE RESTRICT,
+    position INT NOT NULL,
+    marks NUMERIC(6, 2) NOT NULL,
+    PRIMARY KEY (quiz_id, question_id)
+);
+
+-- A quiz given to one student; at most one attempt each
+CREATE TABLE IF NOT EXISTS mentor.quiz_assignments (
+    id SERIAL PRIMARY KEY,
+    quiz_id INT NOT NULL REFERENCES mentor.quizzes(id) ON DELETE CASCADE,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    due_date DATE,
+    assigned_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (quiz_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_assignments_subscription ON mentor.quiz_assignments(subscription_id);
+
+CREATE TABLE IF NOT EXISTS mentor.quiz_attempts (
+    id SERIAL PRIMARY KEY,
+    assignment_id INT NOT NULL UNIQUE REFERENCES mentor.quiz_assignments(id) ON DELETE CASCADE,
+    submitted_by VARCHAR(100) NOT NULL,        -- "student" or the teacher's id/name
+    score NUMERIC(8, 2) NOT NULL DEFAULT 0,
+    max_score NUMERIC(8, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL,               -- graded, pending_review (long answers to mark)
+    submitted_at TIMESTAMP DEFAULT NOW(),
+    reviewed_by VARCHAR(100),
+    reviewed_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mentor.quiz_attempt_answers (
+    attempt_id INT NOT NULL REFERENCES mentor.quiz_attempts(id) ON DELETE CASCADE,
+    question_id INT NOT NULL REFERENCES mentor.questions(id) ON DELETE RESTRICT,
+    answer TEXT,
+    is_correct BOOLEAN,                        -- NULL until a long answer is marked
+    marks_awarded NUMERIC(6, 2),
+    max_marks NUMERIC(6, 2) NOT NULL,
+    PRIMARY KEY (attempt_id, question_id)
+);
@@ -0,0 +1,16 @@
+-- Migration: Cached AI practice worksheets per chapter
+-- Run this in your Supabase SQL editor
+
+-- One worksheet per chapter content version and size; editing the chapter
+-- changes content_version, so a fresh worksheet is generated
+CREATE TABLE IF NOT EXISTS mentor.content_worksheets (
+    id SERIAL PRIMARY KEY,
+    class INT NOT NULL,
+    subject VARCHAR(100) NOT NULL,
+    chapter_number INT NOT NULL,
+    content_version VARCHAR(32) NOT NULL,
+    question_count INT NOT NULL,
+    worksheet JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (class, subject, chapter_number, content_version, question_count)
+);
@@ -0,0 +1,16 @@
+-- Migration: Draft / review / published workflow for chapter content
+-- Run this in your Supabase SQL editor
+
+-- Existing chapters stay visible: the column is added as published, then new
+-- chapters default to draft
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'; -- draft, in_review, published
+ALTER TABLE mentor.content ALTER COLUMN status SET DEFAULT 'draft';
+
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS submitted_by VARCHAR(100);
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS submitted_at TIMESTAMP;
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS review_comment TEXT;
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(100);
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_content_status ON mentor.content(status);
@@ -0,0 +1,16 @@
+-- Migration: Editable syllabus (chapters) + stale progress flag on subscriptions
+-- Run this in your Supabase SQL editor
+
+-- One row per class and subject
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chapters_class_subject ON mentor.chapters(class, subject);
+
+ALTER TABLE mentor.chapters ADD COLUMN IF NOT EXISTS chapter_titles JSONB; -- ["Chapter 1 title", ...]
+ALTER TABLE mentor.chapters ADD COLUMN IF NOT EXISTS updated_by TEXT;
+ALTER TABLE mentor.chapters ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();
+
+-- Set when a chapter count the subscription depends on changes; cleared by
+-- POST /api/admin/subscriptions/recompute-progress
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS progress_stale BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS progress_stale_since TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_progress_stale ON mentor.subscriptions(progress_stale) WHERE progress_stale;
@@ -0,0 +1,4 @@
+-- Migration: Track which chapter a copied chapter came from
+-- Run this in your Supabase SQL editor
+
+ALTER TABLE mentor.content ADD COLUMN IF NOT EXISTS copied_from_id INT REFERENCES mentor.content(id) ON DELETE SET NULL;
@@ -0,0 +1,35 @@
+-- Migration: Video lessons per chapter + student watch progress
+-- Run this in your Supabase SQL editor
+
+-- Ordered videos for a chapter: a YouTube/Vimeo link or an uploaded file
+-- (content asset of kind 'video')
+CREATE TABLE IF NOT EXISTS mentor.chapter_videos (
+    id SERIAL PRIMARY KEY,
+    class INT NOT NULL,
+    subject VARCHAR(100) NOT NULL,
+    chapter_number INT NOT NULL,
+    position INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    provider VARCHAR(10) NOT NULL,            -- youtube, vimeo, upload
+    external_id VARCHAR(50),                  -- YouTube/Vimeo video id
+    asset_id VARCHAR(32) REFERENCES mentor.content_assets(id) ON DELETE RESTRICT,
+    duration_seconds INT,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapter_videos_chapter ON mentor.chapter_videos(class, subject, chapter_number, position);
+
+CREATE TABLE IF NOT EXISTS mentor.video_watch_progress (
+    video_id INT NOT NULL REFERENCES mentor.chapter_videos(id) ON DELETE CASCADE,
+    subscription_id INT NOT NULL REFERENCES mentor.subscriptions(id) ON DELETE CASCADE,
+    position_seconds INT NOT NULL DEFAULT 0,  -- where playback last stopped
+    furthest_seconds INT NOT NULL DEFAULT 0,
+    watched_seconds INT NOT NULL DEFAULT 0,   -- total time played
+    completed_at TIMESTAMP,
+    first_watched_at TIMESTAMP DEFAULT NOW(),
+    last_watched_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (video_id, subscription_id)
+);
@@ -0,0 +1,35 @@
+-- Migration: AI exam grading with a background queue
+-- Run this in your Supabase SQL editor
+
+-- A photographed answer graded by the AI provider. Submissions are accepted
+-- as 'pending' and graded by a background worker, with retries.
+CREATE TABLE IF NOT EXISTS mentor.exam_submissions (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER REFERENCES mentor.subscriptions(id),
+    teacher_id VARCHAR(50) NOT NULL,
+    student_name VARCHAR(255) NOT NULL,
+    class INTEGER NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    chapter_number INTEGER,
+    question_text TEXT,
+    image_data TEXT,                          -- Base64 encoded image
+    image_type VARCHAR(50),                   -- image/jpeg, image/png, image/webp
+    ai_score INTEGER,                         -- 0-100
+    ai_feedback TEXT,
+    ai_suggestions TEXT,
+    teacher_notes TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'grading', 'graded', 'failed', 'reviewed')),
+    attempts INT NOT NULL DEFAULT 0,
+    grading_error TEXT,                       -- last failure, kept until graded
+    next_attempt_at TIMESTAMP DEFAULT NOW(),  -- retries back off
+    grading_started_at TIMESTAMP,
+    graded_at TIMESTAMP,
+    notify BOOLEAN NOT NULL DEFAULT FALSE,    -- message the teacher when done
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exam_submissions_teacher ON mentor.exam_submissions(teacher_id);
+CREATE INDEX IF NOT EXISTS idx_exam_submissions_student ON mentor.exam_submissions(subscription_id);
+CREATE INDEX IF NOT EXISTS idx_exam_submissions_queue ON mentor.exam_submissions(status, next_attempt_at);
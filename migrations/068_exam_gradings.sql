@@ -0,0 +1,50 @@
+-- Migration: Exam grading attempts (regrades with prompt/answer key/model overrides)
+-- Run this in your Supabase SQL editor
+
+-- Every AI grading of a submission, including the first one, is its own
+-- record; the queue and retries now live here. The submission shows the
+-- selected grading's result.
+CREATE TABLE IF NOT EXISTS mentor.exam_gradings (
+    id SERIAL PRIMARY KEY,
+    submission_id INT NOT NULL REFERENCES mentor.exam_submissions(id) ON DELETE CASCADE,
+    model VARCHAR(100),                       -- NULL: the configured default
+    prompt TEXT,                              -- replaces the default grading instructions
+    answer_key TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'grading', 'graded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT NOW(),
+    grading_started_at TIMESTAMP,
+    score INT,
+    feedback TEXT,
+    suggestions TEXT,
+    requested_by VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW(),
+    graded_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_exam_gradings_submission ON mentor.exam_gradings(submission_id);
+CREATE INDEX IF NOT EXISTS idx_exam_gradings_queue ON mentor.exam_gradings(status, next_attempt_at);
+
+ALTER TABLE mentor.exam_submissions
+    ADD COLUMN IF NOT EXISTS selected_grading_id INT REFERENCES mentor.exam_gradings(id) ON DELETE SET NULL;
+
+-- Existing submissions: their grading so far becomes the first attempt
+INSERT INTO mentor.exam_gradings (submission_id, status, attempts, error, next_attempt_at,
+                                  score, feedback, suggestions, created_at, graded_at)
+SELECT id,
+       CASE WHEN status IN ('graded', 'reviewed') THEN 'graded' WHEN status = 'failed' THEN 'failed' ELSE 'pending' END,
+       attempts, grading_error, next_attempt_at, ai_score, ai_feedback, ai_suggestions, created_at, graded_at
+FROM mentor.exam_submissions e
+WHERE NOT EXISTS (SELECT 1 FROM mentor.exam_gradings g WHERE g.submission_id = e.id);
+
+UPDATE mentor.exam_submissions e SET selected_grading_id = g.id
+FROM mentor.exam_gradings g
+WHERE g.submission_id = e.id AND g.status = 'graded' AND e.selected_grading_id IS NULL;
+
+ALTER TABLE mentor.exam_submissions
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS grading_error,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS grading_started_at;
@@ -0,0 +1,15 @@
+-- Migration: Answer keys and marking schemes for exam grading
+-- Run this in your Supabase SQL editor
+
+-- Attached at submission time; regrades use them unless they bring their own.
+-- marking_scheme: [{question_id?, question, max_marks, answer?}], bank
+-- references resolved when attached
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS answer_key TEXT;
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS marking_scheme JSONB;
+
+-- The scheme a grading used, and its marks per question:
+-- breakdown: [{number, question, max_marks, awarded, comment}]
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS marking_scheme JSONB;
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS breakdown JSONB;
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS marks_awarded NUMERIC(7,2);
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS marks_max NUMERIC(7,2);
@@ -0,0 +1,26 @@
+-- Migration: Grading rubrics for AI exam grading
+-- Run this in your Supabase SQL editor
+
+-- Reusable rubrics per subject (class NULL: every class).
+-- criteria: [{name, weight, descriptors: [{level, description}]}], weights add up to 100
+CREATE TABLE IF NOT EXISTS mentor.grading_rubrics (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    class INT,
+    subject TEXT NOT NULL,
+    description TEXT,
+    criteria JSONB NOT NULL,
+    created_by TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_grading_rubrics_subject ON mentor.grading_rubrics(LOWER(subject), class);
+
+-- The rubric chosen at submission; regrades use it unless they pick another
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS rubric_id INT
+    REFERENCES mentor.grading_rubrics(id) ON DELETE SET NULL;
+
+-- A copy of the rubric a grading used, so editing the rubric later doesn't
+-- change how past gradings read
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS rubric JSONB;
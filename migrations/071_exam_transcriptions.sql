@@ -0,0 +1,20 @@
+-- Migration: Transcribed text of graded exam answers
+-- Run this in your Supabase SQL editor
+
+-- What each grading read off the photo
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS transcription TEXT;
+
+-- The submission's transcription comes from its selected grading until a
+-- teacher corrects it
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS transcription TEXT;
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS transcription_edited_by TEXT;
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS transcription_edited_at TIMESTAMPTZ;
+
+-- Question (weight A) plus the transcribed answer (weight B)
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(question_text, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(transcription, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_exam_submissions_search ON mentor.exam_submissions USING GIN (search_vector);
@@ -0,0 +1,5 @@
+-- Migration: Record which AI provider graded an exam answer
+-- Run this in your Supabase SQL editor
+
+-- "provider:model" that actually answered, which may be the fallback provider
+ALTER TABLE mentor.exam_gradings ADD COLUMN IF NOT EXISTS graded_with TEXT;
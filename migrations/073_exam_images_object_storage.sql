@@ -0,0 +1,7 @@
+-- Migration: Keep exam answer images in object storage
+-- Run this in your Supabase SQL editor
+
+-- New submissions store the photo in the bucket and only its key here.
+-- Existing base64 image_data is moved by `go run . migrate-exam-images`;
+-- drop the column once that reports nothing left.
+ALTER TABLE mentor.exam_submissions ADD COLUMN IF NOT EXISTS image_key TEXT;
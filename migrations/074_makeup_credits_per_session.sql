@@ -0,0 +1,10 @@
+-- Migration: Key makeup credits on the cancelled session
+-- Run this in your Supabase SQL editor
+
+-- A student with two cancelled classes on one day gets a credit for each;
+-- whole-day cancellations (broadcasts) still give one credit per day
+ALTER TABLE mentor.makeup_credits DROP CONSTRAINT IF EXISTS makeup_credits_subscription_id_cancelled_date_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_makeup_credits_session
+    ON mentor.makeup_credits(class_session_id) WHERE class_session_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_makeup_credits_broadcast_day
+    ON mentor.makeup_credits(subscription_id, cancelled_date) WHERE broadcast_id IS NOT NULL;
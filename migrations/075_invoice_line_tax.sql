@@ -0,0 +1,6 @@
+-- Migration: Tax rate per invoice line kind
+-- Run this in your Supabase SQL editor
+
+-- Rates by line kind (subject_fee, discount, makeup_credit, late_fee), e.g.
+-- {"late_fee": 0}; kinds not listed use tax_percent
+ALTER TABLE mentor.invoice_settings ADD COLUMN IF NOT EXISTS line_tax_percents JSONB NOT NULL DEFAULT '{}';
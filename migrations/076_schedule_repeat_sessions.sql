@@ -0,0 +1,6 @@
+-- Migration: Count repeated parts as classes held
+-- Run this in your Supabase SQL editor
+
+-- Sessions logged with repeat_part: the class was held without covering a new
+-- part, so it counts towards completed_classes but not progress_percent
+ALTER TABLE mentor.schedule ADD COLUMN IF NOT EXISTS repeat_sessions INT NOT NULL DEFAULT 0;
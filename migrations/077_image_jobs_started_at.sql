@@ -0,0 +1,6 @@
+-- Migration: Requeue page preprocessing interrupted mid-way
+-- Run this in your Supabase SQL editor
+
+-- When a job was claimed, so one left 'processing' by a crash or restart is
+-- picked up again
+ALTER TABLE mentor.image_jobs ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
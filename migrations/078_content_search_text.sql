@@ -0,0 +1,36 @@
+-- Migration: Search chapter content by its text only
+-- Run this in your Supabase SQL editor
+
+-- The readable text of a chapter: every string in content_json except ids,
+-- asset references, links and layout hints (keep the key list in step with
+-- contentPDFSkipKeys), and anything that is a URL
+CREATE OR REPLACE FUNCTION mentor.content_text(doc JSONB) RETURNS TEXT
+LANGUAGE sql IMMUTABLE AS $$
+    WITH RECURSIVE nodes(key, value) AS (
+        SELECT NULL::TEXT, doc
+        UNION ALL
+        SELECT child.key, child.value
+        FROM nodes n
+        CROSS JOIN LATERAL (
+            SELECT e.key, e.value FROM jsonb_each(CASE WHEN jsonb_typeof(n.value) = 'object' THEN n.value ELSE '{}' END) e
+            UNION ALL
+            SELECT n.key, a.value FROM jsonb_array_elements(CASE WHEN jsonb_typeof(n.value) = 'array' THEN n.value ELSE '[]' END) a
+        ) child
+    )
+    SELECT string_agg(value #>> '{}', ' ')
+    FROM nodes
+    WHERE jsonb_typeof(value) = 'string'
+      AND (key IS NULL OR key NOT IN ('id', 'type', 'asset_id', 'asset_url', 'url', 'image', 'image_url',
+                                      'audio_url', 'video_url', 'order', 'position'))
+      AND value #>> '{}' !~* '^(https?|data):'
+$$;
+
+DROP INDEX IF EXISTS mentor.idx_content_search;
+ALTER TABLE mentor.content DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE mentor.content ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(chapter_title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(mentor.content_text(content_json), '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_content_search ON mentor.content USING GIN (search_vector);
@@ -0,0 +1,9 @@
+-- Migration: One-time codes for guardians setting a student PIN
+-- Run this in your Supabase SQL editor
+
+-- Sent to guardian_phone; setting the PIN without an admin or teacher token
+-- needs the latest code, which expires and allows a few wrong tries
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_code_hash TEXT;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_code_expires_at TIMESTAMP;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_code_sent_at TIMESTAMP;
+ALTER TABLE mentor.subscriptions ADD COLUMN IF NOT EXISTS student_pin_code_failures INT NOT NULL DEFAULT 0;
@@ -0,0 +1,26 @@
+-- Migration: Follow text column edits when updating subscription arrays
+-- Run this in your Supabase SQL editor
+
+-- On UPDATE the arrays still hold the old values, so an edit to only
+-- subjects / schedule_days (e.g. in the SQL editor) used to be overwritten by
+-- the stale list. When the text column changed and the list didn't, the list
+-- is rebuilt from the text column.
+CREATE OR REPLACE FUNCTION mentor.fn_subscription_arrays()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.subject_list IS NULL OR (TG_OP = 'UPDATE'
+            AND NEW.subjects IS DISTINCT FROM OLD.subjects
+            AND NEW.subject_list IS NOT DISTINCT FROM OLD.subject_list) THEN
+        NEW.subject_list := ARRAY(SELECT trim(x) FROM unnest(string_to_array(NEW.subjects, ',')) x WHERE trim(x) <> '');
+    END IF;
+    IF TG_OP = 'UPDATE'
+            AND NEW.schedule_days IS DISTINCT FROM OLD.schedule_days
+            AND NEW.schedule_day_list IS NOT DISTINCT FROM OLD.schedule_day_list THEN
+        NEW.schedule_day_list := NULL;
+    END IF;
+    NEW.schedule_day_list := mentor.canonical_schedule_days(
+        COALESCE(NEW.schedule_day_list, string_to_array(NEW.schedule_days, ',')));
+    NEW.schedule_days := array_to_string(NEW.schedule_day_list, ',');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// MISSED CLASSES (End-of-day detection)
+// ============================================
+
+const (
+	missedClassLookbackDays = 7  // older unrecorded sessions are left as they are
+	missedClassNotifyLimit  = 10 // sessions listed in the admin message; the count covers all
+)
+
+// clearMissedSessionSQL puts session $1 back to scheduled when a check-in for
+// it turns up after it was marked missed
+const clearMissedSessionSQL = `
+	UPDATE mentor.class_sessions SET status = 'scheduled', missed_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND status = 'missed'
+`
+
+// detectMissedClasses marks sessions from before today that are still
+// scheduled, with no check-in and no progress logged for the subject that day,
+// as missed, and tells the admin. It returns the sessions it marked.
+func detectMissedClasses() ([]gin.H, error) {
+	rows, err := db.Query(`
+		UPDATE mentor.class_sessions cs
+		SET status = 'missed', missed_at = NOW(), updated_at = NOW()
+		FROM mentor.subscriptions s
+		WHERE s.id = cs.subscription_id
+		  AND cs.status = 'scheduled'
+		  AND cs.session_date < CURRENT_DATE AND cs.session_date >= CURRENT_DATE - $1::int
+		  AND NOT EXISTS (
+			SELECT 1 FROM mentor.attendance a
+			WHERE a.action = 'start'
+			  AND (a.class_session_id = cs.id
+			       OR (a.class_session_id IS NULL AND a.subscription_id = cs.subscription_id
+			           AND a.teacher_id = cs.teacher_id AND a.recorded_at::date = cs.session_date))
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM mentor.progress p
+			WHERE p.subscription_id = cs.subscription_id AND p.subject = cs.subject
+			  AND p.completed_at::date = cs.session_date
+		  )
+		RETURNING cs.id, cs.subscription_id, s.student_name, cs.session_date, COALESCE(cs.session_time, ''),
+		          cs.subject, COALESCE(cs.teacher_id, '')
+	`, missedClassLookbackDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missed := []gin.H{}
+	for rows.Next() {
+		var id, subId int
+		var studentName, sessionTime, subject, teacherID string
+		var sessionDate time.Time
+		if err := rows.Scan(&id, &subId, &studentName, &sessionDate, &sessionTime, &subject, &teacherID); err != nil {
+			continue
+		}
+		missed = append(missed, gin.H{
+			"class_session_id": id,
+			"subscription_id":  subId,
+			"student_name":     studentName,
+			"date":             sessionDate.Format("2006-01-02"),
+			"time":             sessionTime,
+			"subject":          subject,
+			"teacher_id":       teacherID,
+		})
+	}
+	rows.Close()
+
+	for _, m := range missed {
+		logAudit("subscription", m["subscription_id"], "class_missed", "system", gin.H{
+			"session_id": m["class_session_id"],
+			"date":       m["date"],
+			"subject":    m["subject"],
+			"teacher_id": m["teacher_id"],
+		})
+	}
+
+	if len(missed) > 0 {
+		notifyMissedClasses(missed)
+	}
+	return missed, nil
+}
+
+// notifyMissedClasses sends the admin one message listing newly missed
+// classes, on the digest's number and channel
+func notifyMissedClasses(missed []gin.H) {
+	to := os.Getenv("DIGEST_TO")
+	if to == "" {
+		return
+	}
+	channel := os.Getenv("DIGEST_CHANNEL")
+	if channel == "" {
+		channel = "whatsapp"
+	}
+
+	lines := []string{fmt.Sprintf("%d class(es) missed with no check-in or progress:", len(missed))}
+	for i, m := range missed {
+		if i == missedClassNotifyLimit {
+			lines = append(lines, fmt.Sprintf("...and %d more", len(missed)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("- %s %s, %s (%s), teacher %s", m["date"], m["time"], m["student_name"], m["subject"], m["teacher_id"]))
+	}
+	lines = append(lines, adminLink("/class-sessions/missed"))
+
+	notifySubscription(0, channel, to, "missed_classes", strings.Join(lines, "\n"), "system")
+}
+
+// detectMissedClassesJob is the job entry point
+func detectMissedClassesJob() error {
+	_, err := detectMissedClasses()
+	return err
+}
+
+// getMissedClasses - Sessions marked missed over a date range (default the
+// last 30 days), optionally for one teacher or subscription
+func getMissedClasses(c *gin.Context) {
+	from, to, ok := parsePastDateRange(c, 30)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	query := `
+		SELECT cs.id, cs.subscription_id, s.student_name, cs.session_date, COALESCE(cs.session_time, ''),
+		       cs.subject, COALESCE(cs.teacher_id, ''), cs.missed_at
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		WHERE cs.status = 'missed' AND cs.session_date BETWEEN $1 AND $2
+	`
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02")}
+	argCount := 2
+
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND cs.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		query += fmt.Sprintf(" AND cs.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+
+	query += " ORDER BY cs.session_date DESC, cs.session_time, cs.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	classes := []gin.H{}
+	for rows.Next() {
+		var id, subId int
+		var studentName, sessionTime, subject, teacher string
+		var sessionDate time.Time
+		var missedAt sql.NullTime
+		if err := rows.Scan(&id, &subId, &studentName, &sessionDate, &sessionTime, &subject, &teacher, &missedAt); err != nil {
+			continue
+		}
+		class := gin.H{
+			"class_session_id": id,
+			"subscription_id":  subId,
+			"student_name":     studentName,
+			"date":             sessionDate.Format("2006-01-02"),
+			"time":             sessionTime,
+			"subject":          subject,
+			"teacher_id":       teacher,
+		}
+		if missedAt.Valid {
+			class["missed_at"] = isoTimestamp(storedTime(missedAt.Time))
+		}
+		classes = append(classes, class)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+		"count":   len(classes),
+		"classes": classes,
+	})
+}
+
+// runMissedClassDetection - Run detection now rather than waiting for the job
+func runMissedClassDetection(c *gin.Context) {
+	missed, err := detectMissedClasses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "count": len(missed), "classes": missed})
+}
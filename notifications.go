@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// =====================================================
+// NOTIFICATIONS (SMS / WhatsApp / push / email gateway)
+// =====================================================
+
+var notifyClient = &http.Client{Timeout: 15 * time.Second}
+
+// sendNotification delivers a message through the gateway configured in
+// NOTIFY_WEBHOOK_URL. The gateway receives {channel, to, message} as JSON and
+// may reply with {"id": "..."} to enable delivery status callbacks.
+func sendNotification(channel, to, message string) (string, error) {
+	webhookURL := os.Getenv("NOTIFY_WEBHOOK_URL")
+	if webhookURL == "" {
+		return "", fmt.Errorf("NOTIFY_WEBHOOK_URL not configured")
+	}
+	if to == "" {
+		return "", fmt.Errorf("no recipient for %s", channel)
+	}
+
+	payload, _ := json.Marshal(map[string]string{
+		"channel": channel,
+		"to":      to,
+		"message": message,
+	})
+
+	resp, err := notifyClient.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("notification gateway returned %d", resp.StatusCode)
+	}
+
+	var gatewayResp struct {
+		ID string `json:"id"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(body, &gatewayResp)
+
+	return gatewayResp.ID, nil
+}
+
+// notifySubscription sends a message related to a subscription and records it in
+// the communication log, so every outbound message has a delivery record.
+// subId may be 0 for messages not tied to a subscription.
+func notifySubscription(subId interface{}, channel, to, purpose, message, sentBy string) error {
+	var commID int
+	err := db.QueryRow(`
+		INSERT INTO mentor.communications (subscription_id, channel, recipient, purpose, message, sent_by)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6)
+		RETURNING id
+	`, fmt.Sprint(subId), channel, to, purpose, message, sentBy).Scan(&commID)
+	if err != nil {
+		log.Println("Warning: could not log communication:", err)
+	}
+
+	providerID, sendErr := sendNotification(channel, to, message)
+
+	if commID != 0 {
+		status, errText := "sent", ""
+		if sendErr != nil {
+			status, errText = "failed", sendErr.Error()
+		}
+		db.Exec(`
+			UPDATE mentor.communications
+			SET status = $1, error = NULLIF($2, ''), provider_message_id = NULLIF($3, ''), updated_at = NOW()
+			WHERE id = $4
+		`, status, errText, providerID, commID)
+	}
+
+	return sendErr
+}
+
+// getSubscriptionCommunications - Everything sent about a subscription, with delivery status
+func getSubscriptionCommunications(c *gin.Context) {
+	subId := c.Param("id")
+	channel := c.Query("channel")
+
+	query := `
+		SELECT id, channel, recipient, purpose, message, status, error, sent_by, created_at, updated_at
+		FROM mentor.communications
+		WHERE subscription_id = $1
+	`
+	args := []interface{}{subId}
+	if channel != "" {
+		query += " AND channel = $2"
+		args = append(args, channel)
+	}
+	query += " ORDER BY created_at DESC LIMIT 200"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var communications []gin.H
+	for rows.Next() {
+		var id int
+		var commChannel, recipient, message, status string
+		var purpose, errText, sentBy sql.NullString
+		var createdAt, updatedAt time.Time
+
+		if err := rows.Scan(&id, &commChannel, &recipient, &purpose, &message, &status, &errText, &sentBy, &createdAt, &updatedAt); err != nil {
+			continue
+		}
+
+		communications = append(communications, gin.H{
+			"id":         id,
+			"channel":    commChannel,
+			"recipient":  recipient,
+			"purpose":    purpose.String,
+			"message":    message,
+			"status":     status,
+			"error":      errText.String,
+			"sent_by":    sentBy.String,
+			"created_at": isoTimestamp(createdAt),
+			"updated_at": isoTimestamp(updatedAt),
+		})
+	}
+
+	if communications == nil {
+		communications = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "communications": communications})
+}
+
+// logSubscriptionCommunication - Record a message sent outside the app (phone call, personal WhatsApp)
+func logSubscriptionCommunication(c *gin.Context) {
+	subId := c.Param("id")
+
+	var input struct {
+		Channel   string `json:"channel"`
+		Recipient string `json:"recipient"`
+		Purpose   string `json:"purpose"`
+		Message   string `json:"message"`
+		SentBy    string `json:"sent_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Channel == "" || input.Recipient == "" || input.Message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "channel, recipient, and message are required"})
+		return
+	}
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.communications (subscription_id, channel, recipient, purpose, message, status, sent_by)
+		VALUES ($1, $2, $3, $4, $5, 'sent', $6)
+		RETURNING id
+	`, subId, input.Channel, input.Recipient, input.Purpose, input.Message, input.SentBy).Scan(&id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "message": "Communication logged"})
+}
+
+// communicationStatusFrom lists, for each receipt status, the statuses it may
+// replace. Receipts only move a message forward, so a late "sent" can't undo
+// "delivered" or "read", and a message that arrived can't turn "failed".
+var communicationStatusFrom = map[string][]string{
+	"sent":      {"queued"},
+	"delivered": {"queued", "sent"},
+	"read":      {"queued", "sent", "delivered"},
+	"failed":    {"queued", "sent"},
+}
+
+// notificationStatusWebhook - Delivery receipts from the gateway
+func notificationStatusWebhook(c *gin.Context) {
+	secret := os.Getenv("NOTIFY_WEBHOOK_SECRET")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Webhook-Secret")), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid webhook secret"})
+		return
+	}
+
+	var input struct {
+		ID     string `json:"id"` // provider_message_id returned when sending
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	from, ok := communicationStatusFrom[input.Status]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Unknown status"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.communications
+		SET status = $1, error = COALESCE(NULLIF($2, ''), error), updated_at = NOW()
+		WHERE provider_message_id = $3 AND status = ANY($4)
+	`, input.Status, input.Error, input.ID, pq.Array(from))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	n, _ := result.RowsAffected()
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "updated": n > 0})
+}
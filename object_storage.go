@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =====================================================
+// OBJECT STORAGE (S3-compatible: AWS S3, R2, MinIO, Supabase)
+// =====================================================
+
+// objectStore is a bucket on an S3-compatible service, addressed path-style
+// ({endpoint}/{bucket}/{key}) so it works with any provider
+type objectStore struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+}
+
+// getObjectStore returns the configured store, or nil if S3_* isn't set
+func getObjectStore() *objectStore {
+	s := &objectStore{
+		endpoint:  strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		region:    os.Getenv("S3_REGION"),
+		bucket:    os.Getenv("S3_BUCKET"),
+		accessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	if s.endpoint == "" || s.bucket == "" || s.accessKey == "" || s.secretKey == "" {
+		return nil
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	return s
+}
+
+var objectStoreClient = &http.Client{Timeout: 60 * time.Second}
+
+func (s *objectStore) objectURL(key string) *url.URL {
+	u, _ := url.Parse(s.endpoint + "/" + s.bucket + "/" + key)
+	return u
+}
+
+// Put uploads an object
+func (s *objectStore) Put(key, contentType string, data []byte) error {
+	_, err := s.do(http.MethodPut, key, contentType, data)
+	return err
+}
+
+// Get downloads an object
+func (s *objectStore) Get(key string) ([]byte, error) {
+	return s.do(http.MethodGet, key, "", nil)
+}
+
+// Delete removes an object; missing objects are not an error
+func (s *objectStore) Delete(key string) error {
+	_, err := s.do(http.MethodDelete, key, "", nil)
+	return err
+}
+
+func (s *objectStore) do(method, key, contentType string, data []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, s.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, data, time.Now().UTC())
+
+	resp, err := objectStoreClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("object storage %s returned %d: %s", method, resp.StatusCode, body)
+	}
+	if method != http.MethodGet {
+		return nil, nil
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// PresignGet returns a time-limited URL for reading a private object
+func (s *objectStore) PresignGet(key string, expires time.Duration) string {
+	return s.presign(http.MethodGet, s.objectURL(key), expires, time.Now().UTC())
+}
+
+// ---- AWS Signature Version 4 ----
+
+func (s *objectStore) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s *objectStore) signature(now time.Time, stringToSign string) string {
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	key := mac([]byte("AWS4"+s.secretKey), now.Format("20060102"))
+	key = mac(key, s.region)
+	key = mac(key, "s3")
+	key = mac(key, "aws4_request")
+	return hex.EncodeToString(mac(key, stringToSign))
+}
+
+func (s *objectStore) stringToSign(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	return "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + s.scope(now) + "\n" + hex.EncodeToString(hash[:])
+}
+
+// sign adds the Authorization header to a request
+func (s *objectStore) sign(req *http.Request, payload []byte, now time.Time) {
+	payloadHash := sha256.Sum256(payload)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, s.scope(now), signedHeaders, s.signature(now, s.stringToSign(now, canonicalRequest))))
+}
+
+// presign builds a query-string signed URL
+func (s *objectStore) presign(method string, u *url.URL, expires time.Duration, now time.Time) string {
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", fmt.Sprint(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		awsURIEncode(u.Path, false),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	return u.Scheme + "://" + u.Host + awsURIEncode(u.Path, false) + "?" + canonicalQuery(query) +
+		"&X-Amz-Signature=" + s.signature(now, s.stringToSign(now, canonicalRequest))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters (and
+// "/" in paths), as SigV4 requires
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// ONLINE SESSIONS (Meeting links + recordings)
+// ============================================
+
+// createOnlineSession - Store the meeting link for an online class
+func createOnlineSession(c *gin.Context) {
+	var input struct {
+		SubscriptionID int    `json:"subscription_id"`
+		TeacherID      string `json:"teacher_id"`
+		SessionDate    string `json:"session_date"` // YYYY-MM-DD, defaults to today
+		Subject        string `json:"subject"`
+		MeetingLink    string `json:"meeting_link"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.SubscriptionID == 0 || input.TeacherID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "subscription_id and teacher_id are required"})
+		return
+	}
+
+	// Without a link we need a video provider to create the room
+	if input.MeetingLink == "" && getVideoProvider() == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "meeting_link is required when no video provider is configured"})
+		return
+	}
+
+	if input.SessionDate == "" {
+		input.SessionDate = time.Now().Format("2006-01-02")
+	}
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.online_sessions (subscription_id, teacher_id, session_date, subject, meeting_link)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id
+	`, input.SubscriptionID, input.TeacherID, input.SessionDate, input.Subject, input.MeetingLink).Scan(&id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	meetingLink := input.MeetingLink
+	if meetingLink == "" {
+		meetingLink, err = provisionSessionRoom(id)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "warning": "Room not created: " + err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "meeting_link": meetingLink, "message": "Online session created"})
+}
+
+// getOnlineSessions - List online sessions by subscription, teacher, or date
+func getOnlineSessions(c *gin.Context) {
+	subscriptionID := c.Query("subscription_id")
+	teacherID := c.Query("teacher_id")
+	date := c.Query("date")
+
+	query := `
+		SELECT o.id, o.subscription_id, s.student_name, o.teacher_id, o.session_date, o.subject,
+		       o.meeting_link, o.recording_url, o.teacher_joined_at, o.student_joined_at,
+		       o.duration_minutes, o.created_at
+		FROM mentor.online_sessions o
+		LEFT JOIN mentor.subscriptions s ON o.subscription_id = s.id
+		WHERE 1=1
+	`
+	args := []interface{}{}
+	argCount := 0
+
+	if subscriptionID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND o.subscription_id = $%d", argCount)
+		args = append(args, subscriptionID)
+	}
+	if teacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND o.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if date != "" {
+		argCount++
+		query += fmt.Sprintf(" AND o.session_date = $%d", argCount)
+		args = append(args, date)
+	}
+
+	query += " ORDER BY o.session_date DESC, o.created_at DESC LIMIT 100"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var sessions []gin.H
+	for rows.Next() {
+		var id, subscriptionID int
+		var teacherID string
+		var sessionDate, createdAt time.Time
+		var studentName, subject, meetingLink, recordingURL sql.NullString
+		var teacherJoinedAt, studentJoinedAt sql.NullTime
+		var durationMinutes sql.NullInt64
+
+		if err := rows.Scan(&id, &subscriptionID, &studentName, &teacherID, &sessionDate, &subject,
+			&meetingLink, &recordingURL, &teacherJoinedAt, &studentJoinedAt,
+			&durationMinutes, &createdAt); err != nil {
+			continue
+		}
+
+		session := gin.H{
+			"id":              id,
+			"subscription_id": subscriptionID,
+			"student_name":    studentName.String,
+			"teacher_id":      teacherID,
+			"session_date":    sessionDate.Format("2006-01-02"),
+			"subject":         subject.String,
+			"meeting_link":    meetingLink.String,
+			"recording_url":   recordingURL.String,
+			"created_at":      isoTimestamp(createdAt),
+		}
+		if teacherJoinedAt.Valid {
+			session["teacher_joined_at"] = isoTimestamp(teacherJoinedAt.Time)
+		}
+		if studentJoinedAt.Valid {
+			session["student_joined_at"] = isoTimestamp(studentJoinedAt.Time)
+		}
+		if durationMinutes.Valid {
+			session["duration_minutes"] = durationMinutes.Int64
+		}
+		sessions = append(sessions, session)
+	}
+
+	if sessions == nil {
+		sessions = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "sessions": sessions})
+}
+
+// saveSessionRecording - Attach the recording URL after the class
+func saveSessionRecording(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		RecordingURL string `json:"recording_url"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.RecordingURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "recording_url is required"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.online_sessions SET recording_url = $1 WHERE id = $2
+	`, input.RecordingURL, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Recording saved"})
+}
+
+// saveSessionStats - Record join times and duration where the provider reports them
+func saveSessionStats(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		TeacherJoinedAt *time.Time `json:"teacher_joined_at"`
+		StudentJoinedAt *time.Time `json:"student_joined_at"`
+		DurationMinutes *int       `json:"duration_minutes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// Only overwrite the stats that were provided
+	result, err := db.Exec(`
+		UPDATE mentor.online_sessions
+		SET teacher_joined_at = COALESCE($1, teacher_joined_at),
+		    student_joined_at = COALESCE($2, student_joined_at),
+		    duration_minutes = COALESCE($3, duration_minutes)
+		WHERE id = $4
+	`, input.TeacherJoinedAt, input.StudentJoinedAt, input.DurationMinutes, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Session stats saved"})
+}
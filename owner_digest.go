@@ -0,0 +1,459 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// OWNER DIGEST (Weekly summary of exceptions only)
+// =====================================================
+
+const (
+	digestExamReviewDays = 3  // answer papers waiting longer than this are flagged
+	digestStalledDays    = 14 // active subscriptions with no class logged for this long
+	digestItemLimit      = 10 // items listed per section; the count covers all
+)
+
+// adminLink builds a deep link into the admin panel, or a bare path when
+// ADMIN_PANEL_URL is not set
+func adminLink(format string, args ...interface{}) string {
+	return strings.TrimRight(os.Getenv("ADMIN_PANEL_URL"), "/") + fmt.Sprintf(format, args...)
+}
+
+// digestSection is one category of exceptions, e.g. overdue fees
+type digestSection struct {
+	key, title, link string
+	collect          func() ([]gin.H, error)
+}
+
+var digestSections = []digestSection{
+	{"unapproved_expenses", "Unapproved expenses", "/transactions?type=expense&approved=false", digestUnapprovedExpenses},
+	{"unreviewed_exams", "Exams waiting for review", "/grading", digestUnreviewedExams},
+	{"missing_attendance", "Teachers with missing attendance", "/attendance", digestMissingAttendance},
+	{"overdue_fees", "Overdue fees", "/billing/shortfalls", digestOverdueFees},
+	{"stalled_subscriptions", "Stalled subscriptions", "/subscriptions?status=active", digestStalledSubscriptions},
+}
+
+func digestUnapprovedExpenses() ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT id, date, amount, currency, description, category
+		FROM mentor.transactions
+		WHERE type = 'expense' AND approved_at IS NULL AND voided_at IS NULL
+		ORDER BY date, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []gin.H
+	for rows.Next() {
+		var id int
+		var date time.Time
+		var amount float64
+		var currency, description, category sql.NullString
+		if err := rows.Scan(&id, &date, &amount, &currency, &description, &category); err != nil {
+			continue
+		}
+		items = append(items, gin.H{
+			"transaction_id": id,
+			"date":           date.Format("2006-01-02"),
+			"amount":         amount,
+			"currency":       orDefaultCurrency(currency),
+			"description":    description.String,
+			"category":       category.String,
+			"link":           adminLink("/transactions/%d", id),
+		})
+	}
+	return items, nil
+}
+
+func digestUnreviewedExams() ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.student_name, a.subject, a.teacher_id, t.name, a.created_at
+		FROM mentor.answer_papers a
+		LEFT JOIN mentor.teachers t ON t.id = a.teacher_id
+		WHERE a.status = 'pending' AND a.created_at < NOW() - make_interval(days => $1)
+		ORDER BY a.created_at
+	`, digestExamReviewDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []gin.H
+	for rows.Next() {
+		var id int
+		var studentName, subject, teacherID string
+		var teacherName sql.NullString
+		var createdAt time.Time
+		if err := rows.Scan(&id, &studentName, &subject, &teacherID, &teacherName, &createdAt); err != nil {
+			continue
+		}
+		items = append(items, gin.H{
+			"answer_paper_id": id,
+			"student_name":    studentName,
+			"subject":         subject,
+			"teacher_id":      teacherID,
+			"teacher_name":    teacherName.String,
+			"submitted_at":    isoTimestamp(createdAt),
+			"days_waiting":    int(time.Since(createdAt).Hours() / 24),
+			"link":            adminLink("/grading/%d", id),
+		})
+	}
+	return items, nil
+}
+
+// digestMissingAttendance compares each teacher's scheduled classes over the
+// last 7 days (skipping holidays, leave, pauses and cancellations) with the
+// days a class start was actually recorded
+func digestMissingAttendance() ([]gin.H, error) {
+	rows, err := db.Query(`
+		WITH expected AS (
+			SELECT s.teacher_id, s.id AS subscription_id, d::date AS day
+			FROM mentor.subscriptions s
+			CROSS JOIN generate_series(CURRENT_DATE - 7, CURRENT_DATE - 1, INTERVAL '1 day') d
+			WHERE s.status = 'active' AND s.deleted_at IS NULL AND s.teacher_id IS NOT NULL
+			  AND s.created_at::date <= d::date
+			  AND to_char(d, 'Dy') = ANY(s.schedule_day_list)
+			  AND NOT EXISTS (SELECT 1 FROM mentor.holidays h WHERE h.date = d::date AND (h.teacher_id IS NULL OR h.teacher_id = s.teacher_id))
+			  AND NOT EXISTS (SELECT 1 FROM mentor.teacher_blackouts b WHERE b.teacher_id = s.teacher_id AND b.date = d::date)
+			  AND NOT EXISTS (SELECT 1 FROM mentor.class_cancellations cc WHERE cc.subscription_id = s.id AND cc.date = d::date)
+			  AND NOT EXISTS (
+				SELECT 1 FROM mentor.subscription_pauses p
+				WHERE p.subscription_id = s.id AND d::date BETWEEN p.start_date AND p.end_date
+			  )
+		)
+		SELECT e.teacher_id, t.name, COUNT(*) AS expected,
+		       COUNT(*) FILTER (WHERE EXISTS (
+				SELECT 1 FROM mentor.attendance a
+				WHERE a.subscription_id = e.subscription_id AND a.action = 'start'
+				  AND a.recorded_at::date = e.day
+		       )) AS recorded
+		FROM expected e
+		JOIN mentor.teachers t ON t.id = e.teacher_id
+		GROUP BY e.teacher_id, t.name
+		ORDER BY COUNT(*) DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []gin.H
+	for rows.Next() {
+		var teacherID, name string
+		var expected, recorded int
+		if err := rows.Scan(&teacherID, &name, &expected, &recorded); err != nil || recorded >= expected {
+			continue
+		}
+		items = append(items, gin.H{
+			"teacher_id":        teacherID,
+			"teacher_name":      name,
+			"scheduled_classes": expected,
+			"recorded_classes":  recorded,
+			"missing":           expected - recorded,
+			"link":              adminLink("/teachers/%s/attendance", teacherID),
+		})
+	}
+	return items, nil
+}
+
+// digestOverdueFees lists paid subscriptions whose current cycle is still
+// unpaid past the late fee grace period
+func digestOverdueFees() ([]gin.H, error) {
+	policy, _ := loadLateFeePolicy()
+
+	rows, err := db.Query(`
+		SELECT id, student_name, amount, currency, COALESCE(billing_date, 1) FROM mentor.subscriptions
+		WHERE status = 'active' AND deleted_at IS NULL AND subscription_type = 'paid' AND amount > 0
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	type due struct {
+		id          int
+		studentName string
+		amount      float64
+		currency    string
+		billingDate int
+	}
+	var subs []due
+	for rows.Next() {
+		var d due
+		var currency sql.NullString
+		rows.Scan(&d.id, &d.studentName, &d.amount, &currency, &d.billingDate)
+		d.currency = orDefaultCurrency(currency)
+		subs = append(subs, d)
+	}
+	rows.Close()
+
+	today := time.Now()
+	var items []gin.H
+	for _, s := range subs {
+		start, end := billingCycleFor(today, s.billingDate)
+		if today.Before(start.AddDate(0, 0, policy.GraceDays+1)) {
+			continue
+		}
+		paid := cyclePaidAmount(s.id, s.amount, start, end)
+		if paid >= s.amount {
+			continue
+		}
+		items = append(items, gin.H{
+			"subscription_id": s.id,
+			"student_name":    s.studentName,
+			"amount":          s.amount,
+			"currency":        s.currency,
+			"paid":            paid,
+			"outstanding":     s.amount - paid,
+			"due_since":       start.Format("2006-01-02"),
+			"link":            adminLink("/subscriptions/%d", s.id),
+		})
+	}
+	return items, nil
+}
+
+func digestStalledSubscriptions() ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.student_name, s.teacher_id, t.name, MAX(p.completed_at)
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.teachers t ON t.id = s.teacher_id
+		LEFT JOIN mentor.progress p ON p.subscription_id = s.id
+		WHERE s.status = 'active' AND s.deleted_at IS NULL
+		  AND s.created_at < NOW() - make_interval(days => $1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM mentor.subscription_pauses sp
+			WHERE sp.subscription_id = s.id AND CURRENT_DATE BETWEEN sp.start_date AND sp.end_date
+		  )
+		GROUP BY s.id, s.student_name, s.teacher_id, t.name
+		HAVING MAX(p.completed_at) IS NULL OR MAX(p.completed_at) < NOW() - make_interval(days => $1)
+		ORDER BY MAX(p.completed_at) NULLS FIRST
+	`, digestStalledDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []gin.H
+	for rows.Next() {
+		var id int
+		var studentName string
+		var teacherID, teacherName sql.NullString
+		var lastClass sql.NullTime
+		if err := rows.Scan(&id, &studentName, &teacherID, &teacherName, &lastClass); err != nil {
+			continue
+		}
+		item := gin.H{
+			"subscription_id": id,
+			"student_name":    studentName,
+			"teacher_id":      teacherID.String,
+			"teacher_name":    teacherName.String,
+			"link":            adminLink("/subscriptions/%d", id),
+		}
+		if lastClass.Valid {
+			item["last_class_at"] = lastClass.Time.Format("2006-01-02")
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// buildOwnerDigest runs every section and keeps only the ones with exceptions
+func buildOwnerDigest() (gin.H, error) {
+	now := time.Now()
+	sections := []gin.H{}
+	total := 0
+
+	for _, s := range digestSections {
+		items, err := s.collect()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.key, err)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		total += len(items)
+		listed := items
+		if len(listed) > digestItemLimit {
+			listed = listed[:digestItemLimit]
+		}
+		sections = append(sections, gin.H{
+			"key":   s.key,
+			"title": s.title,
+			"count": len(items),
+			"link":  adminLink("%s", s.link),
+			"items": listed,
+		})
+	}
+
+	return gin.H{
+		"period_start":     now.AddDate(0, 0, -7).Format("2006-01-02"),
+		"period_end":       now.Format("2006-01-02"),
+		"total_exceptions": total,
+		"sections":         sections,
+	}, nil
+}
+
+// formatOwnerDigest renders the digest as a plain-text message
+func formatOwnerDigest(digest gin.H) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Weekly digest %s to %s\n", digest["period_start"], digest["period_end"])
+
+	sections, _ := digest["sections"].([]gin.H)
+	if len(sections) == 0 {
+		b.WriteString("No exceptions this week.")
+		return b.String()
+	}
+
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n%s: %v\n", s["title"], s["count"])
+		for _, item := range s["items"].([]gin.H) {
+			fmt.Fprintf(&b, "- %s %s\n", digestItemLabel(s["key"].(string), item), item["link"])
+		}
+		if more := s["count"].(int) - len(s["items"].([]gin.H)); more > 0 {
+			fmt.Fprintf(&b, "- ...and %d more %s\n", more, s["link"])
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func digestItemLabel(key string, item gin.H) string {
+	switch key {
+	case "unapproved_expenses":
+		return fmt.Sprintf("%s %s (%s)", item["date"], formatMoney(item["amount"].(float64), item["currency"].(string)), item["description"])
+	case "unreviewed_exams":
+		return fmt.Sprintf("%s, %s, %v days", item["student_name"], item["subject"], item["days_waiting"])
+	case "missing_attendance":
+		return fmt.Sprintf("%s, %v of %v classes unrecorded", item["teacher_name"], item["missing"], item["scheduled_classes"])
+	case "overdue_fees":
+		return fmt.Sprintf("%s, %s due since %s", item["student_name"], formatMoney(item["outstanding"].(float64), item["currency"].(string)), item["due_since"])
+	case "stalled_subscriptions":
+		if last, ok := item["last_class_at"]; ok {
+			return fmt.Sprintf("%s, last class %s", item["student_name"], last)
+		}
+		return fmt.Sprintf("%s, no classes yet", item["student_name"])
+	}
+	return ""
+}
+
+// sendOwnerDigest builds the digest, sends it to DIGEST_TO and records it
+func sendOwnerDigest() (gin.H, error) {
+	to := os.Getenv("DIGEST_TO")
+	if to == "" {
+		return nil, fmt.Errorf("DIGEST_TO is not configured")
+	}
+	channel := os.Getenv("DIGEST_CHANNEL")
+	if channel == "" {
+		channel = "whatsapp"
+	}
+
+	digest, err := buildOwnerDigest()
+	if err != nil {
+		return nil, err
+	}
+
+	sendErr := notifySubscription(0, channel, to, "owner_digest", formatOwnerDigest(digest), "system")
+	errText := ""
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+
+	content, _ := json.Marshal(digest)
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO mentor.owner_digests (period_start, period_end, content, sent_via, sent_to, error)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING id
+	`, digest["period_start"], digest["period_end"], string(content), channel, to, errText).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	digest["id"] = id
+	return digest, sendErr
+}
+
+// sendWeeklyDigestIfDue is the job entry point: at most one digest a week,
+// and only once DIGEST_TO is configured
+func sendWeeklyDigestIfDue() error {
+	if os.Getenv("DIGEST_TO") == "" {
+		return nil
+	}
+
+	var recent bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.owner_digests
+		              WHERE error IS NULL AND created_at > NOW() - INTERVAL '7 days')
+	`).Scan(&recent)
+	if recent {
+		return nil
+	}
+
+	_, err := sendOwnerDigest()
+	return err
+}
+
+// getOwnerDigest - Preview the digest as it would be sent now
+func getOwnerDigest(c *gin.Context) {
+	digest, err := buildOwnerDigest()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "digest": digest, "message": formatOwnerDigest(digest)})
+}
+
+// sendOwnerDigestHandler - Send the digest immediately
+func sendOwnerDigestHandler(c *gin.Context) {
+	digest, err := sendOwnerDigest()
+	if digest == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Failed to send: " + err.Error(), "digest": digest})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "digest": digest, "message": "Digest sent"})
+}
+
+// approveTransaction - Owner signs off on a recorded expense
+func approveTransaction(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		ApprovedBy string `json:"approved_by"`
+	}
+	c.ShouldBindJSON(&input)
+	if input.ApprovedBy == "" {
+		input.ApprovedBy = "admin"
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.transactions SET approved_at = NOW(), approved_by = $1
+		WHERE id = $2 AND type = 'expense' AND approved_at IS NULL AND voided_at IS NULL
+	`, input.ApprovedBy, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Unapproved expense not found"})
+		return
+	}
+
+	logAudit("transaction", id, "approve", input.ApprovedBy, nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Expense approved"})
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// PAYMENT LINKS (Public invoice page + gateway checkout)
+// ============================================
+
+// paymentLinkTTL is how long a shared payment link works
+const paymentLinkTTL = 30 * 24 * time.Hour
+
+var paymentClient = &http.Client{Timeout: 15 * time.Second}
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// signPaymentToken signs an invoice id for the public payment page with
+// PAYMENT_LINK_SECRET
+func signPaymentToken(invoiceID int, expires time.Time) (string, error) {
+	secret := os.Getenv("PAYMENT_LINK_SECRET")
+	if secret == "" {
+		return "", fmt.Errorf("PAYMENT_LINK_SECRET not configured")
+	}
+	return signJWT(map[string]interface{}{"typ": "pay", "inv": invoiceID, "exp": expires.Unix()}, secret), nil
+}
+
+// verifyPaymentToken returns the invoice id of a valid, unexpired token
+func verifyPaymentToken(token string) (int, bool) {
+	var claims struct {
+		Typ string `json:"typ"`
+		Inv int    `json:"inv"`
+		Exp int64  `json:"exp"`
+	}
+	if !verifyJWT(token, os.Getenv("PAYMENT_LINK_SECRET"), &claims) || claims.Typ != "pay" || time.Now().Unix() > claims.Exp {
+		return 0, false
+	}
+	return claims.Inv, true
+}
+
+// paymentBaseURL is PUBLIC_API_URL. Payment links and gateway callbacks are
+// never built from the request's Host header, which a client controls.
+func paymentBaseURL() (string, error) {
+	base := os.Getenv("PUBLIC_API_URL")
+	if base == "" {
+		return "", fmt.Errorf("PUBLIC_API_URL not configured")
+	}
+	return strings.TrimRight(base, "/"), nil
+}
+
+// createPaymentLink - A signed public link to the invoice's payment page,
+// with a ready-made WhatsApp message to the guardian
+func createPaymentLink(c *gin.Context) {
+	invoice, err := loadInvoice(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Invoice not found"})
+		return
+	}
+	if invoice["status"] == "void" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invoice is void"})
+		return
+	}
+	if invoice["balance"].(float64) <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invoice is already paid"})
+		return
+	}
+
+	baseURL, err := paymentBaseURL()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	expires := time.Now().Add(paymentLinkTTL)
+	token, err := signPaymentToken(invoice["id"].(int), expires)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	link := baseURL + "/api/pay/" + token
+
+	message := fmt.Sprintf("%s: invoice %s for %s, %s due by %s. Pay here: %s",
+		brandName(), invoice["invoice_number"], invoice["student_name"],
+		formatMoney(invoice["balance"].(float64), invoice["currency"].(string)), invoice["due_date"], link)
+
+	var guardianPhone string
+	db.QueryRow("SELECT COALESCE(guardian_phone, '') FROM mentor.subscriptions WHERE id = $1", invoice["subscription_id"]).Scan(&guardianPhone)
+
+	response := gin.H{
+		"success":    true,
+		"url":        link,
+		"expires_at": isoTimestamp(expires),
+		"message":    message,
+	}
+	if phone := nonDigits.ReplaceAllString(guardianPhone, ""); phone != "" {
+		response["whatsapp_url"] = "https://wa.me/" + phone + "?text=" + url.QueryEscape(message)
+	}
+
+	logAudit("invoice", invoice["id"], "payment_link_created", c.Query("created_by"), gin.H{"expires_at": isoTimestamp(expires)})
+	c.JSON(http.StatusOK, response)
+}
+
+var paymentPage = template.Must(template.New("pay").Parse(`<!doctype html>
+<meta charset="utf-8"><meta name="viewport" content="width=device-width">
+<title>{{.Brand}} - {{.Number}}</title>
+<style>
+body{font-family:sans-serif;max-width:420px;margin:2em auto;padding:0 1em;color:#222}
+h1{color:#1f4e79;font-size:1.4em}table{width:100%;border-collapse:collapse}
+td{padding:.4em 0;border-bottom:1px solid #eee}td:last-child{text-align:right}
+.due{font-weight:bold;font-size:1.2em}button{width:100%;padding:1em;margin-top:1.5em;font-size:1.1em;
+background:#1f4e79;color:#fff;border:0;border-radius:6px}p.note{color:#666}
+</style>
+<h1>{{.Brand}}</h1>
+<p>Invoice {{.Number}}</p>
+<table>
+<tr><td>Student</td><td>{{.Student}}</td></tr>
+<tr><td>Period</td><td>{{.Period}}</td></tr>
+<tr><td>Due date</td><td>{{.DueDate}}</td></tr>
+<tr><td>Total</td><td>{{.Total}}</td></tr>
+<tr><td>Paid</td><td>{{.Paid}}</td></tr>
+<tr class="due"><td>Balance due</td><td>{{.Balance}}</td></tr>
+</table>
+{{if .Payable}}<form method="post" action="{{.CheckoutURL}}"><button type="submit">Pay {{.Balance}}</button></form>
+{{else}}<p class="note">{{.Note}}</p>{{end}}
+`))
+
+// paymentPageInvoice loads the invoice behind a payment link token
+func paymentPageInvoice(c *gin.Context) (gin.H, bool) {
+	invoiceID, ok := verifyPaymentToken(c.Param("token"))
+	if !ok {
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte(
+			"<!doctype html><meta name=viewport content=\"width=device-width\"><p>This payment link is invalid or has expired.</p>"))
+		return nil, false
+	}
+	invoice, err := loadInvoice(strconv.Itoa(invoiceID))
+	if err != nil {
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte(
+			"<!doctype html><meta name=viewport content=\"width=device-width\"><p>Invoice not found.</p>"))
+		return nil, false
+	}
+	return invoice, true
+}
+
+// getPaymentPage - Public invoice summary with a pay button, opened from a
+// shared payment link
+func getPaymentPage(c *gin.Context) {
+	invoice, ok := paymentPageInvoice(c)
+	if !ok {
+		return
+	}
+
+	currency := invoice["currency"].(string)
+	data := gin.H{
+		"Brand":       brandName(),
+		"Number":      invoice["invoice_number"],
+		"Student":     invoice["student_name"],
+		"Period":      fmt.Sprintf("%s to %s", invoice["cycle_start"], invoice["cycle_end"]),
+		"DueDate":     invoice["due_date"],
+		"Total":       formatMoney(invoice["total"].(float64), currency),
+		"Paid":        formatMoney(invoice["amount_paid"].(float64), currency),
+		"Balance":     formatMoney(invoice["balance"].(float64), currency),
+		"CheckoutURL": c.Request.URL.Path + "/checkout",
+		"Payable":     false,
+	}
+	switch {
+	case invoice["status"] == "void":
+		data["Note"] = "This invoice has been cancelled."
+	case invoice["balance"].(float64) <= 0:
+		data["Note"] = "This invoice is paid. Thank you!"
+	case os.Getenv("PAYMENT_GATEWAY_URL") == "":
+		data["Note"] = "Online payment is not available yet. Please pay by cash or mobile banking."
+	default:
+		data["Payable"] = true
+	}
+
+	var page bytes.Buffer
+	if err := paymentPage.Execute(&page, data); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// startPaymentCheckout - The pay button: opens a checkout for the invoice
+// balance at the gateway in PAYMENT_GATEWAY_URL and redirects to it. The
+// gateway receives {amount, currency, reference, description, success_url,
+// callback_url} and replies {id, checkout_url}.
+func startPaymentCheckout(c *gin.Context) {
+	invoice, ok := paymentPageInvoice(c)
+	if !ok {
+		return
+	}
+	gatewayURL := os.Getenv("PAYMENT_GATEWAY_URL")
+	balance := invoice["balance"].(float64)
+	if invoice["status"] == "void" || balance <= 0 || gatewayURL == "" {
+		c.Redirect(http.StatusSeeOther, strings.TrimSuffix(c.Request.URL.Path, "/checkout"))
+		return
+	}
+
+	baseURL, err := paymentBaseURL()
+	if err != nil {
+		log.Println("Warning: payment checkout:", err)
+		c.String(http.StatusServiceUnavailable, "Online payment is not available right now. Please try again later.")
+		return
+	}
+
+	invoiceID := invoice["id"].(int)
+	currency := invoice["currency"].(string)
+	var checkoutID int
+	if err := db.QueryRow(`
+		INSERT INTO mentor.payment_checkouts (invoice_id, amount, currency) VALUES ($1, $2, $3) RETURNING id
+	`, invoiceID, balance, currency).Scan(&checkoutID); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	pageURL := baseURL + "/api/pay/" + c.Param("token")
+	payload, _ := json.Marshal(map[string]interface{}{
+		"amount":       balance,
+		"currency":     currency,
+		"reference":    fmt.Sprintf("%s/%d", invoice["invoice_number"], checkoutID),
+		"description":  fmt.Sprintf("%s - %s", invoice["invoice_number"], invoice["student_name"]),
+		"success_url":  pageURL,
+		"cancel_url":   pageURL,
+		"callback_url": baseURL + "/api/webhooks/payments",
+	})
+
+	resp, err := paymentClient.Post(gatewayURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Println("Warning: payment gateway:", err)
+		c.String(http.StatusBadGateway, "The payment gateway is not reachable. Please try again later.")
+		return
+	}
+	defer resp.Body.Close()
+
+	var checkout struct {
+		ID          string `json:"id"`
+		CheckoutURL string `json:"checkout_url"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 || json.Unmarshal(body, &checkout) != nil || checkout.ID == "" || checkout.CheckoutURL == "" {
+		log.Printf("Warning: payment gateway returned %d: %s", resp.StatusCode, body)
+		c.String(http.StatusBadGateway, "The payment gateway could not start the payment. Please try again later.")
+		return
+	}
+
+	db.Exec(`
+		UPDATE mentor.payment_checkouts SET gateway_id = $1, checkout_url = $2 WHERE id = $3
+	`, checkout.ID, checkout.CheckoutURL, checkoutID)
+
+	c.Redirect(http.StatusSeeOther, checkout.CheckoutURL)
+}
+
+// paymentWebhook - The gateway reports a checkout's outcome
+// ({id, status: paid|failed, amount, reference, method}). A paid checkout
+// is recorded once as an income transaction against its invoice.
+func paymentWebhook(c *gin.Context) {
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" || !hmac.Equal([]byte(c.GetHeader("X-Webhook-Secret")), []byte(secret)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid webhook secret"})
+		return
+	}
+
+	var input struct {
+		ID        string  `json:"id"`
+		Status    string  `json:"status"`
+		Amount    float64 `json:"amount"`
+		Currency  string  `json:"currency"`
+		Reference string  `json:"reference"` // the gateway's payment id
+		Method    string  `json:"method"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.ID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "id is required"})
+		return
+	}
+
+	if input.Status != "paid" {
+		db.Exec(`
+			UPDATE mentor.payment_checkouts SET status = 'failed', completed_at = NOW()
+			WHERE gateway_id = $1 AND status = 'pending'
+		`, input.ID)
+		c.JSON(http.StatusOK, gin.H{"success": true, "status": "failed"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the checkout so a retried webhook can't record the payment twice
+	var checkoutID, invoiceID, subId int
+	var amount float64
+	var currency, number string
+	err = tx.QueryRow(`
+		SELECT pc.id, pc.invoice_id, pc.amount, pc.currency, i.subscription_id, i.invoice_number
+		FROM mentor.payment_checkouts pc
+		JOIN mentor.invoices i ON i.id = pc.invoice_id
+		WHERE pc.gateway_id = $1 AND pc.status = 'pending'
+		FOR UPDATE OF pc
+	`, input.ID).Scan(&checkoutID, &invoiceID, &amount, &currency, &subId, &number)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "ignored": true, "reason": "unknown or already recorded checkout"})
+		return
+	}
+	// The gateway must have charged exactly what the checkout asked for
+	if math.Abs(input.Amount-amount) >= 0.005 || (input.Currency != "" && !strings.EqualFold(input.Currency, currency)) {
+		tx.Rollback()
+		log.Printf("Warning: payment checkout %d reported %.2f %s, expected %.2f %s", checkoutID, input.Amount, input.Currency, amount, currency)
+		logAudit("invoice", invoiceID, "payment_amount_mismatch", "gateway", gin.H{
+			"checkout_id": checkoutID,
+			"expected":    amount,
+			"reported":    input.Amount,
+			"currency":    input.Currency,
+			"reference":   input.Reference,
+		})
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "amount does not match the checkout"})
+		return
+	}
+	method := strings.ToLower(input.Method)
+	if _, ok := paymentMethods[method]; !ok {
+		method = "other"
+	}
+
+	var txID int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.transactions (date, type, amount, description, category, subscription_id, invoice_id,
+		                                 payment_method, payment_reference, currency)
+		VALUES (CURRENT_DATE, 'income', $1, $2, 'student_fee', $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id
+	`, amount, "Online payment for "+number, subId, invoiceID, method, input.Reference, currency).Scan(&txID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec(`
+		UPDATE mentor.payment_checkouts
+		SET status = 'paid', gateway_reference = NULLIF($1, ''), transaction_id = $2, completed_at = NOW()
+		WHERE id = $3
+	`, input.Reference, txID, checkoutID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("invoice", invoiceID, "paid_online", "gateway", gin.H{
+		"transaction_id": txID,
+		"amount":         amount,
+		"reference":      input.Reference,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "paid", "transaction_id": txID})
+}
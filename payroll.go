@@ -0,0 +1,283 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// PAYROLL (Teacher salary from completed classes)
+// ============================================
+
+// teacherPayroll is one teacher's computed salary for a month
+type teacherPayroll struct {
+	TeacherID       string
+	TeacherName     string
+	PayType         string
+	PayRate         float64
+	Classes         int
+	Subscriptions   []gin.H
+	Cancelled       gin.H   // cancelled sessions by who cancelled
+	LateCancelled   int     // late guardian cancellations, paid like classes
+	VerifiedVisits  int     // check-ins recorded live with GPS
+	CorrectedVisits int     // check-ins added by an approved correction
+	Amount          float64 // gross, from classes and rate
+	Deductions      float64
+	Advances        float64
+	Adjustments     []gin.H
+	TransactionID   sql.NullInt64
+	PaidAt          sql.NullTime // when the salary was handed over
+	PaymentRef      string
+}
+
+// NetPayable is what the salary transaction should pay out
+func (p teacherPayroll) NetPayable() float64 {
+	return p.Amount - p.Deductions - p.Advances
+}
+
+// payrollMonth parses ?year=&month=, defaulting to the previous month
+func payrollMonth(c *gin.Context) (time.Time, bool) {
+	prev := time.Now().AddDate(0, -1, 0)
+	year, err1 := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(prev.Year())))
+	month, err2 := strconv.Atoi(c.DefaultQuery("month", strconv.Itoa(int(prev.Month()))))
+	if err1 != nil || err2 != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local), true
+}
+
+// computePayroll counts the classes a teacher logged in progress during the
+// month and applies their rate: per class, or per subscription taught
+func computePayroll(teacherID string, monthStart time.Time) (teacherPayroll, error) {
+	p := teacherPayroll{TeacherID: teacherID, Subscriptions: []gin.H{}}
+	err := db.QueryRow(`
+		SELECT name, COALESCE(pay_type, 'per_class'), COALESCE(pay_rate, 0)
+		FROM mentor.teachers WHERE id = $1
+	`, teacherID).Scan(&p.TeacherName, &p.PayType, &p.PayRate)
+	if err != nil {
+		return p, err
+	}
+
+	rows, err := db.Query(`
+		SELECT p.subscription_id, COALESCE(s.student_name, ''), COUNT(*)
+		FROM mentor.progress p
+		LEFT JOIN mentor.subscriptions s ON s.id = p.subscription_id
+		WHERE p.teacher_id = $1 AND p.completed_at >= $2 AND p.completed_at < $3
+		GROUP BY p.subscription_id, s.student_name
+		ORDER BY p.subscription_id
+	`, teacherID, monthStart, monthStart.AddDate(0, 1, 0))
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subId, classes int
+		var studentName string
+		if err := rows.Scan(&subId, &studentName, &classes); err != nil {
+			continue
+		}
+		p.Classes += classes
+		p.Subscriptions = append(p.Subscriptions, gin.H{
+			"subscription_id": subId,
+			"student_name":    studentName,
+			"classes":         classes,
+		})
+	}
+
+	err = db.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE NOT is_corrected), COUNT(*) FILTER (WHERE is_corrected)
+		FROM mentor.attendance
+		WHERE teacher_id = $1 AND action = 'start' AND recorded_at >= $2 AND recorded_at < $3
+	`, teacherID, monthStart, monthStart.AddDate(0, 1, 0)).Scan(&p.VerifiedVisits, &p.CorrectedVisits)
+	if err != nil {
+		return p, err
+	}
+
+	p.Cancelled, p.LateCancelled, err = loadCancelledSessions(teacherID, monthStart)
+	if err != nil {
+		return p, err
+	}
+
+	if p.PayType == "per_subscription" {
+		p.Amount = p.PayRate * float64(len(p.Subscriptions))
+	} else {
+		p.Amount = p.PayRate * float64(p.Classes+p.LateCancelled)
+	}
+
+	p.Adjustments, p.Deductions, p.Advances, err = loadPayAdjustments(teacherID, monthStart)
+	if err != nil {
+		return p, err
+	}
+
+	db.QueryRow(`
+		SELECT id, paid_at, COALESCE(payment_reference, '') FROM mentor.transactions
+		WHERE teacher_id = $1 AND payroll_month = $2 AND voided_at IS NULL
+	`, teacherID, monthStart).Scan(&p.TransactionID, &p.PaidAt, &p.PaymentRef)
+
+	return p, nil
+}
+
+func (p teacherPayroll) toJSON(monthStart time.Time) gin.H {
+	result := gin.H{
+		"teacher_id":     p.TeacherID,
+		"teacher_name":   p.TeacherName,
+		"year":           monthStart.Year(),
+		"month":          int(monthStart.Month()),
+		"pay_type":       p.PayType,
+		"pay_rate":       p.PayRate,
+		"classes":        p.Classes,
+		"subscriptions":  p.Subscriptions,
+		"cancelled":      p.Cancelled,
+		"late_cancelled": p.LateCancelled,
+		"visits":         gin.H{"verified": p.VerifiedVisits, "corrected": p.CorrectedVisits},
+		"amount":         p.Amount,
+		"deductions":     p.Deductions,
+		"advances":       p.Advances,
+		"adjustments":    p.Adjustments,
+		"net_payable":    p.NetPayable(),
+		"currency":       currencyCode(),
+	}
+	if p.TransactionID.Valid {
+		result["transaction_id"] = p.TransactionID.Int64
+		result["payout_status"] = "pending"
+	}
+	if p.PaidAt.Valid {
+		result["payout_status"] = "paid"
+		result["paid_at"] = isoTimestamp(p.PaidAt.Time)
+		result["payment_reference"] = p.PaymentRef
+	}
+	return result
+}
+
+// createPayrollTransaction records the salary as an expense transaction;
+// returns false when one already exists for the teacher and month
+func createPayrollTransaction(p teacherPayroll, monthStart time.Time) (int, bool, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.transactions (date, type, amount, description, category, teacher_id, payroll_month)
+		VALUES (CURRENT_DATE, 'expense', $1, $2, 'teacher_salary', $3, $4)
+		ON CONFLICT (teacher_id, payroll_month) WHERE payroll_month IS NOT NULL AND voided_at IS NULL DO NOTHING
+		RETURNING id
+	`, p.NetPayable(), fmt.Sprintf("Salary %s - %s (%d classes)", monthStart.Format("January 2006"), p.TeacherName, p.Classes),
+		p.TeacherID, monthStart).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+// getPayroll - Salary for a teacher and month, computed from completed classes
+func getPayroll(c *gin.Context) {
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+
+	p, err := computePayroll(c.Param("teacherId"), monthStart)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "payroll": p.toJSON(monthStart)})
+}
+
+// createPayroll - Compute the salary and record it as an expense transaction
+func createPayroll(c *gin.Context) {
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+
+	p, err := computePayroll(c.Param("teacherId"), monthStart)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if p.TransactionID.Valid {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Salary already recorded for this month", "payroll": p.toJSON(monthStart)})
+		return
+	}
+	if p.NetPayable() <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Nothing payable (no classes, no pay rate set, or covered by advances and deductions)", "payroll": p.toJSON(monthStart)})
+		return
+	}
+
+	id, created, err := createPayrollTransaction(p, monthStart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Salary already recorded for this month"})
+		return
+	}
+	p.TransactionID = sql.NullInt64{Int64: int64(id), Valid: true}
+
+	logAudit("teacher", p.TeacherID, "payroll_recorded", c.Query("created_by"), gin.H{
+		"month":          monthStart.Format("2006-01"),
+		"amount":         p.NetPayable(),
+		"classes":        p.Classes,
+		"transaction_id": id,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "payroll": p.toJSON(monthStart), "message": "Salary transaction created"})
+}
+
+// updateTeacherPayRate - Set how a teacher is paid
+func updateTeacherPayRate(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var input struct {
+		PayType string  `json:"pay_type"` // "per_class" (default) or "per_subscription"
+		PayRate float64 `json:"pay_rate"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.PayType == "" {
+		input.PayType = "per_class"
+	}
+	if input.PayType != "per_class" && input.PayType != "per_subscription" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "pay_type must be 'per_class' or 'per_subscription'"})
+		return
+	}
+	if input.PayRate < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "pay_rate must not be negative"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.teachers SET pay_type = $1, pay_rate = $2 WHERE id = $3
+	`, input.PayType, input.PayRate, teacherID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "pay_type": input.PayType, "pay_rate": input.PayRate, "message": "Pay rate updated"})
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// PAYROLL RUN (All teachers at once, payouts)
+// ============================================
+
+// runPayroll - Compute every teacher's salary for ?year=&month= (default last
+// month) and record each net payable as a pending teacher_salary expense.
+// Teachers already recorded or with nothing payable are listed but skipped;
+// ?dry_run=true only computes.
+func runPayroll(c *gin.Context) {
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	// Inactive teachers are included if they taught during the month
+	rows, err := db.Query(`
+		SELECT t.id FROM mentor.teachers t
+		WHERE t.active = 1 OR EXISTS (
+			SELECT 1 FROM mentor.progress p
+			WHERE p.teacher_id = t.id AND p.completed_at >= $1 AND p.completed_at < $2
+		)
+		ORDER BY t.name
+	`, monthStart, monthStart.AddDate(0, 1, 0))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	var teacherIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			teacherIDs = append(teacherIDs, id)
+		}
+	}
+	rows.Close()
+
+	created, existing, skipped := []gin.H{}, []gin.H{}, []gin.H{}
+	total := 0.0
+	for _, teacherID := range teacherIDs {
+		p, err := computePayroll(teacherID, monthStart)
+		if err != nil {
+			skipped = append(skipped, gin.H{"teacher_id": teacherID, "reason": err.Error()})
+			continue
+		}
+		entry := p.toJSON(monthStart)
+		if p.TransactionID.Valid {
+			existing = append(existing, entry)
+			continue
+		}
+		if p.NetPayable() <= 0 {
+			entry["reason"] = "nothing payable"
+			skipped = append(skipped, entry)
+			continue
+		}
+		if dryRun {
+			created = append(created, entry)
+			total += p.NetPayable()
+			continue
+		}
+
+		id, ok, err := createPayrollTransaction(p, monthStart)
+		if err != nil || !ok {
+			if err == nil {
+				err = fmt.Errorf("salary already recorded")
+			}
+			entry["reason"] = err.Error()
+			skipped = append(skipped, entry)
+			continue
+		}
+		p.TransactionID = sql.NullInt64{Int64: int64(id), Valid: true}
+		created = append(created, p.toJSON(monthStart))
+		total += p.NetPayable()
+
+		logAudit("teacher", p.TeacherID, "payroll_recorded", c.Query("created_by"), gin.H{
+			"month":          monthStart.Format("2006-01"),
+			"amount":         p.NetPayable(),
+			"classes":        p.Classes,
+			"transaction_id": id,
+			"run":            true,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"year":     monthStart.Year(),
+		"month":    int(monthStart.Month()),
+		"dry_run":  dryRun,
+		"created":  created,
+		"existing": existing,
+		"skipped":  skipped,
+		"total":    roundMoney(total),
+		"currency": currencyCode(),
+	})
+}
+
+// getPayouts - Salary transactions for ?year=&month= (default last month),
+// with ?status=pending|paid
+func getPayouts(c *gin.Context) {
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+
+	query := `
+		SELECT tr.id, tr.teacher_id, COALESCE(t.name, ''), tr.amount, tr.date, tr.paid_at,
+		       COALESCE(tr.paid_by, ''), COALESCE(tr.payment_reference, ''), COALESCE(tr.payment_method, '')
+		FROM mentor.transactions tr
+		LEFT JOIN mentor.teachers t ON t.id = tr.teacher_id
+		WHERE tr.payroll_month = $1 AND tr.voided_at IS NULL
+	`
+	switch c.Query("status") {
+	case "":
+	case "pending":
+		query += " AND tr.paid_at IS NULL"
+	case "paid":
+		query += " AND tr.paid_at IS NOT NULL"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "status must be 'pending' or 'paid'"})
+		return
+	}
+	query += " ORDER BY t.name"
+
+	rows, err := db.Query(query, monthStart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	payouts := []gin.H{}
+	pending, paid := 0.0, 0.0
+	for rows.Next() {
+		var id int
+		var teacherID, teacherName, paidBy, reference, method string
+		var amount float64
+		var date time.Time
+		var paidAt sql.NullTime
+		if err := rows.Scan(&id, &teacherID, &teacherName, &amount, &date, &paidAt, &paidBy, &reference, &method); err != nil {
+			continue
+		}
+		payout := gin.H{
+			"transaction_id": id,
+			"teacher_id":     teacherID,
+			"teacher_name":   teacherName,
+			"amount":         amount,
+			"recorded_on":    date.Format("2006-01-02"),
+			"status":         "pending",
+		}
+		if paidAt.Valid {
+			payout["status"] = "paid"
+			payout["paid_at"] = isoTimestamp(paidAt.Time)
+			payout["paid_by"] = paidBy
+			payout["payment_reference"] = reference
+			payout["payment_method"] = method
+			paid += amount
+		} else {
+			pending += amount
+		}
+		payouts = append(payouts, payout)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"year":    monthStart.Year(),
+		"month":   int(monthStart.Month()),
+		"payouts": payouts,
+		"count":   len(payouts),
+		"totals":  gin.H{"pending": roundMoney(pending), "paid": roundMoney(paid), "currency": currencyCode()},
+	})
+}
+
+// markPayoutsPaid - Record that salaries were handed over, each with its own
+// payment reference
+func markPayoutsPaid(c *gin.Context) {
+	var input struct {
+		Payouts []struct {
+			TransactionID    int    `json:"transaction_id"`
+			PaymentReference string `json:"payment_reference"`
+			PaymentMethod    string `json:"payment_method"`
+		} `json:"payouts"`
+		PaidBy string `json:"paid_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if len(input.Payouts) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "payouts is required"})
+		return
+	}
+
+	fields := map[string]string{}
+	for i, p := range input.Payouts {
+		if p.TransactionID <= 0 {
+			fields[fmt.Sprintf("payouts[%d].transaction_id", i)] = "is required"
+		}
+		if p.PaymentReference == "" && p.PaymentMethod != "cash" {
+			fields[fmt.Sprintf("payouts[%d].payment_reference", i)] = "is required unless paid in cash"
+		}
+		if _, ok := paymentMethods[p.PaymentMethod]; p.PaymentMethod != "" && !ok {
+			fields[fmt.Sprintf("payouts[%d].payment_method", i)] = "is not a known payment method"
+		}
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	results := []gin.H{}
+	paidCount := 0
+	for _, p := range input.Payouts {
+		var teacherID string
+		var month time.Time
+		err := db.QueryRow(`
+			UPDATE mentor.transactions
+			SET paid_at = NOW(), paid_by = $1, payment_reference = NULLIF($2, ''),
+			    payment_method = COALESCE(NULLIF($3, ''), payment_method)
+			WHERE id = $4 AND payroll_month IS NOT NULL AND paid_at IS NULL AND voided_at IS NULL
+			RETURNING teacher_id, payroll_month
+		`, input.PaidBy, p.PaymentReference, p.PaymentMethod, p.TransactionID).Scan(&teacherID, &month)
+		if err == sql.ErrNoRows {
+			results = append(results, gin.H{"transaction_id": p.TransactionID, "status": "skipped", "error": "Not a pending salary payout"})
+			continue
+		}
+		if err != nil {
+			results = append(results, gin.H{"transaction_id": p.TransactionID, "status": "skipped", "error": err.Error()})
+			continue
+		}
+		paidCount++
+		results = append(results, gin.H{"transaction_id": p.TransactionID, "teacher_id": teacherID, "status": "paid"})
+
+		logAudit("teacher", teacherID, "payroll_paid", input.PaidBy, gin.H{
+			"month":             month.Format("2006-01"),
+			"transaction_id":    p.TransactionID,
+			"payment_reference": p.PaymentReference,
+			"payment_method":    p.PaymentMethod,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "paid": paidCount, "results": results})
+}
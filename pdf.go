@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ============================================
+// PDF (Minimal A4 writer with the standard Helvetica fonts)
+// ============================================
+
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+// pdfDoc builds a PDF page by page. Coordinates are in points from the top
+// left corner. Only the built-in Helvetica fonts are used, so text outside
+// Latin-1 is shown as "?".
+type pdfDoc struct {
+	pages []*bytes.Buffer
+}
+
+// newPDF starts a document with one blank page
+func newPDF() *pdfDoc {
+	d := &pdfDoc{}
+	d.AddPage()
+	return d
+}
+
+func (d *pdfDoc) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDoc) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// pdfLatin1 maps s to Latin-1 bytes and escapes PDF string delimiters
+func pdfLatin1(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// pdfTextWidth approximates the width of s in Helvetica at size; exact for
+// digits and punctuation, close enough for letters
+func pdfTextWidth(s string, size float64, bold bool) float64 {
+	units := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == ' ' || r == '.' || r == ',' || r == ':' || r == 'i' || r == 'l':
+			units += 278
+		case r == '-' || r == '(' || r == ')':
+			units += 333
+		case r >= 'A' && r <= 'Z':
+			units += 667
+		default:
+			units += 556
+		}
+	}
+	if bold {
+		units = units * 105 / 100
+	}
+	return float64(units) * size / 1000
+}
+
+// pdfWrap splits s into lines that fit width at size, breaking between words
+func pdfWrap(s string, size float64, bold bool, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && pdfTextWidth(line+" "+word, size, bold) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Text writes s with its baseline at (x, y)
+func (d *pdfDoc) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, pdfPageHeight-y, pdfLatin1(s))
+}
+
+// TextRight writes s so that it ends at x
+func (d *pdfDoc) TextRight(x, y, size float64, bold bool, s string) {
+	d.Text(x-pdfTextWidth(s, size, bold), y, size, bold, s)
+}
+
+// SetColor sets the fill colour for following text and rectangles (0-255)
+func (d *pdfDoc) SetColor(r, g, b int) {
+	fmt.Fprintf(d.page(), "%.3f %.3f %.3f rg\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// FillRect fills a rectangle whose top left corner is (x, y)
+func (d *pdfDoc) FillRect(x, y, w, h float64) {
+	fmt.Fprintf(d.page(), "%.2f %.2f %.2f %.2f re f\n", x, pdfPageHeight-y-h, w, h)
+}
+
+// Line draws a thin grey rule
+func (d *pdfDoc) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.8 0.8 0.8 RG 0.7 w %.2f %.2f m %.2f %.2f l S\n", x1, pdfPageHeight-y1, x2, pdfPageHeight-y2)
+}
+
+// Bytes assembles the document
+func (d *pdfDoc) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	// 1 catalog, 2 pages, 3-4 fonts, then a page and its content per page
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
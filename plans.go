@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// SUBSCRIPTION PLANS (Reusable templates)
+// ============================================
+
+type subscriptionPlan struct {
+	ID           int
+	Name         string
+	Class        sql.NullInt64
+	Subjects     []string
+	DaysPerWeek  int
+	Amount       float64
+	TotalClasses sql.NullInt64
+	Active       bool
+}
+
+const planColumns = `id, name, class, subject_list, days_per_week, amount, total_classes, active`
+
+func scanPlan(row interface{ Scan(...interface{}) error }) (subscriptionPlan, error) {
+	var p subscriptionPlan
+	err := row.Scan(&p.ID, &p.Name, &p.Class, pq.Array(&p.Subjects), &p.DaysPerWeek, &p.Amount, &p.TotalClasses, &p.Active)
+	return p, err
+}
+
+func loadPlan(id int) (subscriptionPlan, error) {
+	return scanPlan(db.QueryRow("SELECT "+planColumns+" FROM mentor.plans WHERE id = $1", id))
+}
+
+func (p subscriptionPlan) toJSON() gin.H {
+	plan := gin.H{
+		"id":            p.ID,
+		"name":          p.Name,
+		"class":         nil,
+		"subjects":      p.Subjects,
+		"days_per_week": p.DaysPerWeek,
+		"amount":        p.Amount,
+		"total_classes": nil,
+		"active":        p.Active,
+	}
+	if p.Class.Valid {
+		plan["class"] = p.Class.Int64
+	}
+	if p.TotalClasses.Valid {
+		plan["total_classes"] = p.TotalClasses.Int64
+	}
+	return plan
+}
+
+type planInput struct {
+	Name         string     `json:"name"`
+	Class        *int       `json:"class"`
+	Subjects     stringList `json:"subjects"`
+	DaysPerWeek  int        `json:"days_per_week"`
+	Amount       float64    `json:"amount"`
+	TotalClasses *int       `json:"total_classes"`
+}
+
+func (in planInput) validate() string {
+	switch {
+	case in.Name == "":
+		return "name is required"
+	case len(in.Subjects) == 0:
+		return "subjects are required"
+	case in.DaysPerWeek < 0 || in.DaysPerWeek > 7:
+		return "days_per_week must be between 0 and 7"
+	case in.Amount < 0:
+		return "amount must not be negative"
+	case in.TotalClasses != nil && *in.TotalClasses < 1:
+		return "total_classes must be at least 1"
+	}
+	return ""
+}
+
+// getPlans - Active plans (include_inactive=true for all), optionally for one class
+func getPlans(c *gin.Context) {
+	query := "SELECT " + planColumns + " FROM mentor.plans WHERE 1=1"
+	args := []interface{}{}
+
+	if c.Query("include_inactive") != "true" {
+		query += " AND active"
+	}
+	if class := c.Query("class"); class != "" {
+		args = append(args, class)
+		query += " AND (class IS NULL OR class = $1)"
+	}
+	query += " ORDER BY class NULLS FIRST, name"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	plans := []gin.H{}
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			continue
+		}
+		plans = append(plans, p.toJSON())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "plans": plans})
+}
+
+func getPlan(c *gin.Context) {
+	p, err := scanPlan(db.QueryRow("SELECT "+planColumns+" FROM mentor.plans WHERE id = $1", c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Plan not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "plan": p.toJSON()})
+}
+
+func createPlan(c *gin.Context) {
+	var input planInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": msg})
+		return
+	}
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.plans (name, class, subject_list, days_per_week, amount, total_classes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, input.Name, input.Class, pq.Array(input.Subjects), input.DaysPerWeek, input.Amount, input.TotalClasses).Scan(&id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "message": "Plan created"})
+}
+
+// updatePlan - Replace a plan's fields; existing subscriptions keep what they were created with
+func updatePlan(c *gin.Context) {
+	var input planInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": msg})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.plans
+		SET name = $1, class = $2, subject_list = $3, days_per_week = $4, amount = $5,
+		    total_classes = $6, updated_at = NOW()
+		WHERE id = $7
+	`, input.Name, input.Class, pq.Array(input.Subjects), input.DaysPerWeek, input.Amount, input.TotalClasses, c.Param("id"))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Plan not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Plan updated"})
+}
+
+// deletePlan - Retire a plan; subscriptions created from it keep their plan_id
+func deletePlan(c *gin.Context) {
+	result, err := db.Exec("UPDATE mentor.plans SET active = FALSE, updated_at = NOW() WHERE id = $1", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Plan not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Plan retired"})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// BULK PROGRESS RECOMPUTATION
+// ============================================
+
+// recomputeProgress - Admin: recalculate total_classes, completed_classes and
+// progress_percent from the chapters table and schedule rows, in one
+// transaction. Plan-fixed total_classes are kept. dry_run reports without saving.
+// Recomputed subscriptions lose their progress_stale flag.
+func recomputeProgress(c *gin.Context) {
+	var input struct {
+		SubscriptionIDs []int  `json:"subscription_ids"`
+		TeacherID       string `json:"teacher_id"`
+		Class           int    `json:"class"`
+		Status          string `json:"status"`     // default "active"; "all" for every status
+		StaleOnly       bool   `json:"stale_only"` // only subscriptions flagged by a syllabus change
+		DryRun          bool   `json:"dry_run"`
+		Actor           string `json:"actor"`
+	}
+	c.ShouldBindJSON(&input)
+	if input.Status == "" {
+		input.Status = "active"
+	}
+
+	query := `
+		SELECT s.id, s.student_name, s.class, s.subject_list, s.total_classes, s.completed_classes,
+		       COALESCE(s.progress_percent, 0), p.total_classes
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.plans p ON p.id = s.plan_id
+		WHERE s.deleted_at IS NULL
+	`
+	args := []interface{}{}
+	argCount := 0
+
+	if input.Status != "all" {
+		argCount++
+		query += fmt.Sprintf(" AND s.status = $%d", argCount)
+		args = append(args, input.Status)
+	}
+	if len(input.SubscriptionIDs) > 0 {
+		argCount++
+		query += fmt.Sprintf(" AND s.id = ANY($%d)", argCount)
+		args = append(args, pq.Array(input.SubscriptionIDs))
+	}
+	if input.TeacherID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND s.teacher_id = $%d", argCount)
+		args = append(args, input.TeacherID)
+	}
+	if input.Class != 0 {
+		argCount++
+		query += fmt.Sprintf(" AND s.class = $%d", argCount)
+		args = append(args, input.Class)
+	}
+	if input.StaleOnly {
+		query += " AND s.progress_stale"
+	}
+	query += " ORDER BY s.id FOR UPDATE OF s"
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	type target struct {
+		id, class, totalClasses, completedClasses int
+		studentName                               string
+		subjects                                  []string
+		progressPercent                           float64
+		planTotal                                 sql.NullInt64
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.studentName, &t.class, pq.Array(&t.subjects), &t.totalClasses,
+			&t.completedClasses, &t.progressPercent, &t.planTotal); err != nil {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+
+	changes := []gin.H{}
+	scheduleRowsFixed := int64(0)
+	for _, t := range targets {
+		// Schedule rows follow the chapters table too
+		schedRows, err := tx.Query("SELECT id, subject, total_parts_needed FROM mentor.schedule WHERE subscription_id = $1", t.id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		type schedFix struct{ id, needed int }
+		var fixes []schedFix
+		for schedRows.Next() {
+			var id, needed int
+			var subject string
+			if err := schedRows.Scan(&id, &subject, &needed); err != nil {
+				continue
+			}
+			if chapters := subjectChapterCount(t.class, subject); chapters != needed {
+				fixes = append(fixes, schedFix{id, chapters})
+			}
+		}
+		schedRows.Close()
+		for _, f := range fixes {
+			if _, err := tx.Exec("UPDATE mentor.schedule SET total_parts_needed = $1 WHERE id = $2", f.needed, f.id); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+				return
+			}
+		}
+		scheduleRowsFixed += int64(len(fixes))
+
+		totalClasses := countTotalClasses(t.class, t.subjects)
+		if t.planTotal.Valid {
+			totalClasses = int(t.planTotal.Int64)
+		}
+
+		// As in updateSubscriptionProgress: repeated parts are classes held
+		// but not progress
+		var completed, covered int
+		tx.QueryRow(`
+			SELECT COALESCE(SUM(total_parts_done + repeat_sessions), 0), COALESCE(SUM(total_parts_done), 0)
+			FROM mentor.schedule WHERE subscription_id = $1
+		`, t.id).Scan(&completed, &covered)
+
+		percent := float64(0)
+		if totalClasses > 0 {
+			percent = float64(covered) / float64(totalClasses) * 100
+		}
+
+		if totalClasses == t.totalClasses && completed == t.completedClasses &&
+			math.Abs(percent-t.progressPercent) < 0.01 {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			UPDATE mentor.subscriptions
+			SET total_classes = $1, completed_classes = $2, progress_percent = $3, updated_at = NOW()
+			WHERE id = $4
+		`, totalClasses, completed, percent, t.id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		changes = append(changes, gin.H{
+			"subscription_id": t.id,
+			"student_name":    t.studentName,
+			"before": gin.H{
+				"total_classes":     t.totalClasses,
+				"completed_classes": t.completedClasses,
+				"progress_percent":  t.progressPercent,
+			},
+			"after": gin.H{
+				"total_classes":     totalClasses,
+				"completed_classes": completed,
+				"progress_percent":  percent,
+			},
+		})
+	}
+
+	if !input.DryRun {
+		ids := make([]int, len(targets))
+		for i, t := range targets {
+			ids[i] = t.id
+		}
+		_, err := tx.Exec(`
+			UPDATE mentor.subscriptions SET progress_stale = FALSE, progress_stale_since = NULL
+			WHERE id = ANY($1) AND progress_stale
+		`, pq.Array(ids))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		logAudit("subscription", "bulk", "progress_recomputed", input.Actor, gin.H{
+			"examined":      len(targets),
+			"changed":       len(changes),
+			"schedule_rows": scheduleRowsFixed,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":               true,
+		"dry_run":               input.DryRun,
+		"examined":              len(targets),
+		"changed":               len(changes),
+		"schedule_rows_updated": scheduleRowsFixed,
+		"changes":               changes,
+	})
+}
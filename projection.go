@@ -0,0 +1,211 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// COMPLETION PROJECTION
+// ============================================
+
+// maxProjectionDays stops the projection walking forever for subscriptions
+// with no usable schedule days
+const maxProjectionDays = 3 * 365
+
+// projectCompletion estimates the date of the last remaining class, one class
+// per scheduled day from tomorrow, skipping holidays, the teacher's blackout
+// dates, the subscription's pauses and cancelled days
+func projectCompletion(subId int) (gin.H, error) {
+	var teacherID string
+	var scheduleDays []string
+	var totalClasses, completedClasses int
+	err := db.QueryRow(`
+		SELECT teacher_id, schedule_day_list, total_classes, completed_classes
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, subId).Scan(&teacherID, pq.Array(&scheduleDays), &totalClasses, &completedClasses)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := totalClasses - completedClasses
+	projection := gin.H{
+		"total_classes":     totalClasses,
+		"completed_classes": completedClasses,
+		"remaining_classes": remaining,
+	}
+	if remaining <= 0 {
+		projection["remaining_classes"] = 0
+		projection["projected_end_date"] = time.Now().Format("2006-01-02")
+		return projection, nil
+	}
+
+	weekdays := scheduledWeekdays(scheduleDays)
+	if len(weekdays) == 0 {
+		projection["projected_end_date"] = nil
+		projection["reason"] = "No schedule days set"
+		return projection, nil
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	to := from.AddDate(0, 0, maxProjectionDays)
+
+	// Every day that can't have a class, whichever the reason
+	skip := map[string]string{}
+	rows, err := db.Query(`
+		SELECT date, 'holiday' FROM mentor.holidays WHERE date >= $1 AND date < $2 AND (teacher_id IS NULL OR teacher_id = $3)
+		UNION ALL
+		SELECT date, 'teacher_leave' FROM mentor.teacher_blackouts WHERE teacher_id = $3 AND date >= $1 AND date < $2
+		UNION ALL
+		SELECT d::date, 'pause'
+		FROM mentor.subscription_pauses p, generate_series(p.start_date, p.end_date, INTERVAL '1 day') d
+		WHERE p.subscription_id = $4 AND p.end_date >= $1 AND p.start_date < $2
+		UNION ALL
+		SELECT date, 'cancelled' FROM mentor.class_cancellations WHERE subscription_id = $4 AND date >= $1 AND date < $2
+	`, from, to, teacherID, subId)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d time.Time
+		var reason string
+		rows.Scan(&d, &reason)
+		skip[d.Format("2006-01-02")] = reason
+	}
+	rows.Close()
+
+	skipped := map[string]int{}
+	left := remaining
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		if !weekdays[d.Weekday()] {
+			continue
+		}
+		if reason, ok := skip[d.Format("2006-01-02")]; ok {
+			skipped[reason]++
+			continue
+		}
+		left--
+		if left == 0 {
+			projection["projected_end_date"] = d.Format("2006-01-02")
+			projection["skipped_days"] = skipped
+			return projection, nil
+		}
+	}
+
+	projection["projected_end_date"] = nil
+	projection["reason"] = "More than 3 years of classes remaining"
+	return projection, nil
+}
+
+// getSubscriptionProjection - Projected end date with the days skipped on the way
+func getSubscriptionProjection(c *gin.Context) {
+	var subId int
+	if err := db.QueryRow("SELECT id FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL", c.Param("id")).Scan(&subId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	projection, err := projectCompletion(subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "projection": projection})
+}
+
+// ============================================
+// SUBSCRIPTION PAUSES
+// ============================================
+func addSubscriptionPause(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		StartDate string `json:"start_date"` // YYYY-MM-DD
+		EndDate   string `json:"end_date"`   // YYYY-MM-DD, inclusive
+		Reason    string `json:"reason"`
+		CreatedBy string `json:"created_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	start, err1 := time.Parse("2006-01-02", input.StartDate)
+	end, err2 := time.Parse("2006-01-02", input.EndDate)
+	if err1 != nil || err2 != nil || end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "start_date and end_date must be YYYY-MM-DD with end_date on or after start_date"})
+		return
+	}
+
+	var pauseID int
+	err := db.QueryRow(`
+		INSERT INTO mentor.subscription_pauses (subscription_id, start_date, end_date, reason, created_by)
+		SELECT id, $2, $3, $4, $5 FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`, id, input.StartDate, input.EndDate, input.Reason, input.CreatedBy).Scan(&pauseID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	logAudit("subscription", id, "paused", input.CreatedBy, gin.H{
+		"start_date": input.StartDate,
+		"end_date":   input.EndDate,
+		"reason":     input.Reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": pauseID, "message": "Pause added"})
+}
+
+func getSubscriptionPauses(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT id, start_date, end_date, COALESCE(reason, ''), COALESCE(created_by, '')
+		FROM mentor.subscription_pauses WHERE subscription_id = $1
+		ORDER BY start_date DESC
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	pauses := []gin.H{}
+	for rows.Next() {
+		var id int
+		var start, end time.Time
+		var reason, createdBy string
+		if err := rows.Scan(&id, &start, &end, &reason, &createdBy); err != nil {
+			continue
+		}
+		pauses = append(pauses, gin.H{
+			"id":         id,
+			"start_date": start.Format("2006-01-02"),
+			"end_date":   end.Format("2006-01-02"),
+			"reason":     reason,
+			"created_by": createdBy,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "pauses": pauses})
+}
+
+func deleteSubscriptionPause(c *gin.Context) {
+	result, err := db.Exec("DELETE FROM mentor.subscription_pauses WHERE id = $1 AND subscription_id = $2",
+		c.Param("pauseId"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Pause not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Pause removed"})
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// QUESTION BANK (manual and AI-generated)
+// ============================================
+
+var questionTypes = map[string]bool{"mcq": true, "true_false": true, "short": true, "long": true}
+
+// bankQuestion is a question as stored in the bank and accepted by the API
+type bankQuestion struct {
+	ID            int      `json:"id"`
+	Class         int      `json:"class"`
+	Subject       string   `json:"subject"`
+	ChapterNumber *int     `json:"chapter_number"`
+	Type          string   `json:"question_type"`
+	Prompt        string   `json:"prompt"`
+	Options       []string `json:"options"`
+	CorrectOption *int     `json:"correct_option"` // mcq, 0-based
+	Answer        string   `json:"answer"`
+	Marks         float64  `json:"marks"`
+	Explanation   string   `json:"explanation"`
+	Source        string   `json:"source"`
+	CreatedBy     string   `json:"created_by"`
+}
+
+// fieldErrors checks the question is complete for its type; marks default to 1
+func (q *bankQuestion) fieldErrors() map[string]string {
+	fields := map[string]string{}
+	q.Subject = strings.TrimSpace(q.Subject)
+	q.Prompt = strings.TrimSpace(q.Prompt)
+	q.Answer = strings.TrimSpace(q.Answer)
+	if q.Marks == 0 {
+		q.Marks = 1
+	}
+
+	if q.Class <= 0 {
+		fields["class"] = "is required"
+	}
+	if q.Subject == "" {
+		fields["subject"] = "is required"
+	}
+	if q.Prompt == "" {
+		fields["prompt"] = "is required"
+	}
+	if q.Marks < 0 {
+		fields["marks"] = "must be positive"
+	}
+
+	switch q.Type {
+	case "mcq":
+		if len(q.Options) < 2 {
+			fields["options"] = "needs at least 2 choices"
+		}
+		if q.CorrectOption == nil || *q.CorrectOption < 0 || *q.CorrectOption >= len(q.Options) {
+			fields["correct_option"] = "must be the index of one of the options"
+		}
+	case "true_false":
+		q.Answer = strings.ToLower(q.Answer)
+		if q.Answer != "true" && q.Answer != "false" {
+			fields["answer"] = "must be 'true' or 'false'"
+		}
+	case "short":
+		if q.Answer == "" {
+			fields["answer"] = "is required for short answers"
+		}
+	case "long":
+	default:
+		fields["question_type"] = "must be 'mcq', 'true_false', 'short' or 'long'"
+	}
+	if q.Type != "mcq" {
+		q.Options, q.CorrectOption = nil, nil
+	}
+	return fields
+}
+
+// insertQuestion saves a validated question and sets its id
+func insertQuestion(q *bankQuestion) error {
+	var options interface{}
+	if q.Options != nil {
+		b, _ := json.Marshal(q.Options)
+		options = string(b)
+	}
+	if q.Source == "" {
+		q.Source = "manual"
+	}
+	return db.QueryRow(`
+		INSERT INTO mentor.questions (class, subject, chapter_number, question_type, prompt, options,
+		                              correct_option, answer, marks, explanation, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), $11, NULLIF($12, ''))
+		RETURNING id
+	`, q.Class, q.Subject, q.ChapterNumber, q.Type, q.Prompt, options,
+		q.CorrectOption, q.Answer, q.Marks, q.Explanation, q.Source, q.CreatedBy).Scan(&q.ID)
+}
+
+const bankQuestionColumns = `q.id, q.class, q.subject, q.chapter_number, q.question_type, q.prompt,
+	COALESCE(q.options::text, ''), q.correct_option, COALESCE(q.answer, ''), q.marks,
+	COALESCE(q.explanation, ''), q.source, COALESCE(q.created_by, '')`
+
+// scanBankQuestion reads the bankQuestionColumns of one row
+func scanBankQuestion(scan func(...interface{}) error) (bankQuestion, error) {
+	var q bankQuestion
+	var chapter, correct sql.NullInt64
+	var options string
+	err := scan(&q.ID, &q.Class, &q.Subject, &chapter, &q.Type, &q.Prompt,
+		&options, &correct, &q.Answer, &q.Marks, &q.Explanation, &q.Source, &q.CreatedBy)
+	if err != nil {
+		return q, err
+	}
+	if chapter.Valid {
+		n := int(chapter.Int64)
+		q.ChapterNumber = &n
+	}
+	if correct.Valid {
+		n := int(correct.Int64)
+		q.CorrectOption = &n
+	}
+	if options != "" {
+		json.Unmarshal([]byte(options), &q.Options)
+	}
+	return q, nil
+}
+
+// getQuestions - The question bank (class, subject, chapter, question_type, source filters)
+func getQuestions(c *gin.Context) {
+	query := "SELECT " + bankQuestionColumns + " FROM mentor.questions q WHERE 1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	for param, column := range map[string]string{
+		"class":         "q.class",
+		"subject":       "q.subject",
+		"chapter":       "q.chapter_number",
+		"question_type": "q.question_type",
+		"source":        "q.source",
+	} {
+		if value := c.Query(param); value != "" {
+			argCount++
+			query += fmt.Sprintf(" AND %s = $%d", column, argCount)
+			args = append(args, value)
+		}
+	}
+	query += " ORDER BY q.class, q.subject, q.chapter_number NULLS LAST, q.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	questions := []bankQuestion{}
+	for rows.Next() {
+		q, err := scanBankQuestion(rows.Scan)
+		if err != nil {
+			continue
+		}
+		questions = append(questions, q)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "questions": questions})
+}
+
+// createQuestion - Add a question to the bank
+func createQuestion(c *gin.Context) {
+	var input bankQuestion
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if fields := input.fieldErrors(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	input.Source = "manual"
+	if err := insertQuestion(&input); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "question": input})
+}
+
+// updateQuestion - Replace a bank question; quizzes already taken keep their
+// recorded answers and marks
+func updateQuestion(c *gin.Context) {
+	var input bankQuestion
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if fields := input.fieldErrors(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	var options interface{}
+	if input.Options != nil {
+		b, _ := json.Marshal(input.Options)
+		options = string(b)
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.questions
+		SET class = $1, subject = $2, chapter_number = $3, question_type = $4, prompt = $5, options = $6,
+		    correct_option = $7, answer = NULLIF($8, ''), marks = $9, explanation = NULLIF($10, ''), updated_at = NOW()
+		WHERE id = $11
+	`, input.Class, input.Subject, input.ChapterNumber, input.Type, input.Prompt, options,
+		input.CorrectOption, input.Answer, input.Marks, input.Explanation, c.Param("id"))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Question not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Question updated"})
+}
+
+// deleteQuestion - Remove a question that no quiz uses
+func deleteQuestion(c *gin.Context) {
+	var used bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.quiz_questions WHERE question_id = $1)", c.Param("id")).Scan(&used)
+	if used {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Question is used by a quiz"})
+		return
+	}
+
+	result, err := db.Exec("DELETE FROM mentor.questions WHERE id = $1", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Question not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Question deleted"})
+}
+
+// maxGeneratedQuestions caps one AI request
+const maxGeneratedQuestions = 20
+
+// generateQuestions asks the AI provider for questions on a chapter, grounded
+// in its content when the chapter exists, and saves the valid ones to the bank
+func generateQuestions(class int, subject string, chapter *int, count int, types []string, createdBy string) ([]bankQuestion, error) {
+	if !aiConfigured() {
+		return nil, fmt.Errorf("AI provider is not configured")
+	}
+	if len(types) == 0 {
+		types = []string{"mcq", "true_false", "short"}
+	}
+
+	topic := fmt.Sprintf("class %d %s", class, subject)
+	material := ""
+	if chapter != nil {
+		var title sql.NullString
+		var text sql.NullString
+		db.QueryRow(`
+			SELECT chapter_title, `+contentTextSQL+`
+			FROM mentor.content WHERE class = $1 AND subject = $2 AND chapter_number = $3
+		`, class, subject, *chapter).Scan(&title, &text)
+		topic += fmt.Sprintf(", chapter %d", *chapter)
+		if title.String != "" {
+			topic += " (" + title.String + ")"
+		}
+		material = text.String
+		if len(material) > 12000 {
+			material = material[:12000]
+		}
+	}
+
+	prompt := fmt.Sprintf("Write %d quiz questions for a school student on %s. "+
+		"Use only these question types: %s. Reply with only a JSON array, no markdown. Each item: "+
+		`{"question_type": "mcq|true_false|short|long", "prompt": "...", "options": ["..."] (mcq only, 4 choices), `+
+		`"correct_option": 0 (mcq only, 0-based), "answer": "..." (true_false: "true" or "false"; short: a few words; long: a model answer), `+
+		`"marks": 1, "explanation": "one sentence"}`,
+		count, topic, strings.Join(types, ", "))
+	if material != "" {
+		prompt += "\n\nBase the questions on this chapter material:\n" + material
+	}
+
+	text, err := generateText(prompt)
+	if err != nil {
+		return nil, err
+	}
+	text = stripCodeFence(text)
+
+	var drafts []bankQuestion
+	if err := json.Unmarshal([]byte(text), &drafts); err != nil {
+		return nil, fmt.Errorf("AI reply was not a JSON question list: %v", err)
+	}
+
+	allowed := map[string]bool{}
+	for _, t := range types {
+		allowed[t] = true
+	}
+
+	saved := []bankQuestion{}
+	for _, q := range drafts {
+		q.Class, q.Subject, q.ChapterNumber = class, subject, chapter
+		q.Source, q.CreatedBy = "ai", createdBy
+		if !allowed[q.Type] || len(q.fieldErrors()) > 0 {
+			continue
+		}
+		if err := insertQuestion(&q); err != nil {
+			return saved, err
+		}
+		saved = append(saved, q)
+		if len(saved) == count {
+			break
+		}
+	}
+	return saved, nil
+}
+
+// generateBankQuestions - Generate questions for a chapter with AI and add
+// them to the bank for review
+func generateBankQuestions(c *gin.Context) {
+	var input struct {
+		Class         int      `json:"class" binding:"required"`
+		Subject       string   `json:"subject" binding:"required"`
+		ChapterNumber *int     `json:"chapter_number"`
+		Count         int      `json:"count"`
+		Types         []string `json:"question_types"`
+		CreatedBy     string   `json:"created_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if input.Count == 0 {
+		input.Count = 5
+	}
+	if input.Count < 1 || input.Count > maxGeneratedQuestions {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"count": "must be between 1 and " + strconv.Itoa(maxGeneratedQuestions)}))
+		return
+	}
+	for _, t := range input.Types {
+		if !questionTypes[t] {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"question_types": "unknown type '" + t + "'"}))
+			return
+		}
+	}
+
+	questions, err := generateQuestions(input.Class, input.Subject, input.ChapterNumber, input.Count, input.Types, input.CreatedBy)
+	if err != nil && len(questions) == 0 {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Question generation failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"count":     len(questions),
+		"questions": questions,
+	})
+}
@@ -0,0 +1,839 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// QUIZZES (build, assign, deliver, auto-score)
+// ============================================
+
+// quizItem is a bank question as placed in a quiz, with the quiz's marks
+type quizItem struct {
+	bankQuestion
+	Position int `json:"position"`
+}
+
+// loadQuizItems returns a quiz's questions in order
+func loadQuizItems(quizID int) ([]quizItem, error) {
+	rows, err := db.Query(`
+		SELECT `+bankQuestionColumns+`, qq.position, qq.marks
+		FROM mentor.quiz_questions qq
+		JOIN mentor.questions q ON q.id = qq.question_id
+		WHERE qq.quiz_id = $1
+		ORDER BY qq.position
+	`, quizID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []quizItem{}
+	for rows.Next() {
+		var item quizItem
+		var position int
+		var marks float64
+		q, err := scanBankQuestion(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &position, &marks)...)
+		})
+		if err != nil {
+			continue
+		}
+		item.bankQuestion, item.Position = q, position
+		item.Marks = marks
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// studentView drops answers so the quiz can be shown before submission
+func (item quizItem) studentView() gin.H {
+	view := gin.H{
+		"question_id":   item.ID,
+		"position":      item.Position,
+		"question_type": item.Type,
+		"prompt":        item.Prompt,
+		"marks":         item.Marks,
+	}
+	if item.Type == "mcq" {
+		view["options"] = item.Options
+	}
+	return view
+}
+
+// normalizeAnswer makes typed answers comparable: case, spacing and a
+// trailing full stop are ignored
+func normalizeAnswer(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.TrimSuffix(s, ".")
+}
+
+// scoreQuizAnswer marks an objective answer; long answers return nil for
+// review by the teacher
+func scoreQuizAnswer(item quizItem, answer string) (*bool, *float64) {
+	answer = strings.TrimSpace(answer)
+	var correct bool
+	switch item.Type {
+	case "mcq":
+		choice, err := strconv.Atoi(answer)
+		correct = err == nil && item.CorrectOption != nil && choice == *item.CorrectOption
+	case "true_false", "short":
+		correct = answer != "" && normalizeAnswer(answer) == normalizeAnswer(item.Answer)
+	default:
+		return nil, nil
+	}
+
+	awarded := 0.0
+	if correct {
+		awarded = item.Marks
+	}
+	return &correct, &awarded
+}
+
+// getQuizzes - Quizzes with their size (class, subject, chapter filters)
+func getQuizzes(c *gin.Context) {
+	query := `
+		SELECT z.id, z.title, z.class, z.subject, z.chapter_number, COALESCE(z.created_by, ''), z.created_at,
+		       COUNT(qq.question_id), COALESCE(SUM(qq.marks), 0),
+		       (SELECT COUNT(*) FROM mentor.quiz_assignments a WHERE a.quiz_id = z.id)
+		FROM mentor.quizzes z
+		LEFT JOIN mentor.quiz_questions qq ON qq.quiz_id = z.id
+		WHERE 1=1`
+	args := []interface{}{}
+	argCount := 0
+
+	for param, column := range map[string]string{"class": "z.class", "subject": "z.subject", "chapter": "z.chapter_number"} {
+		if value := c.Query(param); value != "" {
+			argCount++
+			query += fmt.Sprintf(" AND %s = $%d", column, argCount)
+			args = append(args, value)
+		}
+	}
+	query += " GROUP BY z.id ORDER BY z.created_at DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	quizzes := []gin.H{}
+	for rows.Next() {
+		var id, class, questionCount, assigned int
+		var title, subject, createdBy string
+		var chapter sql.NullInt64
+		var totalMarks float64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &title, &class, &subject, &chapter, &createdBy, &createdAt,
+			&questionCount, &totalMarks, &assigned); err != nil {
+			continue
+		}
+		quiz := gin.H{
+			"id":             id,
+			"title":          title,
+			"class":          class,
+			"subject":        subject,
+			"created_by":     createdBy,
+			"created_at":     isoTimestamp(createdAt),
+			"question_count": questionCount,
+			"total_marks":    totalMarks,
+			"assigned_count": assigned,
+		}
+		if chapter.Valid {
+			quiz["chapter_number"] = chapter.Int64
+		}
+		quizzes = append(quizzes, quiz)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "quizzes": quizzes})
+}
+
+// createQuiz - Assemble a quiz from chosen bank questions, random bank
+// questions for the chapter (random_count) and/or new AI questions (ai_count)
+func createQuiz(c *gin.Context) {
+	var input struct {
+		Title         string `json:"title" binding:"required"`
+		Class         int    `json:"class" binding:"required"`
+		Subject       string `json:"subject" binding:"required"`
+		ChapterNumber *int   `json:"chapter_number"`
+		QuestionIDs   []int  `json:"question_ids"`
+		RandomCount   int    `json:"random_count"`
+		AICount       int    `json:"ai_count"`
+		CreatedBy     string `json:"created_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	fields := map[string]string{}
+	if input.RandomCount < 0 {
+		fields["random_count"] = "must not be negative"
+	}
+	if input.AICount < 0 || input.AICount > maxGeneratedQuestions {
+		fields["ai_count"] = "must be between 0 and " + strconv.Itoa(maxGeneratedQuestions)
+	}
+	if len(input.QuestionIDs)+input.RandomCount+input.AICount == 0 {
+		fields["question_ids"] = "pick questions, or set random_count or ai_count"
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	questionIDs := []int{}
+	seen := map[int]bool{}
+	for _, id := range input.QuestionIDs {
+		if !seen[id] {
+			seen[id] = true
+			questionIDs = append(questionIDs, id)
+		}
+	}
+
+	if input.RandomCount > 0 {
+		rows, err := db.Query(`
+			SELECT id FROM mentor.questions
+			WHERE class = $1 AND subject = $2 AND ($3::int IS NULL OR chapter_number = $3) AND NOT (id = ANY($4))
+			ORDER BY random() LIMIT $5
+		`, input.Class, input.Subject, input.ChapterNumber, pq.Array(questionIDs), input.RandomCount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		for rows.Next() {
+			var id int
+			if rows.Scan(&id) == nil {
+				seen[id] = true
+				questionIDs = append(questionIDs, id)
+			}
+		}
+		rows.Close()
+	}
+
+	if input.AICount > 0 {
+		generated, err := generateQuestions(input.Class, input.Subject, input.ChapterNumber, input.AICount, nil, input.CreatedBy)
+		if err != nil && len(generated) == 0 {
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Question generation failed: " + err.Error()})
+			return
+		}
+		for _, q := range generated {
+			questionIDs = append(questionIDs, q.ID)
+		}
+	}
+
+	if len(questionIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No questions found for this quiz"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var quizID int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.quizzes (title, class, subject, chapter_number, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id
+	`, input.Title, input.Class, input.Subject, input.ChapterNumber, input.CreatedBy).Scan(&quizID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO mentor.quiz_questions (quiz_id, question_id, position, marks)
+		SELECT $1, q.id, p.position, q.marks
+		FROM unnest($2::int[]) WITH ORDINALITY AS p(question_id, position)
+		JOIN mentor.questions q ON q.id = p.question_id
+	`, quizID, pq.Array(questionIDs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n != int64(len(questionIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "One or more questions not found"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("quiz", quizID, "created", input.CreatedBy, gin.H{"questions": len(questionIDs), "ai_count": input.AICount})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": quizID, "question_count": len(questionIDs), "message": "Quiz created"})
+}
+
+// getQuiz - A quiz with its questions and answers (teacher/admin view)
+func getQuiz(c *gin.Context) {
+	quizID, _ := strconv.Atoi(c.Param("id"))
+
+	var title, subject string
+	var class int
+	var chapter sql.NullInt64
+	err := db.QueryRow("SELECT title, class, subject, chapter_number FROM mentor.quizzes WHERE id = $1", quizID).
+		Scan(&title, &class, &subject, &chapter)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Quiz not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	items, err := loadQuizItems(quizID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	total := 0.0
+	for _, item := range items {
+		total += item.Marks
+	}
+
+	quiz := gin.H{
+		"id":          quizID,
+		"title":       title,
+		"class":       class,
+		"subject":     subject,
+		"total_marks": total,
+		"questions":   items,
+	}
+	if chapter.Valid {
+		quiz["chapter_number"] = chapter.Int64
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "quiz": quiz})
+}
+
+// deleteQuiz - Delete a quiz nobody has attempted yet
+func deleteQuiz(c *gin.Context) {
+	var attempted bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.quiz_attempts t
+		              JOIN mentor.quiz_assignments a ON a.id = t.assignment_id WHERE a.quiz_id = $1)
+	`, c.Param("id")).Scan(&attempted)
+	if attempted {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Quiz has attempts and cannot be deleted"})
+		return
+	}
+
+	result, err := db.Exec("DELETE FROM mentor.quizzes WHERE id = $1", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Quiz not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Quiz deleted"})
+}
+
+// assignQuiz - Give a quiz to one or more students; already assigned ones are skipped
+func assignQuiz(c *gin.Context) {
+	var input struct {
+		SubscriptionIDs []int  `json:"subscription_ids" binding:"required"`
+		DueDate         string `json:"due_date"` // YYYY-MM-DD, optional
+		AssignedBy      string `json:"assigned_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if input.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", input.DueDate); err != nil {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"due_date": "must be YYYY-MM-DD"}))
+			return
+		}
+	}
+
+	rows, err := db.Query(`
+		INSERT INTO mentor.quiz_assignments (quiz_id, subscription_id, due_date, assigned_by)
+		SELECT z.id, s.id, NULLIF($3, '')::date, NULLIF($4, '')
+		FROM mentor.quizzes z, mentor.subscriptions s
+		WHERE z.id = $1 AND s.id = ANY($2) AND s.deleted_at IS NULL
+		ON CONFLICT (quiz_id, subscription_id) DO NOTHING
+		RETURNING id, subscription_id
+	`, c.Param("id"), pq.Array(input.SubscriptionIDs), input.DueDate, input.AssignedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	assignments := []gin.H{}
+	for rows.Next() {
+		var id, subId int
+		if rows.Scan(&id, &subId) == nil {
+			assignments = append(assignments, gin.H{"id": id, "subscription_id": subId})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"assigned":    len(assignments),
+		"skipped":     len(input.SubscriptionIDs) - len(assignments),
+		"assignments": assignments,
+	})
+}
+
+// quizAssignment is one student's copy of a quiz and their attempt, if any
+type quizAssignment struct {
+	ID             int
+	QuizID         int
+	SubscriptionID int
+	Title          string
+	Subject        string
+	Chapter        sql.NullInt64
+	DueDate        sql.NullTime
+	AttemptID      sql.NullInt64
+	Score          sql.NullFloat64
+	MaxScore       sql.NullFloat64
+	Status         sql.NullString
+	SubmittedAt    sql.NullTime
+}
+
+const quizAssignmentColumns = `a.id, a.quiz_id, a.subscription_id, z.title, z.subject, z.chapter_number, a.due_date,
+	t.id, t.score, t.max_score, t.status, t.submitted_at`
+
+const quizAssignmentFrom = `
+	FROM mentor.quiz_assignments a
+	JOIN mentor.quizzes z ON z.id = a.quiz_id
+	LEFT JOIN mentor.quiz_attempts t ON t.assignment_id = a.id`
+
+func scanQuizAssignment(scan func(...interface{}) error) (quizAssignment, error) {
+	var a quizAssignment
+	err := scan(&a.ID, &a.QuizID, &a.SubscriptionID, &a.Title, &a.Subject, &a.Chapter, &a.DueDate,
+		&a.AttemptID, &a.Score, &a.MaxScore, &a.Status, &a.SubmittedAt)
+	return a, err
+}
+
+// summary is the assignment as listed for a student, with the result once submitted
+func (a quizAssignment) summary() gin.H {
+	h := gin.H{
+		"id":              a.ID,
+		"quiz_id":         a.QuizID,
+		"subscription_id": a.SubscriptionID,
+		"title":           a.Title,
+		"subject":         a.Subject,
+		"status":          "assigned",
+	}
+	if a.Chapter.Valid {
+		h["chapter_number"] = a.Chapter.Int64
+	}
+	if a.DueDate.Valid {
+		h["due_date"] = a.DueDate.Time.Format("2006-01-02")
+	}
+	if a.AttemptID.Valid {
+		h["attempt_id"] = a.AttemptID.Int64
+		h["status"] = a.Status.String
+		h["score"] = a.Score.Float64
+		h["max_score"] = a.MaxScore.Float64
+		h["percent"] = quizPercent(a.Score.Float64, a.MaxScore.Float64)
+		h["submitted_at"] = isoTimestamp(a.SubmittedAt.Time)
+	}
+	return h
+}
+
+// quizPercent is the score as a percentage, 0 for a quiz without marks
+func quizPercent(score, maxScore float64) float64 {
+	if maxScore <= 0 {
+		return 0
+	}
+	return roundMoney(score / maxScore * 100)
+}
+
+// loadQuizAssignment finds an assignment, optionally only within one subscription
+func loadQuizAssignment(id string, subId int) (quizAssignment, error) {
+	where := " WHERE a.id = $1"
+	args := []interface{}{id}
+	if subId != 0 {
+		where += " AND a.subscription_id = $2"
+		args = append(args, subId)
+	}
+	return scanQuizAssignment(db.QueryRow("SELECT "+quizAssignmentColumns+quizAssignmentFrom+where, args...).Scan)
+}
+
+// listQuizAssignments - A student's quizzes, newest first
+func listQuizAssignments(c *gin.Context, subId int) {
+	rows, err := db.Query("SELECT "+quizAssignmentColumns+quizAssignmentFrom+`
+		WHERE a.subscription_id = $1
+		ORDER BY a.created_at DESC
+	`, subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	quizzes := []gin.H{}
+	for rows.Next() {
+		a, err := scanQuizAssignment(rows.Scan)
+		if err != nil {
+			continue
+		}
+		quizzes = append(quizzes, a.summary())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "quizzes": quizzes})
+}
+
+// attemptAnswers returns the recorded answers of an attempt by question id
+func attemptAnswers(attemptID int64) map[int]gin.H {
+	answers := map[int]gin.H{}
+	rows, err := db.Query(`
+		SELECT question_id, COALESCE(answer, ''), is_correct, marks_awarded, max_marks
+		FROM mentor.quiz_attempt_answers WHERE attempt_id = $1
+	`, attemptID)
+	if err != nil {
+		return answers
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var questionID int
+		var answer string
+		var correct sql.NullBool
+		var awarded sql.NullFloat64
+		var maxMarks float64
+		if err := rows.Scan(&questionID, &answer, &correct, &awarded, &maxMarks); err != nil {
+			continue
+		}
+		result := gin.H{"answer": answer, "max_marks": maxMarks}
+		if correct.Valid {
+			result["is_correct"] = correct.Bool
+		}
+		if awarded.Valid {
+			result["marks_awarded"] = awarded.Float64
+		}
+		answers[questionID] = result
+	}
+	return answers
+}
+
+// deliverQuizAssignment - The quiz to take (no answers), or after submission
+// the marked answers with the correct ones and explanations
+func deliverQuizAssignment(c *gin.Context, subId int) {
+	a, err := loadQuizAssignment(c.Param("id"), subId)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Quiz not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	items, err := loadQuizItems(a.QuizID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var answers map[int]gin.H
+	if a.AttemptID.Valid {
+		answers = attemptAnswers(a.AttemptID.Int64)
+	}
+
+	questions := []gin.H{}
+	for _, item := range items {
+		q := item.studentView()
+		if answers != nil {
+			if item.Type == "mcq" {
+				q["correct_option"] = item.CorrectOption
+			} else {
+				q["correct_answer"] = item.Answer
+			}
+			q["explanation"] = item.Explanation
+			q["result"] = answers[item.ID]
+		}
+		questions = append(questions, q)
+	}
+
+	quiz := a.summary()
+	quiz["questions"] = questions
+	c.JSON(http.StatusOK, gin.H{"success": true, "quiz": quiz})
+}
+
+// submitQuizAssignment - Record the one attempt at a quiz and auto-score it;
+// long answers leave the attempt pending_review until a teacher marks them
+func submitQuizAssignment(c *gin.Context, subId int, submittedBy string) {
+	var input struct {
+		Answers []struct {
+			QuestionID int    `json:"question_id"`
+			Answer     string `json:"answer"` // mcq: the chosen option index
+		} `json:"answers" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	a, err := loadQuizAssignment(c.Param("id"), subId)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Quiz not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if a.AttemptID.Valid {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Quiz already submitted"})
+		return
+	}
+
+	items, err := loadQuizItems(a.QuizID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	given := map[int]string{}
+	for _, ans := range input.Answers {
+		given[ans.QuestionID] = ans.Answer
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var attemptID int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.quiz_attempts (assignment_id, submitted_by, max_score, status)
+		VALUES ($1, $2, 0, 'graded')
+		ON CONFLICT (assignment_id) DO NOTHING
+		RETURNING id
+	`, a.ID, submittedBy).Scan(&attemptID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Quiz already submitted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	score, maxScore, correctCount, pending := 0.0, 0.0, 0, 0
+	for _, item := range items {
+		answer := given[item.ID]
+		correct, awarded := scoreQuizAnswer(item, answer)
+		maxScore += item.Marks
+		if awarded != nil {
+			score += *awarded
+		} else if strings.TrimSpace(answer) != "" {
+			pending++
+		} else {
+			// An unanswered long question scores zero without review
+			f, zero := false, 0.0
+			correct, awarded = &f, &zero
+		}
+		if correct != nil && *correct {
+			correctCount++
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO mentor.quiz_attempt_answers (attempt_id, question_id, answer, is_correct, marks_awarded, max_marks)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		`, attemptID, item.ID, strings.TrimSpace(answer), correct, awarded, item.Marks)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	status := "graded"
+	if pending > 0 {
+		status = "pending_review"
+	}
+	_, err = tx.Exec("UPDATE mentor.quiz_attempts SET score = $1, max_score = $2, status = $3 WHERE id = $4",
+		score, maxScore, status, attemptID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"attempt_id":     attemptID,
+		"status":         status,
+		"score":          score,
+		"max_score":      maxScore,
+		"correct":        correctCount,
+		"pending_review": pending,
+		"message":        "Quiz submitted",
+	})
+}
+
+// reviewQuizAttempt - Teacher marks long answers (or overrides any answer's
+// marks); the attempt is graded once every answer has marks
+func reviewQuizAttempt(c *gin.Context) {
+	var input struct {
+		Marks []struct {
+			QuestionID   int     `json:"question_id"`
+			MarksAwarded float64 `json:"marks_awarded"`
+		} `json:"marks" binding:"required"`
+		ReviewedBy string `json:"reviewed_by" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	attemptID := c.Param("id")
+	for _, m := range input.Marks {
+		result, err := tx.Exec(`
+			UPDATE mentor.quiz_attempt_answers
+			SET marks_awarded = $1, is_correct = ($1 >= max_marks)
+			WHERE attempt_id = $2 AND question_id = $3 AND $1 BETWEEN 0 AND max_marks
+		`, m.MarksAwarded, attemptID, m.QuestionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+				"marks": fmt.Sprintf("question %d is not in this attempt or marks exceed its maximum", m.QuestionID),
+			}))
+			return
+		}
+	}
+
+	var score float64
+	var status string
+	err = tx.QueryRow(`
+		UPDATE mentor.quiz_attempts t
+		SET score = s.score,
+		    status = CASE WHEN s.unmarked > 0 THEN 'pending_review' ELSE 'graded' END,
+		    reviewed_by = $2, reviewed_at = NOW()
+		FROM (SELECT COALESCE(SUM(marks_awarded), 0) AS score, COUNT(*) FILTER (WHERE marks_awarded IS NULL) AS unmarked
+		      FROM mentor.quiz_attempt_answers WHERE attempt_id = $1) s
+		WHERE t.id = $1
+		RETURNING t.score, t.status
+	`, attemptID, input.ReviewedBy).Scan(&score, &status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Attempt not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("quiz_attempt", attemptID, "reviewed", input.ReviewedBy, gin.H{"score": score, "status": status})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "score": score, "status": status, "message": "Attempt reviewed"})
+}
+
+// getQuizResults - Every assigned student's result for a quiz
+func getQuizResults(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT s.student_name, `+quizAssignmentColumns+quizAssignmentFrom+`
+		JOIN mentor.subscriptions s ON s.id = a.subscription_id
+		WHERE a.quiz_id = $1
+		ORDER BY t.score DESC NULLS LAST, s.student_name
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	results := []gin.H{}
+	submitted, scoreSum, percentSum := 0, 0.0, 0.0
+	for rows.Next() {
+		var studentName string
+		a, err := scanQuizAssignment(func(dest ...interface{}) error {
+			return rows.Scan(append([]interface{}{&studentName}, dest...)...)
+		})
+		if err != nil {
+			continue
+		}
+		result := a.summary()
+		result["student_name"] = studentName
+		results = append(results, result)
+		if a.AttemptID.Valid {
+			submitted++
+			scoreSum += a.Score.Float64
+			percentSum += quizPercent(a.Score.Float64, a.MaxScore.Float64)
+		}
+	}
+
+	summary := gin.H{"assigned": len(results), "submitted": submitted}
+	if submitted > 0 {
+		summary["average_score"] = roundMoney(scoreSum / float64(submitted))
+		summary["average_percent"] = roundMoney(percentSum / float64(submitted))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "summary": summary, "results": results})
+}
+
+// getSubscriptionQuizzes - A student's assigned quizzes and results
+func getSubscriptionQuizzes(c *gin.Context) {
+	subId, _ := strconv.Atoi(c.Param("id"))
+	if caller := c.GetString("teacher_id"); caller != "" && !teachesSubscription(caller, subId) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "You can only view your own students"})
+		return
+	}
+	listQuizAssignments(c, subId)
+}
+
+// getQuizAssignment - Teacher view of one student's quiz
+func getQuizAssignment(c *gin.Context) {
+	deliverQuizAssignment(c, 0)
+}
+
+// getStudentQuizzes - The logged-in student's quizzes
+func getStudentQuizzes(c *gin.Context) {
+	listQuizAssignments(c, c.GetInt("subscription_id"))
+}
+
+// getStudentQuiz - Take (or review) one of the student's quizzes
+func getStudentQuiz(c *gin.Context) {
+	deliverQuizAssignment(c, c.GetInt("subscription_id"))
+}
+
+// submitStudentQuiz - The student submits their answers
+func submitStudentQuiz(c *gin.Context) {
+	submitQuizAssignment(c, c.GetInt("subscription_id"), "student")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// LOW-BANDWIDTH RESPONSES (?fields= and ?lite=true)
+// ============================================
+
+// isLite reports whether the client asked for the minimal payload
+func isLite(c *gin.Context) bool {
+	return c.Query("lite") == "true" || c.Query("lite") == "1"
+}
+
+// requestedFields parses ?fields=a,b,c; nil means "all fields"
+func requestedFields(c *gin.Context) map[string]bool {
+	raw := c.Query("fields")
+	if raw == "" {
+		return nil
+	}
+	fields := map[string]bool{}
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[f] = true
+		}
+	}
+	return fields
+}
+
+// wantsField tells a handler whether it needs to load an expensive field at all
+func wantsField(c *gin.Context, name string) bool {
+	if fields := requestedFields(c); fields != nil {
+		return fields[name]
+	}
+	return !isLite(c)
+}
+
+// shapeItem trims one response object for slow connections. With ?fields= only
+// the listed keys are kept. In lite mode nested objects and arrays of objects
+// are dropped and string lists are sent as a single comma-separated string.
+func shapeItem(c *gin.Context, item gin.H) gin.H {
+	fields := requestedFields(c)
+	lite := isLite(c)
+	if fields == nil && !lite {
+		return item
+	}
+
+	shaped := gin.H{}
+	for key, value := range item {
+		if fields != nil && !fields[key] {
+			continue
+		}
+		if lite {
+			switch v := value.(type) {
+			case gin.H, []gin.H:
+				continue
+			case []string:
+				value = strings.Join(v, ",")
+			}
+		}
+		shaped[key] = value
+	}
+	return shaped
+}
+
+// shapeItems applies shapeItem to every element of a list
+func shapeItems(c *gin.Context, items []gin.H) []gin.H {
+	if items == nil {
+		return []gin.H{}
+	}
+	for i := range items {
+		items[i] = shapeItem(c, items[i])
+	}
+	return items
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// SCHEDULE RANGE (Week view)
+// ============================================
+
+// maxScheduleRangeDays caps how many days one schedule request can cover
+const maxScheduleRangeDays = 62
+
+// parseDateRange reads ?from=&to= (YYYY-MM-DD). from defaults to today and
+// to to from plus defaultDays-1.
+func parseDateRange(c *gin.Context, defaultDays int) (time.Time, time.Time, bool) {
+	from := localToday()
+	if f := c.Query("from"); f != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", f, time.Local)
+		if err != nil {
+			return from, from, false
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, defaultDays-1)
+	if t := c.Query("to"); t != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", t, time.Local)
+		if err != nil {
+			return from, to, false
+		}
+		to = parsed
+	}
+
+	if to.Before(from) || to.Sub(from) > maxScheduleRangeDays*24*time.Hour {
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// parsePastDateRange is parseDateRange for reports: without from/to it covers
+// the last days days up to today rather than the days ahead
+func parsePastDateRange(c *gin.Context, days int) (time.Time, time.Time, bool) {
+	if c.Query("from") != "" || c.Query("to") != "" {
+		return parseDateRange(c, days)
+	}
+	to := localToday()
+	return to.AddDate(0, 0, -(days - 1)), to, true
+}
+
+// getTeacherScheduleRange - A teacher's sessions grouped by date, with holidays,
+// leave days and reschedules, for week and month views
+func getTeacherScheduleRange(c *gin.Context) {
+	teacherID := c.Param("teacherId")
+
+	from, to, ok := parseDateRange(c, 7)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	days := []gin.H{}
+	byDate := map[string]gin.H{}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := gin.H{
+			"date":     d.Format("2006-01-02"),
+			"weekday":  d.Format("Mon"),
+			"sessions": []gin.H{},
+		}
+		days = append(days, day)
+		byDate[d.Format("2006-01-02")] = day
+	}
+
+	holidayRows, err := db.Query(`
+		SELECT date, name FROM mentor.holidays
+		WHERE date BETWEEN $1 AND $2 AND (teacher_id IS NULL OR teacher_id = $3)
+	`, from, to, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for holidayRows.Next() {
+		var date time.Time
+		var name string
+		if holidayRows.Scan(&date, &name) == nil {
+			byDate[date.Format("2006-01-02")]["holiday"] = name
+		}
+	}
+	holidayRows.Close()
+
+	leaveRows, err := db.Query(`
+		SELECT date, COALESCE(reason, '') FROM mentor.teacher_blackouts
+		WHERE teacher_id = $1 AND date BETWEEN $2 AND $3
+	`, teacherID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for leaveRows.Next() {
+		var date time.Time
+		var reason string
+		if leaveRows.Scan(&date, &reason) == nil {
+			byDate[date.Format("2006-01-02")]["leave"] = gin.H{"reason": reason}
+		}
+	}
+	leaveRows.Close()
+
+	rows, err := db.Query(`
+		SELECT cs.id, cs.subscription_id, s.student_name, s.class, cs.session_date, COALESCE(cs.session_time, ''),
+		       cs.subject, cs.status, moved_to.session_date, moved_from.session_date, COALESCE(cs.reschedule_reason, ''),
+		       COALESCE(cs.duration_minutes, 0)
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		LEFT JOIN mentor.class_sessions moved_to ON moved_to.id = cs.rescheduled_to
+		LEFT JOIN mentor.class_sessions moved_from ON moved_from.id = cs.rescheduled_from
+		WHERE cs.teacher_id = $1 AND cs.session_date BETWEEN $2 AND $3
+		ORDER BY cs.session_date, cs.session_time, s.student_name, cs.id
+	`, teacherID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	loc := teacherLocation(teacherID)
+	total := 0
+	for rows.Next() {
+		var id, subId, class, minutes int
+		var studentName, sessionTime, subject, status, reason string
+		var sessionDate time.Time
+		var movedTo, movedFrom sql.NullTime
+		if err := rows.Scan(&id, &subId, &studentName, &class, &sessionDate, &sessionTime,
+			&subject, &status, &movedTo, &movedFrom, &reason, &minutes); err != nil {
+			continue
+		}
+
+		session := gin.H{
+			"id":              id,
+			"subscription_id": subId,
+			"student_name":    studentName,
+			"class":           class,
+			"subject":         subject,
+			"time":            sessionTime,
+			"status":          status,
+		}
+		addSessionTimes(session, sessionDate, sessionTime, minutes, loc)
+		if movedTo.Valid {
+			session["rescheduled_to"] = movedTo.Time.Format("2006-01-02")
+		}
+		if movedFrom.Valid {
+			session["rescheduled_from"] = movedFrom.Time.Format("2006-01-02")
+		}
+		if reason != "" {
+			session["reschedule_reason"] = reason
+		}
+
+		day := byDate[sessionDate.Format("2006-01-02")]
+		day["sessions"] = append(day["sessions"].([]gin.H), session)
+		if status == "scheduled" || status == "completed" {
+			total++
+		}
+	}
+
+	// Days past the sync job's horizon have nothing stored yet
+	forecast, err := forecastSessionsBetween(from, to, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for _, session := range forecast {
+		day := byDate[session["date"].(string)]
+		delete(session, "date")
+		day["sessions"] = append(day["sessions"].([]gin.H), session)
+		if session["status"] == "scheduled" {
+			total++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+		"classes": total,
+		"days":    days,
+	})
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// SCHEDULE CHANGE REQUESTS
+// ============================================
+
+// scheduleRequestSelect lists the columns scanScheduleRequest reads
+const scheduleRequestSelect = `
+	SELECT r.id, r.subscription_id, s.student_name, r.teacher_id, r.proposed_days, r.proposed_time,
+	       COALESCE(r.previous_days, '{}'), COALESCE(r.previous_time, ''), COALESCE(r.reason, ''), r.status,
+	       COALESCE(r.decided_by, ''), COALESCE(r.decision_note, ''), r.decided_at, r.created_at
+	FROM mentor.schedule_change_requests r
+	JOIN mentor.subscriptions s ON s.id = r.subscription_id
+`
+
+func scanScheduleRequest(rows *sql.Rows) (gin.H, error) {
+	var id, subId int
+	var studentName, teacherID, proposedTime, previousTime, reason, status, decidedBy, note string
+	var proposedDays, previousDays []string
+	var decidedAt sql.NullTime
+	var createdAt time.Time
+	if err := rows.Scan(&id, &subId, &studentName, &teacherID, pq.Array(&proposedDays), &proposedTime,
+		pq.Array(&previousDays), &previousTime, &reason, &status, &decidedBy, &note, &decidedAt, &createdAt); err != nil {
+		return nil, err
+	}
+	request := gin.H{
+		"id":              id,
+		"subscription_id": subId,
+		"student_name":    studentName,
+		"teacher_id":      teacherID,
+		"proposed":        gin.H{"schedule_days": proposedDays, "time": proposedTime},
+		"current":         gin.H{"schedule_days": previousDays, "time": previousTime},
+		"reason":          reason,
+		"status":          status,
+		"created_at":      isoTimestamp(createdAt),
+	}
+	if decidedAt.Valid {
+		request["decided_by"] = decidedBy
+		request["decision_note"] = note
+		request["decided_at"] = isoTimestamp(decidedAt.Time)
+	}
+	return request, nil
+}
+
+// queryScheduleRequests runs scheduleRequestSelect with a WHERE clause, newest first
+func queryScheduleRequests(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(scheduleRequestSelect+" WHERE "+where+" ORDER BY r.created_at DESC, r.id DESC LIMIT 200", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []gin.H{}
+	for rows.Next() {
+		if request, err := scanScheduleRequest(rows); err == nil {
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+// describeSlot renders days and time as "Sat,Mon 4:00 PM"
+func describeSlot(days []string, classTime string) string {
+	return strings.TrimSpace(strings.Join(days, ",") + " " + classTime)
+}
+
+// createScheduleRequest - A teacher proposes new days/time for one of their students
+func createScheduleRequest(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+
+	var input struct {
+		SubscriptionID int        `json:"subscription_id"`
+		ScheduleDays   stringList `json:"schedule_days"`
+		Time           string     `json:"time"`
+		Reason         string     `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	fields := map[string]string{}
+	if input.SubscriptionID == 0 {
+		fields["subscription_id"] = "is required"
+	}
+	if len(input.ScheduleDays) == 0 {
+		fields["schedule_days"] = "is required"
+	}
+	for _, d := range input.ScheduleDays {
+		if !validScheduleDay(d) {
+			fields["schedule_days"] = "invalid day " + d + "; use Sat-Fri"
+		}
+	}
+	classTime, ok := parseClassTime(input.Time)
+	if !ok {
+		fields["time"] = `must be a time like "4:00 PM"`
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+	input.ScheduleDays, _ = canonicalScheduleDays(input.ScheduleDays)
+
+	var currentDays []string
+	var currentTime string
+	err := db.QueryRow(`
+		SELECT COALESCE(s.schedule_day_list, '{}'), COALESCE(s.time, '')
+		FROM mentor.subscriptions s
+		WHERE s.id = $2 AND s.deleted_at IS NULL AND `+teacherAssignedSQL+`
+	`, teacherID, input.SubscriptionID).Scan(pq.Array(&currentDays), &currentTime)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO mentor.schedule_change_requests
+		(subscription_id, teacher_id, proposed_days, proposed_time, previous_days, previous_time, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		ON CONFLICT (subscription_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id
+	`, input.SubscriptionID, teacherID, pq.Array([]string(input.ScheduleDays)), classTime,
+		pq.Array(currentDays), currentTime, strings.TrimSpace(input.Reason)).Scan(&id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "This student already has a pending schedule change request"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", input.SubscriptionID, "schedule_change_requested", teacherID, gin.H{
+		"request_id": id,
+		"moved":      describeSlot(currentDays, currentTime) + " → " + describeSlot(input.ScheduleDays, classTime),
+		"reason":     input.Reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "status": "pending", "message": "Schedule change requested"})
+}
+
+// getMyScheduleRequests - The signed-in teacher's requests
+func getMyScheduleRequests(c *gin.Context) {
+	requests, err := queryScheduleRequests("r.teacher_id = $1", c.GetString("teacher_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "requests": requests})
+}
+
+// withdrawScheduleRequest - The teacher takes back a pending request
+func withdrawScheduleRequest(c *gin.Context) {
+	teacherID := c.GetString("teacher_id")
+	id := c.Param("id")
+
+	var subId int
+	err := db.QueryRow(`
+		UPDATE mentor.schedule_change_requests
+		SET status = 'withdrawn', decided_by = $1, decided_at = NOW()
+		WHERE id = $2 AND teacher_id = $1 AND status = 'pending'
+		RETURNING subscription_id
+	`, teacherID, id).Scan(&subId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Pending request not found"})
+		return
+	}
+
+	logAudit("subscription", subId, "schedule_change_withdrawn", teacherID, gin.H{"request_id": id})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Request withdrawn"})
+}
+
+// getScheduleRequests - Admin list, pending first by default
+func getScheduleRequests(c *gin.Context) {
+	where := "1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	status := c.DefaultQuery("status", "pending")
+	if status != "all" {
+		argCount++
+		where += fmt.Sprintf(" AND r.status = $%d", argCount)
+		args = append(args, status)
+	}
+	if subId := c.Query("subscription_id"); subId != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.subscription_id = $%d", argCount)
+		args = append(args, subId)
+	}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+
+	requests, err := queryScheduleRequests(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "requests": requests})
+}
+
+// decideScheduleRequest approves or rejects a pending request. onlySubId > 0
+// limits it to that subscription's requests (guardians). Approving applies
+// the new days and time to the subscription and regenerates its sessions.
+func decideScheduleRequest(id string, onlySubId int, approve bool, decidedBy, note string) (gin.H, int) {
+	var subId int
+	var teacherID, proposedTime, previousTime, reason string
+	var proposedDays, previousDays []string
+	err := db.QueryRow(`
+		SELECT subscription_id, teacher_id, proposed_days, proposed_time,
+		       COALESCE(previous_days, '{}'), COALESCE(previous_time, ''), COALESCE(reason, '')
+		FROM mentor.schedule_change_requests
+		WHERE id = $1 AND status = 'pending' AND ($2 = 0 OR subscription_id = $2)
+	`, id, onlySubId).Scan(&subId, &teacherID, pq.Array(&proposedDays), &proposedTime,
+		pq.Array(&previousDays), &previousTime, &reason)
+	if err != nil {
+		return gin.H{"success": false, "error": "Pending request not found"}, http.StatusNotFound
+	}
+
+	status, action := "rejected", "schedule_change_rejected"
+	if approve {
+		status, action = "approved", "schedule_change_approved"
+
+		// The teacher's other students may have taken the slot since
+		var area, postcode string
+		var minutes int
+		db.QueryRow(`
+			SELECT COALESCE(s.area, ''), COALESCE(s.postcode, ''), `+visitMinutesSQL+`
+			FROM mentor.subscriptions s WHERE s.id = $1
+		`, subId).Scan(&area, &postcode, &minutes)
+		commitments, err := teacherCommitments(teacherID, subId)
+		if err != nil {
+			return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+		}
+		zone := ""
+		if zones := studentZones(area, postcode); len(zones) > 0 {
+			zone = zones[0]
+		}
+		start, _ := clockMinutes(proposedTime)
+		for wd := range scheduledWeekdays(proposedDays) {
+			if ok, _ := slotFit(commitments[wd], start, minutes, zone); !ok {
+				return gin.H{
+					"success": false,
+					"error":   "Teacher " + teacherID + " is no longer free at " + proposedTime + " on " + wd.String()[:3],
+				}, http.StatusConflict
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE mentor.schedule_change_requests
+		SET status = $1, decided_by = $2, decision_note = NULLIF($3, ''), decided_at = NOW()
+		WHERE id = $4 AND status = 'pending'
+	`, status, decidedBy, note, id)
+	if err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return gin.H{"success": false, "error": "Pending request not found"}, http.StatusNotFound
+	}
+
+	if approve {
+		if _, err := tx.Exec(`
+			UPDATE mentor.subscriptions
+			SET schedule_days = $1, schedule_day_list = $2, days_per_week = $3, time = $4, updated_at = NOW()
+			WHERE id = $5 AND deleted_at IS NULL
+		`, strings.Join(proposedDays, ","), pq.Array(proposedDays), len(proposedDays), proposedTime, subId); err != nil {
+			return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+
+	if approve {
+		today := localToday()
+		syncClassSessions(today, today.AddDate(0, 0, classSessionHorizonDays), teacherID)
+	}
+
+	details := gin.H{"request_id": id, "reason": note}
+	if approve {
+		details["moved"] = describeSlot(previousDays, previousTime) + " → " + describeSlot(proposedDays, proposedTime)
+	}
+	logAudit("subscription", subId, action, decidedBy, details)
+
+	return gin.H{"success": true, "status": status, "message": "Request " + status}, http.StatusOK
+}
+
+// approveScheduleRequest / rejectScheduleRequest - Admin decisions
+func approveScheduleRequest(c *gin.Context) {
+	respondScheduleDecision(c, 0, true, "")
+}
+
+func rejectScheduleRequest(c *gin.Context) {
+	respondScheduleDecision(c, 0, false, "")
+}
+
+// getStudentScheduleRequests - Requests for the signed-in family's subscription
+func getStudentScheduleRequests(c *gin.Context) {
+	requests, err := queryScheduleRequests("r.subscription_id = $1", c.GetInt("subscription_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "requests": requests})
+}
+
+// approveStudentScheduleRequest / rejectStudentScheduleRequest - Guardian
+// decisions from the student app
+func approveStudentScheduleRequest(c *gin.Context) {
+	respondScheduleDecision(c, c.GetInt("subscription_id"), true, "guardian")
+}
+
+func rejectStudentScheduleRequest(c *gin.Context) {
+	respondScheduleDecision(c, c.GetInt("subscription_id"), false, "guardian")
+}
+
+// respondScheduleDecision reads {decided_by, note} and decides the request.
+// A fixed decidedBy overrides the body.
+func respondScheduleDecision(c *gin.Context, onlySubId int, approve bool, decidedBy string) {
+	var input struct {
+		DecidedBy string `json:"decided_by"`
+		Note      string `json:"note"`
+	}
+	c.ShouldBindJSON(&input)
+	if decidedBy == "" {
+		decidedBy = input.DecidedBy
+	}
+	if decidedBy == "" {
+		decidedBy = "admin"
+	}
+
+	if _, err := strconv.Atoi(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request id"})
+		return
+	}
+
+	resp, status := decideScheduleRequest(c.Param("id"), onlySubId, approve, decidedBy, strings.TrimSpace(input.Note))
+	c.JSON(status, resp)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// SCHEDULE SLOTS (Per-day, per-subject class times)
+// ============================================
+
+// scheduleSlots maps subscription -> weekday -> subject -> class time
+type scheduleSlots map[int]map[time.Weekday]map[string]string
+
+// loadScheduleSlots reads slot rows matching where (on mentor.schedule_slots
+// aliased sl)
+func loadScheduleSlots(where string, args ...interface{}) (scheduleSlots, error) {
+	rows, err := db.Query(`
+		SELECT sl.subscription_id, sl.day, sl.subject, sl.time
+		FROM mentor.schedule_slots sl
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := scheduleSlots{}
+	for rows.Next() {
+		var subId int
+		var day, subject, classTime string
+		if err := rows.Scan(&subId, &day, &subject, &classTime); err != nil {
+			continue
+		}
+		wd, ok := scheduleDayWeekdays[day]
+		if !ok {
+			continue
+		}
+		if slots[subId] == nil {
+			slots[subId] = map[time.Weekday]map[string]string{}
+		}
+		if slots[subId][wd] == nil {
+			slots[subId][wd] = map[string]string{}
+		}
+		slots[subId][wd][subject] = classTime
+	}
+	return slots, nil
+}
+
+// slotTime is the subject's class time on a day: its slot, else defaultTime
+func slotTime(daySlots map[string]string, subject, defaultTime string) string {
+	if t, ok := daySlots[subject]; ok {
+		return t
+	}
+	return defaultTime
+}
+
+// visitTimes lists the distinct times a subscription's subjects are taught on
+// a day, i.e. the separate visits that day
+func visitTimes(daySlots map[string]string, subjects []string, defaultTime string) []string {
+	seen := map[string]bool{}
+	var times []string
+	for _, subject := range subjects {
+		t := slotTime(daySlots, subject, defaultTime)
+		if !seen[t] {
+			seen[t] = true
+			times = append(times, t)
+		}
+	}
+	if len(times) == 0 {
+		times = append(times, defaultTime)
+	}
+	return times
+}
+
+// splitSessionsByTime groups one day's sessions by their time, earliest
+// first, so classes at different times of day show as separate visits
+func splitSessionsByTime(sessions []gin.H) [][]gin.H {
+	var groups [][]gin.H
+	index := map[string]int{}
+	for _, s := range sessions {
+		t, _ := s["time"].(string)
+		i, ok := index[t]
+		if !ok {
+			i = len(groups)
+			index[t] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, okA := clockMinutes(groups[i][0]["time"].(string))
+		b, okB := clockMinutes(groups[j][0]["time"].(string))
+		if okA != okB {
+			return okA
+		}
+		return a < b
+	})
+	return groups
+}
+
+// getSubscriptionSlots - The subscription's per-day subject times
+func getSubscriptionSlots(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT day, subject, time FROM mentor.schedule_slots
+		WHERE subscription_id = $1
+		ORDER BY array_position(ARRAY['Sat','Sun','Mon','Tue','Wed','Thu','Fri']::varchar[], day), id
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	slots := []gin.H{}
+	for rows.Next() {
+		var day, subject, classTime string
+		if err := rows.Scan(&day, &subject, &classTime); err != nil {
+			continue
+		}
+		slots = append(slots, gin.H{"day": day, "subject": subject, "time": classTime})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "slots": slots})
+}
+
+// setSubscriptionSlots - Replace the subscription's per-day subject times.
+// Slots go on days already in schedule_days; an empty list puts every
+// subject back on the subscription's time.
+func setSubscriptionSlots(c *gin.Context) {
+	subId := c.Param("id")
+
+	var input struct {
+		Slots []struct {
+			Day     string `json:"day"`
+			Subject string `json:"subject"`
+			Time    string `json:"time"`
+		} `json:"slots"`
+		UpdatedBy string `json:"updated_by"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var subjects, scheduleDays []string
+	var defaultTime string
+	err := db.QueryRow(`
+		SELECT subject_list, schedule_day_list, COALESCE(time, '')
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, subId).Scan(pq.Array(&subjects), pq.Array(&scheduleDays), &defaultTime)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	scheduled := scheduledWeekdays(scheduleDays)
+	fields := map[string]string{}
+	type slot struct{ day, subject, time string }
+	var slots []slot
+	taken := map[string]bool{}
+	for i, in := range input.Slots {
+		key := fmt.Sprintf("slots[%d]", i)
+
+		var wd time.Weekday
+		found := false
+		for d := range scheduledWeekdays([]string{in.Day}) {
+			wd, found = d, true
+		}
+		if !found {
+			fields[key+".day"] = "invalid day " + in.Day + "; use Sat-Fri"
+			continue
+		}
+		if !scheduled[wd] {
+			fields[key+".day"] = wd.String()[:3] + " is not one of the subscription's schedule_days"
+			continue
+		}
+
+		subject := ""
+		for _, s := range subjects {
+			if strings.EqualFold(s, strings.TrimSpace(in.Subject)) {
+				subject = s
+			}
+		}
+		if subject == "" {
+			fields[key+".subject"] = "not a subject of this subscription"
+			continue
+		}
+
+		classTime, ok := parseClassTime(in.Time)
+		if !ok {
+			fields[key+".time"] = `must be a time like "4:00 PM"`
+			continue
+		}
+
+		day := wd.String()[:3]
+		if taken[day+"|"+subject] {
+			fields[key] = subject + " already has a slot on " + day
+			continue
+		}
+		taken[day+"|"+subject] = true
+		slots = append(slots, slot{day, subject, classTime})
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mentor.schedule_slots WHERE subscription_id = $1", subId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	described, saved := []string{}, []gin.H{}
+	for _, s := range slots {
+		_, err := tx.Exec(`
+			INSERT INTO mentor.schedule_slots (subscription_id, day, subject, time) VALUES ($1, $2, $3, $4)
+		`, subId, s.day, s.subject, s.time)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		described = append(described, s.day+" "+s.subject+" "+s.time)
+		saved = append(saved, gin.H{"day": s.day, "subject": s.subject, "time": s.time})
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// Regenerate upcoming sessions for every teacher on the subscription
+	teacherRows, err := db.Query(`
+		SELECT DISTINCT COALESCE(sc.teacher_id, s.teacher_id)
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.schedule sc ON sc.subscription_id = s.id
+		WHERE s.id = $1 AND COALESCE(sc.teacher_id, s.teacher_id) IS NOT NULL
+	`, subId)
+	if err == nil {
+		var teachers []string
+		for teacherRows.Next() {
+			var id string
+			if teacherRows.Scan(&id) == nil {
+				teachers = append(teachers, id)
+			}
+		}
+		teacherRows.Close()
+		today := localToday()
+		for _, id := range teachers {
+			syncClassSessions(today, today.AddDate(0, 0, classSessionHorizonDays), id)
+			syncGoogleCalendarLater(id)
+		}
+	}
+
+	moved := "all subjects at " + defaultTime
+	if len(described) > 0 {
+		moved = strings.Join(described, ", ")
+	}
+	logAudit("subscription", subId, "schedule_slots_updated", input.UpdatedBy, gin.H{
+		"slots": described,
+		"moved": moved,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "slots": saved, "message": "Class times updated"})
+}
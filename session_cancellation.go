@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// SESSION CANCELLATION (One class, with reason)
+// ============================================
+
+// lateCancelNoticeHours is the notice a guardian must give; later
+// cancellations are still paid to per-class teachers, so they never earn a
+// makeup class
+const lateCancelNoticeHours = 3
+
+// sessionCaller identifies who is acting on a class session: an admin, a
+// teacher (their id) or a student/guardian session (its subscription). ok is
+// false when none of these tokens was sent.
+func sessionCaller(c *gin.Context) (admin bool, teacherID string, subId int, ok bool) {
+	if isAdminRequest(c) {
+		return true, "", 0, true
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		return false, "", 0, false
+	}
+	if id, isTeacher := sessionTeacher(token); isTeacher {
+		return false, id, 0, true
+	}
+	if id, isStudent := sessionSubscription(token); isStudent {
+		return false, "", id, true
+	}
+	return false, "", 0, false
+}
+
+// cancelClassSession - Cancel one scheduled session: record who cancelled and
+// why, drop it from the teacher's schedule, tell the guardian, and give a
+// makeup credit (by default unless the guardian cancelled). Teachers cancel
+// their own sessions and students/guardians their own; admins may record a
+// cancellation for any party.
+func cancelClassSession(c *gin.Context) {
+	id := c.Param("id")
+
+	admin, callerTeacher, callerSub, ok := sessionCaller(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Teacher, student or admin token required"})
+		return
+	}
+
+	var input struct {
+		Party       string `json:"party"` // "teacher", "guardian" or "admin"
+		CancelledBy string `json:"cancelled_by"`
+		Reason      string `json:"reason"`
+		Makeup      *bool  `json:"makeup"`
+		Notify      *bool  `json:"notify"` // default true; guardian-initiated cancellations aren't echoed back
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	// Only admins choose the party or override the makeup and notify defaults
+	if callerTeacher != "" {
+		input.Party = "teacher"
+	} else if callerSub != 0 {
+		input.Party = "guardian"
+	}
+	if !admin {
+		input.Makeup, input.Notify = nil, nil
+	}
+
+	fields := map[string]string{}
+	switch input.Party {
+	case "teacher", "guardian", "admin":
+	default:
+		fields["party"] = "must be teacher, guardian or admin"
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		fields["reason"] = "is required"
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	var subId int
+	var subject, teacherID, sessionTime, status, studentName, to, channel string
+	var sessionDate time.Time
+	err := db.QueryRow(`
+		SELECT cs.subscription_id, cs.subject, COALESCE(cs.teacher_id, ''), COALESCE(cs.session_time, ''), cs.status,
+		       cs.session_date, s.student_name, COALESCE(NULLIF(s.guardian_phone, ''), s.student_phone, ''),
+		       COALESCE(s.preferred_channel, 'whatsapp')
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		WHERE cs.id = $1
+	`, id).Scan(&subId, &subject, &teacherID, &sessionTime, &status, &sessionDate, &studentName, &to, &channel)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+	if (callerTeacher != "" && callerTeacher != teacherID) || (callerSub != 0 && callerSub != subId) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "You can only cancel your own sessions"})
+		return
+	}
+	if status != "scheduled" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Only scheduled sessions can be cancelled (this one is " + status + ")"})
+		return
+	}
+
+	start, _ := sessionStart(sessionDate, sessionTime, teacherLocation(teacherID))
+	late := input.Party == "guardian" && time.Until(start) < lateCancelNoticeHours*time.Hour
+	if late && input.Makeup != nil && *input.Makeup {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+			"makeup": "is not given for late guardian cancellations; the teacher is paid for the class",
+		}))
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.class_sessions
+		SET status = 'cancelled', cancelled_party = $1, cancelled_by = NULLIF($2, ''), cancel_reason = $3,
+		    cancelled_at = NOW(), late_cancellation = $4, updated_at = NOW()
+		WHERE id = $5 AND status = 'scheduled'
+	`, input.Party, input.CancelledBy, input.Reason, late, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Session changed meanwhile; reload and try again"})
+		return
+	}
+
+	date := sessionDate.Format("2006-01-02")
+	response := gin.H{"success": true, "late_cancellation": late, "message": "Session cancelled"}
+
+	makeup := input.Party != "guardian"
+	if input.Makeup != nil {
+		makeup = *input.Makeup
+	}
+	if makeup {
+		var creditID int
+		err := db.QueryRow(`
+			INSERT INTO mentor.makeup_credits (subscription_id, cancelled_date, reason, class_session_id)
+			VALUES ($1, $2, $3, $4) ON CONFLICT (class_session_id) WHERE class_session_id IS NOT NULL DO NOTHING
+			RETURNING id
+		`, subId, date, input.Reason, id).Scan(&creditID)
+		if err == nil {
+			response["makeup_credit_id"] = creditID
+		}
+	}
+
+	notify := input.Party != "guardian"
+	if input.Notify != nil {
+		notify = *input.Notify
+	}
+	if notify && to != "" {
+		msg := fmt.Sprintf("%s's %s class on %s is cancelled (%s).", studentName, subject, sessionDate.Format("Mon 2 Jan"), input.Reason)
+		if makeup {
+			msg += " A makeup class will be scheduled."
+		}
+		if err := notifySubscription(subId, channel, to, "class_cancellation", msg, input.CancelledBy); err != nil {
+			response["notify_error"] = err.Error()
+		} else {
+			response["guardian_notified"] = true
+		}
+	}
+
+	logAudit("subscription", subId, "session_cancelled", input.CancelledBy, gin.H{
+		"session_id": id,
+		"subject":    subject,
+		"date":       date,
+		"party":      input.Party,
+		"moved":      subject + " " + date + " " + sessionTime + " by " + input.Party,
+		"reason":     input.Reason,
+		"makeup":     makeup,
+		"late":       late,
+	})
+	syncGoogleCalendarLater(teacherID)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// loadCancelledSessions counts a teacher's cancelled sessions in a month by
+// who cancelled, and how many were late guardian cancellations
+func loadCancelledSessions(teacherID string, monthStart time.Time) (gin.H, int, error) {
+	counts := gin.H{"teacher": 0, "guardian": 0, "admin": 0}
+	late := 0
+	rows, err := db.Query(`
+		SELECT COALESCE(cancelled_party, 'admin'), COUNT(*), COUNT(*) FILTER (WHERE late_cancellation)
+		FROM mentor.class_sessions
+		WHERE teacher_id = $1 AND status = 'cancelled' AND session_date >= $2 AND session_date < $3
+		GROUP BY 1
+	`, teacherID, monthStart, monthStart.AddDate(0, 1, 0))
+	if err != nil {
+		return counts, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var party string
+		var n, lateN int
+		if err := rows.Scan(&party, &n, &lateN); err != nil {
+			continue
+		}
+		counts[party] = n
+		late += lateN
+	}
+	return counts, late, nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// =====================================================
+// STAGING CLONE (Anonymized copy of production data)
+// =====================================================
+
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// anonymizationRules replace PII columns with deterministic fakes. {salt} is the
+// quoted salt, so the same real value always maps to the same fake value
+// (e.g. a student's name matches across subscriptions and answer_papers).
+var anonymizationRules = []struct {
+	table, column, expr string
+}{
+	{"subscriptions", "student_name", `'Student ' || upper(substr(md5({salt} || student_name), 1, 6))`},
+	{"subscriptions", "student_phone", anonPhoneSQL("student_phone")},
+	{"subscriptions", "guardian_name", `CASE WHEN guardian_name IS NULL OR guardian_name = '' THEN guardian_name ELSE 'Guardian ' || upper(substr(md5({salt} || guardian_name), 1, 6)) END`},
+	{"subscriptions", "guardian_phone", anonPhoneSQL("guardian_phone")},
+	{"teachers", "name", `'Teacher ' || upper(substr(md5({salt} || name), 1, 6))`},
+	{"teachers", "phone", anonPhoneSQL("phone")},
+	{"teachers", "password", `'staging'`},
+	{"teachers", "calendar_token", `NULL`},
+	{"teachers", "email", `CASE WHEN email IS NULL THEN NULL ELSE 'teacher_' || id || '@example.com' END`},
+	{"answer_papers", "student_name", `'Student ' || upper(substr(md5({salt} || student_name), 1, 6))`},
+	{"answer_papers", "image_urls", `'[]'`},
+	{"attendance", "latitude", `round(latitude, 2)`},
+	{"attendance", "longitude", `round(longitude, 2)`},
+	{"guardian_reports", "summary", `'[redacted]'`},
+	{"guardian_reports", "data", `'{}'`},
+	{"online_sessions", "meeting_link", `NULL`},
+	{"online_sessions", "recording_url", `NULL`},
+	{"teacher_documents", "object_key", `'redacted'`},
+	{"attendance", "photo_key", `NULL`},
+	{"attendance", "photo_thumb_key", `NULL`},
+	{"teacher_google_calendars", "google_email", `NULL`},
+	{"teacher_google_calendars", "refresh_token", `NULL`},
+	{"teacher_google_calendars", "access_token", `NULL`},
+	{"teachers", "address", `NULL`},
+	{"teachers", "photo_url", `NULL`},
+	{"teachers", "available_days", `'{}'`},
+	{"teachers", "available_from", `NULL`},
+	{"teachers", "available_to", `NULL`},
+	{"teacher_identities", "email", `CASE WHEN email IS NULL THEN NULL ELSE 'teacher_' || teacher_id || '@example.com' END`},
+	{"subscriptions", "student_pin_hash", `NULL`},
+	{"subscriptions", "student_pin_code_hash", `NULL`},
+	{"subscriptions", "area", `NULL`},
+	{"subscriptions", "postcode", `NULL`},
+	{"waitlist", "student_name", `'Student ' || upper(substr(md5({salt} || student_name), 1, 6))`},
+	{"waitlist", "student_phone", anonPhoneSQL("student_phone")},
+	{"waitlist", "guardian_name", `CASE WHEN guardian_name IS NULL OR guardian_name = '' THEN guardian_name ELSE 'Guardian ' || upper(substr(md5({salt} || guardian_name), 1, 6)) END`},
+	{"waitlist", "guardian_phone", anonPhoneSQL("guardian_phone")},
+	{"waitlist", "area", `NULL`},
+	{"waitlist", "notes", `NULL`},
+	{"billing_groups", "guardian_name", `CASE WHEN guardian_name IS NULL OR guardian_name = '' THEN guardian_name ELSE 'Guardian ' || upper(substr(md5({salt} || guardian_name), 1, 6)) END`},
+	{"billing_groups", "guardian_phone", anonPhoneSQL("guardian_phone")},
+	{"communications", "recipient", `'redacted'`},
+	{"communications", "message", `'[redacted]'`},
+	{"subscription_notes", "body", `'[redacted]'`},
+	{"teacher_ratings", "comment", `NULL`},
+	{"teacher_ratings", "moderation_note", `NULL`},
+	{"attendance_corrections", "reason", `'[redacted]'`},
+	{"attendance_corrections", "decision_note", `NULL`},
+	{"answer_papers", "processed_image_urls", `NULL`},
+	{"image_jobs", "source", `'redacted'`},
+	{"image_jobs", "result_url", `NULL`},
+	{"exam_submissions", "student_name", `'Student ' || upper(substr(md5({salt} || student_name), 1, 6))`},
+	{"exam_submissions", "image_data", `NULL`},
+	{"exam_submissions", "image_key", `NULL`},
+	{"exam_submissions", "transcription", `NULL`},
+	{"exam_submissions", "answer_key", `NULL`},
+	{"exam_gradings", "transcription", `NULL`},
+	{"exam_gradings", "answer_key", `NULL`},
+	// Audit details repeat reasons, notes and emails redacted above; admin
+	// sign-ins are logged under their email
+	{"audit_log", "details", `'{}'`},
+	{"audit_log", "actor", anonEmailSQL("actor")},
+	{"audit_log", "entity_id", anonEmailSQL("entity_id")},
+}
+
+// stagingEmptyTables are copied without rows: session tokens are live
+// credentials and have no use in staging
+var stagingEmptyTables = map[string]bool{
+	"student_sessions": true,
+	"teacher_sessions": true,
+}
+
+// anonEmailSQL maps a column that may hold an email to a stable fake one,
+// leaving other values (ids, "system") as they are
+func anonEmailSQL(column string) string {
+	return `CASE WHEN ` + column + ` LIKE '%@%' THEN 'user_' || substr(md5({salt} || ` + column + `), 1, 8) || '@example.com'
+		ELSE ` + column + ` END`
+}
+
+// anonPhoneSQL maps a phone column to a stable fake 11-digit number
+func anonPhoneSQL(column string) string {
+	return `CASE WHEN ` + column + ` IS NULL OR ` + column + ` = '' THEN ` + column + `
+		ELSE '01' || lpad((('x' || substr(md5({salt} || ` + column + `), 1, 8))::bit(32)::bigint % 1000000000)::text, 9, '0') END`
+}
+
+// cloneToStaging copies every mentor table into the target schema and
+// anonymizes PII in place. Returns the row count copied per table.
+func cloneToStaging(schema string) (map[string]int64, error) {
+	if !schemaNamePattern.MatchString(schema) || schema == "mentor" || schema == "public" {
+		return nil, fmt.Errorf("invalid staging schema %q", schema)
+	}
+
+	salt := os.Getenv("STAGING_ANON_SALT")
+	if salt == "" {
+		salt = "mentor-staging"
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tableRows, err := tx.Query(`
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'mentor' AND table_type = 'BASE TABLE'
+		ORDER BY table_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for tableRows.Next() {
+		var name string
+		tableRows.Scan(&name)
+		tables = append(tables, name)
+	}
+	tableRows.Close()
+
+	target := pq.QuoteIdentifier(schema)
+	if _, err := tx.Exec("CREATE SCHEMA IF NOT EXISTS " + target); err != nil {
+		return nil, err
+	}
+
+	copied := map[string]int64{}
+	for _, table := range tables {
+		src := "mentor." + pq.QuoteIdentifier(table)
+		dst := target + "." + pq.QuoteIdentifier(table)
+
+		stmts := []string{
+			"DROP TABLE IF EXISTS " + dst + " CASCADE",
+			"CREATE TABLE " + dst + " (LIKE " + src + " INCLUDING ALL)",
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return nil, fmt.Errorf("%s: %w", table, err)
+			}
+		}
+
+		copied[table] = 0
+		if !stagingEmptyTables[table] {
+			result, err := tx.Exec("INSERT INTO " + dst + " SELECT * FROM " + src)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", table, err)
+			}
+			copied[table], _ = result.RowsAffected()
+		}
+
+		if err := detachSequences(tx, schema, table); err != nil {
+			return nil, fmt.Errorf("%s: %w", table, err)
+		}
+	}
+
+	for _, rule := range anonymizationRules {
+		var exists bool
+		tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM information_schema.columns
+			              WHERE table_schema = $1 AND table_name = $2 AND column_name = $3)
+		`, schema, rule.table, rule.column).Scan(&exists)
+		if !exists {
+			continue
+		}
+
+		stmt := fmt.Sprintf("UPDATE %s.%s SET %s = %s", target, pq.QuoteIdentifier(rule.table),
+			pq.QuoteIdentifier(rule.column), strings.ReplaceAll(rule.expr, "{salt}", pq.QuoteLiteral(salt)))
+		if _, err := tx.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("anonymize %s.%s: %w", rule.table, rule.column, err)
+		}
+	}
+
+	return copied, tx.Commit()
+}
+
+// detachSequences gives cloned serial columns their own sequences, so inserts
+// in staging don't advance production sequences.
+func detachSequences(tx *sql.Tx, schema, table string) error {
+	rows, err := tx.Query(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2 AND column_default LIKE 'nextval(%'
+	`, schema, table)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	for rows.Next() {
+		var col string
+		rows.Scan(&col)
+		columns = append(columns, col)
+	}
+	rows.Close()
+
+	for _, col := range columns {
+		seq := pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table+"_"+col+"_seq")
+		dst := pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+		qcol := pq.QuoteIdentifier(col)
+
+		stmts := []string{
+			"CREATE SEQUENCE IF NOT EXISTS " + seq + " OWNED BY " + dst + "." + qcol,
+			"SELECT setval(" + pq.QuoteLiteral(seq) + ", COALESCE((SELECT MAX(" + qcol + ") FROM " + dst + "), 0) + 1, false)",
+			"ALTER TABLE " + dst + " ALTER COLUMN " + qcol + " SET DEFAULT nextval(" + pq.QuoteLiteral(seq) + ")",
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cloneStaging - Admin endpoint: refresh the anonymized staging schema
+func cloneStaging(c *gin.Context) {
+	var input struct {
+		Schema string `json:"schema"`
+	}
+	c.ShouldBindJSON(&input)
+	if input.Schema == "" {
+		input.Schema = "mentor_staging"
+	}
+
+	copied, err := cloneToStaging(input.Schema)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("schema", input.Schema, "staging_clone", "admin", gin.H{"tables": copied})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "schema": input.Schema, "tables": copied})
+}
+
+// runStagingCloneCommand handles `./main clone-staging [schema]`
+func runStagingCloneCommand(args []string) {
+	schema := "mentor_staging"
+	if len(args) > 0 {
+		schema = args[0]
+	}
+
+	copied, err := cloneToStaging(schema)
+	if err != nil {
+		log.Fatal("Staging clone failed:", err)
+	}
+	for table, n := range copied {
+		log.Printf("%s.%s: %d rows", schema, table, n)
+	}
+	log.Println("Staging clone complete")
+}
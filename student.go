@@ -0,0 +1,588 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// STUDENT SELF-SERVICE (PIN login)
+// ============================================
+
+var studentPinPattern = regexp.MustCompile(`^[0-9]{4,6}$`)
+
+// maxStudentPinFailures locks the PIN until the guardian sets a new one
+const maxStudentPinFailures = 5
+
+const (
+	studentPinCodeTTL         = 10 * time.Minute
+	studentPinCodeResendAfter = time.Minute
+	maxStudentPinCodeFailures = 5 // wrong codes before a new one must be sent
+)
+
+func hashStudentPin(pin, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + pin))
+	return salt + "$" + hex.EncodeToString(sum[:])
+}
+
+func checkStudentPin(pin, stored string) bool {
+	salt, _, ok := strings.Cut(stored, "$")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashStudentPin(pin, salt)), []byte(stored)) == 1
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sendStudentPinCode - Text a one-time code to the guardian's phone on the
+// subscription; the guardian enters it to set the student's PIN
+func sendStudentPinCode(c *gin.Context) {
+	id := c.Param("id")
+
+	var guardianPhone sql.NullString
+	var sentAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT guardian_phone, student_pin_code_sent_at FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, id).Scan(&guardianPhone, &sentAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if !guardianPhone.Valid || guardianPhone.String == "" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "No guardian phone on this subscription"})
+		return
+	}
+	if sentAt.Valid && time.Since(sentAt.Time) < studentPinCodeResendAfter {
+		c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "error": "A code was just sent; wait a minute before asking again"})
+		return
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	salt, err := randomHex(8)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.subscriptions
+		SET student_pin_code_hash = $1, student_pin_code_expires_at = $2, student_pin_code_sent_at = NOW(),
+		    student_pin_code_failures = 0
+		WHERE id = $3
+	`, hashStudentPin(code, salt), time.Now().Add(studentPinCodeTTL), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("Your code to set the student PIN is %s. It expires in %d minutes.", code, int(studentPinCodeTTL.Minutes()))
+	if err := notifySubscription(id, "sms", guardianPhone.String, "student_pin_code", message, "system"); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Could not send the code: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "expires_in_minutes": int(studentPinCodeTTL.Minutes()),
+		"message": "Code sent to the guardian's phone"})
+}
+
+// checkStudentPinCode consumes the guardian's one-time code for a subscription.
+// A wrong code counts towards maxStudentPinCodeFailures.
+func checkStudentPinCode(id, code string) bool {
+	var codeHash sql.NullString
+	var expiresAt sql.NullTime
+	var failures int
+	db.QueryRow(`
+		SELECT student_pin_code_hash, student_pin_code_expires_at, student_pin_code_failures
+		FROM mentor.subscriptions WHERE id = $1
+	`, id).Scan(&codeHash, &expiresAt, &failures)
+	if !codeHash.Valid || !expiresAt.Valid || time.Now().After(expiresAt.Time) || failures >= maxStudentPinCodeFailures {
+		return false
+	}
+	if !checkStudentPin(code, codeHash.String) {
+		db.Exec("UPDATE mentor.subscriptions SET student_pin_code_failures = student_pin_code_failures + 1 WHERE id = $1", id)
+		return false
+	}
+	db.Exec(`
+		UPDATE mentor.subscriptions SET student_pin_code_hash = NULL, student_pin_code_expires_at = NULL WHERE id = $1
+	`, id)
+	return true
+}
+
+// setStudentPin - Set the student's PIN. Admins and the subscription's
+// teachers use their token; the guardian sends the one-time code texted by
+// sendStudentPinCode.
+func setStudentPin(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Pin  string `json:"pin"`
+		Code string `json:"code"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if !studentPinPattern.MatchString(input.Pin) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "pin must be 4-6 digits"})
+		return
+	}
+
+	subId, err := strconv.Atoi(id)
+	var exists bool
+	if err == nil {
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL)", subId).Scan(&exists)
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	actor := "guardian"
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if isAdminRequest(c) {
+		actor = "admin"
+	} else if teacherID, ok := sessionTeacher(token); token != "" && ok && teachesSubscription(teacherID, subId) {
+		actor = teacherID
+	} else if input.Code == "" || !checkStudentPinCode(id, input.Code) {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "A valid code from the guardian's phone, or an admin or teacher token, is required"})
+		return
+	}
+
+	salt, err := randomHex(8)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.subscriptions
+		SET student_pin_hash = $1, student_pin_approved_at = NOW(), student_pin_failures = 0, updated_at = NOW()
+		WHERE id = $2
+	`, hashStudentPin(input.Pin, salt), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	// A new PIN signs out existing student sessions
+	db.Exec("DELETE FROM mentor.student_sessions WHERE subscription_id = $1", id)
+
+	logAudit("subscription", id, "student_pin_set", actor, nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Student PIN saved"})
+}
+
+// studentLogin - Exchange subscription id + PIN for a session token
+func studentLogin(c *gin.Context) {
+	var input struct {
+		SubscriptionID int    `json:"subscription_id"`
+		Pin            string `json:"pin"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var studentName string
+	var pinHash sql.NullString
+	var failures int
+	err := db.QueryRow(`
+		SELECT student_name, student_pin_hash, student_pin_failures
+		FROM mentor.subscriptions
+		WHERE id = $1 AND status = 'active' AND deleted_at IS NULL
+	`, input.SubscriptionID).Scan(&studentName, &pinHash, &failures)
+
+	if err != nil || !pinHash.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid student or PIN"})
+		return
+	}
+	if failures >= maxStudentPinFailures {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "Too many attempts. Ask your guardian to reset the PIN"})
+		return
+	}
+	if !checkStudentPin(input.Pin, pinHash.String) {
+		db.Exec("UPDATE mentor.subscriptions SET student_pin_failures = student_pin_failures + 1 WHERE id = $1", input.SubscriptionID)
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid student or PIN"})
+		return
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	expiresAt := time.Now().AddDate(0, 0, 30)
+
+	_, err = db.Exec(`
+		INSERT INTO mentor.student_sessions (token, subscription_id, expires_at) VALUES ($1, $2, $3)
+	`, token, input.SubscriptionID, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	db.Exec("UPDATE mentor.subscriptions SET student_pin_failures = 0 WHERE id = $1", input.SubscriptionID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"token":      token,
+		"expires_at": isoTimestamp(expiresAt),
+		"student": gin.H{
+			"subscription_id": input.SubscriptionID,
+			"name":            studentName,
+		},
+	})
+}
+
+// studentAuth resolves "Authorization: Bearer <token>" to the student's subscription
+func studentAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Student token required"})
+			return
+		}
+
+		subId, ok := sessionSubscription(token)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Session expired, please log in again"})
+			return
+		}
+
+		c.Set("subscription_id", subId)
+		c.Next()
+	}
+}
+
+// sessionSubscription resolves a student/guardian session token to its subscription
+func sessionSubscription(token string) (int, bool) {
+	var subId int
+	err := db.QueryRow(`
+		SELECT ss.subscription_id FROM mentor.student_sessions ss
+		JOIN mentor.subscriptions s ON s.id = ss.subscription_id
+		WHERE ss.token = $1 AND ss.expires_at > NOW() AND s.deleted_at IS NULL
+	`, token).Scan(&subId)
+	return subId, err == nil
+}
+
+// guardianOrAdmin lets admins through, and the student app's guardian only
+// for their own subscription ID in the named route param
+func guardianOrAdmin(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdminRequest(c) {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		subId, ok := sessionSubscription(token)
+		if token == "" || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Student or admin token required"})
+			return
+		}
+		if strconv.Itoa(subId) != c.Param(param) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "error": "You can only view your own records"})
+			return
+		}
+		c.Set("subscription_id", subId)
+		c.Next()
+	}
+}
+
+// getStudentHomework - Homework due today or later
+func getStudentHomework(c *gin.Context) {
+	homework, err := queryHomework(c.GetInt("subscription_id"), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "homework": homework})
+}
+
+// getStudentUpcoming - Scheduled class days for the next week, skipping holidays
+func getStudentUpcoming(c *gin.Context) {
+	subId := c.GetInt("subscription_id")
+
+	var schedTime, status, teacherID string
+	var scheduleDays []string
+	err := db.QueryRow(`
+		SELECT schedule_day_list, time, status, teacher_id FROM mentor.subscriptions WHERE id = $1
+	`, subId).Scan(pq.Array(&scheduleDays), &schedTime, &status, &teacherID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	classes := []gin.H{}
+	if status != "active" {
+		c.JSON(http.StatusOK, gin.H{"success": true, "classes": classes})
+		return
+	}
+
+	today := time.Now()
+	from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 7)
+
+	holidays := map[string]string{}
+	hRows, err := db.Query(`
+		SELECT date, name FROM mentor.holidays
+		WHERE date >= $1 AND date < $2 AND (teacher_id IS NULL OR teacher_id = $3)
+	`, from, to, teacherID)
+	if err == nil {
+		for hRows.Next() {
+			var d time.Time
+			var name string
+			hRows.Scan(&d, &name)
+			holidays[d.Format("2006-01-02")] = name
+		}
+		hRows.Close()
+	}
+
+	// Where each subject is up to
+	var subjects []gin.H
+	sRows, err := db.Query(`
+		SELECT subject, current_chapter, current_part FROM mentor.schedule
+		WHERE subscription_id = $1 ORDER BY id
+	`, subId)
+	if err == nil {
+		for sRows.Next() {
+			var subject string
+			var chapter, part int
+			sRows.Scan(&subject, &chapter, &part)
+			subjects = append(subjects, gin.H{"subject": subject, "current_chapter": chapter, "current_part": part})
+		}
+		sRows.Close()
+	}
+
+	weekdays := scheduledWeekdays(scheduleDays)
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		if !weekdays[d.Weekday()] {
+			continue
+		}
+		key := d.Format("2006-01-02")
+		if name, ok := holidays[key]; ok {
+			classes = append(classes, gin.H{"date": key, "day": d.Format("Mon"), "holiday": name})
+			continue
+		}
+		classes = append(classes, gin.H{"date": key, "day": d.Format("Mon"), "time": schedTime})
+	}
+
+	if subjects == nil {
+		subjects = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "classes": classes, "subjects": subjects})
+}
+
+// getStudentTests - The student's test attempts and scores
+func getStudentTests(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT id, subject, chapter_number, COALESCE(chapter_name, ''), status,
+		       actual_marks, total_marks, created_at
+		FROM mentor.answer_papers
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC LIMIT 50
+	`, c.GetInt("subscription_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	attempts := []gin.H{}
+	for rows.Next() {
+		var id int
+		var subject, chapterName, status string
+		var chapter sql.NullInt64
+		var actualMarks, totalMarks sql.NullFloat64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &subject, &chapter, &chapterName, &status, &actualMarks, &totalMarks, &createdAt); err != nil {
+			continue
+		}
+
+		attempt := gin.H{
+			"id":           id,
+			"subject":      subject,
+			"chapter_name": chapterName,
+			"status":       status,
+			"submitted_at": isoTimestamp(createdAt),
+		}
+		if chapter.Valid {
+			attempt["chapter_number"] = chapter.Int64
+		}
+		// Marks are only shown once graded
+		if status == "graded" && actualMarks.Valid && totalMarks.Valid {
+			attempt["actual_marks"] = actualMarks.Float64
+			attempt["total_marks"] = totalMarks.Float64
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "attempts": attempts})
+}
+
+// studentBadges are earned from progress and test history; each query returns
+// a single boolean for subscription $1
+var studentBadges = []struct {
+	code, title, query string
+}{
+	{"first_class", "First class completed",
+		`SELECT EXISTS(SELECT 1 FROM mentor.progress WHERE subscription_id = $1)`},
+	{"ten_classes", "10 classes completed",
+		`SELECT COUNT(*) >= 10 FROM mentor.progress WHERE subscription_id = $1`},
+	{"fifty_classes", "50 classes completed",
+		`SELECT COUNT(*) >= 50 FROM mentor.progress WHERE subscription_id = $1`},
+	{"chapter_finisher", "Finished a chapter",
+		`SELECT EXISTS(SELECT 1 FROM mentor.schedule WHERE subscription_id = $1 AND current_chapter > 1)`},
+	{"top_score", "Scored 90% or more in a test",
+		`SELECT EXISTS(SELECT 1 FROM mentor.answer_papers WHERE subscription_id = $1 AND status = 'graded'
+		 AND actual_marks::float / NULLIF(total_marks, 0) >= 0.9)`},
+	{"four_week_streak", "Classes every week for 4 weeks",
+		`SELECT COUNT(DISTINCT date_trunc('week', completed_at)) >= 4 FROM mentor.progress
+		 WHERE subscription_id = $1 AND completed_at >= date_trunc('week', NOW()) - INTERVAL '3 weeks'`},
+}
+
+// getStudentBadges - Achievement badges, earned and still locked
+func getStudentBadges(c *gin.Context) {
+	subId := c.GetInt("subscription_id")
+
+	badges := []gin.H{}
+	for _, b := range studentBadges {
+		var earned bool
+		db.QueryRow(b.query, subId).Scan(&earned)
+		badges = append(badges, gin.H{"code": b.code, "title": b.title, "earned": earned})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "badges": badges})
+}
+
+// ============================================
+// HOMEWORK (Assigned by the teacher)
+// ============================================
+func assignHomework(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Subject     string `json:"subject"`
+		Chapter     *int   `json:"chapter"`
+		Description string `json:"description"`
+		DueDate     string `json:"due_date"` // YYYY-MM-DD, defaults to today
+		AssignedBy  string `json:"assigned_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(input.Description) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "description is required"})
+		return
+	}
+	if input.DueDate == "" {
+		input.DueDate = time.Now().Format("2006-01-02")
+	}
+
+	var homeworkID int
+	err := db.QueryRow(`
+		INSERT INTO mentor.homework (subscription_id, subject, chapter, description, due_date, assigned_by)
+		SELECT id, NULLIF($2, ''), $3, $4, $5, NULLIF($6, '')
+		FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`, id, input.Subject, input.Chapter, input.Description, input.DueDate, input.AssignedBy).Scan(&homeworkID)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": homeworkID, "message": "Homework assigned"})
+}
+
+func getHomework(c *gin.Context) {
+	var subId int
+	if err := db.QueryRow("SELECT id FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL", c.Param("id")).Scan(&subId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	homework, err := queryHomework(subId, c.Query("upcoming") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "homework": homework})
+}
+
+func queryHomework(subId int, upcomingOnly bool) ([]gin.H, error) {
+	query := `
+		SELECT id, COALESCE(subject, ''), chapter, description, due_date, COALESCE(assigned_by, ''), created_at
+		FROM mentor.homework WHERE subscription_id = $1`
+	if upcomingOnly {
+		query += " AND due_date >= CURRENT_DATE"
+	}
+	query += " ORDER BY due_date, id LIMIT 100"
+
+	rows, err := db.Query(query, subId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	homework := []gin.H{}
+	for rows.Next() {
+		var id int
+		var subject, description, assignedBy string
+		var chapter sql.NullInt64
+		var dueDate, createdAt time.Time
+		if err := rows.Scan(&id, &subject, &chapter, &description, &dueDate, &assignedBy, &createdAt); err != nil {
+			continue
+		}
+		item := gin.H{
+			"id":          id,
+			"subject":     subject,
+			"description": description,
+			"due_date":    dueDate.Format("2006-01-02"),
+			"assigned_by": assignedBy,
+			"assigned_at": isoTimestamp(createdAt),
+		}
+		if chapter.Valid {
+			item["chapter"] = chapter.Int64
+		}
+		homework = append(homework, item)
+	}
+	return homework, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// STUDENT ATTENDANCE (Present / absent / late per session)
+// ============================================
+
+var studentStatuses = map[string]bool{"present": true, "absent": true, "late": true}
+
+// setSessionStudentStatus records whether the student attended a session
+func setSessionStudentStatus(sessionID int, status, reason string) error {
+	_, err := db.Exec(`
+		UPDATE mentor.class_sessions
+		SET student_status = $1, student_status_reason = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $3
+	`, status, reason, sessionID)
+	return err
+}
+
+// recordStudentAbsence closes today's session for the subject with the
+// student absent. No progress is logged, so the class isn't used up.
+func recordStudentAbsence(c *gin.Context, subId, subject, teacherID, reason string) {
+	sessionID, err := completeClassSession(subId, subject, teacherID, sql.NullInt64{})
+	if err == nil {
+		err = setSessionStudentStatus(sessionID, "absent", reason)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", subId, "student_absent", teacherID, gin.H{
+		"session_id": sessionID,
+		"subject":    subject,
+		"reason":     reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"student_status":   "absent",
+		"class_session_id": sessionID,
+		"message":          "Absence recorded; the class isn't counted",
+	})
+}
+
+// getStudentAttendance - A subscription's sessions with the student's
+// attendance over a date range (default the last 30 days), with counts
+func getStudentAttendance(c *gin.Context) {
+	subId := c.Param("id")
+
+	from, to, ok := parsePastDateRange(c, 30)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, session_date, COALESCE(session_time, ''), subject, COALESCE(teacher_id, ''),
+		       student_status, COALESCE(student_status_reason, '')
+		FROM mentor.class_sessions
+		WHERE subscription_id = $1 AND session_date BETWEEN $2 AND $3 AND student_status IS NOT NULL
+		ORDER BY session_date DESC, session_time, id
+	`, subId, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	sessions := []gin.H{}
+	counts := gin.H{"present": 0, "late": 0, "absent": 0}
+	for rows.Next() {
+		var id int
+		var date time.Time
+		var sessionTime, subject, teacherID, status, reason string
+		if err := rows.Scan(&id, &date, &sessionTime, &subject, &teacherID, &status, &reason); err != nil {
+			continue
+		}
+		sessions = append(sessions, gin.H{
+			"class_session_id": id,
+			"date":             date.Format("2006-01-02"),
+			"time":             sessionTime,
+			"subject":          subject,
+			"teacher_id":       teacherID,
+			"student_status":   status,
+			"reason":           reason,
+		})
+		counts[status] = counts[status].(int) + 1
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"counts":   counts,
+		"sessions": sessions,
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import "fmt"
+
+// ============================================
+// PER-SUBJECT PRICING
+// ============================================
+
+// subjectPriceTotal sums the price list over a subscription's subjects. Every
+// subject must be priced so the total can replace the single amount.
+func subjectPriceTotal(subjects []string, prices map[string]float64) (float64, error) {
+	total := float64(0)
+	for _, subj := range subjects {
+		price, ok := prices[subj]
+		if !ok {
+			return 0, fmt.Errorf("subject_prices is missing a price for %q", subj)
+		}
+		if price < 0 {
+			return 0, fmt.Errorf("price for %q cannot be negative", subj)
+		}
+		total += price
+	}
+	return total, nil
+}
+
+// applySubjectPrices stores the price list on the schedule rows and sets the
+// subscription amount to their total
+func applySubjectPrices(subId interface{}, prices map[string]float64) (float64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for subject, price := range prices {
+		if _, err := tx.Exec(`
+			UPDATE mentor.schedule SET price = $1
+			WHERE subscription_id = $2 AND LOWER(subject) = LOWER($3)
+		`, price, subId, subject); err != nil {
+			return 0, err
+		}
+	}
+
+	var total float64
+	err = tx.QueryRow(`
+		UPDATE mentor.subscriptions
+		SET amount = (SELECT COALESCE(SUM(price), 0) FROM mentor.schedule WHERE subscription_id = $1),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING amount
+	`, subId).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, tx.Commit()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// PER-SUBJECT TEACHER ASSIGNMENT
+// ============================================
+
+// assignSubjectTeacher - Assign a teacher to one subject of a subscription.
+// An empty teacher_id hands the subject back to the subscription's teacher.
+func assignSubjectTeacher(c *gin.Context) {
+	subId := c.Param("id")
+	subject := c.Param("subject")
+
+	var input struct {
+		TeacherID  string `json:"teacher_id"`
+		AssignedBy string `json:"assigned_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var gaps []capabilityGap
+	if input.TeacherID != "" {
+		var exists bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1 AND COALESCE(active, 1) = 1)", input.TeacherID).Scan(&exists)
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Teacher not found or inactive"})
+			return
+		}
+
+		var class int
+		db.QueryRow("SELECT class FROM mentor.subscriptions WHERE id = $1", subId).Scan(&class)
+		gaps = capabilityGaps(class, input.TeacherID, []string{subject}, nil)
+		if len(gaps) > 0 && capabilitiesEnforced() {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": gaps[0].String()})
+			return
+		}
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.schedule SET teacher_id = NULLIF($1, '')
+		WHERE subscription_id = $2 AND LOWER(subject) = LOWER($3)
+	`, input.TeacherID, subId, subject)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subject not found on this subscription"})
+		return
+	}
+
+	logAudit("subscription", subId, "subject_teacher_assigned", input.AssignedBy, gin.H{
+		"subject":    subject,
+		"teacher_id": input.TeacherID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Subject teacher updated", "capability_warnings": capabilityWarnings(gaps)})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ============================================
+// SUBJECT / DAY LISTS
+// ============================================
+
+// stringList is a JSON list field that also accepts the legacy
+// comma-separated string form, e.g. "Math,English"
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var items []string
+	if err := json.Unmarshal(data, &items); err != nil {
+		var joined string
+		if err := json.Unmarshal(data, &joined); err != nil {
+			return errors.New("expected an array of strings or a comma-separated string")
+		}
+		items = strings.Split(joined, ",")
+	}
+
+	cleaned := stringList{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	*l = cleaned
+	return nil
+}
+
+// firstOrEmpty returns the first entry of a list, or "" for an empty list
+func firstOrEmpty(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return list[0]
+}
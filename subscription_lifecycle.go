@@ -0,0 +1,478 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// CANCEL SUBSCRIPTION (Keeps history)
+// ============================================
+func cancelSubscription(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Reason        string `json:"reason"`
+		EffectiveDate string `json:"effective_date"` // YYYY-MM-DD, defaults to today
+		CancelledBy   string `json:"cancelled_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "reason is required"})
+		return
+	}
+
+	effectiveDate := time.Now().Format("2006-01-02")
+	if input.EffectiveDate != "" {
+		if _, err := time.Parse("2006-01-02", input.EffectiveDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "effective_date must be YYYY-MM-DD"})
+			return
+		}
+		effectiveDate = input.EffectiveDate
+	}
+
+	var status string
+	err := db.QueryRow("SELECT status FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL", id).Scan(&status)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Subscription is already cancelled"})
+		return
+	}
+
+	// A later effective date only schedules the cancellation: the subscription
+	// stays active (classes and billing run through end_date) and the
+	// apply-scheduled-cancellations job cancels it the day after
+	if effectiveDate > localToday().Format("2006-01-02") {
+		_, err = db.Exec(`
+			UPDATE mentor.subscriptions
+			SET cancel_reason = $1, cancelled_by = $2, end_date = $3, updated_at = NOW()
+			WHERE id = $4
+		`, input.Reason, input.CancelledBy, effectiveDate, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		logAudit("subscription", id, "cancellation_scheduled", input.CancelledBy, gin.H{
+			"reason":         input.Reason,
+			"effective_date": effectiveDate,
+		})
+		requestClassSessionSync()
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"status":         status,
+			"scheduled":      true,
+			"effective_date": effectiveDate,
+			"message":        "Subscription will be cancelled after " + effectiveDate,
+		})
+		return
+	}
+
+	// end_date is the last billable day; schedule queries only look at active rows
+	_, err = db.Exec(`
+		UPDATE mentor.subscriptions
+		SET status = 'cancelled', cancel_reason = $1, cancelled_by = $2,
+		    cancelled_at = NOW(), end_date = $3, updated_at = NOW()
+		WHERE id = $4
+	`, input.Reason, input.CancelledBy, effectiveDate, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", id, "cancelled", input.CancelledBy, gin.H{
+		"reason":          input.Reason,
+		"effective_date":  effectiveDate,
+		"previous_status": status,
+	})
+	requestClassSessionSync()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"status":         "cancelled",
+		"effective_date": effectiveDate,
+		"message":        "Subscription cancelled",
+	})
+}
+
+// applyScheduledCancellations cancels subscriptions whose scheduled
+// cancellation date (end_date) has passed
+func applyScheduledCancellations() error {
+	rows, err := db.Query(`
+		UPDATE mentor.subscriptions
+		SET status = 'cancelled', cancelled_at = NOW(), updated_at = NOW()
+		WHERE status <> 'cancelled' AND deleted_at IS NULL AND cancel_reason IS NOT NULL
+		  AND cancelled_at IS NULL AND end_date IS NOT NULL AND end_date < $1
+		RETURNING id, COALESCE(cancelled_by, ''), cancel_reason, end_date
+	`, localToday().Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var cancelledBy, reason string
+		var endDate time.Time
+		if err := rows.Scan(&id, &cancelledBy, &reason, &endDate); err != nil {
+			continue
+		}
+		logAudit("subscription", id, "cancelled", cancelledBy, gin.H{
+			"reason":         reason,
+			"effective_date": endDate.Format("2006-01-02"),
+			"scheduled":      true,
+		})
+	}
+	return rows.Err()
+}
+
+// ============================================
+// TRANSFER TO ANOTHER TEACHER (With handover)
+// ============================================
+func transferSubscription(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		TeacherID     string `json:"teacher_id"`
+		Reason        string `json:"reason"`
+		TransferredBy string `json:"transferred_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.TeacherID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "teacher_id is required"})
+		return
+	}
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1 AND COALESCE(active, 1) = 1)", input.TeacherID).Scan(&exists)
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Teacher not found or inactive"})
+		return
+	}
+
+	var subId, class int
+	var previousTeacher, status string
+	var subjects []string
+	err := db.QueryRow(`
+		SELECT id, teacher_id, class, subject_list, status FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, id).Scan(&subId, &previousTeacher, &class, pq.Array(&subjects), &status)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if status != "active" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Only active subscriptions can be transferred (status is " + status + ")"})
+		return
+	}
+	if previousTeacher == input.TeacherID {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Subscription is already assigned to this teacher"})
+		return
+	}
+
+	// Subjects pinned to someone other than the two teachers keep their teacher
+	pinned := map[string]string{}
+	if pinRows, err := db.Query(`
+		SELECT subject, teacher_id FROM mentor.schedule
+		WHERE subscription_id = $1 AND teacher_id IS NOT NULL AND teacher_id NOT IN ($2, $3)
+	`, subId, previousTeacher, input.TeacherID); err == nil {
+		for pinRows.Next() {
+			var subject, teacherID string
+			if pinRows.Scan(&subject, &teacherID) == nil {
+				pinned[subject] = teacherID
+			}
+		}
+		pinRows.Close()
+	}
+	gaps := capabilityGaps(class, input.TeacherID, subjects, pinned)
+	if len(gaps) > 0 && capabilitiesEnforced() {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Teacher is not qualified for this subscription",
+			"capability_warnings": capabilityWarnings(gaps)})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE mentor.subscriptions SET teacher_id = $1, updated_at = NOW()
+		WHERE id = $2 AND status = 'active' AND deleted_at IS NULL
+	`, input.TeacherID, subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Subscription is no longer active"})
+		return
+	}
+
+	// Subjects pinned to either teacher now just follow the subscription's teacher;
+	// subjects taught by someone else stay with them
+	if _, err := tx.Exec(`
+		UPDATE mentor.schedule SET teacher_id = NULL
+		WHERE subscription_id = $1 AND teacher_id IN ($2, $3)
+	`, subId, previousTeacher, input.TeacherID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", subId, "transferred", input.TransferredBy, gin.H{
+		"from_teacher_id": previousTeacher,
+		"to_teacher_id":   input.TeacherID,
+		"reason":          input.Reason,
+	})
+	requestClassSessionSync()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"message":             "Subscription transferred",
+		"handover":            subscriptionHandover(subId, previousTeacher),
+		"capability_warnings": capabilityWarnings(gaps),
+	})
+}
+
+// subscriptionHandover summarises where a student is for an incoming teacher:
+// position per subject, the latest class notes and the last 30 days of visits
+func subscriptionHandover(subId int, previousTeacher string) gin.H {
+	subjects := []gin.H{}
+	rows, err := db.Query(`
+		SELECT subject, current_chapter, current_part, total_parts_done, total_parts_needed
+		FROM mentor.schedule WHERE subscription_id = $1 ORDER BY id
+	`, subId)
+	if err == nil {
+		for rows.Next() {
+			var subject string
+			var chapter, part, done, needed int
+			if err := rows.Scan(&subject, &chapter, &part, &done, &needed); err != nil {
+				continue
+			}
+			subjects = append(subjects, gin.H{
+				"subject":            subject,
+				"current_chapter":    chapter,
+				"current_part":       part,
+				"total_parts_done":   done,
+				"total_parts_needed": needed,
+			})
+		}
+		rows.Close()
+	}
+
+	notes := []gin.H{}
+	noteRows, err := db.Query(`
+		SELECT subject, chapter, part, COALESCE(teacher_id, ''), notes, completed_at
+		FROM mentor.progress
+		WHERE subscription_id = $1 AND notes IS NOT NULL AND notes <> ''
+		ORDER BY completed_at DESC LIMIT 10
+	`, subId)
+	if err == nil {
+		for noteRows.Next() {
+			var subject, teacherID, note string
+			var chapter, part int
+			var completedAt time.Time
+			if err := noteRows.Scan(&subject, &chapter, &part, &teacherID, &note, &completedAt); err != nil {
+				continue
+			}
+			notes = append(notes, gin.H{
+				"subject":    subject,
+				"chapter":    chapter,
+				"part":       part,
+				"teacher_id": teacherID,
+				"notes":      note,
+				"date":       completedAt.Format("2006-01-02"),
+			})
+		}
+		noteRows.Close()
+	}
+
+	var visits, classes int
+	var lastVisit sql.NullTime
+	db.QueryRow(`
+		SELECT COUNT(*), MAX(recorded_at) FROM mentor.attendance
+		WHERE subscription_id = $1 AND action = 'start' AND recorded_at >= NOW() - INTERVAL '30 days'
+	`, subId).Scan(&visits, &lastVisit)
+	db.QueryRow(`
+		SELECT COUNT(*) FROM mentor.progress
+		WHERE subscription_id = $1 AND completed_at >= NOW() - INTERVAL '30 days'
+	`, subId).Scan(&classes)
+
+	attendance := gin.H{"visits_last_30_days": visits, "classes_last_30_days": classes}
+	if lastVisit.Valid {
+		attendance["last_visit"] = lastVisit.Time.Format("2006-01-02")
+	}
+
+	return gin.H{
+		"subscription_id":  subId,
+		"previous_teacher": previousTeacher,
+		"subjects":         subjects,
+		"recent_notes":     notes,
+		"attendance":       attendance,
+	}
+}
+
+// ============================================
+// RESTORE / PURGE SOFT-DELETED SUBSCRIPTIONS
+// ============================================
+func restoreSubscription(c *gin.Context) {
+	id := c.Param("id")
+	restoredBy := c.Query("restored_by")
+
+	result, err := db.Exec(`
+		UPDATE mentor.subscriptions SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "No deleted subscription with this id"})
+		return
+	}
+
+	logAudit("subscription", id, "restored", restoredBy, nil)
+	requestClassSessionSync()
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Subscription restored"})
+}
+
+// getDeletedSubscriptions - Admin view of soft-deleted subscriptions awaiting purge
+func getDeletedSubscriptions(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT id, student_name, class, teacher_id, status, deleted_at, COALESCE(deleted_by, '')
+		FROM mentor.subscriptions
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	retention := softDeleteRetentionDays()
+
+	var subscriptions []gin.H
+	for rows.Next() {
+		var id, class int
+		var studentName, teacherID, status, deletedBy string
+		var deletedAt time.Time
+		if err := rows.Scan(&id, &studentName, &class, &teacherID, &status, &deletedAt, &deletedBy); err != nil {
+			continue
+		}
+		subscriptions = append(subscriptions, gin.H{
+			"id":           id,
+			"student_name": studentName,
+			"class":        class,
+			"teacher_id":   teacherID,
+			"status":       status,
+			"deleted_at":   isoTimestamp(deletedAt),
+			"deleted_by":   deletedBy,
+			"purge_after":  deletedAt.AddDate(0, 0, retention).Format("2006-01-02"),
+		})
+	}
+
+	if subscriptions == nil {
+		subscriptions = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "subscriptions": subscriptions, "retention_days": retention})
+}
+
+// purgeDeletedSubscriptions - Admin: run the purge job now
+func purgeDeletedSubscriptions(c *gin.Context) {
+	purged, err := purgeSoftDeleted()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "purged": purged})
+}
+
+// softDeleteRetentionDays is how long deleted subscriptions stay restorable
+func softDeleteRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("SOFT_DELETE_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		return 30
+	}
+	return days
+}
+
+// purgeSoftDeleted permanently removes subscriptions deleted longer ago than the
+// retention window, together with their schedule and progress rows
+func purgeSoftDeleted() ([]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id FROM mentor.subscriptions
+		WHERE deleted_at IS NOT NULL AND deleted_at < NOW() - make_interval(days => $1)
+	`, softDeleteRetentionDays())
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		for _, stmt := range []string{
+			"DELETE FROM mentor.progress WHERE subscription_id = $1",
+			"DELETE FROM mentor.schedule WHERE subscription_id = $1",
+			"DELETE FROM mentor.subscriptions WHERE id = $1",
+		} {
+			if _, err := tx.Exec(stmt, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		logAudit("subscription", id, "purged", "system", nil)
+	}
+	if ids == nil {
+		ids = []int{}
+	}
+	return ids, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// SUBSCRIPTION NOTES
+// ============================================
+func createSubscriptionNote(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Body   string `json:"body"`
+		Author string `json:"author"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "body is required"})
+		return
+	}
+
+	var noteID int
+	err := db.QueryRow(`
+		INSERT INTO mentor.subscription_notes (subscription_id, author, body)
+		SELECT id, NULLIF($2, ''), $3 FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`, id, input.Author, input.Body).Scan(&noteID)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": noteID, "message": "Note added"})
+}
+
+func getSubscriptionNotes(c *gin.Context) {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(author, ''), body, created_at, updated_at
+		FROM mentor.subscription_notes WHERE subscription_id = $1
+		ORDER BY created_at DESC
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var notes []gin.H
+	for rows.Next() {
+		var id int
+		var author, body string
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&id, &author, &body, &createdAt, &updatedAt); err != nil {
+			continue
+		}
+		notes = append(notes, gin.H{
+			"id":         id,
+			"author":     author,
+			"body":       body,
+			"created_at": isoTimestamp(createdAt),
+			"updated_at": isoTimestamp(updatedAt),
+		})
+	}
+
+	if notes == nil {
+		notes = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "notes": notes})
+}
+
+func updateSubscriptionNote(c *gin.Context) {
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "body is required"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.subscription_notes SET body = $1, updated_at = NOW() WHERE id = $2
+	`, input.Body, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Note not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Note updated"})
+}
+
+func deleteSubscriptionNote(c *gin.Context) {
+	result, err := db.Exec("DELETE FROM mentor.subscription_notes WHERE id = $1", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Note not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Note deleted"})
+}
+
+// ============================================
+// SUBSCRIPTION TIMELINE
+// ============================================
+
+// getSubscriptionTimeline - Notes, completed classes, visits and lifecycle
+// events (cancel, transfer, restore, ...) merged newest first. Page with
+// ?before=<occurred_at of the last item>.
+func getSubscriptionTimeline(c *gin.Context) {
+	id := c.Param("id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	before := time.Now().Add(time.Minute)
+	if b := c.Query("before"); b != "" {
+		parsed, err := parseTimestamp(b)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "before must be an ISO-8601 timestamp"})
+			return
+		}
+		before = parsed
+	}
+
+	rows, err := db.Query(`
+		SELECT * FROM (
+			SELECT 'note' AS kind, id, created_at AS occurred_at, COALESCE(author, '') AS actor,
+			       body AS summary
+			FROM mentor.subscription_notes WHERE subscription_id = $1
+			UNION ALL
+			SELECT 'class', id, completed_at, COALESCE(teacher_id, ''),
+			       subject || ' - chapter ' || chapter || ' part ' || part ||
+			       CASE WHEN COALESCE(notes, '') <> '' THEN ': ' || notes ELSE '' END
+			FROM mentor.progress WHERE subscription_id = $1
+			UNION ALL
+			SELECT 'attendance', id, recorded_at, teacher_id,
+			       'Visit ' || action || CASE WHEN COALESCE(notes, '') <> '' THEN ': ' || notes ELSE '' END
+			FROM mentor.attendance WHERE subscription_id = $1
+			UNION ALL
+			SELECT 'status', id, created_at, COALESCE(actor, ''),
+			       replace(action, '_', ' ') ||
+			       CASE WHEN details ? 'moved' THEN ' ' || (details->>'moved') ELSE '' END ||
+			       CASE WHEN action = 'status_changed' THEN ' from ' || (details->>'from') || ' to ' || (details->>'to') ELSE '' END ||
+			       CASE WHEN COALESCE(details->>'reason', '') <> '' THEN ': ' || (details->>'reason') ELSE '' END
+			FROM mentor.audit_log WHERE entity_type = 'subscription' AND entity_id = $1::text
+		) t
+		WHERE occurred_at < $2
+		ORDER BY occurred_at DESC
+		LIMIT $3
+	`, id, before, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	events := []gin.H{}
+	for rows.Next() {
+		var kind, actor, summary string
+		var refID int
+		var occurredAt time.Time
+		if err := rows.Scan(&kind, &refID, &occurredAt, &actor, &summary); err != nil {
+			continue
+		}
+		events = append(events, gin.H{
+			"kind":        kind,
+			"id":          refID,
+			"occurred_at": isoTimestamp(occurredAt),
+			"actor":       actor,
+			"summary":     summary,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "timeline": events})
+}
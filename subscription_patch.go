@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// PATCH SUBSCRIPTION (Partial update)
+// ============================================
+
+// countTotalClasses counts chapters across subjects (1 chapter = 1 class),
+// defaulting to 15 chapters for subjects missing from the chapters table
+func countTotalClasses(class int, subjects []string) int {
+	total := 0
+	for _, subj := range subjects {
+		total += subjectChapterCount(class, subj)
+	}
+	return total
+}
+
+// subjectChapterCount looks up a subject's chapters, case-insensitively as a
+// fallback, defaulting to 15
+func subjectChapterCount(class int, subject string) int {
+	subject = strings.TrimSpace(subject)
+	var chapters int
+	err := db.QueryRow(
+		"SELECT total_chapters FROM mentor.chapters WHERE class = $1 AND subject = $2",
+		class, subject,
+	).Scan(&chapters)
+	if err != nil {
+		// Try case-insensitive search
+		db.QueryRow(
+			"SELECT total_chapters FROM mentor.chapters WHERE class = $1 AND LOWER(subject) = LOWER($2)",
+			class, subject,
+		).Scan(&chapters)
+	}
+	if chapters == 0 {
+		chapters = 15
+	}
+	return chapters
+}
+
+// patchableStatuses are the statuses PATCH may set; cancelling goes through
+// POST /subscriptions/:id/cancel so the reason is recorded
+var patchableStatuses = map[string]bool{"active": true, "expired": true}
+
+// syncScheduleSubjects brings a subscription's schedule rows in line with its
+// class and subjects: new subjects get a row, parts needed follow the class,
+// and dropped subjects lose their row unless classes were already held
+func syncScheduleSubjects(subId string, class int, subjects []string) error {
+	for _, subj := range subjects {
+		subj = strings.TrimSpace(subj)
+		if _, err := db.Exec(`
+			INSERT INTO mentor.schedule (subscription_id, subject, total_parts_needed)
+			SELECT $1, $2, $3
+			WHERE NOT EXISTS (SELECT 1 FROM mentor.schedule WHERE subscription_id = $1 AND LOWER(subject) = LOWER($2))
+		`, subId, subj, subjectChapterCount(class, subj)); err != nil {
+			return err
+		}
+		if _, err := db.Exec(`
+			UPDATE mentor.schedule SET total_parts_needed = $3
+			WHERE subscription_id = $1 AND LOWER(subject) = LOWER($2)
+		`, subId, subj, subjectChapterCount(class, subj)); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(`
+		DELETE FROM mentor.schedule sc
+		WHERE sc.subscription_id = $1 AND NOT (LOWER(sc.subject) = ANY(SELECT LOWER(TRIM(s)) FROM unnest($2::text[]) s))
+		  AND sc.total_parts_done = 0 AND sc.repeat_sessions = 0
+		  AND NOT EXISTS (SELECT 1 FROM mentor.progress p WHERE p.subscription_id = sc.subscription_id AND LOWER(p.subject) = LOWER(sc.subject))
+	`, subId, pq.Array(subjects))
+	return err
+}
+
+// patchSubscription - Update only the fields present in the body. Omitted
+// fields keep their values; total_classes is only recalculated when class or
+// subjects actually change.
+func patchSubscription(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		StudentName   *string     `json:"student_name"`
+		StudentPhone  *string     `json:"student_phone"`
+		GuardianName  *string     `json:"guardian_name"`
+		GuardianPhone *string     `json:"guardian_phone"`
+		Class         *int        `json:"class"`
+		Subjects      *stringList `json:"subjects"`
+		TeacherID     *string     `json:"teacher_id"`
+		ScheduleDays  *stringList `json:"schedule_days"`
+		DaysPerWeek   *int        `json:"days_per_week"`
+		Time          *string     `json:"time"`
+		ClassMinutes  *int        `json:"class_minutes"` // 0 resets to the default
+		Amount        *float64    `json:"amount"`
+		BillingDate   *int        `json:"billing_date"`
+		Status        *string     `json:"status"`
+		Area          *string     `json:"area"`
+		Postcode      *string     `json:"postcode"`
+		Currency      *string     `json:"currency"` // "" resets to the default
+		UpdatedBy     string      `json:"updated_by"`
+
+		SubjectMinutes map[string]int `json:"subject_minutes"` // per subject; 0 follows class_minutes
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var currentClass int
+	var currentTeacher, currentStatus string
+	var currentSubjects []string
+	err := db.QueryRow(`
+		SELECT class, teacher_id, subject_list, status FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, id).Scan(&currentClass, &currentTeacher, pq.Array(&currentSubjects), &currentStatus)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	changed := []string{}
+	argCount := 0
+	set := func(column string, value interface{}) {
+		argCount++
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, argCount))
+		args = append(args, value)
+		changed = append(changed, column)
+	}
+
+	if input.StudentName != nil {
+		set("student_name", *input.StudentName)
+	}
+	if input.StudentPhone != nil {
+		set("student_phone", *input.StudentPhone)
+	}
+	if input.GuardianName != nil {
+		set("guardian_name", *input.GuardianName)
+	}
+	if input.GuardianPhone != nil {
+		set("guardian_phone", *input.GuardianPhone)
+	}
+	if input.TeacherID != nil {
+		set("teacher_id", *input.TeacherID)
+	}
+	if input.Time != nil {
+		set("time", *input.Time)
+	}
+	if input.ClassMinutes != nil {
+		if !validClassMinutes(*input.ClassMinutes) {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+				"class_minutes": fmt.Sprintf("must be between %d and %d", minClassMinutes, maxClassMinutes),
+			}))
+			return
+		}
+		var minutes interface{}
+		if *input.ClassMinutes > 0 {
+			minutes = *input.ClassMinutes
+		}
+		set("class_minutes", minutes)
+	}
+	for subject, minutes := range input.SubjectMinutes {
+		if !validClassMinutes(minutes) {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+				"subject_minutes": fmt.Sprintf("%s must be between %d and %d minutes", subject, minClassMinutes, maxClassMinutes),
+			}))
+			return
+		}
+	}
+	if input.Amount != nil {
+		set("amount", *input.Amount)
+	}
+	if input.BillingDate != nil {
+		set("billing_date", *input.BillingDate)
+	}
+	if input.Status != nil {
+		if !patchableStatuses[*input.Status] {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"status": "must be active or expired; use POST /subscriptions/:id/cancel to cancel"}))
+			return
+		}
+		set("status", *input.Status)
+	}
+	if input.Area != nil {
+		set("area", strings.TrimSpace(*input.Area))
+	}
+	if input.Postcode != nil {
+		set("postcode", strings.TrimSpace(*input.Postcode))
+	}
+	if input.Currency != nil {
+		code, ok := normalizeCurrency(*input.Currency)
+		if !ok {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{"currency": "must be a 3-letter ISO code"}))
+			return
+		}
+		var currency interface{}
+		if strings.TrimSpace(*input.Currency) != "" {
+			currency = code
+		}
+		set("currency", currency)
+	}
+	if input.ScheduleDays != nil {
+		days, invalid := canonicalScheduleDays(*input.ScheduleDays)
+		if len(invalid) > 0 {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(map[string]string{
+				"schedule_days": fmt.Sprintf("invalid day(s) %s; use Sat-Fri or codes 1-7", strings.Join(invalid, ", ")),
+			}))
+			return
+		}
+		set("schedule_days", strings.Join(days, ","))
+		set("schedule_day_list", pq.Array(days))
+		if input.DaysPerWeek == nil {
+			set("days_per_week", len(days))
+		}
+	}
+	if input.DaysPerWeek != nil {
+		set("days_per_week", *input.DaysPerWeek)
+	}
+
+	class, subjects := currentClass, currentSubjects
+	if input.Class != nil {
+		class = *input.Class
+		set("class", class)
+	}
+	if input.Subjects != nil {
+		subjects = *input.Subjects
+		set("subjects", strings.Join(subjects, ","))
+		set("subject_list", pq.Array(subjects))
+	}
+
+	var totalClasses *int
+	subjectsChanged := class != currentClass || strings.Join(subjects, "\x00") != strings.Join(currentSubjects, "\x00")
+	if subjectsChanged {
+		total := countTotalClasses(class, subjects)
+		totalClasses = &total
+		set("total_classes", total)
+	}
+
+	if len(sets) == 0 && len(input.SubjectMinutes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No fields to update"})
+		return
+	}
+
+	var gaps []capabilityGap
+	if input.TeacherID != nil || input.Class != nil || input.Subjects != nil {
+		teacherID := currentTeacher
+		if input.TeacherID != nil {
+			teacherID = *input.TeacherID
+		}
+		gaps = subscriptionCapabilityGaps(id, class, teacherID, subjects)
+		if len(gaps) > 0 && capabilitiesEnforced() {
+			c.JSON(http.StatusBadRequest, validationErrorResponse(capabilityFieldErrors(gaps)))
+			return
+		}
+	}
+
+	argCount++
+	query := fmt.Sprintf("UPDATE mentor.subscriptions SET %s WHERE id = $%d AND deleted_at IS NULL",
+		strings.Join(append(sets, "updated_at = NOW()"), ", "), argCount)
+	args = append(args, id)
+
+	if _, err := db.Exec(query, args...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if subjectsChanged {
+		if err := syncScheduleSubjects(id, class, subjects); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		updateSubscriptionProgress(id)
+	}
+
+	for subject, minutes := range input.SubjectMinutes {
+		if _, err := db.Exec(`
+			UPDATE mentor.schedule SET class_minutes = NULLIF($1, 0)
+			WHERE subscription_id = $2 AND LOWER(subject) = LOWER($3)
+		`, minutes, id, subject); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	details := gin.H{"fields": changed}
+	if len(input.SubjectMinutes) > 0 {
+		details["subject_minutes"] = input.SubjectMinutes
+	}
+	logAudit("subscription", id, "updated", input.UpdatedBy, details)
+	if input.Status != nil && *input.Status != currentStatus {
+		logAudit("subscription", id, "status_changed", input.UpdatedBy, gin.H{"from": currentStatus, "to": *input.Status})
+	}
+	requestClassSessionSync()
+
+	response := gin.H{"success": true, "message": "Subscription updated"}
+	if totalClasses != nil {
+		response["total_classes"] = *totalClasses
+	}
+	if len(gaps) > 0 {
+		response["capability_warnings"] = capabilityWarnings(gaps)
+	}
+	c.JSON(http.StatusOK, response)
+}
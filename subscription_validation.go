@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// SUBSCRIPTION INPUT VALIDATION
+// ============================================
+
+// classTimeLayouts are the accepted spellings of a class time; stored as "3:04 PM"
+var classTimeLayouts = []string{"3:04 PM", "3:04PM", "3:04 pm", "3:04pm", "15:04"}
+
+func parseClassTime(s string) (string, bool) {
+	for _, layout := range classTimeLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.Format("3:04 PM"), true
+		}
+	}
+	return "", false
+}
+
+// canonicalScheduleDays turns schedule_days input (names like "Mon" or
+// "Monday", or codes Sat=1 ... Fri=7) into the stored encoding: each day once
+// as "Sat".."Fri", in week order. invalid lists the entries that aren't days.
+func canonicalScheduleDays(days []string) (canonical []string, invalid []string) {
+	wanted := map[time.Weekday]bool{}
+	for _, d := range days {
+		parsed := scheduledWeekdays([]string{d})
+		if len(parsed) == 0 {
+			invalid = append(invalid, d)
+		}
+		for wd := range parsed {
+			wanted[wd] = true
+		}
+	}
+	canonical = []string{}
+	for _, wd := range workloadWeek {
+		if wanted[wd] {
+			canonical = append(canonical, wd.String()[:3])
+		}
+	}
+	return canonical, invalid
+}
+
+// validScheduleDay accepts the same spellings scheduledWeekdays understands
+func validScheduleDay(d string) bool {
+	d = strings.TrimSpace(d)
+	if len(d) > 3 {
+		d = d[:3]
+	}
+	_, ok := scheduleDayWeekdays[d]
+	return ok
+}
+
+// bindingFieldErrors turns a ShouldBindJSON error into field -> message,
+// keyed by JSON name. Errors that aren't about one field go under "body".
+func bindingFieldErrors(err error, target interface{}) map[string]string {
+	jsonName := func(structField string) string {
+		if f, ok := reflect.TypeOf(target).Elem().FieldByName(structField); ok {
+			if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" {
+				return name
+			}
+		}
+		return structField
+	}
+
+	fields := map[string]string{}
+
+	var verrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &verrs):
+		for _, fe := range verrs {
+			var msg string
+			switch fe.Tag() {
+			case "required":
+				msg = "is required"
+			case "gte", "min":
+				msg = "must be at least " + fe.Param()
+			case "lte":
+				msg = "must be at most " + fe.Param()
+			case "max":
+				msg = fmt.Sprintf("must be at most %s characters", fe.Param())
+			default:
+				msg = "failed " + fe.Tag() + " check"
+			}
+			fields[jsonName(fe.StructField())] = msg
+		}
+	case errors.As(err, &typeErr):
+		fields[typeErr.Field] = "must be a " + typeErr.Type.String()
+	default:
+		fields["body"] = err.Error()
+	}
+	return fields
+}
+
+// validateSubscriptionInput checks a subscription before it is created and
+// normalizes the class time. Returns field -> message; empty when valid.
+func validateSubscriptionInput(input *subscriptionInput) map[string]string {
+	fields := map[string]string{}
+
+	input.StudentName = strings.TrimSpace(input.StudentName)
+	if input.StudentName == "" {
+		fields["student_name"] = "is required"
+	}
+	if input.Class < 1 || input.Class > 12 {
+		fields["class"] = "must be between 1 and 12"
+	}
+	if len(input.Subjects) == 0 {
+		fields["subjects"] = "at least one subject is required"
+	}
+	if strings.TrimSpace(input.Currency) != "" {
+		code, ok := normalizeCurrency(input.Currency)
+		if !ok {
+			fields["currency"] = "must be a 3-letter ISO code"
+		}
+		input.Currency = code
+	}
+
+	var invalidDays []string
+	seen := map[time.Weekday]bool{}
+	for _, d := range input.ScheduleDays {
+		if !validScheduleDay(d) {
+			invalidDays = append(invalidDays, d)
+			continue
+		}
+		for wd := range scheduledWeekdays([]string{d}) {
+			if seen[wd] {
+				fields["schedule_days"] = "contains the same day twice"
+			}
+			seen[wd] = true
+		}
+	}
+	if len(invalidDays) > 0 {
+		fields["schedule_days"] = fmt.Sprintf("invalid day(s) %s; use Sat-Fri or codes 1-7", strings.Join(invalidDays, ", "))
+	} else {
+		input.ScheduleDays, _ = canonicalScheduleDays(input.ScheduleDays)
+	}
+	if input.DaysPerWeek < 0 || input.DaysPerWeek > 7 {
+		fields["days_per_week"] = "must be between 0 and 7"
+	}
+
+	if input.Time != "" {
+		if normalized, ok := parseClassTime(input.Time); ok {
+			input.Time = normalized
+		} else {
+			fields["time"] = `must be a time like "4:30 PM" or "16:30"`
+		}
+	}
+
+	if !validClassMinutes(input.ClassMinutes) {
+		fields["class_minutes"] = fmt.Sprintf("must be between %d and %d", minClassMinutes, maxClassMinutes)
+	}
+	for subject, minutes := range input.SubjectMinutes {
+		if !validClassMinutes(minutes) {
+			fields["subject_minutes"] = fmt.Sprintf("%s must be between %d and %d minutes", subject, minClassMinutes, maxClassMinutes)
+		}
+	}
+
+	if input.Amount < 0 {
+		fields["amount"] = "must not be negative"
+	}
+	for subject, price := range input.SubjectPrices {
+		if price < 0 {
+			fields["subject_prices"] = fmt.Sprintf("price for %s must not be negative", subject)
+		}
+	}
+	if input.BillingDate < 0 || input.BillingDate > 31 {
+		fields["billing_date"] = "must be a day of the month (1-31)"
+	}
+
+	// Every referenced teacher must exist and be active
+	teacherIDs := []string{}
+	if input.TeacherID == "" {
+		fields["teacher_id"] = "is required"
+	} else {
+		teacherIDs = append(teacherIDs, input.TeacherID)
+	}
+	for _, t := range input.SubjectTeachers {
+		if t != "" {
+			teacherIDs = append(teacherIDs, t)
+		}
+	}
+	if len(teacherIDs) > 0 {
+		var missing []string
+		rows, err := db.Query(`
+			SELECT u.teacher_id FROM UNNEST($1::TEXT[]) AS u(teacher_id)
+			WHERE NOT EXISTS (SELECT 1 FROM mentor.teachers t WHERE t.id = u.teacher_id AND COALESCE(t.active, 1) = 1)
+		`, pq.Array(teacherIDs))
+		if err == nil {
+			for rows.Next() {
+				var id string
+				rows.Scan(&id)
+				missing = append(missing, id)
+			}
+			rows.Close()
+		}
+		for _, id := range missing {
+			if id == input.TeacherID {
+				fields["teacher_id"] = "unknown or inactive teacher " + id
+			} else {
+				fields["subject_teachers"] = "unknown or inactive teacher " + id
+			}
+		}
+	}
+
+	return fields
+}
+
+// validationErrorResponse is the 400 body for field-level errors
+func validationErrorResponse(fields map[string]string) gin.H {
+	return gin.H{"success": false, "error": "Validation failed", "errors": fields}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// SYLLABUS (chapters table CRUD)
+// ============================================
+
+const maxSubjectChapters = 200
+
+// syllabusInput is the body for creating or updating a subject's chapters
+type syllabusInput struct {
+	Class         int      `json:"class"`
+	Subject       string   `json:"subject"`
+	TotalChapters int      `json:"total_chapters"`
+	ChapterTitles []string `json:"chapter_titles"`
+	UpdatedBy     string   `json:"updated_by"`
+}
+
+// fieldErrors validates the counts and titles; titles, when given, must
+// name every chapter
+func (in *syllabusInput) fieldErrors() map[string]string {
+	fields := map[string]string{}
+	in.Subject = strings.TrimSpace(in.Subject)
+
+	if in.Class < 1 || in.Class > 12 {
+		fields["class"] = "must be between 1 and 12"
+	}
+	if in.Subject == "" {
+		fields["subject"] = "is required"
+	}
+	if in.TotalChapters == 0 && len(in.ChapterTitles) > 0 {
+		in.TotalChapters = len(in.ChapterTitles)
+	}
+	if in.TotalChapters < 1 || in.TotalChapters > maxSubjectChapters {
+		fields["total_chapters"] = fmt.Sprintf("must be between 1 and %d", maxSubjectChapters)
+	} else if in.ChapterTitles != nil && len(in.ChapterTitles) != in.TotalChapters {
+		fields["chapter_titles"] = fmt.Sprintf("must list all %d chapters", in.TotalChapters)
+	}
+	for i, title := range in.ChapterTitles {
+		in.ChapterTitles[i] = strings.TrimSpace(title)
+		if in.ChapterTitles[i] == "" {
+			fields["chapter_titles"] = fmt.Sprintf("chapter %d has no title", i+1)
+			break
+		}
+	}
+	return fields
+}
+
+// titlesJSON is the chapter_titles column value (NULL when not given)
+func (in *syllabusInput) titlesJSON() interface{} {
+	if in.ChapterTitles == nil {
+		return nil
+	}
+	b, _ := json.Marshal(in.ChapterTitles)
+	return string(b)
+}
+
+// flagStaleProgress marks subscriptions taking a subject whose chapter count
+// changed, so their totals get recomputed; returns how many were flagged
+func flagStaleProgress(tx *sql.Tx, class int, subject string) (int64, error) {
+	result, err := tx.Exec(`
+		UPDATE mentor.subscriptions
+		SET progress_stale = TRUE, progress_stale_since = COALESCE(progress_stale_since, NOW())
+		WHERE class = $1 AND deleted_at IS NULL
+		  AND EXISTS (SELECT 1 FROM unnest(subject_list) s WHERE LOWER(TRIM(s)) = LOWER($2))
+	`, class, subject)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// getSyllabus - One subject's chapter count and titles
+func getSyllabus(c *gin.Context) {
+	var total int
+	var titles sql.NullString
+	var updatedBy string
+	var updatedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT total_chapters, chapter_titles::text, COALESCE(updated_by, ''), updated_at
+		FROM mentor.chapters WHERE class = $1 AND subject = $2
+	`, c.Param("class"), c.Param("subject")).Scan(&total, &titles, &updatedBy, &updatedAt)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subject not found for this class"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	class, _ := strconv.Atoi(c.Param("class"))
+	chapterTitles := []string{}
+	if titles.Valid {
+		json.Unmarshal([]byte(titles.String), &chapterTitles)
+	}
+
+	var stale int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM mentor.subscriptions
+		WHERE class = $1 AND progress_stale AND deleted_at IS NULL
+		  AND EXISTS (SELECT 1 FROM unnest(subject_list) s WHERE LOWER(TRIM(s)) = LOWER($2))
+	`, class, c.Param("subject")).Scan(&stale)
+
+	chapter := gin.H{
+		"class":               class,
+		"subject":             c.Param("subject"),
+		"total_chapters":      total,
+		"chapter_titles":      chapterTitles,
+		"updated_by":          updatedBy,
+		"stale_subscriptions": stale,
+	}
+	if updatedAt.Valid {
+		chapter["updated_at"] = isoTimestamp(updatedAt.Time)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "chapter": chapter})
+}
+
+// createSyllabus - Add a subject's chapters for a class
+func createSyllabus(c *gin.Context) {
+	var input syllabusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	if fields := input.fieldErrors(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO mentor.chapters (class, subject, total_chapters, chapter_titles, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+		ON CONFLICT (class, subject) DO NOTHING
+	`, input.Class, input.Subject, input.TotalChapters, input.titlesJSON(), input.UpdatedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Subject already exists for this class"})
+		return
+	}
+
+	// Subscriptions already taking the subject were counted with the 15-chapter default
+	flagged, err := flagStaleProgress(tx, input.Class, input.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("chapters", fmt.Sprintf("%d/%s", input.Class, input.Subject), "created", input.UpdatedBy,
+		gin.H{"total_chapters": input.TotalChapters})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "subscriptions_flagged": flagged, "message": "Chapters added"})
+}
+
+// updateSyllabus - Change a subject's chapter count and/or titles; a new count
+// flags the subscriptions taking it for progress recomputation
+func updateSyllabus(c *gin.Context) {
+	var input syllabusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+	input.Class, _ = strconv.Atoi(c.Param("class"))
+	input.Subject = c.Param("subject")
+	if fields := input.fieldErrors(); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var previous int
+	err = tx.QueryRow(`
+		SELECT total_chapters FROM mentor.chapters WHERE class = $1 AND subject = $2 FOR UPDATE
+	`, input.Class, input.Subject).Scan(&previous)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subject not found for this class"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE mentor.chapters
+		SET total_chapters = $3, chapter_titles = $4, updated_by = NULLIF($5, ''), updated_at = NOW()
+		WHERE class = $1 AND subject = $2
+	`, input.Class, input.Subject, input.TotalChapters, input.titlesJSON(), input.UpdatedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	flagged := int64(0)
+	if input.TotalChapters != previous {
+		if flagged, err = flagStaleProgress(tx, input.Class, input.Subject); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("chapters", fmt.Sprintf("%d/%s", input.Class, input.Subject), "updated", input.UpdatedBy,
+		gin.H{"total_chapters": input.TotalChapters, "previous": previous})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":               true,
+		"previous_chapters":     previous,
+		"total_chapters":        input.TotalChapters,
+		"subscriptions_flagged": flagged,
+		"message":               "Chapters updated",
+	})
+}
+
+// deleteSyllabus - Remove a subject's chapters; subscriptions taking it fall
+// back to the 15-chapter default and are flagged
+func deleteSyllabus(c *gin.Context) {
+	class, _ := strconv.Atoi(c.Param("class"))
+	subject := c.Param("subject")
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM mentor.chapters WHERE class = $1 AND subject = $2", class, subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subject not found for this class"})
+		return
+	}
+
+	flagged, err := flagStaleProgress(tx, class, subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("chapters", fmt.Sprintf("%d/%s", class, subject), "deleted", c.Query("deleted_by"), nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "subscriptions_flagged": flagged, "message": "Chapters deleted"})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TEACHER PEER BENCHMARK (Anonymized)
+// ============================================
+
+// benchmarkMetrics are computed for every teacher; each query returns
+// (teacher_id, value, sample_size) rows. Only teachers whose sample reaches
+// benchmarkMinSample take part in a metric.
+var benchmarkMetrics = []struct {
+	name, description, query string
+}{
+	{"pace", "Classes completed per active student per week (last 4 weeks)", `
+		SELECT s.teacher_id, COUNT(p.id)::float / COUNT(DISTINCT s.id) / 4, COUNT(DISTINCT s.id)
+		FROM mentor.subscriptions s
+		LEFT JOIN mentor.progress p ON p.subscription_id = s.id AND p.completed_at >= NOW() - INTERVAL '28 days'
+		WHERE s.status = 'active' AND s.deleted_at IS NULL
+		GROUP BY s.teacher_id`},
+	{"student_improvement", "Average change in test score % from a student's first to latest graded test (last 6 months)", `
+		SELECT teacher_id, AVG(last_pct - first_pct), COUNT(*)
+		FROM (
+			SELECT s.teacher_id, a.subscription_id,
+			       (ARRAY_AGG(a.actual_marks::float / NULLIF(a.total_marks, 0) * 100 ORDER BY a.created_at))[1] AS first_pct,
+			       (ARRAY_AGG(a.actual_marks::float / NULLIF(a.total_marks, 0) * 100 ORDER BY a.created_at DESC))[1] AS last_pct
+			FROM mentor.answer_papers a
+			JOIN mentor.subscriptions s ON s.id = a.subscription_id AND s.deleted_at IS NULL
+			WHERE a.status = 'graded' AND a.total_marks > 0 AND a.created_at >= NOW() - INTERVAL '6 months'
+			GROUP BY s.teacher_id, a.subscription_id
+			HAVING COUNT(*) >= 2
+		) per_student
+		GROUP BY teacher_id`},
+	{"guardian_rating", "Average published guardian rating, 1-5 (last 6 months)", `
+		SELECT teacher_id, AVG(rating), COUNT(DISTINCT subscription_id)
+		FROM mentor.teacher_ratings
+		WHERE status = 'published' AND created_at >= NOW() - INTERVAL '6 months'
+		GROUP BY teacher_id`},
+}
+
+// benchmarkMinTeachers is the smallest peer group a metric is reported for,
+// so no individual colleague's value can be inferred
+func benchmarkMinTeachers() int {
+	n, err := strconv.Atoi(os.Getenv("BENCHMARK_MIN_TEACHERS"))
+	if err != nil || n < 3 {
+		return 5
+	}
+	return n
+}
+
+const benchmarkMinSample = 3
+
+// percentileOf returns the share of values strictly below v, counting ties as half
+func percentileOf(values []float64, v float64) float64 {
+	below, equal := 0, 0
+	for _, x := range values {
+		switch {
+		case x < v:
+			below++
+		case x == v:
+			equal++
+		}
+	}
+	return (float64(below) + float64(equal)/2) / float64(len(values)) * 100
+}
+
+// quantile expects sorted values
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(pos)
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[lo+1]-sorted[lo])*(pos-float64(lo))
+}
+
+// getTeacherBenchmark - How a teacher compares with anonymous peers
+func getTeacherBenchmark(c *gin.Context) {
+	teacherId := c.Param("id")
+	minTeachers := benchmarkMinTeachers()
+
+	metrics := []gin.H{}
+	for _, m := range benchmarkMetrics {
+		rows, err := db.Query(m.query)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		var values []float64
+		var own *float64
+		ownSample := 0
+		for rows.Next() {
+			var tid string
+			var value float64
+			var sample int
+			if err := rows.Scan(&tid, &value, &sample); err != nil {
+				continue
+			}
+			if tid == teacherId {
+				ownSample = sample
+			}
+			if sample < benchmarkMinSample {
+				continue
+			}
+			values = append(values, value)
+			if tid == teacherId {
+				v := value
+				own = &v
+			}
+		}
+		rows.Close()
+
+		metric := gin.H{"metric": m.name, "description": m.description, "peer_count": len(values)}
+		switch {
+		case len(values) < minTeachers:
+			metric["available"] = false
+			metric["reason"] = "Not enough teachers with data to compare anonymously"
+		case own == nil:
+			metric["available"] = false
+			metric["reason"] = "Not enough of your own data yet (" + strconv.Itoa(ownSample) + " of " + strconv.Itoa(benchmarkMinSample) + ")"
+		default:
+			sort.Float64s(values)
+			metric["available"] = true
+			metric["your_value"] = *own
+			metric["percentile"] = percentileOf(values, *own)
+			metric["peer_p25"] = quantile(values, 0.25)
+			metric["peer_median"] = quantile(values, 0.5)
+			metric["peer_p75"] = quantile(values, 0.75)
+		}
+		metrics = append(metrics, metric)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "teacher_id": teacherId, "metrics": metrics})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TEACHER CALENDAR FEED (iCalendar)
+// ============================================
+
+// calendarFeedDays is how far ahead the feed lists sessions; it also keeps
+// the last week so recently finished classes don't vanish from calendars
+const calendarFeedDays = 30
+
+// publicBaseURL is PUBLIC_API_URL, or else derived from the request
+func publicBaseURL(c *gin.Context) string {
+	if base := os.Getenv("PUBLIC_API_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := c.GetHeader("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// calendarFeedURL returns the teacher's feed URL, creating the token on first
+// use or replacing it when rotate is set
+func calendarFeedURL(c *gin.Context, teacherID string, rotate bool) (string, error) {
+	var token sql.NullString
+	if err := db.QueryRow("SELECT calendar_token FROM mentor.teachers WHERE id = $1", teacherID).Scan(&token); err != nil {
+		return "", err
+	}
+
+	if !token.Valid || rotate {
+		tokenBytes := make([]byte, 24)
+		rand.Read(tokenBytes)
+		token = sql.NullString{String: hex.EncodeToString(tokenBytes), Valid: true}
+		if _, err := db.Exec("UPDATE mentor.teachers SET calendar_token = $1 WHERE id = $2", token.String, teacherID); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%s/api/teacher/%s/calendar.ics?token=%s", publicBaseURL(c), teacherID, token.String), nil
+}
+
+// getMyCalendarFeed - The signed-in teacher's calendar subscription URL
+func getMyCalendarFeed(c *gin.Context) {
+	url, err := calendarFeedURL(c, c.GetString("teacher_id"), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "url": url, "webcal_url": webcalURL(url)})
+}
+
+// rotateMyCalendarFeed - Replace the token, e.g. after the URL was shared by mistake
+func rotateMyCalendarFeed(c *gin.Context) {
+	url, err := calendarFeedURL(c, c.GetString("teacher_id"), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "url": url, "webcal_url": webcalURL(url), "message": "Old calendar link no longer works"})
+}
+
+// webcalURL is the subscribe link Apple/Google Calendar open directly
+func webcalURL(url string) string {
+	return "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+}
+
+// icsEscape escapes TEXT values (RFC 5545 3.3.11)
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsLine folds a content line at 75 octets (RFC 5545 3.1)
+func icsLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		// Don't split a UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// getTeacherCalendar - iCalendar feed of a teacher's class sessions. Holidays
+// have no sessions; sessions moved away or cancelled are sent as cancelled so
+// subscribed calendars drop them, and the moved session shows on its new day.
+func getTeacherCalendar(c *gin.Context) {
+	teacherID := c.Param("teacherId")
+
+	var name string
+	var token sql.NullString
+	err := db.QueryRow(`
+		SELECT name, calendar_token FROM mentor.teachers WHERE id = $1 AND COALESCE(active, 1) = 1
+	`, teacherID).Scan(&name, &token)
+	if err != nil || !token.Valid ||
+		subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token.String)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid calendar link"})
+		return
+	}
+
+	today := localToday()
+	until := today.AddDate(0, 0, calendarFeedDays)
+
+	rows, err := db.Query(`
+		SELECT cs.id, cs.session_date, COALESCE(cs.session_time, ''), cs.subject, cs.status,
+		       COALESCE(cs.reschedule_reason, ''), s.student_name, s.class, cs.updated_at, COALESCE(cs.duration_minutes, 0)
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		WHERE cs.teacher_id = $1 AND cs.session_date BETWEEN $2 AND $3
+		ORDER BY cs.session_date, cs.id
+	`, teacherID, today.AddDate(0, 0, -7), until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	loc := teacherLocation(teacherID)
+	host := c.Request.Host
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var b strings.Builder
+	icsLine(&b, "BEGIN:VCALENDAR")
+	icsLine(&b, "VERSION:2.0")
+	icsLine(&b, "PRODID:-//Mentor//Class Sessions//EN")
+	icsLine(&b, "CALSCALE:GREGORIAN")
+	icsLine(&b, "METHOD:PUBLISH")
+	icsLine(&b, "X-WR-CALNAME:"+icsEscape("Classes - "+name))
+	icsLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+
+	writeEvent := func(uid string, sessionDate time.Time, sessionTime string, minutes int, summary, reason, status string, modified time.Time) {
+		start, _ := sessionStart(sessionDate, sessionTime, loc)
+		end := start.Add(time.Duration(classMinutesOr(minutes)) * time.Minute)
+
+		icsLine(&b, "BEGIN:VEVENT")
+		icsLine(&b, "UID:"+uid+"@"+host)
+		icsLine(&b, "DTSTAMP:"+stamp)
+		icsLine(&b, "LAST-MODIFIED:"+modified.UTC().Format("20060102T150405Z"))
+		icsLine(&b, "DTSTART:"+start.UTC().Format("20060102T150405Z"))
+		icsLine(&b, "DTEND:"+end.UTC().Format("20060102T150405Z"))
+		icsLine(&b, "SUMMARY:"+icsEscape(summary))
+		if reason != "" {
+			icsLine(&b, "DESCRIPTION:"+icsEscape("Rescheduled: "+reason))
+		}
+		switch status {
+		case "cancelled", "rescheduled":
+			icsLine(&b, "STATUS:CANCELLED")
+		default:
+			icsLine(&b, "STATUS:CONFIRMED")
+		}
+		icsLine(&b, "END:VEVENT")
+	}
+
+	for rows.Next() {
+		var id, class, minutes int
+		var sessionDate, updatedAt time.Time
+		var sessionTime, subject, status, reason, studentName string
+		if err := rows.Scan(&id, &sessionDate, &sessionTime, &subject, &status, &reason, &studentName, &class, &updatedAt, &minutes); err != nil {
+			continue
+		}
+		writeEvent(fmt.Sprintf("class-session-%d", id), sessionDate, sessionTime, minutes,
+			fmt.Sprintf("%s - %s (Class %d)", subject, studentName, class), reason, status, updatedAt)
+	}
+
+	// Days past the sync job's horizon have nothing stored yet; their events
+	// get the stored session's UID once the job generates it
+	forecast, err := forecastSessionsBetween(today, until, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for _, s := range forecast {
+		sessionDate, _ := time.ParseInLocation("2006-01-02", s["date"].(string), time.Local)
+		subject := s["subject"].(string)
+		uid := fmt.Sprintf("class-forecast-%d-%s-%s", s["subscription_id"].(int), s["date"].(string), hex.EncodeToString([]byte(subject)))
+		writeEvent(uid, sessionDate, s["time"].(string), s["duration_minutes"].(int),
+			fmt.Sprintf("%s - %s (Class %d)", subject, s["student_name"].(string), s["class"].(int)), "", s["status"].(string), time.Now())
+	}
+	icsLine(&b, "END:VCALENDAR")
+
+	c.Header("Content-Disposition", `inline; filename="classes.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TEACHER CAPABILITIES (Classes/subjects a teacher can teach)
+// ============================================
+
+// capabilitiesEnforced turns capability mismatches from warnings into errors
+func capabilitiesEnforced() bool {
+	return os.Getenv("CAPABILITY_ENFORCED") == "true"
+}
+
+// capabilityGap is a subject assigned to a teacher who isn't listed for it
+type capabilityGap struct {
+	TeacherID string `json:"teacher_id"`
+	Subject   string `json:"subject"`
+	Class     int    `json:"class"`
+	Field     string `json:"-"` // request field that made the assignment
+}
+
+func (g capabilityGap) String() string {
+	return fmt.Sprintf("teacher %s is not listed for %s in class %d", g.TeacherID, g.Subject, g.Class)
+}
+
+// capabilityGaps checks who ends up teaching each subject: the subject's own
+// teacher if set, else mainTeacher. Teachers with no capabilities recorded
+// are unrestricted.
+func capabilityGaps(class int, mainTeacher string, subjects []string, subjectTeachers map[string]string) []capabilityGap {
+	var gaps []capabilityGap
+	for _, subject := range subjects {
+		subject = strings.TrimSpace(subject)
+		teacherID, field := subjectTeachers[subject], "subject_teachers"
+		if teacherID == "" {
+			teacherID, field = mainTeacher, "teacher_id"
+		}
+		if teacherID == "" {
+			continue
+		}
+
+		var listed, qualified bool
+		db.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM mentor.teacher_capabilities WHERE teacher_id = $1),
+			       EXISTS(SELECT 1 FROM mentor.teacher_capabilities
+			              WHERE teacher_id = $1 AND class = $2 AND LOWER(subject) = LOWER($3))
+		`, teacherID, class, subject).Scan(&listed, &qualified)
+		if listed && !qualified {
+			gaps = append(gaps, capabilityGap{TeacherID: teacherID, Subject: subject, Class: class, Field: field})
+		}
+	}
+	return gaps
+}
+
+// subscriptionCapabilityGaps is capabilityGaps for an existing subscription,
+// keeping subjects pinned to their own teacher in the schedule
+func subscriptionCapabilityGaps(subId interface{}, class int, mainTeacher string, subjects []string) []capabilityGap {
+	pinned := map[string]string{}
+	rows, err := db.Query(`
+		SELECT subject, teacher_id FROM mentor.schedule
+		WHERE subscription_id = $1 AND teacher_id IS NOT NULL
+	`, subId)
+	if err == nil {
+		for rows.Next() {
+			var subject, teacherID string
+			if rows.Scan(&subject, &teacherID) == nil {
+				pinned[subject] = teacherID
+			}
+		}
+		rows.Close()
+	}
+	return capabilityGaps(class, mainTeacher, subjects, pinned)
+}
+
+// capabilityWarnings renders gaps for a response body
+func capabilityWarnings(gaps []capabilityGap) []string {
+	warnings := []string{}
+	for _, g := range gaps {
+		warnings = append(warnings, g.String())
+	}
+	return warnings
+}
+
+// capabilityFieldErrors maps gaps to field-level validation errors
+func capabilityFieldErrors(gaps []capabilityGap) map[string]string {
+	fields := map[string]string{}
+	for _, g := range gaps {
+		if msg, ok := fields[g.Field]; ok {
+			fields[g.Field] = msg + "; " + g.String()
+		} else {
+			fields[g.Field] = g.String()
+		}
+	}
+	return fields
+}
+
+// getTeacherCapabilities - Classes and subjects a teacher can teach
+func getTeacherCapabilities(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1)", teacherID).Scan(&exists)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT class, subject FROM mentor.teacher_capabilities
+		WHERE teacher_id = $1
+		ORDER BY class, subject
+	`, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	byClass := map[int][]string{}
+	var classes []int
+	for rows.Next() {
+		var class int
+		var subject string
+		if err := rows.Scan(&class, &subject); err != nil {
+			continue
+		}
+		if _, ok := byClass[class]; !ok {
+			classes = append(classes, class)
+		}
+		byClass[class] = append(byClass[class], subject)
+	}
+
+	capabilities := []gin.H{}
+	for _, class := range classes {
+		capabilities = append(capabilities, gin.H{"class": class, "subjects": byClass[class]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"teacher_id":   teacherID,
+		"restricted":   len(capabilities) > 0,
+		"enforced":     capabilitiesEnforced(),
+		"capabilities": capabilities,
+	})
+}
+
+// updateTeacherCapabilities - Replace a teacher's capability matrix.
+// An empty list removes all restrictions.
+func updateTeacherCapabilities(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var input struct {
+		Capabilities []struct {
+			Class    int        `json:"class"`
+			Subjects stringList `json:"subjects"`
+		} `json:"capabilities"`
+		UpdatedBy string `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var classes []int64
+	var subjects []string
+	seen := map[string]bool{}
+	for _, entry := range input.Capabilities {
+		if entry.Class < 1 || entry.Class > 12 {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "class must be between 1 and 12"})
+			return
+		}
+		for _, subject := range entry.Subjects {
+			subject = strings.TrimSpace(subject)
+			key := fmt.Sprintf("%d|%s", entry.Class, strings.ToLower(subject))
+			if subject == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			classes = append(classes, int64(entry.Class))
+			subjects = append(subjects, subject)
+		}
+	}
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1)", teacherID).Scan(&exists)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mentor.teacher_capabilities WHERE teacher_id = $1", teacherID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if len(subjects) > 0 {
+		if _, err := tx.Exec(`
+			INSERT INTO mentor.teacher_capabilities (teacher_id, class, subject)
+			SELECT $1, u.class, u.subject FROM UNNEST($2::INT[], $3::TEXT[]) AS u(class, subject)
+		`, teacherID, pq.Array(classes), pq.Array(subjects)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("teacher", teacherID, "capabilities_updated", input.UpdatedBy, gin.H{"count": len(subjects)})
+
+	// Existing assignments that no longer fit are reported, not changed
+	conflicts := []gin.H{}
+	if len(subjects) > 0 {
+		rows, err := db.Query(`
+			SELECT s.id, s.student_name, s.class, sc.subject
+			FROM mentor.subscriptions s
+			JOIN mentor.schedule sc ON sc.subscription_id = s.id
+			WHERE s.status = 'active' AND s.deleted_at IS NULL
+			  AND COALESCE(sc.teacher_id, s.teacher_id) = $1
+			  AND NOT EXISTS (
+				SELECT 1 FROM mentor.teacher_capabilities tc
+				WHERE tc.teacher_id = $1 AND tc.class = s.class AND LOWER(tc.subject) = LOWER(sc.subject)
+			  )
+			ORDER BY s.id, sc.subject
+		`, teacherID)
+		if err == nil {
+			for rows.Next() {
+				var subId, class int
+				var studentName, subject string
+				if err := rows.Scan(&subId, &studentName, &class, &subject); err != nil {
+					continue
+				}
+				conflicts = append(conflicts, gin.H{
+					"subscription_id": subId,
+					"student_name":    studentName,
+					"class":           class,
+					"subject":         subject,
+				})
+			}
+			rows.Close()
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":                 true,
+		"message":                 "Capabilities updated",
+		"count":                   len(subjects),
+		"conflicting_assignments": conflicts,
+	})
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TEACHER DOCUMENTS (ID proof, certificates, verification)
+// ============================================
+
+const teacherDocumentMaxBytes = 10 << 20
+
+var teacherDocumentTypes = map[string]bool{"id_proof": true, "certificate": true, "other": true}
+
+// teacherDocumentExtensions are the accepted file types, by sniffed content type
+var teacherDocumentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// uploadTeacherDocument - Upload an ID proof or certificate (base64 `file`)
+// to private object storage; it starts pending review
+func uploadTeacherDocument(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var input struct {
+		DocType string `json:"doc_type"` // "id_proof", "certificate" or "other"
+		Title   string `json:"title"`
+		File    string `json:"file"` // base64, optionally a data URL
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if !teacherDocumentTypes[input.DocType] {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "doc_type must be 'id_proof', 'certificate' or 'other'"})
+		return
+	}
+
+	encoded := input.File
+	if i := strings.Index(encoded, "base64,"); i >= 0 {
+		encoded = encoded[i+len("base64,"):]
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file must be base64 encoded"})
+		return
+	}
+	if len(data) > teacherDocumentMaxBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file is larger than 10 MB"})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := teacherDocumentExtensions[contentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file must be a JPEG, PNG or PDF"})
+		return
+	}
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1)", teacherID).Scan(&exists)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	store := getObjectStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "Object storage is not configured"})
+		return
+	}
+
+	key := fmt.Sprintf("teachers/%s/documents/%s%s", teacherID, randomID(), ext)
+	if err := store.Put(key, contentType, data); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": "Upload failed: " + err.Error()})
+		return
+	}
+
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO mentor.teacher_documents (teacher_id, doc_type, title, object_key, content_type, size_bytes)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING id
+	`, teacherID, input.DocType, input.Title, key, contentType, len(data)).Scan(&id)
+
+	if err != nil {
+		store.Delete(key)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "status": "pending", "message": "Document uploaded for review"})
+}
+
+// queryTeacherDocuments lists documents with short-lived view links
+func queryTeacherDocuments(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT d.id, d.teacher_id, t.name, d.doc_type, COALESCE(d.title, ''), d.object_key,
+		       COALESCE(d.content_type, ''), COALESCE(d.size_bytes, 0), d.status,
+		       COALESCE(d.review_note, ''), COALESCE(d.reviewed_by, ''), d.reviewed_at, d.uploaded_at
+		FROM mentor.teacher_documents d
+		LEFT JOIN mentor.teachers t ON t.id = d.teacher_id
+		WHERE `+where+`
+		ORDER BY d.uploaded_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	store := getObjectStore()
+	docs := []gin.H{}
+	for rows.Next() {
+		var id, size int
+		var teacherID, docType, title, key, contentType, status, note, reviewedBy string
+		var teacherName sql.NullString
+		var reviewedAt sql.NullTime
+		var uploadedAt time.Time
+		if err := rows.Scan(&id, &teacherID, &teacherName, &docType, &title, &key, &contentType, &size,
+			&status, &note, &reviewedBy, &reviewedAt, &uploadedAt); err != nil {
+			continue
+		}
+		doc := gin.H{
+			"id":           id,
+			"teacher_id":   teacherID,
+			"teacher_name": teacherName.String,
+			"doc_type":     docType,
+			"title":        title,
+			"content_type": contentType,
+			"size_bytes":   size,
+			"status":       status,
+			"review_note":  note,
+			"reviewed_by":  reviewedBy,
+			"uploaded_at":  isoTimestamp(uploadedAt),
+		}
+		if reviewedAt.Valid {
+			doc["reviewed_at"] = isoTimestamp(reviewedAt.Time)
+		}
+		if store != nil {
+			doc["view_url"] = store.PresignGet(key, 15*time.Minute)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// teacherVerification summarises document review: verified once an ID proof is approved
+func teacherVerification(teacherID string) string {
+	var approvedID, pending bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.teacher_documents WHERE teacher_id = $1 AND doc_type = 'id_proof' AND status = 'approved'),
+		       EXISTS(SELECT 1 FROM mentor.teacher_documents WHERE teacher_id = $1 AND status = 'pending')
+	`, teacherID).Scan(&approvedID, &pending)
+
+	switch {
+	case approvedID:
+		return "verified"
+	case pending:
+		return "pending"
+	}
+	return "unverified"
+}
+
+// getTeacherDocuments - A teacher's documents and verification status
+func getTeacherDocuments(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	docs, err := queryTeacherDocuments("d.teacher_id = $1", teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"verification": teacherVerification(teacherID),
+		"documents":    docs,
+	})
+}
+
+// getDocumentReviewQueue - Documents waiting for review (or ?status=)
+func getDocumentReviewQueue(c *gin.Context) {
+	docs, err := queryTeacherDocuments("d.status = $1", c.DefaultQuery("status", "pending"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "documents": docs})
+}
+
+// reviewTeacherDocument - Approve or reject a document
+func reviewTeacherDocument(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Status     string `json:"status"` // "approved" or "rejected"
+		Note       string `json:"note"`
+		ReviewedBy string `json:"reviewed_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Status != "approved" && input.Status != "rejected" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "status must be 'approved' or 'rejected'"})
+		return
+	}
+	if input.Status == "rejected" && strings.TrimSpace(input.Note) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "note is required when rejecting"})
+		return
+	}
+
+	var teacherID string
+	err := db.QueryRow(`
+		UPDATE mentor.teacher_documents
+		SET status = $1, review_note = NULLIF($2, ''), reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $4
+		RETURNING teacher_id
+	`, input.Status, input.Note, input.ReviewedBy, id).Scan(&teacherID)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Document not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("teacher_document", id, input.Status, input.ReviewedBy, gin.H{"teacher_id": teacherID, "note": input.Note})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"status":       input.Status,
+		"verification": teacherVerification(teacherID),
+		"message":      "Document " + input.Status,
+	})
+}
+
+// deleteTeacherDocument - Remove a document and its stored file
+func deleteTeacherDocument(c *gin.Context) {
+	var key string
+	err := db.QueryRow(`
+		DELETE FROM mentor.teacher_documents WHERE id = $1 AND teacher_id = $2
+		RETURNING object_key
+	`, c.Param("docId"), c.Param("id")).Scan(&key)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Document not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if store := getObjectStore(); store != nil {
+		store.Delete(key)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Document deleted"})
+}
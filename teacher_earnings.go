@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TEACHER EARNINGS (Statement + pay adjustments)
+// ============================================
+
+// loadPayAdjustments returns a teacher's deductions and advances for a month
+func loadPayAdjustments(teacherID string, monthStart time.Time) ([]gin.H, float64, float64, error) {
+	adjustments := []gin.H{}
+	var deductions, advances float64
+
+	rows, err := db.Query(`
+		SELECT id, kind, amount, COALESCE(reason, ''), transaction_id, COALESCE(created_by, ''), created_at
+		FROM mentor.teacher_pay_adjustments
+		WHERE teacher_id = $1 AND payroll_month = $2
+		ORDER BY created_at
+	`, teacherID, monthStart)
+	if err != nil {
+		return adjustments, 0, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var kind, reason, createdBy string
+		var amount float64
+		var transactionID sql.NullInt64
+		var createdAt time.Time
+		if err := rows.Scan(&id, &kind, &amount, &reason, &transactionID, &createdBy, &createdAt); err != nil {
+			continue
+		}
+
+		if kind == "advance" {
+			advances += amount
+		} else {
+			deductions += amount
+		}
+
+		adjustment := gin.H{
+			"id":         id,
+			"kind":       kind,
+			"amount":     amount,
+			"reason":     reason,
+			"created_by": createdBy,
+			"created_at": isoTimestamp(createdAt),
+		}
+		if transactionID.Valid {
+			adjustment["transaction_id"] = transactionID.Int64
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+
+	return adjustments, deductions, advances, nil
+}
+
+// createPayAdjustment - Record a deduction or a salary advance against a month.
+// Advances are paid out now, so they also get their own expense transaction.
+func createPayAdjustment(c *gin.Context) {
+	teacherID := c.Param("teacherId")
+
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+
+	var input struct {
+		Kind      string  `json:"kind"` // "deduction" or "advance"
+		Amount    float64 `json:"amount"`
+		Reason    string  `json:"reason"`
+		CreatedBy string  `json:"created_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Kind != "deduction" && input.Kind != "advance" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "kind must be 'deduction' or 'advance'"})
+		return
+	}
+	if input.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "amount must be positive"})
+		return
+	}
+
+	var teacherName string
+	if err := db.QueryRow("SELECT name FROM mentor.teachers WHERE id = $1", teacherID).Scan(&teacherName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	// Once the salary is recorded the month is closed
+	var salaryRecorded bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.transactions
+		              WHERE teacher_id = $1 AND payroll_month = $2 AND voided_at IS NULL)
+	`, teacherID, monthStart).Scan(&salaryRecorded)
+	if salaryRecorded {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Salary already recorded for this month; void it first to adjust"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var transactionID sql.NullInt64
+	if input.Kind == "advance" {
+		err = tx.QueryRow(`
+			INSERT INTO mentor.transactions (date, type, amount, description, category, teacher_id)
+			VALUES (CURRENT_DATE, 'expense', $1, $2, 'teacher_advance', $3)
+			RETURNING id
+		`, input.Amount, fmt.Sprintf("Salary advance %s - %s", monthStart.Format("January 2006"), teacherName),
+			teacherID).Scan(&transactionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.teacher_pay_adjustments (teacher_id, payroll_month, kind, amount, reason, transaction_id, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''))
+		RETURNING id
+	`, teacherID, monthStart, input.Kind, input.Amount, input.Reason, transactionID, input.CreatedBy).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("teacher", teacherID, "pay_"+input.Kind, input.CreatedBy, gin.H{
+		"month":  monthStart.Format("2006-01"),
+		"amount": input.Amount,
+		"reason": input.Reason,
+	})
+
+	response := gin.H{"success": true, "id": id, "message": "Adjustment recorded"}
+	if transactionID.Valid {
+		response["transaction_id"] = transactionID.Int64
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getTeacherEarnings - Monthly statement a teacher can check their payout against:
+// classes, rate, deductions, advances, net payable, and the salary actually recorded
+func getTeacherEarnings(c *gin.Context) {
+	monthStart, ok := payrollMonth(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid year or month"})
+		return
+	}
+
+	p, err := computePayroll(c.Param("teacherId"), monthStart)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	statement := p.toJSON(monthStart)
+	net := p.NetPayable()
+
+	reconciliation := gin.H{"expected": net, "currency": currencyCode()}
+	if p.TransactionID.Valid {
+		var paid float64
+		var paidOn time.Time
+		var paidCurrency sql.NullString
+		db.QueryRow("SELECT amount, date, currency FROM mentor.transactions WHERE id = $1", p.TransactionID.Int64).Scan(&paid, &paidOn, &paidCurrency)
+
+		// A salary paid in another currency is compared at that day's rate
+		paidDefault, err := toDefaultCurrency(paid, orDefaultCurrency(paidCurrency), paidOn)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		reconciliation["paid"] = paid
+		reconciliation["paid_currency"] = orDefaultCurrency(paidCurrency)
+		reconciliation["paid_on"] = paidOn.Format("2006-01-02")
+		reconciliation["difference"] = paidDefault - net
+		if math.Abs(paidDefault-net) < 0.01 {
+			reconciliation["status"] = "paid"
+		} else {
+			reconciliation["status"] = "mismatch"
+		}
+	} else if net > 0 {
+		reconciliation["status"] = "pending"
+	} else {
+		reconciliation["status"] = "nothing_due"
+	}
+	statement["reconciliation"] = reconciliation
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "earnings": statement})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TEACHER PROFILE
+// ============================================
+
+// teacherProfileInput holds the profile fields accepted by create/update.
+// nil leaves a field unchanged on update.
+type teacherProfileInput struct {
+	PhotoURL          *string    `json:"photo_url"`
+	Email             *string    `json:"email"`
+	Address           *string    `json:"address"`
+	Qualifications    *string    `json:"qualifications"`
+	ExperienceYears   *int       `json:"experience_years"`
+	PreferredSubjects stringList `json:"preferred_subjects"`
+	Bio               *string    `json:"bio"`
+	Timezone          *string    `json:"timezone"` // IANA name; "" falls back to APP_TIMEZONE
+}
+
+// timezoneError validates the timezone field, returning "" when it's fine
+func (in teacherProfileInput) timezoneError() string {
+	if in.Timezone != nil && *in.Timezone != "" && !validTimezone(*in.Timezone) {
+		return "timezone must be an IANA zone like Asia/Dhaka"
+	}
+	return ""
+}
+
+// teacherProfileSelect lists the profile columns in teacherProfile's scan order
+const teacherProfileSelect = `COALESCE(photo_url, ''), COALESCE(email, ''), COALESCE(address, ''),
+	COALESCE(qualifications, ''), experience_years, COALESCE(preferred_subjects, '{}'), COALESCE(bio, ''),
+	COALESCE(timezone, '')`
+
+type teacherProfile struct {
+	PhotoURL, Email, Address, Qualifications, Bio, Timezone string
+	ExperienceYears                                         *int
+	PreferredSubjects                                       []string
+}
+
+func (p *teacherProfile) scanTargets() []interface{} {
+	return []interface{}{&p.PhotoURL, &p.Email, &p.Address, &p.Qualifications, &p.ExperienceYears,
+		pq.Array(&p.PreferredSubjects), &p.Bio, &p.Timezone}
+}
+
+// addTo copies the profile into a teacher response
+func (p *teacherProfile) addTo(teacher gin.H) gin.H {
+	teacher["photo_url"] = p.PhotoURL
+	teacher["email"] = p.Email
+	teacher["address"] = p.Address
+	teacher["qualifications"] = p.Qualifications
+	teacher["experience_years"] = p.ExperienceYears
+	teacher["preferred_subjects"] = p.PreferredSubjects
+	teacher["bio"] = p.Bio
+	teacher["timezone"] = p.Timezone
+	return teacher
+}
+
+// saveTeacherProfile applies the provided profile fields
+func saveTeacherProfile(id string, in teacherProfileInput) error {
+	var subjects interface{}
+	if in.PreferredSubjects != nil {
+		subjects = pq.Array([]string(in.PreferredSubjects))
+	}
+	_, err := db.Exec(`
+		UPDATE mentor.teachers
+		SET photo_url = COALESCE($1, photo_url), email = COALESCE($2, email), address = COALESCE($3, address),
+		    qualifications = COALESCE($4, qualifications), experience_years = COALESCE($5, experience_years),
+		    preferred_subjects = COALESCE($6, preferred_subjects), bio = COALESCE($7, bio),
+		    timezone = CASE WHEN $8::text IS NULL THEN timezone ELSE NULLIF($8, '') END
+		WHERE id = $9
+	`, in.PhotoURL, in.Email, in.Address, in.Qualifications, in.ExperienceYears, subjects, in.Bio, in.Timezone, id)
+	return err
+}
+
+// getTeacherPublicProfile - Guardian-facing profile: no contact details or credentials
+func getTeacherPublicProfile(c *gin.Context) {
+	id := c.Param("id")
+
+	var name string
+	var active int
+	var p teacherProfile
+	err := db.QueryRow(`SELECT name, active, `+teacherProfileSelect+` FROM mentor.teachers WHERE id = $1`, id).
+		Scan(append([]interface{}{&name, &active}, p.scanTargets()...)...)
+	if err != nil || active != 1 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	var students int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM mentor.subscriptions s
+		WHERE `+teacherAssignedSQL+` AND s.status = 'active' AND s.deleted_at IS NULL
+	`, id).Scan(&students)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": gin.H{
+			"id":                 id,
+			"name":               name,
+			"photo_url":          p.PhotoURL,
+			"qualifications":     p.Qualifications,
+			"experience_years":   p.ExperienceYears,
+			"preferred_subjects": p.PreferredSubjects,
+			"bio":                p.Bio,
+			"active_students":    students,
+			"rating":             teacherRatingSummary(id),
+		},
+	})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TEACHER RATINGS (Guardian feedback + moderation)
+// ============================================
+
+type ratingInput struct {
+	Rating     int    `json:"rating"` // 1-5
+	Comment    string `json:"comment"`
+	ProgressID int    `json:"progress_id"` // rate one completed class
+	Month      string `json:"month"`       // or a month, "2006-01" (default: this month)
+	TeacherID  string `json:"teacher_id"`  // monthly: a subject teacher instead of the main one
+}
+
+// saveTeacherRating stores one rating for a subscription, per class or per
+// month, and returns the response body and HTTP status
+func saveTeacherRating(subId int, input ratingInput, submittedBy string) (gin.H, int) {
+	if input.Rating < 1 || input.Rating > 5 {
+		return gin.H{"success": false, "error": "rating must be between 1 and 5"}, http.StatusBadRequest
+	}
+
+	var mainTeacher string
+	if err := db.QueryRow(`
+		SELECT teacher_id FROM mentor.subscriptions WHERE id = $1 AND deleted_at IS NULL
+	`, subId).Scan(&mainTeacher); err != nil {
+		return gin.H{"success": false, "error": "Subscription not found"}, http.StatusNotFound
+	}
+
+	var teacherID string
+	var progressID *int
+	var month *time.Time
+	if input.ProgressID != 0 {
+		var classTeacher sql.NullString
+		err := db.QueryRow(`
+			SELECT teacher_id FROM mentor.progress WHERE id = $1 AND subscription_id = $2
+		`, input.ProgressID, subId).Scan(&classTeacher)
+		if err != nil {
+			return gin.H{"success": false, "error": "Class not found for this subscription"}, http.StatusNotFound
+		}
+		teacherID = mainTeacher
+		if classTeacher.String != "" {
+			teacherID = classTeacher.String
+		}
+		progressID = &input.ProgressID
+	} else {
+		m := time.Now()
+		if input.Month != "" {
+			parsed, err := time.Parse("2006-01", input.Month)
+			if err != nil {
+				return gin.H{"success": false, "error": "month must be YYYY-MM"}, http.StatusBadRequest
+			}
+			m = parsed
+		}
+		first := time.Date(m.Year(), m.Month(), 1, 0, 0, 0, 0, time.Local)
+		month = &first
+
+		teacherID = mainTeacher
+		if input.TeacherID != "" && input.TeacherID != mainTeacher {
+			var teaches bool
+			db.QueryRow(`
+				SELECT EXISTS(SELECT 1 FROM mentor.schedule WHERE subscription_id = $1 AND teacher_id = $2)
+			`, subId, input.TeacherID).Scan(&teaches)
+			if !teaches {
+				return gin.H{"success": false, "error": "Teacher does not teach this subscription"}, http.StatusBadRequest
+			}
+			teacherID = input.TeacherID
+		}
+	}
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.teacher_ratings (subscription_id, teacher_id, progress_id, rating_month, rating, comment, submitted_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id
+	`, subId, teacherID, progressID, month, input.Rating, strings.TrimSpace(input.Comment), submittedBy).Scan(&id)
+
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return gin.H{"success": false, "error": "Already rated"}, http.StatusConflict
+	}
+	if err != nil {
+		return gin.H{"success": false, "error": err.Error()}, http.StatusInternalServerError
+	}
+
+	return gin.H{"success": true, "id": id, "teacher_id": teacherID, "message": "Thank you for your feedback"}, http.StatusOK
+}
+
+// createSubscriptionRating - Guardian rates the teacher of a subscription
+func createSubscriptionRating(c *gin.Context) {
+	subId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid subscription id"})
+		return
+	}
+
+	var input ratingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resp, status := saveTeacherRating(subId, input, "guardian")
+	c.JSON(status, resp)
+}
+
+// createStudentRating - Same from the student app session
+func createStudentRating(c *gin.Context) {
+	var input ratingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resp, status := saveTeacherRating(c.GetInt("subscription_id"), input, "student")
+	c.JSON(status, resp)
+}
+
+// teacherRatingSummary is the published average shown on the profile
+func teacherRatingSummary(teacherID string) gin.H {
+	var avg sql.NullFloat64
+	var count int
+	db.QueryRow(`
+		SELECT AVG(rating), COUNT(*) FROM mentor.teacher_ratings
+		WHERE teacher_id = $1 AND status = 'published'
+	`, teacherID).Scan(&avg, &count)
+
+	summary := gin.H{"count": count}
+	if avg.Valid {
+		summary["average"] = math.Round(avg.Float64*10) / 10
+	}
+	return summary
+}
+
+// queryRatings lists ratings, newest first
+func queryRatings(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.subscription_id, s.student_name, r.teacher_id, r.progress_id, r.rating_month,
+		       r.rating, COALESCE(r.comment, ''), r.submitted_by, r.status,
+		       COALESCE(r.moderated_by, ''), COALESCE(r.moderation_note, ''), r.created_at
+		FROM mentor.teacher_ratings r
+		LEFT JOIN mentor.subscriptions s ON s.id = r.subscription_id
+		WHERE `+where+`
+		ORDER BY r.created_at DESC
+		LIMIT 200
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []gin.H{}
+	for rows.Next() {
+		var id, subId, rating int
+		var studentName sql.NullString
+		var teacherID, comment, submittedBy, status, moderatedBy, note string
+		var progressID sql.NullInt64
+		var month sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&id, &subId, &studentName, &teacherID, &progressID, &month,
+			&rating, &comment, &submittedBy, &status, &moderatedBy, &note, &createdAt); err != nil {
+			continue
+		}
+		r := gin.H{
+			"id":              id,
+			"subscription_id": subId,
+			"student_name":    studentName.String,
+			"teacher_id":      teacherID,
+			"rating":          rating,
+			"comment":         comment,
+			"submitted_by":    submittedBy,
+			"status":          status,
+			"moderated_by":    moderatedBy,
+			"moderation_note": note,
+			"created_at":      isoTimestamp(createdAt),
+		}
+		if progressID.Valid {
+			r["progress_id"] = progressID.Int64
+		}
+		if month.Valid {
+			r["month"] = month.Time.Format("2006-01")
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, nil
+}
+
+// getTeacherRatings - Published ratings and the average for a teacher
+func getTeacherRatings(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	ratings, err := queryRatings("r.teacher_id = $1 AND r.status = 'published'", teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "summary": teacherRatingSummary(teacherID), "ratings": ratings})
+}
+
+// getRatingsForModeration - Admin view of all ratings (status, teacher_id, max_rating filters)
+func getRatingsForModeration(c *gin.Context) {
+	where := "1=1"
+	args := []interface{}{}
+	argCount := 0
+
+	if status := c.Query("status"); status != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.status = $%d", argCount)
+		args = append(args, status)
+	}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.teacher_id = $%d", argCount)
+		args = append(args, teacherID)
+	}
+	if maxRating := c.Query("max_rating"); maxRating != "" {
+		argCount++
+		where += fmt.Sprintf(" AND r.rating <= $%d", argCount)
+		args = append(args, maxRating)
+	}
+
+	ratings, err := queryRatings(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "ratings": ratings})
+}
+
+// moderateRating - Hide or republish a rating
+func moderateRating(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Status      string `json:"status"` // "published" or "hidden"
+		Note        string `json:"note"`
+		ModeratedBy string `json:"moderated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Status != "published" && input.Status != "hidden" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "status must be 'published' or 'hidden'"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.teacher_ratings
+		SET status = $1, moderation_note = NULLIF($2, ''), moderated_by = $3, moderated_at = NOW()
+		WHERE id = $4
+	`, input.Status, input.Note, input.ModeratedBy, id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Rating not found"})
+		return
+	}
+
+	logAudit("teacher_rating", id, "moderated", input.ModeratedBy, gin.H{"status": input.Status, "note": input.Note})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": input.Status, "message": "Rating updated"})
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TEACHER SELF-SERVICE (/api/me, Bearer token)
+// ============================================
+
+const teacherSessionTTL = 30 * 24 * time.Hour
+
+// createTeacherSession issues the token the app sends as "Authorization: Bearer"
+func createTeacherSession(teacherID string) (string, time.Time, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	expiresAt := time.Now().Add(teacherSessionTTL)
+
+	_, err := db.Exec(`
+		INSERT INTO mentor.teacher_sessions (token, teacher_id, expires_at) VALUES ($1, $2, $3)
+	`, token, teacherID, expiresAt)
+	return token, expiresAt, err
+}
+
+// pruneTeacherSessions deletes sessions past their expiry; sessionTeacher
+// already ignores them, this just keeps the table from growing
+func pruneTeacherSessions() error {
+	_, err := db.Exec("DELETE FROM mentor.teacher_sessions WHERE expires_at < NOW()")
+	return err
+}
+
+// teacherAuth resolves "Authorization: Bearer <token>" to an active teacher
+func teacherAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Teacher token required"})
+			return
+		}
+
+		teacherID, ok := sessionTeacher(token)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Session expired, please log in again"})
+			return
+		}
+
+		c.Set("teacher_id", teacherID)
+		c.Set("teacher_token", token)
+		c.Next()
+	}
+}
+
+// sessionTeacher looks up the active teacher a session token belongs to
+func sessionTeacher(token string) (string, bool) {
+	var teacherID string
+	err := db.QueryRow(`
+		SELECT ts.teacher_id FROM mentor.teacher_sessions ts
+		JOIN mentor.teachers t ON t.id = ts.teacher_id
+		WHERE ts.token = $1 AND ts.expires_at > NOW() AND COALESCE(t.active, 1) = 1
+	`, token).Scan(&teacherID)
+	return teacherID, err == nil
+}
+
+// teacherSelfOrAdmin lets admins through, and teachers only for their own
+// teacher ID in the named route param
+func teacherSelfOrAdmin(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdminRequest(c) {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		teacherID, ok := sessionTeacher(token)
+		if token == "" || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Teacher or admin token required"})
+			return
+		}
+		if teacherID != c.Param(param) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "error": "You can only view your own records"})
+			return
+		}
+		c.Set("teacher_id", teacherID)
+		c.Next()
+	}
+}
+
+// teacherOrAdmin lets admins through, and signed-in teachers with teacher_id
+// set so the handler can limit them to their own records
+func teacherOrAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdminRequest(c) {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		teacherID, ok := sessionTeacher(token)
+		if token == "" || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Teacher or admin token required"})
+			return
+		}
+		c.Set("teacher_id", teacherID)
+		c.Set("teacher_token", token)
+		c.Next()
+	}
+}
+
+// teachesSubscription reports whether the teacher teaches at least one of the
+// subscription's subjects, by the same rule as teacherAssignedSQL
+func teachesSubscription(teacherID string, subId int) bool {
+	var teaches bool
+	db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM mentor.subscriptions s WHERE s.id = $2 AND `+teacherAssignedSQL+`)
+	`, teacherID, subId).Scan(&teaches)
+	return teaches
+}
+
+// getMe - The signed-in teacher's own profile and availability
+func getMe(c *gin.Context) {
+	id := c.GetString("teacher_id")
+
+	var name, phone string
+	var profile teacherProfile
+	var availableDays []string
+	var availableFrom, availableTo sql.NullString
+	err := db.QueryRow(`
+		SELECT name, phone, available_days, available_from, available_to, `+teacherProfileSelect+`
+		FROM mentor.teachers WHERE id = $1
+	`, id).Scan(append([]interface{}{&name, &phone, pq.Array(&availableDays), &availableFrom, &availableTo},
+		profile.scanTargets()...)...)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	if availableDays == nil {
+		availableDays = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"teacher": profile.addTo(gin.H{
+			"id":    id,
+			"name":  name,
+			"phone": phone,
+			"availability": gin.H{
+				"days": availableDays,
+				"from": availableFrom.String,
+				"to":   availableTo.String,
+			},
+			"verification": teacherVerification(id),
+		}),
+	})
+}
+
+// updateMe - Teachers edit their own phone, photo, bio, time zone and availability.
+// Everything else (name, rates, zones, capabilities) stays with admins.
+func updateMe(c *gin.Context) {
+	id := c.GetString("teacher_id")
+
+	var input struct {
+		Phone        *string `json:"phone"`
+		PhotoURL     *string `json:"photo_url"`
+		Bio          *string `json:"bio"`
+		Timezone     *string `json:"timezone"`
+		Availability *struct {
+			Days stringList `json:"days"` // ["Sat", "Mon"]
+			From string     `json:"from"` // "3:00 PM" or "15:00"
+			To   string     `json:"to"`
+		} `json:"availability"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	fields := map[string]string{}
+	if input.Phone != nil {
+		*input.Phone = strings.TrimSpace(*input.Phone)
+		if len(*input.Phone) < 6 || len(*input.Phone) > 20 {
+			fields["phone"] = "must be a phone number"
+		} else {
+			var taken bool
+			db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE phone = $1 AND id <> $2)", *input.Phone, id).Scan(&taken)
+			if taken {
+				fields["phone"] = "is already used by another teacher"
+			}
+		}
+	}
+
+	if input.Timezone != nil && *input.Timezone != "" && !validTimezone(*input.Timezone) {
+		fields["timezone"] = "must be an IANA zone like Asia/Dhaka"
+	}
+
+	var days []string
+	var from, to *string
+	if a := input.Availability; a != nil {
+		days = []string{}
+		for _, d := range a.Days {
+			if !validScheduleDay(d) {
+				fields["availability.days"] = "invalid day " + d + "; use Sat-Fri"
+			}
+			days = append(days, d)
+		}
+		if a.From != "" || a.To != "" {
+			f, okFrom := parseClassTime(a.From)
+			t, okTo := parseClassTime(a.To)
+			if !okFrom || !okTo {
+				fields["availability.from"] = `from and to must be times like "3:00 PM"`
+			} else {
+				from, to = &f, &t
+			}
+		}
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+
+	var daysArg interface{}
+	if days != nil {
+		daysArg = pq.Array(days)
+	}
+	// Setting availability replaces all of it, so clear times that weren't sent
+	availabilitySent := input.Availability != nil
+
+	_, err := db.Exec(`
+		UPDATE mentor.teachers
+		SET phone = COALESCE($1, phone), photo_url = COALESCE($2, photo_url), bio = COALESCE($3, bio),
+		    available_days = COALESCE($4, available_days),
+		    available_from = CASE WHEN $5 THEN $6 ELSE available_from END,
+		    available_to = CASE WHEN $5 THEN $7 ELSE available_to END,
+		    timezone = CASE WHEN $8::text IS NULL THEN timezone ELSE NULLIF($8, '') END
+		WHERE id = $9
+	`, input.Phone, input.PhotoURL, input.Bio, daysArg, availabilitySent, from, to, input.Timezone, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("teacher", id, "self_updated", id, nil)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Profile updated"})
+}
+
+// logoutMe - End the current session
+func logoutMe(c *gin.Context) {
+	db.Exec("DELETE FROM mentor.teacher_sessions WHERE token = $1", c.GetString("teacher_token"))
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Logged out"})
+}
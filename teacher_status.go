@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TEACHER ACTIVATION (Deactivate instead of delete)
+// ============================================
+
+// teacherActiveSubscriptions lists the active subscriptions a teacher
+// teaches, as the main teacher or for a subject
+func teacherActiveSubscriptions(teacherID string) []gin.H {
+	subs := []gin.H{}
+	rows, err := db.Query(`
+		SELECT s.id, s.student_name, s.class FROM mentor.subscriptions s
+		WHERE `+teacherAssignedSQL+` AND s.status = 'active' AND s.deleted_at IS NULL
+		ORDER BY s.id
+	`, teacherID)
+	if err != nil {
+		return subs
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, class int
+		var studentName string
+		if err := rows.Scan(&id, &studentName, &class); err != nil {
+			continue
+		}
+		subs = append(subs, gin.H{"id": id, "student_name": studentName, "class": class})
+	}
+	return subs
+}
+
+// setTeacherActive - Deactivate or reactivate a teacher. Inactive teachers
+// can't log in or be assigned; their history is kept.
+func setTeacherActive(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Active    *bool  `json:"active"`
+		UpdatedBy string `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil || input.Active == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "active (true/false) is required"})
+		return
+	}
+
+	active := 0
+	if *input.Active {
+		active = 1
+	}
+
+	result, err := db.Exec("UPDATE mentor.teachers SET active = $1 WHERE id = $2", active, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	action := "deactivated"
+	if *input.Active {
+		action = "reactivated"
+	}
+	logAudit("teacher", id, action, input.UpdatedBy, nil)
+
+	response := gin.H{"success": true, "active": *input.Active, "message": "Teacher " + action}
+	if !*input.Active {
+		db.Exec("DELETE FROM mentor.teacher_sessions WHERE teacher_id = $1", id)
+
+		// Still-assigned students need a new teacher
+		if subs := teacherActiveSubscriptions(id); len(subs) > 0 {
+			response["active_subscriptions"] = subs
+			response["warning"] = "Teacher still has active subscriptions; transfer them"
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
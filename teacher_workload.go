@@ -0,0 +1,194 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TEACHER WORKLOAD (Classes, hours, travel)
+// ============================================
+
+// defaultClassMinutes is the assumed length of one class visit
+const defaultClassMinutes = 60
+
+// workloadWeek is the teaching week in schedule code order (Sat=1 ... Fri=7)
+var workloadWeek = []time.Weekday{
+	time.Saturday, time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday,
+}
+
+// distanceKm is the great-circle distance between two coordinates
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// getTeacherWorkload - Classes per week, hours per day, students and travel
+// spread for a teacher's active subscriptions
+func getTeacherWorkload(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM mentor.teachers WHERE id = $1", teacherID).Scan(&name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT s.id, s.student_name, COALESCE(s.time, ''), COALESCE(s.days_per_week, 0), s.schedule_day_list,
+		       `+visitMinutesSQL+`
+		FROM mentor.subscriptions s
+		WHERE `+teacherAssignedSQL+` AND s.status = 'active' AND s.deleted_at IS NULL
+		ORDER BY s.id
+	`, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type daySlot struct {
+		classes, minutes int
+		times            []string
+	}
+	days := map[time.Weekday]*daySlot{}
+	classesPerWeek, unscheduled, minutesPerWeek := 0, 0, 0
+	var subIDs []int64
+
+	for rows.Next() {
+		var id int
+		var studentName, classTime string
+		var daysPerWeek, minutes int
+		var scheduleDays []string
+		if err := rows.Scan(&id, &studentName, &classTime, &daysPerWeek, pq.Array(&scheduleDays), &minutes); err != nil {
+			continue
+		}
+		subIDs = append(subIDs, int64(id))
+
+		weekdays := scheduledWeekdays(scheduleDays)
+		if len(weekdays) == 0 {
+			// Counted for the week but not placed on a day
+			classesPerWeek += daysPerWeek
+			unscheduled += daysPerWeek
+			minutesPerWeek += daysPerWeek * minutes
+			continue
+		}
+		classesPerWeek += len(weekdays)
+		minutesPerWeek += len(weekdays) * minutes
+		for wd := range weekdays {
+			if days[wd] == nil {
+				days[wd] = &daySlot{}
+			}
+			days[wd].classes++
+			days[wd].minutes += minutes
+			if classTime != "" {
+				days[wd].times = append(days[wd].times, classTime)
+			}
+		}
+	}
+
+	perDay := []gin.H{}
+	busiest := 0.0
+	for _, wd := range workloadWeek {
+		slot := days[wd]
+		if slot == nil {
+			slot = &daySlot{}
+		}
+		hours := float64(slot.minutes) / 60
+		busiest = max(busiest, hours)
+		sort.Slice(slot.times, func(i, j int) bool {
+			ti, _ := time.Parse("3:04 PM", slot.times[i])
+			tj, _ := time.Parse("3:04 PM", slot.times[j])
+			return ti.Before(tj)
+		})
+		perDay = append(perDay, gin.H{
+			"day":     wd.String()[:3],
+			"classes": slot.classes,
+			"hours":   hours,
+			"times":   append([]string{}, slot.times...),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"teacher_id":          teacherID,
+		"teacher_name":        name,
+		"student_count":       len(subIDs),
+		"classes_per_week":    classesPerWeek,
+		"unscheduled_classes": unscheduled,
+		"hours_per_week":      float64(minutesPerWeek) / 60,
+		"max_hours_per_day":   busiest,
+		"class_minutes":       defaultClassMinutes,
+		"hours_per_day":       perDay,
+		"travel":              teacherTravelSpread(teacherID, subIDs),
+	})
+}
+
+// teacherTravelSpread locates each student from the teacher's recent in-person
+// check-ins and reports how far apart they are
+func teacherTravelSpread(teacherID string, subIDs []int64) gin.H {
+	travel := gin.H{"located_students": 0}
+	if len(subIDs) == 0 {
+		return travel
+	}
+
+	rows, err := db.Query(`
+		SELECT subscription_id, AVG(latitude), AVG(longitude)
+		FROM mentor.attendance
+		WHERE teacher_id = $1 AND subscription_id = ANY($2) AND action = 'start'
+		  AND COALESCE(mode, 'offline') <> 'online'
+		  AND latitude <> 0 AND longitude <> 0
+		  AND recorded_at > NOW() - INTERVAL '60 days'
+		GROUP BY subscription_id
+	`, teacherID, pq.Array(subIDs))
+	if err != nil {
+		return travel
+	}
+	defer rows.Close()
+
+	type point struct{ lat, lng float64 }
+	var points []point
+	for rows.Next() {
+		var subId int
+		var lat, lng sql.NullFloat64
+		if err := rows.Scan(&subId, &lat, &lng); err != nil || !lat.Valid || !lng.Valid {
+			continue
+		}
+		points = append(points, point{lat.Float64, lng.Float64})
+	}
+	if len(points) == 0 {
+		return travel
+	}
+
+	var center point
+	for _, p := range points {
+		center.lat += p.lat / float64(len(points))
+		center.lng += p.lng / float64(len(points))
+	}
+
+	radius, spread := 0.0, 0.0
+	for i, p := range points {
+		radius = max(radius, distanceKm(center.lat, center.lng, p.lat, p.lng))
+		for _, q := range points[i+1:] {
+			spread = max(spread, distanceKm(p.lat, p.lng, q.lat, q.lng))
+		}
+	}
+
+	round := func(km float64) float64 { return math.Round(km*10) / 10 }
+	travel["located_students"] = len(points)
+	travel["center"] = gin.H{"latitude": center.lat, "longitude": center.lng}
+	travel["radius_km"] = round(radius)
+	travel["max_distance_km"] = round(spread)
+	return travel
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TEACHER ZONES (Neighborhoods / postcodes covered)
+// ============================================
+
+// normalizeZone makes "  Dhanmondi  R/A " and "dhanmondi r/a" the same zone
+func normalizeZone(zone string) string {
+	return strings.ToLower(strings.Join(strings.Fields(zone), " "))
+}
+
+// studentZones are the zone keys a student location can match on
+func studentZones(area, postcode string) []string {
+	zones := []string{}
+	for _, z := range []string{area, postcode} {
+		if z = normalizeZone(z); z != "" {
+			zones = append(zones, z)
+		}
+	}
+	return zones
+}
+
+// teacherCoversLocation reports whether a teacher covers the area or
+// postcode. Teachers with no zones set are not restricted.
+func teacherCoversLocation(teacherID, area, postcode string) bool {
+	zones := studentZones(area, postcode)
+	if len(zones) == 0 {
+		return true
+	}
+
+	var hasZones, covered bool
+	db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mentor.teacher_zones WHERE teacher_id = $1),
+		       EXISTS(SELECT 1 FROM mentor.teacher_zones WHERE teacher_id = $1 AND zone = ANY($2))
+	`, teacherID, pq.Array(zones)).Scan(&hasZones, &covered)
+	return !hasZones || covered
+}
+
+// getTeacherZones - Areas and postcodes a teacher covers
+func getTeacherZones(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	rows, err := db.Query("SELECT zone FROM mentor.teacher_zones WHERE teacher_id = $1 ORDER BY zone", teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	zones := []string{}
+	for rows.Next() {
+		var zone string
+		if rows.Scan(&zone) == nil {
+			zones = append(zones, zone)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "teacher_id": teacherID, "zones": zones})
+}
+
+// updateTeacherZones - Replace the areas and postcodes a teacher covers.
+// An empty list means the teacher travels anywhere.
+func updateTeacherZones(c *gin.Context) {
+	teacherID := c.Param("id")
+
+	var input struct {
+		Zones     stringList `json:"zones"` // ["Dhanmondi", "1209"]
+		UpdatedBy string     `json:"updated_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	zones := []string{}
+	seen := map[string]bool{}
+	for _, z := range input.Zones {
+		if z = normalizeZone(z); z != "" && !seen[z] {
+			seen[z] = true
+			zones = append(zones, z)
+		}
+	}
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM mentor.teachers WHERE id = $1)", teacherID).Scan(&exists)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mentor.teacher_zones WHERE teacher_id = $1", teacherID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO mentor.teacher_zones (teacher_id, zone)
+		SELECT $1, UNNEST($2::TEXT[])
+	`, teacherID, pq.Array(zones)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("teacher", teacherID, "zones_updated", input.UpdatedBy, gin.H{"zones": zones})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "zones": zones, "message": "Zones updated"})
+}
+
+// matchTeachers - Active teachers covering a student's area or postcode,
+// optionally limited to those listed for the class and subjects. Least
+// loaded first.
+func matchTeachers(c *gin.Context) {
+	zones := studentZones(c.Query("area"), c.Query("postcode"))
+	if len(zones) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "area or postcode is required"})
+		return
+	}
+	class := c.Query("class")
+	subjects := []string{}
+	for _, s := range strings.Split(c.Query("subjects"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			subjects = append(subjects, s)
+		}
+	}
+
+	// Teachers with no zones are included only when asked for
+	includeUnzoned := c.Query("include_unzoned") == "true"
+
+	rows, err := db.Query(`
+		SELECT t.id, t.name,
+		       EXISTS(SELECT 1 FROM mentor.teacher_zones z WHERE z.teacher_id = t.id AND z.zone = ANY($1)) AS covers,
+		       (SELECT COUNT(*) FROM mentor.subscriptions s
+		        WHERE s.teacher_id = t.id AND s.status = 'active' AND s.deleted_at IS NULL) AS students
+		FROM mentor.teachers t
+		WHERE COALESCE(t.active, 1) = 1
+		  AND (EXISTS(SELECT 1 FROM mentor.teacher_zones z WHERE z.teacher_id = t.id AND z.zone = ANY($1))
+		       OR ($2 AND NOT EXISTS(SELECT 1 FROM mentor.teacher_zones z WHERE z.teacher_id = t.id)))
+		  AND ($3 = '' OR NOT EXISTS(SELECT 1 FROM mentor.teacher_capabilities tc WHERE tc.teacher_id = t.id)
+		       OR NOT EXISTS(
+				SELECT 1 FROM UNNEST($4::TEXT[]) AS u(subject)
+				WHERE NOT EXISTS(
+					SELECT 1 FROM mentor.teacher_capabilities tc
+					WHERE tc.teacher_id = t.id AND tc.class::TEXT = $3 AND LOWER(tc.subject) = LOWER(u.subject)
+				)
+		       ))
+		ORDER BY students, t.id
+	`, pq.Array(zones), includeUnzoned, class, pq.Array(subjects))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	teachers := []gin.H{}
+	for rows.Next() {
+		var id, name string
+		var covers bool
+		var students int
+		if err := rows.Scan(&id, &name, &covers, &students); err != nil {
+			continue
+		}
+		teachers = append(teachers, gin.H{
+			"id":              id,
+			"name":            name,
+			"covers_zone":     covers,
+			"active_students": students,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "zones": zones, "teachers": teachers})
+}
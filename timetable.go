@@ -0,0 +1,452 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// TIMETABLE SUGGESTIONS (Conflict-free slots)
+// ============================================
+
+const (
+	// Used when a teacher hasn't set their available hours
+	defaultAvailableFrom = "3:00 PM"
+	defaultAvailableTo   = "9:00 PM"
+
+	suggestStepMinutes   = 30 // candidate start times are this far apart
+	travelBufferMinutes  = 30 // extra gap needed between visits in different zones
+	maxScheduleSuggested = 20
+)
+
+// commitment is one weekly class visit already on a teacher's timetable
+type commitment struct {
+	subscriptionID int
+	studentName    string
+	start          int // minutes after midnight
+	minutes        int
+	zone           string
+}
+
+// clockMinutes turns a "4:00 PM" class time into minutes after midnight
+func clockMinutes(classTime string) (int, bool) {
+	t, ok := parseClassTime(classTime)
+	if !ok {
+		return 0, false
+	}
+	clock, _ := time.Parse("3:04 PM", t)
+	return clock.Hour()*60 + clock.Minute(), true
+}
+
+// formatClockMinutes is the inverse of clockMinutes
+func formatClockMinutes(m int) string {
+	return time.Date(2000, 1, 1, m/60, m%60, 0, 0, time.UTC).Format("3:04 PM")
+}
+
+// teacherCommitments lists the teacher's weekly visits per weekday, leaving
+// out the subscription being scheduled
+func teacherCommitments(teacherID string, excludeSubId int) (map[time.Weekday][]commitment, error) {
+	slots, err := loadScheduleSlots(`sl.subscription_id IN (
+		SELECT s.id FROM mentor.subscriptions s WHERE `+teacherAssignedSQL+`)`, teacherID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT s.id, s.student_name, COALESCE(s.time, ''), s.schedule_day_list, s.subject_list,
+		       COALESCE(NULLIF(s.area, ''), s.postcode, ''), `+visitMinutesSQL+`
+		FROM mentor.subscriptions s
+		WHERE `+teacherAssignedSQL+` AND s.status = 'active' AND s.deleted_at IS NULL AND s.id <> $2
+	`, teacherID, excludeSubId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	week := map[time.Weekday][]commitment{}
+	for rows.Next() {
+		var cm commitment
+		var classTime, zone string
+		var scheduleDays, subjects []string
+		if err := rows.Scan(&cm.subscriptionID, &cm.studentName, &classTime, pq.Array(&scheduleDays), pq.Array(&subjects),
+			&zone, &cm.minutes); err != nil {
+			continue
+		}
+		cm.zone = normalizeZone(zone)
+		for wd := range scheduledWeekdays(scheduleDays) {
+			// Subjects with their own slot that day are separate visits
+			for _, t := range visitTimes(slots[cm.subscriptionID][wd], subjects, classTime) {
+				start, ok := clockMinutes(t)
+				if !ok {
+					continue
+				}
+				visit := cm
+				visit.start = start
+				week[wd] = append(week[wd], visit)
+			}
+		}
+	}
+	return week, nil
+}
+
+// slotFit checks a visit of minutes at start against one day's commitments.
+// Visits in a different (or unknown) zone need travel time in between.
+// Returns whether it fits and the travel score: 2 when it sits right next to
+// a visit in the student's zone, 1 when the teacher is in that zone that day
+// anyway.
+func slotFit(day []commitment, start, minutes int, zone string) (bool, int) {
+	score := 0
+	for _, cm := range day {
+		buffer := 0
+		if zone == "" || cm.zone != zone {
+			buffer = travelBufferMinutes
+		}
+		if start < cm.start+cm.minutes+buffer && cm.start < start+minutes+buffer {
+			return false, 0
+		}
+		if zone != "" && cm.zone == zone {
+			adjacent := start == cm.start+cm.minutes || cm.start == start+minutes
+			if adjacent {
+				score = 2
+			} else if score == 0 {
+				score = 1
+			}
+		}
+	}
+	return true, score
+}
+
+// dayCombinations lists every way to pick k of days, keeping their order
+func dayCombinations(days []time.Weekday, k int) [][]time.Weekday {
+	var out [][]time.Weekday
+	var pick func(start int, chosen []time.Weekday)
+	pick = func(start int, chosen []time.Weekday) {
+		if len(chosen) == k {
+			out = append(out, append([]time.Weekday{}, chosen...))
+			return
+		}
+		for i := start; i < len(days); i++ {
+			pick(i+1, append(chosen, days[i]))
+		}
+	}
+	if k > 0 && k <= len(days) {
+		pick(0, nil)
+	}
+	return out
+}
+
+// suggestSchedule - Ranked, conflict-free day/time options for a student with
+// a teacher: within the teacher's availability, clear of their other visits
+// (with travel time between zones), preferring the student's days and slots
+// next to visits in the same zone.
+func suggestSchedule(c *gin.Context) {
+	teacherID := c.Query("teacher_id")
+	area, postcode := c.Query("area"), c.Query("postcode")
+	daysPerWeek, _ := strconv.Atoi(c.Query("days_per_week"))
+	minutes, _ := strconv.Atoi(c.Query("class_minutes"))
+	subId, _ := strconv.Atoi(c.Query("subscription_id"))
+
+	// An existing subscription fills in whatever wasn't given
+	if subId != 0 {
+		var subTeacher, subArea, subPostcode string
+		var subDays, subMinutes int
+		err := db.QueryRow(`
+			SELECT COALESCE(s.teacher_id, ''), COALESCE(s.area, ''), COALESCE(s.postcode, ''), COALESCE(s.days_per_week, 0),
+			       `+visitMinutesSQL+`
+			FROM mentor.subscriptions s WHERE s.id = $1 AND s.deleted_at IS NULL
+		`, subId).Scan(&subTeacher, &subArea, &subPostcode, &subDays, &subMinutes)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+			return
+		}
+		if teacherID == "" {
+			teacherID = subTeacher
+		}
+		if area == "" && postcode == "" {
+			area, postcode = subArea, subPostcode
+		}
+		if daysPerWeek == 0 {
+			daysPerWeek = subDays
+		}
+		if minutes == 0 {
+			minutes = subMinutes
+		}
+	}
+
+	if teacherID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "teacher_id or subscription_id is required"})
+		return
+	}
+	if daysPerWeek < 1 || daysPerWeek > 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "days_per_week must be between 1 and 7"})
+		return
+	}
+	if !validClassMinutes(minutes) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("class_minutes must be between %d and %d", minClassMinutes, maxClassMinutes)})
+		return
+	}
+	minutes = classMinutesOr(minutes)
+
+	preferred := map[time.Weekday]bool{}
+	for _, d := range strings.Split(c.Query("preferred_days"), ",") {
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
+		if !validScheduleDay(d) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid preferred day " + d + "; use Sat-Fri"})
+			return
+		}
+		for wd := range scheduledWeekdays([]string{d}) {
+			preferred[wd] = true
+		}
+	}
+	onlyPreferred := c.Query("only_preferred") == "true"
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if limit < 1 || limit > maxScheduleSuggested {
+		limit = 5
+	}
+
+	var availableDays []string
+	var availableFrom, availableTo string
+	err := db.QueryRow(`
+		SELECT COALESCE(available_days, '{}'), COALESCE(available_from, ''), COALESCE(available_to, '')
+		FROM mentor.teachers WHERE id = $1 AND COALESCE(active, 1) = 1
+	`, teacherID).Scan(pq.Array(&availableDays), &availableFrom, &availableTo)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Teacher not found"})
+		return
+	}
+
+	from, okFrom := clockMinutes(availableFrom)
+	to, okTo := clockMinutes(availableTo)
+	if !okFrom || !okTo || to <= from {
+		from, _ = clockMinutes(defaultAvailableFrom)
+		to, _ = clockMinutes(defaultAvailableTo)
+	}
+
+	// Teaching days in week order, limited to the teacher's available days
+	teacherDays := scheduledWeekdays(availableDays)
+	var days []time.Weekday
+	for _, wd := range workloadWeek {
+		if len(teacherDays) > 0 && !teacherDays[wd] {
+			continue
+		}
+		if onlyPreferred && len(preferred) > 0 && !preferred[wd] {
+			continue
+		}
+		days = append(days, wd)
+	}
+
+	commitments, err := teacherCommitments(teacherID, subId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	busy, busyErr := googleWeekBusy(teacherID)
+
+	zones := studentZones(area, postcode)
+	zone := ""
+	if len(zones) > 0 {
+		zone = zones[0]
+	}
+
+	type option struct {
+		days          []string
+		start         int
+		score         int
+		preferredDays int
+		sameZoneDays  int
+	}
+	var options []option
+	for start := from; start+minutes <= to; start += suggestStepMinutes {
+		// Days this time fits, with their travel score
+		fits := map[time.Weekday]int{}
+		var free []time.Weekday
+		for _, wd := range days {
+			if _, taken := busy.at(wd, start, minutes); taken {
+				continue
+			}
+			if ok, score := slotFit(commitments[wd], start, minutes, zone); ok {
+				fits[wd] = score
+				free = append(free, wd)
+			}
+		}
+
+		for _, combo := range dayCombinations(free, daysPerWeek) {
+			opt := option{start: start}
+			for _, wd := range combo {
+				opt.days = append(opt.days, wd.String()[:3])
+				opt.score += fits[wd]
+				if fits[wd] > 0 {
+					opt.sameZoneDays++
+				}
+				if preferred[wd] {
+					opt.preferredDays++
+					opt.score += 3
+				}
+			}
+			options = append(options, opt)
+		}
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		if options[i].score != options[j].score {
+			return options[i].score > options[j].score
+		}
+		return options[i].start < options[j].start
+	})
+	if len(options) > limit {
+		options = options[:limit]
+	}
+
+	suggestions := []gin.H{}
+	for _, opt := range options {
+		suggestions = append(suggestions, gin.H{
+			"schedule_days":    opt.days,
+			"end_time":         formatClockMinutes(opt.start + minutes),
+			"time":             formatClockMinutes(opt.start),
+			"score":            opt.score,
+			"preferred_days":   opt.preferredDays,
+			"same_zone_days":   opt.sameZoneDays,
+			"travel_efficient": opt.sameZoneDays > 0,
+		})
+	}
+
+	response := gin.H{
+		"success":        true,
+		"teacher_id":     teacherID,
+		"days_per_week":  daysPerWeek,
+		"class_minutes":  minutes,
+		"available_from": formatClockMinutes(from),
+		"available_to":   formatClockMinutes(to),
+		"suggestions":    suggestions,
+	}
+	if subId != 0 {
+		response["subscription_id"] = subId
+	}
+	if busy != nil {
+		response["calendar_checked"] = true
+	}
+	if busyErr != nil {
+		response["calendar_error"] = busyErr.Error()
+	}
+	if zone != "" && !teacherCoversLocation(teacherID, area, postcode) {
+		response["zone_warning"] = "Teacher " + teacherID + " does not cover this area"
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// acceptScheduleSuggestion - Fill in a subscription's schedule_days and time
+// from a suggestion, after checking it still fits the teacher's timetable
+func acceptScheduleSuggestion(c *gin.Context) {
+	var input struct {
+		SubscriptionID int        `json:"subscription_id"`
+		ScheduleDays   stringList `json:"schedule_days"`
+		Time           string     `json:"time"`
+		AcceptedBy     string     `json:"accepted_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	fields := map[string]string{}
+	if input.SubscriptionID == 0 {
+		fields["subscription_id"] = "is required"
+	}
+	if len(input.ScheduleDays) == 0 {
+		fields["schedule_days"] = "is required"
+	}
+	for _, d := range input.ScheduleDays {
+		if !validScheduleDay(d) {
+			fields["schedule_days"] = "invalid day " + d + "; use Sat-Fri"
+		}
+	}
+	classTime, ok := parseClassTime(input.Time)
+	if !ok {
+		fields["time"] = `must be a time like "4:00 PM"`
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(fields))
+		return
+	}
+	input.ScheduleDays, _ = canonicalScheduleDays(input.ScheduleDays)
+
+	var teacherID, area, postcode string
+	var minutes int
+	err := db.QueryRow(`
+		SELECT COALESCE(s.teacher_id, ''), COALESCE(s.area, ''), COALESCE(s.postcode, ''), `+visitMinutesSQL+`
+		FROM mentor.subscriptions s WHERE s.id = $1 AND s.deleted_at IS NULL
+	`, input.SubscriptionID).Scan(&teacherID, &area, &postcode, &minutes)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+
+	// The timetable may have changed since the suggestion was made
+	if teacherID != "" {
+		commitments, err := teacherCommitments(teacherID, input.SubscriptionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		zone := ""
+		if zones := studentZones(area, postcode); len(zones) > 0 {
+			zone = zones[0]
+		}
+		start, _ := clockMinutes(classTime)
+		busy, _ := googleWeekBusy(teacherID)
+		for wd := range scheduledWeekdays(input.ScheduleDays) {
+			if ok, _ := slotFit(commitments[wd], start, minutes, zone); !ok {
+				c.JSON(http.StatusConflict, gin.H{
+					"success": false,
+					"error":   "Teacher " + teacherID + " is no longer free at " + classTime + " on " + wd.String()[:3],
+				})
+				return
+			}
+			if p, taken := busy.at(wd, start, minutes); taken {
+				c.JSON(http.StatusConflict, gin.H{
+					"success": false,
+					"error":   "Teacher " + teacherID + "'s calendar is busy at " + classTime + " on " + wd.String()[:3] + " (" + p.Summary + ")",
+				})
+				return
+			}
+		}
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.subscriptions
+		SET schedule_days = $1, schedule_day_list = $2, days_per_week = $3, time = $4, updated_at = NOW()
+		WHERE id = $5 AND deleted_at IS NULL
+	`, strings.Join(input.ScheduleDays, ","), pq.Array([]string(input.ScheduleDays)), len(input.ScheduleDays),
+		classTime, input.SubscriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if teacherID != "" {
+		today := localToday()
+		syncClassSessions(today, today.AddDate(0, 0, classSessionHorizonDays), teacherID)
+	}
+
+	logAudit("subscription", strconv.Itoa(input.SubscriptionID), "schedule_accepted", input.AcceptedBy, gin.H{
+		"schedule_days": input.ScheduleDays,
+		"time":          classTime,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"schedule_days": input.ScheduleDays,
+		"time":          classTime,
+		"message":       "Schedule updated",
+	})
+}
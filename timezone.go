@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // the alpine image has no zoneinfo
+)
+
+// ============================================
+// TIME ZONES
+// ============================================
+
+// setupTimezone makes APP_TIMEZONE (e.g. "Asia/Dhaka") the zone for "today",
+// class times and stored timestamps, instead of whatever the host runs in.
+// Every time.Local in the app follows it.
+func setupTimezone() {
+	name := os.Getenv("APP_TIMEZONE")
+	if name == "" {
+		return
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Println("Warning: invalid APP_TIMEZONE, using server time:", err)
+		return
+	}
+	time.Local = loc
+	log.Println("Time zone:", loc)
+}
+
+// databaseURLWithTimezone makes the database session use the app zone too, so
+// NOW() and CURRENT_DATE agree with Go's "today"
+func databaseURLWithTimezone(dsn string) string {
+	if os.Getenv("APP_TIMEZONE") == "" || time.Local.String() == "Local" {
+		return dsn
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		if q.Get("timezone") == "" {
+			q.Set("timezone", time.Local.String())
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	if strings.Contains(dsn, "timezone=") {
+		return dsn
+	}
+	return dsn + " timezone=" + time.Local.String()
+}
+
+// isoTimestamp formats a stored timestamp as ISO-8601 with the app zone's
+// offset. TIMESTAMP columns hold app-zone wall clock time, so the wall clock
+// is read as-is rather than converted.
+func isoTimestamp(t time.Time) string {
+	return storedTime(t).Format(time.RFC3339)
+}
+
+// storedTime reads a TIMESTAMP column's wall clock as an app-zone instant
+func storedTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+}
+
+// parseTimestamp accepts ISO-8601 or the older "2006-01-02 15:04:05" form
+func parseTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(time.Local), nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+}
+
+// teacherLocation is the teacher's own zone, or the app zone
+func teacherLocation(teacherID string) *time.Location {
+	var name string
+	db.QueryRow("SELECT COALESCE(timezone, '') FROM mentor.teachers WHERE id = $1", teacherID).Scan(&name)
+	if name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// teacherToday is the teacher's current calendar date, as midnight in time.Local
+// so it works with the date helpers
+func teacherToday(teacherID string) time.Time {
+	now := time.Now().In(teacherLocation(teacherID))
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// sessionStart combines a session date and "4:00 PM" class time in loc; ok is
+// false when the time can't be read
+func sessionStart(date time.Time, classTime string, loc *time.Location) (time.Time, bool) {
+	t, ok := parseClassTime(classTime)
+	if !ok {
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc), false
+	}
+	clock, _ := time.Parse("3:04 PM", t)
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), true
+}
+
+// validTimezone reports whether name is an IANA zone
+func validTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil && name != "" && name != "Local"
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// DUPLICATE TRANSACTION DETECTION
+// ============================================
+
+// duplicateWindowMinutes is how close two entries' created_at must be to be
+// considered the same payment entered twice
+func duplicateWindowMinutes() int {
+	minutes, err := strconv.Atoi(os.Getenv("DUPLICATE_TRANSACTION_WINDOW_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return 10
+	}
+	return minutes
+}
+
+// detectDuplicateTransactions flags pairs with the same type, amount, date,
+// category and subscription entered within the window. Already-flagged pairs
+// (including dismissed ones) are left alone.
+func detectDuplicateTransactions() (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO mentor.transaction_duplicates (transaction_id, duplicate_of)
+		SELECT b.id, a.id
+		FROM mentor.transactions a
+		JOIN mentor.transactions b
+		  ON b.id > a.id
+		 AND b.type = a.type AND b.amount = a.amount AND b.date = a.date
+		 AND COALESCE(b.category, '') = COALESCE(a.category, '')
+		 AND b.subscription_id IS NOT DISTINCT FROM a.subscription_id
+		 AND b.created_at BETWEEN a.created_at AND a.created_at + make_interval(mins => $1)
+		WHERE a.voided_at IS NULL AND b.voided_at IS NULL
+		ON CONFLICT (transaction_id, duplicate_of) DO NOTHING
+	`, duplicateWindowMinutes())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// getDuplicateTransactions - Admin: probable duplicates awaiting review
+func getDuplicateTransactions(c *gin.Context) {
+	status := c.DefaultQuery("status", "pending")
+
+	rows, err := db.Query(`
+		SELECT d.id, d.status, d.detected_at,
+		       a.id, a.date, a.type, a.amount, COALESCE(a.description, ''), COALESCE(a.category, ''), a.subscription_id, a.created_at,
+		       b.id, COALESCE(b.description, ''), b.created_at
+		FROM mentor.transaction_duplicates d
+		JOIN mentor.transactions a ON a.id = d.duplicate_of
+		JOIN mentor.transactions b ON b.id = d.transaction_id
+		WHERE d.status = $1
+		ORDER BY d.detected_at DESC
+	`, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var duplicates []gin.H
+	for rows.Next() {
+		var id, originalID, duplicateID int
+		var dupStatus, date, txType, description, category, duplicateDescription string
+		var amount float64
+		var subscriptionID sql.NullInt64
+		var detectedAt, originalCreated, duplicateCreated time.Time
+
+		if err := rows.Scan(&id, &dupStatus, &detectedAt,
+			&originalID, &date, &txType, &amount, &description, &category, &subscriptionID, &originalCreated,
+			&duplicateID, &duplicateDescription, &duplicateCreated); err != nil {
+			continue
+		}
+
+		entry := gin.H{
+			"id":          id,
+			"status":      dupStatus,
+			"detected_at": isoTimestamp(detectedAt),
+			"date":        date,
+			"type":        txType,
+			"amount":      amount,
+			"category":    category,
+			"original": gin.H{
+				"id":          originalID,
+				"description": description,
+				"created_at":  isoTimestamp(originalCreated),
+			},
+			"duplicate": gin.H{
+				"id":          duplicateID,
+				"description": duplicateDescription,
+				"created_at":  isoTimestamp(duplicateCreated),
+			},
+		}
+		if subscriptionID.Valid {
+			entry["subscription_id"] = subscriptionID.Int64
+		}
+		duplicates = append(duplicates, entry)
+	}
+
+	if duplicates == nil {
+		duplicates = []gin.H{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "duplicates": duplicates})
+}
+
+// scanDuplicateTransactions - Admin: run detection now
+func scanDuplicateTransactions(c *gin.Context) {
+	flagged, err := detectDuplicateTransactions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "flagged": flagged})
+}
+
+// resolveDuplicateTransaction - Admin: "merge" keeps the original (adding the
+// duplicate's note to it) and voids the duplicate, "void" just voids the
+// duplicate, "dismiss" marks the pair as two real payments
+func resolveDuplicateTransaction(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Action     string `json:"action"` // "merge", "void" or "dismiss"
+		ResolvedBy string `json:"resolved_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	statuses := map[string]string{"merge": "merged", "void": "voided", "dismiss": "dismissed"}
+	newStatus, ok := statuses[input.Action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "action must be merge, void or dismiss"})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var transactionID, duplicateOf int
+	err = tx.QueryRow(`
+		SELECT transaction_id, duplicate_of FROM mentor.transaction_duplicates
+		WHERE id = $1 AND status = 'pending' FOR UPDATE
+	`, id).Scan(&transactionID, &duplicateOf)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Pending duplicate not found"})
+		return
+	}
+
+	if input.Action == "merge" {
+		_, err = tx.Exec(`
+			UPDATE mentor.transactions a
+			SET description = CONCAT_WS(' / ', NULLIF(a.description, ''), NULLIF(b.description, ''))
+			FROM mentor.transactions b
+			WHERE a.id = $1 AND b.id = $2 AND COALESCE(b.description, '') <> COALESCE(a.description, '')
+		`, duplicateOf, transactionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	if input.Action != "dismiss" {
+		_, err = tx.Exec(`
+			UPDATE mentor.transactions SET voided_at = NOW(), void_reason = $1
+			WHERE id = $2 AND voided_at IS NULL
+		`, "duplicate of #"+strconv.Itoa(duplicateOf), transactionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE mentor.transaction_duplicates SET status = $1, resolved_by = $2, resolved_at = NOW()
+		WHERE id = $3
+	`, newStatus, input.ResolvedBy, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("transaction", transactionID, "duplicate_"+newStatus, input.ResolvedBy, gin.H{"duplicate_of": duplicateOf})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": newStatus})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TRANSACTIONS EXPORT (CSV / Excel)
+// ============================================
+
+// transactionExportColumns is the header row of the export
+var transactionExportColumns = []string{
+	"date", "transaction_id", "type", "category", "description",
+	"subscription_id", "student_name", "billing_group", "invoice_number", "payment_method",
+	"currency", "income", "expense", "running_balance",
+}
+
+// getTransactionsExport - A month's books (?year=&month=, default this month)
+// as a CSV or Excel file for the accountant. The first row carries the
+// opening balance of everything recorded before the month, and each
+// transaction after it updates running_balance. Income and expense are in the
+// transaction's currency; balances are in the default currency.
+func getTransactionsExport(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	now := localToday()
+	year, yearErr := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(now.Year())))
+	month, monthErr := strconv.Atoi(c.DefaultQuery("month", strconv.Itoa(int(now.Month()))))
+	if yearErr != nil || monthErr != nil || month < 1 || month > 12 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "year and month must be numbers, month 1-12"})
+		return
+	}
+	monthStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	if rejectMissingExchangeRates(c, "t.date < $1", monthEnd) {
+		return
+	}
+
+	var opening float64
+	if err := db.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN t.type = 'income' THEN 1 ELSE -1 END * `+inDefaultCurrencySQL("t")+`), 0)
+		FROM mentor.transactions t
+		WHERE t.voided_at IS NULL AND t.date < $1
+	`, monthStart).Scan(&opening); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT t.id, t.date, t.type, t.amount, COALESCE(t.category, ''), COALESCE(t.description, ''),
+		       t.subscription_id, COALESCE(s.student_name, ''), COALESCE(g.name, ''),
+		       COALESCE(i.invoice_number, ''), COALESCE(t.payment_method, ''), t.currency, `+inDefaultCurrencySQL("t")+`
+		FROM mentor.transactions t
+		LEFT JOIN mentor.subscriptions s ON s.id = t.subscription_id
+		LEFT JOIN mentor.billing_groups g ON g.id = t.billing_group_id
+		LEFT JOIN mentor.invoices i ON i.id = t.invoice_id
+		WHERE t.voided_at IS NULL AND t.date >= $1 AND t.date < $2
+		ORDER BY t.date, t.created_at, t.id
+	`, monthStart, monthEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("transactions_%04d_%02d.%s", year, month, format)
+	writeRow, finish, ok := startExport(c, format, filename, "Transactions", transactionExportColumns)
+	if !ok {
+		return
+	}
+
+	balance := roundMoney(opening)
+	writeRow([]interface{}{
+		monthStart.Format("2006-01-02"), nil, "opening_balance", nil, "Balance brought forward",
+		nil, nil, nil, nil, nil, currencyCode(), nil, nil, balance,
+	})
+
+	for rows.Next() {
+		var id int
+		var date time.Time
+		var txType, category, description, studentName, groupName, invoiceNumber, paymentMethod string
+		var amount, converted float64
+		var subId sql.NullInt64
+		var currency sql.NullString
+		if err := rows.Scan(&id, &date, &txType, &amount, &category, &description,
+			&subId, &studentName, &groupName, &invoiceNumber, &paymentMethod, &currency, &converted); err != nil {
+			continue
+		}
+
+		var income, expense, subscription interface{}
+		if txType == "income" {
+			income = amount
+			balance = roundMoney(balance + converted)
+		} else {
+			expense = amount
+			balance = roundMoney(balance - converted)
+		}
+		if subId.Valid {
+			subscription = subId.Int64
+		}
+
+		writeRow([]interface{}{
+			date.Format("2006-01-02"), id, txType, category, description,
+			subscription, studentName, groupName, invoiceNumber, paymentMethod,
+			orDefaultCurrency(currency), income, expense, balance,
+		})
+	}
+
+	if err := finish(); err != nil {
+		log.Println("Warning: transactions export:", err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// TRIAL SUBSCRIPTIONS
+// ============================================
+
+// trialDefaults returns the class limit and window (days) for new trials,
+// configurable via TRIAL_CLASS_LIMIT and TRIAL_DAYS
+func trialDefaults() (int, int) {
+	classLimit, err := strconv.Atoi(os.Getenv("TRIAL_CLASS_LIMIT"))
+	if err != nil || classLimit <= 0 {
+		classLimit = 3
+	}
+	days, err := strconv.Atoi(os.Getenv("TRIAL_DAYS"))
+	if err != nil || days <= 0 {
+		days = 14
+	}
+	return classLimit, days
+}
+
+// expireTrials moves active trials past their window to status 'expired'
+func expireTrials() (int64, error) {
+	rows, err := db.Query(`
+		UPDATE mentor.subscriptions SET status = 'expired', updated_at = NOW()
+		WHERE subscription_type = 'trial' AND status = 'active'
+		  AND deleted_at IS NULL AND trial_ends_at < CURRENT_DATE
+		RETURNING id
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		logAudit("subscription", id, "status_changed", "system", gin.H{"from": "active", "to": "expired", "reason": "trial ended"})
+	}
+	if len(ids) > 0 {
+		requestClassSessionSync()
+	}
+	return int64(len(ids)), rows.Err()
+}
+
+// convertTrial - Convert a trial (active or expired) to a paid subscription.
+// Progress is kept as-is and the first fee is recorded as an income transaction.
+func convertTrial(c *gin.Context) {
+	id := c.Param("id")
+
+	var input struct {
+		Amount      float64 `json:"amount"`       // Monthly fee going forward
+		BillingDate int     `json:"billing_date"` // Day of month
+		PaidAmount  float64 `json:"paid_amount"`  // First payment, defaults to amount
+		PaidDate    string  `json:"paid_date"`    // YYYY-MM-DD, defaults to today
+		ConvertedBy string  `json:"converted_by"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "amount is required"})
+		return
+	}
+	if input.PaidAmount == 0 {
+		input.PaidAmount = input.Amount
+	}
+	if input.PaidDate == "" {
+		input.PaidDate = time.Now().Format("2006-01-02")
+	}
+	if input.BillingDate == 0 {
+		input.BillingDate = time.Now().Day()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var subType, studentName string
+	err = tx.QueryRow(`
+		SELECT subscription_type, student_name FROM mentor.subscriptions
+		WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+	`, id).Scan(&subType, &studentName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Subscription not found"})
+		return
+	}
+	if subType != "trial" {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Subscription is not a trial"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE mentor.subscriptions
+		SET subscription_type = 'paid', status = 'active', amount = $1, billing_date = $2,
+		    trial_class_limit = NULL, trial_ends_at = NULL, converted_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`, input.Amount, input.BillingDate, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	var transactionID int
+	err = tx.QueryRow(`
+		INSERT INTO mentor.transactions (date, type, amount, description, category, subscription_id)
+		VALUES ($1, 'income', $2, $3, 'student_fee', $4)
+		RETURNING id
+	`, input.PaidDate, input.PaidAmount, "First fee - "+studentName, id).Scan(&transactionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	logAudit("subscription", id, "trial_converted", input.ConvertedBy, gin.H{
+		"amount":         input.Amount,
+		"transaction_id": transactionID,
+	})
+	requestClassSessionSync()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"transaction_id": transactionID,
+		"message":        "Trial converted to paid subscription",
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// UPCOMING CLASSES (Home screen and reminders)
+// ============================================
+
+const (
+	defaultUpcomingDays = 7
+	maxUpcomingDays     = 31
+)
+
+// getTeacherUpcoming - The teacher's next scheduled sessions over the coming
+// days as one list in start order, each with the student and the chapter
+// they're on. Classes that have already started today are left out.
+func getTeacherUpcoming(c *gin.Context) {
+	teacherID := c.Param("teacherId")
+
+	days := defaultUpcomingDays
+	if d := c.Query("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > maxUpcomingDays {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "days must be between 1 and " + strconv.Itoa(maxUpcomingDays)})
+			return
+		}
+		days = parsed
+	}
+
+	from := teacherToday(teacherID)
+	to := from.AddDate(0, 0, days-1)
+
+	rows, err := db.Query(`
+		SELECT cs.id, cs.subscription_id, cs.session_date, cs.subject, COALESCE(cs.session_time, ''),
+		       COALESCE(cs.duration_minutes, 0), cs.rescheduled_from IS NOT NULL,
+		       s.student_name, s.class, COALESCE(s.area, ''),
+		       COALESCE(sc.current_chapter, 1), COALESCE(sc.current_part, 1), COALESCE(ch.total_chapters, 0)
+		FROM mentor.class_sessions cs
+		JOIN mentor.subscriptions s ON s.id = cs.subscription_id
+		LEFT JOIN mentor.schedule sc ON sc.subscription_id = cs.subscription_id AND sc.subject = cs.subject
+		LEFT JOIN mentor.chapters ch ON ch.class = s.class AND ch.subject = cs.subject
+		WHERE cs.teacher_id = $1 AND cs.session_date BETWEEN $2 AND $3 AND cs.status = 'scheduled'
+		  AND s.deleted_at IS NULL
+	`, teacherID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type upcoming struct {
+		start time.Time
+		item  gin.H
+	}
+	loc := teacherLocation(teacherID)
+	now := time.Now()
+	var list []upcoming
+	for rows.Next() {
+		var id, subId, minutes, class, chapter, part, totalChapters int
+		var sessionDate time.Time
+		var subject, sessionTime, studentName, area string
+		var moved bool
+		if err := rows.Scan(&id, &subId, &sessionDate, &subject, &sessionTime, &minutes, &moved,
+			&studentName, &class, &area, &chapter, &part, &totalChapters); err != nil {
+			continue
+		}
+
+		start, timed := sessionStart(sessionDate, sessionTime, loc)
+		if timed && start.Before(now) {
+			continue
+		}
+
+		item := gin.H{
+			"session_id":      id,
+			"subscription_id": subId,
+			"student_name":    studentName,
+			"class":           class,
+			"area":            area,
+			"subject":         subject,
+			"date":            sessionDate.Format("2006-01-02"),
+			"time":            sessionTime,
+			"current_chapter": chapter,
+			"current_part":    part,
+			"content_url":     contentPartURL(c, class, subject, chapter, part),
+			"total_chapters":  totalChapters,
+			"rescheduled":     moved,
+		}
+		addSessionTimes(item, sessionDate, sessionTime, minutes, loc)
+		if !timed {
+			// Untimed classes sort to the end of their day
+			start = start.AddDate(0, 0, 1).Add(-time.Minute)
+		}
+		list = append(list, upcoming{start, item})
+	}
+
+	// Days past the sync job's horizon have nothing stored yet; forecast them
+	// (no session_id)
+	forecast, err := forecastSessionsBetween(from, to, teacherID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	for _, s := range forecast {
+		if s["status"] != "scheduled" {
+			continue
+		}
+		subId, class, subject := s["subscription_id"].(int), s["class"].(int), s["subject"].(string)
+		sessionDate, _ := time.ParseInLocation("2006-01-02", s["date"].(string), time.Local)
+		start, timed := sessionStart(sessionDate, s["time"].(string), loc)
+
+		var area string
+		chapter, part, totalChapters := 1, 1, 0
+		db.QueryRow(`
+			SELECT COALESCE(s.area, ''), COALESCE(sc.current_chapter, 1), COALESCE(sc.current_part, 1),
+			       COALESCE(ch.total_chapters, 0)
+			FROM mentor.subscriptions s
+			LEFT JOIN mentor.schedule sc ON sc.subscription_id = s.id AND sc.subject = $2
+			LEFT JOIN mentor.chapters ch ON ch.class = s.class AND ch.subject = $2
+			WHERE s.id = $1
+		`, subId, subject).Scan(&area, &chapter, &part, &totalChapters)
+
+		item := gin.H{
+			"subscription_id": subId,
+			"student_name":    s["student_name"],
+			"class":           class,
+			"area":            area,
+			"subject":         subject,
+			"date":            s["date"],
+			"time":            s["time"],
+			"current_chapter": chapter,
+			"current_part":    part,
+			"content_url":     contentPartURL(c, class, subject, chapter, part),
+			"total_chapters":  totalChapters,
+			"rescheduled":     false,
+			"forecast":        true,
+		}
+		addSessionTimes(item, sessionDate, s["time"].(string), s["duration_minutes"].(int), loc)
+		if !timed {
+			start = start.AddDate(0, 0, 1).Add(-time.Minute)
+		}
+		list = append(list, upcoming{start, item})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool { return list[i].start.Before(list[j].start) })
+
+	sessions := []gin.H{}
+	for _, u := range list {
+		sessions = append(sessions, u.item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"sessions": shapeItems(c, sessions),
+	})
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =====================================================
+// VIDEO PROVIDER (Jitsi / 100ms room provisioning)
+// =====================================================
+
+// videoProvider creates meeting rooms, issues join tokens, and parses
+// end-of-meeting webhooks for one video service.
+type videoProvider interface {
+	Name() string
+	CreateRoom(name string) (roomID, meetingLink string, err error)
+	JoinToken(roomID, userID, displayName, role string) (token, joinURL string, err error)
+	ParseMeetingEnded(body []byte) (*meetingEndedEvent, error)
+}
+
+// meetingEndedEvent is the provider-independent shape of an end-of-meeting webhook
+type meetingEndedEvent struct {
+	RoomID    string
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// getVideoProvider returns the provider selected by VIDEO_PROVIDER, or nil if none is configured
+func getVideoProvider() videoProvider {
+	switch os.Getenv("VIDEO_PROVIDER") {
+	case "jitsi":
+		domain := os.Getenv("JITSI_DOMAIN")
+		if domain == "" {
+			domain = "meet.jit.si"
+		}
+		return &jitsiProvider{
+			domain:    domain,
+			appID:     os.Getenv("JITSI_APP_ID"),
+			appSecret: os.Getenv("JITSI_APP_SECRET"),
+		}
+	case "100ms":
+		return &hmsProvider{
+			accessKey:  os.Getenv("HMS_ACCESS_KEY"),
+			secret:     os.Getenv("HMS_SECRET"),
+			templateID: os.Getenv("HMS_TEMPLATE_ID"),
+		}
+	}
+	return nil
+}
+
+// signJWT builds an HS256 JSON Web Token for the given claims
+func signJWT(claims map[string]interface{}, secret string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyJWT checks an HS256 token's signature and decodes its payload into
+// claims; callers check expiry and type themselves
+func verifyJWT(token, secret string, claims interface{}) bool {
+	parts := strings.Split(token, ".")
+	if secret == "" || len(parts) != 3 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(payload, claims) == nil
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ---------- Jitsi ----------
+
+type jitsiProvider struct {
+	domain    string
+	appID     string
+	appSecret string // Optional: enables JWT auth on self-hosted Jitsi
+}
+
+func (p *jitsiProvider) Name() string { return "jitsi" }
+
+// Jitsi rooms are created on first join, so provisioning only picks a unique name
+func (p *jitsiProvider) CreateRoom(name string) (string, string, error) {
+	roomID := "mentor-" + name + "-" + randomID()
+	return roomID, fmt.Sprintf("https://%s/%s", p.domain, roomID), nil
+}
+
+func (p *jitsiProvider) JoinToken(roomID, userID, displayName, role string) (string, string, error) {
+	joinURL := fmt.Sprintf("https://%s/%s", p.domain, roomID)
+	if p.appSecret == "" {
+		return "", joinURL, nil
+	}
+
+	now := time.Now()
+	token := signJWT(map[string]interface{}{
+		"aud":  p.appID,
+		"iss":  p.appID,
+		"sub":  p.domain,
+		"room": roomID,
+		"iat":  now.Unix(),
+		"exp":  now.Add(4 * time.Hour).Unix(),
+		"context": map[string]interface{}{
+			"user": map[string]interface{}{
+				"id":        userID,
+				"name":      displayName,
+				"moderator": role == "teacher",
+			},
+		},
+	}, p.appSecret)
+
+	return token, joinURL + "?jwt=" + token, nil
+}
+
+// Expects a Jitsi event-sync style payload: {"event_name": "muc-room-destroyed", "room_name": ..., "created_at": ..., "destroyed_at": ...}
+func (p *jitsiProvider) ParseMeetingEnded(body []byte) (*meetingEndedEvent, error) {
+	var payload struct {
+		EventName   string `json:"event_name"`
+		RoomName    string `json:"room_name"`
+		CreatedAt   int64  `json:"created_at"`
+		DestroyedAt int64  `json:"destroyed_at"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.EventName != "muc-room-destroyed" {
+		return nil, nil
+	}
+
+	return &meetingEndedEvent{
+		RoomID:    payload.RoomName,
+		StartedAt: time.Unix(payload.CreatedAt, 0),
+		EndedAt:   time.Unix(payload.DestroyedAt, 0),
+	}, nil
+}
+
+// ---------- 100ms ----------
+
+type hmsProvider struct {
+	accessKey  string
+	secret     string
+	templateID string
+}
+
+func (p *hmsProvider) Name() string { return "100ms" }
+
+func (p *hmsProvider) token(claims map[string]interface{}) string {
+	now := time.Now()
+	claims["access_key"] = p.accessKey
+	claims["version"] = 2
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(24 * time.Hour).Unix()
+	claims["jti"] = randomID()
+	return signJWT(claims, p.secret)
+}
+
+func (p *hmsProvider) CreateRoom(name string) (string, string, error) {
+	if p.accessKey == "" || p.secret == "" {
+		return "", "", fmt.Errorf("HMS_ACCESS_KEY/HMS_SECRET not configured")
+	}
+
+	reqBody, _ := json.Marshal(map[string]string{
+		"name":        "mentor-" + name + "-" + randomID(),
+		"template_id": p.templateID,
+	})
+	req, _ := http.NewRequest("POST", "https://api.100ms.live/v2/rooms", bytes.NewReader(reqBody))
+	req.Header.Set("Authorization", "Bearer "+p.token(map[string]interface{}{"type": "management"}))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("100ms error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var room struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(body, &room)
+
+	// 100ms rooms are joined through the SDK with a token, not a public link
+	return room.ID, "", nil
+}
+
+func (p *hmsProvider) JoinToken(roomID, userID, displayName, role string) (string, string, error) {
+	hmsRole := "guest"
+	if role == "teacher" {
+		hmsRole = "host"
+	}
+	token := p.token(map[string]interface{}{
+		"type":    "app",
+		"room_id": roomID,
+		"user_id": userID,
+		"role":    hmsRole,
+	})
+	return token, "", nil
+}
+
+// Expects the 100ms "session.close.success" webhook
+func (p *hmsProvider) ParseMeetingEnded(body []byte) (*meetingEndedEvent, error) {
+	var payload struct {
+		Type string `json:"type"`
+		Data struct {
+			RoomID           string    `json:"room_id"`
+			SessionStartedAt time.Time `json:"session_started_at"`
+			SessionStoppedAt time.Time `json:"session_stopped_at"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Type != "session.close.success" {
+		return nil, nil
+	}
+
+	return &meetingEndedEvent{
+		RoomID:    payload.Data.RoomID,
+		StartedAt: payload.Data.SessionStartedAt,
+		EndedAt:   payload.Data.SessionStoppedAt,
+	}, nil
+}
+
+// ---------- Handlers ----------
+
+// provisionSessionRoom creates a provider room for an online session and stores it
+func provisionSessionRoom(sessionID int) (string, error) {
+	provider := getVideoProvider()
+	if provider == nil {
+		return "", fmt.Errorf("VIDEO_PROVIDER not configured")
+	}
+
+	roomID, meetingLink, err := provider.CreateRoom(fmt.Sprint(sessionID))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(`
+		UPDATE mentor.online_sessions
+		SET provider = $1, room_id = $2, meeting_link = COALESCE(NULLIF($3, ''), meeting_link)
+		WHERE id = $4
+	`, provider.Name(), roomID, meetingLink, sessionID)
+
+	return meetingLink, err
+}
+
+// createSessionRoom - Provision (or re-provision) a video room for a session
+func createSessionRoom(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid session id"})
+		return
+	}
+
+	meetingLink, err := provisionSessionRoom(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "Failed to create room: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "meeting_link": meetingLink, "message": "Room created"})
+}
+
+// getSessionJoinToken - Join token for the teacher or guardian app
+func getSessionJoinToken(c *gin.Context) {
+	id := c.Param("id")
+	role := c.DefaultQuery("role", "guardian") // teacher or guardian
+
+	if role != "teacher" && role != "guardian" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "role must be 'teacher' or 'guardian'"})
+		return
+	}
+
+	var providerName, roomID, teacherID, studentName sql.NullString
+	var subscriptionID int
+	err := db.QueryRow(`
+		SELECT o.provider, o.room_id, o.teacher_id, o.subscription_id, s.student_name
+		FROM mentor.online_sessions o
+		LEFT JOIN mentor.subscriptions s ON o.subscription_id = s.id
+		WHERE o.id = $1
+	`, id).Scan(&providerName, &roomID, &teacherID, &subscriptionID, &studentName)
+
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Session not found"})
+		return
+	}
+
+	provider := getVideoProvider()
+	if provider == nil || !roomID.Valid || provider.Name() != providerName.String {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "No provider room for this session"})
+		return
+	}
+
+	userID := "teacher-" + teacherID.String
+	displayName := c.DefaultQuery("name", "Teacher")
+	if role == "guardian" {
+		userID = fmt.Sprintf("guardian-%d", subscriptionID)
+		displayName = c.DefaultQuery("name", studentName.String)
+	}
+
+	token, joinURL, err := provider.JoinToken(roomID.String, userID, displayName, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"provider": provider.Name(),
+		"room_id":  roomID.String,
+		"token":    token,
+		"join_url": joinURL,
+		"role":     role,
+	})
+}
+
+// videoWebhook - End-of-meeting webhook: records the real duration as online attendance
+func videoWebhook(c *gin.Context) {
+	secret := os.Getenv("VIDEO_WEBHOOK_SECRET")
+	if secret == "" || !hmac.Equal([]byte(c.GetHeader("X-Webhook-Secret")), []byte(secret)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid webhook secret"})
+		return
+	}
+
+	provider := getVideoProvider()
+	if provider == nil || provider.Name() != c.Param("provider") {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Unknown provider"})
+		return
+	}
+
+	body, _ := io.ReadAll(c.Request.Body)
+	event, err := provider.ParseMeetingEnded(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if event == nil {
+		// Not an end-of-meeting event; acknowledge so the provider doesn't retry
+		c.JSON(http.StatusOK, gin.H{"success": true, "ignored": true})
+		return
+	}
+
+	var sessionID, subscriptionID int
+	var teacherID string
+	var endedAt sql.NullTime
+	err = db.QueryRow(`
+		SELECT id, subscription_id, teacher_id, ended_at FROM mentor.online_sessions
+		WHERE provider = $1 AND room_id = $2
+	`, provider.Name(), event.RoomID).Scan(&sessionID, &subscriptionID, &teacherID, &endedAt)
+
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "ignored": true, "reason": "unknown room"})
+		return
+	}
+	if endedAt.Valid {
+		c.JSON(http.StatusOK, gin.H{"success": true, "ignored": true, "reason": "already recorded"})
+		return
+	}
+
+	duration := int(event.EndedAt.Sub(event.StartedAt).Minutes())
+
+	db.Exec(`
+		UPDATE mentor.online_sessions
+		SET duration_minutes = $1, ended_at = $2, teacher_joined_at = COALESCE(teacher_joined_at, $3)
+		WHERE id = $4
+	`, duration, event.EndedAt, event.StartedAt, sessionID)
+
+	// Record the meeting window as start/end attendance
+	for _, ev := range []struct {
+		action string
+		at     time.Time
+	}{{"start", event.StartedAt}, {"end", event.EndedAt}} {
+		_, err := db.Exec(`
+			INSERT INTO mentor.attendance (teacher_id, subscription_id, latitude, longitude, action, notes, mode, online_session_id, recorded_at)
+			VALUES ($1, $2, 0, 0, $3, $4, 'online', $5, $6)
+		`, teacherID, subscriptionID, ev.action, "Recorded from "+provider.Name()+" webhook", sessionID, ev.at)
+		if err != nil {
+			log.Println("Warning: could not record online attendance:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "session_id": sessionID, "duration_minutes": duration})
+}
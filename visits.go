@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================
+// VISITS (Paired check-in / check-out)
+// ============================================
+
+// visitStatus names a visit's state from the view's flags
+func visitStatus(missingOut, missingIn, ended bool) string {
+	switch {
+	case missingOut:
+		return "missing_check_out"
+	case missingIn:
+		return "missing_check_in"
+	case !ended:
+		return "in_progress"
+	}
+	return "completed"
+}
+
+// getTeacherVisits - The teacher's visits over a date range (default the last
+// 7 days) with duration, missing check-ins/outs and per-day totals
+func getTeacherVisits(c *gin.Context) {
+	teacherID := c.Param("teacherId")
+
+	from, to, ok := parsePastDateRange(c, 7)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "from/to must be YYYY-MM-DD, in order, at most 62 days apart"})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT v.visit_date, v.subscription_id, COALESCE(s.student_name, ''), v.class_session_id, v.mode,
+		       v.start_attendance_id, v.end_attendance_id, v.started_at, v.ended_at,
+		       v.duration_minutes, v.missing_check_out, v.missing_check_in
+		FROM mentor.visits v
+		LEFT JOIN mentor.subscriptions s ON s.id = v.subscription_id
+		WHERE v.teacher_id = $1 AND v.visit_date BETWEEN $2 AND $3
+		ORDER BY COALESCE(v.started_at, v.ended_at)
+	`, teacherID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	visits := []gin.H{}
+	days := []gin.H{}
+	byDate := map[string]gin.H{}
+	totalMinutes, missingCheckOuts := 0, 0
+	for rows.Next() {
+		var date time.Time
+		var subId, sessionID, startID, endID, minutes sql.NullInt64
+		var studentName, mode string
+		var startedAt, endedAt sql.NullTime
+		var missingOut, missingIn bool
+		if err := rows.Scan(&date, &subId, &studentName, &sessionID, &mode, &startID, &endID, &startedAt, &endedAt,
+			&minutes, &missingOut, &missingIn); err != nil {
+			continue
+		}
+
+		status := visitStatus(missingOut, missingIn, endedAt.Valid)
+		visit := gin.H{
+			"date":             date.Format("2006-01-02"),
+			"subscription_id":  subId.Int64,
+			"student_name":     studentName,
+			"mode":             mode,
+			"status":           status,
+			"duration_minutes": nil,
+		}
+		if sessionID.Valid {
+			visit["class_session_id"] = sessionID.Int64
+		}
+		if startID.Valid {
+			visit["check_in_id"] = startID.Int64
+			visit["checked_in_at"] = isoTimestamp(startedAt.Time)
+		}
+		if endID.Valid {
+			visit["check_out_id"] = endID.Int64
+			visit["checked_out_at"] = isoTimestamp(endedAt.Time)
+		}
+		if minutes.Valid {
+			visit["duration_minutes"] = minutes.Int64
+		}
+		visits = append(visits, visit)
+
+		key := date.Format("2006-01-02")
+		day, ok := byDate[key]
+		if !ok {
+			day = gin.H{"date": key, "visits": 0, "total_minutes": 0, "missing_check_outs": 0, "missing_check_ins": 0}
+			byDate[key] = day
+			days = append(days, day)
+		}
+		day["visits"] = day["visits"].(int) + 1
+		day["total_minutes"] = day["total_minutes"].(int) + int(minutes.Int64)
+		if missingOut {
+			day["missing_check_outs"] = day["missing_check_outs"].(int) + 1
+			missingCheckOuts++
+		}
+		if missingIn {
+			day["missing_check_ins"] = day["missing_check_ins"].(int) + 1
+		}
+		totalMinutes += int(minutes.Int64)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"teacher_id":         teacherID,
+		"from":               from.Format("2006-01-02"),
+		"to":                 to.Format("2006-01-02"),
+		"visits":             visits,
+		"days":               days,
+		"total_minutes":      totalMinutes,
+		"missing_check_outs": missingCheckOuts,
+	})
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ============================================
+// WAITLIST (Leads waiting for a teacher)
+// ============================================
+
+func createWaitlistEntry(c *gin.Context) {
+	var input struct {
+		StudentName   string     `json:"student_name"`
+		StudentPhone  string     `json:"student_phone"`
+		GuardianName  string     `json:"guardian_name"`
+		GuardianPhone string     `json:"guardian_phone"`
+		Class         int        `json:"class"`
+		Subjects      stringList `json:"subjects"`
+		ScheduleDays  stringList `json:"schedule_days"` // preferred days
+		PreferredTime string     `json:"preferred_time"`
+		Area          string     `json:"area"`
+		Notes         string     `json:"notes"`
+		Priority      int        `json:"priority"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(input.StudentName) == "" || input.Class == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "student_name and class are required"})
+		return
+	}
+	days, invalidDays := canonicalScheduleDays(input.ScheduleDays)
+	if len(invalidDays) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid day(s) " + strings.Join(invalidDays, ", ") + "; use Sat-Fri"})
+		return
+	}
+	input.ScheduleDays = days
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO mentor.waitlist (student_name, student_phone, guardian_name, guardian_phone, class,
+		                             subject_list, schedule_day_list, preferred_time, area, notes, priority)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11)
+		RETURNING id
+	`, input.StudentName, input.StudentPhone, input.GuardianName, input.GuardianPhone, input.Class,
+		pq.Array(input.Subjects), pq.Array(input.ScheduleDays), input.PreferredTime, input.Area, input.Notes,
+		input.Priority).Scan(&id)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id, "message": "Added to waitlist"})
+}
+
+// getWaitlist - Entries by priority then age (status defaults to waiting)
+func getWaitlist(c *gin.Context) {
+	query := `
+		SELECT id, student_name, student_phone, guardian_name, guardian_phone, class, subject_list,
+		       schedule_day_list, preferred_time, area, notes, priority, status, subscription_id, created_at
+		FROM mentor.waitlist
+		WHERE status = $1
+	`
+	args := []interface{}{c.DefaultQuery("status", "waiting")}
+	argCount := 1
+
+	if class := c.Query("class"); class != "" {
+		argCount++
+		query += fmt.Sprintf(" AND class = $%d", argCount)
+		args = append(args, class)
+	}
+	if subject := c.Query("subject"); subject != "" {
+		argCount++
+		query += fmt.Sprintf(" AND subject_list @> ARRAY[$%d]", argCount)
+		args = append(args, subject)
+	}
+	if area := c.Query("area"); area != "" {
+		argCount++
+		query += fmt.Sprintf(" AND area ILIKE '%%' || $%d || '%%'", argCount)
+		args = append(args, area)
+	}
+	query += " ORDER BY priority DESC, created_at"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	entries := []gin.H{}
+	for rows.Next() {
+		var id, class, priority int
+		var studentName, status string
+		var studentPhone, guardianName, guardianPhone, preferredTime, area, notes sql.NullString
+		var subjects, days []string
+		var subscriptionID sql.NullInt64
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &studentName, &studentPhone, &guardianName, &guardianPhone, &class,
+			pq.Array(&subjects), pq.Array(&days), &preferredTime, &area, &notes, &priority, &status,
+			&subscriptionID, &createdAt); err != nil {
+			continue
+		}
+
+		entry := gin.H{
+			"id":              id,
+			"student_name":    studentName,
+			"student_phone":   studentPhone.String,
+			"guardian_name":   guardianName.String,
+			"guardian_phone":  guardianPhone.String,
+			"class":           class,
+			"subjects":        subjects,
+			"schedule_days":   days,
+			"preferred_time":  preferredTime.String,
+			"area":            area.String,
+			"notes":           notes.String,
+			"priority":        priority,
+			"status":          status,
+			"subscription_id": nil,
+			"waiting_days":    int(time.Since(createdAt).Hours() / 24),
+			"created_at":      isoTimestamp(createdAt),
+		}
+		if subscriptionID.Valid {
+			entry["subscription_id"] = subscriptionID.Int64
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "waitlist": entries})
+}
+
+// updateWaitlistEntry - Reprioritize, annotate, or drop an entry
+func updateWaitlistEntry(c *gin.Context) {
+	var input struct {
+		Priority *int    `json:"priority"`
+		Notes    *string `json:"notes"`
+		Area     *string `json:"area"`
+		Status   *string `json:"status"` // "waiting" or "dropped"
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if input.Status != nil && *input.Status != "waiting" && *input.Status != "dropped" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "status must be 'waiting' or 'dropped'; use /promote to create the subscription"})
+		return
+	}
+
+	result, err := db.Exec(`
+		UPDATE mentor.waitlist
+		SET priority = COALESCE($1, priority), notes = COALESCE($2, notes), area = COALESCE($3, area),
+		    status = COALESCE($4, status), updated_at = NOW()
+		WHERE id = $5 AND status <> 'promoted'
+	`, input.Priority, input.Notes, input.Area, input.Status, c.Param("id"))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Waitlist entry not found or already promoted"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Waitlist entry updated"})
+}
+
+// promoteWaitlistEntry - Create the subscription once a teacher is assigned.
+// Student details, class, subjects and days come from the entry; the body
+// supplies the teacher and billing and may override subjects/days.
+func promoteWaitlistEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	var input subscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(bindingFieldErrors(err, &input)))
+		return
+	}
+
+	if input.TeacherID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "teacher_id is required"})
+		return
+	}
+
+	// Claim the entry first, so two promotions at once can't both create a
+	// subscription; it goes back to waiting if this one fails
+	var studentPhone, guardianName, guardianPhone, preferredTime, area sql.NullString
+	var subjects, days []string
+	err := db.QueryRow(`
+		UPDATE mentor.waitlist SET status = 'promoted', updated_at = NOW()
+		WHERE id = $1 AND status = 'waiting'
+		RETURNING student_name, student_phone, guardian_name, guardian_phone, class,
+		          subject_list, schedule_day_list, preferred_time, area
+	`, id).Scan(&input.StudentName, &studentPhone, &guardianName, &guardianPhone, &input.Class,
+		pq.Array(&subjects), pq.Array(&days), &preferredTime, &area)
+
+	if err == sql.ErrNoRows {
+		var status string
+		if db.QueryRow("SELECT status FROM mentor.waitlist WHERE id = $1", id).Scan(&status) != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Waitlist entry not found"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "Waitlist entry is " + status})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	input.StudentPhone, input.GuardianName, input.GuardianPhone = studentPhone.String, guardianName.String, guardianPhone.String
+	if len(input.Subjects) == 0 && input.PlanID == 0 {
+		input.Subjects = subjects
+	}
+	if len(input.ScheduleDays) == 0 {
+		input.ScheduleDays = days
+	}
+	if input.Time == "" {
+		input.Time = preferredTime.String
+	}
+	if input.Area == "" {
+		input.Area = area.String
+	}
+
+	resp, code := createSubscriptionFrom(input)
+	if code != http.StatusOK {
+		db.Exec("UPDATE mentor.waitlist SET status = 'waiting', updated_at = NOW() WHERE id = $1 AND subscription_id IS NULL", id)
+		c.JSON(code, resp)
+		return
+	}
+
+	db.Exec("UPDATE mentor.waitlist SET subscription_id = $1, updated_at = NOW() WHERE id = $2", resp["id"], id)
+
+	logAudit("waitlist", id, "promoted", input.TeacherID, gin.H{"subscription_id": resp["id"]})
+
+	resp["waitlist_id"] = id
+	resp["message"] = "Waitlist entry promoted to subscription"
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ============================================
+// XLSX (Minimal single-sheet spreadsheet writer)
+// ============================================
+
+// xlsxStaticParts are the package files every single-sheet workbook needs
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+// xlsxWriter streams rows into a one-sheet workbook. Strings are written
+// inline, so nothing is buffered beyond the zip entry being written.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+// newXLSXWriter starts a workbook with one sheet called name
+func newXLSXWriter(w io.Writer, name string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxStaticParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(f, part.body)
+	}
+
+	f, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`, xmlEscape(name))
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &xlsxWriter{zip: zw, sheet: sheet}, nil
+}
+
+// WriteRow adds one row; numbers and booleans keep their type, nil is an
+// empty cell and anything else text
+func (x *xlsxWriter) WriteRow(cells []interface{}) {
+	x.row++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+			b.WriteString(`<c/>`)
+		case int, int64, float64:
+			fmt.Fprintf(&b, `<c><v>%v</v></c>`, v)
+		case bool:
+			if v {
+				b.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				b.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		default:
+			fmt.Fprintf(&b, `<c t="inlineStr"><is><t>%s</t></is></c>`, xmlEscape(fmt.Sprint(v)))
+		}
+	}
+	b.WriteString(`</row>`)
+	io.WriteString(x.sheet, b.String())
+}
+
+// Close finishes the sheet and the zip
+func (x *xlsxWriter) Close() error {
+	io.WriteString(x.sheet, `</sheetData></worksheet>`)
+	return x.zip.Close()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}